@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/karpenter-optimizer/internal/config"
+	"github.com/karpenter-optimizer/internal/eval"
+	"github.com/karpenter-optimizer/internal/ollama"
+	"github.com/karpenter-optimizer/internal/recommender"
+	"github.com/spf13/cobra"
+)
+
+var (
+	evalCorpus           string
+	evalOut              string
+	evalLLMURL           string
+	evalLLMModel         string
+	evalLLMProvider      string
+	evalLLMAPIKey        string
+	evalFailOnRegression bool
+)
+
+var evalCmd = &cobra.Command{
+	Use:   "eval",
+	Short: "Evaluate recommendations against recorded cluster snapshots",
+	Long: `Evaluate the recommender, and optionally the LLM explanations, against a corpus of
+recorded cluster snapshots with expected outcomes. Runs offline: no cluster or AWS access is needed.`,
+}
+
+var evalRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Score a corpus and write a report",
+	Long: `Generate recommendations for every snapshot in the corpus and score savings, constraint
+violations, hallucinated instance types and explanation quality. Without --llm-url only the
+rule-based recommender runs.`,
+	Args: cobra.NoArgs,
+	RunE: runEval,
+}
+
+var evalDiffCmd = &cobra.Command{
+	Use:   "diff <base.json> <head.json>",
+	Short: "Compare two eval reports",
+	Long:  `Show the metric deltas and the NodePool results that changed between two eval reports, regressions first.`,
+	Args:  cobra.ExactArgs(2),
+	RunE:  runEvalDiff,
+}
+
+func init() {
+	evalRunCmd.Flags().StringVar(&evalCorpus, "corpus", "examples/eval", "Directory of snapshot cases (*.yaml, *.json)")
+	evalRunCmd.Flags().StringVarP(&evalOut, "out", "o", "", "Write the JSON report to this file")
+	evalRunCmd.Flags().StringVar(&evalLLMURL, "llm-url", "", "LLM server URL (e.g. http://localhost:11434); rules only when empty")
+	evalRunCmd.Flags().StringVar(&evalLLMModel, "llm-model", "granite4:latest", "LLM model")
+	evalRunCmd.Flags().StringVar(&evalLLMProvider, "llm-provider", "", "LLM provider (detected from the URL when empty)")
+	evalRunCmd.Flags().StringVar(&evalLLMAPIKey, "llm-api-key", os.Getenv("LLM_API_KEY"), "LLM API key")
+	evalDiffCmd.Flags().BoolVar(&evalFailOnRegression, "fail-on-regression", false, "Exit with an error when a NodePool regressed")
+
+	evalCmd.AddCommand(evalRunCmd)
+	evalCmd.AddCommand(evalDiffCmd)
+	rootCmd.AddCommand(evalCmd)
+}
+
+func runEval(cmd *cobra.Command, args []string) error {
+	cases, err := eval.LoadCorpus(evalCorpus)
+	if err != nil {
+		return err
+	}
+
+	// Keep the LLM resilience settings and PROMPT_TEMPLATES_DIR from the environment, but only
+	// talk to the LLM given on the command line, and never answer from cached replies
+	cfg := config.Load()
+	cfg.LLMURL, cfg.OllamaURL, cfg.LLMFallbacks = "", "", ""
+	cfg.LLMCacheEnabled = false
+	if evalLLMURL != "" {
+		cfg.LLMURL = evalLLMURL
+		cfg.LLMModel = evalLLMModel
+		cfg.LLMAPIKey = evalLLMAPIKey
+		cfg.LLMProvider = evalLLMProvider
+		if cfg.LLMProvider == "" {
+			cfg.LLMProvider = ollama.DetectProvider(evalLLMURL)
+		}
+	}
+	rec := recommender.NewOfflineRecommender(cfg)
+	if evalLLMURL != "" && !rec.HasLLM() {
+		return fmt.Errorf("failed to configure LLM %s", evalLLMURL)
+	}
+
+	report := eval.Run(context.Background(), rec, cases)
+
+	if evalOut != "" {
+		file, err := os.Create(evalOut)
+		if err != nil {
+			return fmt.Errorf("failed to create report file: %w", err)
+		}
+		if err := report.WriteJSON(file); err != nil {
+			_ = file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write report file: %w", err)
+		}
+	}
+
+	if outputJSON {
+		return report.WriteJSON(os.Stdout)
+	}
+	return report.WriteText(os.Stdout)
+}
+
+func runEvalDiff(cmd *cobra.Command, args []string) error {
+	base, err := eval.LoadReport(args[0])
+	if err != nil {
+		return err
+	}
+	head, err := eval.LoadReport(args[1])
+	if err != nil {
+		return err
+	}
+
+	diff := eval.Compare(base, head)
+	if outputJSON {
+		prettyJSON, _ := json.MarshalIndent(diff, "", "  ")
+		fmt.Println(string(prettyJSON))
+	} else if err := diff.WriteText(os.Stdout); err != nil {
+		return err
+	}
+
+	if evalFailOnRegression && diff.Regressions() > 0 {
+		return fmt.Errorf("%d NodePool(s) regressed", diff.Regressions())
+	}
+	return nil
+}
@@ -0,0 +1,536 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/recommender"
+)
+
+const (
+	instanceTypeLabel = "node.kubernetes.io/instance-type"
+	capacityTypeLabel = "karpenter.sh/capacity-type"
+
+	// revertTimeout bounds a revert, which is not cut short when the watch is cancelled
+	revertTimeout = 30 * time.Second
+)
+
+// ApplyGuardrails limit which plans may be applied to a NodePool and when an applied plan is reverted
+type ApplyGuardrails struct {
+	MaxCapacityReductionPercent float64             `json:"maxCapacityReductionPercent"` // 0 disables the check
+	MaintenanceWindows          []MaintenanceWindow `json:"maintenanceWindows"`          // Empty allows any time
+	MinOnDemandRatio            float64             `json:"minOnDemandRatio"`            // Cluster-wide on-demand share of CPU (0-1), 0 disables
+	WatchWindow                 time.Duration       `json:"watchWindow"`                 // How long an applied plan is watched
+	CheckInterval               time.Duration       `json:"checkInterval"`
+	MaxPendingPods              int                 `json:"maxPendingPods"`      // More pending pods reverts the plan
+	MaxDisruptionErrors         int                 `json:"maxDisruptionErrors"` // More Karpenter warnings reverts the plan
+}
+
+// MaintenanceWindow is a recurring weekly time range, e.g. "Sat,Sun 02:00-06:00".
+// Windows whose end is before their start run past midnight into the next day.
+type MaintenanceWindow struct {
+	Days     [7]bool        `json:"-"` // Indexed by time.Weekday
+	Start    int            `json:"-"` // Minutes since midnight
+	End      int            `json:"-"`
+	Location *time.Location `json:"-"`
+	Spec     string         `json:"spec"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseMaintenanceWindows parses semicolon-separated windows such as
+// "Sat,Sun 02:00-06:00; Mon-Fri 22:00-01:00" in the given location (UTC if nil)
+func ParseMaintenanceWindows(spec string, loc *time.Location) ([]MaintenanceWindow, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	var windows []MaintenanceWindow
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fields := strings.Fields(part)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid maintenance window %q: expected \"<days> <HH:MM>-<HH:MM>\"", part)
+		}
+		window := MaintenanceWindow{Location: loc, Spec: part}
+		for _, days := range strings.Split(fields[0], ",") {
+			from, to, isRange := strings.Cut(strings.ToLower(days), "-")
+			first, ok := weekdays[from]
+			if !ok {
+				return nil, fmt.Errorf("invalid maintenance window %q: unknown day %q", part, from)
+			}
+			last := first
+			if isRange {
+				if last, ok = weekdays[to]; !ok {
+					return nil, fmt.Errorf("invalid maintenance window %q: unknown day %q", part, to)
+				}
+			}
+			for d := first; ; d = (d + 1) % 7 {
+				window.Days[d] = true
+				if d == last {
+					break
+				}
+			}
+		}
+		start, end, ok := strings.Cut(fields[1], "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid maintenance window %q: expected a time range", part)
+		}
+		var err error
+		if window.Start, err = parseClock(start); err != nil {
+			return nil, fmt.Errorf("invalid maintenance window %q: %w", part, err)
+		}
+		if window.End, err = parseClock(end); err != nil {
+			return nil, fmt.Errorf("invalid maintenance window %q: %w", part, err)
+		}
+		if window.Start == window.End {
+			return nil, fmt.Errorf("invalid maintenance window %q: empty time range", part)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// parseClock parses HH:MM into minutes since midnight (24:00 is allowed as an end time)
+func parseClock(value string) (int, error) {
+	hours, minutes, ok := strings.Cut(value, ":")
+	h, err1 := strconv.Atoi(hours)
+	m, err2 := strconv.Atoi(minutes)
+	if !ok || err1 != nil || err2 != nil || h < 0 || m < 0 || m > 59 || h*60+m > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return h*60 + m, nil
+}
+
+// Contains reports whether t falls inside the window
+func (w MaintenanceWindow) Contains(t time.Time) bool {
+	if w.Location != nil {
+		t = t.In(w.Location)
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if w.Start < w.End {
+		return w.Days[t.Weekday()] && minute >= w.Start && minute < w.End
+	}
+	// Overnight: the start day's evening or the following morning
+	previous := (t.Weekday() + 6) % 7
+	return (w.Days[t.Weekday()] && minute >= w.Start) || (w.Days[previous] && minute < w.End)
+}
+
+// InMaintenanceWindow reports whether t is inside any window (always true without windows)
+func (g ApplyGuardrails) InMaintenanceWindow(t time.Time) bool {
+	if len(g.MaintenanceWindows) == 0 {
+		return true
+	}
+	for _, w := range g.MaintenanceWindows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyHealth is what an applied NodePool looks like while it is being watched
+type ApplyHealth struct {
+	PendingPods int      `json:"pendingPods"`
+	Warnings    []string `json:"warnings,omitempty"` // Karpenter launch/disruption errors
+}
+
+// ApplyMonitor provides the cluster signals used by guardrails and post-apply watching
+type ApplyMonitor interface {
+	// Health returns pending pods and Karpenter warnings for a NodePool since the given time
+	Health(ctx context.Context, nodePool string, since time.Time) (*ApplyHealth, error)
+	// CPUByCapacityType returns allocatable CPU per NodePool and capacity type
+	CPUByCapacityType(ctx context.Context) (map[string]map[string]float64, error)
+}
+
+// ClusterApplyMonitor reads apply health from the cluster
+type ClusterApplyMonitor struct {
+	k8sClient *kubernetes.Client
+}
+
+// NewClusterApplyMonitor creates a monitor backed by the Kubernetes client
+func NewClusterApplyMonitor(k8sClient *kubernetes.Client) *ClusterApplyMonitor {
+	return &ClusterApplyMonitor{k8sClient: k8sClient}
+}
+
+// Health returns pending pods the NodePool could run and Karpenter warnings for it since the given time
+func (m *ClusterApplyMonitor) Health(ctx context.Context, nodePool string, since time.Time) (*ApplyHealth, error) {
+	pending, err := m.k8sClient.DiagnosePendingPods(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to diagnose pending pods: %w", err)
+	}
+	warnings, err := m.k8sClient.KarpenterWarnings(ctx, nodePool, since)
+	if err != nil {
+		return nil, err
+	}
+	return &ApplyHealth{PendingPods: countPendingForNodePool(pending, nodePool), Warnings: warnings}, nil
+}
+
+// CPUByCapacityType sums allocatable node CPU per NodePool and capacity type
+func (m *ClusterApplyMonitor) CPUByCapacityType(ctx context.Context) (map[string]map[string]float64, error) {
+	nodes, err := m.k8sClient.GetAllNodesWithUsage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	cpu := make(map[string]map[string]float64)
+	for _, node := range nodes {
+		if node.CPUUsage == nil {
+			continue
+		}
+		if cpu[node.NodePool] == nil {
+			cpu[node.NodePool] = make(map[string]float64)
+		}
+		cpu[node.NodePool][node.CapacityType] += node.CPUUsage.Allocatable
+	}
+	return cpu, nil
+}
+
+// ApplyResult describes an attempt to apply a plan to its NodePool
+type ApplyResult struct {
+	PlanID     string                 `json:"planId"`
+	NodePool   string                 `json:"nodePool"`
+	DryRun     bool                   `json:"dryRun"`
+	Blocked    bool                   `json:"blocked"`              // A guardrail prevented the apply
+	Violations []string               `json:"violations,omitempty"` // Why the apply was blocked
+	Patch      map[string]interface{} `json:"patch,omitempty"`      // Merge patch of the NodePool spec
+	AppliedAt  time.Time              `json:"appliedAt,omitempty"`
+	// RevertPatch restores the requirements the patch replaced; other spec changes are kept
+	RevertPatch map[string]interface{} `json:"revertPatch,omitempty"`
+}
+
+// WatchResult is the outcome of watching an applied plan
+type WatchResult struct {
+	Reverted    bool     `json:"reverted"`
+	Reason      string   `json:"reason,omitempty"`
+	Checks      int      `json:"checks"`
+	PendingPods int      `json:"pendingPods"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// PlanApplier applies approved plans to NodePools: guardrails first, then a
+// server-side dry-run, the real patch, and a watch that reverts on breach
+type PlanApplier struct {
+	k8sClient  *kubernetes.Client
+	monitor    ApplyMonitor
+	guardrails ApplyGuardrails
+	now        func() time.Time
+}
+
+// NewPlanApplier creates an applier. Zero watch settings default to a 30 minute window checked every minute.
+func NewPlanApplier(k8sClient *kubernetes.Client, monitor ApplyMonitor, guardrails ApplyGuardrails) *PlanApplier {
+	if guardrails.WatchWindow <= 0 {
+		guardrails.WatchWindow = 30 * time.Minute
+	}
+	if guardrails.CheckInterval <= 0 {
+		guardrails.CheckInterval = time.Minute
+	}
+	return &PlanApplier{k8sClient: k8sClient, monitor: monitor, guardrails: guardrails, now: time.Now}
+}
+
+// Guardrails returns the applier's guardrails
+func (a *PlanApplier) Guardrails() ApplyGuardrails {
+	return a.guardrails
+}
+
+// Apply checks guardrails for a plan and dry-runs its NodePool patch on the API
+// server. Unless dryRun is set, the patch is then applied and the patch that
+// reverts it kept for Watch. A plan blocked by guardrails is returned with Blocked set, not as an error.
+func (a *PlanApplier) Apply(ctx context.Context, plan *OptimizationPlan, dryRun bool) (*ApplyResult, error) {
+	if len(plan.Recommendations) == 0 {
+		return nil, fmt.Errorf("plan %s has no NodePool recommendation to apply", plan.ID)
+	}
+	rec := plan.Recommendations[0]
+	result := &ApplyResult{PlanID: plan.ID, NodePool: plan.NodePoolName, DryRun: dryRun}
+
+	spec, annotations, err := a.k8sClient.GetNodePoolSpec(ctx, plan.NodePoolName)
+	if err != nil {
+		return nil, err
+	}
+	result.Violations = a.checkGuardrails(ctx, plan.NodePoolName, rec, annotations, dryRun)
+	if len(result.Violations) > 0 {
+		result.Blocked = true
+		return result, nil
+	}
+
+	patch, err := NodePoolSpecPatch(spec, rec)
+	if err != nil {
+		return nil, err
+	}
+	result.Patch = patch
+
+	if err := a.k8sClient.PatchNodePoolSpec(ctx, plan.NodePoolName, patch, true); err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return result, nil
+	}
+	if err := a.k8sClient.PatchNodePoolSpec(ctx, plan.NodePoolName, patch, false); err != nil {
+		return nil, err
+	}
+	result.AppliedAt = a.now()
+	result.RevertPatch = nodePoolRevertPatch(spec)
+	return result, nil
+}
+
+// Revert restores the NodePool requirements an applied plan replaced
+func (a *PlanApplier) Revert(ctx context.Context, result *ApplyResult) error {
+	if result == nil || result.RevertPatch == nil {
+		return fmt.Errorf("nothing was applied to revert")
+	}
+	return a.k8sClient.PatchNodePoolSpec(ctx, result.NodePool, result.RevertPatch, false)
+}
+
+// PendingWatch returns what to watch for an applied plan whose watch window has not
+// passed yet (e.g. after a restart), or nil
+func (a *PlanApplier) PendingWatch(plan *OptimizationPlan) *ApplyResult {
+	appliedAt := plan.AppliedAt()
+	if plan.Status != PlanStatusApplied || plan.RevertPatch == nil || !a.now().Before(appliedAt.Add(a.guardrails.WatchWindow)) {
+		return nil
+	}
+	return &ApplyResult{PlanID: plan.ID, NodePool: plan.NodePoolName, AppliedAt: appliedAt, RevertPatch: plan.RevertPatch}
+}
+
+// checkGuardrails returns the guardrails a recommendation would violate.
+// Dry runs skip the maintenance window so plans can be checked ahead of time.
+func (a *PlanApplier) checkGuardrails(ctx context.Context, nodePool string, rec recommender.NodePoolCapacityRecommendation, annotations map[string]string, dryRun bool) []string {
+	var violations []string
+	if annotations[kubernetes.AnnotationExcludeFromAutoApply] == "true" {
+		violations = append(violations, fmt.Sprintf("NodePool %s is excluded by the %s annotation", nodePool, kubernetes.AnnotationExcludeFromAutoApply))
+	}
+	if !dryRun && !a.guardrails.InMaintenanceWindow(a.now()) {
+		violations = append(violations, "outside the maintenance windows")
+	}
+	if limit := a.guardrails.MaxCapacityReductionPercent; limit > 0 {
+		if reduction := capacityReductionPercent(rec); reduction > limit {
+			violations = append(violations, fmt.Sprintf("capacity reduction %.1f%% exceeds the %.1f%% limit", reduction, limit))
+		}
+	}
+	if minRatio := a.guardrails.MinOnDemandRatio; minRatio > 0 && a.monitor != nil {
+		cpu, err := a.monitor.CPUByCapacityType(ctx)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("cannot check on-demand ratio: %v", err))
+		} else if before, after := onDemandRatios(cpu, nodePool, rec); after < minRatio && after < before {
+			violations = append(violations, fmt.Sprintf("cluster on-demand CPU ratio would drop to %.2f, below the %.2f minimum", after, minRatio))
+		}
+	}
+	return violations
+}
+
+// capacityReductionPercent is the larger of the CPU and memory capacity reduction of a recommendation
+func capacityReductionPercent(rec recommender.NodePoolCapacityRecommendation) float64 {
+	reduction := 0.0
+	for _, pair := range [][2]float64{
+		{rec.CurrentCPUCapacity, rec.RecommendedTotalCPU},
+		{rec.CurrentMemoryCapacity, rec.RecommendedTotalMemory},
+	} {
+		if pair[0] > 0 && pair[1] < pair[0] {
+			if r := (pair[0] - pair[1]) / pair[0] * 100; r > reduction {
+				reduction = r
+			}
+		}
+	}
+	return reduction
+}
+
+// onDemandRatios returns the cluster on-demand CPU share now and after the recommendation is applied
+func onDemandRatios(cpu map[string]map[string]float64, nodePool string, rec recommender.NodePoolCapacityRecommendation) (before, after float64) {
+	var total, onDemand, poolTotal, poolOnDemand float64
+	for pool, byType := range cpu {
+		for capacityType, value := range byType {
+			total += value
+			if capacityType == "on-demand" {
+				onDemand += value
+			}
+			if pool == nodePool {
+				poolTotal += value
+				if capacityType == "on-demand" {
+					poolOnDemand += value
+				}
+			}
+		}
+	}
+	newPoolTotal := rec.RecommendedTotalCPU
+	if newPoolTotal <= 0 {
+		newPoolTotal = poolTotal
+	}
+	newPoolOnDemand := 0.0
+	if rec.CapacityType == "on-demand" {
+		newPoolOnDemand = newPoolTotal
+	}
+	if total > 0 {
+		before = onDemand / total
+	}
+	if newTotal := total - poolTotal + newPoolTotal; newTotal > 0 {
+		after = (onDemand - poolOnDemand + newPoolOnDemand) / newTotal
+	}
+	return before, after
+}
+
+// NodePoolSpecPatch builds a merge patch of a NodePool spec that restricts it to the
+// recommended instance types and capacity type. Other requirements are kept, except
+// instance selectors (karpenter.k8s.aws/instance-*) that the explicit list replaces.
+func NodePoolSpecPatch(spec map[string]interface{}, rec recommender.NodePoolCapacityRecommendation) (map[string]interface{}, error) {
+	if len(rec.RecommendedInstanceTypes) == 0 && rec.CapacityType == "" {
+		return nil, fmt.Errorf("recommendation for NodePool %s has no instance or capacity type", rec.NodePoolName)
+	}
+
+	path := requirementsPath(spec)
+	existing := nestedSlice(spec, path...)
+
+	requirements := make([]interface{}, 0, len(existing)+2)
+	for _, item := range existing {
+		req, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key, _ := req["key"].(string)
+		if key == capacityTypeLabel && rec.CapacityType == "" {
+			requirements = append(requirements, req)
+			continue
+		}
+		if key == instanceTypeLabel || key == capacityTypeLabel || strings.HasPrefix(key, "karpenter.k8s.aws/instance-") {
+			continue
+		}
+		requirements = append(requirements, req)
+	}
+	if len(rec.RecommendedInstanceTypes) > 0 {
+		requirements = append(requirements, requirement(instanceTypeLabel, rec.RecommendedInstanceTypes))
+	} else {
+		for _, item := range existing {
+			if req, ok := item.(map[string]interface{}); ok && req["key"] == instanceTypeLabel {
+				requirements = append(requirements, req)
+			}
+		}
+	}
+	if rec.CapacityType != "" {
+		requirements = append(requirements, requirement(capacityTypeLabel, []string{rec.CapacityType}))
+	}
+	if reflect.DeepEqual(normalizeRequirements(existing), normalizeRequirements(requirements)) {
+		return nil, fmt.Errorf("plan does not change NodePool %s", rec.NodePoolName)
+	}
+
+	// Merge patches replace lists, so the patch carries the complete requirement list
+	return nestedPatch(path, requirements), nil
+}
+
+// nodePoolRevertPatch builds the merge patch that puts back a NodePool spec's current
+// requirements, or removes them if it has none
+func nodePoolRevertPatch(spec map[string]interface{}) map[string]interface{} {
+	path := requirementsPath(spec)
+	if existing := nestedSlice(spec, path...); existing != nil {
+		return nestedPatch(path, existing)
+	}
+	return nestedPatch(path, nil)
+}
+
+// requirementsPath is where a NodePool spec keeps its requirements: karpenter.sh/v1 and
+// v1beta1 nest them under spec.template.spec, v1alpha5 has them on the spec
+func requirementsPath(spec map[string]interface{}) []string {
+	if _, ok := spec["template"]; !ok {
+		return []string{"requirements"}
+	}
+	return []string{"template", "spec", "requirements"}
+}
+
+func nestedPatch(path []string, value interface{}) map[string]interface{} {
+	for i := len(path) - 1; i >= 0; i-- {
+		value = map[string]interface{}{path[i]: value}
+	}
+	return value.(map[string]interface{})
+}
+
+func requirement(key string, values []string) map[string]interface{} {
+	list := make([]interface{}, len(values))
+	for i, v := range values {
+		list[i] = v
+	}
+	return map[string]interface{}{"key": key, "operator": "In", "values": list}
+}
+
+// normalizeRequirements indexes requirements by key so ordering does not count as a change
+func normalizeRequirements(requirements []interface{}) map[string]string {
+	normalized := make(map[string]string, len(requirements))
+	for _, item := range requirements {
+		if req, ok := item.(map[string]interface{}); ok {
+			key, _ := req["key"].(string)
+			normalized[key] = fmt.Sprint(req["operator"], req["values"], req["minValues"])
+		}
+	}
+	return normalized
+}
+
+func nestedSlice(obj map[string]interface{}, path ...string) []interface{} {
+	var current interface{} = obj
+	for _, key := range path {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	list, _ := current.([]interface{})
+	return list
+}
+
+// Watch checks an applied NodePool every CheckInterval until the watch window
+// passes or ctx is done, and restores the requirements the plan replaced as soon as
+// pending pods or Karpenter warnings exceed their thresholds. A revert that has started
+// is finished even if ctx is cancelled. Transient monitor errors are logged and skipped.
+func (a *PlanApplier) Watch(ctx context.Context, result *ApplyResult) (*WatchResult, error) {
+	if result == nil || result.RevertPatch == nil {
+		return nil, fmt.Errorf("nothing was applied to watch")
+	}
+	watch := &WatchResult{}
+	deadline := result.AppliedAt.Add(a.guardrails.WatchWindow)
+	ticker := time.NewTicker(a.guardrails.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return watch, ctx.Err()
+		case <-ticker.C:
+		}
+
+		health, err := a.monitor.Health(ctx, result.NodePool, result.AppliedAt)
+		if err != nil {
+			fmt.Printf("Warning: Failed to check NodePool %s after applying plan %s: %v\n", result.NodePool, result.PlanID, err)
+		} else {
+			watch.Checks++
+			watch.PendingPods = health.PendingPods
+			watch.Warnings = health.Warnings
+			var reasons []string
+			if health.PendingPods > a.guardrails.MaxPendingPods {
+				reasons = append(reasons, fmt.Sprintf("%d pending pods (max %d)", health.PendingPods, a.guardrails.MaxPendingPods))
+			}
+			if len(health.Warnings) > a.guardrails.MaxDisruptionErrors {
+				reasons = append(reasons, fmt.Sprintf("%d Karpenter warnings (max %d)", len(health.Warnings), a.guardrails.MaxDisruptionErrors))
+			}
+			if len(reasons) > 0 {
+				watch.Reason = strings.Join(reasons, ", ")
+				revertCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), revertTimeout)
+				err := a.Revert(revertCtx, result)
+				cancel()
+				if err != nil {
+					return watch, fmt.Errorf("failed to revert NodePool %s after %s: %w", result.NodePool, watch.Reason, err)
+				}
+				watch.Reverted = true
+				return watch, nil
+			}
+		}
+
+		if !a.now().Before(deadline) {
+			return watch, nil
+		}
+	}
+}
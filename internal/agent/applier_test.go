@@ -0,0 +1,225 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/recommender"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestParseMaintenanceWindows(t *testing.T) {
+	windows, err := ParseMaintenanceWindows("Sat,Sun 02:00-06:00; Mon-Fri 22:00-01:00", time.UTC)
+	require.NoError(t, err)
+	require.Len(t, windows, 2)
+	guardrails := ApplyGuardrails{MaintenanceWindows: windows}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{name: "saturday morning", at: time.Date(2026, 3, 14, 3, 0, 0, 0, time.UTC), want: true},
+		{name: "saturday afternoon", at: time.Date(2026, 3, 14, 15, 0, 0, 0, time.UTC), want: false},
+		{name: "monday night", at: time.Date(2026, 3, 9, 23, 0, 0, 0, time.UTC), want: true},
+		{name: "friday night runs into saturday", at: time.Date(2026, 3, 14, 0, 30, 0, 0, time.UTC), want: true},
+		{name: "monday just after midnight", at: time.Date(2026, 3, 9, 0, 30, 0, 0, time.UTC), want: false},
+		{name: "end is exclusive", at: time.Date(2026, 3, 15, 6, 0, 0, 0, time.UTC), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, guardrails.InMaintenanceWindow(tt.at))
+		})
+	}
+
+	assert.True(t, ApplyGuardrails{}.InMaintenanceWindow(time.Now()), "no windows allows any time")
+	for _, invalid := range []string{"Sat", "Funday 01:00-02:00", "Sat 25:00-26:00", "Sat 02:00-02:00"} {
+		_, err := ParseMaintenanceWindows(invalid, nil)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestNodePoolSpecPatch(t *testing.T) {
+	spec := map[string]interface{}{
+		"template": map[string]interface{}{"spec": map[string]interface{}{"requirements": []interface{}{
+			map[string]interface{}{"key": "kubernetes.io/arch", "operator": "In", "values": []interface{}{"amd64"}},
+			map[string]interface{}{"key": "karpenter.k8s.aws/instance-family", "operator": "In", "values": []interface{}{"m5"}},
+			map[string]interface{}{"key": "node.kubernetes.io/instance-type", "operator": "In", "values": []interface{}{"m5.xlarge"}},
+		}}},
+	}
+	rec := recommender.NodePoolCapacityRecommendation{NodePoolName: "default", RecommendedInstanceTypes: []string{"m6i.large"}, CapacityType: "spot"}
+
+	patch, err := NodePoolSpecPatch(spec, rec)
+	require.NoError(t, err)
+	requirements, _, _ := unstructured.NestedSlice(patch, "template", "spec", "requirements")
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"key": "kubernetes.io/arch", "operator": "In", "values": []interface{}{"amd64"}},
+		map[string]interface{}{"key": "node.kubernetes.io/instance-type", "operator": "In", "values": []interface{}{"m6i.large"}},
+		map[string]interface{}{"key": "karpenter.sh/capacity-type", "operator": "In", "values": []interface{}{"spot"}},
+	}, requirements)
+
+	// Applying the same recommendation again is not a change
+	unstructured.SetNestedSlice(spec, requirements, "template", "spec", "requirements")
+	_, err = NodePoolSpecPatch(spec, rec)
+	assert.Error(t, err)
+
+	// v1alpha5 NodePools keep requirements on the spec
+	patch, err = NodePoolSpecPatch(map[string]interface{}{}, rec)
+	require.NoError(t, err)
+	assert.Contains(t, patch, "requirements")
+}
+
+// scriptedMonitor returns one health result per check, repeating the last one
+type scriptedMonitor struct {
+	health []ApplyHealth
+	cpu    map[string]map[string]float64
+	checks int
+}
+
+func (m *scriptedMonitor) Health(ctx context.Context, nodePool string, since time.Time) (*ApplyHealth, error) {
+	h := m.health[min(m.checks, len(m.health)-1)]
+	m.checks++
+	return &h, nil
+}
+
+func (m *scriptedMonitor) CPUByCapacityType(ctx context.Context) (map[string]map[string]float64, error) {
+	return m.cpu, nil
+}
+
+// newApplierTestClient returns a client with one karpenter.sh/v1 NodePool on m5.xlarge
+func newApplierTestClient(annotations map[string]string) *kubernetes.Client {
+	gvr := schema.GroupVersionResource{Group: "karpenter.sh", Version: "v1", Resource: "nodepools"}
+	np := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "karpenter.sh/v1",
+		"kind":       "NodePool",
+		"metadata":   map[string]interface{}{"name": "default"},
+		"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{"requirements": []interface{}{
+			map[string]interface{}{"key": "node.kubernetes.io/instance-type", "operator": "In", "values": []interface{}{"m5.xlarge"}},
+			map[string]interface{}{"key": "karpenter.sh/capacity-type", "operator": "In", "values": []interface{}{"on-demand"}},
+		}}}},
+	}}
+	np.SetAnnotations(annotations)
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{gvr: "NodePoolList"}, np)
+	// Dry runs are admitted without being persisted, like the API server
+	dynamicClient.PrependReactor("patch", "nodepools", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return len(action.(k8stesting.PatchActionImpl).GetPatchOptions().DryRun) > 0, nil, nil
+	})
+	return kubernetes.NewClientFromInterfaces(fake.NewSimpleClientset(), dynamicClient)
+}
+
+func instanceTypes(t *testing.T, client *kubernetes.Client) []interface{} {
+	spec, _, err := client.GetNodePoolSpec(context.Background(), "default")
+	require.NoError(t, err)
+	requirements, _, _ := unstructured.NestedSlice(spec, "template", "spec", "requirements")
+	for _, r := range requirements {
+		if req := r.(map[string]interface{}); req["key"] == instanceTypeLabel {
+			return req["values"].([]interface{})
+		}
+	}
+	return nil
+}
+
+func TestPlanApplier(t *testing.T) {
+	ctx := context.Background()
+	plan := &OptimizationPlan{ID: "plan-1", NodePoolName: "default", Recommendations: []recommender.NodePoolCapacityRecommendation{{
+		NodePoolName:             "default",
+		CurrentCPUCapacity:       16,
+		CurrentMemoryCapacity:    64,
+		RecommendedInstanceTypes: []string{"m6i.large"},
+		RecommendedTotalCPU:      12,
+		RecommendedTotalMemory:   48,
+		CapacityType:             "spot",
+	}}}
+	guardrails := ApplyGuardrails{WatchWindow: 50 * time.Millisecond, CheckInterval: 5 * time.Millisecond, MaxPendingPods: 2, MaxDisruptionErrors: 1}
+	cpu := map[string]map[string]float64{"default": {"on-demand": 16}, "system": {"on-demand": 8}}
+
+	t.Run("guardrails block", func(t *testing.T) {
+		strict := guardrails
+		strict.MaxCapacityReductionPercent = 20
+		strict.MinOnDemandRatio = 0.5
+		strict.MaintenanceWindows, _ = ParseMaintenanceWindows("Sun 00:00-00:01", time.UTC)
+		client := newApplierTestClient(map[string]string{kubernetes.AnnotationExcludeFromAutoApply: "true"})
+		applier := NewPlanApplier(client, &scriptedMonitor{cpu: cpu}, strict)
+		applier.now = func() time.Time { return time.Date(2026, 3, 11, 12, 0, 0, 0, time.UTC) }
+
+		result, err := applier.Apply(ctx, plan, false)
+		require.NoError(t, err)
+		assert.True(t, result.Blocked)
+		assert.Len(t, result.Violations, 4) // excluded, window, 25% reduction, on-demand ratio 8/20
+		assert.Equal(t, []interface{}{"m5.xlarge"}, instanceTypes(t, client))
+	})
+
+	t.Run("dry run does not change the NodePool", func(t *testing.T) {
+		client := newApplierTestClient(nil)
+		result, err := NewPlanApplier(client, &scriptedMonitor{cpu: cpu}, guardrails).Apply(ctx, plan, true)
+		require.NoError(t, err)
+		assert.False(t, result.Blocked)
+		assert.NotEmpty(t, result.Patch)
+		assert.Equal(t, []interface{}{"m5.xlarge"}, instanceTypes(t, client))
+		_, err = NewPlanApplier(client, nil, guardrails).Watch(ctx, result)
+		assert.Error(t, err, "nothing to watch after a dry run")
+	})
+
+	t.Run("healthy plan is kept", func(t *testing.T) {
+		client := newApplierTestClient(nil)
+		monitor := &scriptedMonitor{health: []ApplyHealth{{PendingPods: 1}}}
+		applier := NewPlanApplier(client, monitor, guardrails)
+		result, err := applier.Apply(ctx, plan, false)
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{"m6i.large"}, instanceTypes(t, client))
+
+		watch, err := applier.Watch(ctx, result)
+		require.NoError(t, err)
+		assert.False(t, watch.Reverted)
+		assert.Greater(t, watch.Checks, 1)
+		assert.Equal(t, []interface{}{"m6i.large"}, instanceTypes(t, client))
+	})
+
+	t.Run("breach reverts the requirements only", func(t *testing.T) {
+		client := newApplierTestClient(nil)
+		monitor := &scriptedMonitor{health: []ApplyHealth{{}, {PendingPods: 3, Warnings: []string{"NodeClaim default-x: FailedLaunch"}}}}
+		applier := NewPlanApplier(client, monitor, guardrails)
+		result, err := applier.Apply(ctx, plan, false)
+		require.NoError(t, err)
+		// Edited by someone else during the watch window
+		require.NoError(t, client.PatchNodePoolSpec(ctx, "default", map[string]interface{}{"limits": map[string]interface{}{"cpu": "100"}}, false))
+
+		watch, err := applier.Watch(ctx, result)
+		require.NoError(t, err)
+		assert.True(t, watch.Reverted)
+		assert.Equal(t, 2, watch.Checks)
+		assert.Contains(t, watch.Reason, "3 pending pods")
+		assert.Equal(t, []interface{}{"m5.xlarge"}, instanceTypes(t, client))
+		spec, _, err := client.GetNodePoolSpec(ctx, "default")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"cpu": "100"}, spec["limits"])
+	})
+
+	t.Run("pending watch after a restart", func(t *testing.T) {
+		applier := NewPlanApplier(newApplierTestClient(nil), nil, guardrails)
+		now := time.Now()
+		applier.now = func() time.Time { return now }
+		revert := nodePoolRevertPatch(map[string]interface{}{"requirements": []interface{}{}})
+		stored := &OptimizationPlan{ID: "plan-1", NodePoolName: "default", Status: PlanStatusApplied, RevertPatch: revert,
+			Transitions: []PlanTransition{{To: PlanStatusApplied, At: now.Add(-10 * time.Millisecond)}}}
+
+		result := applier.PendingWatch(stored)
+		require.NotNil(t, result)
+		assert.Equal(t, "default", result.NodePool)
+		assert.Equal(t, revert, result.RevertPatch)
+
+		stored.Transitions[0].At = now.Add(-time.Hour)
+		assert.Nil(t, applier.PendingWatch(stored), "watch window passed")
+		stored.Transitions[0].At, stored.RevertPatch = now, nil
+		assert.Nil(t, applier.PendingWatch(stored), "applied by hand")
+	})
+}
@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+// BanditPolicy is the name of the strategy selection policy reported in stats
+const BanditPolicy = "thompson-sampling"
+
+// BanditConfig tunes how strategies are chosen from past outcomes
+type BanditConfig struct {
+	HalfLife        time.Duration // Outcomes lose half their weight after this long (0 = no decay)
+	IncidentPenalty float64       // Reward subtracted per incident (rewards are between 0 and 1)
+}
+
+// DefaultBanditConfig halves the weight of an outcome every 30 days
+var DefaultBanditConfig = BanditConfig{HalfLife: 30 * 24 * time.Hour, IncidentPenalty: 0.25}
+
+// ArmStats describes one NodePool-strategy pair of the strategy bandit. Pulls and
+// RewardSum are decayed by outcome age; Alpha and Beta are the Beta posterior of
+// the arm's reward, starting from a uniform prior.
+type ArmStats struct {
+	NodePoolName   string               `json:"nodePoolName"`
+	Strategy       OptimizationStrategy `json:"strategy"`
+	Outcomes       int                  `json:"outcomes"`
+	Pulls          float64              `json:"pulls"`
+	RewardSum      float64              `json:"rewardSum"`
+	MeanReward     float64              `json:"meanReward"`
+	Alpha          float64              `json:"alpha"`
+	Beta           float64              `json:"beta"`
+	ExpectedReward float64              `json:"expectedReward"`
+	LastOutcomeAt  time.Time            `json:"lastOutcomeAt,omitempty"`
+}
+
+// outcomeReward scores an outcome between 0 and 1: the share of predicted savings
+// actually realized, minus a penalty per incident. Rejected or rolled-back plans
+// earn nothing.
+func outcomeReward(outcome OptimizationOutcome, incidentPenalty float64) float64 {
+	if outcome.UserFeedback == "rejected" {
+		return 0
+	}
+	reward := 0.0
+	if outcome.PredictedSavings > 0 {
+		reward = math.Min(math.Max(outcome.ActualSavings/outcome.PredictedSavings, 0), 1)
+	} else if outcome.ActualSavings > 0 {
+		reward = 1
+	}
+	incidents := len(outcome.Incidents)
+	if outcome.PerformanceImpact == "negative" {
+		incidents++
+	}
+	return math.Max(reward-incidentPenalty*float64(incidents), 0)
+}
+
+// decayWeight is the weight of an outcome of the given age
+func decayWeight(age, halfLife time.Duration) float64 {
+	if halfLife <= 0 || age <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, float64(age)/float64(halfLife))
+}
+
+// buildArms aggregates outcomes into arms keyed by NodePool and strategy. Applied
+// outcomes still waiting for verification have no reward yet and are skipped.
+func buildArms(history []OptimizationOutcome, config BanditConfig, now time.Time) map[string]map[OptimizationStrategy]*ArmStats {
+	arms := make(map[string]map[OptimizationStrategy]*ArmStats)
+	for _, outcome := range history {
+		if outcome.Strategy == "" || needsVerification(outcome) {
+			continue
+		}
+		at := outcome.AppliedAt
+		if outcome.VerifiedAt != nil {
+			at = *outcome.VerifiedAt
+		}
+		if at.IsZero() {
+			at = now
+		}
+
+		pool, ok := arms[outcome.NodePoolName]
+		if !ok {
+			pool = make(map[OptimizationStrategy]*ArmStats)
+			arms[outcome.NodePoolName] = pool
+		}
+		arm, ok := pool[outcome.Strategy]
+		if !ok {
+			arm = &ArmStats{NodePoolName: outcome.NodePoolName, Strategy: outcome.Strategy}
+			pool[outcome.Strategy] = arm
+		}
+
+		weight := decayWeight(now.Sub(at), config.HalfLife)
+		arm.Outcomes++
+		arm.Pulls += weight
+		arm.RewardSum += weight * outcomeReward(outcome, config.IncidentPenalty)
+		if at.After(arm.LastOutcomeAt) {
+			arm.LastOutcomeAt = at
+		}
+	}
+
+	for _, pool := range arms {
+		for _, arm := range pool {
+			arm.finish()
+		}
+	}
+	return arms
+}
+
+// finish derives the mean and posterior from the decayed sums
+func (a *ArmStats) finish() {
+	if a.Pulls > 0 {
+		a.MeanReward = a.RewardSum / a.Pulls
+	}
+	a.Alpha = 1 + a.RewardSum
+	a.Beta = 1 + a.Pulls - a.RewardSum
+	a.ExpectedReward = a.Alpha / (a.Alpha + a.Beta)
+}
+
+// sampleBeta draws from Beta(alpha, beta) as the ratio of two gamma draws
+func sampleBeta(rng *rand.Rand, alpha, beta float64) float64 {
+	x := sampleGamma(rng, alpha)
+	y := sampleGamma(rng, beta)
+	if x+y == 0 {
+		return 0.5
+	}
+	return x / (x + y)
+}
+
+// sampleGamma draws from Gamma(shape, 1) (Marsaglia and Tsang)
+func sampleGamma(rng *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		return sampleGamma(rng, shape+1) * math.Pow(rng.Float64(), 1/shape)
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rng.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
+
+// sortedArms flattens arms, ordered by NodePool and strategy
+func sortedArms(arms map[string]map[OptimizationStrategy]*ArmStats) []ArmStats {
+	list := make([]ArmStats, 0)
+	for _, pool := range arms {
+		for _, arm := range pool {
+			list = append(list, *arm)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].NodePoolName != list[j].NodePoolName {
+			return list[i].NodePoolName < list[j].NodePoolName
+		}
+		return list[i].Strategy < list[j].Strategy
+	})
+	return list
+}
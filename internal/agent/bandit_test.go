@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutcomeReward(t *testing.T) {
+	tests := []struct {
+		name    string
+		outcome OptimizationOutcome
+		want    float64
+	}{
+		{name: "savings as predicted", outcome: OptimizationOutcome{PredictedSavings: 2, ActualSavings: 2}, want: 1},
+		{name: "more than predicted is capped", outcome: OptimizationOutcome{PredictedSavings: 2, ActualSavings: 3}, want: 1},
+		{name: "half the predicted savings", outcome: OptimizationOutcome{PredictedSavings: 2, ActualSavings: 1}, want: 0.5},
+		{name: "cost went up", outcome: OptimizationOutcome{PredictedSavings: 2, ActualSavings: -1}, want: 0},
+		{name: "incidents are penalized", outcome: OptimizationOutcome{PredictedSavings: 2, ActualSavings: 2, Incidents: []string{"pending pods"}, PerformanceImpact: "negative"}, want: 0.5},
+		{name: "penalties stop at zero", outcome: OptimizationOutcome{PredictedSavings: 2, ActualSavings: 1, Incidents: []string{"a", "b", "c"}}, want: 0},
+		{name: "rolled back", outcome: OptimizationOutcome{PredictedSavings: 2, ActualSavings: 2, UserFeedback: "rejected"}, want: 0},
+		{name: "savings without a prediction", outcome: OptimizationOutcome{ActualSavings: 0.1}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, outcomeReward(tt.outcome, 0.25), 1e-9)
+		})
+	}
+}
+
+func TestBuildArms(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	monthAgo := now.Add(-30 * 24 * time.Hour)
+	history := []OptimizationOutcome{
+		{NodePoolName: "default", Strategy: StrategyBalanced, AppliedAt: now, ActualCost: 1, PredictedSavings: 1, ActualSavings: 1},
+		{NodePoolName: "default", Strategy: StrategyBalanced, AppliedAt: monthAgo, ActualCost: 1, PredictedSavings: 1, ActualSavings: 0},
+		{NodePoolName: "default", Strategy: StrategySpotFirst, AppliedAt: now, UserFeedback: "rejected"},
+		// Waiting for verification: no reward yet
+		{NodePoolName: "default", Strategy: StrategyAggressive, AppliedAt: now, PredictedSavings: 1},
+	}
+
+	arms := buildArms(history, BanditConfig{HalfLife: 30 * 24 * time.Hour, IncidentPenalty: 0.25}, now)
+	require.Len(t, arms["default"], 2)
+
+	balanced := arms["default"][StrategyBalanced]
+	assert.Equal(t, 2, balanced.Outcomes)
+	assert.InDelta(t, 1.5, balanced.Pulls, 1e-9, "the month-old outcome counts half")
+	assert.InDelta(t, 1.0, balanced.RewardSum, 1e-9)
+	assert.InDelta(t, 2.0/3, balanced.MeanReward, 1e-9)
+	assert.InDelta(t, 2.0, balanced.Alpha, 1e-9)
+	assert.InDelta(t, 1.5, balanced.Beta, 1e-9)
+	assert.Equal(t, now, balanced.LastOutcomeAt)
+
+	spot := arms["default"][StrategySpotFirst]
+	assert.InDelta(t, 1.0, spot.Alpha, 1e-9)
+	assert.InDelta(t, 2.0, spot.Beta, 1e-9)
+
+	// Without decay every outcome counts fully
+	arms = buildArms(history, BanditConfig{}, now)
+	assert.InDelta(t, 2.0, arms["default"][StrategyBalanced].Pulls, 1e-9)
+}
+
+func TestSelectStrategy(t *testing.T) {
+	learning, err := NewLearningAgent(filepath.Join(t.TempDir(), "history.json"))
+	require.NoError(t, err)
+	learning.rng = rand.New(rand.NewPCG(1, 2))
+	candidates := []OptimizationStrategy{StrategyBalanced, StrategySpotFirst, StrategyConservative}
+
+	_, ok := learning.SelectStrategy("default", candidates)
+	assert.False(t, ok, "no outcomes for the NodePool yet")
+	strategy, reward := learning.GetBestStrategyForNodePool("default")
+	assert.Equal(t, StrategyBalanced, strategy)
+	assert.Equal(t, 0.5, reward)
+
+	ctx := context.Background()
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		good := OptimizationOutcome{PlanID: fmt.Sprintf("good-%d", i), NodePoolName: "default", Strategy: StrategySpotFirst, AppliedAt: now, ActualCost: 1, PredictedSavings: 1, ActualSavings: 1}
+		bad := OptimizationOutcome{PlanID: fmt.Sprintf("bad-%d", i), NodePoolName: "default", Strategy: StrategyBalanced, AppliedAt: now, ActualCost: 1, PredictedSavings: 1, ActualSavings: 0.1}
+		require.NoError(t, learning.RecordOutcome(ctx, good))
+		require.NoError(t, learning.RecordOutcome(ctx, bad))
+	}
+
+	strategy, reward = learning.GetBestStrategyForNodePool("default")
+	assert.Equal(t, StrategySpotFirst, strategy)
+	assert.Greater(t, reward, 0.8)
+
+	picks := make(map[OptimizationStrategy]int)
+	for i := 0; i < 500; i++ {
+		strategy, ok := learning.SelectStrategy("default", candidates)
+		require.True(t, ok)
+		picks[strategy]++
+	}
+	assert.Greater(t, picks[StrategySpotFirst], 300, "the proven strategy is chosen most often")
+	assert.Greater(t, picks[StrategyConservative], 0, "an untried strategy is still explored")
+	assert.Less(t, picks[StrategyBalanced], picks[StrategyConservative], "a poor strategy is tried less than an untried one")
+
+	arms := learning.GetArmStats()
+	require.Len(t, arms, 2)
+	assert.Equal(t, StrategyBalanced, arms[0].Strategy)
+	assert.Equal(t, 10, arms[1].Outcomes)
+}
+
+func TestCostAgentSelectStrategy(t *testing.T) {
+	learning, err := NewLearningAgent(filepath.Join(t.TempDir(), "history.json"))
+	require.NoError(t, err)
+	costAgent := NewCostOptimizationAgentWithLearning(nil, nil, StrategyBalanced, learning)
+
+	strategy, learned := costAgent.selectStrategy("default", "")
+	assert.Equal(t, StrategyBalanced, strategy, "no outcomes: the default strategy")
+	assert.False(t, learned)
+
+	ctx := context.Background()
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		outcome := OptimizationOutcome{PlanID: fmt.Sprintf("spot-%d", i), NodePoolName: "default", Strategy: StrategySpotFirst, AppliedAt: now, ActualCost: 1, PredictedSavings: 1, ActualSavings: 1}
+		require.NoError(t, learning.RecordOutcome(ctx, outcome))
+	}
+
+	_, learned = costAgent.selectStrategy("default", "")
+	assert.True(t, learned, "the bandit picks once the NodePool has outcomes")
+
+	// A requested strategy, built-in or custom, is never overridden
+	for _, requested := range []OptimizationStrategy{StrategyConservative, "stable-on-demand"} {
+		strategy, learned = costAgent.selectStrategy("default", requested)
+		assert.Equal(t, requested, strategy)
+		assert.False(t, learned)
+	}
+}
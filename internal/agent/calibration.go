@@ -0,0 +1,246 @@
+package agent
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// calibrationBuckets splits predicted confidence into equal-width buckets
+	calibrationBuckets = 10
+	// minCalibrationOutcomes is how many resolved outcomes with a predicted
+	// confidence are needed before confidences are recalibrated
+	minCalibrationOutcomes = 10
+	// calibrationPriorWeight is how many outcomes the identity mapping counts as;
+	// the fitted curve gets weight n/(n+calibrationPriorWeight)
+	calibrationPriorWeight = 20
+)
+
+// CalibrationBucket compares the confidence predicted for plans in a range with
+// how often those plans succeeded
+type CalibrationBucket struct {
+	Lower                float64 `json:"lower"`
+	Upper                float64 `json:"upper"`
+	Count                int     `json:"count"`
+	MeanConfidence       float64 `json:"meanConfidence"`
+	SuccessRate          float64 `json:"successRate"`
+	CalibratedConfidence float64 `json:"calibratedConfidence"` // Recalibrated MeanConfidence
+}
+
+// CalibrationPoint maps a predicted confidence to a calibrated one
+type CalibrationPoint struct {
+	Confidence float64 `json:"confidence"`
+	Calibrated float64 `json:"calibrated"`
+}
+
+// Recalibration is the monotonic mapping applied to new plan confidences. It is an
+// isotonic fit of success rate on predicted confidence, blended with the identity
+// mapping by Weight so a few outcomes only nudge confidences.
+type Recalibration struct {
+	Applied bool               `json:"applied"` // False until enough outcomes are resolved
+	Samples int                `json:"samples"`
+	Weight  float64            `json:"weight"`
+	Points  []CalibrationPoint `json:"points,omitempty"`
+}
+
+// SavingsError summarizes how far predicted savings were from realized savings
+// (dollars per hour) for a group of outcomes
+type SavingsError struct {
+	Key                  string  `json:"key"`
+	Count                int     `json:"count"`
+	MeanAbsoluteError    float64 `json:"meanAbsoluteError"`
+	MeanBias             float64 `json:"meanBias"` // Actual minus predicted; negative means overestimated savings
+	MeanPredictedSavings float64 `json:"meanPredictedSavings"`
+	MeanActualSavings    float64 `json:"meanActualSavings"`
+}
+
+// CalibrationReport checks predicted confidences and savings against outcomes
+type CalibrationReport struct {
+	Outcomes                 int                 `json:"outcomes"`            // Resolved outcomes
+	CalibrationOutcomes      int                 `json:"calibrationOutcomes"` // Resolved outcomes with a predicted confidence
+	Buckets                  []CalibrationBucket `json:"buckets"`
+	BrierScore               float64             `json:"brierScore"`               // Mean squared error of confidence vs success
+	CalibratedBrierScore     float64             `json:"calibratedBrierScore"`     // The same after recalibration
+	ExpectedCalibrationError float64             `json:"expectedCalibrationError"` // Count-weighted |confidence - success rate| over buckets
+	Recalibration            Recalibration       `json:"recalibration"`
+	SavingsError             SavingsError        `json:"savingsError"`
+	SavingsErrorByStrategy   []SavingsError      `json:"savingsErrorByStrategy"`
+	SavingsErrorByNodePool   []SavingsError      `json:"savingsErrorByNodePool"`
+	GeneratedAt              time.Time           `json:"generatedAt"`
+}
+
+// Calibrate maps a predicted confidence through the recalibration curve,
+// interpolating linearly between points
+func (r Recalibration) Calibrate(confidence float64) float64 {
+	confidence = math.Min(math.Max(confidence, 0), 1)
+	if !r.Applied || len(r.Points) == 0 {
+		return confidence
+	}
+	fitted := r.Points[0].Calibrated
+	if confidence >= r.Points[len(r.Points)-1].Confidence {
+		fitted = r.Points[len(r.Points)-1].Calibrated
+	} else if confidence > r.Points[0].Confidence {
+		i := sort.Search(len(r.Points), func(i int) bool { return r.Points[i].Confidence >= confidence })
+		lo, hi := r.Points[i-1], r.Points[i]
+		t := (confidence - lo.Confidence) / (hi.Confidence - lo.Confidence)
+		fitted = lo.Calibrated + t*(hi.Calibrated-lo.Calibrated)
+	}
+	return r.Weight*fitted + (1-r.Weight)*confidence
+}
+
+// buildCalibrationReport computes calibration and savings error from history.
+// Applied outcomes waiting for verification are left out; calibration also needs
+// the confidence the plan was proposed with, and savings error measured savings.
+func buildCalibrationReport(history []OptimizationOutcome, now time.Time) *CalibrationReport {
+	report := &CalibrationReport{GeneratedAt: now}
+
+	var resolved, predicted []OptimizationOutcome
+	for _, outcome := range history {
+		if needsVerification(outcome) {
+			continue
+		}
+		resolved = append(resolved, outcome)
+		if outcome.PredictedConfidence > 0 {
+			predicted = append(predicted, outcome)
+		}
+	}
+	report.Outcomes = len(resolved)
+	report.CalibrationOutcomes = len(predicted)
+
+	// Calibration buckets
+	buckets := make([]CalibrationBucket, calibrationBuckets)
+	successes := make([]float64, calibrationBuckets)
+	for i := range buckets {
+		buckets[i].Lower = float64(i) / calibrationBuckets
+		buckets[i].Upper = float64(i+1) / calibrationBuckets
+	}
+	for _, outcome := range predicted {
+		confidence := math.Min(math.Max(outcome.PredictedConfidence, 0), 1)
+		i := int(confidence * calibrationBuckets)
+		if i == calibrationBuckets {
+			i--
+		}
+		buckets[i].Count++
+		buckets[i].MeanConfidence += confidence
+		successes[i] += boolToFloat(outcome.Success)
+	}
+	for i := range buckets {
+		if buckets[i].Count > 0 {
+			buckets[i].MeanConfidence /= float64(buckets[i].Count)
+			buckets[i].SuccessRate = successes[i] / float64(buckets[i].Count)
+		}
+	}
+
+	report.Recalibration = fitRecalibration(buckets, len(predicted))
+	for i := range buckets {
+		if buckets[i].Count > 0 {
+			buckets[i].CalibratedConfidence = report.Recalibration.Calibrate(buckets[i].MeanConfidence)
+			report.ExpectedCalibrationError += float64(buckets[i].Count) * math.Abs(buckets[i].MeanConfidence-buckets[i].SuccessRate)
+		}
+	}
+	report.Buckets = buckets
+
+	for _, outcome := range predicted {
+		actual := boolToFloat(outcome.Success)
+		report.BrierScore += math.Pow(outcome.PredictedConfidence-actual, 2)
+		report.CalibratedBrierScore += math.Pow(report.Recalibration.Calibrate(outcome.PredictedConfidence)-actual, 2)
+	}
+	if len(predicted) > 0 {
+		n := float64(len(predicted))
+		report.BrierScore /= n
+		report.CalibratedBrierScore /= n
+		report.ExpectedCalibrationError /= n
+	}
+	// Savings error overall, per strategy and per NodePool
+	var measured []OptimizationOutcome
+	byStrategy := make(map[string][]OptimizationOutcome)
+	byNodePool := make(map[string][]OptimizationOutcome)
+	for _, outcome := range resolved {
+		if !savingsMeasured(outcome) {
+			continue
+		}
+		measured = append(measured, outcome)
+		byStrategy[string(outcome.Strategy)] = append(byStrategy[string(outcome.Strategy)], outcome)
+		byNodePool[outcome.NodePoolName] = append(byNodePool[outcome.NodePoolName], outcome)
+	}
+	report.SavingsError = savingsError("all", measured)
+	report.SavingsErrorByStrategy = groupSavingsErrors(byStrategy)
+	report.SavingsErrorByNodePool = groupSavingsErrors(byNodePool)
+
+	return report
+}
+
+// fitRecalibration fits an isotonic (non-decreasing) curve of success rate on
+// mean predicted confidence with the pool-adjacent-violators algorithm
+func fitRecalibration(buckets []CalibrationBucket, samples int) Recalibration {
+	recalibration := Recalibration{Samples: samples}
+	if samples < minCalibrationOutcomes {
+		return recalibration
+	}
+
+	type block struct {
+		confidence, rate, weight float64
+	}
+	var blocks []block
+	for _, b := range buckets {
+		if b.Count == 0 {
+			continue
+		}
+		blocks = append(blocks, block{confidence: b.MeanConfidence, rate: b.SuccessRate, weight: float64(b.Count)})
+		// Merge backwards while the rates decrease
+		for len(blocks) > 1 && blocks[len(blocks)-2].rate > blocks[len(blocks)-1].rate {
+			last, prev := blocks[len(blocks)-1], blocks[len(blocks)-2]
+			weight := prev.weight + last.weight
+			blocks = append(blocks[:len(blocks)-2], block{
+				confidence: (prev.confidence*prev.weight + last.confidence*last.weight) / weight,
+				rate:       (prev.rate*prev.weight + last.rate*last.weight) / weight,
+				weight:     weight,
+			})
+		}
+	}
+
+	for _, b := range blocks {
+		recalibration.Points = append(recalibration.Points, CalibrationPoint{Confidence: b.confidence, Calibrated: b.rate})
+	}
+	recalibration.Weight = float64(samples) / float64(samples+calibrationPriorWeight)
+	recalibration.Applied = true
+	return recalibration
+}
+
+// savingsMeasured reports whether an outcome's actual savings were measured. Rejected
+// plans and outcomes recorded without actual results never realized savings, so their
+// zero ActualSavings says nothing about the prediction.
+func savingsMeasured(outcome OptimizationOutcome) bool {
+	return outcome.UserFeedback != "rejected" &&
+		(outcome.VerifiedAt != nil || outcome.ActualCost > 0 || outcome.ActualNodes > 0)
+}
+
+func savingsError(key string, outcomes []OptimizationOutcome) SavingsError {
+	e := SavingsError{Key: key, Count: len(outcomes)}
+	if len(outcomes) == 0 {
+		return e
+	}
+	for _, outcome := range outcomes {
+		e.MeanAbsoluteError += math.Abs(outcome.ActualSavings - outcome.PredictedSavings)
+		e.MeanBias += outcome.ActualSavings - outcome.PredictedSavings
+		e.MeanPredictedSavings += outcome.PredictedSavings
+		e.MeanActualSavings += outcome.ActualSavings
+	}
+	n := float64(len(outcomes))
+	e.MeanAbsoluteError /= n
+	e.MeanBias /= n
+	e.MeanPredictedSavings /= n
+	e.MeanActualSavings /= n
+	return e
+}
+
+// groupSavingsErrors returns the savings error of each group, ordered by key
+func groupSavingsErrors(groups map[string][]OptimizationOutcome) []SavingsError {
+	result := make([]SavingsError, 0, len(groups))
+	for key, outcomes := range groups {
+		result = append(result, savingsError(key, outcomes))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
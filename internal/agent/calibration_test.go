@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// calibrationHistory returns verified outcomes: plans at 0.9 confidence succeed half
+// of the time, plans at 0.3 confidence succeed 3 times out of 4
+func calibrationHistory() []OptimizationOutcome {
+	var history []OptimizationOutcome
+	for i := 0; i < 20; i++ {
+		outcome := OptimizationOutcome{NodePoolName: "default", Strategy: StrategySpotFirst, PredictedConfidence: 0.9, PredictedSavings: 2, ActualCost: 1}
+		if i%2 == 0 {
+			outcome.Success = true
+			outcome.ActualSavings = 2
+		} else {
+			outcome.ActualSavings = 0.5
+		}
+		history = append(history, outcome)
+	}
+	for i := 0; i < 8; i++ {
+		outcome := OptimizationOutcome{NodePoolName: "batch", Strategy: StrategyConservative, PredictedConfidence: 0.3, PredictedSavings: 1, ActualSavings: 1, ActualCost: 1, Success: i%4 != 0}
+		history = append(history, outcome)
+	}
+	// Waiting for verification: not counted
+	history = append(history, OptimizationOutcome{NodePoolName: "default", Strategy: StrategySpotFirst, PredictedConfidence: 0.9, PredictedSavings: 2, AppliedAt: time.Now()})
+	return history
+}
+
+func TestBuildCalibrationReport(t *testing.T) {
+	report := buildCalibrationReport(calibrationHistory(), time.Now())
+	assert.Equal(t, 28, report.Outcomes)
+	assert.Equal(t, 28, report.CalibrationOutcomes)
+	require.Len(t, report.Buckets, calibrationBuckets)
+
+	high := report.Buckets[9]
+	assert.Equal(t, 20, high.Count)
+	assert.InDelta(t, 0.9, high.MeanConfidence, 1e-9)
+	assert.InDelta(t, 0.5, high.SuccessRate, 1e-9)
+	low := report.Buckets[3]
+	assert.Equal(t, 8, low.Count)
+	assert.InDelta(t, 0.75, low.SuccessRate, 1e-9)
+
+	// The success rates decrease with confidence, so isotonic fitting pools them
+	recalibration := report.Recalibration
+	require.True(t, recalibration.Applied)
+	require.Len(t, recalibration.Points, 1)
+	pooled := (20*0.5 + 8*0.75) / 28
+	assert.InDelta(t, pooled, recalibration.Points[0].Calibrated, 1e-9)
+	assert.InDelta(t, 28.0/48, recalibration.Weight, 1e-9)
+
+	// Overconfident plans are pulled down, underconfident ones up
+	assert.Less(t, high.CalibratedConfidence, 0.9)
+	assert.Greater(t, low.CalibratedConfidence, 0.3)
+	assert.Less(t, report.CalibratedBrierScore, report.BrierScore)
+	assert.InDelta(t, (20*0.4+8*0.45)/28, report.ExpectedCalibrationError, 1e-9)
+
+	// Savings error
+	assert.Equal(t, 28, report.SavingsError.Count)
+	require.Len(t, report.SavingsErrorByStrategy, 2)
+	conservative, spot := report.SavingsErrorByStrategy[0], report.SavingsErrorByStrategy[1]
+	assert.Equal(t, string(StrategyConservative), conservative.Key)
+	assert.Equal(t, 0.0, conservative.MeanAbsoluteError)
+	assert.Equal(t, string(StrategySpotFirst), spot.Key)
+	assert.InDelta(t, 0.75, spot.MeanAbsoluteError, 1e-9)
+	assert.InDelta(t, -0.75, spot.MeanBias, 1e-9)
+	require.Len(t, report.SavingsErrorByNodePool, 2)
+	assert.Equal(t, "batch", report.SavingsErrorByNodePool[0].Key)
+
+	t.Run("unmeasured savings", func(t *testing.T) {
+		// Rejected and never measured plans still count for calibration, but their zero
+		// actual savings are not a savings error
+		history := append(calibrationHistory(),
+			OptimizationOutcome{NodePoolName: "default", Strategy: StrategySpotFirst, PredictedConfidence: 0.9, PredictedSavings: 2, UserFeedback: "rejected"},
+			OptimizationOutcome{NodePoolName: "web", Strategy: StrategyAggressive, PredictedConfidence: 0.9, PredictedSavings: 3},
+		)
+		withUnmeasured := buildCalibrationReport(history, time.Now())
+		assert.Equal(t, 30, withUnmeasured.Outcomes)
+		assert.Equal(t, 30, withUnmeasured.CalibrationOutcomes)
+		assert.Equal(t, report.SavingsError, withUnmeasured.SavingsError)
+		assert.Equal(t, report.SavingsErrorByStrategy, withUnmeasured.SavingsErrorByStrategy)
+		assert.Equal(t, report.SavingsErrorByNodePool, withUnmeasured.SavingsErrorByNodePool)
+	})
+}
+
+func TestRecalibrationCalibrate(t *testing.T) {
+	// Too few outcomes: confidences are kept
+	report := buildCalibrationReport(calibrationHistory()[:5], time.Now())
+	assert.False(t, report.Recalibration.Applied)
+	assert.Equal(t, 0.9, report.Recalibration.Calibrate(0.9))
+
+	recalibration := Recalibration{Applied: true, Weight: 1, Points: []CalibrationPoint{
+		{Confidence: 0.2, Calibrated: 0.1},
+		{Confidence: 0.6, Calibrated: 0.5},
+		{Confidence: 0.8, Calibrated: 0.9},
+	}}
+	assert.InDelta(t, 0.1, recalibration.Calibrate(0.05), 1e-9, "flat below the first point")
+	assert.InDelta(t, 0.3, recalibration.Calibrate(0.4), 1e-9)
+	assert.InDelta(t, 0.7, recalibration.Calibrate(0.7), 1e-9)
+	assert.InDelta(t, 0.9, recalibration.Calibrate(1.2), 1e-9, "flat above the last point")
+
+	recalibration.Weight = 0.5
+	assert.InDelta(t, 0.35, recalibration.Calibrate(0.4), 1e-9, "blended with the identity")
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+)
+
+// CapacitySimulation sizes an instance mix for a NodePool's current usage
+type CapacitySimulation struct {
+	NodePoolName      string   `json:"nodePoolName"`
+	InstanceTypes     []string `json:"instanceTypes"`
+	CapacityType      string   `json:"capacityType"`
+	HeadroomPercent   float64  `json:"headroomPercent"`
+	CPUUsed           float64  `json:"cpuUsed"`    // Cores in use across the NodePool's nodes
+	MemoryUsed        float64  `json:"memoryUsed"` // GiB in use across the NodePool's nodes
+	Nodes             int      `json:"nodes"`      // Fewest nodes of the mix that fit usage plus headroom
+	TotalCPU          float64  `json:"totalCpu"`
+	TotalMemory       float64  `json:"totalMemory"`
+	HourlyCost        float64  `json:"hourlyCost"`
+	CurrentNodes      int      `json:"currentNodes"`
+	CurrentHourlyCost float64  `json:"currentHourlyCost"`
+	HourlySavings     float64  `json:"hourlySavings"` // Negative when the mix costs more
+}
+
+// SimulateCapacity sizes instanceTypes (spread evenly) for the NodePool's current
+// CPU and memory usage plus headroom, and prices it against the current nodes
+func SimulateCapacity(ctx context.Context, pricer CapacityPricer, np kubernetes.NodePoolInfo, instanceTypes []string, capacityType string, headroomPercent float64) (*CapacitySimulation, error) {
+	if len(instanceTypes) == 0 {
+		return nil, fmt.Errorf("at least one instance type is required")
+	}
+	if capacityType != "spot" && capacityType != "on-demand" {
+		return nil, fmt.Errorf("capacity type must be spot or on-demand, got %q", capacityType)
+	}
+	if headroomPercent < 0 {
+		return nil, fmt.Errorf("headroom must not be negative")
+	}
+	for _, instanceType := range instanceTypes {
+		if cpu, memory := pricer.EstimateInstanceCapacity(instanceType); cpu <= 0 || memory <= 0 {
+			return nil, fmt.Errorf("unknown capacity for instance type %s", instanceType)
+		}
+	}
+
+	sim := &CapacitySimulation{
+		NodePoolName:    np.Name,
+		InstanceTypes:   instanceTypes,
+		CapacityType:    capacityType,
+		HeadroomPercent: headroomPercent,
+		CurrentNodes:    len(np.ActualNodes),
+	}
+	for _, node := range np.ActualNodes {
+		if node.CPUUsage != nil {
+			sim.CPUUsed += node.CPUUsage.Used
+		}
+		if node.MemoryUsage != nil {
+			sim.MemoryUsed += node.MemoryUsage.Used
+		}
+		if node.InstanceType != "" {
+			sim.CurrentHourlyCost += pricer.EstimateCost(ctx, []string{node.InstanceType}, node.CapacityType, 1)
+		}
+	}
+
+	sim.Nodes, sim.TotalCPU, sim.TotalMemory = sizeMix(pricer, instanceTypes, sim.CPUUsed, sim.MemoryUsed, headroomPercent)
+	if sim.Nodes == 0 {
+		return nil, fmt.Errorf("usage does not fit in %d nodes of %v", maxCandidateNodes, instanceTypes)
+	}
+	sim.HourlyCost = pricer.EstimateCost(ctx, instanceTypes, capacityType, sim.Nodes)
+	sim.HourlySavings = sim.CurrentHourlyCost - sim.HourlyCost
+	return sim, nil
+}
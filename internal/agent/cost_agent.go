@@ -28,21 +28,31 @@ func NewCostOptimizationAgent(
 	k8sClient *kubernetes.Client,
 	strategy OptimizationStrategy,
 ) *CostOptimizationAgent {
-	if strategy == "" {
-		strategy = StrategyBalanced // Default
-	}
-	
-	llmEnhancer := NewLLMEnhancer(rec)
-	
 	// Initialize learning agent (history stored in /tmp/karpenter-optimizer-history.json)
-	// In production, this could be a database or persistent storage
-	historyFile := "/tmp/karpenter-optimizer-history.json"
-	learningAgent, err := NewLearningAgent(historyFile)
+	learningAgent, err := NewLearningAgent(DefaultHistoryFile)
 	if err != nil {
 		fmt.Printf("Warning: Failed to initialize learning agent: %v\n", err)
 		learningAgent = nil
 	}
 	
+	return NewCostOptimizationAgentWithLearning(rec, k8sClient, strategy, learningAgent)
+}
+
+// NewCostOptimizationAgentWithLearning creates a cost optimization agent that learns
+// through the given learning agent (nil disables learning). The agent is safe to
+// share between requests; use GenerateRecommendationsWithStrategy for per-request strategies.
+func NewCostOptimizationAgentWithLearning(
+	rec *recommender.Recommender,
+	k8sClient *kubernetes.Client,
+	strategy OptimizationStrategy,
+	learningAgent *LearningAgent,
+) *CostOptimizationAgent {
+	if strategy == "" {
+		strategy = StrategyBalanced // Default
+	}
+	
+	llmEnhancer := NewLLMEnhancer(rec)
+	
 	// Check if LLM is actually available
 	useLLM := llmEnhancer.HasLLM()
 	if useLLM {
@@ -60,12 +70,25 @@ func NewCostOptimizationAgent(
 		llmEnhancer:   llmEnhancer,
 		learningAgent: learningAgent,
 		useLLM:        useLLM,  // Only enable if LLM is actually available
-		useLearning:   true,    // Enable learning by default
+		useLearning:   learningAgent != nil, // Enable learning when available
 	}
 }
 
-// GenerateRecommendations generates cost-optimized recommendations using the agent
+// GenerateRecommendations generates cost-optimized recommendations using the agent,
+// letting learning choose each NodePool's strategy
 func (a *CostOptimizationAgent) GenerateRecommendations(ctx context.Context) ([]*OptimizationPlan, error) {
+	return a.GenerateRecommendationsWithStrategy(ctx, "")
+}
+
+// GenerateRecommendationsWithStrategy generates recommendations with the given strategy
+// without changing the agent's default strategy. An empty strategy uses the default,
+// and the strategy bandit picks the strategy of NodePools with outcomes; a requested
+// strategy is always used as is.
+func (a *CostOptimizationAgent) GenerateRecommendationsWithStrategy(ctx context.Context, requested OptimizationStrategy) ([]*OptimizationPlan, error) {
+	if a.useLearning && a.learningAgent != nil {
+		a.learningAgent.RefreshIfStale(ctx)
+	}
+	
 	// Get all NodePools
 	nodePools, err := a.k8sClient.ListNodePools(ctx)
 	if err != nil {
@@ -87,14 +110,10 @@ func (a *CostOptimizationAgent) GenerateRecommendations(ctx context.Context) ([]
 			continue
 		}
 		
-		// Apply learning: adjust strategy if we have learned patterns
-		strategy := a.strategy
-		if a.useLearning && a.learningAgent != nil {
-			learnedStrategy, successRate := a.learningAgent.GetBestStrategyForNodePool(np.Name)
-			if successRate > 0.7 && learnedStrategy != "" {
-				strategy = learnedStrategy
-				analysis.Confidence = a.learningAgent.AdjustConfidence(analysis.Confidence, np.Name, strategy)
-			}
+		// Apply learning
+		strategy, banditSelected := a.selectStrategy(np.Name, requested)
+		if banditSelected {
+			analysis.Confidence = a.learningAgent.AdjustConfidence(analysis.Confidence, np.Name, strategy)
 		}
 		
 		// Plan optimization
@@ -107,6 +126,10 @@ func (a *CostOptimizationAgent) GenerateRecommendations(ctx context.Context) ([]
 		// Apply learning insights
 		if a.useLearning && a.learningAgent != nil {
 			plan.LearnedFromHistory = true
+			if banditSelected && strategy != a.strategy {
+				plan.LearningInsights = append(plan.LearningInsights,
+					fmt.Sprintf("Strategy '%s' selected from past outcomes for this NodePool instead of '%s'", strategy, a.strategy))
+			}
 			
 			// Get optimal configuration if learned
 			optimalConfig := a.learningAgent.GetOptimalConfiguration(np.Name)
@@ -116,8 +139,9 @@ func (a *CostOptimizationAgent) GenerateRecommendations(ctx context.Context) ([]
 						optimalConfig.NodeCount, optimalConfig.InstanceTypes, optimalConfig.CapacityType))
 			}
 			
-			// Adjust confidence based on learning
+			// Adjust confidence based on learning, then recalibrate it against outcomes
 			plan.Confidence = a.learningAgent.AdjustConfidence(plan.Confidence, np.Name, strategy)
+			a.calibrate(plan)
 			
 			// Add strategy success rate insight
 			strategyRate := a.learningAgent.GetStrategySuccessRate(strategy)
@@ -160,6 +184,44 @@ func (a *CostOptimizationAgent) GenerateRecommendations(ctx context.Context) ([]
 	return plans, nil
 }
 
+// GenerateParetoPlans generates a Pareto set of candidate plans for each NodePool with
+// nodes (or only the named NodePool), trading off cost, interruption risk, bin-packing
+// efficiency and disruptions, with a recommended pick per NodePool
+func (a *CostOptimizationAgent) GenerateParetoPlans(ctx context.Context, nodePoolName string, weights ParetoWeights) ([]*ParetoPlanSet, error) {
+	nodePools, err := a.k8sClient.ListNodePools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NodePools: %w", err)
+	}
+	
+	sets := make([]*ParetoPlanSet, 0)
+	for _, np := range nodePools {
+		if len(np.ActualNodes) == 0 || (nodePoolName != "" && np.Name != nodePoolName) {
+			continue
+		}
+		analysis, err := a.analyzer.AnalyzeNodePool(ctx, np)
+		if err != nil {
+			fmt.Printf("Warning: Failed to analyze NodePool %s: %v\n", np.Name, err)
+			continue
+		}
+		if a.useLearning && a.learningAgent != nil {
+			analysis.Confidence = a.learningAgent.AdjustConfidence(analysis.Confidence, np.Name, StrategyPareto)
+		}
+		set, err := a.planner.PlanParetoSet(ctx, analysis, np, weights)
+		if err != nil {
+			fmt.Printf("Warning: Failed to plan Pareto set for %s: %v\n", np.Name, err)
+			continue
+		}
+		if a.useLearning && a.learningAgent != nil {
+			for _, plan := range set.Plans {
+				a.calibrate(plan)
+			}
+		}
+		sets = append(sets, set)
+	}
+	
+	return sets, nil
+}
+
 // GenerateRecommendationsForNodePool generates recommendations for a specific NodePool
 func (a *CostOptimizationAgent) GenerateRecommendationsForNodePool(ctx context.Context, nodePoolName string) (*OptimizationPlan, error) {
 	// Get NodePool
@@ -217,6 +279,65 @@ func (a *CostOptimizationAgent) GetStrategy() OptimizationStrategy {
 	return a.strategy
 }
 
+// RegisterStrategies adds user-defined strategies alongside the built-in ones
+func (a *CostOptimizationAgent) RegisterStrategies(definitions []StrategyDefinition) error {
+	for _, def := range definitions {
+		if err := a.planner.RegisterStrategy(NewCustomStrategy(a.recommender, def)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HasStrategy reports whether a built-in or user-defined strategy exists
+func (a *CostOptimizationAgent) HasStrategy(strategy OptimizationStrategy) bool {
+	return a.planner.HasStrategy(strategy)
+}
+
+// Strategies returns the names of all selectable strategies
+func (a *CostOptimizationAgent) Strategies() []OptimizationStrategy {
+	return a.planner.Strategies()
+}
+
+// CustomStrategies returns the definitions of the user-defined strategies
+func (a *CostOptimizationAgent) CustomStrategies() []StrategyDefinition {
+	return a.planner.CustomStrategies()
+}
+
+// calibrate recalibrates a plan's confidence against past outcomes, keeping the
+// planner's value in RawConfidence
+func (a *CostOptimizationAgent) calibrate(plan *OptimizationPlan) {
+	plan.RawConfidence = plan.Confidence
+	plan.Confidence = a.learningAgent.CalibrateConfidence(plan.Confidence)
+}
+
+// selectStrategy returns the strategy for a NodePool and whether the strategy bandit
+// chose it. A requested strategy is always used; otherwise, once the NodePool has
+// outcomes, the bandit picks one, trading off proven strategies against rarely tried ones.
+func (a *CostOptimizationAgent) selectStrategy(nodePoolName string, requested OptimizationStrategy) (OptimizationStrategy, bool) {
+	if requested != "" {
+		return requested, false
+	}
+	if a.useLearning && a.learningAgent != nil {
+		if strategy, ok := a.learningAgent.SelectStrategy(nodePoolName, a.strategiesFor(nodePoolName)); ok {
+			return strategy, true
+		}
+	}
+	return a.strategy, false
+}
+
+// strategiesFor returns the strategies the learning agent may choose for a NodePool:
+// the built-in ones and the custom ones that apply to it
+func (a *CostOptimizationAgent) strategiesFor(nodePoolName string) []OptimizationStrategy {
+	strategies := BuiltInStrategies()
+	for _, def := range a.planner.CustomStrategies() {
+		if def.AppliesTo(nodePoolName) {
+			strategies = append(strategies, OptimizationStrategy(def.Name))
+		}
+	}
+	return strategies
+}
+
 // GetLearningAgent returns the learning agent (for outcome tracking)
 func (a *CostOptimizationAgent) GetLearningAgent() *LearningAgent {
 	return a.learningAgent
@@ -0,0 +1,242 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/recommender"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// riskLevels orders plan risk levels
+var riskLevels = map[string]int{"low": 0, "medium": 1, "high": 2}
+
+// StrategyDefinition declares a user-defined optimization strategy. All criteria are optional;
+// a recommendation is kept only if it satisfies every criterion that is set.
+type StrategyDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Savings
+	MinSavingsPercent float64 `json:"minSavingsPercent,omitempty"`
+	MinSavingsPerHour float64 `json:"minSavingsPerHour,omitempty"` // Dollars per hour, like CostSavings
+	// Risk and confidence
+	MaxRiskLevel  string  `json:"maxRiskLevel,omitempty"` // low, medium or high
+	MinConfidence float64 `json:"minConfidence,omitempty"`
+	// Instance families (e.g. m6i, c7g); blocked families win over allowed ones
+	AllowedInstanceFamilies []string `json:"allowedInstanceFamilies,omitempty"`
+	BlockedInstanceFamilies []string `json:"blockedInstanceFamilies,omitempty"`
+	// Share of the recommended capacity on spot (0-1). Recommendations use a single
+	// capacity type, so this is 0 or 1; a minimum above 0 generates spot recommendations.
+	MinSpotRatio *float64 `json:"minSpotRatio,omitempty"`
+	MaxSpotRatio *float64 `json:"maxSpotRatio,omitempty"`
+	// Recommended CPU and memory must exceed current usage by this percentage
+	HeadroomPercent float64 `json:"headroomPercent,omitempty"`
+	// NodePool names or glob patterns (e.g. "batch-*") the strategy applies to; empty means all
+	NodePools []string `json:"nodePools,omitempty"`
+}
+
+// StrategyFile is the YAML document holding strategy definitions
+type StrategyFile struct {
+	Strategies []StrategyDefinition `json:"strategies"`
+}
+
+// LoadStrategyDefinitions reads and validates strategy definitions from a YAML file
+func LoadStrategyDefinitions(file string) ([]StrategyDefinition, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read strategy file %s: %w", file, err)
+	}
+	return ParseStrategyDefinitions(data)
+}
+
+// ParseStrategyDefinitions parses and validates strategy definitions
+func ParseStrategyDefinitions(data []byte) ([]StrategyDefinition, error) {
+	var file StrategyFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse strategies: %w", err)
+	}
+	seen := make(map[string]bool)
+	for i := range file.Strategies {
+		def := &file.Strategies[i]
+		def.Name = strings.TrimSpace(def.Name)
+		if err := def.validate(); err != nil {
+			return nil, err
+		}
+		if seen[def.Name] {
+			return nil, fmt.Errorf("strategy %q is defined twice", def.Name)
+		}
+		seen[def.Name] = true
+	}
+	return file.Strategies, nil
+}
+
+func (d *StrategyDefinition) validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("strategy name is required")
+	}
+	for _, builtIn := range BuiltInStrategies() {
+		if OptimizationStrategy(d.Name) == builtIn {
+			return fmt.Errorf("strategy %q conflicts with a built-in strategy", d.Name)
+		}
+	}
+	if _, ok := riskLevels[d.MaxRiskLevel]; d.MaxRiskLevel != "" && !ok {
+		return fmt.Errorf("strategy %q: maxRiskLevel must be low, medium or high", d.Name)
+	}
+	for _, ratio := range []*float64{d.MinSpotRatio, d.MaxSpotRatio} {
+		if ratio != nil && (*ratio < 0 || *ratio > 1) {
+			return fmt.Errorf("strategy %q: spot ratios must be between 0 and 1", d.Name)
+		}
+	}
+	if d.MinSpotRatio != nil && d.MaxSpotRatio != nil && *d.MinSpotRatio > *d.MaxSpotRatio {
+		return fmt.Errorf("strategy %q: minSpotRatio is above maxSpotRatio", d.Name)
+	}
+	for _, pattern := range d.NodePools {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("strategy %q: invalid NodePool pattern %q", d.Name, pattern)
+		}
+	}
+	return nil
+}
+
+// AppliesTo reports whether the strategy may be used for a NodePool
+func (d *StrategyDefinition) AppliesTo(nodePool string) bool {
+	if len(d.NodePools) == 0 {
+		return true
+	}
+	for _, pattern := range d.NodePools {
+		if ok, _ := path.Match(pattern, nodePool); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomStrategy filters recommender output by a StrategyDefinition
+type CustomStrategy struct {
+	BaseStrategy
+	definition StrategyDefinition
+}
+
+// NewCustomStrategy creates a strategy from a definition
+func NewCustomStrategy(rec *recommender.Recommender, def StrategyDefinition) *CustomStrategy {
+	return &CustomStrategy{
+		BaseStrategy: BaseStrategy{
+			recommender: rec,
+			name:        def.Name,
+		},
+		definition: def,
+	}
+}
+
+func (s *CustomStrategy) GetName() string {
+	return s.name
+}
+
+// Definition returns the strategy's definition
+func (s *CustomStrategy) Definition() StrategyDefinition {
+	return s.definition
+}
+
+func (s *CustomStrategy) GenerateRecommendations(ctx context.Context, analysis *AnalysisResult, np kubernetes.NodePoolInfo) ([]recommender.NodePoolCapacityRecommendation, error) {
+	if !s.definition.AppliesTo(np.Name) {
+		return []recommender.NodePoolCapacityRecommendation{}, nil
+	}
+	recommendations, err := s.recommender.GenerateRecommendationsWithHeadroom(ctx, []kubernetes.NodePoolInfo{s.definition.constrain(np)}, s.definition.HeadroomPercent)
+	if err != nil {
+		return nil, err
+	}
+	filtered := make([]recommender.NodePoolCapacityRecommendation, 0)
+	for _, rec := range recommendations {
+		if s.definition.Accepts(analysis, rec) {
+			filtered = append(filtered, rec)
+		}
+	}
+	return filtered, nil
+}
+
+// constrain adds the definition's instance families and spot ratio bounds to the NodePool's
+// requirements, so the recommender only considers instance types and capacity types it allows
+func (d *StrategyDefinition) constrain(np kubernetes.NodePoolInfo) kubernetes.NodePoolInfo {
+	requirements := append([]corev1.NodeSelectorRequirement{}, np.NodeRequirements...)
+	if len(d.AllowedInstanceFamilies) > 0 {
+		requirements = append(requirements, corev1.NodeSelectorRequirement{Key: "karpenter.k8s.aws/instance-family", Operator: corev1.NodeSelectorOpIn, Values: lowerAll(d.AllowedInstanceFamilies)})
+	}
+	if len(d.BlockedInstanceFamilies) > 0 {
+		requirements = append(requirements, corev1.NodeSelectorRequirement{Key: "karpenter.k8s.aws/instance-family", Operator: corev1.NodeSelectorOpNotIn, Values: lowerAll(d.BlockedInstanceFamilies)})
+	}
+	switch {
+	case d.MinSpotRatio != nil && *d.MinSpotRatio > 0:
+		requirements = append(requirements, corev1.NodeSelectorRequirement{Key: "karpenter.sh/capacity-type", Operator: corev1.NodeSelectorOpIn, Values: []string{"spot"}})
+	case d.MaxSpotRatio != nil && *d.MaxSpotRatio == 0:
+		requirements = append(requirements, corev1.NodeSelectorRequirement{Key: "karpenter.sh/capacity-type", Operator: corev1.NodeSelectorOpIn, Values: []string{"on-demand"}})
+	}
+	np.NodeRequirements = requirements
+	return np
+}
+
+// Accepts reports whether a recommendation satisfies the definition's criteria
+func (d *StrategyDefinition) Accepts(analysis *AnalysisResult, rec recommender.NodePoolCapacityRecommendation) bool {
+	if rec.CostSavingsPercent < d.MinSavingsPercent || rec.CostSavings < d.MinSavingsPerHour {
+		return false
+	}
+	if d.MinConfidence > 0 && analysis.Confidence < d.MinConfidence {
+		return false
+	}
+	if d.MaxRiskLevel != "" {
+		risk := calculateRiskLevel(analysis, []recommender.NodePoolCapacityRecommendation{rec})
+		if riskLevels[risk] > riskLevels[d.MaxRiskLevel] {
+			return false
+		}
+	}
+	for _, instanceType := range rec.RecommendedInstanceTypes {
+		family := instanceFamily(instanceType)
+		if containsFold(d.BlockedInstanceFamilies, family) {
+			return false
+		}
+		if len(d.AllowedInstanceFamilies) > 0 && !containsFold(d.AllowedInstanceFamilies, family) {
+			return false
+		}
+	}
+	spotRatio := 0.0
+	if rec.CapacityType == "spot" {
+		spotRatio = 1
+	}
+	if (d.MinSpotRatio != nil && spotRatio < *d.MinSpotRatio) || (d.MaxSpotRatio != nil && spotRatio > *d.MaxSpotRatio) {
+		return false
+	}
+	if d.HeadroomPercent > 0 {
+		factor := 1 + d.HeadroomPercent/100
+		if rec.RecommendedTotalCPU < rec.CurrentCPUUsed*factor || rec.RecommendedTotalMemory < rec.CurrentMemoryUsed*factor {
+			return false
+		}
+	}
+	return true
+}
+
+// instanceFamily returns the family of an instance type (m6i.large -> m6i)
+func instanceFamily(instanceType string) string {
+	family, _, _ := strings.Cut(instanceType, ".")
+	return family
+}
+
+func lowerAll(values []string) []string {
+	lower := make([]string, len(values))
+	for i, v := range values {
+		lower[i] = strings.ToLower(v)
+	}
+	return lower
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/karpenter-optimizer/internal/config"
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/recommender"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStrategyDefinitions(t *testing.T) {
+	data, err := os.ReadFile("../../examples/strategies.yaml")
+	require.NoError(t, err)
+	definitions, err := ParseStrategyDefinitions(data)
+	require.NoError(t, err, "example strategy file must stay valid")
+	require.Len(t, definitions, 2)
+	assert.Equal(t, "graviton-savings", definitions[0].Name)
+	assert.True(t, definitions[0].AppliesTo("batch-arm"))
+	assert.False(t, definitions[0].AppliesTo("default"))
+
+	invalid := map[string]string{
+		"missing name":  "strategies:\n- minSavingsPercent: 10\n",
+		"built-in name": "strategies:\n- name: balanced\n",
+		"duplicate":     "strategies:\n- name: a\n- name: a\n",
+		"risk level":    "strategies:\n- name: a\n  maxRiskLevel: extreme\n",
+		"spot ratio":    "strategies:\n- name: a\n  maxSpotRatio: 2\n",
+		"ratio bounds":  "strategies:\n- name: a\n  minSpotRatio: 1\n  maxSpotRatio: 0\n",
+		"bad pattern":   "strategies:\n- name: a\n  nodePools: [\"[\"]\n",
+		"unknown field": "strategies:\n- name: a\n  minSaving: 10\n",
+		"not a list":    "strategies: a\n",
+	}
+	for name, yaml := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseStrategyDefinitions([]byte(yaml))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestStrategyDefinitionAccepts(t *testing.T) {
+	zero, one := 0.0, 1.0
+	analysis := &AnalysisResult{NodePoolState: &NodePoolState{Name: "default", CapacityType: "on-demand", CurrentNodes: 10}, Confidence: 0.8}
+	base := recommender.NodePoolCapacityRecommendation{
+		RecommendedNodes:         8,
+		RecommendedInstanceTypes: []string{"m6i.large", "m6i.xlarge"},
+		CapacityType:             "on-demand",
+		CostSavings:              1.2,
+		CostSavingsPercent:       20,
+		CurrentCPUUsed:           10,
+		CurrentMemoryUsed:        40,
+		RecommendedTotalCPU:      16,
+		RecommendedTotalMemory:   64,
+	}
+	spot := base
+	spot.CapacityType = "spot"
+
+	tests := []struct {
+		name string
+		def  StrategyDefinition
+		rec  recommender.NodePoolCapacityRecommendation
+		want bool
+	}{
+		{name: "no criteria", rec: base, want: true},
+		{name: "savings percent too low", def: StrategyDefinition{MinSavingsPercent: 25}, rec: base, want: false},
+		{name: "savings dollars too low", def: StrategyDefinition{MinSavingsPerHour: 2}, rec: base, want: false},
+		{name: "confidence too low", def: StrategyDefinition{MinConfidence: 0.9}, rec: base, want: false},
+		{name: "spot conversion is medium risk", def: StrategyDefinition{MaxRiskLevel: "low"}, rec: spot, want: false},
+		{name: "medium risk allowed", def: StrategyDefinition{MaxRiskLevel: "medium"}, rec: spot, want: true},
+		{name: "allowed families", def: StrategyDefinition{AllowedInstanceFamilies: []string{"M6I"}}, rec: base, want: true},
+		{name: "family not allowed", def: StrategyDefinition{AllowedInstanceFamilies: []string{"m7g"}}, rec: base, want: false},
+		{name: "blocked family wins", def: StrategyDefinition{AllowedInstanceFamilies: []string{"m6i"}, BlockedInstanceFamilies: []string{"m6i"}}, rec: base, want: false},
+		{name: "spot required", def: StrategyDefinition{MinSpotRatio: &one}, rec: base, want: false},
+		{name: "spot forbidden", def: StrategyDefinition{MaxSpotRatio: &zero}, rec: spot, want: false},
+		{name: "enough headroom", def: StrategyDefinition{HeadroomPercent: 50}, rec: base, want: true},
+		{name: "not enough headroom", def: StrategyDefinition{HeadroomPercent: 70}, rec: base, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.def.Accepts(analysis, tt.rec))
+		})
+	}
+}
+
+func TestCustomStrategyGenerateRecommendations(t *testing.T) {
+	rec := recommender.NewOfflineRecommender(&config.Config{})
+	node := func(name string) kubernetes.NodeInfo {
+		return kubernetes.NodeInfo{
+			Name:         name,
+			InstanceType: "m5.2xlarge",
+			CapacityType: "on-demand",
+			Architecture: "amd64",
+			CPUUsage:     &kubernetes.NodeUsage{Used: 2, Allocatable: 7.9},
+			MemoryUsage:  &kubernetes.NodeUsage{Used: 8, Allocatable: 30.5},
+		}
+	}
+	np := kubernetes.NodePoolInfo{
+		Name:         "default",
+		Architecture: "amd64",
+		CapacityType: "on-demand",
+		CurrentNodes: 3,
+		ActualNodes:  []kubernetes.NodeInfo{node("a"), node("b"), node("c")},
+	}
+	analysis := &AnalysisResult{NodePoolState: &NodePoolState{Name: "default", CapacityType: "on-demand", CurrentNodes: 3}, Confidence: 0.8}
+	zero := 0.0
+
+	t.Run("allowed families are generated, not only filtered", func(t *testing.T) {
+		// The cheapest candidate overall is c6a, which the allow list rules out
+		strategy := NewCustomStrategy(rec, StrategyDefinition{Name: "c6i-only", AllowedInstanceFamilies: []string{"C6I"}})
+		recommendations, err := strategy.GenerateRecommendations(context.Background(), analysis, np)
+		require.NoError(t, err)
+		require.Len(t, recommendations, 1)
+		assert.True(t, recommendations[0].HasRecommendation)
+		for _, it := range recommendations[0].RecommendedInstanceTypes {
+			assert.Equal(t, "c6i", instanceFamily(it))
+		}
+	})
+
+	t.Run("blocked families and on-demand only", func(t *testing.T) {
+		def := StrategyDefinition{Name: "no-spot", BlockedInstanceFamilies: []string{"c6a"}, MaxSpotRatio: &zero}
+		recommendations, err := rec.GenerateRecommendationsWithHeadroom(context.Background(), []kubernetes.NodePoolInfo{def.constrain(np)}, 0)
+		require.NoError(t, err)
+		require.Len(t, recommendations, 1)
+		assert.Equal(t, "on-demand", recommendations[0].CapacityType)
+		for _, it := range recommendations[0].RecommendedInstanceTypes {
+			assert.NotEqual(t, "c6a", instanceFamily(it))
+		}
+	})
+
+	t.Run("sized for the headroom", func(t *testing.T) {
+		strategy := NewCustomStrategy(rec, StrategyDefinition{Name: "roomy", HeadroomPercent: 400})
+		recommendations, err := strategy.GenerateRecommendations(context.Background(), analysis, np)
+		require.NoError(t, err)
+		require.Len(t, recommendations, 1)
+		assert.GreaterOrEqual(t, recommendations[0].RecommendedTotalCPU, recommendations[0].CurrentCPUUsed*5)
+		assert.GreaterOrEqual(t, recommendations[0].RecommendedTotalMemory, recommendations[0].CurrentMemoryUsed*5)
+	})
+}
+
+func TestPlannerRegisterStrategy(t *testing.T) {
+	planner := NewPlanner(nil)
+	require.NoError(t, planner.RegisterStrategy(NewCustomStrategy(nil, StrategyDefinition{Name: "zeta"})))
+	require.NoError(t, planner.RegisterStrategy(NewCustomStrategy(nil, StrategyDefinition{Name: "alpha"})))
+	assert.Error(t, planner.RegisterStrategy(NewCustomStrategy(nil, StrategyDefinition{Name: "alpha"})))
+	assert.Error(t, planner.RegisterStrategy(NewBalancedStrategy(nil)), "built-ins cannot be replaced")
+
+	assert.True(t, planner.HasStrategy("alpha"))
+	assert.False(t, planner.HasStrategy("beta"))
+	assert.Equal(t, append(BuiltInStrategies(), "alpha", "zeta"), planner.Strategies())
+	require.Len(t, planner.CustomStrategies(), 2)
+	assert.Equal(t, "alpha", planner.CustomStrategies()[0].Name)
+}
@@ -0,0 +1,273 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/karpenter-optimizer/internal/ollama"
+)
+
+// maxQASourceBytes caps the JSON of one source in the prompt
+const maxQASourceBytes = 12 * 1024
+
+// maxQAAttempts is how many answers are requested before refusing
+const maxQAAttempts = 2
+
+// QARefusal is the answer given when the LLM keeps using numbers that are not in
+// the retrieved data
+const QARefusal = "I can't answer this reliably: the answer needed numbers that are not in the retrieved cluster data. Try asking about a specific NodePool, time window or metric."
+
+// QASource is a piece of retrieved cluster data an answer may cite
+type QASource struct {
+	ID    string      `json:"id"`   // Citation key, e.g. nodepool:batch
+	Kind  string      `json:"kind"` // cluster-summary, nodepool, recommendation, disruptions, churn, karpenter-errors, namespaces
+	Title string      `json:"title"`
+	Data  interface{} `json:"data"`
+}
+
+// QACitation references a source an answer used
+type QACitation struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Title string `json:"title"`
+}
+
+// GroundedAnswer is an answer to a question about the cluster with the data it cites
+type GroundedAnswer struct {
+	Question           string       `json:"question"`
+	Answer             string       `json:"answer"`
+	Citations          []QACitation `json:"citations"`          // Sources cited in the answer, in order of first use
+	Sources            []QACitation `json:"sources"`            // Every source retrieved for the question
+	Grounded           bool         `json:"grounded"`           // Every number in the answer appears in the retrieved data
+	Refused            bool         `json:"refused"`            // The LLM's answers were replaced by QARefusal
+	UnsupportedNumbers []string     `json:"unsupportedNumbers"` // Numbers of the last rejected answer that are not in the data
+	Attempts           int          `json:"attempts"`
+}
+
+// AnswerQuestion asks the LLM to answer question from sources only. Every number in
+// the answer must appear in the sources (or the question); otherwise the LLM is asked
+// once to rewrite it, and the answer is refused if it still invents numbers.
+func AnswerQuestion(ctx context.Context, client ToolChatClient, question string, sources []QASource) (*GroundedAnswer, error) {
+	data, err := qaContext(sources)
+	if err != nil {
+		return nil, err
+	}
+	supported := newNumberIndex(data + "\n" + question)
+	for _, source := range sources {
+		supported.addCounts(source.Data)
+	}
+
+	answer := &GroundedAnswer{
+		Question:           question,
+		Citations:          make([]QACitation, 0),
+		Sources:            make([]QACitation, 0, len(sources)),
+		UnsupportedNumbers: make([]string, 0),
+	}
+	for _, source := range sources {
+		answer.Sources = append(answer.Sources, QACitation{ID: source.ID, Kind: source.Kind, Title: source.Title})
+	}
+
+	messages := []ollama.Message{
+		{Role: "system", Content: groundedQASystemPrompt},
+		{Role: "user", Content: fmt.Sprintf("Data:\n%s\nQuestion: %s", data, question)},
+	}
+	for answer.Attempts < maxQAAttempts {
+		answer.Attempts++
+		reply, err := client.ChatWithTools(ctx, messages, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get answer from LLM: %w", err)
+		}
+		content := strings.TrimSpace(reply.Content)
+		unsupported := supported.unsupported(content)
+		if len(unsupported) == 0 {
+			answer.Answer = content
+			answer.Grounded = true
+			answer.UnsupportedNumbers = make([]string, 0)
+			answer.Citations = citedSources(content, sources)
+			return answer, nil
+		}
+		answer.UnsupportedNumbers = unsupported
+		messages = append(messages,
+			ollama.Message{Role: "assistant", Content: content},
+			ollama.Message{Role: "user", Content: fmt.Sprintf("These numbers do not appear in the data: %s. Rewrite the answer using only numbers that appear in the data, without estimating or calculating new ones. If the data does not contain what the question asks, say so.", strings.Join(unsupported, ", "))},
+		)
+	}
+
+	answer.Answer = QARefusal
+	answer.Refused = true
+	return answer, nil
+}
+
+// qaContext renders the sources for the prompt, each under its citation key
+func qaContext(sources []QASource) (string, error) {
+	var b strings.Builder
+	for _, source := range sources {
+		data, err := json.Marshal(source.Data)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode source %s: %w", source.ID, err)
+		}
+		fmt.Fprintf(&b, "[%s] %s\n%s\n\n", source.ID, source.Title, truncateUTF8(data, maxQASourceBytes))
+	}
+	return b.String(), nil
+}
+
+var citationPattern = regexp.MustCompile(`\[([a-z][a-z0-9-]*(?::[^\]\s]+)?)\]`)
+
+// citedSources returns the known sources cited as [id] in content
+func citedSources(content string, sources []QASource) []QACitation {
+	byID := make(map[string]QASource, len(sources))
+	for _, source := range sources {
+		byID[source.ID] = source
+	}
+	citations := make([]QACitation, 0)
+	seen := make(map[string]bool)
+	for _, match := range citationPattern.FindAllStringSubmatch(content, -1) {
+		source, ok := byID[match[1]]
+		if !ok || seen[source.ID] {
+			continue
+		}
+		seen[source.ID] = true
+		citations = append(citations, QACitation{ID: source.ID, Kind: source.Kind, Title: source.Title})
+	}
+	return citations
+}
+
+var (
+	anyNumberPattern    = regexp.MustCompile(`\d+(?:\.\d+)?`)
+	answerNumberPattern = regexp.MustCompile(`\d+(?:,\d{3})*(?:\.\d+)?`)
+)
+
+// answerUnits are suffixes that still make a number a quantity (16GiB, 24h);
+// other letters make it part of a name such as 2xlarge
+var answerUnits = map[string]bool{
+	"k": true, "m": true, "g": true, "gi": true, "gib": true, "mi": true, "mib": true, "gb": true, "mb": true,
+	"h": true, "hr": true, "hrs": true, "d": true, "s": true, "ms": true, "min": true, "x": true,
+}
+
+// numberIndex holds the numbers an answer may use
+type numberIndex struct {
+	values []float64
+}
+
+func newNumberIndex(text string) *numberIndex {
+	idx := &numberIndex{}
+	for _, token := range anyNumberPattern.FindAllString(text, -1) {
+		if v, err := strconv.ParseFloat(token, 64); err == nil {
+			idx.values = append(idx.values, v)
+		}
+	}
+	return idx
+}
+
+// addCounts adds the length of every list in data, so answers may count items
+func (idx *numberIndex) addCounts(data interface{}) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch t := v.(type) {
+		case []interface{}:
+			idx.values = append(idx.values, float64(len(t)))
+			for _, item := range t {
+				walk(item)
+			}
+		case map[string]interface{}:
+			for _, item := range t {
+				walk(item)
+			}
+		}
+	}
+	walk(decoded)
+}
+
+// supports reports whether x, written with decimals digits, is a rounding of a known
+// number. Percentages may also be written from ratios (0.35 as 35%). Signs are
+// ignored, since answers say "saves 0.38" for a -0.38 change.
+func (idx *numberIndex) supports(x float64, decimals int, percent bool) bool {
+	tolerance := 0.5*math.Pow(10, -float64(decimals)) + 1e-9
+	for _, v := range idx.values {
+		v = math.Abs(v)
+		if math.Abs(v-x) <= tolerance || (percent && math.Abs(v*100-x) <= tolerance) {
+			return true
+		}
+	}
+	return false
+}
+
+// unsupported returns the numbers in answer that are not in the index. Citation keys
+// and numbers inside names (m6i.2xlarge, ip-10-0-1-2) are ignored.
+func (idx *numberIndex) unsupported(answer string) []string {
+	text := citationPattern.ReplaceAllString(answer, " ")
+	result := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, loc := range answerNumberPattern.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if start > 0 && partOfName(text[:start]) {
+			continue
+		}
+		suffix := strings.ToLower(leadingLetters(text[end:]))
+		if suffix != "" && !answerUnits[suffix] {
+			continue
+		}
+		token := text[start:end]
+		x, err := strconv.ParseFloat(strings.ReplaceAll(token, ",", ""), 64)
+		if err != nil {
+			continue
+		}
+		decimals := 0
+		if dot := strings.Index(token, "."); dot >= 0 {
+			decimals = len(token) - dot - 1
+		}
+		percent := strings.HasPrefix(strings.TrimSpace(text[end:]), "%")
+		if !idx.supports(x, decimals, percent) && !seen[token] {
+			seen[token] = true
+			result = append(result, token)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// partOfName reports whether a number following prefix continues a word or name
+func partOfName(prefix string) bool {
+	last := rune(prefix[len(prefix)-1])
+	switch {
+	case unicode.IsLetter(last) || unicode.IsDigit(last) || strings.ContainsRune("_./:", last):
+		return true
+	case last == '-' && len(prefix) > 1:
+		before := rune(prefix[len(prefix)-2])
+		return unicode.IsLetter(before) || unicode.IsDigit(before)
+	}
+	return false
+}
+
+func leadingLetters(s string) string {
+	for i, r := range s {
+		if !unicode.IsLetter(r) {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+const groundedQASystemPrompt = `You answer questions about a Kubernetes cluster that uses Karpenter, using only the data provided.
+Each data block starts with its citation key in brackets, e.g. [nodepool:batch].
+Rules:
+- Cite the key of every data block you use, in brackets, right after the statement it supports.
+- Only use numbers that appear in the data, exactly or rounded. Never estimate, extrapolate or invent prices, costs, counts, percentages or dates.
+- If the data does not contain what is needed to answer, say which data is missing instead of guessing.
+- Costs are hourly in USD unless the data says otherwise.
+Answer in a few short paragraphs or bullet points.`
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/karpenter-optimizer/internal/ollama"
+	"github.com/karpenter-optimizer/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testQASources() []QASource {
+	return []QASource{
+		{ID: "cluster-summary", Kind: "cluster-summary", Title: "Cluster totals", Data: map[string]interface{}{"totalNodes": 12, "costPerHour": 4.3172, "cpuPercent": 41.6}},
+		{ID: "churn:batch", Kind: "churn", Title: "Churn of batch", Data: map[string]interface{}{
+			"nodePool":   "batch",
+			"wastedCost": 1.874,
+			"daily":      []map[string]interface{}{{"date": "2026-10-17", "consolidation": 14}, {"date": "2026-10-18", "consolidation": 2}},
+			"spotRatio":  0.35,
+			"hourlyDiff": -0.62,
+		}},
+	}
+}
+
+func TestAnswerQuestion(t *testing.T) {
+	t.Run("grounded answer with citations", func(t *testing.T) {
+		server := testutil.NewFakeLLMServer(ollama.Message{Role: "assistant", Content: "Consolidation replaced 14 batch nodes on 2026-10-17 [churn:batch], wasting about $1.87 [churn:batch]. The cluster has 12 nodes [cluster-summary] running m6i.2xlarge on 2 days [unknown:source]."})
+		defer server.Close()
+		client := ollama.NewClient(server.URL, "test-model", "litellm", "", false)
+
+		answer, err := AnswerQuestion(context.Background(), client, "Why did batch cost jump yesterday?", testQASources())
+		require.NoError(t, err)
+		assert.True(t, answer.Grounded)
+		assert.False(t, answer.Refused)
+		assert.Equal(t, 1, answer.Attempts)
+		assert.Empty(t, answer.UnsupportedNumbers)
+		require.Len(t, answer.Citations, 2)
+		assert.Equal(t, "churn:batch", answer.Citations[0].ID)
+		assert.Equal(t, "cluster-summary", answer.Citations[1].ID)
+		assert.Len(t, answer.Sources, 2)
+
+		requests := server.Requests()
+		require.Len(t, requests, 1)
+		assert.Empty(t, requests[0].Tools)
+		assert.Contains(t, requests[0].Messages[1].Content, "[churn:batch] Churn of batch")
+	})
+
+	t.Run("invented number is rewritten", func(t *testing.T) {
+		server := testutil.NewFakeLLMServer(
+			ollama.Message{Role: "assistant", Content: "Batch cost rose by 37% to $5.10 per hour [churn:batch]."},
+			ollama.Message{Role: "assistant", Content: "The data has no cost history, but 14 consolidations on 2026-10-17 [churn:batch] wasted $1.874 [churn:batch]."},
+		)
+		defer server.Close()
+		client := ollama.NewClient(server.URL, "test-model", "ollama", "", false)
+
+		answer, err := AnswerQuestion(context.Background(), client, "Why did batch cost jump?", testQASources())
+		require.NoError(t, err)
+		assert.True(t, answer.Grounded)
+		assert.Equal(t, 2, answer.Attempts)
+
+		retry := server.Requests()[1].Messages
+		require.Len(t, retry, 4)
+		assert.Equal(t, "assistant", retry[2].Role)
+		assert.Contains(t, retry[3].Content, "37, 5.10")
+	})
+
+	t.Run("refuses when numbers stay invented", func(t *testing.T) {
+		server := testutil.NewFakeLLMServer(
+			ollama.Message{Role: "assistant", Content: "Batch will cost $120 per month."},
+			ollama.Message{Role: "assistant", Content: "Batch will cost about $118 per month."},
+		)
+		defer server.Close()
+		client := ollama.NewClient(server.URL, "test-model", "litellm", "", false)
+
+		answer, err := AnswerQuestion(context.Background(), client, "What will batch cost this month?", testQASources())
+		require.NoError(t, err)
+		assert.False(t, answer.Grounded)
+		assert.True(t, answer.Refused)
+		assert.Equal(t, QARefusal, answer.Answer)
+		assert.Equal(t, []string{"118"}, answer.UnsupportedNumbers)
+		assert.Empty(t, answer.Citations)
+	})
+
+	t.Run("LLM failure", func(t *testing.T) {
+		server := testutil.NewFakeLLMServer()
+		defer server.Close()
+		client := ollama.NewClient(server.URL, "test-model", "litellm", "", false)
+
+		_, err := AnswerQuestion(context.Background(), client, "Anything?", testQASources())
+		assert.Error(t, err)
+	})
+}
+
+func TestUnsupportedNumbers(t *testing.T) {
+	data, err := qaContext(testQASources())
+	require.NoError(t, err)
+	idx := newNumberIndex(data + "\nHow did the last 3 days go?")
+	for _, source := range testQASources() {
+		idx.addCounts(source.Data)
+	}
+
+	tests := []struct {
+		name   string
+		answer string
+		want   []string
+	}{
+		{"exact and rounded", "Cost is $4.3172/hr, about $4.32 or $4 [cluster-summary].", []string{}},
+		{"ratio as percent", "35% of nodes are spot and CPU is at 42%.", []string{}},
+		{"negative values by magnitude", "Savings of $0.62 per hour.", []string{}},
+		{"list length", "There are 2 days of churn data.", []string{}},
+		{"question numbers", "Over the last 3 days nothing failed.", []string{}},
+		{"names are ignored", "Nodes use m6i.2xlarge and c7g.large in us-east-1a on ip-10-0-1-27.", []string{}},
+		{"invented values", "Cost rose 19% to 1,234.5 USD with 16GiB and 7 nodes.", []string{"1,234.5", "16", "19", "7"}},
+		{"wrong precision", "Wasted cost was $1.90.", []string{"1.90"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, idx.unsupported(tt.answer))
+		})
+	}
+}
+
+func TestQAContextTruncatesAtRuneBoundary(t *testing.T) {
+	// A multi-byte rune straddles the source size limit
+	name := strings.Repeat("a", maxQASourceBytes-len(`{"name":"`)-1) + strings.Repeat("é", 10)
+	data, err := qaContext([]QASource{{ID: "nodepool:big", Title: "Big", Data: map[string]string{"name": name}}})
+	require.NoError(t, err)
+	assert.True(t, utf8.ValidString(data))
+	assert.Contains(t, data, "... (truncated)")
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package agent
+
+import "sync"
+
+var historyFileLock sync.Mutex
+
+// lockFile serializes writers within this process; Windows builds are only used
+// for local development, where a single replica shares the file
+func lockFile(path string) (func(), error) {
+	historyFileLock.Lock()
+	return historyFileLock.Unlock, nil
+}
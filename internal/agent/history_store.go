@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	k8sclient "k8s.io/client-go/kubernetes"
+)
+
+// History storage backends
+const (
+	HistoryBackendFile      = "file"
+	HistoryBackendConfigMap = "configmap"
+	HistoryBackendSecret    = "secret"
+	HistoryBackendSQLite    = "sqlite"
+)
+
+// Default history locations when no path is configured
+const (
+	DefaultHistoryFile     = "/tmp/karpenter-optimizer-history.json"
+	DefaultHistoryDatabase = "/tmp/karpenter-optimizer-history.db"
+)
+
+// HistoryStore persists optimization outcomes for the learning agent.
+// Update is an atomic read-modify-write against the latest stored history, so
+// several replicas sharing a backend never overwrite each other's outcomes.
+type HistoryStore interface {
+	Load(ctx context.Context) ([]OptimizationOutcome, error)
+	Update(ctx context.Context, fn func([]OptimizationOutcome) []OptimizationOutcome) ([]OptimizationOutcome, error)
+	Backend() string
+}
+
+// HistoryStoreOptions selects and configures a history backend
+type HistoryStoreOptions struct {
+	Backend   string // file (default), configmap, secret or sqlite
+	Path      string // File or SQLite database path
+	Namespace string // Namespace of the ConfigMap/Secret
+	Name      string // Name of the ConfigMap/Secret
+}
+
+// NewHistoryStore creates the history store selected by opts. The ConfigMap and
+// Secret backends need a clientset.
+func NewHistoryStore(opts HistoryStoreOptions, clientset k8sclient.Interface) (HistoryStore, error) {
+	switch strings.ToLower(opts.Backend) {
+	case "", HistoryBackendFile:
+		if opts.Path == "" {
+			opts.Path = DefaultHistoryFile
+		}
+		return NewFileHistoryStore(opts.Path), nil
+	case HistoryBackendConfigMap, HistoryBackendSecret:
+		if clientset == nil {
+			return nil, fmt.Errorf("%s history backend requires a Kubernetes client", opts.Backend)
+		}
+		return NewKubernetesHistoryStore(clientset, strings.ToLower(opts.Backend), opts.Namespace, opts.Name), nil
+	case HistoryBackendSQLite:
+		if opts.Path == "" {
+			opts.Path = DefaultHistoryDatabase
+		}
+		return NewSQLiteHistoryStore(opts.Path)
+	default:
+		return nil, fmt.Errorf("unknown history backend %q (expected file, configmap, secret or sqlite)", opts.Backend)
+	}
+}
+
+// upsertOutcome replaces the outcome with the same plan ID, or appends it
+func upsertOutcome(history []OptimizationOutcome, outcome OptimizationOutcome) []OptimizationOutcome {
+	if outcome.PlanID != "" {
+		for i := range history {
+			if history[i].PlanID == outcome.PlanID {
+				history[i] = outcome
+				return history
+			}
+		}
+	}
+	return append(history, outcome)
+}
+
+// FileHistoryStore keeps history in a JSON file. Writes take an exclusive lock on
+// a sibling .lock file, so replicas sharing a volume serialize their updates.
+type FileHistoryStore struct {
+	path string
+}
+
+// NewFileHistoryStore creates a file-backed history store ("" disables persistence)
+func NewFileHistoryStore(path string) *FileHistoryStore {
+	return &FileHistoryStore{path: path}
+}
+
+// Backend returns the backend name
+func (s *FileHistoryStore) Backend() string {
+	return HistoryBackendFile
+}
+
+// Load reads the history file
+func (s *FileHistoryStore) Load(ctx context.Context) ([]OptimizationOutcome, error) {
+	if s.path == "" {
+		return nil, nil // No file specified
+	}
+	return s.read()
+}
+
+// Update applies fn to the latest history while holding the file lock
+func (s *FileHistoryStore) Update(ctx context.Context, fn func([]OptimizationOutcome) []OptimizationOutcome) ([]OptimizationOutcome, error) {
+	if s.path == "" {
+		return fn(nil), nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock history: %w", err)
+	}
+	defer unlock()
+
+	history, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	history = fn(history)
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal history: %w", err)
+	}
+	// Write to a temp file and rename so readers never see a partial file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write history: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return nil, fmt.Errorf("failed to write history: %w", err)
+	}
+	return history, nil
+}
+
+func (s *FileHistoryStore) read() ([]OptimizationOutcome, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // File doesn't exist yet, that's OK
+		}
+		return nil, err
+	}
+
+	var history []OptimizationOutcome
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("failed to parse history: %w", err)
+	}
+	return history, nil
+}
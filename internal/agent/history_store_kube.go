@@ -0,0 +1,167 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	historyDataKey = "history.json"
+	// ConfigMaps and Secrets are limited to 1MiB; keep headroom for metadata
+	maxKubernetesHistoryBytes = 900 * 1024
+)
+
+// KubernetesHistoryStore keeps history in a ConfigMap or Secret. Updates use the
+// object's resourceVersion for optimistic concurrency and retry on conflicts, so
+// concurrent replicas never lose outcomes.
+type KubernetesHistoryStore struct {
+	clientset k8sclient.Interface
+	kind      string // configmap or secret
+	namespace string
+	name      string
+}
+
+// NewKubernetesHistoryStore creates a ConfigMap (kind "configmap") or Secret (kind "secret") backed history store
+func NewKubernetesHistoryStore(clientset k8sclient.Interface, kind, namespace, name string) *KubernetesHistoryStore {
+	if namespace == "" {
+		namespace = "default"
+	}
+	if name == "" {
+		name = "karpenter-optimizer-history"
+	}
+	return &KubernetesHistoryStore{clientset: clientset, kind: kind, namespace: namespace, name: name}
+}
+
+// Backend returns the backend name
+func (s *KubernetesHistoryStore) Backend() string {
+	return s.kind
+}
+
+// Load reads history from the ConfigMap/Secret
+func (s *KubernetesHistoryStore) Load(ctx context.Context) ([]OptimizationOutcome, error) {
+	data, _, err := s.get(ctx)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get %s %s/%s: %w", s.kind, s.namespace, s.name, err)
+	}
+	return decodeHistory(data)
+}
+
+// Update applies fn to the latest history and writes it back, retrying on conflicts
+func (s *KubernetesHistoryStore) Update(ctx context.Context, fn func([]OptimizationOutcome) []OptimizationOutcome) ([]OptimizationOutcome, error) {
+	var result []OptimizationOutcome
+	err := retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		data, resourceVersion, err := s.get(ctx)
+		exists := true
+		if apierrors.IsNotFound(err) {
+			exists = false
+		} else if err != nil {
+			return fmt.Errorf("failed to get %s %s/%s: %w", s.kind, s.namespace, s.name, err)
+		}
+
+		history, err := decodeHistory(data)
+		if err != nil {
+			return err
+		}
+		history = fn(history)
+
+		encoded, history, err := encodeHistory(history, maxKubernetesHistoryBytes)
+		if err != nil {
+			return err
+		}
+		if err := s.put(ctx, encoded, resourceVersion, exists); err != nil {
+			return err
+		}
+		result = history
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update %s %s/%s: %w", s.kind, s.namespace, s.name, err)
+	}
+	return result, nil
+}
+
+// get returns the stored history and the object's resourceVersion
+func (s *KubernetesHistoryStore) get(ctx context.Context) ([]byte, string, error) {
+	if s.kind == HistoryBackendSecret {
+		secret, err := s.clientset.CoreV1().Secrets(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, "", err
+		}
+		return secret.Data[historyDataKey], secret.ResourceVersion, nil
+	}
+	cm, err := s.clientset.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+	return []byte(cm.Data[historyDataKey]), cm.ResourceVersion, nil
+}
+
+// put creates the object or updates it at resourceVersion (a stale version fails with a conflict)
+func (s *KubernetesHistoryStore) put(ctx context.Context, data []byte, resourceVersion string, exists bool) error {
+	meta := metav1.ObjectMeta{
+		Name:            s.name,
+		Namespace:       s.namespace,
+		ResourceVersion: resourceVersion,
+		Labels:          map[string]string{"app.kubernetes.io/name": "karpenter-optimizer"},
+	}
+
+	var err error
+	if s.kind == HistoryBackendSecret {
+		secret := &corev1.Secret{ObjectMeta: meta, Data: map[string][]byte{historyDataKey: data}}
+		if exists {
+			_, err = s.clientset.CoreV1().Secrets(s.namespace).Update(ctx, secret, metav1.UpdateOptions{})
+		} else {
+			_, err = s.clientset.CoreV1().Secrets(s.namespace).Create(ctx, secret, metav1.CreateOptions{})
+		}
+		return err
+	}
+	cm := &corev1.ConfigMap{ObjectMeta: meta, Data: map[string]string{historyDataKey: string(data)}}
+	if exists {
+		_, err = s.clientset.CoreV1().ConfigMaps(s.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	} else {
+		_, err = s.clientset.CoreV1().ConfigMaps(s.namespace).Create(ctx, cm, metav1.CreateOptions{})
+	}
+	return err
+}
+
+func decodeHistory(data []byte) ([]OptimizationOutcome, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var history []OptimizationOutcome
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("failed to parse history: %w", err)
+	}
+	return history, nil
+}
+
+// encodeHistory marshals history, dropping the oldest outcomes until it fits in maxBytes
+func encodeHistory(history []OptimizationOutcome, maxBytes int) ([]byte, []OptimizationOutcome, error) {
+	for {
+		data, err := json.Marshal(history)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal history: %w", err)
+		}
+		if len(data) <= maxBytes || len(history) == 0 {
+			return data, history, nil
+		}
+		// Drop the oldest tenth (at least one) and try again
+		drop := len(history) / 10
+		if drop == 0 {
+			drop = 1
+		}
+		history = history[drop:]
+	}
+}
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite" // Pure Go driver, the image is built with CGO disabled
+)
+
+// SQLiteHistoryStore keeps history in a SQLite database. Updates run in an
+// immediate transaction, so writers on a shared volume are serialized by SQLite.
+type SQLiteHistoryStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteHistoryStore opens (and creates if needed) the database at path
+func NewSQLiteHistoryStore(path string) (*SQLiteHistoryStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("sqlite history backend requires a database path")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	dsn := "file:" + path + "?_txlock=immediate&_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS optimization_outcomes (
+		seq     INTEGER PRIMARY KEY AUTOINCREMENT,
+		plan_id TEXT NOT NULL,
+		outcome TEXT NOT NULL
+	)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create history table: %w", err)
+	}
+	return &SQLiteHistoryStore{db: db}, nil
+}
+
+// Backend returns the backend name
+func (s *SQLiteHistoryStore) Backend() string {
+	return HistoryBackendSQLite
+}
+
+// Close closes the database
+func (s *SQLiteHistoryStore) Close() error {
+	return s.db.Close()
+}
+
+// Load reads all outcomes in insertion order
+func (s *SQLiteHistoryStore) Load(ctx context.Context) ([]OptimizationOutcome, error) {
+	return s.load(ctx, s.db)
+}
+
+// Update applies fn to the latest history inside a write transaction
+func (s *SQLiteHistoryStore) Update(ctx context.Context, fn func([]OptimizationOutcome) []OptimizationOutcome) ([]OptimizationOutcome, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin history transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	history, err := s.load(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	history = fn(history)
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM optimization_outcomes`); err != nil {
+		return nil, fmt.Errorf("failed to write history: %w", err)
+	}
+	for _, outcome := range history {
+		data, err := json.Marshal(outcome)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal outcome: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO optimization_outcomes (plan_id, outcome) VALUES (?, ?)`, outcome.PlanID, string(data)); err != nil {
+			return nil, fmt.Errorf("failed to write history: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit history: %w", err)
+	}
+	return history, nil
+}
+
+type sqlQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (s *SQLiteHistoryStore) load(ctx context.Context, q sqlQuerier) ([]OptimizationOutcome, error) {
+	rows, err := q.QueryContext(ctx, `SELECT outcome FROM optimization_outcomes ORDER BY seq`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []OptimizationOutcome
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+		var outcome OptimizationOutcome
+		if err := json.Unmarshal([]byte(data), &outcome); err != nil {
+			return nil, fmt.Errorf("failed to parse outcome: %w", err)
+		}
+		history = append(history, outcome)
+	}
+	return history, rows.Err()
+}
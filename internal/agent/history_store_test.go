@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestHistoryStores(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		newStore func(t *testing.T) HistoryStore
+	}{
+		{
+			name: "file",
+			newStore: func(t *testing.T) HistoryStore {
+				return NewFileHistoryStore(filepath.Join(t.TempDir(), "history", "history.json"))
+			},
+		},
+		{
+			name: "configmap",
+			newStore: func(t *testing.T) HistoryStore {
+				return NewKubernetesHistoryStore(fake.NewSimpleClientset(), HistoryBackendConfigMap, "optimizer", "")
+			},
+		},
+		{
+			name: "secret",
+			newStore: func(t *testing.T) HistoryStore {
+				return NewKubernetesHistoryStore(fake.NewSimpleClientset(), HistoryBackendSecret, "optimizer", "history")
+			},
+		},
+		{
+			name: "sqlite",
+			newStore: func(t *testing.T) HistoryStore {
+				store, err := NewSQLiteHistoryStore(filepath.Join(t.TempDir(), "history.db"))
+				require.NoError(t, err)
+				t.Cleanup(func() { store.Close() })
+				return store
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.newStore(t)
+			assert.Equal(t, tt.name, store.Backend())
+
+			history, err := store.Load(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, history)
+
+			// Concurrent writers (as several replicas would be) must not lose outcomes
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					_, err := store.Update(ctx, func(history []OptimizationOutcome) []OptimizationOutcome {
+						return upsertOutcome(history, OptimizationOutcome{PlanID: fmt.Sprintf("plan-%d", i), NodePoolName: "default"})
+					})
+					assert.NoError(t, err)
+				}(i)
+			}
+			wg.Wait()
+
+			history, err = store.Load(ctx)
+			require.NoError(t, err)
+			assert.Len(t, history, 10)
+
+			// Outcomes with an existing plan ID replace it
+			history, err = store.Update(ctx, func(history []OptimizationOutcome) []OptimizationOutcome {
+				return upsertOutcome(history, OptimizationOutcome{PlanID: "plan-3", NodePoolName: "default", ActualSavings: 42})
+			})
+			require.NoError(t, err)
+			assert.Len(t, history, 10)
+
+			history, err = store.Load(ctx)
+			require.NoError(t, err)
+			require.Len(t, history, 10)
+			for _, outcome := range history {
+				if outcome.PlanID == "plan-3" {
+					assert.Equal(t, 42.0, outcome.ActualSavings)
+				}
+			}
+		})
+	}
+}
+
+func TestLearningAgentRecordOutcome(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "history.json")
+
+	agent, err := NewLearningAgent(path)
+	require.NoError(t, err)
+	require.NoError(t, agent.RecordOutcome(ctx, OptimizationOutcome{
+		PlanID:           "plan-1",
+		NodePoolName:     "default",
+		Strategy:         StrategySpotFirst,
+		PredictedSavings: 100,
+		UserFeedback:     "approved",
+	}))
+	// Updating the plan's actual results replaces the outcome instead of adding another
+	require.NoError(t, agent.RecordOutcome(ctx, OptimizationOutcome{
+		PlanID:           "plan-1",
+		NodePoolName:     "default",
+		Strategy:         StrategySpotFirst,
+		PredictedSavings: 100,
+		ActualSavings:    90,
+		UserFeedback:     "approved",
+	}))
+
+	history := agent.GetHistory()
+	require.Len(t, history, 1)
+	assert.True(t, history[0].Success)
+	assert.InDelta(t, 0.9, history[0].Accuracy, 0.001)
+	assert.Equal(t, 1.0, agent.GetStrategySuccessRate(StrategySpotFirst))
+
+	// Another replica sharing the file records an outcome; a new agent sees both
+	other, err := NewLearningAgent(path)
+	require.NoError(t, err)
+	require.NoError(t, other.RecordOutcome(ctx, OptimizationOutcome{PlanID: "plan-2", NodePoolName: "default", Strategy: StrategyBalanced}))
+	assert.Len(t, other.GetHistory(), 2)
+
+	reloaded, err := NewLearningAgent(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, reloaded.GetHistoryCount())
+	assert.Equal(t, HistoryBackendFile, reloaded.GetHistoryBackend())
+}
@@ -2,10 +2,8 @@ package agent
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"math/rand/v2"
 	"sync"
 	"time"
 )
@@ -34,19 +32,34 @@ type OptimizationOutcome struct {
 	ActualInstanceTypes []string `json:"actualInstanceTypes,omitempty"`
 	ActualCapacityType  string    `json:"actualCapacityType,omitempty"`
 	
+	// Automatic verification (see OutcomeVerifier)
+	BaselineCost        float64   `json:"baselineCost,omitempty"`         // NodePool hourly cost when the plan was applied
+	BaselineNodes       int       `json:"baselineNodes,omitempty"`        // NodePool node count when the plan was applied
+	PendingPods         int       `json:"pendingPods,omitempty"`          // Pods the NodePool could run that were pending at verification
+	Disruptions         map[string]int `json:"disruptions,omitempty"`     // Disruptions since the plan was applied, by reason
+	VerifiedAt          *time.Time `json:"verifiedAt,omitempty"`          // When the outcome was measured automatically
+	
 	// Learning data
 	Success             bool      `json:"success"`                        // Whether optimization was successful
 	Accuracy            float64   `json:"accuracy"`                       // How close prediction was to actual (0-1)
 	LessonsLearned      []string  `json:"lessonsLearned,omitempty"`       // Key learnings from this outcome
 }
 
+// historyRefreshInterval is how often history is re-read so outcomes recorded by
+// other replicas are learned from
+const historyRefreshInterval = time.Minute
+
 // LearningAgent learns from optimization outcomes
 type LearningAgent struct {
-	historyFile string
+	store       HistoryStore
 	history     []OptimizationOutcome
 	historyMu   sync.RWMutex
+	loadedAt    time.Time
 	patterns    *LearnedPatterns
 	patternsMu  sync.RWMutex
+	bandit      BanditConfig
+	rng         *rand.Rand // Thompson sampling draws, guarded by rngMu
+	rngMu       sync.Mutex
 }
 
 // LearnedPatterns stores patterns learned from history
@@ -55,6 +68,7 @@ type LearnedPatterns struct {
 	NodePoolPatterns     map[string]*NodePoolPattern       `json:"nodePoolPatterns"`
 	RiskFactors          map[string]float64                `json:"riskFactors"` // Risk factor -> impact score
 	InstanceTypePrefs    map[string]float64                `json:"instanceTypePrefs"` // Instance type -> success rate
+	Recalibration        Recalibration                     `json:"recalibration"` // Maps predicted confidence to observed success
 	LastUpdated          time.Time                         `json:"lastUpdated"`
 }
 
@@ -77,10 +91,18 @@ type OptimalConfiguration struct {
 	Confidence       float64  `json:"confidence"`
 }
 
-// NewLearningAgent creates a new learning agent
+// NewLearningAgent creates a new learning agent with history stored in a JSON file
 func NewLearningAgent(historyFile string) (*LearningAgent, error) {
+	return NewLearningAgentWithStore(NewFileHistoryStore(historyFile))
+}
+
+// NewLearningAgentWithStore creates a new learning agent persisting history in store
+func NewLearningAgentWithStore(store HistoryStore) (*LearningAgent, error) {
+	if store == nil {
+		return nil, fmt.Errorf("history store is required")
+	}
 	agent := &LearningAgent{
-		historyFile: historyFile,
+		store:       store,
 		history:     make([]OptimizationOutcome, 0),
 		patterns: &LearnedPatterns{
 			StrategySuccessRates: make(map[OptimizationStrategy]float64),
@@ -88,10 +110,12 @@ func NewLearningAgent(historyFile string) (*LearningAgent, error) {
 			RiskFactors:          make(map[string]float64),
 			InstanceTypePrefs:    make(map[string]float64),
 		},
+		bandit:      DefaultBanditConfig,
+		rng:         rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), 0)),
 	}
 	
 	// Load existing history (non-blocking - continue even if it fails)
-	if err := agent.loadHistory(); err != nil {
+	if err := agent.loadHistory(context.Background()); err != nil {
 		fmt.Printf("Warning: Failed to load optimization history: %v (continuing without history)\n", err)
 		// Continue with empty history - this is not critical
 	}
@@ -110,11 +134,10 @@ func NewLearningAgent(historyFile string) (*LearningAgent, error) {
 	return agent, nil
 }
 
-// RecordOutcome records an optimization outcome for learning
+// RecordOutcome records an optimization outcome for learning. An outcome with the
+// PlanID of an existing one replaces it, so updating a plan's actual results does
+// not count it twice.
 func (l *LearningAgent) RecordOutcome(ctx context.Context, outcome OptimizationOutcome) error {
-	l.historyMu.Lock()
-	defer l.historyMu.Unlock()
-	
 	// Calculate accuracy if we have actual savings
 	if outcome.PredictedSavings > 0 && outcome.ActualSavings >= 0 {
 		// Accuracy: how close predicted was to actual (1.0 = perfect match)
@@ -136,31 +159,110 @@ func (l *LearningAgent) RecordOutcome(ctx context.Context, outcome OptimizationO
 	// Extract lessons learned
 	outcome.LessonsLearned = l.extractLessons(outcome)
 	
-	// Add to history
-	l.history = append(l.history, outcome)
-	
-	// Save history
-	if err := l.saveHistory(); err != nil {
+	// Add to the latest stored history (which includes other replicas' outcomes)
+	history, err := l.store.Update(ctx, func(history []OptimizationOutcome) []OptimizationOutcome {
+		return upsertOutcome(history, outcome)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to save history: %w", err)
 	}
 	
-	// Learn from this outcome
-	l.learnFromOutcome(outcome)
+	l.historyMu.Lock()
+	l.history = history
+	l.loadedAt = time.Now()
+	l.historyMu.Unlock()
+	
+	// Learn from the updated history
+	l.learnFromHistory()
 	
 	return nil
 }
 
-// GetBestStrategyForNodePool returns the best strategy learned for a NodePool
-func (l *LearningAgent) GetBestStrategyForNodePool(nodePoolName string) (OptimizationStrategy, float64) {
+// RefreshIfStale re-reads history from the store when it was last loaded more than
+// a minute ago, picking up outcomes recorded by other replicas
+func (l *LearningAgent) RefreshIfStale(ctx context.Context) {
+	l.historyMu.RLock()
+	stale := time.Since(l.loadedAt) > historyRefreshInterval
+	l.historyMu.RUnlock()
+	if !stale {
+		return
+	}
+	
+	if err := l.loadHistory(ctx); err != nil {
+		fmt.Printf("Warning: Failed to refresh optimization history: %v\n", err)
+		return
+	}
+	l.learnFromHistory()
+}
+
+// GetHistoryBackend returns the name of the history storage backend
+func (l *LearningAgent) GetHistoryBackend() string {
+	return l.store.Backend()
+}
+
+// SetBanditConfig changes the decay and incident penalty used to score strategies
+func (l *LearningAgent) SetBanditConfig(config BanditConfig) {
+	l.patternsMu.Lock()
+	defer l.patternsMu.Unlock()
+	l.bandit = config
+}
+
+// GetBanditConfig returns the decay and incident penalty used to score strategies
+func (l *LearningAgent) GetBanditConfig() BanditConfig {
 	l.patternsMu.RLock()
 	defer l.patternsMu.RUnlock()
-	
-	pattern, ok := l.patterns.NodePoolPatterns[nodePoolName]
-	if !ok || pattern.BestStrategy == "" {
+	return l.bandit
+}
+
+// GetArmStats returns the bandit arm of every NodePool-strategy pair with outcomes
+func (l *LearningAgent) GetArmStats() []ArmStats {
+	return sortedArms(buildArms(l.GetHistory(), l.GetBanditConfig(), time.Now()))
+}
+
+// GetBestStrategyForNodePool returns the strategy with the highest expected reward
+// for a NodePool and that reward. It does not explore; use SelectStrategy to choose
+// the strategy of a new plan.
+func (l *LearningAgent) GetBestStrategyForNodePool(nodePoolName string) (OptimizationStrategy, float64) {
+	arms := buildArms(l.GetHistory(), l.GetBanditConfig(), time.Now())
+	if len(arms[nodePoolName]) == 0 {
 		return StrategyBalanced, 0.5 // Default
 	}
 	
-	return pattern.BestStrategy, pattern.SuccessRate
+	var best *ArmStats
+	for _, arm := range sortedArms(map[string]map[OptimizationStrategy]*ArmStats{nodePoolName: arms[nodePoolName]}) {
+		if best == nil || arm.ExpectedReward > best.ExpectedReward {
+			arm := arm
+			best = &arm
+		}
+	}
+	return best.Strategy, best.ExpectedReward
+}
+
+// SelectStrategy chooses a strategy for a NodePool by Thompson sampling: each
+// candidate's reward is drawn from its arm's posterior and the highest draw wins,
+// so strategies with few or old outcomes keep being tried. It returns false when
+// the NodePool has no outcomes yet, leaving the choice to the caller.
+func (l *LearningAgent) SelectStrategy(nodePoolName string, candidates []OptimizationStrategy) (OptimizationStrategy, bool) {
+	arms := buildArms(l.GetHistory(), l.GetBanditConfig(), time.Now())[nodePoolName]
+	if len(arms) == 0 || len(candidates) == 0 {
+		return "", false
+	}
+	
+	l.rngMu.Lock()
+	defer l.rngMu.Unlock()
+	
+	var best OptimizationStrategy
+	bestDraw := -1.0
+	for _, strategy := range candidates {
+		alpha, beta := 1.0, 1.0 // Uniform prior for untried strategies
+		if arm, ok := arms[strategy]; ok {
+			alpha, beta = arm.Alpha, arm.Beta
+		}
+		if draw := sampleBeta(l.rng, alpha, beta); draw > bestDraw {
+			best, bestDraw = strategy, draw
+		}
+	}
+	return best, true
 }
 
 // GetStrategySuccessRate returns the overall success rate for a strategy
@@ -219,6 +321,19 @@ func (l *LearningAgent) AdjustConfidence(baseConfidence float64, nodePoolName st
 	return adjusted
 }
 
+// CalibrateConfidence maps a plan confidence through the recalibration fitted to
+// past outcomes. Until enough outcomes are resolved it is returned unchanged.
+func (l *LearningAgent) CalibrateConfidence(confidence float64) float64 {
+	l.patternsMu.RLock()
+	defer l.patternsMu.RUnlock()
+	return l.patterns.Recalibration.Calibrate(confidence)
+}
+
+// GetCalibrationReport compares predicted confidences and savings with outcomes
+func (l *LearningAgent) GetCalibrationReport() *CalibrationReport {
+	return buildCalibrationReport(l.GetHistory(), time.Now())
+}
+
 // GetRiskFactorImpact returns learned impact score for a risk factor
 func (l *LearningAgent) GetRiskFactorImpact(riskFactor string) float64 {
 	l.patternsMu.RLock()
@@ -259,6 +374,12 @@ func (l *LearningAgent) learnFromHistory() {
 	nodePoolOutcomes := make(map[string][]OptimizationOutcome)
 	
 	for _, outcome := range history {
+		// Applied outcomes waiting for verification have no result yet, so they are
+		// neither successes nor failures
+		if needsVerification(outcome) {
+			continue
+		}
+
 		// Strategy success rate
 		strategyCounts[outcome.Strategy]++
 		if outcome.Success {
@@ -345,57 +466,10 @@ func (l *LearningAgent) learnFromHistory() {
 		l.patterns.NodePoolPatterns[nodePoolName] = pattern
 	}
 	
-	l.patterns.LastUpdated = time.Now()
-}
-
-// learnFromOutcome learns from a single outcome
-func (l *LearningAgent) learnFromOutcome(outcome OptimizationOutcome) {
-	l.patternsMu.Lock()
-	defer l.patternsMu.Unlock()
-	
-	// Update strategy success rate
-	currentRate, ok := l.patterns.StrategySuccessRates[outcome.Strategy]
-	if !ok {
-		currentRate = 0.5
-	}
-	// Exponential moving average
-	alpha := 0.3 // Learning rate
-	newRate := currentRate*(1-alpha) + boolToFloat(outcome.Success)*alpha
-	l.patterns.StrategySuccessRates[outcome.Strategy] = newRate
-	
-	// Update NodePool pattern
-	pattern, ok := l.patterns.NodePoolPatterns[outcome.NodePoolName]
-	if !ok {
-		pattern = &NodePoolPattern{
-			NodePoolName:    outcome.NodePoolName,
-			StrategyHistory: make(map[OptimizationStrategy]int),
-		}
-		l.patterns.NodePoolPatterns[outcome.NodePoolName] = pattern
-	}
-	
-	pattern.StrategyHistory[outcome.Strategy]++
-	
-	// Update success rate (exponential moving average)
-	pattern.SuccessRate = pattern.SuccessRate*(1-alpha) + boolToFloat(outcome.Success)*alpha
-	
-	// Update average savings
-	if outcome.ActualSavings > 0 {
-		if pattern.AvgSavings == 0 {
-			pattern.AvgSavings = outcome.ActualSavings
-		} else {
-			pattern.AvgSavings = pattern.AvgSavings*(1-alpha) + outcome.ActualSavings*alpha
-		}
-	}
+	// Fit confidence recalibration to the outcomes
+	l.patterns.Recalibration = buildCalibrationReport(history, time.Now()).Recalibration
 	
-	// Update optimal configuration if this is better
-	if outcome.Success && (pattern.OptimalConfig == nil || outcome.ActualSavings > pattern.AvgSavings*1.1) {
-		pattern.OptimalConfig = &OptimalConfiguration{
-			InstanceTypes: outcome.ActualInstanceTypes,
-			CapacityType:  outcome.ActualCapacityType,
-			NodeCount:     outcome.ActualNodes,
-			Confidence:    outcome.Accuracy,
-		}
-	}
+	l.patterns.LastUpdated = time.Now()
 }
 
 // determineSuccess determines if an optimization was successful
@@ -474,59 +548,21 @@ func (l *LearningAgent) findBestOutcome(outcomes []OptimizationOutcome) *Optimiz
 	return best
 }
 
-// loadHistory loads optimization history from file
-func (l *LearningAgent) loadHistory() error {
-	if l.historyFile == "" {
-		return nil // No file specified
-	}
-	
-	data, err := os.ReadFile(l.historyFile)
+// loadHistory loads optimization history from the store
+func (l *LearningAgent) loadHistory(ctx context.Context) error {
+	history, err := l.store.Load(ctx)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // File doesn't exist yet, that's OK
-		}
 		return err
 	}
 	
-	var history []OptimizationOutcome
-	if err := json.Unmarshal(data, &history); err != nil {
-		return fmt.Errorf("failed to parse history: %w", err)
-	}
-	
 	l.historyMu.Lock()
 	l.history = history
+	l.loadedAt = time.Now()
 	l.historyMu.Unlock()
 	
 	return nil
 }
 
-// saveHistory saves optimization history to file
-func (l *LearningAgent) saveHistory() error {
-	if l.historyFile == "" {
-		return nil // No file specified
-	}
-	
-	l.historyMu.RLock()
-	data, err := json.MarshalIndent(l.history, "", "  ")
-	l.historyMu.RUnlock()
-	
-	if err != nil {
-		return fmt.Errorf("failed to marshal history: %w", err)
-	}
-	
-	// Ensure directory exists
-	dir := filepath.Dir(l.historyFile)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
-	}
-	
-	if err := os.WriteFile(l.historyFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write history: %w", err)
-	}
-	
-	return nil
-}
-
 // Helper functions
 func abs(x float64) float64 {
 	if x < 0 {
@@ -35,6 +35,15 @@ func (t *OutcomeTracker) RecordAppliedPlan(ctx context.Context, plan *Optimizati
 		PredictedRiskLevel:  plan.RiskLevel,
 		UserFeedback:        userFeedback,
 	}
+	if plan.RawConfidence > 0 {
+		outcome.PredictedConfidence = plan.RawConfidence // Calibrate against what the planner predicted
+	}
+	
+	// Baseline for automatic verification
+	if plan.CurrentState != nil {
+		outcome.BaselineCost = plan.CurrentState.CurrentCost
+		outcome.BaselineNodes = plan.CurrentState.CurrentNodes
+	}
 	
 	// If plan has recommendations, extract initial values
 	if len(plan.Recommendations) > 0 {
@@ -0,0 +1,299 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/recommender"
+)
+
+// candidateHeadrooms are the CPU/memory headroom percentages candidates are sized with
+var candidateHeadrooms = []float64{10, 25, 40}
+
+// maxCandidateNodes bounds candidate sizing
+const maxCandidateNodes = 1000
+
+// PlanObjectives scores a candidate configuration on the objectives a Pareto set trades off
+type PlanObjectives struct {
+	HourlyCost           float64 `json:"hourlyCost"`           // Lower is better
+	HourlySavings        float64 `json:"hourlySavings"`        // Versus the current configuration
+	InterruptionRisk     float64 `json:"interruptionRisk"`     // 0 (on-demand) to 1 (spot on one instance type); lower is better
+	BinPackingEfficiency float64 `json:"binPackingEfficiency"` // Share of CPU and memory used (0-1); higher is better
+	Disruptions          int     `json:"disruptions"`          // Nodes replaced or removed to get there; lower is better
+	HeadroomPercent      float64 `json:"headroomPercent"`      // CPU/memory headroom above current usage
+	SpotRatio            float64 `json:"spotRatio"`            // Share of capacity on spot (0 or 1)
+	Score                float64 `json:"score"`                // Weighted, normalized within the set; lower is better
+}
+
+// ParetoWeights weight the normalized objectives when picking the recommended candidate
+type ParetoWeights struct {
+	Cost       float64 `json:"cost"`
+	Risk       float64 `json:"risk"`
+	Efficiency float64 `json:"efficiency"`
+	Disruption float64 `json:"disruption"`
+}
+
+// DefaultParetoWeights favor cost, then interruption risk
+var DefaultParetoWeights = ParetoWeights{Cost: 0.4, Risk: 0.25, Efficiency: 0.2, Disruption: 0.15}
+
+// ParetoPlanSet holds the Pareto-optimal plans for a NodePool: no plan in the set is
+// beaten on every objective by another candidate
+type ParetoPlanSet struct {
+	NodePoolName      string              `json:"nodePoolName"`
+	CurrentState      *NodePoolState      `json:"currentState"`
+	Plans             []*OptimizationPlan `json:"plans"` // Cheapest first
+	RecommendedPlanID string              `json:"recommendedPlanId,omitempty"`
+	Weights           ParetoWeights       `json:"weights"`
+	Evaluated         int                 `json:"evaluated"` // Candidates scored, including dominated ones
+}
+
+// CapacityPricer sizes and prices candidate configurations (implemented by the recommender)
+type CapacityPricer interface {
+	EstimateCost(ctx context.Context, instanceTypes []string, capacityType string, nodeCount int) float64
+	EstimateInstanceCapacity(instanceType string) (float64, float64)
+}
+
+// planCandidate is a configuration with its scores
+type planCandidate struct {
+	rec        recommender.NodePoolCapacityRecommendation
+	objectives PlanObjectives
+}
+
+// PlanParetoSet generates candidate configurations for a NodePool (instance mixes,
+// capacity types and headroom), keeps the Pareto-optimal ones that save money and
+// recommends the one with the best weighted score
+func (p *Planner) PlanParetoSet(ctx context.Context, analysis *AnalysisResult, np kubernetes.NodePoolInfo, weights ParetoWeights) (*ParetoPlanSet, error) {
+	recommendations, err := p.recommender.GenerateRecommendationsFromNodePools(ctx, []kubernetes.NodePoolInfo{np}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate recommendations: %w", err)
+	}
+	set := &ParetoPlanSet{NodePoolName: analysis.NodePoolState.Name, CurrentState: analysis.NodePoolState, Plans: []*OptimizationPlan{}, Weights: weights}
+	if len(recommendations) == 0 {
+		return set, nil
+	}
+
+	candidates := generateCandidates(ctx, p.recommender, analysis.NodePoolState, recommendations[0])
+	set.Evaluated = len(candidates)
+	front := paretoFront(candidates)
+	recommended := scoreCandidates(front, weights)
+
+	now := time.Now()
+	fingerprint := NodePoolFingerprint(np)
+	for i, candidate := range front {
+		objectives := candidate.objectives
+		plan := &OptimizationPlan{
+			ID:                  fmt.Sprintf("plan-%s-%d-%d", set.NodePoolName, now.UnixNano(), i),
+			NodePoolName:        set.NodePoolName,
+			Strategy:            StrategyPareto,
+			CurrentState:        analysis.NodePoolState,
+			Recommendations:     []recommender.NodePoolCapacityRecommendation{candidate.rec},
+			RiskLevel:           calculateRiskLevel(analysis, []recommender.NodePoolCapacityRecommendation{candidate.rec}),
+			EstimatedSavings:    candidate.rec.CostSavings,
+			Confidence:          analysis.Confidence,
+			CreatedAt:           now,
+			NodePoolFingerprint: fingerprint,
+			Objectives:          &objectives,
+		}
+		set.Plans = append(set.Plans, plan)
+		if i == recommended {
+			set.RecommendedPlanID = plan.ID
+		}
+	}
+	return set, nil
+}
+
+// generateCandidates sizes every combination of instance mix (recommended and current
+// types), capacity type and headroom, keeping those cheaper than the current setup
+func generateCandidates(ctx context.Context, pricer CapacityPricer, state *NodePoolState, base recommender.NodePoolCapacityRecommendation) []planCandidate {
+	mixes := [][]string{base.RecommendedInstanceTypes}
+	if current := dedupeSorted(state.InstanceTypes); len(current) > 0 && !sameTypes(current, base.RecommendedInstanceTypes) {
+		mixes = append(mixes, current)
+	}
+
+	var candidates []planCandidate
+	for _, mix := range mixes {
+		if len(mix) == 0 {
+			continue
+		}
+		for _, capacityType := range []string{"on-demand", "spot"} {
+			for _, headroom := range candidateHeadrooms {
+				nodes, cpu, memory := sizeMix(pricer, mix, base.CurrentCPUUsed, base.CurrentMemoryUsed, headroom)
+				if nodes == 0 {
+					continue
+				}
+				cost := pricer.EstimateCost(ctx, mix, capacityType, nodes)
+				if cost <= 0 || cost >= base.CurrentCost {
+					continue
+				}
+				rec := base
+				rec.RecommendedInstanceTypes = mix
+				rec.RecommendedNodes = nodes
+				rec.RecommendedTotalCPU = cpu
+				rec.RecommendedTotalMemory = memory
+				rec.RecommendedCost = cost
+				rec.CapacityType = capacityType
+				rec.CostSavings = base.CurrentCost - cost
+				rec.CostSavingsPercent = rec.CostSavings / base.CurrentCost * 100
+				rec.HasRecommendation = true
+				rec.AIReasoning = ""
+				rec.Reasoning = fmt.Sprintf("%d nodes of %v (%s) with %.0f%% headroom providing %.1f CPU cores and %.1f GiB memory at $%.2f/hr. Savings: $%.2f/hr (%.1f%%).",
+					nodes, mix, capacityType, headroom, cpu, memory, cost, rec.CostSavings, rec.CostSavingsPercent)
+				candidates = append(candidates, planCandidate{rec: rec, objectives: objectivesFor(state, rec, headroom)})
+			}
+		}
+	}
+	return candidates
+}
+
+// sizeMix returns the fewest nodes of an instance mix (spread evenly, like the recommender
+// prices them) whose CPU and memory exceed usage by the headroom, with that capacity
+func sizeMix(pricer CapacityPricer, mix []string, cpuUsed, memoryUsed, headroom float64) (int, float64, float64) {
+	factor := 1 + headroom/100
+	for nodes := 1; nodes <= maxCandidateNodes; nodes++ {
+		var cpu, memory float64
+		for i, instanceType := range mix {
+			count := nodes / len(mix)
+			if i < nodes%len(mix) {
+				count++
+			}
+			c, m := pricer.EstimateInstanceCapacity(instanceType)
+			cpu += c * float64(count)
+			memory += m * float64(count)
+		}
+		if cpu >= cpuUsed*factor && memory >= memoryUsed*factor {
+			return nodes, cpu, memory
+		}
+	}
+	return 0, 0, 0
+}
+
+// objectivesFor scores a candidate against the current NodePool state
+func objectivesFor(state *NodePoolState, rec recommender.NodePoolCapacityRecommendation, headroom float64) PlanObjectives {
+	objectives := PlanObjectives{
+		HourlyCost:      rec.RecommendedCost,
+		HourlySavings:   rec.CostSavings,
+		HeadroomPercent: headroom,
+	}
+	if rec.CapacityType == "spot" {
+		// Diversified spot capacity is less likely to be reclaimed at once
+		objectives.SpotRatio = 1
+		objectives.InterruptionRisk = 1 / math.Sqrt(float64(len(rec.RecommendedInstanceTypes)))
+	}
+	var utilization []float64
+	if rec.RecommendedTotalCPU > 0 {
+		utilization = append(utilization, math.Min(rec.CurrentCPUUsed/rec.RecommendedTotalCPU, 1))
+	}
+	if rec.RecommendedTotalMemory > 0 {
+		utilization = append(utilization, math.Min(rec.CurrentMemoryUsed/rec.RecommendedTotalMemory, 1))
+	}
+	for _, u := range utilization {
+		objectives.BinPackingEfficiency += u / float64(len(utilization))
+	}
+	// Same instance types and capacity type only removes surplus nodes; anything else replaces every node
+	if rec.CapacityType == state.CapacityType && sameTypes(dedupeSorted(state.InstanceTypes), rec.RecommendedInstanceTypes) {
+		if removed := state.CurrentNodes - rec.RecommendedNodes; removed > 0 {
+			objectives.Disruptions = removed
+		}
+	} else {
+		objectives.Disruptions = state.CurrentNodes
+	}
+	return objectives
+}
+
+// dominates reports whether a is at least as good as b on every objective and better on one
+func dominates(a, b PlanObjectives) bool {
+	if a.HourlyCost > b.HourlyCost || a.InterruptionRisk > b.InterruptionRisk ||
+		a.BinPackingEfficiency < b.BinPackingEfficiency || a.Disruptions > b.Disruptions {
+		return false
+	}
+	return a.HourlyCost < b.HourlyCost || a.InterruptionRisk < b.InterruptionRisk ||
+		a.BinPackingEfficiency > b.BinPackingEfficiency || a.Disruptions < b.Disruptions
+}
+
+// sameObjectives reports whether two candidates score the same on every objective
+func sameObjectives(a, b PlanObjectives) bool {
+	return a.HourlyCost == b.HourlyCost && a.InterruptionRisk == b.InterruptionRisk &&
+		a.BinPackingEfficiency == b.BinPackingEfficiency && a.Disruptions == b.Disruptions
+}
+
+// paretoFront returns the non-dominated candidates, cheapest first. Candidates with
+// identical objectives are kept once.
+func paretoFront(candidates []planCandidate) []planCandidate {
+	var front []planCandidate
+	for i, candidate := range candidates {
+		dominated := false
+		for j, other := range candidates {
+			if i != j && (dominates(other.objectives, candidate.objectives) || (j < i && sameObjectives(other.objectives, candidate.objectives))) {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			front = append(front, candidate)
+		}
+	}
+	sort.SliceStable(front, func(i, j int) bool {
+		if front[i].objectives.HourlyCost != front[j].objectives.HourlyCost {
+			return front[i].objectives.HourlyCost < front[j].objectives.HourlyCost
+		}
+		return front[i].objectives.InterruptionRisk < front[j].objectives.InterruptionRisk
+	})
+	return front
+}
+
+// scoreCandidates sets each candidate's weighted score over objectives normalized
+// within the set and returns the index of the best (lowest) one, or -1 for an empty set
+func scoreCandidates(front []planCandidate, weights ParetoWeights) int {
+	if len(front) == 0 {
+		return -1
+	}
+	normalize := func(value func(PlanObjectives) float64) func(PlanObjectives) float64 {
+		low, high := math.Inf(1), math.Inf(-1)
+		for _, c := range front {
+			low = math.Min(low, value(c.objectives))
+			high = math.Max(high, value(c.objectives))
+		}
+		return func(o PlanObjectives) float64 {
+			if high == low {
+				return 0
+			}
+			return (value(o) - low) / (high - low)
+		}
+	}
+	cost := normalize(func(o PlanObjectives) float64 { return o.HourlyCost })
+	risk := normalize(func(o PlanObjectives) float64 { return o.InterruptionRisk })
+	waste := normalize(func(o PlanObjectives) float64 { return -o.BinPackingEfficiency })
+	disruption := normalize(func(o PlanObjectives) float64 { return float64(o.Disruptions) })
+
+	best := 0
+	for i := range front {
+		o := front[i].objectives
+		front[i].objectives.Score = weights.Cost*cost(o) + weights.Risk*risk(o) + weights.Efficiency*waste(o) + weights.Disruption*disruption(o)
+		if front[i].objectives.Score < front[best].objectives.Score {
+			best = i
+		}
+	}
+	return best
+}
+
+func dedupeSorted(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var result []string
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func sameTypes(a, b []string) bool {
+	return strings.Join(dedupeSorted(a), ",") == strings.Join(dedupeSorted(b), ",")
+}
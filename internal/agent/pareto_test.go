@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/karpenter-optimizer/internal/recommender"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePricer prices instances from a table; spot costs a quarter of on-demand
+type fakePricer map[string]struct{ cpu, memory, price float64 }
+
+func (f fakePricer) EstimateCost(ctx context.Context, instanceTypes []string, capacityType string, nodeCount int) float64 {
+	total := 0.0
+	for i, it := range instanceTypes {
+		count := nodeCount / len(instanceTypes)
+		if i < nodeCount%len(instanceTypes) {
+			count++
+		}
+		total += f[it].price * float64(count)
+	}
+	if capacityType == "spot" {
+		total *= 0.25
+	}
+	return total
+}
+
+func (f fakePricer) EstimateInstanceCapacity(instanceType string) (float64, float64) {
+	return f[instanceType].cpu, f[instanceType].memory
+}
+
+func TestGenerateCandidatesAndParetoFront(t *testing.T) {
+	pricer := fakePricer{
+		"m5.2xlarge": {cpu: 8, memory: 32, price: 0.384},
+		"m6i.large":  {cpu: 2, memory: 8, price: 0.096},
+		"m6a.large":  {cpu: 2, memory: 8, price: 0.0864},
+	}
+	state := &NodePoolState{Name: "default", CurrentNodes: 4, CapacityType: "on-demand", InstanceTypes: []string{"m5.2xlarge"}}
+	base := recommender.NodePoolCapacityRecommendation{
+		NodePoolName:             "default",
+		CurrentNodes:             4,
+		CurrentCPUUsed:           10,
+		CurrentMemoryUsed:        40,
+		CurrentCost:              1.536,
+		RecommendedInstanceTypes: []string{"m6a.large", "m6i.large"},
+	}
+
+	candidates := generateCandidates(context.Background(), pricer, state, base)
+	// 2 mixes x 2 capacity types x 3 headrooms, all cheaper than 4 x m5.2xlarge on-demand
+	require.Len(t, candidates, 12)
+	for _, c := range candidates {
+		o := c.objectives
+		assert.Less(t, o.HourlyCost, base.CurrentCost)
+		assert.GreaterOrEqual(t, c.rec.RecommendedTotalCPU, base.CurrentCPUUsed*(1+o.HeadroomPercent/100))
+		assert.GreaterOrEqual(t, c.rec.RecommendedTotalMemory, base.CurrentMemoryUsed*(1+o.HeadroomPercent/100))
+		if c.rec.CapacityType == "on-demand" && sameTypes(c.rec.RecommendedInstanceTypes, state.InstanceTypes) {
+			assert.Less(t, o.Disruptions, state.CurrentNodes, "right-sizing in place only removes nodes")
+		} else {
+			assert.Equal(t, state.CurrentNodes, o.Disruptions)
+		}
+	}
+
+	front := paretoFront(candidates)
+	require.NotEmpty(t, front)
+	assert.Less(t, len(front), len(candidates))
+	for i, a := range front {
+		for j, b := range front {
+			if i != j {
+				assert.False(t, dominates(a.objectives, b.objectives), "front members must not dominate each other")
+			}
+		}
+		if i > 0 {
+			assert.GreaterOrEqual(t, a.objectives.HourlyCost, front[i-1].objectives.HourlyCost, "cheapest first")
+		}
+	}
+
+	// The cheapest option (spot) and a zero-risk option (on-demand) are both on the front
+	assert.Equal(t, "spot", front[0].rec.CapacityType)
+	hasOnDemand := false
+	for _, c := range front {
+		hasOnDemand = hasOnDemand || c.objectives.InterruptionRisk == 0
+	}
+	assert.True(t, hasOnDemand)
+
+	// Only cost counts: the cheapest plan is recommended; only risk counts: an on-demand plan is
+	assert.Equal(t, 0, scoreCandidates(front, ParetoWeights{Cost: 1}))
+	assert.Equal(t, 0.0, front[scoreCandidates(front, ParetoWeights{Risk: 1})].objectives.InterruptionRisk)
+	assert.Equal(t, -1, scoreCandidates(nil, DefaultParetoWeights))
+}
+
+func TestDominates(t *testing.T) {
+	base := PlanObjectives{HourlyCost: 1, InterruptionRisk: 0.5, BinPackingEfficiency: 0.7, Disruptions: 3}
+	cheaper := base
+	cheaper.HourlyCost = 0.8
+	riskier := cheaper
+	riskier.InterruptionRisk = 1
+
+	assert.True(t, dominates(cheaper, base))
+	assert.False(t, dominates(base, cheaper))
+	assert.False(t, dominates(base, base), "equal candidates do not dominate")
+	assert.False(t, dominates(riskier, base), "a trade-off is not dominance")
+	assert.False(t, dominates(base, riskier))
+}
+
+func TestProposeParetoSetKeepsAllCandidates(t *testing.T) {
+	now := time.Now()
+	store := NewPlanStore("", 0)
+	set := []*OptimizationPlan{
+		{ID: "p-0", NodePoolName: "default", Strategy: StrategyPareto, CreatedAt: now},
+		{ID: "p-1", NodePoolName: "default", Strategy: StrategyPareto, CreatedAt: now},
+	}
+	require.NoError(t, store.Propose(set, now))
+	plans, err := store.List(PlanFilter{Status: PlanStatusProposed})
+	require.NoError(t, err)
+	assert.Len(t, plans, 2)
+
+	// A later set supersedes the earlier one
+	require.NoError(t, store.Propose([]*OptimizationPlan{{ID: "p-2", NodePoolName: "default", Strategy: StrategyPareto, CreatedAt: now}}, now))
+	plans, err = store.List(PlanFilter{Status: PlanStatusExpired})
+	require.NoError(t, err)
+	assert.Len(t, plans, 2)
+}
@@ -0,0 +1,385 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+)
+
+// PlanStatus is the lifecycle state of an optimization plan
+type PlanStatus string
+
+const (
+	PlanStatusProposed   PlanStatus = "proposed"    // Generated, awaiting review
+	PlanStatusApproved   PlanStatus = "approved"    // Approved for applying
+	PlanStatusRejected   PlanStatus = "rejected"    // Rejected by a reviewer
+	PlanStatusApplied    PlanStatus = "applied"     // Changes applied to the NodePool
+	PlanStatusVerified   PlanStatus = "verified"    // Outcome measured after applying
+	PlanStatusRolledBack PlanStatus = "rolled-back" // Applied changes were reverted
+	PlanStatusExpired    PlanStatus = "expired"     // NodePool changed (or a newer plan superseded it) before it was applied
+)
+
+// planTransitions lists the statuses each status may move to
+var planTransitions = map[PlanStatus][]PlanStatus{
+	PlanStatusProposed: {PlanStatusApproved, PlanStatusRejected, PlanStatusExpired},
+	PlanStatusApproved: {PlanStatusApplied, PlanStatusRejected, PlanStatusExpired},
+	PlanStatusApplied:  {PlanStatusVerified, PlanStatusRolledBack},
+	PlanStatusVerified: {PlanStatusRolledBack},
+}
+
+// PlanTransition records a status change of a plan
+type PlanTransition struct {
+	From    PlanStatus `json:"from,omitempty"`
+	To      PlanStatus `json:"to"`
+	Actor   string     `json:"actor"` // Approver identity, or "agent"/"verifier" for automatic changes
+	Comment string     `json:"comment,omitempty"`
+	At      time.Time  `json:"at"`
+}
+
+var (
+	// ErrPlanNotFound is returned for unknown plan IDs
+	ErrPlanNotFound = errors.New("plan not found")
+	// ErrInvalidPlanTransition is returned when a plan cannot move to the requested status
+	ErrInvalidPlanTransition = errors.New("invalid plan status transition")
+	// ErrPlanConflict is returned when a plan's NodePool changed or another plan was just applied to it
+	ErrPlanConflict = errors.New("plan conflicts with its NodePool")
+)
+
+// CanTransition reports whether a plan in status from may move to status to
+func CanTransition(from, to PlanStatus) bool {
+	for _, allowed := range planTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// NodePoolFingerprint identifies the NodePool spec a plan was computed against;
+// it changes whenever the NodePool's spec (generation) or parsed settings change
+func NodePoolFingerprint(np kubernetes.NodePoolInfo) string {
+	data, _ := json.Marshal(struct {
+		Generation          int64
+		InstanceTypes       []string
+		CapacityType        string
+		Architecture        string
+		Requirements        map[string]string
+		Taints              []kubernetes.Taint
+		MinSize, MaxSize    int
+		ConsolidationPolicy string
+		ConsolidateAfter    string
+		ExpireAfter         string
+	}{np.Generation, np.InstanceTypes, np.CapacityType, np.Architecture, np.Requirements, np.Taints,
+		np.MinSize, np.MaxSize, np.ConsolidationPolicy, np.ConsolidateAfter, np.ExpireAfter})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// PlanFilter selects plans to list
+type PlanFilter struct {
+	NodePool string
+	Status   PlanStatus
+}
+
+// PlanStore persists optimization plans and their lifecycle in a JSON file. Like
+// FileHistoryStore, updates lock a sibling .lock file and re-read the file, so
+// replicas sharing a volume see each other's plans.
+type PlanStore struct {
+	path     string
+	maxPlans int
+
+	mu    sync.Mutex
+	plans []*OptimizationPlan // Used when path is "" (memory only)
+}
+
+// NewPlanStore creates a plan store ("" keeps plans in memory). Only the newest
+// maxPlans plans are kept; open (proposed/approved/applied) plans are never dropped.
+func NewPlanStore(path string, maxPlans int) *PlanStore {
+	if maxPlans <= 0 {
+		maxPlans = 500
+	}
+	return &PlanStore{path: path, maxPlans: maxPlans}
+}
+
+// List returns plans matching filter, newest first
+func (s *PlanStore) List(filter PlanFilter) ([]*OptimizationPlan, error) {
+	plans, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*OptimizationPlan, 0, len(plans))
+	for _, plan := range plans {
+		if filter.NodePool != "" && plan.NodePoolName != filter.NodePool {
+			continue
+		}
+		if filter.Status != "" && plan.Status != filter.Status {
+			continue
+		}
+		result = append(result, plan)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result, nil
+}
+
+// Get returns a plan by ID
+func (s *PlanStore) Get(id string) (*OptimizationPlan, error) {
+	plans, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, plan := range plans {
+		if plan.ID == id {
+			return plan, nil
+		}
+	}
+	return nil, ErrPlanNotFound
+}
+
+// Propose stores newly generated plans as proposed. Earlier proposed plans for the
+// same NodePool and strategy are expired as superseded.
+func (s *PlanStore) Propose(newPlans []*OptimizationPlan, now time.Time) error {
+	if len(newPlans) == 0 {
+		return nil
+	}
+	return s.update(func(plans []*OptimizationPlan) ([]*OptimizationPlan, error) {
+		// Plans proposed together (e.g. a Pareto set) do not supersede each other
+		batch := make(map[string]bool, len(newPlans))
+		for _, plan := range newPlans {
+			batch[plan.ID] = true
+		}
+		for _, plan := range newPlans {
+			for _, existing := range plans {
+				if existing.Status == PlanStatusProposed && !batch[existing.ID] && existing.NodePoolName == plan.NodePoolName && existing.Strategy == plan.Strategy {
+					transition(existing, PlanStatusExpired, "agent", "superseded by "+plan.ID, now)
+				}
+			}
+			plan.Status = PlanStatusProposed
+			plan.Transitions = []PlanTransition{{To: PlanStatusProposed, Actor: "agent", At: now}}
+			plan.UpdatedAt = now
+			plans = append(plans, plan)
+		}
+		return plans, nil
+	})
+}
+
+// Transition moves a plan to a new status, recording who did it and why
+func (s *PlanStore) Transition(id string, to PlanStatus, actor, comment string, now time.Time) (*OptimizationPlan, error) {
+	var updated *OptimizationPlan
+	err := s.update(func(plans []*OptimizationPlan) ([]*OptimizationPlan, error) {
+		for _, plan := range plans {
+			if plan.ID != id {
+				continue
+			}
+			if !CanTransition(plan.Status, to) {
+				return nil, fmt.Errorf("%w: plan %s is %s and cannot become %s", ErrInvalidPlanTransition, id, plan.Status, to)
+			}
+			transition(plan, to, actor, comment, now)
+			updated = plan
+			return plans, nil
+		}
+		return nil, ErrPlanNotFound
+	})
+	return updated, err
+}
+
+// MarkApplied moves an approved plan to applied, keeping the patch that reverts it, and
+// expires the other proposed and approved plans for its NodePool: they were computed
+// against the spec the plan replaced
+func (s *PlanStore) MarkApplied(id, actor, comment string, now time.Time, revertPatch map[string]interface{}) (*OptimizationPlan, error) {
+	var updated *OptimizationPlan
+	err := s.update(func(plans []*OptimizationPlan) ([]*OptimizationPlan, error) {
+		for _, plan := range plans {
+			if plan.ID != id {
+				continue
+			}
+			if !CanTransition(plan.Status, PlanStatusApplied) {
+				return nil, fmt.Errorf("%w: plan %s is %s and cannot become %s", ErrInvalidPlanTransition, id, plan.Status, PlanStatusApplied)
+			}
+			transition(plan, PlanStatusApplied, actor, comment, now)
+			plan.RevertPatch = revertPatch
+			updated = plan
+		}
+		if updated == nil {
+			return nil, ErrPlanNotFound
+		}
+		for _, plan := range plans {
+			if plan.ID != id && plan.NodePoolName == updated.NodePoolName && (plan.Status == PlanStatusProposed || plan.Status == PlanStatusApproved) {
+				transition(plan, PlanStatusExpired, "agent", "superseded by applied plan "+id, now)
+			}
+		}
+		return plans, nil
+	})
+	return updated, err
+}
+
+// AppliedAt returns when the plan was last applied, or the zero time
+func (p *OptimizationPlan) AppliedAt() time.Time {
+	for i := len(p.Transitions) - 1; i >= 0; i-- {
+		if p.Transitions[i].To == PlanStatusApplied {
+			return p.Transitions[i].At
+		}
+	}
+	return time.Time{}
+}
+
+// ExpireChanged expires proposed and approved plans whose NodePool no longer exists
+// or has changed since the plan was created. fingerprints maps NodePool name to its
+// current NodePoolFingerprint. It returns the number of plans expired.
+func (s *PlanStore) ExpireChanged(fingerprints map[string]string, now time.Time) (int, error) {
+	expired := 0
+	err := s.update(func(plans []*OptimizationPlan) ([]*OptimizationPlan, error) {
+		for _, plan := range plans {
+			if reason := plan.StaleReason(fingerprints); reason != "" {
+				transition(plan, PlanStatusExpired, "agent", reason, now)
+				expired++
+			}
+		}
+		return plans, nil
+	})
+	return expired, err
+}
+
+// StaleReason returns why a proposed or approved plan no longer matches its NodePool, or "".
+// fingerprints maps NodePool name to its current NodePoolFingerprint.
+func (p *OptimizationPlan) StaleReason(fingerprints map[string]string) string {
+	if p.Status != PlanStatusProposed && p.Status != PlanStatusApproved {
+		return ""
+	}
+	current, ok := fingerprints[p.NodePoolName]
+	switch {
+	case !ok:
+		return "NodePool no longer exists"
+	case p.NodePoolFingerprint != "" && current != p.NodePoolFingerprint:
+		return "NodePool changed since the plan was created"
+	}
+	return ""
+}
+
+func transition(plan *OptimizationPlan, to PlanStatus, actor, comment string, now time.Time) {
+	plan.Transitions = append(plan.Transitions, PlanTransition{From: plan.Status, To: to, Actor: actor, Comment: comment, At: now})
+	plan.Status = to
+	plan.UpdatedAt = now
+}
+
+// isOpenPlan reports whether a plan may still change state through review or verification
+func isOpenPlan(plan *OptimizationPlan) bool {
+	return plan.Status == PlanStatusProposed || plan.Status == PlanStatusApproved || plan.Status == PlanStatusApplied
+}
+
+// prunePlans drops the oldest closed plans beyond maxPlans
+func prunePlans(plans []*OptimizationPlan, maxPlans int) []*OptimizationPlan {
+	excess := len(plans) - maxPlans
+	if excess <= 0 {
+		return plans
+	}
+	sort.SliceStable(plans, func(i, j int) bool { return plans[i].CreatedAt.Before(plans[j].CreatedAt) })
+	kept := make([]*OptimizationPlan, 0, len(plans))
+	for _, plan := range plans {
+		if excess > 0 && !isOpenPlan(plan) {
+			excess--
+			continue
+		}
+		kept = append(kept, plan)
+	}
+	return kept
+}
+
+func (s *PlanStore) load() ([]*OptimizationPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path == "" {
+		return clonePlans(s.plans)
+	}
+	return s.read()
+}
+
+// update applies fn to the latest plans and persists the result; if fn fails nothing is written
+func (s *PlanStore) update(fn func([]*OptimizationPlan) ([]*OptimizationPlan, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path == "" {
+		plans, err := clonePlans(s.plans)
+		if err != nil {
+			return err
+		}
+		plans, err = fn(plans)
+		if err != nil {
+			return err
+		}
+		s.plans = prunePlans(plans, s.maxPlans)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock plans: %w", err)
+	}
+	defer unlock()
+
+	plans, err := s.read()
+	if err != nil {
+		return err
+	}
+	plans, err = fn(plans)
+	if err != nil {
+		return err
+	}
+	plans = prunePlans(plans, s.maxPlans)
+
+	data, err := json.MarshalIndent(plans, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plans: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write plans: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write plans: %w", err)
+	}
+	return nil
+}
+
+func (s *PlanStore) read() ([]*OptimizationPlan, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var plans []*OptimizationPlan
+	if err := json.Unmarshal(data, &plans); err != nil {
+		return nil, fmt.Errorf("failed to parse plans: %w", err)
+	}
+	return plans, nil
+}
+
+// clonePlans deep-copies in-memory plans so callers cannot modify the store
+func clonePlans(plans []*OptimizationPlan) ([]*OptimizationPlan, error) {
+	if len(plans) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(plans)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy plans: %w", err)
+	}
+	var clone []*OptimizationPlan
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, fmt.Errorf("failed to copy plans: %w", err)
+	}
+	return clone, nil
+}
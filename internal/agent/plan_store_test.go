@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanStoreLifecycle(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "plans.json")
+	store := NewPlanStore(path, 0)
+
+	defaultPool := kubernetes.NodePoolInfo{Name: "default", Generation: 3, CapacityType: "on-demand"}
+	batchPool := kubernetes.NodePoolInfo{Name: "batch", Generation: 1}
+	require.NoError(t, store.Propose([]*OptimizationPlan{
+		{ID: "plan-a", NodePoolName: "default", Strategy: StrategyBalanced, CreatedAt: now, NodePoolFingerprint: NodePoolFingerprint(defaultPool)},
+		{ID: "plan-b", NodePoolName: "batch", Strategy: StrategyBalanced, CreatedAt: now, NodePoolFingerprint: NodePoolFingerprint(batchPool)},
+	}, now))
+
+	plan, err := store.Get("plan-a")
+	require.NoError(t, err)
+	assert.Equal(t, PlanStatusProposed, plan.Status)
+
+	// Approve, then apply and verify
+	plan, err = store.Transition("plan-a", PlanStatusApproved, "alice", "looks good", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, PlanStatusApproved, plan.Status)
+	_, err = store.Transition("plan-a", PlanStatusApplied, "alice", "", now.Add(2*time.Minute))
+	require.NoError(t, err)
+	_, err = store.Transition("plan-a", PlanStatusVerified, "verifier", "", now.Add(3*time.Minute))
+	require.NoError(t, err)
+
+	// Invalid transitions and unknown plans are reported as such
+	_, err = store.Transition("plan-a", PlanStatusApproved, "bob", "", now)
+	assert.True(t, errors.Is(err, ErrInvalidPlanTransition))
+	_, err = store.Transition("plan-x", PlanStatusApproved, "bob", "", now)
+	assert.True(t, errors.Is(err, ErrPlanNotFound))
+
+	// A plan store on the same file (another replica) sees the full history
+	reloaded, err := NewPlanStore(path, 0).Get("plan-a")
+	require.NoError(t, err)
+	assert.Equal(t, PlanStatusVerified, reloaded.Status)
+	require.Len(t, reloaded.Transitions, 4)
+	assert.Equal(t, PlanTransition{From: PlanStatusProposed, To: PlanStatusApproved, Actor: "alice", Comment: "looks good", At: now.Add(time.Minute)}, reloaded.Transitions[1])
+
+	// A newer plan for the same NodePool and strategy supersedes a proposed one
+	require.NoError(t, store.Propose([]*OptimizationPlan{
+		{ID: "plan-c", NodePoolName: "batch", Strategy: StrategyBalanced, CreatedAt: now.Add(time.Hour), NodePoolFingerprint: NodePoolFingerprint(batchPool)},
+	}, now.Add(time.Hour)))
+	plan, err = store.Get("plan-b")
+	require.NoError(t, err)
+	assert.Equal(t, PlanStatusExpired, plan.Status)
+
+	history, err := store.List(PlanFilter{NodePool: "batch"})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "plan-c", history[0].ID, "newest first")
+}
+
+func TestPlanStoreExpireChanged(t *testing.T) {
+	now := time.Now()
+	store := NewPlanStore("", 0)
+
+	pool := kubernetes.NodePoolInfo{Name: "default", Generation: 1}
+	require.NoError(t, store.Propose([]*OptimizationPlan{
+		{ID: "unchanged", NodePoolName: "default", Strategy: StrategyBalanced, CreatedAt: now, NodePoolFingerprint: NodePoolFingerprint(pool)},
+		{ID: "changed", NodePoolName: "default", Strategy: StrategySpotFirst, CreatedAt: now, NodePoolFingerprint: NodePoolFingerprint(pool)},
+		{ID: "deleted", NodePoolName: "gone", Strategy: StrategyBalanced, CreatedAt: now, NodePoolFingerprint: "abc"},
+	}, now))
+	_, err := store.Transition("changed", PlanStatusApproved, "alice", "", now)
+	require.NoError(t, err)
+
+	// Unchanged NodePool: nothing expires
+	expired, err := store.ExpireChanged(map[string]string{"default": NodePoolFingerprint(pool), "gone": "abc"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, expired)
+
+	// Spec change (new generation) and NodePool deletion expire open plans
+	pool.Generation = 2
+	pool.ConsolidateAfter = "5m"
+	expired, err = store.ExpireChanged(map[string]string{"default": NodePoolFingerprint(pool)}, now)
+	require.NoError(t, err)
+	assert.Equal(t, 3, expired)
+
+	plans, err := store.List(PlanFilter{Status: PlanStatusExpired})
+	require.NoError(t, err)
+	assert.Len(t, plans, 3)
+	_, err = store.Transition("changed", PlanStatusApplied, "alice", "", now)
+	assert.True(t, errors.Is(err, ErrInvalidPlanTransition), "expired plans cannot be applied")
+}
+
+func TestPrunePlans(t *testing.T) {
+	now := time.Now()
+	store := NewPlanStore("", 2)
+	for i, status := range []PlanStatus{PlanStatusRejected, PlanStatusApproved, PlanStatusRejected} {
+		plan := &OptimizationPlan{ID: string(rune('a' + i)), NodePoolName: string(rune('a' + i)), CreatedAt: now.Add(time.Duration(i) * time.Minute)}
+		require.NoError(t, store.Propose([]*OptimizationPlan{plan}, now))
+		_, err := store.Transition(plan.ID, status, "alice", "", now)
+		require.NoError(t, err)
+	}
+
+	plans, err := store.List(PlanFilter{})
+	require.NoError(t, err)
+	ids := make([]string, 0, len(plans))
+	for _, plan := range plans {
+		ids = append(ids, plan.ID)
+	}
+	// The oldest closed plan is dropped; the open (approved) one is kept
+	assert.Equal(t, []string{"c", "b"}, ids)
+}
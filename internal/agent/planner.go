@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 	
 	"github.com/karpenter-optimizer/internal/kubernetes"
@@ -37,6 +38,52 @@ func NewPlanner(rec *recommender.Recommender) *Planner {
 	return p
 }
 
+// BuiltInStrategies returns the strategies every planner provides
+func BuiltInStrategies() []OptimizationStrategy {
+	return []OptimizationStrategy{StrategyAggressive, StrategyBalanced, StrategyConservative, StrategySpotFirst, StrategyRightSize}
+}
+
+// RegisterStrategy adds a strategy selectable by its name. Strategies are registered
+// at startup, before the planner is used; built-in strategies cannot be replaced.
+func (p *Planner) RegisterStrategy(strategy Strategy) error {
+	name := OptimizationStrategy(strategy.GetName())
+	if _, exists := p.strategies[name]; exists {
+		return fmt.Errorf("strategy %q is already registered", name)
+	}
+	p.strategies[name] = &StrategyWrapper{strategy: strategy}
+	return nil
+}
+
+// HasStrategy reports whether a strategy is registered
+func (p *Planner) HasStrategy(name OptimizationStrategy) bool {
+	_, ok := p.strategies[name]
+	return ok
+}
+
+// Strategies returns the registered strategy names, built-ins first, then user-defined ones by name
+func (p *Planner) Strategies() []OptimizationStrategy {
+	names := BuiltInStrategies()
+	custom := make([]OptimizationStrategy, 0, len(p.strategies)-len(names))
+	for name, wrapper := range p.strategies {
+		if _, ok := wrapper.strategy.(*CustomStrategy); ok {
+			custom = append(custom, name)
+		}
+	}
+	sort.Slice(custom, func(i, j int) bool { return custom[i] < custom[j] })
+	return append(names, custom...)
+}
+
+// CustomStrategies returns the definitions of the user-defined strategies
+func (p *Planner) CustomStrategies() []StrategyDefinition {
+	var definitions []StrategyDefinition
+	for _, name := range p.Strategies() {
+		if custom, ok := p.strategies[name].strategy.(*CustomStrategy); ok {
+			definitions = append(definitions, custom.Definition())
+		}
+	}
+	return definitions
+}
+
 // PlanOptimization creates an optimization plan for a NodePool
 func (p *Planner) PlanOptimization(ctx context.Context, analysis *AnalysisResult, strategy OptimizationStrategy, np kubernetes.NodePoolInfo) (*OptimizationPlan, error) {
 	// Select strategy
@@ -53,7 +100,7 @@ func (p *Planner) PlanOptimization(ctx context.Context, analysis *AnalysisResult
 	}
 	
 	// Calculate risk level
-	riskLevel := p.calculateRiskLevel(analysis, recommendations)
+	riskLevel := calculateRiskLevel(analysis, recommendations)
 	
 	// Calculate estimated savings
 	estimatedSavings := 0.0
@@ -62,7 +109,7 @@ func (p *Planner) PlanOptimization(ctx context.Context, analysis *AnalysisResult
 	}
 	
 	plan := &OptimizationPlan{
-		ID:               fmt.Sprintf("plan-%d", time.Now().Unix()),
+		ID:               fmt.Sprintf("plan-%s-%d", analysis.NodePoolState.Name, time.Now().UnixNano()),
 		NodePoolName:    analysis.NodePoolState.Name,
 		Strategy:         strategy,
 		CurrentState:     analysis.NodePoolState,
@@ -71,13 +118,14 @@ func (p *Planner) PlanOptimization(ctx context.Context, analysis *AnalysisResult
 		EstimatedSavings: estimatedSavings,
 		Confidence:       analysis.Confidence,
 		CreatedAt:        time.Now(),
+		NodePoolFingerprint: NodePoolFingerprint(np),
 	}
 	
 	return plan, nil
 }
 
 // calculateRiskLevel calculates the risk level of an optimization plan
-func (p *Planner) calculateRiskLevel(analysis *AnalysisResult, recommendations []recommender.NodePoolCapacityRecommendation) string {
+func calculateRiskLevel(analysis *AnalysisResult, recommendations []recommender.NodePoolCapacityRecommendation) string {
 	riskScore := 0
 	
 	// Check if converting to spot
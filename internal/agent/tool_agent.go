@@ -0,0 +1,304 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/karpenter-optimizer/internal/ollama"
+	"github.com/karpenter-optimizer/internal/recommender"
+)
+
+// DefaultToolAgentMaxSteps is how many LLM calls a tool agent run may make
+const DefaultToolAgentMaxSteps = 8
+
+// maxToolResultBytes caps a tool result sent back to the LLM
+const maxToolResultBytes = 16 * 1024
+
+// ErrStepBudgetExhausted is returned when a run uses all its steps without a plan
+var ErrStepBudgetExhausted = errors.New("step budget exhausted before the agent produced a plan")
+
+// ToolChatClient is the LLM driving a tool agent (implemented by ollama.Client)
+type ToolChatClient interface {
+	ChatWithTools(ctx context.Context, messages []ollama.Message, tools []ollama.Tool) (*ollama.Message, error)
+}
+
+// AgentTool is a function the LLM may call during a tool agent run
+type AgentTool struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{} // JSON Schema of the arguments object
+	Run         func(ctx context.Context, args json.RawMessage) (interface{}, error)
+}
+
+// ToolAgentPlan is the plan a tool agent run ends with
+type ToolAgentPlan struct {
+	Summary    string                     `json:"summary"`
+	Changes    []ToolAgentChange          `json:"changes"`
+	Rejections []recommender.LLMRejection `json:"rejections,omitempty"` // Plan values that failed validation and were dropped
+}
+
+// ToolAgentChange is one NodePool change of a tool agent plan
+type ToolAgentChange struct {
+	NodePoolName           string   `json:"nodePoolName"`
+	InstanceTypes          []string `json:"instanceTypes"`
+	CapacityType           string   `json:"capacityType"`
+	NodeCount              int      `json:"nodeCount"`
+	EstimatedHourlySavings float64  `json:"estimatedHourlySavings"`
+	Reasoning              string   `json:"reasoning"`
+}
+
+// TranscriptEntry is one message of a tool agent run
+type TranscriptEntry struct {
+	Step       int               `json:"step"` // LLM call the entry belongs to (0 = initial prompt)
+	Role       string            `json:"role"` // system, user, assistant or tool
+	Content    string            `json:"content,omitempty"`
+	ToolCalls  []ollama.ToolCall `json:"toolCalls,omitempty"`
+	ToolName   string            `json:"toolName,omitempty"`
+	ToolCallID string            `json:"toolCallId,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	DurationMs int64             `json:"durationMs,omitempty"` // LLM call or tool run time
+	At         time.Time         `json:"at"`
+}
+
+// ToolAgentRun is the result and full transcript of a tool agent run
+type ToolAgentRun struct {
+	ID              string            `json:"id"`
+	Goal            string            `json:"goal"`
+	Plan            *ToolAgentPlan    `json:"plan,omitempty"`
+	Steps           int               `json:"steps"`
+	MaxSteps        int               `json:"maxSteps"`
+	ToolCalls       int               `json:"toolCalls"`
+	BudgetExhausted bool              `json:"budgetExhausted"`
+	Error           string            `json:"error,omitempty"`
+	Transcript      []TranscriptEntry `json:"transcript"`
+	TranscriptFile  string            `json:"transcriptFile,omitempty"`
+	StartedAt       time.Time         `json:"startedAt"`
+	FinishedAt      time.Time         `json:"finishedAt"`
+}
+
+// ToolAgent lets an LLM call the optimizer's tools until it produces a plan
+type ToolAgent struct {
+	client        ToolChatClient
+	tools         map[string]AgentTool
+	specs         []ollama.Tool
+	maxSteps      int
+	transcriptDir string
+	debug         bool
+}
+
+// NewToolAgent creates a tool agent; maxSteps <= 0 uses DefaultToolAgentMaxSteps
+func NewToolAgent(client ToolChatClient, tools []AgentTool, maxSteps int) *ToolAgent {
+	if maxSteps <= 0 {
+		maxSteps = DefaultToolAgentMaxSteps
+	}
+	a := &ToolAgent{
+		client:   client,
+		tools:    make(map[string]AgentTool, len(tools)),
+		maxSteps: maxSteps,
+	}
+	for _, tool := range tools {
+		a.tools[tool.Name] = tool
+		a.specs = append(a.specs, ollama.NewFunctionTool(tool.Name, tool.Description, tool.Parameters))
+	}
+	return a
+}
+
+// SetTranscriptDir makes every run write its transcript to <dir>/<run id>.json
+func (a *ToolAgent) SetTranscriptDir(dir string) {
+	a.transcriptDir = dir
+}
+
+// SetDebug logs every transcript entry as it happens
+func (a *ToolAgent) SetDebug(debug bool) {
+	a.debug = debug
+}
+
+// Run asks the LLM to reach goal, running the tools it calls, until it answers with
+// a plan or the step budget is used. The run is returned with its transcript even
+// when it fails.
+func (a *ToolAgent) Run(ctx context.Context, goal string) (*ToolAgentRun, error) {
+	run := &ToolAgentRun{
+		ID:         fmt.Sprintf("run-%d", time.Now().UnixNano()),
+		Goal:       goal,
+		MaxSteps:   a.maxSteps,
+		Transcript: make([]TranscriptEntry, 0),
+		StartedAt:  time.Now(),
+	}
+	messages := []ollama.Message{
+		{Role: "system", Content: toolAgentSystemPrompt},
+		{Role: "user", Content: goal},
+	}
+	for _, m := range messages {
+		a.record(run, TranscriptEntry{Role: m.Role, Content: m.Content})
+	}
+
+	var runErr error
+	for run.Plan == nil && run.Steps < a.maxSteps {
+		run.Steps++
+		started := time.Now()
+		reply, err := a.client.ChatWithTools(ctx, messages, a.specs)
+		if err != nil {
+			runErr = fmt.Errorf("LLM call failed at step %d: %w", run.Steps, err)
+			a.record(run, TranscriptEntry{Step: run.Steps, Role: "assistant", Error: err.Error(), DurationMs: time.Since(started).Milliseconds()})
+			break
+		}
+		reply.Role = "assistant"
+		for i := range reply.ToolCalls {
+			if reply.ToolCalls[i].ID == "" { // Ollama does not number calls
+				reply.ToolCalls[i].ID = fmt.Sprintf("call-%d-%d", run.Steps, i)
+			}
+			reply.ToolCalls[i].Type = "function"
+		}
+		messages = append(messages, *reply)
+		a.record(run, TranscriptEntry{Step: run.Steps, Role: "assistant", Content: reply.Content, ToolCalls: reply.ToolCalls, DurationMs: time.Since(started).Milliseconds()})
+
+		if len(reply.ToolCalls) == 0 {
+			plan, err := parseToolAgentPlan(reply.Content)
+			if err == nil {
+				run.Plan = plan
+				break
+			}
+			retry := ollama.Message{Role: "user", Content: fmt.Sprintf("That is not a valid plan (%v). Call more tools if you need data, or reply with only the JSON plan in the required format.", err)}
+			messages = append(messages, retry)
+			a.record(run, TranscriptEntry{Step: run.Steps, Role: retry.Role, Content: retry.Content})
+			continue
+		}
+
+		for _, call := range reply.ToolCalls {
+			run.ToolCalls++
+			started := time.Now()
+			result, err := a.callTool(ctx, call)
+			entry := TranscriptEntry{Step: run.Steps, Role: "tool", Content: result, ToolName: call.Function.Name, ToolCallID: call.ID, DurationMs: time.Since(started).Milliseconds()}
+			if err != nil {
+				entry.Error = err.Error()
+			}
+			messages = append(messages, ollama.Message{Role: "tool", Content: result, ToolCallID: call.ID, Name: call.Function.Name})
+			a.record(run, entry)
+		}
+	}
+
+	if runErr == nil && run.Plan == nil {
+		run.BudgetExhausted = true
+		runErr = ErrStepBudgetExhausted
+	}
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+	run.FinishedAt = time.Now()
+	if err := a.writeTranscript(run); err != nil {
+		fmt.Printf("Warning: Failed to write tool agent transcript: %v\n", err)
+	}
+	return run, runErr
+}
+
+// callTool runs a tool call and returns the JSON result for the LLM. Failures are
+// returned to the LLM as {"error": ...} so it can correct itself.
+func (a *ToolAgent) callTool(ctx context.Context, call ollama.ToolCall) (string, error) {
+	tool, ok := a.tools[call.Function.Name]
+	var result interface{}
+	var err error
+	switch {
+	case !ok:
+		err = fmt.Errorf("unknown tool %q", call.Function.Name)
+	case !json.Valid([]byte(call.Function.Arguments)):
+		err = fmt.Errorf("arguments are not valid JSON: %s", call.Function.Arguments)
+	default:
+		result, err = tool.Run(ctx, json.RawMessage(call.Function.Arguments))
+	}
+	if err != nil {
+		result = map[string]string{"error": err.Error()}
+	}
+
+	data, marshalErr := json.Marshal(result)
+	if marshalErr != nil {
+		err = fmt.Errorf("failed to encode result: %w", marshalErr)
+		data, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	return string(truncateUTF8(data, maxToolResultBytes)), err
+}
+
+// truncateUTF8 cuts data to at most limit bytes without splitting a rune, marking it truncated
+func truncateUTF8(data []byte, limit int) []byte {
+	if len(data) <= limit {
+		return data
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(data[cut]) {
+		cut--
+	}
+	return append(data[:cut:cut], "... (truncated)"...)
+}
+
+func (a *ToolAgent) record(run *ToolAgentRun, entry TranscriptEntry) {
+	entry.At = time.Now()
+	run.Transcript = append(run.Transcript, entry)
+	if !a.debug {
+		return
+	}
+	switch {
+	case entry.Error != "":
+		fmt.Printf("[Agent] %s step %d %s %s error: %s\n", run.ID, entry.Step, entry.Role, entry.ToolName, entry.Error)
+	case len(entry.ToolCalls) > 0:
+		for _, call := range entry.ToolCalls {
+			fmt.Printf("[Agent] %s step %d assistant calls %s(%s)\n", run.ID, entry.Step, call.Function.Name, call.Function.Arguments)
+		}
+	default:
+		fmt.Printf("[Agent] %s step %d %s %s: %s\n", run.ID, entry.Step, entry.Role, entry.ToolName, entry.Content)
+	}
+}
+
+func (a *ToolAgent) writeTranscript(run *ToolAgentRun) error {
+	if a.transcriptDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(a.transcriptDir, 0755); err != nil {
+		return err
+	}
+	run.TranscriptFile = filepath.Join(a.transcriptDir, run.ID+".json")
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(run.TranscriptFile, data, 0644)
+}
+
+// parseToolAgentPlan reads the JSON plan from the LLM's final answer, which may be
+// wrapped in a code fence or surrounded by text
+func parseToolAgentPlan(content string) (*ToolAgentPlan, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object found")
+	}
+	var plan ToolAgentPlan
+	if err := json.Unmarshal([]byte(content[start:end+1]), &plan); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if strings.TrimSpace(plan.Summary) == "" {
+		return nil, fmt.Errorf("summary is required")
+	}
+	for _, change := range plan.Changes {
+		if change.NodePoolName == "" || len(change.InstanceTypes) == 0 {
+			return nil, fmt.Errorf("every change needs nodePoolName and instanceTypes")
+		}
+	}
+	if plan.Changes == nil {
+		plan.Changes = []ToolAgentChange{}
+	}
+	return &plan, nil
+}
+
+const toolAgentSystemPrompt = `You are a Kubernetes cost optimization agent for Karpenter NodePools.
+Use the tools to look up NodePools, node usage, instance pricing, disruptions and Karpenter errors, and run capacity simulations before proposing a change.
+Only use numbers returned by the tools; never guess prices, node counts or usage.
+Prefer changes that keep enough headroom and avoid NodePools with recent Karpenter errors or frequent disruptions.
+When you are done, reply with only a JSON object and no tool calls:
+{"summary": "...", "changes": [{"nodePoolName": "...", "instanceTypes": ["..."], "capacityType": "spot|on-demand", "nodeCount": 0, "estimatedHourlySavings": 0.0, "reasoning": "..."}]}
+Use an empty changes list if no NodePool should change.`
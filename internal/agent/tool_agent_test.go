@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/karpenter-optimizer/internal/ollama"
+	"github.com/karpenter-optimizer/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTools returns a NodePool lister and a pricing tool that fails for unknown types
+func testTools() []AgentTool {
+	return []AgentTool{
+		{
+			Name:        "list_nodepools",
+			Description: "List NodePools",
+			Parameters:  map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+			Run: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				return []map[string]interface{}{{"name": "default", "currentNodes": 4}}, nil
+			},
+		},
+		{
+			Name:        "get_instance_pricing",
+			Description: "Price an instance type",
+			Parameters:  map[string]interface{}{"type": "object", "properties": map[string]interface{}{"instanceType": map[string]interface{}{"type": "string"}}},
+			Run: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				var req struct {
+					InstanceType string `json:"instanceType"`
+				}
+				if err := json.Unmarshal(args, &req); err != nil {
+					return nil, err
+				}
+				if req.InstanceType != "m6i.large" {
+					return nil, fmt.Errorf("unknown instance type %s", req.InstanceType)
+				}
+				return map[string]interface{}{"hourlyPrice": 0.096}, nil
+			},
+		},
+	}
+}
+
+const testPlanAnswer = "Here is the plan:\n```json\n" +
+	`{"summary": "Move default to m6i.large", "changes": [{"nodePoolName": "default", "instanceTypes": ["m6i.large"], "capacityType": "on-demand", "nodeCount": 3, "estimatedHourlySavings": 0.5, "reasoning": "Lower utilization"}]}` +
+	"\n```"
+
+func TestToolAgentRun(t *testing.T) {
+	for _, provider := range []string{"litellm", "ollama"} {
+		t.Run(provider, func(t *testing.T) {
+			server := testutil.NewFakeLLMServer(
+				testutil.ToolCallReply("call-a", "list_nodepools", map[string]interface{}{}),
+				testutil.ToolCallReply("call-b", "get_instance_pricing", map[string]interface{}{"instanceType": "x9.huge"}),
+				testutil.ToolCallReply("call-c", "get_instance_pricing", map[string]interface{}{"instanceType": "m6i.large"}),
+				ollama.Message{Role: "assistant", Content: "I think m6i.large is cheaper."},
+				ollama.Message{Role: "assistant", Content: testPlanAnswer},
+			)
+			defer server.Close()
+			client := ollama.NewClient(server.URL, "test-model", provider, "", false)
+
+			dir := t.TempDir()
+			toolAgent := NewToolAgent(client, testTools(), 10)
+			toolAgent.SetTranscriptDir(dir)
+			run, err := toolAgent.Run(context.Background(), "Reduce cost")
+			require.NoError(t, err)
+
+			require.NotNil(t, run.Plan)
+			assert.Equal(t, "Move default to m6i.large", run.Plan.Summary)
+			require.Len(t, run.Plan.Changes, 1)
+			assert.Equal(t, []string{"m6i.large"}, run.Plan.Changes[0].InstanceTypes)
+			assert.Equal(t, 5, run.Steps)
+			assert.Equal(t, 3, run.ToolCalls)
+			assert.False(t, run.BudgetExhausted)
+
+			// system, user, then per step: assistant + tool results (+ retry prompt)
+			var roles []string
+			for _, entry := range run.Transcript {
+				roles = append(roles, entry.Role)
+			}
+			assert.Equal(t, []string{"system", "user", "assistant", "tool", "assistant", "tool", "assistant", "tool", "assistant", "user", "assistant"}, roles)
+			failed := run.Transcript[5]
+			assert.Equal(t, "get_instance_pricing", failed.ToolName)
+			assert.Contains(t, failed.Error, "unknown instance type x9.huge")
+			assert.JSONEq(t, `{"error": "unknown instance type x9.huge"}`, failed.Content)
+
+			// Tools are advertised and results are sent back for each call
+			requests := server.Requests()
+			require.Len(t, requests, 5)
+			require.Len(t, requests[0].Tools, 2)
+			assert.Equal(t, "list_nodepools", requests[0].Tools[0].Function.Name)
+			last := requests[3].Messages[len(requests[3].Messages)-1]
+			assert.Equal(t, "tool", last.Role)
+			assert.JSONEq(t, `{"hourlyPrice": 0.096}`, last.Content)
+			if provider == "litellm" {
+				assert.Equal(t, "call-c", last.ToolCallID)
+			}
+			assistant := requests[1].Messages[2]
+			require.Len(t, assistant.ToolCalls, 1)
+			assert.Equal(t, "list_nodepools", assistant.ToolCalls[0].Function.Name)
+
+			// The full transcript is written
+			data, err := os.ReadFile(run.TranscriptFile)
+			require.NoError(t, err)
+			var written ToolAgentRun
+			require.NoError(t, json.Unmarshal(data, &written))
+			assert.Len(t, written.Transcript, len(run.Transcript))
+		})
+	}
+}
+
+func TestToolAgentStepBudget(t *testing.T) {
+	server := testutil.NewFakeLLMServer(
+		testutil.ToolCallReply("call-a", "list_nodepools", map[string]interface{}{}),
+		testutil.ToolCallReply("call-b", "list_nodepools", map[string]interface{}{}),
+		testutil.ToolCallReply("call-c", "list_nodepools", map[string]interface{}{}),
+	)
+	defer server.Close()
+	client := ollama.NewClient(server.URL, "test-model", "litellm", "", false)
+
+	run, err := NewToolAgent(client, testTools(), 2).Run(context.Background(), "Reduce cost")
+	assert.ErrorIs(t, err, ErrStepBudgetExhausted)
+	assert.True(t, run.BudgetExhausted)
+	assert.Nil(t, run.Plan)
+	assert.Equal(t, 2, run.Steps)
+	assert.Len(t, server.Requests(), 2)
+
+	// LLM failures end the run with the transcript so far
+	run, err = NewToolAgent(client, testTools(), 5).Run(context.Background(), "Reduce cost")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrStepBudgetExhausted)
+	assert.Equal(t, 2, run.Steps)
+	assert.Contains(t, run.Transcript[len(run.Transcript)-1].Error, "script exhausted")
+}
+
+func TestParseToolAgentPlan(t *testing.T) {
+	plan, err := parseToolAgentPlan(`{"summary": "Nothing to change"}`)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Changes)
+
+	for _, invalid := range []string{"no plan", `{"changes": []}`, `{"summary": "x", "changes": [{"nodePoolName": "default"}]}`, `{"summary": `} {
+		_, err := parseToolAgentPlan(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	assert.Equal(t, "héllo", string(truncateUTF8([]byte("héllo"), 6)))
+	assert.Equal(t, "ab... (truncated)", string(truncateUTF8([]byte("abcdef"), 2)))
+
+	// "é" is two bytes: cutting inside it steps back to the rune start
+	truncated := truncateUTF8([]byte("hé€llo"), 2)
+	assert.Equal(t, "h... (truncated)", string(truncated))
+	truncated = truncateUTF8([]byte("hé€llo"), 5)
+	assert.Equal(t, "hé... (truncated)", string(truncated))
+	assert.True(t, utf8.Valid(truncated))
+}
@@ -14,6 +14,7 @@ const (
 	StrategyConservative  OptimizationStrategy = "conservative"  // Prioritize stability
 	StrategySpotFirst     OptimizationStrategy = "spot-first"    // Prefer spot instances
 	StrategyRightSize     OptimizationStrategy = "right-size"    // Focus on right-sizing
+	StrategyPareto        OptimizationStrategy = "pareto"        // Candidate from a cost-risk Pareto set (see Planner.PlanParetoSet)
 )
 
 // OptimizationPlan represents a planned optimization
@@ -26,9 +27,17 @@ type OptimizationPlan struct {
 	RiskLevel       string                                          `json:"riskLevel"` // "low", "medium", "high"
 	EstimatedSavings float64                                        `json:"estimatedSavings"`
 	Confidence      float64                                         `json:"confidence"` // 0.0 - 1.0
+	RawConfidence   float64                                         `json:"rawConfidence,omitempty"` // Confidence before recalibration against outcomes
 	CreatedAt       time.Time                                       `json:"createdAt"`
 	LearnedFromHistory bool                                        `json:"learnedFromHistory,omitempty"` // Whether this plan was informed by learning
 	LearningInsights   []string                                    `json:"learningInsights,omitempty"`     // Insights from learning
+	NodePoolFingerprint string                                     `json:"nodePoolFingerprint,omitempty"`  // NodePool spec the plan was computed against
+	Objectives      *PlanObjectives                                 `json:"objectives,omitempty"`  // Scores of Pareto candidates
+	RevertPatch     map[string]interface{}                          `json:"revertPatch,omitempty"` // Restores the NodePool requirements the applied plan replaced
+	// Lifecycle (see PlanStore)
+	Status          PlanStatus                                      `json:"status,omitempty"`
+	Transitions     []PlanTransition                                `json:"transitions,omitempty"` // Status changes, oldest first
+	UpdatedAt       time.Time                                       `json:"updatedAt,omitempty"`
 }
 
 // NodePoolState represents the current state of a NodePool
@@ -0,0 +1,266 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/recommender"
+)
+
+// NodePoolMeasurement is the observed state of a NodePool used to verify an applied plan
+type NodePoolMeasurement struct {
+	Nodes         int            `json:"nodes"`
+	InstanceTypes []string       `json:"instanceTypes"`
+	CapacityType  string         `json:"capacityType"` // spot, on-demand or mixed
+	HourlyCost    float64        `json:"hourlyCost"`
+	PendingPods   int            `json:"pendingPods"`           // Pending pods this NodePool could run
+	Disruptions   map[string]int `json:"disruptions,omitempty"` // Disruptions since the given time, by reason
+}
+
+// NodePoolMeasurer measures a NodePool's current state
+type NodePoolMeasurer interface {
+	MeasureNodePool(ctx context.Context, nodePool string, since time.Time) (*NodePoolMeasurement, error)
+}
+
+// ClusterMeasurer measures NodePools from the cluster, pricing nodes like the analyzer does
+type ClusterMeasurer struct {
+	analyzer  *Analyzer
+	k8sClient *kubernetes.Client
+}
+
+// NewClusterMeasurer creates a measurer backed by the Kubernetes client
+func NewClusterMeasurer(rec *recommender.Recommender, k8sClient *kubernetes.Client) *ClusterMeasurer {
+	return &ClusterMeasurer{analyzer: NewAnalyzer(rec, k8sClient), k8sClient: k8sClient}
+}
+
+// MeasureNodePool returns node count, instance types, capacity type, hourly cost,
+// pending pods and disruptions since the given time for a NodePool
+func (m *ClusterMeasurer) MeasureNodePool(ctx context.Context, nodePool string, since time.Time) (*NodePoolMeasurement, error) {
+	// ListNodePools (unlike GetNodePool) includes the NodePool's current nodes
+	nodePools, err := m.k8sClient.ListNodePools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NodePools: %w", err)
+	}
+	var np *kubernetes.NodePoolInfo
+	for i := range nodePools {
+		if nodePools[i].Name == nodePool {
+			np = &nodePools[i]
+			break
+		}
+	}
+	if np == nil {
+		return nil, fmt.Errorf("NodePool %s not found", nodePool)
+	}
+	state := m.analyzer.calculateNodePoolState(ctx, *np)
+	measurement := &NodePoolMeasurement{
+		Nodes:         state.CurrentNodes,
+		InstanceTypes: state.InstanceTypes,
+		CapacityType:  state.CapacityType,
+		HourlyCost:    state.CurrentCost,
+		Disruptions:   make(map[string]int),
+	}
+
+	// Pending pods are informative; a failed diagnosis does not fail the measurement
+	if pending, err := m.k8sClient.DiagnosePendingPods(ctx, ""); err == nil {
+		measurement.PendingPods = countPendingForNodePool(pending, nodePool)
+	} else {
+		fmt.Printf("Warning: Failed to diagnose pending pods for NodePool %s: %v\n", nodePool, err)
+	}
+
+	if !since.IsZero() {
+		hours := int(math.Ceil(time.Since(since).Hours()))
+		if hours < 1 {
+			hours = 1
+		}
+		disruptions, err := m.k8sClient.GetNodeDisruptions(ctx, hours)
+		if err != nil {
+			fmt.Printf("Warning: Failed to get disruptions for NodePool %s: %v\n", nodePool, err)
+		}
+		for _, d := range disruptions {
+			if d.NodePool != nodePool || disruptionTime(d).Before(since) {
+				continue
+			}
+			measurement.Disruptions[kubernetes.ClassifyDisruptionReason(d.Reason, d.Message)]++
+		}
+	}
+
+	return measurement, nil
+}
+
+// countPendingForNodePool counts pending pods the NodePool is compatible with
+func countPendingForNodePool(pending []kubernetes.PendingPodDiagnosis, nodePool string) int {
+	count := 0
+	for _, pod := range pending {
+		for _, eval := range pod.NodePools {
+			if eval.NodePool == nodePool && eval.Compatible {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// disruptionTime returns when a disruption happened (deletion, else last event)
+func disruptionTime(d kubernetes.NodeDisruptionInfo) time.Time {
+	for _, value := range []string{d.DeletedTime, d.DeletionTime, d.LastSeen, d.FirstSeen} {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// OutcomeVerifier measures NodePools after applied plans have settled and records
+// the resulting outcomes, so the learning agent gets data without manual reports
+type OutcomeVerifier struct {
+	learningAgent *LearningAgent
+	measurer      NodePoolMeasurer
+	settlePeriod  time.Duration
+	interval      time.Duration
+	onVerified    func(OptimizationOutcome)
+}
+
+// NewOutcomeVerifier creates a verifier that checks for settled plans every interval
+func NewOutcomeVerifier(learningAgent *LearningAgent, measurer NodePoolMeasurer, settlePeriod, interval time.Duration) *OutcomeVerifier {
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+	return &OutcomeVerifier{
+		learningAgent: learningAgent,
+		measurer:      measurer,
+		settlePeriod:  settlePeriod,
+		interval:      interval,
+	}
+}
+
+// OnVerified registers a callback run for every outcome the verifier records
+func (v *OutcomeVerifier) OnVerified(fn func(OptimizationOutcome)) {
+	v.onVerified = fn
+}
+
+// SettlePeriod returns how long after a plan is applied it is verified
+func (v *OutcomeVerifier) SettlePeriod() time.Duration {
+	return v.settlePeriod
+}
+
+// Run verifies due outcomes every interval until ctx is cancelled
+func (v *OutcomeVerifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(v.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := v.VerifyDue(ctx, time.Now()); err != nil {
+			fmt.Printf("Warning: Outcome verification failed: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CaptureBaseline records the NodePool's current cost and node count on an outcome
+// that has none, so its savings can be computed when it is verified
+func (v *OutcomeVerifier) CaptureBaseline(ctx context.Context, outcome *OptimizationOutcome) error {
+	if outcome.BaselineCost > 0 || outcome.NodePoolName == "" {
+		return nil
+	}
+	measurement, err := v.measurer.MeasureNodePool(ctx, outcome.NodePoolName, time.Time{})
+	if err != nil {
+		return err
+	}
+	outcome.BaselineCost = measurement.HourlyCost
+	outcome.BaselineNodes = measurement.Nodes
+	return nil
+}
+
+// PendingVerification returns applied outcomes that have not been measured yet
+func (v *OutcomeVerifier) PendingVerification() []OptimizationOutcome {
+	var pending []OptimizationOutcome
+	for _, outcome := range v.learningAgent.GetHistory() {
+		if needsVerification(outcome) {
+			pending = append(pending, outcome)
+		}
+	}
+	return pending
+}
+
+// VerifyDue measures every applied outcome whose settle period has passed and
+// records the results. It returns how many outcomes were verified.
+func (v *OutcomeVerifier) VerifyDue(ctx context.Context, now time.Time) (int, error) {
+	v.learningAgent.RefreshIfStale(ctx)
+
+	due := v.PendingVerification()
+	sort.Slice(due, func(i, j int) bool { return due[i].AppliedAt.Before(due[j].AppliedAt) })
+
+	verified := 0
+	var lastErr error
+	for _, outcome := range due {
+		if now.Sub(outcome.AppliedAt) < v.settlePeriod {
+			continue
+		}
+		measurement, err := v.measurer.MeasureNodePool(ctx, outcome.NodePoolName, outcome.AppliedAt)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to measure NodePool %s for plan %s: %w", outcome.NodePoolName, outcome.PlanID, err)
+			continue
+		}
+		applyMeasurement(&outcome, measurement, now)
+		if err := v.learningAgent.RecordOutcome(ctx, outcome); err != nil {
+			lastErr = fmt.Errorf("failed to record outcome for plan %s: %w", outcome.PlanID, err)
+			continue
+		}
+		if v.onVerified != nil {
+			v.onVerified(outcome)
+		}
+		verified++
+	}
+	return verified, lastErr
+}
+
+// needsVerification reports whether an outcome was applied and has no measured
+// results yet (outcomes reported with actual cost or nodes are left as reported)
+func needsVerification(outcome OptimizationOutcome) bool {
+	return !outcome.AppliedAt.IsZero() &&
+		outcome.VerifiedAt == nil &&
+		outcome.UserFeedback != "rejected" &&
+		outcome.ActualCost == 0 && outcome.ActualNodes == 0
+}
+
+// applyMeasurement fills an outcome's actual results and incidents from a measurement
+func applyMeasurement(outcome *OptimizationOutcome, m *NodePoolMeasurement, now time.Time) {
+	outcome.ActualCost = m.HourlyCost
+	outcome.ActualNodes = m.Nodes
+	outcome.ActualInstanceTypes = m.InstanceTypes
+	outcome.ActualCapacityType = m.CapacityType
+	outcome.PendingPods = m.PendingPods
+	outcome.Disruptions = m.Disruptions
+	if outcome.BaselineCost > 0 {
+		outcome.ActualSavings = outcome.BaselineCost - m.HourlyCost
+	}
+
+	var incidents []string
+	if m.PendingPods > 0 {
+		incidents = append(incidents, fmt.Sprintf("%d pod(s) pending that NodePool %s could run", m.PendingPods, outcome.NodePoolName))
+	}
+	if n := m.Disruptions[kubernetes.DisruptionReasonSpotInterruption]; n > 0 {
+		incidents = append(incidents, fmt.Sprintf("%d spot interruption(s) since the plan was applied", n))
+	}
+	outcome.Incidents = append(outcome.Incidents, incidents...)
+
+	switch {
+	case m.PendingPods > 0:
+		outcome.PerformanceImpact = "negative"
+	case len(outcome.Incidents) == 0 && outcome.ActualSavings > 0:
+		outcome.PerformanceImpact = "positive"
+	default:
+		outcome.PerformanceImpact = "neutral"
+	}
+
+	verifiedAt := now
+	outcome.VerifiedAt = &verifiedAt
+}
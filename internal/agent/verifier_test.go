@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMeasurer struct {
+	measurements map[string]*NodePoolMeasurement
+	calls        []string
+}
+
+func (f *fakeMeasurer) MeasureNodePool(ctx context.Context, nodePool string, since time.Time) (*NodePoolMeasurement, error) {
+	f.calls = append(f.calls, nodePool)
+	m := *f.measurements[nodePool]
+	return &m, nil
+}
+
+func TestOutcomeVerifier(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	learning, err := NewLearningAgent(filepath.Join(t.TempDir(), "history.json"))
+	require.NoError(t, err)
+
+	measurer := &fakeMeasurer{measurements: map[string]*NodePoolMeasurement{
+		"default": {Nodes: 4, InstanceTypes: []string{"m6i.large"}, CapacityType: "spot", HourlyCost: 0.6, Disruptions: map[string]int{kubernetes.DisruptionReasonConsolidation: 2}},
+		"batch":   {Nodes: 2, InstanceTypes: []string{"c6i.xlarge"}, CapacityType: "spot", HourlyCost: 0.5, PendingPods: 3, Disruptions: map[string]int{kubernetes.DisruptionReasonSpotInterruption: 1}},
+	}}
+	verifier := NewOutcomeVerifier(learning, measurer, 6*time.Hour, time.Minute)
+
+	for _, outcome := range []OptimizationOutcome{
+		{PlanID: "settled", NodePoolName: "default", Strategy: StrategySpotFirst, AppliedAt: now.Add(-8 * time.Hour), PredictedSavings: 0.5, BaselineCost: 1.0, BaselineNodes: 5},
+		{PlanID: "incidents", NodePoolName: "batch", Strategy: StrategyAggressive, AppliedAt: now.Add(-7 * time.Hour), PredictedSavings: 0.4, BaselineCost: 1.0},
+		{PlanID: "too-recent", NodePoolName: "default", AppliedAt: now.Add(-time.Hour), BaselineCost: 1.0},
+		{PlanID: "rejected", NodePoolName: "default", AppliedAt: now.Add(-8 * time.Hour), UserFeedback: "rejected"},
+		{PlanID: "reported", NodePoolName: "default", AppliedAt: now.Add(-8 * time.Hour), ActualCost: 0.7, ActualNodes: 4},
+	} {
+		require.NoError(t, learning.RecordOutcome(ctx, outcome))
+	}
+
+	verified, err := verifier.VerifyDue(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, verified)
+	assert.ElementsMatch(t, []string{"default", "batch"}, measurer.calls)
+
+	outcomes := make(map[string]OptimizationOutcome)
+	for _, outcome := range learning.GetHistory() {
+		outcomes[outcome.PlanID] = outcome
+	}
+	require.Len(t, outcomes, 5)
+
+	settled := outcomes["settled"]
+	require.NotNil(t, settled.VerifiedAt)
+	assert.InDelta(t, 0.4, settled.ActualSavings, 0.0001)
+	assert.InDelta(t, 0.8, settled.Accuracy, 0.0001)
+	assert.Equal(t, 4, settled.ActualNodes)
+	assert.Equal(t, "spot", settled.ActualCapacityType)
+	assert.Equal(t, 2, settled.Disruptions[kubernetes.DisruptionReasonConsolidation])
+	assert.Empty(t, settled.Incidents)
+	assert.Equal(t, "positive", settled.PerformanceImpact)
+	assert.True(t, settled.Success)
+
+	withIncidents := outcomes["incidents"]
+	require.NotNil(t, withIncidents.VerifiedAt)
+	assert.Equal(t, 3, withIncidents.PendingPods)
+	assert.Len(t, withIncidents.Incidents, 2)
+	assert.Equal(t, "negative", withIncidents.PerformanceImpact)
+	assert.False(t, withIncidents.Success)
+
+	assert.Nil(t, outcomes["too-recent"].VerifiedAt)
+	assert.Nil(t, outcomes["rejected"].VerifiedAt)
+	assert.Nil(t, outcomes["reported"].VerifiedAt)
+
+	// Verified outcomes are not measured again; the recent one is once it settles
+	assert.Len(t, verifier.PendingVerification(), 1)
+	verified, err = verifier.VerifyDue(ctx, now.Add(6*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, verified)
+	assert.Empty(t, verifier.PendingVerification())
+}
+
+func TestOutcomeVerifierCaptureBaseline(t *testing.T) {
+	learning, err := NewLearningAgent("")
+	require.NoError(t, err)
+	measurer := &fakeMeasurer{measurements: map[string]*NodePoolMeasurement{"default": {Nodes: 3, HourlyCost: 1.5}}}
+	verifier := NewOutcomeVerifier(learning, measurer, time.Hour, time.Minute)
+
+	outcome := OptimizationOutcome{PlanID: "plan-1", NodePoolName: "default"}
+	require.NoError(t, verifier.CaptureBaseline(context.Background(), &outcome))
+	assert.Equal(t, 1.5, outcome.BaselineCost)
+	assert.Equal(t, 3, outcome.BaselineNodes)
+
+	// An existing baseline (e.g. from the plan) is kept
+	outcome.BaselineCost = 2.0
+	require.NoError(t, verifier.CaptureBaseline(context.Background(), &outcome))
+	assert.Equal(t, 2.0, outcome.BaselineCost)
+	assert.Len(t, measurer.calls, 1)
+}
+
+func TestLearningIgnoresPendingOutcomes(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	learning, err := NewLearningAgent(filepath.Join(t.TempDir(), "history.json"))
+	require.NoError(t, err)
+
+	for _, outcome := range []OptimizationOutcome{
+		{PlanID: "verified", NodePoolName: "default", Strategy: StrategySpotFirst, AppliedAt: now.Add(-8 * time.Hour), ActualCost: 0.6, ActualNodes: 3, ActualSavings: 0.4, PredictedSavings: 0.4, Accuracy: 1},
+		{PlanID: "pending-1", NodePoolName: "default", Strategy: StrategySpotFirst, AppliedAt: now.Add(-time.Hour), PredictedSavings: 0.4},
+		{PlanID: "pending-2", NodePoolName: "batch", Strategy: StrategyAggressive, AppliedAt: now.Add(-time.Hour), PredictedSavings: 0.4},
+	} {
+		require.NoError(t, learning.RecordOutcome(ctx, outcome))
+	}
+
+	// Pending plans would otherwise count as failures until they are verified
+	assert.Equal(t, 1.0, learning.GetStrategySuccessRate(StrategySpotFirst))
+	assert.Equal(t, 0.5, learning.GetStrategySuccessRate(StrategyAggressive), "no resolved outcomes: default rate")
+	assert.Equal(t, 1, learning.GetNodePoolPatternsCount())
+	assert.Equal(t, 1.0, learning.AdjustConfidence(1.0, "default", StrategySpotFirst))
+}
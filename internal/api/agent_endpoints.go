@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"time"
 	
 	"github.com/gin-gonic/gin"
@@ -14,8 +15,9 @@ import (
 // @Tags         agent
 // @Accept       json
 // @Produce      json
-// @Param        strategy  query     string  false  "Optimization strategy: aggressive, balanced, conservative, spot-first, right-size (default: balanced)"
+// @Param        strategy  query     string  false  "Optimization strategy: aggressive, balanced, conservative, spot-first, right-size or a user-defined strategy (default: balanced, or learned from past outcomes per NodePool)"
 // @Success      200  {object}  map[string]interface{}  "Optimization plans"
+// @Failure      400  {object}  map[string]interface{}  "Unknown strategy"
 // @Failure      500  {object}  map[string]interface{}  "Internal server error"
 // @Failure      503  {object}  map[string]interface{}  "Service not configured"
 // @Router       /agent/cost-optimization [get]
@@ -25,31 +27,27 @@ func (s *Server) getCostOptimizationRecommendations(c *gin.Context) {
 		return
 	}
 	
-	// Get strategy from query parameter
-	strategyParam := c.Query("strategy")
-	var strategy agent.OptimizationStrategy
-	switch strategyParam {
-	case "aggressive":
-		strategy = agent.StrategyAggressive
-	case "conservative":
-		strategy = agent.StrategyConservative
-	case "spot-first":
-		strategy = agent.StrategySpotFirst
-	case "right-size":
-		strategy = agent.StrategyRightSize
-	default:
-		strategy = agent.StrategyBalanced
+	// Get strategy from query parameter (built-in or user-defined). Without one, NodePools
+	// with outcomes get the strategy learned from them
+	requested := agent.OptimizationStrategy(c.Query("strategy"))
+	strategy := requested
+	if strategy == "" {
+		strategy = s.costAgent.GetStrategy()
+	}
+	if !s.costAgent.HasStrategy(strategy) {
+		c.JSON(400, gin.H{
+			"error":      fmt.Sprintf("unknown strategy %q", strategy),
+			"strategies": s.costAgent.Strategies(),
+		})
+		return
 	}
 	
 	// Use request context with timeout to allow cancellation
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 120*time.Second)
 	defer cancel()
 	
-	// Create agent
-	costAgent := agent.NewCostOptimizationAgent(s.recommender, s.k8sClient, strategy)
-	
-	// Generate recommendations with error handling
-	plans, err := costAgent.GenerateRecommendations(ctx)
+	// Generate recommendations with the shared agent (strategy applies to this request only)
+	plans, err := s.costAgent.GenerateRecommendationsWithStrategy(ctx, requested)
 	if err != nil {
 		// Check if context was cancelled/timed out
 		if ctx.Err() == context.DeadlineExceeded {
@@ -60,6 +58,11 @@ func (s *Server) getCostOptimizationRecommendations(c *gin.Context) {
 		return
 	}
 	
+	// Store the plans as proposed so they can be reviewed, approved and tracked
+	if err := s.planStore.Propose(plans, time.Now()); err != nil {
+		fmt.Printf("Warning: Failed to store optimization plans: %v\n", err)
+	}
+	
 	c.JSON(200, gin.H{
 		"plans":    plans,
 		"count":    len(plans),
@@ -69,7 +72,7 @@ func (s *Server) getCostOptimizationRecommendations(c *gin.Context) {
 
 // RecordOptimizationOutcome godoc
 // @Summary      Record optimization outcome for learning
-// @Description  Record the outcome of applying an optimization plan so the agent can learn
+// @Description  Record the outcome of applying an optimization plan so the agent can learn. Outcomes without actual results are measured automatically (cost, nodes, pending pods, disruptions) once AGENT_VERIFY_SETTLE_PERIOD has passed since appliedAt
 // @Tags         agent
 // @Accept       json
 // @Produce      json
@@ -90,18 +93,27 @@ func (s *Server) recordOptimizationOutcome(c *gin.Context) {
 		return
 	}
 	
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
 	defer cancel()
 	
-	// Create agent to access learning agent
-	costAgent := agent.NewCostOptimizationAgent(s.recommender, s.k8sClient, agent.StrategyBalanced)
-	learningAgent := costAgent.GetLearningAgent()
+	learningAgent := s.costAgent.GetLearningAgent()
 	
 	if learningAgent == nil {
 		c.JSON(503, gin.H{"error": "Learning agent not available"})
 		return
 	}
 	
+	// Applied outcomes are measured automatically once they settle; capture the
+	// NodePool's current cost as the baseline their savings are computed against
+	if outcome.AppliedAt.IsZero() {
+		outcome.AppliedAt = time.Now()
+	}
+	if s.outcomeVerifier != nil {
+		if err := s.outcomeVerifier.CaptureBaseline(ctx, &outcome); err != nil {
+			debugLog(s.config.Debug, "Warning: Failed to capture baseline for plan %s: %v\n", outcome.PlanID, err)
+		}
+	}
+	
 	// Record outcome for learning
 	if err := learningAgent.RecordOutcome(ctx, outcome); err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
@@ -116,7 +128,7 @@ func (s *Server) recordOptimizationOutcome(c *gin.Context) {
 
 // GetLearningStats godoc
 // @Summary      Get learning statistics
-// @Description  Get statistics about what the agent has learned from optimization outcomes
+// @Description  Get statistics about what the agent has learned from optimization outcomes, including the strategy bandit arm of each NodePool-strategy pair (decayed pulls, rewards and Beta posterior)
 // @Tags         agent
 // @Accept       json
 // @Produce      json
@@ -129,9 +141,7 @@ func (s *Server) getLearningStats(c *gin.Context) {
 		return
 	}
 	
-	// Create agent to access learning agent
-	costAgent := agent.NewCostOptimizationAgent(s.recommender, s.k8sClient, agent.StrategyBalanced)
-	learningAgent := costAgent.GetLearningAgent()
+	learningAgent := s.costAgent.GetLearningAgent()
 	
 	if learningAgent == nil {
 		c.JSON(200, gin.H{
@@ -141,20 +151,17 @@ func (s *Server) getLearningStats(c *gin.Context) {
 		return
 	}
 	
+	learningAgent.RefreshIfStale(c.Request.Context())
+	
 	// Get stats from learning agent
 	stats := map[string]interface{}{
-		"enabled": true,
+		"enabled":        true,
+		"historyBackend": learningAgent.GetHistoryBackend(),
 	}
 	
 	// Get strategy success rates
 	strategyRates := make(map[string]float64)
-	strategies := []agent.OptimizationStrategy{
-		agent.StrategyAggressive,
-		agent.StrategyBalanced,
-		agent.StrategyConservative,
-		agent.StrategySpotFirst,
-		agent.StrategyRightSize,
-	}
+	strategies := s.costAgent.Strategies()
 	for _, strategy := range strategies {
 		rate := learningAgent.GetStrategySuccessRate(strategy)
 		strategyRates[string(strategy)] = rate
@@ -164,9 +171,61 @@ func (s *Server) getLearningStats(c *gin.Context) {
 	// Get history count
 	stats["totalOutcomes"] = learningAgent.GetHistoryCount()
 	
+	// Strategy bandit arms (one per NodePool-strategy pair with outcomes)
+	bandit := learningAgent.GetBanditConfig()
+	arms := learningAgent.GetArmStats()
+	stats["bandit"] = gin.H{
+		"policy":          agent.BanditPolicy,
+		"halfLife":        bandit.HalfLife.String(),
+		"incidentPenalty": bandit.IncidentPenalty,
+		"arms":            arms,
+		"count":           len(arms),
+	}
+	
+	// Automatic verification of applied plans
+	if s.outcomeVerifier != nil {
+		stats["verification"] = gin.H{
+			"enabled":      true,
+			"settlePeriod": s.outcomeVerifier.SettlePeriod().String(),
+			"pending":      len(s.outcomeVerifier.PendingVerification()),
+		}
+	} else {
+		stats["verification"] = gin.H{"enabled": false}
+	}
+	
 	c.JSON(200, stats)
 }
 
+// GetLearningCalibration godoc
+// @Summary      Get confidence calibration and prediction accuracy
+// @Description  Compares verified outcomes with their plans: a calibration curve of predicted confidence (10 buckets) against actual success rate, Brier score and expected calibration error, the recalibration applied to new plan confidences, and the mean absolute error of predicted vs realized savings overall, per strategy and per NodePool
+// @Tags         agent
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Calibration report"
+// @Failure      503  {object}  map[string]interface{}  "Service not configured"
+// @Router       /agent/learning/calibration [get]
+func (s *Server) getLearningCalibration(c *gin.Context) {
+	if s.recommender == nil {
+		c.JSON(503, gin.H{"error": "Recommender not configured"})
+		return
+	}
+	
+	learningAgent := s.costAgent.GetLearningAgent()
+	if learningAgent == nil {
+		c.JSON(200, gin.H{
+			"enabled": false,
+			"message": "Learning agent not available",
+		})
+		return
+	}
+	
+	learningAgent.RefreshIfStale(c.Request.Context())
+	c.JSON(200, gin.H{
+		"enabled":     true,
+		"calibration": learningAgent.GetCalibrationReport(),
+	})
+}
+
 // GetOptimizationHistory godoc
 // @Summary      Get optimization history
 // @Description  Get the history of optimization outcomes for learning
@@ -182,9 +241,7 @@ func (s *Server) getOptimizationHistory(c *gin.Context) {
 		return
 	}
 	
-	// Create agent to access learning agent
-	costAgent := agent.NewCostOptimizationAgent(s.recommender, s.k8sClient, agent.StrategyBalanced)
-	learningAgent := costAgent.GetLearningAgent()
+	learningAgent := s.costAgent.GetLearningAgent()
 	
 	if learningAgent == nil {
 		c.JSON(200, gin.H{
@@ -194,7 +251,8 @@ func (s *Server) getOptimizationHistory(c *gin.Context) {
 		return
 	}
 	
-	// Get history
+	// Get history (including outcomes recorded by other replicas)
+	learningAgent.RefreshIfStale(c.Request.Context())
 	history := learningAgent.GetHistory()
 	
 	c.JSON(200, gin.H{
@@ -203,3 +261,19 @@ func (s *Server) getOptimizationHistory(c *gin.Context) {
 	})
 }
 
+// GetOptimizationStrategies godoc
+// @Summary      List optimization strategies
+// @Description  Built-in strategies and user-defined strategies loaded from AGENT_STRATEGIES_FILE, selectable with the strategy parameter of the cost optimization endpoint
+// @Tags         agent
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Strategies"
+// @Router       /agent/strategies [get]
+func (s *Server) getOptimizationStrategies(c *gin.Context) {
+	strategies := s.costAgent.Strategies()
+	c.JSON(200, gin.H{
+		"strategies": strategies,
+		"builtIn":    agent.BuiltInStrategies(),
+		"custom":     s.costAgent.CustomStrategies(),
+		"count":      len(strategies),
+	})
+}
@@ -0,0 +1,325 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/karpenter-optimizer/internal/agent"
+	"github.com/karpenter-optimizer/internal/kubernetes"
+)
+
+// objectSchema is the JSON Schema of a tool's arguments object
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// decodeToolArgs decodes a tool call's arguments
+func decodeToolArgs(args json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(args, v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// toolNodePool is the NodePool summary returned to the tool agent
+type toolNodePool struct {
+	Name              string   `json:"name"`
+	CapacityType      string   `json:"capacityType"`
+	InstanceTypes     []string `json:"instanceTypes"`
+	CurrentNodes      int      `json:"currentNodes"`
+	PodCount          int      `json:"podCount"`
+	CPUUsed           float64  `json:"cpuUsed"`
+	CPUAllocatable    float64  `json:"cpuAllocatable"`
+	MemoryUsed        float64  `json:"memoryUsedGiB"`
+	MemoryAllocatable float64  `json:"memoryAllocatableGiB"`
+}
+
+func summarizeNodePool(np kubernetes.NodePoolInfo) toolNodePool {
+	summary := toolNodePool{
+		Name:          np.Name,
+		CapacityType:  np.CapacityType,
+		InstanceTypes: np.InstanceTypes,
+		CurrentNodes:  len(np.ActualNodes),
+		PodCount:      np.PodCount,
+	}
+	for _, node := range np.ActualNodes {
+		if node.CPUUsage != nil {
+			summary.CPUUsed += node.CPUUsage.Used
+			summary.CPUAllocatable += node.CPUUsage.Allocatable
+		}
+		if node.MemoryUsage != nil {
+			summary.MemoryUsed += node.MemoryUsage.Used
+			summary.MemoryAllocatable += node.MemoryUsage.Allocatable
+		}
+	}
+	return summary
+}
+
+// toolDisruption is a node disruption returned to the LLM
+type toolDisruption struct {
+	NodeName       string `json:"nodeName"`
+	NodePool       string `json:"nodePool"`
+	InstanceType   string `json:"instanceType"`
+	Reason         string `json:"reason"`
+	LastSeen       string `json:"lastSeen"`
+	EventCount     int    `json:"eventCount"`
+	IsBlocked      bool   `json:"isBlocked"`
+	BlockingReason string `json:"blockingReason,omitempty"`
+}
+
+// summarizeDisruptions trims disruptions for the LLM, keeping only nodePools when given
+func summarizeDisruptions(disruptions []kubernetes.NodeDisruptionInfo, nodePools []string) []toolDisruption {
+	result := make([]toolDisruption, 0)
+	for _, d := range disruptions {
+		if len(nodePools) > 0 && !slices.Contains(nodePools, d.NodePool) {
+			continue
+		}
+		result = append(result, toolDisruption{
+			NodeName: d.NodeName, NodePool: d.NodePool, InstanceType: d.InstanceType, Reason: d.Reason,
+			LastSeen: d.LastSeen, EventCount: d.EventCount, IsBlocked: d.IsBlocked, BlockingReason: d.BlockingReason,
+		})
+	}
+	return result
+}
+
+// toolError is a Karpenter error fingerprint returned to the LLM
+type toolError struct {
+	Pattern   string    `json:"pattern"`
+	Count     int       `json:"count"`
+	LastSeen  time.Time `json:"lastSeen,omitempty"`
+	NodePools []string  `json:"nodePools"`
+	Category  string    `json:"category,omitempty"`
+	Severity  string    `json:"severity,omitempty"`
+	Sample    string    `json:"sample"`
+}
+
+func summarizeLogErrors(scan *karpenterLogScan) []toolError {
+	result := make([]toolError, 0, len(scan.fingerprints))
+	for _, fp := range scan.fingerprints {
+		sample := fp.SampleError
+		if sample == "" {
+			sample = fp.SampleMessage
+		}
+		result = append(result, toolError{Pattern: fp.Pattern, Count: fp.Count, LastSeen: fp.LastSeen, NodePools: fp.NodePools, Category: fp.Category, Severity: fp.Severity, Sample: sample})
+	}
+	return result
+}
+
+// findNodePool returns a NodePool with its nodes
+func (s *Server) findNodePool(ctx context.Context, name string) (*kubernetes.NodePoolInfo, error) {
+	nodePools, err := s.k8sClient.ListNodePools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NodePools: %w", err)
+	}
+	for i := range nodePools {
+		if nodePools[i].Name == name {
+			return &nodePools[i], nil
+		}
+	}
+	return nil, fmt.Errorf("NodePool %s not found", name)
+}
+
+// agentTools are the optimizer functions the tool agent's LLM may call
+func (s *Server) agentTools() []agent.AgentTool {
+	return []agent.AgentTool{
+		{
+			Name:        "list_nodepools",
+			Description: "List all Karpenter NodePools with capacity type, instance types, node and pod counts, and CPU (cores) and memory (GiB) used and allocatable",
+			Parameters:  objectSchema(map[string]interface{}{}),
+			Run: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				nodePools, err := s.k8sClient.ListNodePools(ctx)
+				if err != nil {
+					return nil, err
+				}
+				summaries := make([]toolNodePool, 0, len(nodePools))
+				for _, np := range nodePools {
+					summaries = append(summaries, summarizeNodePool(np))
+				}
+				return summaries, nil
+			},
+		},
+		{
+			Name:        "get_nodepool",
+			Description: "Get one NodePool: requirements, limits, disruption settings and every node with instance type, capacity type, zone, usage and pod count",
+			Parameters: objectSchema(map[string]interface{}{
+				"name": map[string]interface{}{"type": "string", "description": "NodePool name"},
+			}, "name"),
+			Run: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				var req struct {
+					Name string `json:"name"`
+				}
+				if err := decodeToolArgs(args, &req); err != nil {
+					return nil, err
+				}
+				np, err := s.findNodePool(ctx, req.Name)
+				if err != nil {
+					return nil, err
+				}
+				type toolNode struct {
+					Name         string  `json:"name"`
+					InstanceType string  `json:"instanceType"`
+					CapacityType string  `json:"capacityType"`
+					Zone         string  `json:"zone,omitempty"`
+					CPUUsed      float64 `json:"cpuUsed"`
+					MemoryUsed   float64 `json:"memoryUsedGiB"`
+					PodCount     int     `json:"podCount"`
+				}
+				nodes := make([]toolNode, 0, len(np.ActualNodes))
+				for _, node := range np.ActualNodes {
+					n := toolNode{Name: node.Name, InstanceType: node.InstanceType, CapacityType: node.CapacityType, Zone: node.Zone, PodCount: node.PodCount}
+					if node.CPUUsage != nil {
+						n.CPUUsed = node.CPUUsage.Used
+					}
+					if node.MemoryUsage != nil {
+						n.MemoryUsed = node.MemoryUsage.Used
+					}
+					nodes = append(nodes, n)
+				}
+				return map[string]interface{}{
+					"summary":             summarizeNodePool(*np),
+					"architecture":        np.Architecture,
+					"requirements":        np.Requirements,
+					"taints":              np.Taints,
+					"minSize":             np.MinSize,
+					"maxSize":             np.MaxSize,
+					"consolidationPolicy": np.ConsolidationPolicy,
+					"consolidateAfter":    np.ConsolidateAfter,
+					"expireAfter":         np.ExpireAfter,
+					"nodes":               nodes,
+				}, nil
+			},
+		},
+		{
+			Name:        "get_instance_pricing",
+			Description: "Get the hourly price in USD of one node of an instance type, with its vCPUs and memory (GiB) and where the price comes from",
+			Parameters: objectSchema(map[string]interface{}{
+				"instanceType": map[string]interface{}{"type": "string", "description": "EC2 instance type, e.g. m6i.large"},
+				"capacityType": map[string]interface{}{"type": "string", "enum": []string{"on-demand", "spot"}},
+			}, "instanceType"),
+			Run: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				var req struct {
+					InstanceType string `json:"instanceType"`
+					CapacityType string `json:"capacityType"`
+				}
+				if err := decodeToolArgs(args, &req); err != nil {
+					return nil, err
+				}
+				if req.CapacityType == "" {
+					req.CapacityType = "on-demand"
+				}
+				cpu, memory := s.recommender.EstimateInstanceCapacity(req.InstanceType)
+				if cpu <= 0 {
+					return nil, fmt.Errorf("unknown instance type %s", req.InstanceType)
+				}
+				price, _ := s.recommender.EstimateCostWithSource(ctx, []string{req.InstanceType}, req.CapacityType, 1)
+				return map[string]interface{}{
+					"instanceType": req.InstanceType,
+					"capacityType": req.CapacityType,
+					"hourlyPrice":  price.Cost,
+					"source":       price.Source,
+					"cpu":          cpu,
+					"memoryGiB":    memory,
+				}, nil
+			},
+		},
+		{
+			Name:        "simulate_capacity",
+			Description: "Size a NodePool's current CPU and memory usage plus headroom on a mix of instance types (spread evenly) and compare its hourly cost with the current nodes",
+			Parameters: objectSchema(map[string]interface{}{
+				"nodePool":        map[string]interface{}{"type": "string"},
+				"instanceTypes":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+				"capacityType":    map[string]interface{}{"type": "string", "enum": []string{"on-demand", "spot"}},
+				"headroomPercent": map[string]interface{}{"type": "number", "description": "Extra capacity above usage in percent (default 20)"},
+			}, "nodePool", "instanceTypes", "capacityType"),
+			Run: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				var req struct {
+					NodePool        string   `json:"nodePool"`
+					InstanceTypes   []string `json:"instanceTypes"`
+					CapacityType    string   `json:"capacityType"`
+					HeadroomPercent *float64 `json:"headroomPercent"`
+				}
+				if err := decodeToolArgs(args, &req); err != nil {
+					return nil, err
+				}
+				headroom := 20.0
+				if req.HeadroomPercent != nil {
+					headroom = *req.HeadroomPercent
+				}
+				np, err := s.findNodePool(ctx, req.NodePool)
+				if err != nil {
+					return nil, err
+				}
+				return agent.SimulateCapacity(ctx, s.recommender, *np, req.InstanceTypes, req.CapacityType, headroom)
+			},
+		},
+		{
+			Name:        "get_disruptions",
+			Description: "Get node disruptions (consolidation, drift, expiration, spot interruption) and blocked deletions in the last hours",
+			Parameters: objectSchema(map[string]interface{}{
+				"hours":    map[string]interface{}{"type": "integer", "description": "Hours to look back (default 24)"},
+				"nodePool": map[string]interface{}{"type": "string", "description": "Only this NodePool"},
+			}),
+			Run: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				var req struct {
+					Hours    int    `json:"hours"`
+					NodePool string `json:"nodePool"`
+				}
+				if err := decodeToolArgs(args, &req); err != nil {
+					return nil, err
+				}
+				if req.Hours <= 0 {
+					req.Hours = 24
+				}
+				if maxHours := s.maxDisruptionHours(); req.Hours > maxHours {
+					req.Hours = maxHours
+				}
+				disruptions, err := s.k8sClient.GetNodeDisruptions(ctx, req.Hours)
+				if err != nil {
+					return nil, err
+				}
+				var nodePools []string
+				if req.NodePool != "" {
+					nodePools = []string{req.NodePool}
+				}
+				return map[string]interface{}{"hours": req.Hours, "disruptions": summarizeDisruptions(disruptions, nodePools)}, nil
+			},
+		},
+		{
+			Name:        "get_karpenter_errors",
+			Description: "Get Karpenter controller errors grouped by message pattern, with count, last occurrence, affected NodePools and category",
+			Parameters: objectSchema(map[string]interface{}{
+				"since":    map[string]interface{}{"type": "string", "description": "Time window as a duration, e.g. 1h or 6h (default 1h)"},
+				"nodePool": map[string]interface{}{"type": "string", "description": "Only errors about this NodePool"},
+			}),
+			Run: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				var req struct {
+					Since    string `json:"since"`
+					NodePool string `json:"nodePool"`
+				}
+				if err := decodeToolArgs(args, &req); err != nil {
+					return nil, err
+				}
+				since := time.Hour
+				if req.Since != "" {
+					parsed, err := time.ParseDuration(req.Since)
+					if err != nil || parsed <= 0 {
+						return nil, fmt.Errorf("invalid since duration: %q", req.Since)
+					}
+					since = parsed
+				}
+				scan, err := s.scanKarpenterLogs(ctx, since, "ERROR", req.NodePool)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"since": since.String(), "errors": summarizeLogErrors(scan)}, nil
+			},
+		},
+	}
+}
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/karpenter-optimizer/internal/agent"
+	"github.com/karpenter-optimizer/internal/config"
+)
+
+// autoApplyInterval is how often approved plans are considered for automatic application
+const autoApplyInterval = 5 * time.Minute
+
+// autoApplyActor records automatic applies and reverts in plan transitions
+const autoApplyActor = "auto-apply"
+
+// applyPlanRequest is the body of plan apply requests
+type applyPlanRequest struct {
+	Approver string `json:"approver"`
+	Comment  string `json:"comment"`
+	DryRun   bool   `json:"dryRun"`
+}
+
+// newApplyGuardrails builds the apply guardrails from configuration
+func newApplyGuardrails(cfg *config.Config) (agent.ApplyGuardrails, error) {
+	loc, err := time.LoadLocation(cfg.AutoApplyTimezone)
+	if err != nil {
+		return agent.ApplyGuardrails{}, fmt.Errorf("invalid AUTO_APPLY_TIMEZONE: %w", err)
+	}
+	windows, err := agent.ParseMaintenanceWindows(cfg.AutoApplyMaintenanceWindows, loc)
+	if err != nil {
+		return agent.ApplyGuardrails{}, err
+	}
+	return agent.ApplyGuardrails{
+		MaxCapacityReductionPercent: cfg.AutoApplyMaxCapacityReduction,
+		MaintenanceWindows:          windows,
+		MinOnDemandRatio:            cfg.AutoApplyMinOnDemandRatio,
+		WatchWindow:                 cfg.AutoApplyWatchWindow,
+		MaxPendingPods:              cfg.AutoApplyMaxPendingPods,
+		MaxDisruptionErrors:         cfg.AutoApplyMaxDisruptionErrors,
+	}, nil
+}
+
+// startPlanApplier creates the plan applier and, when enabled, applies approved
+// plans in the background whenever a maintenance window is open
+func (s *Server) startPlanApplier() {
+	guardrails, err := newApplyGuardrails(s.config)
+	if err != nil {
+		fmt.Printf("Warning: Plan applier disabled: %v\n", err)
+		return
+	}
+	s.planApplier = agent.NewPlanApplier(s.k8sClient, agent.NewClusterApplyMonitor(s.k8sClient), guardrails)
+	s.resumePlanWatches()
+	if !s.config.AutoApplyEnabled {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(autoApplyInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.lifecycle.Done():
+				return
+			case <-ticker.C:
+				s.autoApplyApprovedPlans(s.lifecycle)
+			}
+		}
+	}()
+}
+
+// resumePlanWatches watches plans applied before a restart for the rest of their watch window
+func (s *Server) resumePlanWatches() {
+	plans, err := s.planStore.List(agent.PlanFilter{Status: agent.PlanStatusApplied})
+	if err != nil {
+		fmt.Printf("Warning: Failed to list applied plans: %v\n", err)
+		return
+	}
+	for _, plan := range plans {
+		if result := s.planApplier.PendingWatch(plan); result != nil {
+			debugLog(s.config.Debug, "Resuming watch of plan %s on NodePool %s\n", plan.ID, plan.NodePoolName)
+			s.startPlanWatch(result)
+		}
+	}
+}
+
+// autoApplyApprovedPlans applies approved plans while a maintenance window is open
+func (s *Server) autoApplyApprovedPlans(ctx context.Context) {
+	if !s.planApplier.Guardrails().InMaintenanceWindow(time.Now()) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	s.expireChangedPlans(ctx)
+
+	plans, err := s.planStore.List(agent.PlanFilter{Status: agent.PlanStatusApproved})
+	if err != nil {
+		fmt.Printf("Warning: Failed to list approved plans: %v\n", err)
+		return
+	}
+	for _, plan := range plans {
+		result, err := s.applyPlan(ctx, plan, autoApplyActor, "applied automatically in maintenance window")
+		switch {
+		case errors.Is(err, agent.ErrPlanConflict), errors.Is(err, agent.ErrInvalidPlanTransition):
+			debugLog(s.config.Debug, "Plan %s not applied: %v\n", plan.ID, err)
+		case err != nil:
+			fmt.Printf("Warning: Failed to apply plan %s: %v\n", plan.ID, err)
+		case result.Blocked:
+			debugLog(s.config.Debug, "Plan %s not applied: %v\n", plan.ID, result.Violations)
+		default:
+			debugLog(s.config.Debug, "Applied plan %s to NodePool %s\n", plan.ID, plan.NodePoolName)
+		}
+	}
+}
+
+// applyPlan patches an approved plan's NodePool, records it as applied (expiring the
+// NodePool's other open plans) and watches it in the background, reverting the NodePool
+// if it becomes unhealthy
+func (s *Server) applyPlan(ctx context.Context, plan *agent.OptimizationPlan, actor, comment string) (*agent.ApplyResult, error) {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+
+	// Re-read under the lock: another apply may have moved the plan on
+	plan, err := s.planStore.Get(plan.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !agent.CanTransition(plan.Status, agent.PlanStatusApplied) {
+		return nil, fmt.Errorf("%w: plan %s is %s, only approved plans can be applied", agent.ErrInvalidPlanTransition, plan.ID, plan.Status)
+	}
+	if err := s.checkApplyConflicts(ctx, plan); err != nil {
+		return nil, err
+	}
+
+	result, err := s.planApplier.Apply(ctx, plan, false)
+	if err != nil || result.Blocked {
+		return result, err
+	}
+
+	plan, err = s.planStore.MarkApplied(plan.ID, actor, comment, result.AppliedAt, result.RevertPatch)
+	if err != nil {
+		// Nothing would watch the change, so take it back, even if the request was cancelled
+		if revertErr := s.planApplier.Revert(context.WithoutCancel(ctx), result); revertErr != nil {
+			return nil, fmt.Errorf("NodePool %s was patched but plan %s could not be marked applied (%v) nor reverted: %w", result.NodePool, result.PlanID, err, revertErr)
+		}
+		return nil, fmt.Errorf("NodePool %s was reverted because plan %s could not be marked applied: %w", result.NodePool, result.PlanID, err)
+	}
+	if learningAgent := s.costAgent.GetLearningAgent(); learningAgent != nil {
+		if err := s.recordPlanOutcome(ctx, learningAgent, plan, actor, comment); err != nil {
+			fmt.Printf("Warning: Failed to record outcome for plan %s: %v\n", plan.ID, err)
+		}
+	}
+	s.startPlanWatch(result)
+	return result, nil
+}
+
+// checkApplyConflicts refuses a plan whose NodePool changed since the plan was created
+// (expiring it) or is still watched after another plan was applied to it
+func (s *Server) checkApplyConflicts(ctx context.Context, plan *agent.OptimizationPlan) error {
+	if plan.NodePoolFingerprint != "" {
+		nodePools, err := s.k8sClient.ListNodePoolSpecs(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to check NodePool %s: %w", plan.NodePoolName, err)
+		}
+		reason := "NodePool no longer exists"
+		for _, np := range nodePools {
+			if np.Name == plan.NodePoolName {
+				reason = "NodePool changed since the plan was created"
+				if agent.NodePoolFingerprint(np) == plan.NodePoolFingerprint {
+					reason = ""
+				}
+			}
+		}
+		if reason != "" {
+			if _, err := s.planStore.Transition(plan.ID, agent.PlanStatusExpired, "agent", reason, time.Now()); err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: %s, plan %s expired", agent.ErrPlanConflict, reason, plan.ID)
+		}
+	}
+
+	applied, err := s.planStore.List(agent.PlanFilter{NodePool: plan.NodePoolName, Status: agent.PlanStatusApplied})
+	if err != nil {
+		return err
+	}
+	for _, other := range applied {
+		watchedUntil := other.AppliedAt().Add(s.planApplier.Guardrails().WatchWindow)
+		if other.ID != plan.ID && time.Now().Before(watchedUntil) {
+			return fmt.Errorf("%w: plan %s was applied to NodePool %s and is watched until %s", agent.ErrPlanConflict, other.ID, plan.NodePoolName, watchedUntil.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// startPlanWatch watches an applied plan in the background until its watch window
+// passes or the server shuts down
+func (s *Server) startPlanWatch(result *agent.ApplyResult) {
+	s.planWatches.Add(1)
+	go func() {
+		defer s.planWatches.Done()
+		s.watchAppliedPlan(s.lifecycle, result)
+	}()
+}
+
+// watchAppliedPlan watches an applied plan and rolls it back if the applier reverted it
+func (s *Server) watchAppliedPlan(ctx context.Context, result *agent.ApplyResult) {
+	guardrails := s.planApplier.Guardrails()
+	ctx, cancel := context.WithTimeout(ctx, guardrails.WatchWindow+5*time.Minute)
+	defer cancel()
+
+	watch, err := s.planApplier.Watch(ctx, result)
+	switch {
+	case errors.Is(err, context.Canceled):
+		debugLog(s.config.Debug, "Stopped watching plan %s; the watch resumes on restart\n", result.PlanID)
+	case err != nil:
+		fmt.Printf("Warning: Watching plan %s failed: %v\n", result.PlanID, err)
+	}
+	if watch == nil || !watch.Reverted {
+		return
+	}
+	// The NodePool was reverted: record it even if the server is shutting down
+	ctx = context.WithoutCancel(ctx)
+
+	comment := "reverted automatically: " + watch.Reason
+	plan, err := s.planStore.Transition(result.PlanID, agent.PlanStatusRolledBack, autoApplyActor, comment, time.Now())
+	if err != nil {
+		fmt.Printf("Warning: Failed to mark plan %s rolled back: %v\n", result.PlanID, err)
+		return
+	}
+	if learningAgent := s.costAgent.GetLearningAgent(); learningAgent != nil {
+		if err := s.recordPlanOutcome(ctx, learningAgent, plan, autoApplyActor, comment); err != nil {
+			fmt.Printf("Warning: Failed to record outcome for plan %s: %v\n", plan.ID, err)
+		}
+	}
+}
+
+// ApplyOptimizationPlan godoc
+// @Summary      Apply an approved optimization plan to its NodePool
+// @Description  Checks guardrails (exclusion annotation, maintenance windows, maximum capacity reduction, minimum on-demand ratio), dry-runs the NodePool patch on the API server and, unless dryRun is set, applies it. The NodePool is then watched for pending pods and Karpenter errors and its previous requirements restored if thresholds are exceeded. Dry runs work for proposed and approved plans at any time; real applies need an approved plan and AUTO_APPLY_ENABLED.
+// @Tags         agent
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string  true  "Plan ID"
+// @Param        request  body      object  true  "Approver, comment and dryRun"
+// @Success      200      {object}  map[string]interface{}  "Apply result and plan"
+// @Failure      400      {object}  map[string]interface{}  "Missing approver"
+// @Failure      403      {object}  map[string]interface{}  "Auto-apply is disabled"
+// @Failure      404      {object}  map[string]interface{}  "Plan not found"
+// @Failure      409      {object}  map[string]interface{}  "Plan is not approved, its NodePool changed, or another plan applied to the NodePool is still watched"
+// @Failure      422      {object}  map[string]interface{}  "Blocked by guardrails"
+// @Failure      503      {object}  map[string]interface{}  "Kubernetes client not configured"
+// @Router       /agent/plans/{id}/apply [post]
+func (s *Server) applyOptimizationPlan(c *gin.Context) {
+	var body applyPlanRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if s.k8sClient == nil || s.planApplier == nil {
+		c.JSON(503, gin.H{"error": "Kubernetes client not configured"})
+		return
+	}
+	actor := s.reviewerIdentity(c, planReviewRequest{Approver: body.Approver})
+	if actor == "" && !body.DryRun {
+		c.JSON(400, gin.H{"error": "approver is required (request body or auth proxy header)"})
+		return
+	}
+	if !body.DryRun && !s.config.AutoApplyEnabled {
+		c.JSON(403, gin.H{"error": "applying plans is disabled (set AUTO_APPLY_ENABLED=true); use dryRun to check a plan"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+	s.expireChangedPlans(ctx)
+
+	plan, err := s.planStore.Get(c.Param("id"))
+	if err != nil {
+		planError(c, err)
+		return
+	}
+
+	var result *agent.ApplyResult
+	if body.DryRun {
+		if plan.Status != agent.PlanStatusProposed && plan.Status != agent.PlanStatusApproved {
+			c.JSON(409, gin.H{"error": fmt.Sprintf("plan %s is %s and cannot be applied", plan.ID, plan.Status)})
+			return
+		}
+		result, err = s.planApplier.Apply(ctx, plan, true)
+	} else {
+		result, err = s.applyPlan(ctx, plan, actor, body.Comment)
+	}
+	if err != nil {
+		planError(c, err)
+		return
+	}
+	if result.Blocked {
+		c.JSON(422, gin.H{"error": "plan blocked by guardrails", "result": result})
+		return
+	}
+
+	if plan, err = s.planStore.Get(plan.ID); err != nil {
+		planError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{
+		"result":     result,
+		"plan":       plan,
+		"guardrails": s.planApplier.Guardrails(),
+	})
+}
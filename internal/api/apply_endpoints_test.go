@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karpenter-optimizer/internal/agent"
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/recommender"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newApplyTestServer returns a server with auto-apply enabled against one NodePool on m5.xlarge
+func newApplyTestServer(t *testing.T) *Server {
+	t.Helper()
+	gvr := schema.GroupVersionResource{Group: "karpenter.sh", Version: "v1", Resource: "nodepools"}
+	np := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "karpenter.sh/v1",
+		"kind":       "NodePool",
+		"metadata":   map[string]interface{}{"name": "default"},
+		"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{"requirements": []interface{}{
+			map[string]interface{}{"key": "node.kubernetes.io/instance-type", "operator": "In", "values": []interface{}{"m5.xlarge"}},
+		}}}},
+	}}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{gvr: "NodePoolList"}, np)
+	// Dry runs are admitted without being persisted, like the API server
+	dynamicClient.PrependReactor("patch", "nodepools", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return len(action.(k8stesting.PatchActionImpl).GetPatchOptions().DryRun) > 0, nil, nil
+	})
+
+	server := setupTestServer()
+	server.config.AutoApplyEnabled = true
+	server.k8sClient = kubernetes.NewClientFromInterfaces(fake.NewSimpleClientset(), dynamicClient)
+	server.planApplier = agent.NewPlanApplier(server.k8sClient, nil, agent.ApplyGuardrails{WatchWindow: time.Hour, CheckInterval: time.Hour})
+	return server
+}
+
+// proposeApproved stores approved plans for the default NodePool, computed against its current spec
+func proposeApproved(t *testing.T, server *Server, instanceTypes map[string]string) {
+	t.Helper()
+	nodePools, err := server.k8sClient.ListNodePoolSpecs(context.Background())
+	require.NoError(t, err)
+	require.Len(t, nodePools, 1)
+	var plans []*agent.OptimizationPlan
+	for id, instanceType := range instanceTypes {
+		plans = append(plans, &agent.OptimizationPlan{
+			ID:                  id,
+			NodePoolName:        "default",
+			NodePoolFingerprint: agent.NodePoolFingerprint(nodePools[0]),
+			Strategy:            agent.OptimizationStrategy(id),
+			CreatedAt:           time.Now(),
+			Recommendations: []recommender.NodePoolCapacityRecommendation{{
+				NodePoolName:             "default",
+				RecommendedInstanceTypes: []string{instanceType},
+				CapacityType:             "spot",
+			}},
+		})
+	}
+	require.NoError(t, server.planStore.Propose(plans, time.Now()))
+	for id := range instanceTypes {
+		_, err := server.planStore.Transition(id, agent.PlanStatusApproved, "alice", "", time.Now())
+		require.NoError(t, err)
+	}
+}
+
+func TestApplyOnePlanPerNodePool(t *testing.T) {
+	server := newApplyTestServer(t)
+	proposeApproved(t, server, map[string]string{"plan-1": "m6i.large", "plan-2": "c6i.large"})
+
+	server.autoApplyApprovedPlans(context.Background())
+
+	applied, err := server.planStore.List(agent.PlanFilter{Status: agent.PlanStatusApplied})
+	require.NoError(t, err)
+	require.Len(t, applied, 1, "only one plan is applied to the NodePool")
+	expired, err := server.planStore.List(agent.PlanFilter{Status: agent.PlanStatusExpired})
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "superseded by applied plan "+applied[0].ID, expired[0].Transitions[len(expired[0].Transitions)-1].Comment)
+
+	spec, _, err := server.k8sClient.GetNodePoolSpec(context.Background(), "default")
+	require.NoError(t, err)
+	requirements, _, _ := unstructured.NestedSlice(spec, "template", "spec", "requirements")
+	assert.Contains(t, requirements, map[string]interface{}{
+		"key": "node.kubernetes.io/instance-type", "operator": "In",
+		"values": []interface{}{applied[0].Recommendations[0].RecommendedInstanceTypes[0]},
+	})
+
+	// A plan computed against the patched NodePool waits for the applied plan's watch window
+	proposeApproved(t, server, map[string]string{"plan-3": "r6i.large"})
+	req := httptest.NewRequest("POST", "/api/v1/agent/plans/plan-3/apply", strings.NewReader(`{"approver":"alice"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "is watched until")
+	plan, err := server.planStore.Get("plan-3")
+	require.NoError(t, err)
+	assert.Equal(t, agent.PlanStatusApproved, plan.Status)
+}
+
+// breachedMonitor reports more pending pods than any watch allows
+type breachedMonitor struct{}
+
+func (breachedMonitor) Health(ctx context.Context, nodePool string, since time.Time) (*agent.ApplyHealth, error) {
+	return &agent.ApplyHealth{PendingPods: 100}, nil
+}
+
+func (breachedMonitor) CPUByCapacityType(ctx context.Context) (map[string]map[string]float64, error) {
+	return nil, nil
+}
+
+func TestResumePlanWatches(t *testing.T) {
+	server := newApplyTestServer(t)
+	proposeApproved(t, server, map[string]string{"plan-1": "m6i.large"})
+	plan, err := server.planStore.Get("plan-1")
+	require.NoError(t, err)
+	// Applied by a server that stopped before the watch window passed
+	result, err := server.planApplier.Apply(context.Background(), plan, false)
+	require.NoError(t, err)
+	_, err = server.planStore.MarkApplied(plan.ID, "alice", "", result.AppliedAt, result.RevertPatch)
+	require.NoError(t, err)
+
+	// A restarted server picks the watch up from the stored plan and reverts the breach
+	server.planApplier = agent.NewPlanApplier(server.k8sClient, breachedMonitor{}, agent.ApplyGuardrails{WatchWindow: time.Hour, CheckInterval: time.Millisecond})
+	server.resumePlanWatches()
+	require.Eventually(t, func() bool {
+		plan, err := server.planStore.Get("plan-1")
+		return err == nil && plan.Status == agent.PlanStatusRolledBack
+	}, 5*time.Second, 10*time.Millisecond)
+
+	spec, _, err := server.k8sClient.GetNodePoolSpec(context.Background(), "default")
+	require.NoError(t, err)
+	requirements, _, _ := unstructured.NestedSlice(spec, "template", "spec", "requirements")
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"key": "node.kubernetes.io/instance-type", "operator": "In", "values": []interface{}{"m5.xlarge"}},
+	}, requirements)
+}
+
+func TestShutdownStopsPlanWatches(t *testing.T) {
+	server := newApplyTestServer(t)
+	proposeApproved(t, server, map[string]string{"plan-1": "m6i.large"})
+	plan, err := server.planStore.Get("plan-1")
+	require.NoError(t, err)
+	_, err = server.applyPlan(context.Background(), plan, "alice", "")
+	require.NoError(t, err)
+
+	start := time.Now()
+	server.shutdown()
+	assert.Less(t, time.Since(start), 5*time.Second, "the hour-long watch stops on shutdown")
+	plan, err = server.planStore.Get("plan-1")
+	require.NoError(t, err)
+	assert.Equal(t, agent.PlanStatusApplied, plan.Status, "left for the next start to resume")
+	assert.NotNil(t, server.planApplier.PendingWatch(plan))
+}
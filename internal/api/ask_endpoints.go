@@ -0,0 +1,288 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/karpenter-optimizer/internal/agent"
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/recommender"
+)
+
+const (
+	maxQADisruptions = 50 // Most recent disruptions put in the prompt
+	maxQAErrors      = 20 // Most frequent Karpenter error patterns put in the prompt
+	maxQANamespaces  = 25 // Namespaces with the most requested CPU put in the prompt
+)
+
+// Question words that select the data retrieved for /ask. Words match by prefix.
+var (
+	qaCostWords       = []string{"cost", "cheap", "expensive", "sav", "spend", "pric", "bill", "recommend", "rightsiz", "right-siz", "oversiz", "overprovision", "waste", "wasting", "money", "$"}
+	qaDisruptionWords = []string{"disrupt", "consolidat", "drift", "expir", "interrupt", "churn", "evict", "replac", "delet", "terminat", "jump", "spike", "increas", "grew", "yesterday", "why", "chang"}
+	qaErrorWords      = []string{"error", "fail", "karpenter", "launch", "insufficient", "pending", "stuck", "schedul", "why"}
+	qaNamespaceWords  = []string{"namespace", "workload", "team", "request", "deployment", "statefulset", "waste", "wasting"}
+)
+
+// AskRequest is the request body for a cluster question
+type AskRequest struct {
+	Question string `json:"question" binding:"required" example:"Why did nodepool batch cost jump yesterday?"`
+	Hours    int    `json:"hours" example:"48"` // Look-back for disruptions and Karpenter errors (default: 24, 48 for "yesterday", 168 for "week")
+}
+
+// qaRetrieval is the data retrieved to answer a question
+type qaRetrieval struct {
+	nodePools       []string // NodePools named in the question (all when empty)
+	recommendations bool
+	disruptions     bool
+	karpenterErrors bool
+	namespaces      bool
+	hours           int
+}
+
+// planQARetrieval chooses the data to retrieve from the words of a question. The
+// cluster summary and NodePools are always retrieved; questions that match no topic
+// get recommendations, disruptions and Karpenter errors.
+func planQARetrieval(question string, nodePoolNames []string) qaRetrieval {
+	words := strings.FieldsFunc(strings.ToLower(question), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '$'
+	})
+	mentions := func(keywords []string) bool {
+		for _, word := range words {
+			for _, keyword := range keywords {
+				if strings.HasPrefix(word, keyword) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	plan := qaRetrieval{
+		recommendations: mentions(qaCostWords),
+		disruptions:     mentions(qaDisruptionWords),
+		karpenterErrors: mentions(qaErrorWords),
+		namespaces:      mentions(qaNamespaceWords),
+		hours:           24,
+	}
+	if !plan.recommendations && !plan.disruptions && !plan.karpenterErrors && !plan.namespaces {
+		plan.recommendations, plan.disruptions, plan.karpenterErrors = true, true, true
+	}
+	switch {
+	case mentions([]string{"week"}):
+		plan.hours = 7 * 24
+	case mentions([]string{"yesterday"}):
+		plan.hours = 48
+	}
+	for _, name := range nodePoolNames {
+		if slices.Contains(words, strings.ToLower(name)) {
+			plan.nodePools = append(plan.nodePools, name)
+		}
+	}
+	return plan
+}
+
+// AskCluster godoc
+// @Summary      Ask a question about the cluster
+// @Description  Retrieves the cluster summary, NodePools and, depending on the question, NodePool recommendations, disruptions and churn, Karpenter errors and per-namespace requests, and has the LLM answer from that data only. The answer cites the data it used as [id]. Answers with numbers that are not in the retrieved data are rewritten once and otherwise refused.
+// @Tags         agent
+// @Accept       json
+// @Produce      json
+// @Param        request  body      AskRequest              true  "Question"
+// @Param        noCache  query     bool                    false  "Skip the LLM response cache (fresh replies are still cached)"
+// @Success      200      {object}  map[string]interface{}  "Answer with citations and retrieved sources"
+// @Failure      400      {object}  map[string]interface{}  "Bad request"
+// @Failure      502      {object}  map[string]interface{}  "LLM request failed"
+// @Failure      503      {object}  map[string]interface{}  "Service not configured"
+// @Failure      500      {object}  map[string]interface{}  "Internal server error"
+// @Router       /ask [post]
+func (s *Server) askCluster(c *gin.Context) {
+	if s.k8sClient == nil {
+		c.JSON(503, gin.H{"error": "Kubernetes client not configured"})
+		return
+	}
+	llm := s.recommender.GetOllamaClient()
+	if llm == nil {
+		c.JSON(503, gin.H{"error": "LLM not configured"})
+		return
+	}
+
+	var req AskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Hours < 0 {
+		c.JSON(400, gin.H{"error": "hours must not be negative"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Minute)
+	defer cancel()
+	ctx = llmCacheContext(ctx, c)
+
+	nodePools, err := s.k8sClient.ListNodePools(ctx)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	names := make([]string, 0, len(nodePools))
+	for _, np := range nodePools {
+		names = append(names, np.Name)
+	}
+	plan := planQARetrieval(req.Question, names)
+	if req.Hours > 0 {
+		plan.hours = req.Hours
+	}
+	if maxHours := s.maxDisruptionHours(); plan.hours > maxHours {
+		plan.hours = maxHours
+	}
+
+	sources, unavailable := s.retrieveQASources(ctx, plan, nodePools)
+	debugLog(s.config.Debug, "[Ask] Retrieved %d sources for %q (unavailable: %v)\n", len(sources), req.Question, unavailable)
+	if len(unavailable) > 0 {
+		sources = append(sources, agent.QASource{ID: "unavailable", Kind: "unavailable", Title: "Data that could not be retrieved for this question", Data: unavailable})
+	}
+
+	answer, err := agent.AnswerQuestion(ctx, llm, req.Question, sources)
+	if err != nil {
+		c.JSON(502, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"answer":      answer,
+		"hours":       plan.hours,
+		"unavailable": unavailable,
+	})
+}
+
+// retrieveQASources fetches the data selected by plan. Data that cannot be fetched is
+// reported in unavailable so the answer can say it is missing.
+func (s *Server) retrieveQASources(ctx context.Context, plan qaRetrieval, nodePools []kubernetes.NodePoolInfo) ([]agent.QASource, []string) {
+	sources := make([]agent.QASource, 0)
+	unavailable := make([]string, 0)
+
+	if summary, err := s.clusterSummary(ctx); err != nil {
+		unavailable = append(unavailable, fmt.Sprintf("cluster-summary: %v", err))
+	} else {
+		sources = append(sources, agent.QASource{ID: "cluster-summary", Kind: "cluster-summary", Title: "Cluster totals: nodes, pods, CPU (cores) and memory (GiB) used and allocatable, hourly cost in USD", Data: summary})
+	}
+
+	selected := nodePools
+	if len(plan.nodePools) > 0 {
+		selected = make([]kubernetes.NodePoolInfo, 0, len(plan.nodePools))
+		for _, np := range nodePools {
+			if slices.Contains(plan.nodePools, np.Name) {
+				selected = append(selected, np)
+			}
+		}
+	}
+	for _, np := range selected {
+		sources = append(sources, agent.QASource{ID: "nodepool:" + np.Name, Kind: "nodepool", Title: fmt.Sprintf("NodePool %s now: nodes, pods, CPU (cores) and memory (GiB) used and allocatable", np.Name), Data: summarizeNodePool(np)})
+	}
+
+	if plan.recommendations {
+		recommendations, err := s.recommender.GenerateRecommendationsFromNodePools(ctx, selected, nil)
+		if err != nil {
+			unavailable = append(unavailable, fmt.Sprintf("recommendations: %v", err))
+		}
+		for _, rec := range recommendations {
+			rec.AIReasoning = ""
+			sources = append(sources, agent.QASource{ID: "recommendation:" + rec.NodePoolName, Kind: "recommendation", Title: fmt.Sprintf("Right-sizing recommendation for NodePool %s: current and recommended nodes and hourly cost in USD", rec.NodePoolName), Data: rec})
+		}
+	}
+
+	if plan.disruptions {
+		disruptions, err := s.k8sClient.GetNodeDisruptions(ctx, plan.hours)
+		if err != nil {
+			unavailable = append(unavailable, fmt.Sprintf("disruptions: %v", err))
+		} else {
+			recent := summarizeDisruptions(disruptions, plan.nodePools)
+			if len(recent) > maxQADisruptions {
+				recent = recent[:maxQADisruptions]
+			}
+			sources = append(sources, agent.QASource{ID: "disruptions", Kind: "disruptions", Title: fmt.Sprintf("Node disruptions and blocked deletions in the last %d hours, most recent first", plan.hours), Data: gin.H{"hours": plan.hours, "disruptions": recent, "historyRecorded": s.disruptionStore != nil}})
+
+			now := time.Now()
+			for _, report := range s.recommender.AnalyzeDisruptionChurn(ctx, disruptions, selected, now.Add(-time.Duration(plan.hours)*time.Hour), now, recommender.ChurnOptions{}) {
+				if len(plan.nodePools) > 0 && !slices.Contains(plan.nodePools, report.NodePool) {
+					continue
+				}
+				sources = append(sources, agent.QASource{ID: "churn:" + report.NodePool, Kind: "churn", Title: fmt.Sprintf("Disruption churn of NodePool %s in the last %d hours: disruptions per day by reason, node lifetimes and cost wasted by short-lived nodes in USD", report.NodePool, plan.hours), Data: report})
+			}
+		}
+	}
+
+	if plan.karpenterErrors {
+		nodePool := ""
+		if len(plan.nodePools) == 1 {
+			nodePool = plan.nodePools[0]
+		}
+		since := time.Duration(plan.hours) * time.Hour
+		scan, err := s.scanKarpenterLogs(ctx, since, "ERROR", nodePool)
+		if err != nil {
+			unavailable = append(unavailable, fmt.Sprintf("karpenter-errors: %v", err))
+		} else {
+			errs := summarizeLogErrors(scan)
+			if len(errs) > maxQAErrors {
+				errs = errs[:maxQAErrors]
+			}
+			sources = append(sources, agent.QASource{ID: "karpenter-errors", Kind: "karpenter-errors", Title: fmt.Sprintf("Karpenter controller errors in the last %s grouped by message pattern", since), Data: gin.H{"since": since.String(), "errors": errs}})
+		}
+	}
+
+	if plan.namespaces {
+		workloads, err := s.k8sClient.ListAllWorkloads(ctx)
+		if err != nil {
+			unavailable = append(unavailable, fmt.Sprintf("namespaces: %v", err))
+		} else {
+			sources = append(sources, agent.QASource{ID: "namespaces", Kind: "namespaces", Title: "CPU (cores) and memory (GiB) requested by running pods per namespace, most CPU first; these are requests, not measured usage", Data: summarizeNamespaces(workloads)})
+		}
+	}
+
+	return sources, unavailable
+}
+
+// qaNamespace is the resources requested by one namespace's running pods
+type qaNamespace struct {
+	Namespace       string  `json:"namespace"`
+	Workloads       int     `json:"workloads"`
+	RunningPods     int32   `json:"runningPods"`
+	CPURequested    float64 `json:"cpuRequested"`
+	MemoryRequested float64 `json:"memoryRequestedGiB"`
+}
+
+// summarizeNamespaces totals workload requests per namespace, most CPU first
+func summarizeNamespaces(workloads []kubernetes.WorkloadInfo) []qaNamespace {
+	byNamespace := make(map[string]*qaNamespace)
+	for _, w := range workloads {
+		ns, ok := byNamespace[w.Namespace]
+		if !ok {
+			ns = &qaNamespace{Namespace: w.Namespace}
+			byNamespace[w.Namespace] = ns
+		}
+		ns.Workloads++
+		ns.RunningPods += w.RunningPods
+		ns.CPURequested += w.CPUUsed
+		ns.MemoryRequested += w.MemoryUsed
+	}
+	result := make([]qaNamespace, 0, len(byNamespace))
+	for _, ns := range byNamespace {
+		result = append(result, *ns)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CPURequested != result[j].CPURequested {
+			return result[i].CPURequested > result[j].CPURequested
+		}
+		return result[i].Namespace < result[j].Namespace
+	})
+	if len(result) > maxQANamespaces {
+		result = result[:maxQANamespaces]
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanQARetrieval(t *testing.T) {
+	nodePools := []string{"default", "batch", "gpu-a10g"}
+
+	tests := []struct {
+		name     string
+		question string
+		want     qaRetrieval
+	}{
+		{
+			name:     "cost jump of one NodePool",
+			question: "Why did nodepool batch cost jump yesterday?",
+			want:     qaRetrieval{nodePools: []string{"batch"}, recommendations: true, disruptions: true, karpenterErrors: true, hours: 48},
+		},
+		{
+			name:     "namespace waste",
+			question: "Which namespaces waste the most CPU?",
+			want:     qaRetrieval{recommendations: true, namespaces: true, hours: 24},
+		},
+		{
+			name:     "errors this week on two NodePools",
+			question: "Did gpu-a10g or default fail to launch nodes this week?",
+			want:     qaRetrieval{nodePools: []string{"default", "gpu-a10g"}, karpenterErrors: true, hours: 168},
+		},
+		{
+			name:     "no topic retrieves the defaults",
+			question: "How is the cluster doing?",
+			want:     qaRetrieval{recommendations: true, disruptions: true, karpenterErrors: true, hours: 24},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, planQARetrieval(tt.question, nodePools))
+		})
+	}
+}
+
+func TestSummarizeNamespaces(t *testing.T) {
+	summary := summarizeNamespaces([]kubernetes.WorkloadInfo{
+		{Namespace: "web", CPUUsed: 1.5, MemoryUsed: 2, RunningPods: 3},
+		{Namespace: "batch", CPUUsed: 4, MemoryUsed: 8, RunningPods: 2},
+		{Namespace: "web", CPUUsed: 0.5, MemoryUsed: 1, RunningPods: 1},
+	})
+
+	assert.Equal(t, []qaNamespace{
+		{Namespace: "batch", Workloads: 1, RunningPods: 2, CPURequested: 4, MemoryRequested: 8},
+		{Namespace: "web", Workloads: 2, RunningPods: 4, CPURequested: 2, MemoryRequested: 3},
+	}, summary)
+}
+
+func TestAskClusterRequiresKubernetes(t *testing.T) {
+	server := setupTestServer()
+
+	req := httptest.NewRequest("POST", "/api/v1/ask", strings.NewReader(`{"question": "Which namespaces waste the most CPU?"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "Kubernetes client not configured")
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPendingPodDiagnosis godoc
+// @Summary      Diagnose pending pods
+// @Description  Lists unschedulable pods and explains, per pod, why Karpenter is not provisioning for it. Each pod is evaluated against every NodePool (taints, requirements, architecture, limits) and correlated with FailedScheduling, Nominated and FailedProvisioning events.
+// @Tags         pods
+// @Accept       json
+// @Produce      json
+// @Param        namespace  query     string  false  "Namespace (default: all namespaces)"
+// @Success      200        {object}  map[string]interface{}  "Pending pod diagnoses"
+// @Failure      503        {object}  map[string]interface{}  "Kubernetes client not configured"
+// @Failure      500        {object}  map[string]interface{}  "Internal server error"
+// @Router       /pods/pending/diagnosis [get]
+func (s *Server) getPendingPodDiagnosis(c *gin.Context) {
+	if s.k8sClient == nil {
+		c.JSON(503, gin.H{"error": "Kubernetes client not configured"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	diagnoses, err := s.k8sClient.DiagnosePendingPods(ctx, c.Query("namespace"))
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	categories := make(map[string]int)
+	for _, d := range diagnoses {
+		categories[d.Category]++
+	}
+
+	c.JSON(200, gin.H{
+		"pods":       diagnoses,
+		"count":      len(diagnoses),
+		"categories": categories,
+	})
+}
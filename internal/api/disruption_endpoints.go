@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/recommender"
+)
+
+// GetDisruptionChurn godoc
+// @Summary      Get disruption churn cost per NodePool
+// @Description  Node lifetime distribution, disruptions per day by reason (consolidation, drift, expiration, spot interruption), pods evicted per day and the cost wasted by short-lived nodes (boot time plus the EC2 billed minimum), with recommended consolidateAfter and expireAfter values
+// @Tags         nodes
+// @Produce      json
+// @Param        days        query     int     false  "Days to analyze (default: 7)"
+// @Param        nodepool    query     string  false  "Only report this NodePool"
+// @Param        shortLived  query     string  false  "Nodes removed before this age are short-lived (default: 1h)"
+// @Param        bootTime    query     string  false  "Time a new node is billed before it runs pods (default: 3m)"
+// @Success      200         {object}  map[string]interface{}  "Churn report"
+// @Failure      400         {object}  map[string]interface{}  "Bad request"
+// @Failure      503         {object}  map[string]interface{}  "Kubernetes client not configured"
+// @Failure      500         {object}  map[string]interface{}  "Internal server error"
+// @Router       /disruptions/churn [get]
+func (s *Server) getDisruptionChurn(c *gin.Context) {
+	if s.k8sClient == nil {
+		c.JSON(503, gin.H{"error": "Kubernetes client not configured"})
+		return
+	}
+
+	days := 7
+	if daysStr := c.Query("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(400, gin.H{"error": "invalid days parameter, must be a positive integer"})
+			return
+		}
+		days = parsed
+	}
+	if maxDays := s.maxDisruptionHours() / 24; days > maxDays {
+		days = maxDays
+	}
+
+	var opts recommender.ChurnOptions
+	for param, target := range map[string]*time.Duration{"shortLived": &opts.ShortLivedThreshold, "bootTime": &opts.BootTime} {
+		if value := c.Query(param); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				c.JSON(400, gin.H{"error": "invalid " + param + " parameter, must be a positive duration (e.g. 30m)"})
+				return
+			}
+			*target = d
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	disruptions, err := s.k8sClient.GetNodeDisruptions(ctx, days*24)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	nodePools, err := s.k8sClient.ListNodePools(ctx)
+	if err != nil {
+		// Current settings are informational; the report still works without them
+		debugLog(s.config.Debug, "Warning: Failed to list NodePools for churn report: %v\n", err)
+		nodePools = nil
+	}
+
+	if nodePool := c.Query("nodepool"); nodePool != "" {
+		filtered := make([]kubernetes.NodeDisruptionInfo, 0, len(disruptions))
+		for _, d := range disruptions {
+			if d.NodePool == nodePool {
+				filtered = append(filtered, d)
+			}
+		}
+		disruptions = filtered
+	}
+
+	now := time.Now()
+	reports := s.recommender.AnalyzeDisruptionChurn(ctx, disruptions, nodePools, now.AddDate(0, 0, -days), now, opts)
+	totalWasted := 0.0
+	for _, report := range reports {
+		totalWasted += report.WastedCost
+	}
+
+	c.JSON(200, gin.H{
+		"nodePools":       reports,
+		"count":           len(reports),
+		"days":            days,
+		"totalWastedCost": totalWasted,
+		"historyRecorded": s.disruptionStore != nil, // Without recorded history only about an hour of events is available
+	})
+}
+
+// GetDisruptionAudit godoc
+// @Summary      Audit consolidation blockers
+// @Description  Proactively lists PDBs that allow 0 disruptions in steady state, single-replica workloads guarded by minAvailable 1, and pods/nodes annotated karpenter.sh/do-not-disrupt, with the node capacity and hourly cost Karpenter cannot consolidate because of them
+// @Tags         nodes
+// @Produce      json
+// @Param        nodepool  query     string  false  "Only report blocked nodes of this NodePool"
+// @Success      200       {object}  map[string]interface{}  "Disruption audit"
+// @Failure      503       {object}  map[string]interface{}  "Kubernetes client not configured"
+// @Failure      500       {object}  map[string]interface{}  "Internal server error"
+// @Router       /disruptions/audit [get]
+func (s *Server) getDisruptionAudit(c *gin.Context) {
+	if s.k8sClient == nil {
+		c.JSON(503, gin.H{"error": "Kubernetes client not configured"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	audit, err := s.k8sClient.AuditDisruptionBlockers(ctx)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	nodePool := c.Query("nodepool")
+	prices := make(map[string]float64) // instanceType/capacityType -> hourly cost
+	blockedNodes := make([]kubernetes.BlockedNode, 0, len(audit.BlockedNodes))
+	blockedCPU, blockedMemory, blockedHourlyCost := 0.0, 0.0, 0.0
+	byNodePool := make(map[string]float64)
+	for _, node := range audit.BlockedNodes {
+		if nodePool != "" && node.NodePool != nodePool {
+			continue
+		}
+		if node.InstanceType != "" {
+			capacityType := node.CapacityType
+			if capacityType == "" {
+				capacityType = "on-demand"
+			}
+			key := node.InstanceType + "/" + capacityType
+			price, ok := prices[key]
+			if !ok {
+				price = s.recommender.EstimateCost(ctx, []string{node.InstanceType}, capacityType, 1)
+				prices[key] = price
+			}
+			node.HourlyCost = price
+		}
+		blockedCPU += node.CPU
+		blockedMemory += node.MemoryGiB
+		blockedHourlyCost += node.HourlyCost
+		byNodePool[node.NodePool] += node.HourlyCost
+		blockedNodes = append(blockedNodes, node)
+	}
+	audit.BlockedNodes = blockedNodes
+	audit.BlockedCPU = blockedCPU
+	audit.BlockedMemoryGiB = blockedMemory
+
+	c.JSON(200, gin.H{
+		"audit":                   audit,
+		"count":                   len(blockedNodes),
+		"blockedHourlyCost":       blockedHourlyCost,
+		"blockedHourlyCostByPool": byNodePool,
+		"summary": fmt.Sprintf("%d node(s) (%.1f CPU, %.1f GiB, $%.2f/hour) cannot be consolidated: %d PDB(s) allow 0 disruptions (%d single-replica), %d do-not-disrupt pod(s), %d do-not-disrupt node(s)",
+			len(blockedNodes), blockedCPU, blockedMemory, blockedHourlyCost, len(audit.PDBs), len(audit.SingleReplicaWorkloads), len(audit.DoNotDisruptPods), len(audit.DoNotDisruptNodes)),
+	})
+}
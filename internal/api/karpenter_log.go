@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/karpenter-optimizer/internal/logrules"
+	"github.com/karpenter-optimizer/internal/prompts"
 )
 
 // KarpenterLogError represents a parsed Karpenter error log
@@ -44,6 +47,7 @@ type LogAnalysisResponse struct {
 	ParsedError     *KarpenterLogError `json:"parsedError,omitempty"`
 	Summary         string             `json:"summary"`
 	Explanation     string             `json:"explanation"`
+	PromptVersion   string             `json:"promptVersion,omitempty"` // Prompt template of an AI explanation, e.g. "log-error-explanation@1"
 	ErrorCauses     []ErrorCauseDetail `json:"errorCauses"`
 	Recommendations []string           `json:"recommendations"`
 	Error           string             `json:"error,omitempty"`
@@ -51,100 +55,51 @@ type LogAnalysisResponse struct {
 
 // ErrorCauseDetail provides detailed information about each error cause
 type ErrorCauseDetail struct {
-	Error       string `json:"error"`
-	Category    string `json:"category"`
-	Explanation string `json:"explanation"`
-	Severity    string `json:"severity"` // "critical", "warning", "info"
+	Error       string   `json:"error"`
+	Category    string   `json:"category"`
+	Explanation string   `json:"explanation"`
+	Severity    string   `json:"severity"`         // "critical", "warning", "info"
+	RuleID      string   `json:"ruleId,omitempty"` // Log rule that matched (empty when uncategorized)
+	Remediation []string `json:"remediation,omitempty"`
 }
 
-// parseKarpenterLog parses a JSON Karpenter error log
+// parseKarpenterLog parses a Karpenter error log in JSON or console format
 func parseKarpenterLog(logStr string) (*KarpenterLogError, error) {
 	var logError KarpenterLogError
 
-	// Try to parse as JSON
-	if err := json.Unmarshal([]byte(logStr), &logError); err != nil {
-		return nil, fmt.Errorf("failed to parse log as JSON: %w", err)
-	}
-
-	return &logError, nil
-}
-
-// categorizeErrorCause categorizes an error cause and provides basic explanation
-func categorizeErrorCause(errorMsg string) (category, explanation, severity string) {
-	errorLower := strings.ToLower(errorMsg)
-
-	// Admission webhook errors
-	if strings.Contains(errorLower, "admission webhook") || strings.Contains(errorLower, "denied the request") {
-		webhookName := ""
-		if strings.Contains(errorMsg, "admission webhook") {
-			parts := strings.Split(errorMsg, "admission webhook")
-			if len(parts) > 1 {
-				webhookPart := strings.TrimSpace(parts[1])
-				if strings.Contains(webhookPart, "denied") {
-					webhookName = strings.Split(webhookPart, "denied")[0]
-					webhookName = strings.Trim(webhookName, `"`)
-				}
-			}
-		}
-		return "Admission Webhook",
-			fmt.Sprintf("An admission webhook (%s) denied the request. This is typically a policy enforcement (e.g., Strimzi drain cleaner, Pod Security Standards). Check webhook configuration and pod spec compliance.", webhookName),
-			"critical"
-	}
-
-	// Reconciler errors
-	if strings.Contains(errorLower, "reconciler error") {
-		return "Reconciler Error",
-			"A controller reconciler encountered an error. This could be due to resource conflicts, webhook denials, or controller logic issues. Check the specific error message and controller logs.",
-			"critical"
+	fields, _, err := parseKarpenterLogFields(logStr)
+	if err != nil {
+		return nil, err
 	}
 
-	// Eviction errors
-	if strings.Contains(errorLower, "eviction") || strings.Contains(errorLower, "drain") {
-		return "Eviction/Drain Error",
-			"Pod eviction or node draining failed. This could be due to PodDisruptionBudgets, admission webhooks, or other protection mechanisms preventing the operation.",
-			"warning"
+	// Re-encode the canonical fields so both formats decode the same way
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse log: %w", err)
 	}
-
-	// Label/typo errors
-	if strings.Contains(errorLower, "does not have known values") || strings.Contains(errorLower, "typo") {
-		return "Label Error",
-			"This indicates a label typo or unknown label value. Check if you meant 'karpenter.sh/capacity-type' or 'karpenter.k8s.aws/capacity-reservation-type'.",
-			"critical"
+	if err := json.Unmarshal(data, &logError); err != nil {
+		return nil, fmt.Errorf("failed to parse log as JSON: %w", err)
 	}
 
-	// Taint tolerance errors
-	if strings.Contains(errorLower, "did not tolerate taint") {
-		// Extract taint name
-		taintMatch := ""
-		if strings.Contains(errorMsg, "taint=") {
-			parts := strings.Split(errorMsg, "taint=")
-			if len(parts) > 1 {
-				taintMatch = strings.TrimSpace(strings.Split(parts[1], ";")[0])
-			}
-		}
-		return "Taint Tolerance",
-			fmt.Sprintf("The pod cannot tolerate the taint '%s'. Add a matching toleration to the pod spec or remove the taint from the NodePool.", taintMatch),
-			"critical"
-	}
+	return &logError, nil
+}
 
-	// Instance type limits
-	if strings.Contains(errorLower, "exceed limits for nodepool") {
-		return "NodePool Limits",
-			"The NodePool has resource limits (CPU, memory, or instance type constraints) that prevent scheduling. Check NodePool spec limits and pod resource requests.",
-			"critical"
+// categorizeErrorCause categorizes an error cause using the log rule engine
+func (s *Server) categorizeErrorCause(errorMsg string) ErrorCauseDetail {
+	rules := s.logRules
+	if rules == nil {
+		rules = logrules.Default()
 	}
 
-	// Resource constraints
-	if strings.Contains(errorLower, "insufficient") || strings.Contains(errorLower, "not enough") {
-		return "Resource Constraint",
-			"Insufficient resources available in the cluster. Consider adding nodes or adjusting pod resource requests.",
-			"warning"
+	result := rules.Categorize(errorMsg)
+	return ErrorCauseDetail{
+		Error:       errorMsg,
+		Category:    result.Category,
+		Explanation: result.Explanation,
+		Severity:    result.Severity,
+		RuleID:      result.RuleID,
+		Remediation: result.Remediation,
 	}
-
-	// Default
-	return "Unknown",
-		"Unable to categorize this error. Review the error message for details.",
-		"info"
 }
 
 // analyzeKarpenterLogInternal analyzes a Karpenter error log and provides explanations
@@ -224,7 +179,40 @@ func (s *Server) analyzeKarpenterLogInternal(ctx context.Context, logStr string)
 		summary = fmt.Sprintf("Karpenter %s error detected", errorType)
 	}
 
-	// Analyze error causes - include main error field if errorCauses is empty
+	errorCauses := s.analyzeErrorCauses(parsedError)
+
+	// Generate recommendations
+	recommendations := s.generateLogRecommendations(parsedError, errorCauses)
+
+	// Generate AI explanation if Ollama is available
+	explanation := ""
+	promptVersion := ""
+	if s.recommender != nil && s.recommender.HasOllama() {
+		aiExplanation, version, err := s.generateAIExplanation(ctx, parsedError, errorCauses)
+		if err == nil && aiExplanation != "" {
+			explanation = aiExplanation
+			promptVersion = version
+		}
+	}
+
+	// If no AI explanation, provide a basic one
+	if explanation == "" {
+		explanation = s.generateBasicExplanation(parsedError, errorCauses)
+	}
+
+	return &LogAnalysisResponse{
+		ParsedError:     parsedError,
+		Summary:         summary,
+		Explanation:     explanation,
+		PromptVersion:   promptVersion,
+		ErrorCauses:     errorCauses,
+		Recommendations: recommendations,
+	}, nil
+}
+
+// analyzeErrorCauses categorizes the main error and each error cause of a parsed log, deduplicated
+func (s *Server) analyzeErrorCauses(parsedError *KarpenterLogError) []ErrorCauseDetail {
+	// Include main error field if errorCauses is empty
 	errorCauses := make([]ErrorCauseDetail, 0)
 	seenErrors := make(map[string]bool)
 
@@ -241,13 +229,7 @@ func (s *Server) analyzeKarpenterLogInternal(ctx context.Context, logStr string)
 		}
 		// If not found in errorCauses, analyze it (especially important when errorCauses is empty)
 		if !foundInCauses {
-			category, explanation, severity := categorizeErrorCause(parsedError.Error)
-			errorCauses = append(errorCauses, ErrorCauseDetail{
-				Error:       parsedError.Error,
-				Category:    category,
-				Explanation: explanation,
-				Severity:    severity,
-			})
+			errorCauses = append(errorCauses, s.categorizeErrorCause(parsedError.Error))
 			seenErrors[parsedError.Error] = true
 		}
 	}
@@ -260,39 +242,10 @@ func (s *Server) analyzeKarpenterLogInternal(ctx context.Context, logStr string)
 		}
 		seenErrors[cause.Error] = true
 
-		category, explanation, severity := categorizeErrorCause(cause.Error)
-		errorCauses = append(errorCauses, ErrorCauseDetail{
-			Error:       cause.Error,
-			Category:    category,
-			Explanation: explanation,
-			Severity:    severity,
-		})
-	}
-
-	// Generate recommendations
-	recommendations := s.generateLogRecommendations(parsedError, errorCauses)
-
-	// Generate AI explanation if Ollama is available
-	explanation := ""
-	if s.recommender != nil && s.recommender.HasOllama() {
-		aiExplanation, err := s.generateAIExplanation(ctx, parsedError, errorCauses)
-		if err == nil && aiExplanation != "" {
-			explanation = aiExplanation
-		}
-	}
-
-	// If no AI explanation, provide a basic one
-	if explanation == "" {
-		explanation = s.generateBasicExplanation(parsedError, errorCauses)
+		errorCauses = append(errorCauses, s.categorizeErrorCause(cause.Error))
 	}
 
-	return &LogAnalysisResponse{
-		ParsedError:     parsedError,
-		Summary:         summary,
-		Explanation:     explanation,
-		ErrorCauses:     errorCauses,
-		Recommendations: recommendations,
-	}, nil
+	return errorCauses
 }
 
 // generateBasicExplanation creates a basic explanation without AI
@@ -315,22 +268,23 @@ func (s *Server) generateBasicExplanation(parsedError *KarpenterLogError, errorC
 	return strings.Join(parts, "\n")
 }
 
-// generateAIExplanation uses Ollama to generate an intelligent explanation
-func (s *Server) generateAIExplanation(ctx context.Context, parsedError *KarpenterLogError, errorCauses []ErrorCauseDetail) (string, error) {
+// logExplanationPromptData is the data of the log-error-explanation prompt template
+type logExplanationPromptData struct {
+	ErrorType string
+	Context   []string
+	Causes    []ErrorCauseDetail
+}
+
+// generateAIExplanation uses Ollama to generate an intelligent explanation and returns it
+// with the version of the prompt template
+func (s *Server) generateAIExplanation(ctx context.Context, parsedError *KarpenterLogError, errorCauses []ErrorCauseDetail) (string, string, error) {
 	if s.recommender == nil || !s.recommender.HasOllama() {
-		return "", fmt.Errorf("ollama client not available")
+		return "", "", fmt.Errorf("ollama client not available")
 	}
 
 	ollamaClient := s.recommender.GetOllamaClient()
 	if ollamaClient == nil {
-		return "", fmt.Errorf("ollama client not available")
-	}
-
-	// Build prompt with available information
-	causesText := ""
-	for i, cause := range errorCauses {
-		causesText += fmt.Sprintf("%d. [%s] %s\n   Explanation: %s\n",
-			i+1, cause.Severity, cause.Error, cause.Explanation)
+		return "", "", fmt.Errorf("ollama client not available")
 	}
 
 	// Build context information dynamically based on what's available
@@ -371,11 +325,6 @@ func (s *Server) generateAIExplanation(ctx context.Context, parsedError *Karpent
 		contextParts = append(contextParts, fmt.Sprintf("- Error: %s", parsedError.Error))
 	}
 
-	contextInfo := strings.Join(contextParts, "\n")
-	if contextInfo == "" {
-		contextInfo = "No specific pod, namespace, or NodePool information available in the log."
-	}
-
 	// Determine error type for better prompt context
 	errorType := "scheduling"
 	if parsedError.Message != "" {
@@ -406,46 +355,25 @@ func (s *Server) generateAIExplanation(ctx context.Context, parsedError *Karpent
 		}
 	}
 
-	prompt := fmt.Sprintf(`You are a Kubernetes and Karpenter expert. Analyze this Karpenter error and provide a clear, actionable explanation.
-
-Error Type: %s
-
-Available Context:
-%s
-
-Error Causes:
-%s
-
-Provide a concise explanation (2-3 sentences) that:
-1. Identifies what type of error this is (%s error) and what it means
-2. Explains the root cause based on the error message and error causes
-3. Suggests specific, actionable steps to resolve the problem
-
-Be specific and technical, but clear. Focus on the most critical issues first. 
-- For reconciler errors: The "Error" field contains the actual failure reason (often an admission webhook denial). Explain what the controller (shown in "Controller" field) was trying to do, identify the specific webhook or issue that blocked it, and why. For example, if it's a Strimzi drain cleaner webhook, explain that it's preventing pod eviction because the Strimzi operator will handle rolling the pod.
-- For admission webhook errors: Identify which webhook denied the request (extract from the error message), explain why it denied (e.g., Strimzi drain cleaner prevents manual eviction, Pod Security Standards violation), and what action is needed.
-- For eviction errors: Explain what prevented the eviction (PodDisruptionBudgets, admission webhooks, etc.) and how to resolve it.
-- For scheduling errors: Focus on resource constraints, taints, labels, or NodePool limits.
-
-IMPORTANT: For reconciler errors, the "Error" field is the key - it contains the actual failure (often an admission webhook denial). Always analyze this field even if error causes are empty.
-
-If pod/namespace information is missing, analyze the error message, error field, and error causes to provide useful guidance.`,
-		errorType,
-		contextInfo,
-		causesText,
-		errorType,
-	)
+	prompt, err := s.recommender.Prompts().Render(prompts.LogErrorExplanation, logExplanationPromptData{
+		ErrorType: errorType,
+		Context:   contextParts,
+		Causes:    errorCauses,
+	})
+	if err != nil {
+		return "", "", err
+	}
 
 	// Use timeout for AI request
 	aiCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	response, err := ollamaClient.Chat(aiCtx, prompt)
+	response, err := ollamaClient.Chat(aiCtx, prompt.Text)
 	if err != nil {
-		return "", fmt.Errorf("AI explanation failed: %w", err)
+		return "", "", fmt.Errorf("AI explanation failed: %w", err)
 	}
 
-	return strings.TrimSpace(response), nil
+	return strings.TrimSpace(response), prompt.Version, nil
 }
 
 // generateLogRecommendations generates actionable recommendations for log analysis
@@ -453,20 +381,7 @@ func (s *Server) generateLogRecommendations(parsedError *KarpenterLogError, erro
 	recommendations := []string{}
 
 	for _, cause := range errorCauses {
-		switch cause.Category {
-		case "Label Error":
-			recommendations = append(recommendations,
-				"Check pod labels for typos. Common labels: 'karpenter.sh/capacity-type' (spot/on-demand), 'karpenter.k8s.aws/capacity-reservation-type'")
-		case "Taint Tolerance":
-			recommendations = append(recommendations,
-				"Add matching tolerations to the pod spec, or remove the taint from the NodePool if not needed")
-		case "NodePool Limits":
-			recommendations = append(recommendations,
-				"Review NodePool resource limits and pod resource requests. Ensure pod requests fit within NodePool constraints")
-		case "Resource Constraint":
-			recommendations = append(recommendations,
-				"Consider scaling the cluster or adjusting pod resource requests to match available capacity")
-		}
+		recommendations = append(recommendations, cause.Remediation...)
 	}
 
 	// Remove duplicates
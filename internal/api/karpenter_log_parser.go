@@ -0,0 +1,363 @@
+package api
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Log formats emitted by Karpenter (zap encoder)
+const (
+	LogFormatJSON    = "json"
+	LogFormatConsole = "console"
+)
+
+// KarpenterLogEntry is a single Karpenter log line parsed from either JSON or console format
+type KarpenterLogEntry struct {
+	Time       time.Time `json:"time,omitempty"`
+	Level      string    `json:"level"` // Upper-case: DEBUG, INFO, WARN, ERROR
+	Logger     string    `json:"logger,omitempty"`
+	Message    string    `json:"message"`
+	Controller string    `json:"controller,omitempty"`
+	NodeClaim  string    `json:"nodeClaim,omitempty"`
+	NodePool   string    `json:"nodePool,omitempty"`
+	Node       string    `json:"node,omitempty"`
+	Pod        string    `json:"pod,omitempty"` // namespace/name of the pod the line is about
+	Error      string    `json:"error,omitempty"`
+	Format     string    `json:"format"`
+	SourcePod  string    `json:"sourcePod,omitempty"` // Karpenter pod that emitted the line
+	Raw        string    `json:"-"`
+}
+
+// LogFingerprint aggregates log lines that normalize to the same message pattern
+type LogFingerprint struct {
+	ID            string    `json:"id"`
+	Pattern       string    `json:"pattern"`
+	Level         string    `json:"level"`
+	Controller    string    `json:"controller,omitempty"`
+	SampleMessage string    `json:"sampleMessage"`
+	SampleError   string    `json:"sampleError,omitempty"`
+	Count         int       `json:"count"`
+	FirstSeen     time.Time `json:"firstSeen,omitempty"`
+	LastSeen      time.Time `json:"lastSeen,omitempty"`
+	NodePools     []string  `json:"nodePools"`
+	SourcePods    []string  `json:"sourcePods"`
+	Category      string    `json:"category,omitempty"` // From the log rules, for ERROR fingerprints
+	Severity      string    `json:"severity,omitempty"`
+	RuleID        string    `json:"ruleId,omitempty"`
+}
+
+// consoleLogPattern matches zap console lines when fields are not tab-separated:
+// "2024-05-01T12:00:00.000Z  ERROR  controller.provisioner  could not schedule pod  {"commit": "..."}"
+var consoleLogPattern = regexp.MustCompile(`^(\S+)\s+(DEBUG|INFO|WARN|WARNING|ERROR|DPANIC|PANIC|FATAL|debug|info|warn|warning|error|dpanic|panic|fatal)\s+(\S+)\s+(.*?)\s*(\{.*\})?$`)
+
+// fingerprintReplacements strip variable parts of a message, most specific first
+var fingerprintReplacements = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`arn:aws[\w-]*:[^\s",]+`), "<arn>"},
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<uuid>"},
+	{regexp.MustCompile(`\b(i|ami|subnet|sg|lt|eni|vol|cr|fleet|vpc|snap)-[0-9a-f]{8,17}\b`), "<id>"},
+	{regexp.MustCompile(`\bip-\d+-\d+-\d+-\d+[\w.-]*`), "<node>"},
+	{regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}(/\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`"[^"]*"`), `"*"`},
+	{regexp.MustCompile(`'[^']*'`), `'*'`},
+	{regexp.MustCompile(`\b[a-z0-9]+(-[a-z0-9]+)*-[bcdfghjklmnpqrstvwxz2456789]{5}\b`), "<name>"}, // generated name suffixes (nodeclaims, pods)
+	{regexp.MustCompile(`\b[0-9a-f]{12,}\b`), "<hex>"},
+	{regexp.MustCompile(`\d+(\.\d+)?`), "<n>"},
+	{regexp.MustCompile(`\s+`), " "},
+}
+
+// parseKarpenterLogFields parses a JSON or console log line into a flat field map with canonical keys
+// (level, time, logger, message, controller, error, NodePool, NodeClaim, Node, Pod)
+func parseKarpenterLogFields(line string) (map[string]interface{}, string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, "", fmt.Errorf("empty log line")
+	}
+
+	fields := make(map[string]interface{})
+	format := LogFormatJSON
+	if strings.HasPrefix(line, "{") {
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			return nil, "", fmt.Errorf("failed to parse log as JSON: %w", err)
+		}
+	} else {
+		format = LogFormatConsole
+		if err := parseConsoleLogLine(line, fields); err != nil {
+			return nil, "", err
+		}
+	}
+
+	canonicalizeLogFields(fields)
+	return fields, format, nil
+}
+
+// parseConsoleLogLine parses zap console output: time, level, logger, [caller,] message, [JSON context]
+func parseConsoleLogLine(line string, fields map[string]interface{}) error {
+	var timeStr, level, logger, message, context string
+
+	parts := strings.Split(line, "\t")
+	if len(parts) >= 4 {
+		timeStr, level, logger = parts[0], parts[1], parts[2]
+		rest := parts[3:]
+		if last := strings.TrimSpace(rest[len(rest)-1]); strings.HasPrefix(last, "{") && len(rest) > 1 {
+			context = last
+			rest = rest[:len(rest)-1]
+		}
+		// With caller enabled the message follows the caller (file.go:123)
+		message = rest[len(rest)-1]
+	} else {
+		match := consoleLogPattern.FindStringSubmatch(line)
+		if match == nil {
+			return fmt.Errorf("failed to parse log: not JSON and not a recognized console format")
+		}
+		timeStr, level, logger, message, context = match[1], match[2], match[3], match[4], match[5]
+	}
+
+	if context != "" {
+		if err := json.Unmarshal([]byte(context), &fields); err != nil {
+			// Keep the context as part of the message rather than failing the line
+			message = strings.TrimSpace(message + " " + context)
+		}
+	}
+	fields["time"] = strings.TrimSpace(timeStr)
+	fields["level"] = strings.TrimSpace(level)
+	fields["logger"] = strings.TrimSpace(logger)
+	fields["message"] = strings.TrimSpace(message)
+	return nil
+}
+
+// canonicalizeLogFields normalizes field names and shapes across Karpenter versions:
+// v1 logs objects as {"NodePool": {"name": "x"}}, older versions as {"nodepool": "x"} / {"provisioner": "x"}.
+func canonicalizeLogFields(fields map[string]interface{}) {
+	if _, ok := fields["message"]; !ok {
+		if msg, ok := fields["msg"]; ok {
+			fields["message"] = msg
+			delete(fields, "msg")
+		}
+	}
+
+	// Epoch timestamps ("ts": 1714564800.123) become RFC3339
+	for _, key := range []string{"time", "ts"} {
+		if epoch, ok := fields[key].(float64); ok {
+			sec, frac := math.Modf(epoch)
+			fields["time"] = time.Unix(int64(sec), int64(frac*1e9)).UTC().Format(time.RFC3339Nano)
+			if key != "time" {
+				delete(fields, key)
+			}
+			break
+		}
+		if key == "ts" {
+			if ts, ok := fields[key].(string); ok && fields["time"] == nil {
+				fields["time"] = ts
+				delete(fields, key)
+			}
+		}
+	}
+	if timeStr, ok := fields["time"].(string); ok {
+		if _, err := time.Parse(time.RFC3339Nano, timeStr); err != nil {
+			// Unparseable timestamps would break decoding into time.Time
+			delete(fields, "time")
+		}
+	}
+
+	if level, ok := fields["level"].(string); ok {
+		level = strings.ToUpper(level)
+		if level == "WARNING" {
+			level = "WARN"
+		}
+		fields["level"] = level
+	}
+
+	canonical := map[string][]string{
+		"NodePool":  {"nodepool", "provisioner"},
+		"NodeClaim": {"nodeclaim", "machine"},
+		"Node":      {"node"},
+		"Pod":       {"pod"},
+	}
+	for key, aliases := range canonical {
+		var value interface{}
+		for k, v := range fields {
+			lower := strings.ToLower(k)
+			if lower == strings.ToLower(key) || containsString(aliases, lower) {
+				value = v
+				if k != key {
+					delete(fields, k)
+				}
+			}
+		}
+		if value == nil {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			obj := map[string]interface{}{"name": v}
+			if key == "Pod" {
+				if ns, name, found := strings.Cut(v, "/"); found {
+					obj = map[string]interface{}{"namespace": ns, "name": name}
+				}
+			}
+			fields[key] = obj
+		case map[string]interface{}:
+			fields[key] = v
+		}
+	}
+}
+
+// parseKarpenterLogEntry parses a single JSON or console Karpenter log line
+func parseKarpenterLogEntry(line string) (*KarpenterLogEntry, error) {
+	fields, format, err := parseKarpenterLogFields(line)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &KarpenterLogEntry{
+		Level:      stringField(fields, "level"),
+		Logger:     stringField(fields, "logger"),
+		Message:    stringField(fields, "message"),
+		Controller: stringField(fields, "controller"),
+		Error:      stringField(fields, "error"),
+		NodePool:   objectName(fields, "NodePool"),
+		NodeClaim:  objectName(fields, "NodeClaim"),
+		Node:       objectName(fields, "Node"),
+		Format:     format,
+		Raw:        line,
+	}
+	if pod, ok := fields["Pod"].(map[string]interface{}); ok {
+		name, _ := pod["name"].(string)
+		if namespace, _ := pod["namespace"].(string); namespace != "" {
+			name = namespace + "/" + name
+		}
+		entry.Pod = name
+	}
+	if timeStr := stringField(fields, "time"); timeStr != "" {
+		entry.Time, _ = time.Parse(time.RFC3339Nano, timeStr)
+	}
+	// Pre-v1 Karpenter encodes the controller in the logger name (controller.provisioner)
+	if entry.Controller == "" && strings.HasPrefix(entry.Logger, "controller.") {
+		entry.Controller = strings.TrimPrefix(entry.Logger, "controller.")
+	}
+	return entry, nil
+}
+
+// stringField returns a string field or ""
+func stringField(fields map[string]interface{}, key string) string {
+	value, _ := fields[key].(string)
+	return value
+}
+
+// objectName returns the name of a logged Kubernetes object ({"name": "..."})
+func objectName(fields map[string]interface{}, key string) string {
+	if obj, ok := fields[key].(map[string]interface{}); ok {
+		name, _ := obj["name"].(string)
+		return name
+	}
+	return ""
+}
+
+// containsString reports whether a slice contains a string
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// fingerprintLogMessage normalizes a message by stripping names, IDs, addresses and numbers
+// so that repeated occurrences of the same problem share a pattern
+func fingerprintLogMessage(message string) string {
+	normalized := message
+	for _, r := range fingerprintReplacements {
+		normalized = r.pattern.ReplaceAllString(normalized, r.replacement)
+	}
+	return strings.TrimSpace(normalized)
+}
+
+// fingerprintLogEntry returns the pattern and a short stable ID for an entry
+func fingerprintLogEntry(entry *KarpenterLogEntry) (string, string) {
+	pattern := fingerprintLogMessage(entry.Message)
+	if entry.Error != "" {
+		pattern += ": " + fingerprintLogMessage(entry.Error)
+	}
+	sum := sha1.Sum([]byte(entry.Level + "|" + entry.Controller + "|" + pattern))
+	return pattern, hex.EncodeToString(sum[:])[:12]
+}
+
+// aggregateLogFingerprints groups entries by fingerprint. Entries with a timestamp before
+// `since` are dropped; a zero `since` keeps everything. Results are sorted by count, descending.
+func aggregateLogFingerprints(entries []*KarpenterLogEntry, since time.Time) []LogFingerprint {
+	byID := make(map[string]*LogFingerprint)
+	nodePools := make(map[string]map[string]bool)
+	sourcePods := make(map[string]map[string]bool)
+
+	for _, entry := range entries {
+		if !since.IsZero() && !entry.Time.IsZero() && entry.Time.Before(since) {
+			continue
+		}
+		pattern, id := fingerprintLogEntry(entry)
+		fp, ok := byID[id]
+		if !ok {
+			fp = &LogFingerprint{
+				ID:            id,
+				Pattern:       pattern,
+				Level:         entry.Level,
+				Controller:    entry.Controller,
+				SampleMessage: entry.Message,
+				SampleError:   entry.Error,
+			}
+			byID[id] = fp
+			nodePools[id] = make(map[string]bool)
+			sourcePods[id] = make(map[string]bool)
+		}
+
+		fp.Count++
+		if !entry.Time.IsZero() {
+			if fp.FirstSeen.IsZero() || entry.Time.Before(fp.FirstSeen) {
+				fp.FirstSeen = entry.Time
+			}
+			if entry.Time.After(fp.LastSeen) {
+				fp.LastSeen = entry.Time
+			}
+		}
+		if entry.NodePool != "" {
+			nodePools[id][entry.NodePool] = true
+		}
+		if entry.SourcePod != "" {
+			sourcePods[id][entry.SourcePod] = true
+		}
+	}
+
+	result := make([]LogFingerprint, 0, len(byID))
+	for id, fp := range byID {
+		fp.NodePools = sortedKeys(nodePools[id])
+		fp.SourcePods = sortedKeys(sourcePods[id])
+		result = append(result, *fp)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].LastSeen.After(result[j].LastSeen)
+	})
+	return result
+}
+
+// sortedKeys returns the keys of a set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKarpenterLogEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected KarpenterLogEntry
+	}{
+		{
+			name: "v1 JSON",
+			line: `{"level":"ERROR","time":"2024-05-01T12:00:00.000Z","logger":"controller","message":"failed launching nodeclaim","controller":"nodeclaim.lifecycle","NodeClaim":{"name":"default-x7k2p"},"NodePool":{"name":"default"},"error":"creating instance, insufficient capacity"}`,
+			expected: KarpenterLogEntry{
+				Level: "ERROR", Logger: "controller", Message: "failed launching nodeclaim", Controller: "nodeclaim.lifecycle",
+				NodeClaim: "default-x7k2p", NodePool: "default", Error: "creating instance, insufficient capacity", Format: LogFormatJSON,
+			},
+		},
+		{
+			name: "pre-v1 JSON with string fields",
+			line: `{"level":"error","time":"2024-05-01T12:00:00.000Z","logger":"controller.provisioner","message":"could not schedule pod","pod":"default/web-1","nodepool":"general"}`,
+			expected: KarpenterLogEntry{
+				Level: "ERROR", Logger: "controller.provisioner", Message: "could not schedule pod", Controller: "provisioner",
+				NodePool: "general", Pod: "default/web-1", Format: LogFormatJSON,
+			},
+		},
+		{
+			name: "console with JSON context",
+			line: "2024-05-01T12:00:00.000Z\tERROR\tcontroller.provisioner\tcould not schedule pod\t{\"commit\": \"abc\", \"pod\": \"default/web-1\", \"error\": \"incompatible with nodepool \\\"gpu\\\"\"}",
+			expected: KarpenterLogEntry{
+				Level: "ERROR", Logger: "controller.provisioner", Message: "could not schedule pod", Controller: "provisioner",
+				Pod: "default/web-1", Error: `incompatible with nodepool "gpu"`, Format: LogFormatConsole,
+			},
+		},
+		{
+			name: "console space separated",
+			line: `2024-05-01T12:00:00.000Z  INFO  controller.disruption  disrupting via consolidation delete`,
+			expected: KarpenterLogEntry{
+				Level: "INFO", Logger: "controller.disruption", Message: "disrupting via consolidation delete", Controller: "disruption",
+				Format: LogFormatConsole,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := parseKarpenterLogEntry(tt.line)
+			require.NoError(t, err)
+			assert.Equal(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), entry.Time.UTC())
+			entry.Time = time.Time{}
+			entry.Raw = ""
+			assert.Equal(t, tt.expected, *entry)
+		})
+	}
+
+	_, err := parseKarpenterLogEntry("not a log line")
+	assert.Error(t, err)
+}
+
+func TestFingerprintLogMessage(t *testing.T) {
+	a := fingerprintLogMessage(`launched nodeclaim default-x7k2p, instance i-0abc123def4567890 in 10.0.12.4 with 3 pods`)
+	b := fingerprintLogMessage(`launched nodeclaim general-b9zqm, instance i-0fff000111222aaab in 10.0.99.7 with 12 pods`)
+	assert.Equal(t, a, b)
+	assert.Equal(t, "launched nodeclaim <name>, instance <id> in <ip> with <n> pods", a)
+
+	// Words that only look like generated names are kept
+	assert.Equal(t, "capacity-types on-demand", fingerprintLogMessage("capacity-types on-demand"))
+}
+
+func TestAggregateLogFingerprints(t *testing.T) {
+	now := time.Now()
+	entry := func(msg, nodePool, pod string, at time.Time) *KarpenterLogEntry {
+		return &KarpenterLogEntry{Level: "ERROR", Message: msg, NodePool: nodePool, SourcePod: pod, Time: at}
+	}
+	entries := []*KarpenterLogEntry{
+		entry(`could not launch nodeclaim "default-x7k2p"`, "default", "karpenter-a", now.Add(-10*time.Minute)),
+		entry(`could not launch nodeclaim "gpu-b9zqm"`, "gpu", "karpenter-b", now.Add(-5*time.Minute)),
+		entry(`could not launch nodeclaim "gpu-c2lkq"`, "gpu", "karpenter-b", now.Add(-2*time.Hour)), // outside window
+		entry("disrupting via consolidation", "default", "karpenter-a", now.Add(-time.Minute)),
+	}
+
+	fingerprints := aggregateLogFingerprints(entries, now.Add(-time.Hour))
+	require.Len(t, fingerprints, 2)
+
+	top := fingerprints[0]
+	assert.Equal(t, 2, top.Count)
+	assert.Equal(t, `could not launch nodeclaim "*"`, top.Pattern)
+	assert.Equal(t, []string{"default", "gpu"}, top.NodePools)
+	assert.Equal(t, []string{"karpenter-a", "karpenter-b"}, top.SourcePods)
+	assert.True(t, top.FirstSeen.Before(top.LastSeen))
+	assert.Len(t, top.ID, 12)
+}
+
+func TestKarpenterLogFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newFilter := func(query string) (*karpenterLogFilter, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/api/v1/karpenter/logs/stream?"+query, nil)
+		return newKarpenterLogFilter(c)
+	}
+
+	entry, err := parseKarpenterLogEntry(`{"level":"ERROR","logger":"controller","message":"failed launching nodeclaim","controller":"nodeclaim.lifecycle","NodePool":{"name":"gpu"},"error":"InsufficientInstanceCapacity"}`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		query   string
+		matches bool
+	}{
+		{"", true},
+		{"level=error,warn", true},
+		{"level=INFO", false},
+		{"controller=LIFECYCLE", true},
+		{"controller=disruption", false},
+		{"nodepool=gpu", true},
+		{"nodepool=default", false},
+		{"regex=Insufficient%5Cw%2BCapacity", true},
+		{"level=ERROR&nodepool=default", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			filter, err := newFilter(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.matches, filter.matches(entry))
+		})
+	}
+
+	_, err = newFilter("regex=%28")
+	assert.Error(t, err)
+}
+
+func TestGetKarpenterLogFingerprintsRequiresKubernetes(t *testing.T) {
+	server := setupTestServer()
+
+	req := httptest.NewRequest("GET", "/api/v1/karpenter/logs/fingerprints", nil)
+	w := httptest.NewRecorder()
+
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "Kubernetes client not configured")
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/karpenter-optimizer/internal/kubernetes"
+)
+
+// logAnalysisInterval limits how often the same error fingerprint is re-analyzed on a stream
+const logAnalysisInterval = time.Minute
+
+// karpenterLogFilter holds the server-side filters for the log stream
+type karpenterLogFilter struct {
+	levels     map[string]bool
+	controller string
+	nodePool   string
+	pattern    *regexp.Regexp
+}
+
+// newKarpenterLogFilter builds a filter from query parameters
+func newKarpenterLogFilter(c *gin.Context) (*karpenterLogFilter, error) {
+	filter := &karpenterLogFilter{
+		controller: strings.ToLower(c.Query("controller")),
+		nodePool:   c.Query("nodepool"),
+	}
+	if levels := c.Query("level"); levels != "" {
+		filter.levels = make(map[string]bool)
+		for _, level := range strings.Split(levels, ",") {
+			level = strings.ToUpper(strings.TrimSpace(level))
+			if level == "WARNING" {
+				level = "WARN"
+			}
+			filter.levels[level] = true
+		}
+	}
+	if expr := c.Query("regex"); expr != "" {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		filter.pattern = re
+	}
+	return filter, nil
+}
+
+// matches reports whether a parsed entry passes all filters
+func (f *karpenterLogFilter) matches(entry *KarpenterLogEntry) bool {
+	if f.levels != nil && !f.levels[entry.Level] {
+		return false
+	}
+	if f.controller != "" &&
+		!strings.Contains(strings.ToLower(entry.Controller), f.controller) &&
+		!strings.Contains(strings.ToLower(entry.Logger), f.controller) {
+		return false
+	}
+	if f.nodePool != "" && entry.NodePool != f.nodePool {
+		return false
+	}
+	if f.pattern != nil && !f.pattern.MatchString(entry.Raw) {
+		return false
+	}
+	return true
+}
+
+// StreamKarpenterLogs godoc
+// @Summary      Stream Karpenter logs
+// @Description  Follows logs from all Karpenter pods over Server-Sent Events, surviving leader changes and pod restarts. Lines are parsed (JSON or console format) and filtered server-side; ERROR lines are run through the log rules and sent as analysis events (at most once per minute per error fingerprint). Events: status, log, analysis, heartbeat, error.
+// @Tags         karpenter
+// @Produce      text/event-stream
+// @Param        level       query     string  false  "Comma-separated levels to include (e.g. ERROR,WARN)"
+// @Param        controller  query     string  false  "Only lines whose controller/logger contains this value"
+// @Param        nodepool    query     string  false  "Only lines about this NodePool"
+// @Param        regex       query     string  false  "Only lines whose raw text matches this regular expression"
+// @Param        since       query     string  false  "Backfill this much history before following (e.g. 5m)" default(0s)
+// @Success      200         {string}  text/event-stream  "SSE stream of Karpenter log entries"
+// @Failure      400         {object}  map[string]interface{}  "Invalid filter"
+// @Failure      503         {object}  map[string]interface{}  "Kubernetes client not configured"
+// @Router       /karpenter/logs/stream [get]
+func (s *Server) streamKarpenterLogs(c *gin.Context) {
+	if s.k8sClient == nil {
+		c.JSON(503, gin.H{"error": "Kubernetes client not configured"})
+		return
+	}
+
+	filter, err := newKarpenterLogFilter(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	var since time.Duration
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		since, err = time.ParseDuration(sinceStr)
+		if err != nil || since < 0 {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("invalid since duration: %q", sinceStr)})
+			return
+		}
+	}
+
+	// Set up SSE headers
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable nginx buffering
+
+	// Follow until the client disconnects
+	ctx := c.Request.Context()
+	events := s.k8sClient.FollowKarpenterLogs(ctx, since, 10*time.Second)
+
+	c.SSEvent("status", gin.H{"type": "connected", "message": "Following Karpenter logs"})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	lastAnalyzed := make(map[string]time.Time)
+	occurrences := make(map[string]int)
+	linesReceived, linesSent, unparsed := 0, 0, 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			c.SSEvent("heartbeat", gin.H{"linesReceived": linesReceived, "linesSent": linesSent, "linesUnparsed": unparsed})
+			c.Writer.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.Type != kubernetes.LogEventLine {
+				c.SSEvent("status", event)
+				c.Writer.Flush()
+				continue
+			}
+
+			linesReceived++
+			entry, err := parseKarpenterLogEntry(event.Line)
+			if err != nil {
+				unparsed++
+				continue
+			}
+			entry.SourcePod = event.Pod
+			if !filter.matches(entry) {
+				continue
+			}
+
+			linesSent++
+			c.SSEvent("log", entry)
+
+			if entry.Level == "ERROR" {
+				pattern, id := fingerprintLogEntry(entry)
+				occurrences[id]++
+				if time.Since(lastAnalyzed[id]) >= logAnalysisInterval {
+					lastAnalyzed[id] = time.Now()
+					c.SSEvent("analysis", s.analyzeStreamedError(entry, id, pattern, occurrences[id]))
+				}
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// analyzeStreamedError runs an ERROR line through the log rules (no LLM call, to keep the stream live)
+func (s *Server) analyzeStreamedError(entry *KarpenterLogEntry, fingerprint, pattern string, occurrences int) gin.H {
+	analysis := gin.H{
+		"fingerprint": fingerprint,
+		"pattern":     pattern,
+		"occurrences": occurrences,
+		"entry":       entry,
+	}
+
+	parsedError, err := parseKarpenterLog(entry.Raw)
+	if err != nil {
+		// Fall back to categorizing the message alone
+		message := entry.Error
+		if message == "" {
+			message = entry.Message
+		}
+		cause := s.categorizeErrorCause(message)
+		analysis["errorCauses"] = []ErrorCauseDetail{cause}
+		analysis["recommendations"] = cause.Remediation
+		return analysis
+	}
+
+	errorCauses := s.analyzeErrorCauses(parsedError)
+	if len(errorCauses) == 0 {
+		errorCauses = append(errorCauses, s.categorizeErrorCause(parsedError.Message))
+	}
+	analysis["errorCauses"] = errorCauses
+	analysis["recommendations"] = s.generateLogRecommendations(parsedError, errorCauses)
+	return analysis
+}
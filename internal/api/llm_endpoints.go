@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/karpenter-optimizer/internal/ollama"
+)
+
+// GetLLMRejections godoc
+// @Summary      Get rejected LLM outputs
+// @Description  Lists the most recent LLM-suggested values that failed validation and were not used, newest first: instance types outside the instance catalog or the NodePool's requirements, inconsistent min/max sizes, invalid tool agent plan changes, and instance prices outside the plausible band around the family estimate
+// @Tags         llm
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Rejected outputs with reasons"
+// @Failure      503  {object}  map[string]interface{}  "Service not configured"
+// @Router       /llm/rejections [get]
+func (s *Server) getLLMRejections(c *gin.Context) {
+	if s.recommender == nil {
+		c.JSON(503, gin.H{"error": "Recommender not configured"})
+		return
+	}
+
+	rejections := s.recommender.RecentLLMRejections()
+	c.JSON(200, gin.H{
+		"rejections": rejections,
+		"count":      len(rejections),
+	})
+}
+
+// GetLLMCache godoc
+// @Summary      Get LLM response cache statistics
+// @Description  Returns the size, bounds and hit/miss counters of the cache that answers repeated LLM prompts (keyed by model and whitespace-normalized prompt) without calling the LLM
+// @Tags         llm
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Cache statistics"
+// @Router       /llm/cache [get]
+func (s *Server) getLLMCache(c *gin.Context) {
+	cache := s.llmCache()
+	if cache == nil {
+		c.JSON(200, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(200, gin.H{
+		"enabled": true,
+		"stats":   cache.Stats(),
+	})
+}
+
+// ClearLLMCache godoc
+// @Summary      Clear the LLM response cache
+// @Description  Drops every cached LLM reply so the next prompts are sent to the LLM; the hit/miss counters are kept
+// @Tags         llm
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Cache cleared"
+// @Failure      503  {object}  map[string]interface{}  "LLM response cache not enabled"
+// @Router       /llm/cache [delete]
+func (s *Server) clearLLMCache(c *gin.Context) {
+	cache := s.llmCache()
+	if cache == nil {
+		c.JSON(503, gin.H{"error": "LLM response cache not enabled"})
+		return
+	}
+	cleared := cache.Stats().Entries
+	cache.Clear()
+	c.JSON(200, gin.H{"cleared": cleared})
+}
+
+// GetLLMProviders godoc
+// @Summary      Get LLM provider status
+// @Description  Lists the LLM providers in fallback order with their circuit breaker state (closed, open or half-open) and request, failure, retry and rejection counters. While every circuit is open, AI enhancement is skipped and recommendations use the rule-based reasoning.
+// @Tags         llm
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Providers and whether the LLM is available"
+// @Router       /llm/providers [get]
+func (s *Server) getLLMProviders(c *gin.Context) {
+	if s.recommender == nil || s.recommender.GetOllamaClient() == nil {
+		c.JSON(200, gin.H{"configured": false, "available": false, "providers": []ollama.ProviderStatus{}})
+		return
+	}
+	llm := s.recommender.GetOllamaClient()
+	c.JSON(200, gin.H{
+		"configured": true,
+		"available":  llm.Available(),
+		"providers":  llm.ProviderStatuses(),
+	})
+}
+
+// GetLLMPrompts godoc
+// @Summary      Get LLM prompt templates
+// @Description  Lists the prompt templates with their version and whether they are embedded or loaded from the override directory (PROMPT_TEMPLATES_DIR). AI outputs record the template they came from as name@version.
+// @Tags         llm
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Prompt templates"
+// @Failure      503  {object}  map[string]interface{}  "Service not configured"
+// @Router       /llm/prompts [get]
+func (s *Server) getLLMPrompts(c *gin.Context) {
+	if s.recommender == nil {
+		c.JSON(503, gin.H{"error": "Recommender not configured"})
+		return
+	}
+	c.JSON(200, gin.H{"templates": s.recommender.Prompts().List()})
+}
+
+// llmCache returns the LLM client's response cache, or nil when there is none
+func (s *Server) llmCache() *ollama.ResponseCache {
+	if s.recommender == nil || s.recommender.GetOllamaClient() == nil {
+		return nil
+	}
+	return s.recommender.GetOllamaClient().Cache()
+}
+
+// llmCacheContext makes the request's LLM calls skip the response cache when ?noCache=true
+func llmCacheContext(ctx context.Context, c *gin.Context) context.Context {
+	if c.Query("noCache") == "true" {
+		return ollama.WithoutCache(ctx)
+	}
+	return ctx
+}
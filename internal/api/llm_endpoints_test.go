@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/karpenter-optimizer/internal/prompts"
+	"github.com/karpenter-optimizer/internal/recommender"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLLMRejections(t *testing.T) {
+	server := setupTestServer()
+	server.recommender.RecordLLMRejections(
+		recommender.LLMRejection{NodePool: "default", Field: "instanceTypes", Value: "m9z.large", Reason: "instance family m9z is not in the catalog"},
+		recommender.LLMRejection{Field: "pricePerHour", Value: "$12.5000/hr for c7i.large", Reason: "outside the plausible range"},
+	)
+
+	req := httptest.NewRequest("GET", "/api/v1/llm/rejections", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Rejections []recommender.LLMRejection `json:"rejections"`
+		Count      int                        `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 2, body.Count)
+	assert.Equal(t, "pricePerHour", body.Rejections[0].Field)
+	assert.Equal(t, "m9z.large", body.Rejections[1].Value)
+}
+
+func TestLLMCacheEndpoints(t *testing.T) {
+	server := setupTestServer()
+
+	req := httptest.NewRequest("GET", "/api/v1/llm/cache", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"enabled": false}`, w.Body.String())
+
+	req = httptest.NewRequest("DELETE", "/api/v1/llm/cache", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestGetLLMProviders(t *testing.T) {
+	server := setupTestServer()
+
+	req := httptest.NewRequest("GET", "/api/v1/llm/providers", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"configured": false, "available": false, "providers": []}`, w.Body.String())
+}
+
+func TestGetLLMPrompts(t *testing.T) {
+	server := setupTestServer()
+
+	req := httptest.NewRequest("GET", "/api/v1/llm/prompts", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Templates []prompts.Template `json:"templates"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Templates, len(prompts.Names()))
+	assert.Equal(t, prompts.ClusterSummaryAdvice, body.Templates[0].Name)
+	assert.Equal(t, "1", body.Templates[0].Version)
+	assert.Equal(t, prompts.SourceEmbedded, body.Templates[0].Source)
+}
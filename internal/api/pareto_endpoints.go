@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/karpenter-optimizer/internal/agent"
+)
+
+// paretoWeights reads objective weights from the query, defaulting to agent.DefaultParetoWeights
+func paretoWeights(c *gin.Context) (agent.ParetoWeights, error) {
+	weights := agent.DefaultParetoWeights
+	for param, weight := range map[string]*float64{
+		"costWeight":       &weights.Cost,
+		"riskWeight":       &weights.Risk,
+		"efficiencyWeight": &weights.Efficiency,
+		"disruptionWeight": &weights.Disruption,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 {
+			return weights, fmt.Errorf("%s must be a non-negative number", param)
+		}
+		*weight = parsed
+	}
+	return weights, nil
+}
+
+// GetParetoPlans godoc
+// @Summary      Get cost-risk Pareto plan sets
+// @Description  For each NodePool, sizes candidate configurations (recommended and current instance types, on-demand and spot, 10/25/40% headroom), scores them on hourly cost, interruption risk, bin-packing efficiency and disruptions, and returns the Pareto-optimal plans with a recommended pick. Nothing is stored; POST /agent/pareto proposes the plans.
+// @Tags         agent
+// @Produce      json
+// @Param        nodepool          query     string  false  "Only this NodePool"
+// @Param        costWeight        query     number  false  "Weight of hourly cost when picking the recommended plan (default 0.4)"
+// @Param        riskWeight        query     number  false  "Weight of interruption risk (default 0.25)"
+// @Param        efficiencyWeight  query     number  false  "Weight of bin-packing efficiency (default 0.2)"
+// @Param        disruptionWeight  query     number  false  "Weight of disrupted nodes (default 0.15)"
+// @Success      200               {object}  map[string]interface{}  "Pareto plan sets"
+// @Failure      400               {object}  map[string]interface{}  "Invalid weight"
+// @Failure      500               {object}  map[string]interface{}  "Internal server error"
+// @Failure      503               {object}  map[string]interface{}  "Service not configured"
+// @Router       /agent/pareto [get]
+func (s *Server) getParetoPlans(c *gin.Context) {
+	s.respondParetoPlans(c, false)
+}
+
+// ProposeParetoPlans godoc
+// @Summary      Propose cost-risk Pareto plan sets
+// @Description  Generates the Pareto plan sets like GET /agent/pareto and stores every plan as proposed (strategy "pareto") so any trade-off can be approved. A newer set for a NodePool expires its previous one.
+// @Tags         agent
+// @Produce      json
+// @Param        nodepool          query     string  false  "Only this NodePool"
+// @Param        costWeight        query     number  false  "Weight of hourly cost when picking the recommended plan (default 0.4)"
+// @Param        riskWeight        query     number  false  "Weight of interruption risk (default 0.25)"
+// @Param        efficiencyWeight  query     number  false  "Weight of bin-packing efficiency (default 0.2)"
+// @Param        disruptionWeight  query     number  false  "Weight of disrupted nodes (default 0.15)"
+// @Success      200               {object}  map[string]interface{}  "Proposed Pareto plan sets"
+// @Failure      400               {object}  map[string]interface{}  "Invalid weight"
+// @Failure      500               {object}  map[string]interface{}  "Internal server error"
+// @Failure      503               {object}  map[string]interface{}  "Service not configured"
+// @Router       /agent/pareto [post]
+func (s *Server) proposeParetoPlans(c *gin.Context) {
+	s.respondParetoPlans(c, true)
+}
+
+// respondParetoPlans generates the Pareto plan sets, storing them as proposed plans when propose is set
+func (s *Server) respondParetoPlans(c *gin.Context, propose bool) {
+	if s.recommender == nil || s.k8sClient == nil {
+		c.JSON(503, gin.H{"error": "Recommender or Kubernetes client not configured"})
+		return
+	}
+	weights, err := paretoWeights(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 120*time.Second)
+	defer cancel()
+
+	sets, err := s.costAgent.GenerateParetoPlans(ctx, c.Query("nodepool"), weights)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	if propose {
+		var plans []*agent.OptimizationPlan
+		for _, set := range sets {
+			plans = append(plans, set.Plans...)
+		}
+		if err := s.planStore.Propose(plans, time.Now()); err != nil {
+			c.JSON(500, gin.H{"error": fmt.Sprintf("failed to store Pareto plans: %v", err)})
+			return
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"sets":     sets,
+		"count":    len(sets),
+		"weights":  weights,
+		"proposed": propose,
+	})
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/karpenter-optimizer/internal/agent"
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/recommender"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParetoPlansProposedOnlyOnPost(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "karpenter.sh", Version: "v1", Resource: "nodepools"}
+	np := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "karpenter.sh/v1",
+		"kind":       "NodePool",
+		"metadata":   map[string]interface{}{"name": "default"},
+		"spec":       map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{}}},
+	}}
+	var objects []runtime.Object
+	for i := 0; i < 3; i++ {
+		name := fmt.Sprintf("node-%d", i)
+		objects = append(objects,
+			&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{
+					"karpenter.sh/nodepool":            "default",
+					"karpenter.sh/capacity-type":       "on-demand",
+					"node.kubernetes.io/instance-type": "m5.4xlarge",
+					"kubernetes.io/arch":               "amd64",
+				}},
+				Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("15.9"),
+					corev1.ResourceMemory: resource.MustParse("61Gi"),
+					corev1.ResourcePods:   resource.MustParse("110"),
+				}},
+			},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "app-" + name, Namespace: "default"},
+				Spec: corev1.PodSpec{NodeName: name, Containers: []corev1.Container{{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("2"),
+						corev1.ResourceMemory: resource.MustParse("6Gi"),
+					}},
+				}}},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning},
+			})
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{gvr: "NodePoolList"}, np)
+
+	server := setupTestServer()
+	server.recommender = recommender.NewOfflineRecommender(server.config)
+	server.k8sClient = kubernetes.NewClientFromInterfaces(fake.NewSimpleClientset(objects...), dynamicClient)
+	server.costAgent = agent.NewCostOptimizationAgentWithLearning(server.recommender, server.k8sClient, agent.StrategyBalanced, nil)
+
+	request := func(method string) (sets int, proposed bool) {
+		req := httptest.NewRequest(method, "/api/v1/agent/pareto?nodepool=default", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp struct {
+			Count    int  `json:"count"`
+			Proposed bool `json:"proposed"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Count, resp.Proposed
+	}
+
+	sets, proposed := request("GET")
+	require.Equal(t, 1, sets)
+	assert.False(t, proposed)
+	plans, err := server.planStore.List(agent.PlanFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, plans, "previewing the sets stores nothing")
+
+	_, proposed = request("POST")
+	assert.True(t, proposed)
+	plans, err = server.planStore.List(agent.PlanFilter{Status: agent.PlanStatusProposed})
+	require.NoError(t, err)
+	require.NotEmpty(t, plans)
+	for _, plan := range plans {
+		assert.Equal(t, agent.StrategyPareto, plan.Strategy)
+	}
+}
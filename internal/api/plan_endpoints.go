@@ -0,0 +1,316 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/karpenter-optimizer/internal/agent"
+)
+
+// planReviewRequest is the body of plan approve/reject/status requests
+type planReviewRequest struct {
+	Approver string `json:"approver"`
+	Comment  string `json:"comment"`
+	Status   string `json:"status,omitempty"` // Only for the status endpoint
+}
+
+// reviewerHeaders carry the authenticated user when the API is behind an auth proxy
+var reviewerHeaders = []string{"X-Auth-Request-User", "X-Forwarded-User", "X-Remote-User"}
+
+// reviewerIdentity returns the authenticated user from an auth proxy header, else the
+// approver in the body. The headers are only read with AUTH_PROXY_ENABLED: without a
+// proxy that sets them, any caller could send them.
+func (s *Server) reviewerIdentity(c *gin.Context, body planReviewRequest) string {
+	if s.config.AuthProxyEnabled {
+		for _, header := range reviewerHeaders {
+			if user := strings.TrimSpace(c.GetHeader(header)); user != "" {
+				return user
+			}
+		}
+	}
+	return strings.TrimSpace(body.Approver)
+}
+
+// nodePoolFingerprints maps every NodePool to its current fingerprint. It returns nil
+// when the NodePools can't be listed.
+func (s *Server) nodePoolFingerprints(ctx context.Context) map[string]string {
+	if s.k8sClient == nil {
+		return nil
+	}
+	nodePools, err := s.k8sClient.ListNodePoolSpecs(ctx)
+	if err != nil {
+		debugLog(s.config.Debug, "Warning: Failed to list NodePools for plan expiry: %v\n", err)
+		return nil
+	}
+	fingerprints := make(map[string]string, len(nodePools))
+	for _, np := range nodePools {
+		fingerprints[np.Name] = agent.NodePoolFingerprint(np)
+	}
+	return fingerprints
+}
+
+// expireChangedPlans expires open plans whose NodePool changed or was deleted since they were created
+func (s *Server) expireChangedPlans(ctx context.Context) {
+	fingerprints := s.nodePoolFingerprints(ctx)
+	if fingerprints == nil {
+		return
+	}
+	if expired, err := s.planStore.ExpireChanged(fingerprints, time.Now()); err != nil {
+		fmt.Printf("Warning: Failed to expire changed plans: %v\n", err)
+	} else if expired > 0 {
+		debugLog(s.config.Debug, "Expired %d plan(s) whose NodePool changed\n", expired)
+	}
+}
+
+// stalePlans maps the open plans whose NodePool changed since they were created to why. Read-only
+// endpoints report them; they are only expired when a plan is approved or applied.
+func (s *Server) stalePlans(ctx context.Context, plans []*agent.OptimizationPlan) map[string]string {
+	stale := make(map[string]string)
+	fingerprints := s.nodePoolFingerprints(ctx)
+	if fingerprints == nil {
+		return stale
+	}
+	for _, plan := range plans {
+		if reason := plan.StaleReason(fingerprints); reason != "" {
+			stale[plan.ID] = reason
+		}
+	}
+	return stale
+}
+
+// markPlanVerified moves an applied plan to verified once its outcome has been measured
+func (s *Server) markPlanVerified(outcome agent.OptimizationOutcome) {
+	plan, err := s.planStore.Get(outcome.PlanID)
+	if err != nil || plan.Status != agent.PlanStatusApplied {
+		return // Outcome recorded without a stored plan, or plan already closed
+	}
+	comment := fmt.Sprintf("actual savings $%.4f/hour (predicted $%.4f/hour)", outcome.ActualSavings, outcome.PredictedSavings)
+	if len(outcome.Incidents) > 0 {
+		comment += "; incidents: " + strings.Join(outcome.Incidents, "; ")
+	}
+	if _, err := s.planStore.Transition(outcome.PlanID, agent.PlanStatusVerified, "verifier", comment, time.Now()); err != nil {
+		fmt.Printf("Warning: Failed to mark plan %s verified: %v\n", outcome.PlanID, err)
+	}
+}
+
+// planError maps plan store errors to HTTP responses
+func planError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, agent.ErrPlanNotFound):
+		c.JSON(404, gin.H{"error": err.Error()})
+	case errors.Is(err, agent.ErrInvalidPlanTransition), errors.Is(err, agent.ErrPlanConflict):
+		c.JSON(409, gin.H{"error": err.Error()})
+	default:
+		c.JSON(500, gin.H{"error": err.Error()})
+	}
+}
+
+// ListOptimizationPlans godoc
+// @Summary      List optimization plans
+// @Description  Stored optimization plans with their lifecycle status (proposed, approved, rejected, applied, verified, rolled-back, expired), newest first. stale maps open plans whose NodePool changed since they were created to why; they are expired when approved or applied.
+// @Tags         agent
+// @Produce      json
+// @Param        nodepool  query     string  false  "Only plans for this NodePool"
+// @Param        status    query     string  false  "Only plans in this status"
+// @Success      200       {object}  map[string]interface{}  "Plans"
+// @Failure      500       {object}  map[string]interface{}  "Internal server error"
+// @Router       /agent/plans [get]
+func (s *Server) listOptimizationPlans(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	plans, err := s.planStore.List(agent.PlanFilter{NodePool: c.Query("nodepool"), Status: agent.PlanStatus(c.Query("status"))})
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"plans": plans,
+		"count": len(plans),
+		"stale": s.stalePlans(ctx, plans),
+	})
+}
+
+// GetOptimizationPlan godoc
+// @Summary      Get an optimization plan
+// @Description  A stored optimization plan with its status and transition history. staleReason is set when the plan is open and its NodePool changed since it was created.
+// @Tags         agent
+// @Produce      json
+// @Param        id   path      string  true  "Plan ID"
+// @Success      200  {object}  map[string]interface{}  "Plan"
+// @Failure      404  {object}  map[string]interface{}  "Plan not found"
+// @Router       /agent/plans/{id} [get]
+func (s *Server) getOptimizationPlan(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	plan, err := s.planStore.Get(c.Param("id"))
+	if err != nil {
+		planError(c, err)
+		return
+	}
+	response := gin.H{"plan": plan}
+	if reason := s.stalePlans(ctx, []*agent.OptimizationPlan{plan})[plan.ID]; reason != "" {
+		response["staleReason"] = reason
+	}
+	c.JSON(200, response)
+}
+
+// ApproveOptimizationPlan godoc
+// @Summary      Approve an optimization plan
+// @Description  Approve a proposed plan. The approver is taken from the X-Auth-Request-User, X-Forwarded-User or X-Remote-User header when AUTH_PROXY_ENABLED is set, else from the body. Plans whose NodePool changed since they were created are expired and cannot be approved.
+// @Tags         agent
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string  true  "Plan ID"
+// @Param        review  body      object  true  "Approver and comment"
+// @Success      200     {object}  map[string]interface{}  "Approved plan"
+// @Failure      400     {object}  map[string]interface{}  "Missing approver"
+// @Failure      404     {object}  map[string]interface{}  "Plan not found"
+// @Failure      409     {object}  map[string]interface{}  "Plan is not in a state that can be approved"
+// @Router       /agent/plans/{id}/approve [post]
+func (s *Server) approveOptimizationPlan(c *gin.Context) {
+	s.reviewOptimizationPlan(c, agent.PlanStatusApproved)
+}
+
+// RejectOptimizationPlan godoc
+// @Summary      Reject an optimization plan
+// @Description  Reject a proposed or approved plan with a comment. The approver is taken from an auth proxy header (with AUTH_PROXY_ENABLED) or the body.
+// @Tags         agent
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string  true  "Plan ID"
+// @Param        review  body      object  true  "Approver and comment"
+// @Success      200     {object}  map[string]interface{}  "Rejected plan"
+// @Failure      400     {object}  map[string]interface{}  "Missing approver"
+// @Failure      404     {object}  map[string]interface{}  "Plan not found"
+// @Failure      409     {object}  map[string]interface{}  "Plan is not in a state that can be rejected"
+// @Router       /agent/plans/{id}/reject [post]
+func (s *Server) rejectOptimizationPlan(c *gin.Context) {
+	s.reviewOptimizationPlan(c, agent.PlanStatusRejected)
+}
+
+// UpdateOptimizationPlanStatus godoc
+// @Summary      Report an optimization plan as applied or rolled back
+// @Description  Move a plan to applied (records an outcome that is verified automatically after the settle period) or rolled-back (records the plan as failed for learning)
+// @Tags         agent
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string  true  "Plan ID"
+// @Param        review  body      object  true  "status (applied or rolled-back), approver and comment"
+// @Success      200     {object}  map[string]interface{}  "Updated plan"
+// @Failure      400     {object}  map[string]interface{}  "Invalid status or missing approver"
+// @Failure      404     {object}  map[string]interface{}  "Plan not found"
+// @Failure      409     {object}  map[string]interface{}  "Invalid status transition"
+// @Router       /agent/plans/{id}/status [post]
+func (s *Server) updateOptimizationPlanStatus(c *gin.Context) {
+	var body planReviewRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	status := agent.PlanStatus(body.Status)
+	if status != agent.PlanStatusApplied && status != agent.PlanStatusRolledBack {
+		c.JSON(400, gin.H{"error": "status must be applied or rolled-back"})
+		return
+	}
+	s.transitionOptimizationPlan(c, status, body)
+}
+
+func (s *Server) reviewOptimizationPlan(c *gin.Context, status agent.PlanStatus) {
+	var body planReviewRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	s.transitionOptimizationPlan(c, status, body)
+}
+
+func (s *Server) transitionOptimizationPlan(c *gin.Context, status agent.PlanStatus, body planReviewRequest) {
+	actor := s.reviewerIdentity(c, body)
+	if actor == "" {
+		c.JSON(400, gin.H{"error": "approver is required (request body or auth proxy header)"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+	// Only approvals need the NodePool unchanged: a plan applied by hand has changed
+	// its NodePool, so expiring before the applied transition would reject it
+	if status == agent.PlanStatusApproved {
+		s.expireChangedPlans(ctx)
+	}
+
+	plan, err := s.planStore.Transition(c.Param("id"), status, actor, body.Comment, time.Now())
+	if err != nil {
+		planError(c, err)
+		return
+	}
+
+	if learningAgent := s.costAgent.GetLearningAgent(); learningAgent != nil {
+		if err := s.recordPlanOutcome(ctx, learningAgent, plan, actor, body.Comment); err != nil {
+			fmt.Printf("Warning: Failed to record outcome for plan %s: %v\n", plan.ID, err)
+		}
+	}
+
+	c.JSON(200, gin.H{"plan": plan})
+}
+
+// recordPlanOutcome feeds applied and rolled-back plans to the learning agent
+func (s *Server) recordPlanOutcome(ctx context.Context, learningAgent *agent.LearningAgent, plan *agent.OptimizationPlan, actor, comment string) error {
+	switch plan.Status {
+	case agent.PlanStatusApplied:
+		// Verified automatically once it settles
+		return agent.NewOutcomeTracker(learningAgent).RecordAppliedPlan(ctx, plan, "approved")
+	case agent.PlanStatusRolledBack:
+		for _, outcome := range learningAgent.GetHistory() {
+			if outcome.PlanID != plan.ID {
+				continue
+			}
+			incident := "Rolled back by " + actor
+			if comment != "" {
+				incident += ": " + comment
+			}
+			outcome.Incidents = append(outcome.Incidents, incident)
+			outcome.UserFeedback = "rejected" // Not verified again
+			return learningAgent.RecordOutcome(ctx, outcome)
+		}
+	}
+	return nil
+}
+
+// GetNodePoolPlanHistory godoc
+// @Summary      Get optimization plan history for a NodePool
+// @Description  All stored plans for a NodePool, newest first, with counts per status and the open plans whose NodePool changed since they were created (stale)
+// @Tags         agent
+// @Produce      json
+// @Param        name  path      string  true  "NodePool name"
+// @Success      200   {object}  map[string]interface{}  "Plan history"
+// @Failure      500   {object}  map[string]interface{}  "Internal server error"
+// @Router       /agent/nodepools/{name}/plans [get]
+func (s *Server) getNodePoolPlanHistory(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	name := c.Param("name")
+	plans, err := s.planStore.List(agent.PlanFilter{NodePool: name})
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	byStatus := make(map[agent.PlanStatus]int)
+	for _, plan := range plans {
+		byStatus[plan.Status]++
+	}
+	c.JSON(200, gin.H{
+		"nodePool": name,
+		"plans":    plans,
+		"count":    len(plans),
+		"byStatus": byStatus,
+		"stale":    s.stalePlans(ctx, plans),
+	})
+}
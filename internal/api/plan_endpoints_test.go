@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karpenter-optimizer/internal/agent"
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPlanApprovalWorkflow(t *testing.T) {
+	server := setupTestServer()
+	server.k8sClient = nil // Plan expiry needs a cluster; exercise the workflow only
+	require.NoError(t, server.planStore.Propose([]*agent.OptimizationPlan{
+		{ID: "plan-1", NodePoolName: "default", Strategy: agent.StrategyBalanced, CreatedAt: time.Now()},
+		{ID: "plan-2", NodePoolName: "default", Strategy: agent.StrategySpotFirst, CreatedAt: time.Now()},
+		{ID: "plan-3", NodePoolName: "default", Strategy: agent.StrategyAggressive, CreatedAt: time.Now()},
+	}, time.Now()))
+
+	post := func(path, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name              string
+		path              string
+		body              string
+		headers           map[string]string
+		authProxyDisabled bool
+		wantStatus        int
+		wantActor         string
+	}{
+		{name: "missing approver", path: "/api/v1/agent/plans/plan-1/approve", body: `{"comment":"ok"}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown plan", path: "/api/v1/agent/plans/plan-9/approve", body: `{"approver":"alice"}`, wantStatus: http.StatusNotFound},
+		{name: "approve with body approver", path: "/api/v1/agent/plans/plan-1/approve", body: `{"approver":"alice","comment":"ok"}`, wantStatus: http.StatusOK, wantActor: "alice"},
+		{name: "approve twice", path: "/api/v1/agent/plans/plan-1/approve", body: `{"approver":"alice"}`, wantStatus: http.StatusConflict},
+		{name: "auth proxy identity wins", path: "/api/v1/agent/plans/plan-2/reject", body: `{"approver":"mallory","comment":"too risky"}`, headers: map[string]string{"X-Forwarded-User": "bob"}, wantStatus: http.StatusOK, wantActor: "bob"},
+		{name: "auth proxy headers ignored when disabled", path: "/api/v1/agent/plans/plan-3/reject", body: `{"approver":"carol"}`, headers: map[string]string{"X-Forwarded-User": "bob"}, authProxyDisabled: true, wantStatus: http.StatusOK, wantActor: "carol"},
+		{name: "invalid status", path: "/api/v1/agent/plans/plan-1/status", body: `{"status":"verified","approver":"alice"}`, wantStatus: http.StatusBadRequest},
+		{name: "rejected plan cannot be applied", path: "/api/v1/agent/plans/plan-2/status", body: `{"status":"applied","approver":"alice"}`, wantStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.config.AuthProxyEnabled = !tt.authProxyDisabled
+			w := post(tt.path, tt.body, tt.headers)
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantActor == "" {
+				return
+			}
+			var resp struct {
+				Plan agent.OptimizationPlan `json:"plan"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			last := resp.Plan.Transitions[len(resp.Plan.Transitions)-1]
+			assert.Equal(t, tt.wantActor, last.Actor)
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/agent/nodepools/default/plans", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var history struct {
+		Count    int                      `json:"count"`
+		ByStatus map[agent.PlanStatus]int `json:"byStatus"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	assert.Equal(t, 3, history.Count)
+	assert.Equal(t, map[agent.PlanStatus]int{agent.PlanStatusApproved: 1, agent.PlanStatusRejected: 2}, history.ByStatus)
+}
+
+func TestApplyOptimizationPlanWithoutCluster(t *testing.T) {
+	server := setupTestServer()
+	server.k8sClient = nil
+
+	req := httptest.NewRequest("POST", "/api/v1/agent/plans/plan-1/apply", strings.NewReader(`{"approver":"alice","dryRun":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestPlanAppliedAfterNodePoolChange(t *testing.T) {
+	ctx := context.Background()
+	gvr := schema.GroupVersionResource{Group: "karpenter.sh", Version: "v1", Resource: "nodepools"}
+	np := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "karpenter.sh/v1",
+		"kind":       "NodePool",
+		"metadata":   map[string]interface{}{"name": "default"},
+		"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{"requirements": []interface{}{
+			map[string]interface{}{"key": "node.kubernetes.io/instance-type", "operator": "In", "values": []interface{}{"m5.xlarge"}},
+		}}}},
+	}}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{gvr: "NodePoolList"}, np)
+
+	server := setupTestServer()
+	server.k8sClient = kubernetes.NewClientFromInterfaces(fake.NewSimpleClientset(), dynamicClient)
+	nodePools, err := server.k8sClient.ListNodePoolSpecs(ctx)
+	require.NoError(t, err)
+	require.Len(t, nodePools, 1)
+	fingerprint := agent.NodePoolFingerprint(nodePools[0])
+	require.NoError(t, server.planStore.Propose([]*agent.OptimizationPlan{
+		{ID: "plan-1", NodePoolName: "default", NodePoolFingerprint: fingerprint, Strategy: agent.StrategyBalanced, CreatedAt: time.Now()},
+		{ID: "plan-2", NodePoolName: "default", NodePoolFingerprint: fingerprint, Strategy: agent.StrategySpotFirst, CreatedAt: time.Now()},
+	}, time.Now()))
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/api/v1/agent/plans/plan-1/approve", `{"approver":"alice"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Applying plan-1 by hand changes the NodePool
+	require.NoError(t, unstructured.SetNestedSlice(np.Object, []interface{}{
+		map[string]interface{}{"key": "node.kubernetes.io/instance-type", "operator": "In", "values": []interface{}{"m6i.large"}},
+	}, "spec", "template", "spec", "requirements"))
+	_, err = dynamicClient.Resource(gvr).Update(ctx, np, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	w = post("/api/v1/agent/plans/plan-1/status", `{"status":"applied","approver":"alice"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	plan, err := server.planStore.Get("plan-1")
+	require.NoError(t, err)
+	assert.Equal(t, agent.PlanStatusApplied, plan.Status)
+
+	// Reading plans reports the stale one without expiring it
+	for _, path := range []string{"/api/v1/agent/plans", "/api/v1/agent/plans/plan-2", "/api/v1/agent/nodepools/default/plans"} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp struct {
+			Stale       map[string]string `json:"stale"`
+			StaleReason string            `json:"staleReason"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		if path == "/api/v1/agent/plans/plan-2" {
+			assert.Equal(t, "NodePool changed since the plan was created", resp.StaleReason)
+		} else {
+			assert.Equal(t, map[string]string{"plan-2": "NodePool changed since the plan was created"}, resp.Stale, path)
+		}
+	}
+	plan, err = server.planStore.Get("plan-2")
+	require.NoError(t, err)
+	assert.Equal(t, agent.PlanStatusProposed, plan.Status)
+
+	// A plan computed against the old spec can no longer be approved
+	w = post("/api/v1/agent/plans/plan-2/approve", `{"approver":"alice"}`)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	plan, err = server.planStore.Get("plan-2")
+	require.NoError(t, err)
+	assert.Equal(t, agent.PlanStatusExpired, plan.Status)
+}
@@ -36,20 +36,29 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"os/signal"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/karpenter-optimizer/internal/agent"
 	"github.com/karpenter-optimizer/internal/config"
 	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/logrules"
 	"github.com/karpenter-optimizer/internal/recommender"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 
 	"github.com/karpenter-optimizer/internal/docs/swagger" // Swagger docs
 	"k8s.io/apimachinery/pkg/api/resource"
+	k8sclient "k8s.io/client-go/kubernetes"
 )
 
 // debugLog prints debug messages only if debug logging is enabled
@@ -64,6 +73,25 @@ type Server struct {
 	config      *config.Config
 	recommender *recommender.Recommender
 	k8sClient   *kubernetes.Client
+	logRules    *logrules.Engine
+	// disruptionStore holds node disruption history recorded since startup (nil without a cluster)
+	disruptionStore *kubernetes.DisruptionStore
+	// stopRecorder stops the disruption recorder, which closes recorderDone once its history is written
+	stopRecorder context.CancelFunc
+	recorderDone chan struct{}
+	// lifecycle is cancelled on shutdown to stop the auto-apply loop and plan watches; planWatches tracks the watches
+	lifecycle     context.Context
+	stopLifecycle context.CancelFunc
+	planWatches   sync.WaitGroup
+	// costAgent is shared by all agent endpoints so learned patterns are kept between requests
+	costAgent *agent.CostOptimizationAgent
+	// outcomeVerifier measures applied plans once they settle (nil without a cluster or learning)
+	outcomeVerifier *agent.OutcomeVerifier
+	// planStore keeps generated plans and their approval lifecycle
+	planStore *agent.PlanStore
+	// planApplier applies approved plans to NodePools behind guardrails (nil without a cluster)
+	planApplier *agent.PlanApplier
+	applyMu     sync.Mutex // Serializes plan applies; applyPlan's conflict checks then keep a NodePool to one plan at a time
 }
 
 func NewServer(cfg *config.Config) *Server {
@@ -116,11 +144,43 @@ func NewServer(cfg *config.Config) *Server {
 		rec.SetK8sClient(k8sClient)
 	}
 
+	logRules, err := logrules.Load(cfg.LogRulesFile)
+	if err != nil {
+		fmt.Printf("Warning: %v; using default log rules\n", err)
+		logRules = logrules.Default()
+	}
+
 	server := &Server{
 		router:      r,
 		config:      cfg,
 		recommender: rec,
 		k8sClient:   k8sClient,
+		logRules:    logRules,
+	}
+	server.lifecycle, server.stopLifecycle = context.WithCancel(context.Background())
+
+	if k8sClient != nil {
+		server.startDisruptionRecorder()
+	}
+	learningAgent := server.newLearningAgent()
+	server.costAgent = agent.NewCostOptimizationAgentWithLearning(rec, k8sClient, agent.StrategyBalanced, learningAgent)
+	if cfg.AgentStrategiesFile != "" {
+		definitions, err := agent.LoadStrategyDefinitions(cfg.AgentStrategiesFile)
+		if err == nil {
+			err = server.costAgent.RegisterStrategies(definitions)
+		}
+		if err != nil {
+			fmt.Printf("Warning: %v; using built-in strategies only\n", err)
+		}
+	}
+	server.planStore = agent.NewPlanStore(cfg.AgentPlansPath, 0)
+	if k8sClient != nil && learningAgent != nil {
+		server.outcomeVerifier = agent.NewOutcomeVerifier(learningAgent, agent.NewClusterMeasurer(rec, k8sClient), cfg.AgentVerifySettlePeriod, cfg.AgentVerifyInterval)
+		server.outcomeVerifier.OnVerified(server.markPlanVerified)
+		go server.outcomeVerifier.Run(context.Background())
+	}
+	if k8sClient != nil {
+		server.startPlanApplier()
 	}
 
 	server.setupRoutes()
@@ -128,6 +188,62 @@ func NewServer(cfg *config.Config) *Server {
 	return server
 }
 
+// startDisruptionRecorder records node disruptions in the background so the disruption
+// endpoints can report history beyond what Kubernetes events retain
+func (s *Server) startDisruptionRecorder() {
+	retention := time.Duration(s.config.DisruptionRetentionDays) * 24 * time.Hour
+	store, err := kubernetes.NewDisruptionStore(s.config.DisruptionStorePath, retention)
+	if err != nil {
+		fmt.Printf("Warning: %v; disruption history will not survive restarts\n", err)
+		if store, err = kubernetes.NewDisruptionStore("", retention); err != nil {
+			return
+		}
+	}
+	s.disruptionStore = store
+
+	recorder := kubernetes.NewDisruptionRecorder(s.k8sClient, store)
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopRecorder, s.recorderDone = cancel, make(chan struct{})
+	go func() {
+		defer close(s.recorderDone)
+		if err := recorder.Run(ctx); err != nil {
+			fmt.Printf("Warning: Disruption recorder stopped: %v\n", err)
+		}
+	}()
+}
+
+// newLearningAgent creates the learning agent with the configured history backend,
+// falling back to the default history file when that backend is unavailable
+func (s *Server) newLearningAgent() *agent.LearningAgent {
+	opts := agent.HistoryStoreOptions{
+		Backend:   s.config.AgentHistoryBackend,
+		Path:      s.config.AgentHistoryPath,
+		Namespace: s.config.AgentHistoryNamespace,
+		Name:      s.config.AgentHistoryName,
+	}
+	var clientset k8sclient.Interface
+	if s.k8sClient != nil {
+		clientset = s.k8sClient.Clientset()
+	}
+
+	store, err := agent.NewHistoryStore(opts, clientset)
+	if err != nil {
+		fmt.Printf("Warning: %v; storing agent history in %s\n", err, agent.DefaultHistoryFile)
+		store = agent.NewFileHistoryStore(agent.DefaultHistoryFile)
+	}
+
+	learningAgent, err := agent.NewLearningAgentWithStore(store)
+	if err != nil {
+		fmt.Printf("Warning: Failed to initialize learning agent: %v\n", err)
+		return nil
+	}
+	learningAgent.SetBanditConfig(agent.BanditConfig{
+		HalfLife:        s.config.AgentBanditHalfLife,
+		IncidentPenalty: s.config.AgentBanditIncidentPenalty,
+	})
+	return learningAgent
+}
+
 func (s *Server) setupRoutes() {
 	// Swagger UI endpoint with dynamic host detection
 	// Accessible at /api/swagger/index.html
@@ -212,8 +328,12 @@ func (s *Server) setupRoutes() {
 		api.GET("/nodepools/recommendations", s.getNodePoolRecommendations)
 		api.GET("/disruptions", s.getNodeDisruptions)
 		api.GET("/disruptions/recent", s.getRecentNodeDeletions)
+		api.GET("/disruptions/churn", s.getDisruptionChurn)
+		api.GET("/disruptions/audit", s.getDisruptionAudit)
 		api.GET("/nodes", s.getNodesWithUsage)
+		api.GET("/nodes/gpu/idle", s.getIdleGPUs)
 		api.GET("/topology", s.getTopology)
+		api.GET("/pods/pending/diagnosis", s.getPendingPodDiagnosis)
 		api.GET("/cluster/summary", s.getClusterSummary)
 		api.GET("/recommendations/cluster-summary", s.getRecommendationsFromClusterSummary)
 		api.GET("/recommendations/cluster-summary/stream", s.getRecommendationsFromClusterSummarySSE)
@@ -222,17 +342,92 @@ func (s *Server) setupRoutes() {
 		api.POST("/karpenter/logs/analyze", s.analyzeKarpenterLog)
 		api.GET("/karpenter/pods", s.getKarpenterPods)
 		api.GET("/karpenter/logs", s.getKarpenterLogs)
+		api.GET("/karpenter/logs/fingerprints", s.getKarpenterLogFingerprints)
+		api.GET("/karpenter/logs/stream", s.streamKarpenterLogs)
+
+		// What-if simulations
+		api.POST("/simulate/drain", s.simulateDrain)
 
 		// Agent endpoints
 		api.GET("/agent/cost-optimization", s.getCostOptimizationRecommendations)
 		api.POST("/agent/outcomes", s.recordOptimizationOutcome)
 		api.GET("/agent/learning/stats", s.getLearningStats)
+		api.GET("/agent/learning/calibration", s.getLearningCalibration)
 		api.GET("/agent/learning/history", s.getOptimizationHistory)
+		api.POST("/agent/tool-run", s.runToolAgent)
+		api.POST("/ask", s.askCluster)
+		api.GET("/llm/rejections", s.getLLMRejections)
+		api.GET("/llm/cache", s.getLLMCache)
+		api.DELETE("/llm/cache", s.clearLLMCache)
+		api.GET("/llm/providers", s.getLLMProviders)
+		api.GET("/llm/prompts", s.getLLMPrompts)
+		api.GET("/agent/strategies", s.getOptimizationStrategies)
+		api.GET("/agent/pareto", s.getParetoPlans)
+		api.POST("/agent/pareto", s.proposeParetoPlans)
+		api.GET("/agent/plans", s.listOptimizationPlans)
+		api.GET("/agent/plans/:id", s.getOptimizationPlan)
+		api.POST("/agent/plans/:id/approve", s.approveOptimizationPlan)
+		api.POST("/agent/plans/:id/reject", s.rejectOptimizationPlan)
+		api.POST("/agent/plans/:id/status", s.updateOptimizationPlanStatus)
+		api.POST("/agent/plans/:id/apply", s.applyOptimizationPlan)
+		api.GET("/agent/nodepools/:name/plans", s.getNodePoolPlanHistory)
 	}
 }
 
+// Run serves the API until SIGINT or SIGTERM, then drains in-flight requests and stops
+// the disruption recorder so its pending history is written
 func (s *Server) Run(addr string) error {
-	return s.router.Run(addr)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: addr, Handler: s.router}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-serveErr:
+		s.shutdown()
+		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	s.shutdown()
+	return err
+}
+
+// shutdown stops the background workers that hold unwritten state
+func (s *Server) shutdown() {
+	s.stopLifecycle()
+	watchesDone := make(chan struct{})
+	go func() {
+		s.planWatches.Wait()
+		close(watchesDone)
+	}()
+	select {
+	case <-watchesDone:
+	case <-time.After(10 * time.Second):
+		fmt.Println("Warning: Timed out stopping plan watches")
+	}
+	if cache := s.llmCache(); cache != nil {
+		cache.Flush()
+	}
+
+	if s.stopRecorder == nil {
+		return
+	}
+	s.stopRecorder()
+	select {
+	case <-s.recorderDone:
+	case <-time.After(10 * time.Second):
+		fmt.Println("Warning: Timed out writing disruption history")
+	}
 }
 
 // HealthCheck godoc
@@ -339,6 +534,10 @@ func (s *Server) analyzeWorkloads(c *gin.Context) {
 			CPU:           cpu,
 			Memory:        memory,
 			GPU:           w.GPU,
+			GPUModel:      w.GPUModel,
+			GPUMemoryMiB:  w.GPUMemoryMiB,
+			MIGProfiles:   w.MIGProfiles,
+			SharedGPU:     w.SharedGPU,
 			Labels:        w.Labels,
 			CPURequest:    w.CPURequest,
 			MemoryRequest: w.MemoryRequest,
@@ -412,6 +611,7 @@ func (s *Server) getRecommendations(c *gin.Context) {
 // @Tags         recommendations
 // @Accept       json
 // @Produce      text/event-stream
+// @Param        noCache  query     bool  false  "Skip the LLM response cache (fresh replies are still cached)"
 // @Success      200  {string}  text/event-stream  "SSE stream with progress and recommendations"
 // @Failure      503  {object}  map[string]interface{}  "Kubernetes client not configured"
 // @Failure      500  {object}  map[string]interface{}  "Internal server error"
@@ -425,6 +625,7 @@ func (s *Server) getRecommendationsFromClusterSummarySSE(c *gin.Context) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
+	ctx = llmCacheContext(ctx, c)
 
 	// Set up SSE headers
 	c.Header("Content-Type", "text/event-stream")
@@ -897,7 +1098,7 @@ func (s *Server) getNodePool(c *gin.Context) {
 // @Tags         nodes
 // @Accept       json
 // @Produce      json
-// @Param        hours  query     int  false  "Hours to look back (default: 24, max: 168)" default(24)
+// @Param        hours  query     int  false  "Hours to look back (default: 24, max: 168, or DISRUPTION_RETENTION_DAYS*24 when history is recorded)" default(24)
 // @Success      200    {object}  map[string]interface{}  "Node disruptions"
 // @Failure      400    {object}  map[string]interface{}  "Bad request - invalid hours parameter"
 // @Failure      503    {object}  map[string]interface{}  "Kubernetes client not configured"
@@ -919,9 +1120,9 @@ func (s *Server) getNodeDisruptions(c *gin.Context) {
 		} else {
 			if hours <= 0 {
 				sinceHours = 24
-			} else if hours > 168 {
-				// Cap at 168 hours (7 days) to prevent excessive queries
-				sinceHours = 168
+			} else if maxHours := s.maxDisruptionHours(); hours > maxHours {
+				// Cap at the recorded history window to prevent excessive queries
+				sinceHours = maxHours
 			} else {
 				sinceHours = hours
 			}
@@ -941,17 +1142,33 @@ func (s *Server) getNodeDisruptions(c *gin.Context) {
 		"disruptions": disruptions,
 		"sinceHours":  sinceHours, // Only used for historical deleted nodes
 		"count":       len(disruptions),
-		"note":        "Disruptions are based on current live node state, not historical events",
+		"note":        s.disruptionHistoryNote(),
 	})
 }
 
+// maxDisruptionHours is the look-back cap: 7 days from events alone, the retention window with recorded history
+func (s *Server) maxDisruptionHours() int {
+	if s.disruptionStore != nil && s.config.DisruptionRetentionDays*24 > 168 {
+		return s.config.DisruptionRetentionDays * 24
+	}
+	return 168
+}
+
+// disruptionHistoryNote explains where deleted-node history comes from
+func (s *Server) disruptionHistoryNote() string {
+	if s.disruptionStore != nil {
+		return "Current disruptions are based on live node state; deleted nodes come from history recorded since the optimizer started"
+	}
+	return "Disruptions are based on current live node state, not historical events"
+}
+
 // GetRecentNodeDeletions godoc
 // @Summary      Get recent node deletions
 // @Description  Get information about recently deleted nodes
 // @Tags         nodes
 // @Accept       json
 // @Produce      json
-// @Param        hours  query     int  false  "Hours to look back (default: 24, max: 168)" default(24)
+// @Param        hours  query     int  false  "Hours to look back (default: 24, max: 168, or DISRUPTION_RETENTION_DAYS*24 when history is recorded)" default(24)
 // @Success      200    {object}  map[string]interface{}  "Recent node deletions"
 // @Failure      400    {object}  map[string]interface{}  "Bad request - invalid hours parameter"
 // @Failure      503    {object}  map[string]interface{}  "Kubernetes client not configured"
@@ -972,9 +1189,9 @@ func (s *Server) getRecentNodeDeletions(c *gin.Context) {
 		} else {
 			if hours <= 0 {
 				sinceHours = 24
-			} else if hours > 168 {
-				// Cap at 168 hours (7 days) to prevent excessive queries
-				sinceHours = 168
+			} else if maxHours := s.maxDisruptionHours(); hours > maxHours {
+				// Cap at the recorded history window to prevent excessive queries
+				sinceHours = maxHours
 			} else {
 				sinceHours = hours
 			}
@@ -994,6 +1211,7 @@ func (s *Server) getRecentNodeDeletions(c *gin.Context) {
 		"deletions":  deletions,
 		"sinceHours": sinceHours,
 		"count":      len(deletions),
+		"note":       s.disruptionHistoryNote(),
 	})
 }
 
@@ -1030,6 +1248,44 @@ func (s *Server) getNodesWithUsage(c *gin.Context) {
 	})
 }
 
+// GetIdleGPUs godoc
+// @Summary      Get idle GPUs
+// @Description  Find GPU nodes with unallocated GPUs or GPUs held by pods that are not Ready, with the hourly cost they waste
+// @Tags         nodes
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Idle GPU findings"
+// @Failure      503  {object}  map[string]interface{}  "Kubernetes client not configured"
+// @Failure      500  {object}  map[string]interface{}  "Internal server error"
+// @Router       /nodes/gpu/idle [get]
+func (s *Server) getIdleGPUs(c *gin.Context) {
+	if s.k8sClient == nil {
+		c.JSON(503, gin.H{"error": "Kubernetes client not configured"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	nodes, err := s.k8sClient.GetAllNodesWithUsage(ctx)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	findings := s.recommender.DetectIdleGPUs(ctx, nodes)
+	totalWasted := 0.0
+	for _, f := range findings {
+		totalWasted += f.WastedCost
+	}
+
+	c.JSON(200, gin.H{
+		"findings":        findings,
+		"count":           len(findings),
+		"totalWastedCost": totalWasted,
+	})
+}
+
 // TopologyPodResources is per-pod request totals for topology visualization.
 type TopologyPodResources struct {
 	CPUCores  float64 `json:"cpuCores"`
@@ -1259,12 +1515,24 @@ func (s *Server) getClusterSummary(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	nodes, err := s.k8sClient.GetAllNodesWithUsage(ctx)
+	summaryData, err := s.clusterSummary(ctx)
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
+	c.JSON(200, gin.H{
+		"summary": summaryData,
+	})
+}
+
+// clusterSummary aggregates node counts, CPU/memory usage and hourly cost across all nodes
+func (s *Server) clusterSummary(ctx context.Context) (gin.H, error) {
+	nodes, err := s.k8sClient.GetAllNodesWithUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Aggregate cluster-wide statistics
 	var totalNodes, spotNodes, onDemandNodes, totalPods int
 	var totalCPUUsed, totalCPUAllocatable, totalMemoryUsed, totalMemoryAllocatable float64
@@ -1364,9 +1632,7 @@ func (s *Server) getClusterSummary(c *gin.Context) {
 		summaryData["pricingSource"] = overallPricingSource
 	}
 
-	c.JSON(200, gin.H{
-		"summary": summaryData,
-	})
+	return summaryData, nil
 }
 
 // GetRecommendationsFromClusterSummary godoc
@@ -1375,6 +1641,7 @@ func (s *Server) getClusterSummary(c *gin.Context) {
 // @Tags         recommendations
 // @Accept       json
 // @Produce      json
+// @Param        noCache  query     bool  false  "Skip the LLM response cache (fresh replies are still cached)"
 // @Success      200  {object}  map[string]interface{}  "Recommendations with AI explanations"
 // @Failure      503  {object}  map[string]interface{}  "Kubernetes client not configured"
 // @Failure      500  {object}  map[string]interface{}  "Internal server error"
@@ -1388,6 +1655,7 @@ func (s *Server) getRecommendationsFromClusterSummary(c *gin.Context) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
+	ctx = llmCacheContext(ctx, c)
 
 	// Get NodePools with actual node data
 	nodePools, err := s.k8sClient.ListNodePools(ctx)
@@ -1487,6 +1755,10 @@ type WorkloadRequest struct {
 	CPURequest    string            `json:"cpuRequest,omitempty" example:"250m"`              // CPU request (optional)
 	MemoryRequest string            `json:"memoryRequest,omitempty" example:"256Mi"`          // Memory request (optional)
 	GPU           int               `json:"gpu" example:"0"`                                  // Number of GPUs required
+	GPUModel      string            `json:"gpuModel,omitempty" example:"NVIDIA-A10G"`         // Required GPU model (optional)
+	GPUMemoryMiB  int64             `json:"gpuMemoryMiB,omitempty" example:"16384"`           // Minimum memory per GPU in MiB (optional)
+	MIGProfiles   map[string]int    `json:"migProfiles,omitempty"`                            // MIG profile -> slices requested (optional)
+	SharedGPU     int               `json:"sharedGpu,omitempty" example:"0"`                  // Time-sliced GPU replicas requested (optional)
 	Labels        map[string]string `json:"labels,omitempty" example:"app:web,tier:frontend"` // Workload labels
 }
 
@@ -1605,3 +1877,114 @@ func (s *Server) getKarpenterLogs(c *gin.Context) {
 		"errorOnly": errorOnly,
 	})
 }
+
+// getKarpenterLogFingerprints handles GET /api/v1/karpenter/logs/fingerprints
+// @Summary Aggregate Karpenter log fingerprints
+// @Description Parses logs (JSON or console format) from all Karpenter pods within a time window, normalizes messages into fingerprints (names, IDs and numbers stripped) and aggregates count, first/last seen and affected NodePools per fingerprint
+// @Tags karpenter
+// @Produce json
+// @Param since query string false "Time window as a duration (e.g. 30m, 6h)" default(1h)
+// @Param level query string false "Only include this level (DEBUG, INFO, WARN, ERROR)"
+// @Param nodepool query string false "Only include lines about this NodePool"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /karpenter/logs/fingerprints [get]
+func (s *Server) getKarpenterLogFingerprints(c *gin.Context) {
+	if s.k8sClient == nil {
+		c.JSON(503, gin.H{"error": "Kubernetes client not configured"})
+		return
+	}
+
+	since := time.Hour
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		parsed, err := time.ParseDuration(sinceStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("invalid since duration: %q", sinceStr)})
+			return
+		}
+		since = parsed
+	}
+	level := strings.ToUpper(c.Query("level"))
+	nodePool := c.Query("nodepool")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	scan, err := s.scanKarpenterLogs(ctx, since, level, nodePool)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"fingerprints":  scan.fingerprints,
+		"count":         len(scan.fingerprints),
+		"since":         since.String(),
+		"pods":          scan.pods,
+		"linesScanned":  scan.linesScanned,
+		"linesMatched":  scan.linesMatched,
+		"linesUnparsed": scan.linesUnparsed,
+		"podErrors":     scan.podErrors,
+	})
+}
+
+// karpenterLogScan is the result of fingerprinting Karpenter logs
+type karpenterLogScan struct {
+	fingerprints  []LogFingerprint
+	pods          int
+	linesScanned  int
+	linesMatched  int
+	linesUnparsed int
+	podErrors     map[string]string
+}
+
+// scanKarpenterLogs fingerprints the logs of all Karpenter pods within since,
+// keeping lines of the given level and NodePool ("" = all), and categorizes errors
+func (s *Server) scanKarpenterLogs(ctx context.Context, since time.Duration, level, nodePool string) (*karpenterLogScan, error) {
+	podLogs, err := s.k8sClient.GetAllKarpenterLogs(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get Karpenter logs: %w", err)
+	}
+
+	scan := &karpenterLogScan{pods: len(podLogs), podErrors: make(map[string]string)}
+	var entries []*KarpenterLogEntry
+	for _, pl := range podLogs {
+		if pl.Error != "" {
+			scan.podErrors[pl.Pod] = pl.Error
+		}
+		for _, line := range pl.Lines {
+			scan.linesScanned++
+			entry, err := parseKarpenterLogEntry(line)
+			if err != nil {
+				scan.linesUnparsed++
+				continue
+			}
+			if level != "" && entry.Level != level {
+				continue
+			}
+			if nodePool != "" && entry.NodePool != nodePool {
+				continue
+			}
+			entry.SourcePod = pl.Pod
+			entries = append(entries, entry)
+		}
+	}
+	scan.linesMatched = len(entries)
+
+	scan.fingerprints = aggregateLogFingerprints(entries, time.Now().Add(-since))
+	for i := range scan.fingerprints {
+		fp := &scan.fingerprints[i]
+		if fp.Level != "ERROR" {
+			continue
+		}
+		sample := fp.SampleError
+		if sample == "" {
+			sample = fp.SampleMessage
+		}
+		cause := s.categorizeErrorCause(sample)
+		fp.Category, fp.Severity, fp.RuleID = cause.Category, cause.Severity, cause.RuleID
+	}
+	return scan, nil
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// SimulateDrainRequest is the request body for a node drain what-if
+type SimulateDrainRequest struct {
+	NodeNames []string `json:"nodeNames" binding:"required,min=1" example:"ip-10-0-1-23.ec2.internal"` // Nodes to cordon and drain
+}
+
+// SimulateDrain godoc
+// @Summary      Simulate draining nodes
+// @Description  What-if analysis for cordoning and draining nodes: which pods reschedule onto remaining nodes of the NodePool, which would go pending, and which PDBs would block eviction. Nothing is modified.
+// @Tags         simulate
+// @Accept       json
+// @Produce      json
+// @Param        request  body      SimulateDrainRequest  true  "Nodes to drain"
+// @Success      200      {object}  map[string]interface{}  "Drain simulation"
+// @Failure      400      {object}  map[string]interface{}  "Bad request"
+// @Failure      404      {object}  map[string]interface{}  "Node not found"
+// @Failure      503      {object}  map[string]interface{}  "Kubernetes client not configured"
+// @Failure      500      {object}  map[string]interface{}  "Internal server error"
+// @Router       /simulate/drain [post]
+func (s *Server) simulateDrain(c *gin.Context) {
+	if s.k8sClient == nil {
+		c.JSON(503, gin.H{"error": "Kubernetes client not configured"})
+		return
+	}
+
+	var req SimulateDrainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	simulation, err := s.k8sClient.SimulateDrain(ctx, req.NodeNames)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			c.JSON(404, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, simulation)
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/karpenter-optimizer/internal/agent"
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/recommender"
+)
+
+// defaultToolAgentGoal is used when a tool agent request has no goal
+const defaultToolAgentGoal = "Find the NodePools whose cost can be reduced safely and propose a plan."
+
+// ToolAgentRequest is the request body for a tool agent run
+type ToolAgentRequest struct {
+	Goal     string `json:"goal" example:"Reduce the cost of the batch NodePool without using spot"` // Task for the agent (default: reduce cost safely across NodePools)
+	MaxSteps int    `json:"maxSteps" example:"6"`                                                    // LLM calls allowed, up to AGENT_TOOL_MAX_STEPS
+}
+
+// RunToolAgent godoc
+// @Summary      Run the tool-calling optimization agent
+// @Description  The LLM calls the optimizer's tools (list_nodepools, get_nodepool, get_instance_pricing, simulate_capacity, get_disruptions, get_karpenter_errors) through function calling until it answers with a JSON plan or the step budget is used. The response has the plan and the full transcript of messages and tool calls.
+// @Tags         agent
+// @Accept       json
+// @Produce      json
+// @Param        request  body      ToolAgentRequest        false  "Goal and step budget"
+// @Param        noCache  query     bool                    false  "Skip the LLM response cache (fresh replies are still cached)"
+// @Success      200      {object}  map[string]interface{}  "Run with plan and transcript (budgetExhausted when no plan was produced)"
+// @Failure      400      {object}  map[string]interface{}  "Bad request"
+// @Failure      502      {object}  map[string]interface{}  "LLM request failed"
+// @Failure      503      {object}  map[string]interface{}  "Service not configured"
+// @Router       /agent/tool-run [post]
+func (s *Server) runToolAgent(c *gin.Context) {
+	if s.k8sClient == nil {
+		c.JSON(503, gin.H{"error": "Kubernetes client not configured"})
+		return
+	}
+	llm := s.recommender.GetOllamaClient()
+	if llm == nil {
+		c.JSON(503, gin.H{"error": "LLM not configured"})
+		return
+	}
+
+	var req ToolAgentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Goal == "" {
+		req.Goal = defaultToolAgentGoal
+	}
+	maxSteps := s.config.AgentToolMaxSteps
+	if req.MaxSteps > 0 && (maxSteps <= 0 || req.MaxSteps < maxSteps) {
+		maxSteps = req.MaxSteps
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+	defer cancel()
+	ctx = llmCacheContext(ctx, c)
+
+	toolAgent := agent.NewToolAgent(llm, s.agentTools(), maxSteps)
+	toolAgent.SetTranscriptDir(s.config.AgentToolTranscriptDir)
+	toolAgent.SetDebug(s.config.Debug)
+
+	run, err := toolAgent.Run(ctx, req.Goal)
+	if err != nil && !errors.Is(err, agent.ErrStepBudgetExhausted) {
+		c.JSON(502, gin.H{"error": err.Error(), "run": run})
+		return
+	}
+	if run.Plan != nil {
+		if err := s.validateToolAgentPlan(ctx, run.Plan); err != nil {
+			c.JSON(500, gin.H{"error": err.Error(), "run": run})
+			return
+		}
+	}
+	c.JSON(200, gin.H{"run": run})
+}
+
+// validateToolAgentPlan drops the plan's instance types that are not in the catalog or
+// violate their NodePool's requirements, invalid capacity types, and changes for unknown
+// NodePools or left without an instance type. What was dropped is reported on the plan.
+func (s *Server) validateToolAgentPlan(ctx context.Context, plan *agent.ToolAgentPlan) error {
+	nodePools, err := s.k8sClient.ListNodePools(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list NodePools: %w", err)
+	}
+	byName := make(map[string]kubernetes.NodePoolInfo, len(nodePools))
+	for _, np := range nodePools {
+		byName[np.Name] = np
+	}
+
+	var rejections []recommender.LLMRejection
+	changes := []agent.ToolAgentChange{}
+	for _, change := range plan.Changes {
+		np, ok := byName[change.NodePoolName]
+		if !ok {
+			rejections = append(rejections, recommender.LLMRejection{NodePool: change.NodePoolName, Field: "nodePoolName", Value: change.NodePoolName, Reason: "NodePool not found", At: time.Now()})
+			continue
+		}
+		valid, rejected := recommender.ValidateInstanceTypes(np, change.InstanceTypes, recommender.NodePoolUsesGPU(np))
+		rejections = append(rejections, rejected...)
+		if len(valid) == 0 {
+			continue
+		}
+		change.InstanceTypes = valid
+		if change.CapacityType != "" && change.CapacityType != "spot" && change.CapacityType != "on-demand" {
+			rejections = append(rejections, recommender.LLMRejection{NodePool: np.Name, Field: "capacityType", Value: change.CapacityType, Reason: "must be spot or on-demand", At: time.Now()})
+			change.CapacityType = ""
+		}
+		changes = append(changes, change)
+	}
+
+	plan.Changes = changes
+	plan.Rejections = rejections
+	s.recommender.RecordLLMRejections(rejections...)
+	return nil
+}
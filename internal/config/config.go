@@ -2,18 +2,30 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/karpenter-optimizer/internal/ollama"
 )
 
 type Config struct {
 	KubeconfigPath string
 	KubeContext    string
 	APIPort        string
-	// LLM Configuration (supports Ollama or LiteLLM)
-	LLMProvider string // "ollama" or "litellm"
+	// LLM Configuration (supports Ollama, OpenAI-compatible e.g. LiteLLM, Anthropic or Azure OpenAI)
+	LLMProvider string // "ollama", "litellm"/"openai", "anthropic" or "azure"
 	LLMURL      string
-	LLMModel    string
+	LLMModel    string // Model, or deployment for Azure OpenAI
 	LLMAPIKey   string
+	LLMAzureAPIVersion string // Azure OpenAI api-version
+	LLMFallbacks       string // Providers tried in order when the primary fails: "provider,url,model[,API key env var];..."
+	// LLM request resilience (per provider)
+	LLMMaxRetries              int           // Retries of transport errors, 408, 429 and 5xx replies
+	LLMRetryBaseDelay          time.Duration // Backoff before the first retry, doubled per retry with jitter
+	LLMMaxConcurrency          int           // Concurrent requests per provider (0 = unlimited)
+	LLMCircuitFailureThreshold int           // Consecutive failures that open the circuit and pause AI enhancement (0 = never)
+	LLMCircuitOpenDuration     time.Duration // How long an open circuit rejects requests before a trial request
 	// Legacy Ollama configuration (for backward compatibility)
 	OllamaURL   string
 	OllamaModel string
@@ -22,6 +34,46 @@ type Config struct {
 	AWSAccessKeyID     string // AWS access key ID (optional, can use IAM role)
 	AWSSecretAccessKey string // AWS secret access key (optional, can use IAM role)
 	AWSSessionToken    string // AWS session token (for temporary credentials)
+	// Karpenter log analysis
+	LogRulesFile string // Optional YAML file overriding/extending the embedded error categorization rules
+	// Node disruption history
+	DisruptionStorePath     string // JSON file where the disruption recorder persists history ("" = memory only)
+	DisruptionRetentionDays int    // How long recorded disruptions are kept
+	// User-defined optimization strategies
+	AgentStrategiesFile string // Optional YAML file with strategies selectable alongside the built-in ones
+	// Agent learning history
+	AgentHistoryBackend   string // file, configmap, secret or sqlite
+	AgentHistoryPath      string // File or SQLite database path ("" = backend default under /tmp)
+	AgentHistoryNamespace string // Namespace of the history ConfigMap/Secret
+	AgentHistoryName      string // Name of the history ConfigMap/Secret
+	// Strategy selection from outcomes (bandit over NodePool-strategy pairs)
+	AgentBanditHalfLife        time.Duration // Outcomes lose half their weight after this long (0 = no decay)
+	AgentBanditIncidentPenalty float64       // Reward subtracted per incident (rewards are between 0 and 1)
+	// Automatic verification of applied plans
+	AgentVerifySettlePeriod time.Duration // How long after a plan is applied its NodePool is measured
+	AgentVerifyInterval     time.Duration // How often applied plans are checked
+	AgentPlansPath          string        // JSON file where optimization plans and their approvals are kept ("" = memory only)
+	AuthProxyEnabled        bool          // Take the plan reviewer from auth proxy headers (only behind a proxy that sets them)
+	// Tool-calling agent (LLM calls the optimizer's tools until it produces a plan)
+	AgentToolMaxSteps      int    // Maximum LLM calls per run
+	AgentToolTranscriptDir string // Directory where run transcripts are written as JSON ("" = not written)
+	// LLM response cache (keyed by model and normalized prompt)
+	LLMCacheEnabled    bool          // Answer repeated prompts from the cache
+	LLMCacheTTL        time.Duration // How long a cached reply is served
+	LLMCacheMaxEntries int           // Maximum cached replies; least recently used are evicted
+	LLMCacheMaxBytes   int           // Maximum total size of cached replies in bytes
+	LLMCachePath       string        // JSON file where the cache is persisted across restarts ("" = memory only)
+	// LLM prompt templates
+	PromptTemplatesDir string // Directory of *.tmpl files overriding the embedded prompt templates (e.g. a mounted ConfigMap)
+	// Guarded application of approved plans to NodePools
+	AutoApplyEnabled              bool          // Apply approved plans automatically inside maintenance windows
+	AutoApplyMaxCapacityReduction float64       // Maximum CPU/memory capacity reduction in percent (0 = no limit)
+	AutoApplyMaintenanceWindows   string        // e.g. "Sat,Sun 02:00-06:00;Mon-Fri 22:00-23:30" ("" = any time)
+	AutoApplyTimezone             string        // Time zone of the maintenance windows
+	AutoApplyMinOnDemandRatio     float64       // Minimum cluster-wide on-demand share of CPU (0-1, 0 = no minimum)
+	AutoApplyWatchWindow          time.Duration // How long an applied plan is watched before it is kept
+	AutoApplyMaxPendingPods       int           // More pending pods during the watch reverts the plan
+	AutoApplyMaxDisruptionErrors  int           // More Karpenter warnings during the watch reverts the plan
 	Debug              bool
 }
 
@@ -44,15 +96,7 @@ func Load() *Config {
 
 	// Auto-detect provider from URL if not explicitly set
 	if llmURL != "" && llmProvider == "" {
-		llmURLLower := strings.ToLower(llmURL)
-		if strings.Contains(llmURLLower, "/v1/chat/completions") ||
-			strings.Contains(llmURLLower, "litellm") ||
-			strings.Contains(llmURLLower, "openai") ||
-			strings.Contains(llmURLLower, "vllm") {
-			llmProvider = "litellm"
-		} else {
-			llmProvider = "ollama"
-		}
+		llmProvider = ollama.DetectProvider(llmURL)
 	}
 
 	// Set defaults if still empty
@@ -89,6 +133,43 @@ func Load() *Config {
 		AWSAccessKeyID:    getEnv("AWS_ACCESS_KEY_ID", ""),
 		AWSSecretAccessKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
 		AWSSessionToken:   getEnv("AWS_SESSION_TOKEN", ""),
+		LogRulesFile:      getEnv("LOG_RULES_FILE", ""),
+		DisruptionStorePath:     getEnv("DISRUPTION_STORE_PATH", "/tmp/karpenter-optimizer-disruptions.json"),
+		DisruptionRetentionDays: getEnvInt("DISRUPTION_RETENTION_DAYS", 30),
+		AgentStrategiesFile:     getEnv("AGENT_STRATEGIES_FILE", ""),
+		AgentHistoryBackend:     getEnv("AGENT_HISTORY_BACKEND", "file"),
+		AgentHistoryPath:        getEnv("AGENT_HISTORY_PATH", ""),
+		AgentHistoryNamespace:   getEnv("AGENT_HISTORY_NAMESPACE", podNamespace()),
+		AgentHistoryName:        getEnv("AGENT_HISTORY_NAME", "karpenter-optimizer-history"),
+		AgentBanditHalfLife:        getEnvDuration("AGENT_BANDIT_HALF_LIFE", 30*24*time.Hour),
+		AgentBanditIncidentPenalty: getEnvFloat("AGENT_BANDIT_INCIDENT_PENALTY", 0.25),
+		AgentVerifySettlePeriod: getEnvDuration("AGENT_VERIFY_SETTLE_PERIOD", 6*time.Hour),
+		AgentVerifyInterval:     getEnvDuration("AGENT_VERIFY_INTERVAL", 15*time.Minute),
+		AgentPlansPath:          getEnv("AGENT_PLANS_PATH", "/tmp/karpenter-optimizer-plans.json"),
+		AuthProxyEnabled:        getEnvBool("AUTH_PROXY_ENABLED", false),
+		AgentToolMaxSteps:       getEnvInt("AGENT_TOOL_MAX_STEPS", 8),
+		AgentToolTranscriptDir:  getEnv("AGENT_TOOL_TRANSCRIPT_DIR", ""),
+		LLMCacheEnabled:    getEnvBool("LLM_CACHE_ENABLED", true),
+		LLMCacheTTL:        getEnvDuration("LLM_CACHE_TTL", 24*time.Hour),
+		LLMCacheMaxEntries: getEnvInt("LLM_CACHE_MAX_ENTRIES", 500),
+		LLMCacheMaxBytes:   getEnvInt("LLM_CACHE_MAX_BYTES", 8<<20),
+		LLMCachePath:       getEnv("LLM_CACHE_PATH", ""),
+		PromptTemplatesDir: getEnv("PROMPT_TEMPLATES_DIR", ""),
+		LLMAzureAPIVersion:         getEnv("LLM_AZURE_API_VERSION", ollama.DefaultAzureAPIVersion),
+		LLMFallbacks:               getEnv("LLM_FALLBACKS", ""),
+		LLMMaxRetries:              getEnvInt("LLM_MAX_RETRIES", 2),
+		LLMRetryBaseDelay:          getEnvDuration("LLM_RETRY_BASE_DELAY", time.Second),
+		LLMMaxConcurrency:          getEnvInt("LLM_MAX_CONCURRENCY", 4),
+		LLMCircuitFailureThreshold: getEnvInt("LLM_CIRCUIT_FAILURE_THRESHOLD", 5),
+		LLMCircuitOpenDuration:     getEnvDuration("LLM_CIRCUIT_OPEN_DURATION", time.Minute),
+		AutoApplyEnabled:              getEnvBool("AUTO_APPLY_ENABLED", false),
+		AutoApplyMaxCapacityReduction: getEnvFloat("AUTO_APPLY_MAX_CAPACITY_REDUCTION_PERCENT", 30),
+		AutoApplyMaintenanceWindows:   getEnv("AUTO_APPLY_MAINTENANCE_WINDOWS", ""),
+		AutoApplyTimezone:             getEnv("AUTO_APPLY_TIMEZONE", "UTC"),
+		AutoApplyMinOnDemandRatio:     getEnvFloat("AUTO_APPLY_MIN_ON_DEMAND_RATIO", 0),
+		AutoApplyWatchWindow:          getEnvDuration("AUTO_APPLY_WATCH_WINDOW", 30*time.Minute),
+		AutoApplyMaxPendingPods:       getEnvInt("AUTO_APPLY_MAX_PENDING_PODS", 5),
+		AutoApplyMaxDisruptionErrors:  getEnvInt("AUTO_APPLY_MAX_DISRUPTION_ERRORS", 3),
 		Debug:             getEnvBool("DEBUG", false),
 	}
 }
@@ -106,3 +187,44 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// podNamespace returns the namespace the server runs in (POD_NAMESPACE or the
+// mounted service account namespace), or "default" outside a cluster
+func podNamespace() string {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	if data, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+		if ns := strings.TrimSpace(string(data)); ns != "" {
+			return ns
+		}
+	}
+	return "default"
+}
@@ -0,0 +1,97 @@
+// Package eval scores the recommender, and the LLM explanations it asks for, against a
+// corpus of recorded cluster snapshots with expected outcomes. Reports are stable JSON so
+// that two runs (e.g. before and after a prompt or algorithm change) can be compared.
+package eval
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// Case is a recorded cluster snapshot. NodePools has the shape of the nodePools returned by
+// GET /api/v1/nodepools, so a response can be saved and annotated with expectations.
+type Case struct {
+	Name        string                    `json:"name"` // Defaults to the file name
+	Description string                    `json:"description,omitempty"`
+	NodePools   []kubernetes.NodePoolInfo `json:"nodePools"`
+	Expect      map[string]Expectation    `json:"expect,omitempty"` // By NodePool name
+}
+
+// Expectation is the outcome expected for a NodePool; unset fields are not checked
+type Expectation struct {
+	HasRecommendation      *bool    `json:"hasRecommendation,omitempty"`
+	MinSavingsPercent      *float64 `json:"minSavingsPercent,omitempty"`
+	MaxSavingsPercent      *float64 `json:"maxSavingsPercent,omitempty"`
+	CapacityType           string   `json:"capacityType,omitempty"`
+	ForbiddenInstanceTypes []string `json:"forbiddenInstanceTypes,omitempty"` // Glob patterns, e.g. "g5.*"
+	ExplanationMustMention []string `json:"explanationMustMention,omitempty"` // Case-insensitive terms
+}
+
+// LoadCorpus loads the *.yaml, *.yml and *.json cases in dir, sorted by name
+func LoadCorpus(dir string) ([]Case, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read eval corpus: %w", err)
+	}
+
+	var cases []Case
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		c, err := LoadCase(path)
+		if err != nil {
+			return nil, err
+		}
+		if c.Name == "" {
+			c.Name = strings.TrimSuffix(entry.Name(), ext)
+		}
+		if other, ok := seen[c.Name]; ok {
+			return nil, fmt.Errorf("case %s: defined in both %s and %s", c.Name, other, entry.Name())
+		}
+		seen[c.Name] = entry.Name()
+		cases = append(cases, c)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no cases (*.yaml, *.json) in eval corpus %s", dir)
+	}
+	sort.Slice(cases, func(i, j int) bool { return cases[i].Name < cases[j].Name })
+	return cases, nil
+}
+
+// LoadCase loads and validates a YAML or JSON case file
+func LoadCase(path string) (Case, error) {
+	var c Case
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, fmt.Errorf("failed to read eval case: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("failed to parse eval case %s: %w", path, err)
+	}
+	if len(c.NodePools) == 0 {
+		return c, fmt.Errorf("eval case %s: no nodePools", path)
+	}
+	names := make(map[string]bool, len(c.NodePools))
+	for _, np := range c.NodePools {
+		if np.Name == "" {
+			return c, fmt.Errorf("eval case %s: NodePool without a name", path)
+		}
+		names[np.Name] = true
+	}
+	for name := range c.Expect {
+		if !names[name] {
+			return c, fmt.Errorf("eval case %s: expectation for unknown NodePool %s", path, name)
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,278 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// MetricChange is a summary metric in two reports
+type MetricChange struct {
+	Name  string  `json:"name"`
+	Base  float64 `json:"base"`
+	Head  float64 `json:"head"`
+	Delta float64 `json:"delta"`
+}
+
+// ResultChange lists what changed for a NodePool between two reports
+type ResultChange struct {
+	Case       string   `json:"case"`
+	NodePool   string   `json:"nodePool"`
+	Regression bool     `json:"regression"` // Stopped passing, or gained violations, hallucinations or failures
+	Changes    []string `json:"changes"`
+}
+
+// Diff compares a head report with a base report
+type Diff struct {
+	BaseLLM            string         `json:"baseLLM,omitempty"`
+	HeadLLM            string         `json:"headLLM,omitempty"`
+	BasePromptVersions []string       `json:"basePromptVersions,omitempty"`
+	HeadPromptVersions []string       `json:"headPromptVersions,omitempty"`
+	Metrics            []MetricChange `json:"metrics"`
+	Changes            []ResultChange `json:"changes"`
+}
+
+// LoadReport reads a JSON report written by WriteJSON
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read eval report: %w", err)
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse eval report %s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// WriteJSON writes the report as indented JSON, which diffs line by line between runs
+func (r *Report) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal eval report: %w", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteText writes the summary and a line per NodePool
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	llm := r.LLM
+	if llm == "" {
+		llm = "none (rules only)"
+	}
+	s := r.Summary
+	fmt.Fprintf(tw, "LLM:\t%s\n", llm)
+	if len(r.PromptVersions) > 0 {
+		fmt.Fprintf(tw, "Prompts:\t%s\n", strings.Join(r.PromptVersions, ", "))
+	}
+	fmt.Fprintf(tw, "Passed:\t%d/%d NodePools in %d case(s)\n", s.Passed, s.NodePools, s.Cases)
+	fmt.Fprintf(tw, "Savings:\t%.2f%% ($%.4f/hr -> $%.4f/hr over %d recommendation(s))\n", s.SavingsPercent, s.CurrentCost, s.RecommendedCost, s.Recommendations)
+	fmt.Fprintf(tw, "Violations:\t%d\n", s.Violations)
+	fmt.Fprintf(tw, "Hallucinated types:\t%d\n", s.HallucinatedTypes)
+	fmt.Fprintf(tw, "Expectation failures:\t%d\n", s.ExpectationFailures)
+	fmt.Fprintf(tw, "Explanation score:\t%.3f\n", s.ExplanationScore)
+	if s.Errors > 0 {
+		fmt.Fprintf(tw, "Errors:\t%d\n", s.Errors)
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "CASE\tNODEPOOL\tRESULT\tSAVINGS\tEXPLANATION\tISSUES")
+	for _, res := range r.Results {
+		status := "pass"
+		if res.Error != "" {
+			status = "error"
+		} else if !res.Passed {
+			status = "FAIL"
+		}
+		var issues []string
+		issues = append(issues, res.Violations...)
+		for _, it := range res.HallucinatedTypes {
+			issues = append(issues, "hallucinated "+it)
+		}
+		issues = append(issues, res.ExpectationFailures...)
+		if res.Error != "" {
+			issues = append(issues, res.Error)
+		}
+		explanation := "-"
+		if res.Explanation != nil {
+			explanation = fmt.Sprintf("%.3f (%s)", res.Explanation.Score, res.Explanation.Source)
+			issues = append(issues, res.Explanation.Issues...)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.2f%%\t%s\t%s\n", res.Case, res.NodePool, status, res.SavingsPercent, explanation, strings.Join(issues, "; "))
+	}
+	return tw.Flush()
+}
+
+// Compare returns the summary metric deltas and the NodePools whose results changed
+func Compare(base, head *Report) *Diff {
+	diff := &Diff{
+		BaseLLM:            base.LLM,
+		HeadLLM:            head.LLM,
+		BasePromptVersions: base.PromptVersions,
+		HeadPromptVersions: head.PromptVersions,
+		Changes:            []ResultChange{},
+	}
+	metric := func(name string, b, h float64) {
+		diff.Metrics = append(diff.Metrics, MetricChange{Name: name, Base: b, Head: h, Delta: round(h-b, 4)})
+	}
+	bs, hs := base.Summary, head.Summary
+	metric("passRate", bs.PassRate, hs.PassRate)
+	metric("savingsPercent", bs.SavingsPercent, hs.SavingsPercent)
+	metric("recommendedCost", bs.RecommendedCost, hs.RecommendedCost)
+	metric("violations", float64(bs.Violations), float64(hs.Violations))
+	metric("hallucinatedTypes", float64(bs.HallucinatedTypes), float64(hs.HallucinatedTypes))
+	metric("expectationFailures", float64(bs.ExpectationFailures), float64(hs.ExpectationFailures))
+	metric("explanationScore", bs.ExplanationScore, hs.ExplanationScore)
+	metric("errors", float64(bs.Errors), float64(hs.Errors))
+
+	baseResults := make(map[string]Result, len(base.Results))
+	for _, r := range base.Results {
+		baseResults[r.Case+"/"+r.NodePool] = r
+	}
+	seen := make(map[string]bool, len(head.Results))
+	for _, h := range head.Results {
+		key := h.Case + "/" + h.NodePool
+		seen[key] = true
+		b, ok := baseResults[key]
+		if !ok {
+			diff.Changes = append(diff.Changes, ResultChange{Case: h.Case, NodePool: h.NodePool, Changes: []string{"new in head"}})
+			continue
+		}
+		if change := compareResults(b, h); len(change.Changes) > 0 {
+			diff.Changes = append(diff.Changes, change)
+		}
+	}
+	for _, b := range base.Results {
+		if !seen[b.Case+"/"+b.NodePool] {
+			diff.Changes = append(diff.Changes, ResultChange{Case: b.Case, NodePool: b.NodePool, Changes: []string{"missing in head"}})
+		}
+	}
+	return diff
+}
+
+func compareResults(b, h Result) ResultChange {
+	change := ResultChange{Case: h.Case, NodePool: h.NodePool}
+	add := func(format string, args ...interface{}) {
+		change.Changes = append(change.Changes, fmt.Sprintf(format, args...))
+	}
+
+	if b.Passed && !h.Passed {
+		add("now failing")
+		change.Regression = true
+	} else if !b.Passed && h.Passed {
+		add("now passing")
+	}
+	if h.Error != b.Error {
+		if h.Error != "" {
+			add("error: %s", h.Error)
+			change.Regression = true
+		} else {
+			add("error resolved")
+		}
+	}
+	if strings.Join(b.InstanceTypes, ",") != strings.Join(h.InstanceTypes, ",") {
+		add("instance types %v -> %v", b.InstanceTypes, h.InstanceTypes)
+	}
+	if b.CapacityType != h.CapacityType {
+		add("capacity type %s -> %s", b.CapacityType, h.CapacityType)
+	}
+	if b.SavingsPercent != h.SavingsPercent {
+		add("savings %.2f%% -> %.2f%%", b.SavingsPercent, h.SavingsPercent)
+	}
+	for _, v := range added(b.Violations, h.Violations) {
+		add("new violation: %s", v)
+		change.Regression = true
+	}
+	for _, it := range added(b.HallucinatedTypes, h.HallucinatedTypes) {
+		add("new hallucinated type: %s", it)
+		change.Regression = true
+	}
+	for _, f := range added(b.ExpectationFailures, h.ExpectationFailures) {
+		add("new expectation failure: %s", f)
+		change.Regression = true
+	}
+	if bs, hs := explanationScore(b), explanationScore(h); bs != hs {
+		add("explanation score %s -> %s", bs, hs)
+	}
+	return change
+}
+
+func explanationScore(r Result) string {
+	if r.Explanation == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.3f", r.Explanation.Score)
+}
+
+// added returns the values in head that are not in base
+func added(base, head []string) []string {
+	inBase := make(map[string]bool, len(base))
+	for _, v := range base {
+		inBase[v] = true
+	}
+	var out []string
+	for _, v := range head {
+		if !inBase[v] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Regressions returns the number of NodePools that regressed
+func (d *Diff) Regressions() int {
+	n := 0
+	for _, c := range d.Changes {
+		if c.Regression {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteText writes the metric deltas and the changed NodePools, regressions first
+func (d *Diff) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if d.BaseLLM != d.HeadLLM {
+		fmt.Fprintf(tw, "LLM:\t%s -> %s\n", orNone(d.BaseLLM), orNone(d.HeadLLM))
+	}
+	if strings.Join(d.BasePromptVersions, ",") != strings.Join(d.HeadPromptVersions, ",") {
+		fmt.Fprintf(tw, "Prompts:\t%s -> %s\n", orNone(strings.Join(d.BasePromptVersions, ", ")), orNone(strings.Join(d.HeadPromptVersions, ", ")))
+	}
+	fmt.Fprintln(tw, "METRIC\tBASE\tHEAD\tDELTA")
+	for _, m := range d.Metrics {
+		fmt.Fprintf(tw, "%s\t%g\t%g\t%+g\n", m.Name, m.Base, m.Head, m.Delta)
+	}
+	fmt.Fprintln(tw)
+
+	if len(d.Changes) == 0 {
+		fmt.Fprintln(tw, "No NodePool results changed.")
+		return tw.Flush()
+	}
+	fmt.Fprintf(tw, "%d NodePool result(s) changed, %d regression(s):\n", len(d.Changes), d.Regressions())
+	for _, regression := range []bool{true, false} {
+		for _, c := range d.Changes {
+			if c.Regression != regression {
+				continue
+			}
+			marker := " "
+			if c.Regression {
+				marker = "!"
+			}
+			fmt.Fprintf(tw, "%s %s/%s:\t%s\n", marker, c.Case, c.NodePool, strings.Join(c.Changes, "; "))
+		}
+	}
+	return tw.Flush()
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
@@ -0,0 +1,322 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/recommender"
+)
+
+// Explanation length bounds in words
+const (
+	minExplanationWords = 15
+	maxExplanationWords = 150
+)
+
+// instanceTypeMention matches EC2 instance type names in free text
+var instanceTypeMention = regexp.MustCompile(`(?i)\b[a-z]+[0-9][a-z0-9-]*\.(?:nano|micro|small|medium|large|[0-9]*xlarge|metal(?:-[0-9]+xl)?)\b`)
+
+// Report is the outcome of a run over a corpus
+type Report struct {
+	LLM            string   `json:"llm,omitempty"`            // provider/model, empty when only the rules ran
+	PromptVersions []string `json:"promptVersions,omitempty"` // Prompt templates the AI explanations came from
+	Summary        Summary  `json:"summary"`
+	Results        []Result `json:"results"` // Sorted by case and NodePool
+}
+
+// Summary aggregates the results
+type Summary struct {
+	Cases               int     `json:"cases"`
+	NodePools           int     `json:"nodePools"`
+	Passed              int     `json:"passed"`
+	PassRate            float64 `json:"passRate"`
+	Recommendations     int     `json:"recommendations"`
+	CurrentCost         float64 `json:"currentCost"`     // Hourly, NodePools with a recommendation
+	RecommendedCost     float64 `json:"recommendedCost"` // Hourly, NodePools with a recommendation
+	SavingsPercent      float64 `json:"savingsPercent"`
+	Violations          int     `json:"violations"`
+	HallucinatedTypes   int     `json:"hallucinatedTypes"`
+	ExpectationFailures int     `json:"expectationFailures"`
+	ExplanationScore    float64 `json:"explanationScore"` // Mean over recommendations, 0-1
+	Errors              int     `json:"errors"`
+}
+
+// Result scores the recommendation for one NodePool of a case
+type Result struct {
+	Case                string            `json:"case"`
+	NodePool            string            `json:"nodePool"`
+	Passed              bool              `json:"passed"`
+	HasRecommendation   bool              `json:"hasRecommendation"`
+	InstanceTypes       []string          `json:"instanceTypes"`
+	CapacityType        string            `json:"capacityType"`
+	CurrentCost         float64           `json:"currentCost"`
+	RecommendedCost     float64           `json:"recommendedCost"`
+	SavingsPercent      float64           `json:"savingsPercent"`
+	Violations          []string          `json:"violations,omitempty"`        // Recommendations the NodePool or its usage rules out
+	HallucinatedTypes   []string          `json:"hallucinatedTypes,omitempty"` // Instance types not in the catalog
+	ExpectationFailures []string          `json:"expectationFailures,omitempty"`
+	Explanation         *ExplanationScore `json:"explanation,omitempty"` // Only for recommendations
+	Error               string            `json:"error,omitempty"`
+}
+
+// ExplanationScore rates an explanation with heuristics: length, specificity, plain text,
+// grounding in the recommended and current instance types, and the expected terms
+type ExplanationScore struct {
+	Source string   `json:"source"` // "ai" or "rules"
+	Words  int      `json:"words"`
+	Score  float64  `json:"score"` // Share of the checks passed, 0-1
+	Issues []string `json:"issues,omitempty"`
+}
+
+// Run generates recommendations for every case and scores them. A case whose
+// recommendations fail is reported with an error result instead of aborting the run.
+func Run(ctx context.Context, rec *recommender.Recommender, cases []Case) *Report {
+	report := &Report{Results: []Result{}}
+	if rec.HasLLM() {
+		if statuses := rec.GetOllamaClient().ProviderStatuses(); len(statuses) > 0 {
+			report.LLM = statuses[0].Provider + "/" + statuses[0].Model
+		}
+	}
+
+	promptVersions := make(map[string]bool)
+	for _, c := range cases {
+		recommendations, err := rec.GenerateRecommendationsFromNodePools(ctx, c.NodePools, nil)
+		if err != nil {
+			report.Results = append(report.Results, Result{Case: c.Name, Error: err.Error()})
+			continue
+		}
+		byName := make(map[string]recommender.NodePoolCapacityRecommendation, len(recommendations))
+		for _, r := range recommendations {
+			byName[r.NodePoolName] = r
+			if r.AIPromptVersion != "" {
+				promptVersions[r.AIPromptVersion] = true
+			}
+		}
+		for _, np := range c.NodePools {
+			r, ok := byName[np.Name]
+			if !ok {
+				report.Results = append(report.Results, Result{Case: c.Name, NodePool: np.Name, Error: "no recommendation returned"})
+				continue
+			}
+			report.Results = append(report.Results, Score(c.Name, np, r, c.Expect[np.Name]))
+		}
+	}
+
+	for version := range promptVersions {
+		report.PromptVersions = append(report.PromptVersions, version)
+	}
+	sort.Strings(report.PromptVersions)
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		if report.Results[i].Case != report.Results[j].Case {
+			return report.Results[i].Case < report.Results[j].Case
+		}
+		return report.Results[i].NodePool < report.Results[j].NodePool
+	})
+	report.Summary = summarize(len(cases), report.Results)
+	return report
+}
+
+// Score checks a NodePool's recommendation against the NodePool, its usage and the expectation
+func Score(caseName string, np kubernetes.NodePoolInfo, rec recommender.NodePoolCapacityRecommendation, expect Expectation) Result {
+	result := Result{
+		Case:              caseName,
+		NodePool:          np.Name,
+		HasRecommendation: rec.HasRecommendation,
+		InstanceTypes:     []string{},
+		CapacityType:      rec.CapacityType,
+		CurrentCost:       round(rec.CurrentCost, 4),
+		RecommendedCost:   round(rec.RecommendedCost, 4),
+		SavingsPercent:    round(rec.CostSavingsPercent, 2),
+	}
+	// Without a recommendation the current instance types are echoed back with their node counts
+	if rec.HasRecommendation {
+		result.InstanceTypes = append(result.InstanceTypes, rec.RecommendedInstanceTypes...)
+	}
+	sort.Strings(result.InstanceTypes)
+
+	// Made-up instance types, then catalog types the NodePool rules out
+	var catalogTypes []string
+	for _, it := range result.InstanceTypes {
+		if recommender.InstanceTypeInCatalog(it) {
+			catalogTypes = append(catalogTypes, it)
+		} else {
+			result.HallucinatedTypes = append(result.HallucinatedTypes, it)
+		}
+	}
+	_, rejections := recommender.ValidateInstanceTypes(np, catalogTypes, recommender.NodePoolUsesGPU(np))
+	for _, rejection := range rejections {
+		result.Violations = append(result.Violations, fmt.Sprintf("%s: %s", rejection.Value, rejection.Reason))
+	}
+	if rec.HasRecommendation {
+		if rec.RecommendedTotalCPU < rec.CurrentCPUUsed {
+			result.Violations = append(result.Violations, fmt.Sprintf("recommended CPU %.2f cores is below the %.2f cores used", rec.RecommendedTotalCPU, rec.CurrentCPUUsed))
+		}
+		if rec.RecommendedTotalMemory < rec.CurrentMemoryUsed {
+			result.Violations = append(result.Violations, fmt.Sprintf("recommended memory %.2f GiB is below the %.2f GiB used", rec.RecommendedTotalMemory, rec.CurrentMemoryUsed))
+		}
+	}
+
+	result.ExpectationFailures = checkExpectation(rec, result.InstanceTypes, expect)
+
+	if rec.HasRecommendation {
+		text, source := rec.AIReasoning, "ai"
+		if text == "" {
+			text, source = rec.Reasoning, "rules"
+		}
+		explanation, hallucinated := scoreExplanation(text, source, rec, expect.ExplanationMustMention)
+		result.Explanation = &explanation
+		for _, it := range hallucinated {
+			result.HallucinatedTypes = appendUnique(result.HallucinatedTypes, it)
+		}
+	}
+
+	result.Passed = len(result.Violations) == 0 && len(result.HallucinatedTypes) == 0 && len(result.ExpectationFailures) == 0
+	return result
+}
+
+func checkExpectation(rec recommender.NodePoolCapacityRecommendation, instanceTypes []string, expect Expectation) []string {
+	var failures []string
+	if expect.HasRecommendation != nil && *expect.HasRecommendation != rec.HasRecommendation {
+		failures = append(failures, fmt.Sprintf("hasRecommendation is %t, expected %t", rec.HasRecommendation, *expect.HasRecommendation))
+	}
+	if expect.MinSavingsPercent != nil && rec.CostSavingsPercent < *expect.MinSavingsPercent {
+		failures = append(failures, fmt.Sprintf("savings %.1f%% below the expected minimum %.1f%%", rec.CostSavingsPercent, *expect.MinSavingsPercent))
+	}
+	if expect.MaxSavingsPercent != nil && rec.CostSavingsPercent > *expect.MaxSavingsPercent {
+		failures = append(failures, fmt.Sprintf("savings %.1f%% above the expected maximum %.1f%%", rec.CostSavingsPercent, *expect.MaxSavingsPercent))
+	}
+	if expect.CapacityType != "" && rec.HasRecommendation && rec.CapacityType != expect.CapacityType {
+		failures = append(failures, fmt.Sprintf("capacity type %s, expected %s", rec.CapacityType, expect.CapacityType))
+	}
+	for _, it := range instanceTypes {
+		for _, pattern := range expect.ForbiddenInstanceTypes {
+			if matched, _ := path.Match(pattern, it); matched {
+				failures = append(failures, fmt.Sprintf("instance type %s matches forbidden pattern %s", it, pattern))
+			}
+		}
+	}
+	return failures
+}
+
+// scoreExplanation returns the explanation's score and the instance types it mentions that
+// are not in the catalog
+func scoreExplanation(text, source string, rec recommender.NodePoolCapacityRecommendation, mustMention []string) (ExplanationScore, []string) {
+	score := ExplanationScore{Source: source, Words: len(strings.Fields(text))}
+	checks := 0
+	fail := func(issue string) { score.Issues = append(score.Issues, issue) }
+
+	checks++
+	if score.Words < minExplanationWords {
+		fail(fmt.Sprintf("too short (%d words, at least %d)", score.Words, minExplanationWords))
+	} else if score.Words > maxExplanationWords {
+		fail(fmt.Sprintf("too long (%d words, at most %d)", score.Words, maxExplanationWords))
+	}
+
+	checks++
+	if !strings.ContainsAny(instanceTypeMention.ReplaceAllString(text, ""), "0123456789") {
+		fail("no figures (node counts, costs or utilization)")
+	}
+
+	checks++
+	if strings.Contains(text, "**") || strings.Contains(text, "```") || strings.HasPrefix(strings.TrimSpace(text), "#") || strings.Contains(text, "\n#") {
+		fail("markdown formatting")
+	}
+
+	checks++
+	known := make(map[string]bool)
+	for _, it := range append(append([]string{}, rec.CurrentInstanceTypes...), rec.RecommendedInstanceTypes...) {
+		known[strings.ToLower(it)] = true
+	}
+	var ungrounded, hallucinated []string
+	for _, mention := range instanceTypeMention.FindAllString(text, -1) {
+		it := strings.ToLower(mention)
+		if known[it] {
+			continue
+		}
+		ungrounded = appendUnique(ungrounded, it)
+		if !recommender.InstanceTypeInCatalog(it) {
+			hallucinated = appendUnique(hallucinated, it)
+		}
+	}
+	if len(ungrounded) > 0 {
+		fail("mentions instance types that are neither current nor recommended: " + strings.Join(ungrounded, ", "))
+	}
+
+	if len(mustMention) > 0 {
+		checks++
+		lower := strings.ToLower(text)
+		var missing []string
+		for _, term := range mustMention {
+			if !strings.Contains(lower, strings.ToLower(term)) {
+				missing = append(missing, term)
+			}
+		}
+		if len(missing) > 0 {
+			fail("does not mention " + strings.Join(missing, ", "))
+		}
+	}
+
+	score.Score = round(float64(checks-len(score.Issues))/float64(checks), 3)
+	return score, hallucinated
+}
+
+func summarize(cases int, results []Result) Summary {
+	summary := Summary{Cases: cases}
+	var explanationTotal float64
+	explanations := 0
+	for _, r := range results {
+		if r.Error != "" {
+			summary.Errors++
+			continue
+		}
+		summary.NodePools++
+		if r.Passed {
+			summary.Passed++
+		}
+		if r.HasRecommendation {
+			summary.Recommendations++
+			summary.CurrentCost += r.CurrentCost
+			summary.RecommendedCost += r.RecommendedCost
+		}
+		summary.Violations += len(r.Violations)
+		summary.HallucinatedTypes += len(r.HallucinatedTypes)
+		summary.ExpectationFailures += len(r.ExpectationFailures)
+		if r.Explanation != nil {
+			explanationTotal += r.Explanation.Score
+			explanations++
+		}
+	}
+	if summary.NodePools > 0 {
+		summary.PassRate = round(float64(summary.Passed)/float64(summary.NodePools), 3)
+	}
+	if explanations > 0 {
+		summary.ExplanationScore = round(explanationTotal/float64(explanations), 3)
+	}
+	if summary.CurrentCost > 0 {
+		summary.SavingsPercent = round((summary.CurrentCost-summary.RecommendedCost)/summary.CurrentCost*100, 2)
+	}
+	summary.CurrentCost = round(summary.CurrentCost, 4)
+	summary.RecommendedCost = round(summary.RecommendedCost, 4)
+	return summary
+}
+
+// round keeps reports stable across runs by dropping floating point noise
+func round(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
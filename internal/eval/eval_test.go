@@ -0,0 +1,262 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/karpenter-optimizer/internal/config"
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/ollama"
+	"github.com/karpenter-optimizer/internal/recommender"
+	"github.com/karpenter-optimizer/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const exampleCorpus = "../../examples/eval"
+
+func TestLoadCorpus(t *testing.T) {
+	cases, err := LoadCorpus(exampleCorpus)
+	require.NoError(t, err)
+	require.Len(t, cases, 3)
+	assert.Equal(t, "graviton-requirements", cases[0].Name)
+	assert.Equal(t, "overprovisioned-on-demand", cases[1].Name)
+	assert.Equal(t, "right-sized-spot", cases[2].Name)
+	require.NotNil(t, cases[1].Expect["default"].MinSavingsPercent)
+	assert.Equal(t, 40.0, *cases[1].Expect["default"].MinSavingsPercent)
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		err   string
+	}{
+		{
+			name:  "empty corpus",
+			files: map[string]string{"README.md": "not a case"},
+			err:   "no cases",
+		},
+		{
+			name:  "no NodePools",
+			files: map[string]string{"empty.yaml": "name: empty\n"},
+			err:   "no nodePools",
+		},
+		{
+			name:  "expectation for unknown NodePool",
+			files: map[string]string{"a.yaml": "nodePools:\n- name: default\nexpect:\n  other:\n    hasRecommendation: true\n"},
+			err:   "unknown NodePool other",
+		},
+		{
+			name: "duplicate names",
+			files: map[string]string{
+				"a.yaml": "name: same\nnodePools:\n- name: default\n",
+				"b.json": `{"name": "same", "nodePools": [{"name": "default"}]}`,
+			},
+			err: "defined in both a.yaml and b.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+			}
+			_, err := LoadCorpus(dir)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	yes := true
+	minSavings := 30.0
+	np := kubernetes.NodePoolInfo{
+		Name: "default",
+		NodeRequirements: []corev1.NodeSelectorRequirement{
+			{Key: "kubernetes.io/arch", Operator: corev1.NodeSelectorOpIn, Values: []string{"amd64"}},
+		},
+	}
+	rec := recommender.NodePoolCapacityRecommendation{
+		NodePoolName:             "default",
+		HasRecommendation:        true,
+		CurrentInstanceTypes:     []string{"m6i.2xlarge"},
+		RecommendedInstanceTypes: []string{"m9z.large", "m7g.large", "g5.xlarge"},
+		CapacityType:             "on-demand",
+		CurrentCost:              1.536,
+		RecommendedCost:          1.2,
+		CostSavingsPercent:       21.875,
+		CurrentCPUUsed:           6,
+		RecommendedTotalCPU:      4,
+		CurrentMemoryUsed:        8,
+		RecommendedTotalMemory:   16,
+		AIReasoning:              "**Move** to c9q.large nodes.",
+	}
+	expect := Expectation{
+		HasRecommendation:      &yes,
+		MinSavingsPercent:      &minSavings,
+		CapacityType:           "spot",
+		ForbiddenInstanceTypes: []string{"g*"},
+		ExplanationMustMention: []string{"spot"},
+	}
+
+	result := Score("case", np, rec, expect)
+	assert.False(t, result.Passed)
+	assert.Equal(t, []string{"g5.xlarge", "m7g.large", "m9z.large"}, result.InstanceTypes)
+	assert.Equal(t, []string{"m9z.large", "c9q.large"}, result.HallucinatedTypes)
+	require.Len(t, result.Violations, 3)
+	assert.Contains(t, result.Violations[0], "g5.xlarge: GPU instance type")
+	assert.Contains(t, result.Violations[1], "m7g.large: NodePool requires kubernetes.io/arch")
+	assert.Contains(t, result.Violations[2], "recommended CPU 4.00 cores is below the 6.00 cores used")
+	assert.Equal(t, []string{
+		"savings 21.9% below the expected minimum 30.0%",
+		"capacity type on-demand, expected spot",
+		"instance type g5.xlarge matches forbidden pattern g*",
+	}, result.ExpectationFailures)
+	assert.Equal(t, 21.88, result.SavingsPercent)
+
+	require.NotNil(t, result.Explanation)
+	assert.Equal(t, "ai", result.Explanation.Source)
+	assert.Equal(t, 4, result.Explanation.Words)
+	assert.Equal(t, 0.0, result.Explanation.Score)
+	assert.Len(t, result.Explanation.Issues, 5)
+
+	t.Run("no recommendation", func(t *testing.T) {
+		result := Score("case", kubernetes.NodePoolInfo{Name: "idle"}, recommender.NodePoolCapacityRecommendation{NodePoolName: "idle", Reasoning: "No nodes."}, Expectation{})
+		assert.True(t, result.Passed)
+		assert.Nil(t, result.Explanation)
+	})
+
+	t.Run("grounded explanation", func(t *testing.T) {
+		rec := recommender.NodePoolCapacityRecommendation{
+			HasRecommendation:        true,
+			CurrentInstanceTypes:     []string{"m6i.2xlarge"},
+			RecommendedInstanceTypes: []string{"m6i.xlarge"},
+			CapacityType:             "spot",
+			Reasoning:                "Replacing 4 m6i.2xlarge nodes at 20% CPU utilization with 3 m6i.xlarge spot nodes keeps headroom for the workloads and cuts the hourly cost by 60%.",
+		}
+		result := Score("case", np, rec, Expectation{ExplanationMustMention: []string{"Spot"}})
+		assert.True(t, result.Passed)
+		require.NotNil(t, result.Explanation)
+		assert.Equal(t, "rules", result.Explanation.Source)
+		assert.Equal(t, 1.0, result.Explanation.Score)
+		assert.Empty(t, result.Explanation.Issues)
+	})
+}
+
+func TestRunExampleCorpus(t *testing.T) {
+	cases, err := LoadCorpus(exampleCorpus)
+	require.NoError(t, err)
+	rec := recommender.NewOfflineRecommender(&config.Config{})
+
+	report := Run(context.Background(), rec, cases)
+	assert.Empty(t, report.LLM)
+	assert.Empty(t, report.PromptVersions)
+	assert.Equal(t, 3, report.Summary.Cases)
+	assert.Equal(t, 4, report.Summary.NodePools)
+	assert.Zero(t, report.Summary.Errors)
+	assert.Zero(t, report.Summary.HallucinatedTypes)
+	require.Len(t, report.Results, 4)
+	assert.Equal(t, "graviton-requirements", report.Results[0].Case)
+	assert.Equal(t, "arm", report.Results[0].NodePool)
+	for _, result := range report.Results {
+		assert.True(t, result.Passed, "%s/%s: %v %v", result.Case, result.NodePool, result.Violations, result.ExpectationFailures)
+	}
+	assert.Equal(t, 1.0, report.Summary.PassRate)
+	assert.Zero(t, report.Summary.Violations)
+
+	// The rules are deterministic, so two runs produce the same report and an empty diff
+	var first, second bytes.Buffer
+	require.NoError(t, report.WriteJSON(&first))
+	require.NoError(t, Run(context.Background(), rec, cases).WriteJSON(&second))
+	assert.Equal(t, first.String(), second.String())
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, os.WriteFile(path, first.Bytes(), 0o644))
+	loaded, err := LoadReport(path)
+	require.NoError(t, err)
+	diff := Compare(report, loaded)
+	assert.Empty(t, diff.Changes)
+	assert.Zero(t, diff.Regressions())
+
+	var text bytes.Buffer
+	require.NoError(t, report.WriteText(&text))
+	assert.Contains(t, text.String(), "none (rules only)")
+	assert.Contains(t, text.String(), "overprovisioned-on-demand  default")
+}
+
+func TestRunWithLLM(t *testing.T) {
+	server := testutil.NewFakeLLMServer(ollama.Message{
+		Role:    "assistant",
+		Content: "Move the 4 m6i.2xlarge nodes to m9x.large nodes to cut the cost by 60% while keeping enough CPU and memory headroom for the workloads.",
+	})
+	defer server.Close()
+
+	cases, err := LoadCorpus(exampleCorpus)
+	require.NoError(t, err)
+	var overprovisioned []Case
+	for _, c := range cases {
+		if c.Name == "overprovisioned-on-demand" {
+			overprovisioned = append(overprovisioned, c)
+		}
+	}
+	rec := recommender.NewOfflineRecommender(&config.Config{LLMURL: server.URL, LLMProvider: "litellm", LLMModel: "test-model"})
+
+	report := Run(context.Background(), rec, overprovisioned)
+	assert.Equal(t, "litellm/test-model", report.LLM)
+	assert.Equal(t, []string{"reasoning-enhancement@1"}, report.PromptVersions)
+	require.Len(t, report.Results, 1)
+	result := report.Results[0]
+	require.NotNil(t, result.Explanation)
+	assert.Equal(t, "ai", result.Explanation.Source)
+	assert.Equal(t, []string{"m9x.large"}, result.HallucinatedTypes)
+	assert.False(t, result.Passed)
+}
+
+func TestCompare(t *testing.T) {
+	base := &Report{
+		Summary: Summary{PassRate: 1, SavingsPercent: 40},
+		Results: []Result{
+			{Case: "a", NodePool: "default", Passed: true, InstanceTypes: []string{"m6i.xlarge"}, SavingsPercent: 40},
+			{Case: "a", NodePool: "batch", Passed: true},
+			{Case: "b", NodePool: "default", Passed: true},
+		},
+	}
+	head := &Report{
+		LLM:            "ollama/llama3",
+		PromptVersions: []string{"reasoning-enhancement@2"},
+		Summary:        Summary{PassRate: 0.5, SavingsPercent: 45, HallucinatedTypes: 1},
+		Results: []Result{
+			{Case: "a", NodePool: "default", InstanceTypes: []string{"m9x.large"}, SavingsPercent: 45, HallucinatedTypes: []string{"m9x.large"}},
+			{Case: "a", NodePool: "batch", Passed: true},
+			{Case: "c", NodePool: "default", Passed: true},
+		},
+	}
+
+	diff := Compare(base, head)
+	assert.Equal(t, 1, diff.Regressions())
+	require.Len(t, diff.Changes, 3)
+	assert.Equal(t, ResultChange{
+		Case:       "a",
+		NodePool:   "default",
+		Regression: true,
+		Changes: []string{
+			"now failing",
+			"instance types [m6i.xlarge] -> [m9x.large]",
+			"savings 40.00% -> 45.00%",
+			"new hallucinated type: m9x.large",
+		},
+	}, diff.Changes[0])
+	assert.Equal(t, []string{"new in head"}, diff.Changes[1].Changes)
+	assert.Equal(t, []string{"missing in head"}, diff.Changes[2].Changes)
+	assert.Equal(t, MetricChange{Name: "passRate", Base: 1, Head: 0.5, Delta: -0.5}, diff.Metrics[0])
+
+	var text bytes.Buffer
+	require.NoError(t, diff.WriteText(&text))
+	assert.Contains(t, text.String(), "none -> ollama/llama3")
+	assert.Contains(t, text.String(), "3 NodePool result(s) changed, 1 regression(s):")
+	assert.Contains(t, text.String(), "! a/default:")
+}
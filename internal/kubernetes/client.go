@@ -21,7 +21,6 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
@@ -31,10 +30,12 @@ import (
 )
 
 type Client struct {
-	clientset       *kubernetes.Clientset
+	clientset       kubernetes.Interface
 	dynamicClient   dynamic.Interface
 	discoveryClient discovery.DiscoveryInterface
 	debug           bool
+	// disruptionStore holds recorded disruption history (nil when the recorder is not running)
+	disruptionStore *DisruptionStore
 }
 
 type WorkloadInfo struct {
@@ -48,12 +49,16 @@ type WorkloadInfo struct {
 	Replicas      int32             `json:"replicas"` // For jobs, this is parallelism/completions
 	Labels        map[string]string `json:"labels"`
 	GPU           int               `json:"gpu"`
-	CPUUsed       float64           `json:"cpuUsed,omitempty"`     // Total CPU usage from running pods (resource requests)
-	MemoryUsed    float64           `json:"memoryUsed,omitempty"`  // Total Memory usage from running pods (resource requests) in GiB
-	RunningPods   int32             `json:"runningPods,omitempty"` // Number of running pods for this workload
-	StorageSize   float64           `json:"storageSize,omitempty"` // Total storage size from PVCs in GiB
-	StorageUsed   float64           `json:"storageUsed,omitempty"` // Storage usage (if available from metrics) in GiB
-	PVCCount      int               `json:"pvcCount,omitempty"`    // Number of PVCs associated with this workload
+	GPUModel      string            `json:"gpuModel,omitempty"`     // GPU model pinned via node selector/affinity (e.g., NVIDIA-A10G, a10g)
+	GPUMemoryMiB  int64             `json:"gpuMemoryMiB,omitempty"` // Minimum memory per GPU in MiB (karpenter.k8s.aws/instance-gpu-memory)
+	MIGProfiles   map[string]int    `json:"migProfiles,omitempty"`  // MIG profile -> slices requested (e.g., "1g.5gb": 2)
+	SharedGPU     int               `json:"sharedGpu,omitempty"`    // Time-sliced GPU replicas requested (nvidia.com/gpu.shared)
+	CPUUsed       float64           `json:"cpuUsed,omitempty"`      // Total CPU usage from running pods (resource requests)
+	MemoryUsed    float64           `json:"memoryUsed,omitempty"`   // Total Memory usage from running pods (resource requests) in GiB
+	RunningPods   int32             `json:"runningPods,omitempty"`  // Number of running pods for this workload
+	StorageSize   float64           `json:"storageSize,omitempty"`  // Total storage size from PVCs in GiB
+	StorageUsed   float64           `json:"storageUsed,omitempty"`  // Storage usage (if available from metrics) in GiB
+	PVCCount      int               `json:"pvcCount,omitempty"`     // Number of PVCs associated with this workload
 }
 
 func NewClient(kubeconfigPath, kubeContext string) (*Client, error) {
@@ -147,6 +152,11 @@ func NewClientWithDebug(kubeconfigPath, kubeContext string, debug bool) (*Client
 	}, nil
 }
 
+// Clientset returns the underlying Kubernetes clientset (for packages storing state in the cluster)
+func (c *Client) Clientset() kubernetes.Interface {
+	return c.clientset
+}
+
 // debugLog prints debug messages only if debug logging is enabled
 func (c *Client) debugLog(format string, args ...interface{}) {
 	if c.debug {
@@ -711,6 +721,7 @@ func (c *Client) extractResourcesFromPodSpec(podSpec *corev1.PodSpec, workload *
 		workload.MemoryLimit = totalMemoryLimit.String()
 	}
 	workload.GPU = gpuCount
+	extractGPURequirements(podSpec, workload)
 }
 
 // NodeInfo represents actual node information from the cluster
@@ -726,6 +737,7 @@ type NodeInfo struct {
 	PodCount     int        `json:"podCount"`               // Number of pods scheduled on this node
 	PodNames     []string   `json:"podNames,omitempty"`     // Names of pods running on this node (namespace/name format)
 	CreationTime string     `json:"creationTime,omitempty"` // Node creation timestamp
+	GPU          *GPUUsage  `json:"gpu,omitempty"`          // GPU allocation (nil for non-GPU nodes)
 }
 
 // NodeUsage represents resource usage for a node
@@ -761,10 +773,17 @@ type NodePoolInfo struct {
 	ActualNodes   []NodeInfo        `json:"actualNodes,omitempty"`   // Actual node details
 	// Selectors for matching workloads to this NodePool
 	Selector map[string]string `json:"selector,omitempty"` // NodePool selector labels
+	// Disruption settings
+	ConsolidationPolicy string `json:"consolidationPolicy,omitempty"` // WhenEmpty, WhenEmptyOrUnderutilized (WhenUnderutilized in v1beta1)
+	ConsolidateAfter    string `json:"consolidateAfter,omitempty"`    // Duration or "Never"
+	ExpireAfter         string `json:"expireAfter,omitempty"`         // Duration or "Never"
+	Generation          int64  `json:"generation,omitempty"`          // metadata.generation, bumped on every spec change
+	// All node requirements with their operators and values (Requirements only keeps the first In value)
+	NodeRequirements []corev1.NodeSelectorRequirement `json:"nodeRequirements,omitempty"`
 }
 
-// ListNodePools lists all Karpenter NodePools in the cluster
-func (c *Client) ListNodePools(ctx context.Context) ([]NodePoolInfo, error) {
+// listNodePoolObjects lists raw NodePool objects, trying the discovered API version first
+func (c *Client) listNodePoolObjects(ctx context.Context) (*unstructured.UnstructuredList, error) {
 	// First, try to discover the actual resource name and version
 	gvr, err := c.discoverNodePoolResource(ctx)
 	if err != nil {
@@ -794,6 +813,16 @@ func (c *Client) ListNodePools(ctx context.Context) ([]NodePoolInfo, error) {
 	if nodePools == nil {
 		return nil, fmt.Errorf("failed to list nodepools (tried versions: %v). Last error: %w. Check RBAC permissions: kubectl auth can-i list nodepools.karpenter.sh", versions, lastErr)
 	}
+	return nodePools, nil
+}
+
+// ListNodePoolSpecs lists NodePools with their parsed spec only, without the node
+// and usage lookups ListNodePools does
+func (c *Client) ListNodePoolSpecs(ctx context.Context) ([]NodePoolInfo, error) {
+	nodePools, err := c.listNodePoolObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	var result []NodePoolInfo
 	for _, item := range nodePools.Items {
@@ -818,6 +847,17 @@ func (c *Client) ListNodePools(ctx context.Context) ([]NodePoolInfo, error) {
 		result = append(result, *np)
 	}
 
+	c.debugLog("Successfully parsed %d out of %d NodePools\n", len(result), len(nodePools.Items))
+	return result, nil
+}
+
+// ListNodePools lists all Karpenter NodePools in the cluster
+func (c *Client) ListNodePools(ctx context.Context) ([]NodePoolInfo, error) {
+	result, err := c.ListNodePoolSpecs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get actual node information with usage data (including pod counts) for each NodePool
 	allNodes, err := c.GetAllNodesWithUsage(ctx)
 	if err == nil {
@@ -844,7 +884,6 @@ func (c *Client) ListNodePools(ctx context.Context) ([]NodePoolInfo, error) {
 		}
 	}
 
-	c.debugLog("Successfully parsed %d out of %d NodePools\n", len(result), len(nodePools.Items))
 	return result, nil
 }
 
@@ -952,6 +991,7 @@ func (c *Client) GetAllNodesWithUsage(ctx context.Context) ([]NodeInfo, error) {
 			Zone:         zone,
 			CPUUsage:     nil, // Explicitly initialize to nil
 			MemoryUsage:  nil, // Explicitly initialize to nil
+			GPU:          gpuUsageFromNode(&node),
 		}
 
 		// Extract CPU capacity and allocatable
@@ -1026,6 +1066,10 @@ func (c *Client) GetAllNodesWithUsage(ctx context.Context) ([]NodeInfo, error) {
 				// Store pod name in namespace/name format
 				podNames = append(podNames, fmt.Sprintf("%s/%s", pod.Namespace, pod.Name))
 
+				if nodeInfo.GPU != nil {
+					nodeInfo.GPU.addPodGPUUsage(pod)
+				}
+
 				// Process only regular containers (exclude init containers per eks-node-viewer)
 				// Init containers are transient and don't contribute to steady-state resource usage
 				for j := range pod.Spec.Containers {
@@ -1142,6 +1186,15 @@ func (c *Client) GetPodsOnNodes(ctx context.Context, nodeNames map[string]bool)
 
 // discoverNodePoolResource discovers the NodePool resource using API discovery
 func (c *Client) discoverNodePoolResource(ctx context.Context) (schema.GroupVersionResource, error) {
+	return c.discoverKarpenterResource(ctx, "nodepools", "nodepool")
+}
+
+// discoverKarpenterResource finds a karpenter.sh resource (by plural or singular name) in the newest served version
+func (c *Client) discoverKarpenterResource(ctx context.Context, names ...string) (schema.GroupVersionResource, error) {
+	if c.discoveryClient == nil {
+		return schema.GroupVersionResource{}, fmt.Errorf("discovery client not configured")
+	}
+
 	// Try different API versions
 	versions := []string{"karpenter.sh/v1", "karpenter.sh/v1beta1", "karpenter.sh/v1alpha1"}
 
@@ -1151,9 +1204,8 @@ func (c *Client) discoverNodePoolResource(ctx context.Context) (schema.GroupVers
 			continue
 		}
 
-		// Find nodepools resource (can be plural or singular)
 		for _, resource := range apiResourceList.APIResources {
-			if resource.Name == "nodepools" || resource.Name == "nodepool" {
+			if contains(names, resource.Name) {
 				// Parse group and version from groupVersion (e.g., "karpenter.sh/v1")
 				parts := splitGroupVersion(groupVersion)
 				return schema.GroupVersionResource{
@@ -1165,7 +1217,7 @@ func (c *Client) discoverNodePoolResource(ctx context.Context) (schema.GroupVers
 		}
 	}
 
-	return schema.GroupVersionResource{}, fmt.Errorf("nodepools resource not found in karpenter.sh API")
+	return schema.GroupVersionResource{}, fmt.Errorf("%s resource not found in karpenter.sh API", names[0])
 }
 
 // splitGroupVersion splits "group/version" into [group, version]
@@ -1224,6 +1276,7 @@ func (c *Client) parseNodePool(item *unstructured.Unstructured) (*NodePoolInfo,
 		Labels:       item.GetLabels(),
 		Requirements: make(map[string]string),
 		Selector:     make(map[string]string),
+		Generation:   item.GetGeneration(),
 	}
 
 	// Extract spec
@@ -1282,6 +1335,16 @@ func (c *Client) parseNodePool(item *unstructured.Unstructured) (*NodePoolInfo,
 			operator, _ := reqMap["operator"].(string)
 			values, _ := reqMap["values"].([]interface{})
 
+			if key != "" {
+				nodeReq := corev1.NodeSelectorRequirement{Key: key, Operator: corev1.NodeSelectorOperator(operator)}
+				for _, v := range values {
+					if str, ok := v.(string); ok {
+						nodeReq.Values = append(nodeReq.Values, str)
+					}
+				}
+				np.NodeRequirements = append(np.NodeRequirements, nodeReq)
+			}
+
 			if operator == "In" && len(values) > 0 {
 				if val, ok := values[0].(string); ok {
 					switch key {
@@ -1332,6 +1395,16 @@ func (c *Client) parseNodePool(item *unstructured.Unstructured) (*NodePoolInfo,
 		if min, ok := disruption["consolidationPolicy"].(string); ok && min == "WhenEmpty" {
 			np.MinSize = 0
 		}
+		np.ConsolidationPolicy, _ = disruption["consolidationPolicy"].(string)
+		np.ConsolidateAfter, _ = disruption["consolidateAfter"].(string)
+		// v1beta1 keeps expireAfter under disruption
+		np.ExpireAfter, _ = disruption["expireAfter"].(string)
+	}
+	// v1 moved expireAfter to the NodeClaim template
+	if templateSpec != nil {
+		if expireAfter, ok := templateSpec["expireAfter"].(string); ok {
+			np.ExpireAfter = expireAfter
+		}
 	}
 
 	// Extract selector/labels from spec (NodePools can have selectors to match workloads)
@@ -1496,6 +1569,14 @@ type NodeDisruptionInfo struct {
 	ResourceAllocatable ResourceInfo    `json:"resourceAllocatable,omitempty"` // Node allocatable resources
 	CreationTime        string          `json:"creationTime,omitempty"`        // When node was created
 	DeletionTime        string          `json:"deletionTime,omitempty"`        // When node was marked for deletion
+	// Persistent history from the disruption recorder
+	NodeClaim       string  `json:"nodeClaim,omitempty"`
+	CapacityType    string  `json:"capacityType,omitempty"`    // spot, on-demand, reserved
+	DeletedTime     string  `json:"deletedTime,omitempty"`     // When the Node object was removed (RFC3339)
+	LifetimeSeconds float64 `json:"lifetimeSeconds,omitempty"` // Node creation to removal
+	BlockedSeconds  float64 `json:"blockedSeconds,omitempty"`  // How long eviction was blocked (FailedDraining/DisruptionBlocked)
+	BlockedSince    string  `json:"blockedSince,omitempty"`    // RFC3339, set while blocked
+	Recorded        bool    `json:"recorded,omitempty"`        // True if this entry comes from the disruption store
 }
 
 // NodeCondition represents a node condition
@@ -1747,6 +1828,20 @@ func (c *Client) GetNodeDisruptions(ctx context.Context, sinceHours int) ([]Node
 		disruptions = append(disruptions, *disruption)
 	}
 
+	// Add recorded history: disruptions of deleted nodes outlive their events (about an hour)
+	if c.disruptionStore != nil {
+		liveNodes := make(map[string]bool)
+		for _, d := range disruptions {
+			liveNodes[d.NodeName] = true
+		}
+		for _, record := range c.disruptionStore.List(now.Add(-time.Duration(sinceHours) * time.Hour)) {
+			if record.NodeStillExists || liveNodes[record.NodeName] {
+				continue
+			}
+			disruptions = append(disruptions, record)
+		}
+	}
+
 	// Also check for recent Karpenter events to catch nodes that might have been disrupted
 	// but are no longer in the cluster (already deleted)
 	events, err := c.clientset.CoreV1().Events("").List(ctx, metav1.ListOptions{
@@ -1892,42 +1987,12 @@ func (c *Client) checkBlockingConstraints(ctx context.Context, disruption *NodeD
 				pdbKey := fmt.Sprintf("%s/%s", pdb.Namespace, pdb.Name)
 
 				// Check if PDB would block eviction
-				// A PDB blocks eviction when DisruptionsAllowed is 0 and we're at or below the desired healthy threshold
-				isBlocking := pdb.Status.DisruptionsAllowed == 0 && pdb.Status.CurrentHealthy <= pdb.Status.DesiredHealthy
+				isBlocking := pdbBlocksEviction(&pdb)
 
 				if isBlocking {
 					// Initialize PDB details if not already tracked
 					if _, exists := pdbDetailsMap[pdbKey]; !exists {
-						pdbDetails := &PDBBlockingInfo{
-							PDBName:            pdbKey,
-							Namespace:          pdb.Namespace,
-							Name:               pdb.Name,
-							BlockingPods:       []string{},
-							CurrentHealthy:     pdb.Status.CurrentHealthy,
-							DesiredHealthy:     pdb.Status.DesiredHealthy,
-							DisruptionsAllowed: pdb.Status.DisruptionsAllowed,
-						}
-
-						// Extract minAvailable or maxUnavailable from spec
-						// These can be either int values or percentage strings (e.g., "50%")
-						if pdb.Spec.MinAvailable != nil {
-							switch pdb.Spec.MinAvailable.Type {
-							case intstr.Int:
-								pdbDetails.MinAvailable = fmt.Sprintf("%d", pdb.Spec.MinAvailable.IntVal)
-							case intstr.String:
-								pdbDetails.MinAvailable = pdb.Spec.MinAvailable.StrVal
-							}
-						}
-						if pdb.Spec.MaxUnavailable != nil {
-							switch pdb.Spec.MaxUnavailable.Type {
-							case intstr.Int:
-								pdbDetails.MaxUnavailable = fmt.Sprintf("%d", pdb.Spec.MaxUnavailable.IntVal)
-							case intstr.String:
-								pdbDetails.MaxUnavailable = pdb.Spec.MaxUnavailable.StrVal
-							}
-						}
-
-						pdbDetailsMap[pdbKey] = pdbDetails
+						pdbDetailsMap[pdbKey] = newPDBBlockingInfo(&pdb)
 						blockingPDBs = append(blockingPDBs, pdbKey)
 					}
 
@@ -2173,8 +2238,9 @@ func (c *Client) GetRecentNodeDeletions(ctx context.Context, sinceHours int) ([]
 	// Filter for actual deletions/terminations
 	var deletions []NodeDisruptionInfo
 	for _, disruption := range disruptions {
-		// Check if this is a deletion event
-		if strings.Contains(strings.ToLower(disruption.Reason), "terminat") ||
+		// Check if this is a deletion event (recorded entries know when the node was removed)
+		if disruption.DeletedTime != "" ||
+			strings.Contains(strings.ToLower(disruption.Reason), "terminat") ||
 			strings.Contains(strings.ToLower(disruption.Reason), "delet") ||
 			strings.Contains(strings.ToLower(disruption.Message), "terminat") ||
 			strings.Contains(strings.ToLower(disruption.Message), "delet") ||
@@ -2242,6 +2308,57 @@ func (c *Client) FindKarpenterPods(ctx context.Context) ([]KarpenterPodInfo, err
 	return karpenterPods, nil
 }
 
+// KarpenterPodLogs holds raw log lines from one Karpenter pod
+type KarpenterPodLogs struct {
+	Pod       string   `json:"pod"`
+	Namespace string   `json:"namespace"`
+	Lines     []string `json:"lines"`
+	Error     string   `json:"error,omitempty"` // Set when this pod's logs could not be read
+}
+
+// GetAllKarpenterLogs gets logs from every Karpenter pod (leader and standby replicas) written within the last `since`.
+// A failure reading one pod is recorded on its entry instead of failing the whole call.
+func (c *Client) GetAllKarpenterLogs(ctx context.Context, since time.Duration) ([]KarpenterPodLogs, error) {
+	pods, err := c.FindKarpenterPods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var sinceSeconds *int64
+	if since > 0 {
+		seconds := int64(since.Seconds())
+		sinceSeconds = &seconds
+	}
+
+	results := make([]KarpenterPodLogs, 0, len(pods))
+	for _, pod := range pods {
+		podLogs := KarpenterPodLogs{Pod: pod.Name, Namespace: pod.Namespace, Lines: []string{}}
+
+		stream, err := c.clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			SinceSeconds: sinceSeconds,
+		}).Stream(ctx)
+		if err != nil {
+			podLogs.Error = fmt.Sprintf("failed to get log stream: %v", err)
+			results = append(results, podLogs)
+			continue
+		}
+
+		scanner := bufio.NewScanner(stream)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024) // Karpenter error lines with many causes can exceed 64KB
+		for scanner.Scan() {
+			podLogs.Lines = append(podLogs.Lines, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil && err != io.EOF {
+			podLogs.Error = fmt.Sprintf("failed to read log stream: %v", err)
+		}
+		_ = stream.Close() // Ignore close errors
+
+		results = append(results, podLogs)
+	}
+
+	return results, nil
+}
+
 // GetKarpenterLogs gets logs from a Karpenter pod, optionally filtered for ERROR level
 func (c *Client) GetKarpenterLogs(ctx context.Context, namespace, podName string, errorOnly bool, tailLines *int64) ([]string, error) {
 	// Get pod logs
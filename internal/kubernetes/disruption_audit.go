@@ -0,0 +1,277 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Karpenter annotations that opt pods and nodes out of voluntary disruption
+const (
+	AnnotationDoNotDisrupt = "karpenter.sh/do-not-disrupt"
+	// Pre-v1beta1 equivalents, still honored by older Karpenter versions
+	annotationDoNotEvict       = "karpenter.sh/do-not-evict"       // Pods
+	annotationDoNotConsolidate = "karpenter.sh/do-not-consolidate" // Nodes
+)
+
+// PDBAuditFinding is a PDB that allows no disruptions even when every pod it covers is healthy
+type PDBAuditFinding struct {
+	PDBBlockingInfo
+	ExpectedPods  int32    `json:"expectedPods"`
+	SingleReplica bool     `json:"singleReplica"`          // One replica guarded by minAvailable 1 (or 100% / maxUnavailable 0)
+	Workload      string   `json:"workload,omitempty"`     // namespace/name of the owning workload
+	WorkloadType  string   `json:"workloadType,omitempty"` // deployment, statefulset, ...
+	Nodes         []string `json:"nodes"`                  // Nodes running the covered pods
+	Reason        string   `json:"reason"`
+}
+
+// DoNotDisruptFinding is a pod or node annotated to opt out of Karpenter disruption
+type DoNotDisruptFinding struct {
+	Kind       string `json:"kind"` // Pod or Node
+	Name       string `json:"name"` // namespace/name for pods
+	Node       string `json:"node"`
+	NodePool   string `json:"nodePool,omitempty"`
+	Annotation string `json:"annotation"`
+}
+
+// BlockedNode is a Karpenter node that cannot be voluntarily disrupted (consolidated, drifted or expired gracefully)
+type BlockedNode struct {
+	Name         string   `json:"name"`
+	NodePool     string   `json:"nodePool"`
+	InstanceType string   `json:"instanceType"`
+	CapacityType string   `json:"capacityType"`
+	CPU          float64  `json:"cpu"`       // Allocatable cores
+	MemoryGiB    float64  `json:"memoryGiB"` // Allocatable memory
+	Reasons      []string `json:"reasons"`
+	BlockingPods []string `json:"blockingPods,omitempty"`
+	BlockingPDBs []string `json:"blockingPDBs,omitempty"`
+	HourlyCost   float64  `json:"hourlyCost"` // Filled by the caller from pricing
+}
+
+// DisruptionAudit lists everything that currently prevents Karpenter from consolidating nodes
+type DisruptionAudit struct {
+	PDBs                   []PDBAuditFinding     `json:"pdbs"`
+	SingleReplicaWorkloads []PDBAuditFinding     `json:"singleReplicaWorkloads"`
+	DoNotDisruptPods       []DoNotDisruptFinding `json:"doNotDisruptPods"`
+	DoNotDisruptNodes      []DoNotDisruptFinding `json:"doNotDisruptNodes"`
+	BlockedNodes           []BlockedNode         `json:"blockedNodes"`
+	BlockedCPU             float64               `json:"blockedCPU"`
+	BlockedMemoryGiB       float64               `json:"blockedMemoryGiB"`
+}
+
+// AuditDisruptionBlockers finds PDBs that allow no disruptions in steady state, single-replica
+// workloads guarded by minAvailable 1, and do-not-disrupt pods and nodes, then reports which
+// Karpenter nodes they make impossible to consolidate. Unlike checkBlockingConstraints it runs
+// before any disruption is attempted.
+func (c *Client) AuditDisruptionBlockers(ctx context.Context) (*DisruptionAudit, error) {
+	nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	pods, err := c.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	var pdbs []policyv1.PodDisruptionBudget
+	if pdbList, err := c.clientset.PolicyV1().PodDisruptionBudgets("").List(ctx, metav1.ListOptions{}); err == nil {
+		pdbs = pdbList.Items
+	} else {
+		// PDB API might not be available, continue with annotation checks
+		c.debugLog("Debug: Failed to list PDBs for disruption audit: %v\n", err)
+	}
+
+	nodePoolOf := make(map[string]string, len(nodes.Items))
+	for i := range nodes.Items {
+		nodePoolOf[nodes.Items[i].Name] = nodes.Items[i].Labels["karpenter.sh/nodepool"]
+	}
+
+	audit := &DisruptionAudit{
+		PDBs:                   []PDBAuditFinding{},
+		SingleReplicaWorkloads: []PDBAuditFinding{},
+		DoNotDisruptPods:       []DoNotDisruptFinding{},
+		DoNotDisruptNodes:      []DoNotDisruptFinding{},
+		BlockedNodes:           []BlockedNode{},
+	}
+	blocked := make(map[string]*BlockedNode)
+	block := func(nodeName, reason string) *BlockedNode {
+		if nodePoolOf[nodeName] == "" {
+			// Only Karpenter-managed nodes can be consolidated
+			return nil
+		}
+		b, ok := blocked[nodeName]
+		if !ok {
+			b = &BlockedNode{Name: nodeName, NodePool: nodePoolOf[nodeName]}
+			blocked[nodeName] = b
+		}
+		if !contains(b.Reasons, reason) {
+			b.Reasons = append(b.Reasons, reason)
+		}
+		return b
+	}
+
+	// Running pods are what Karpenter must evict
+	var running []*corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		running = append(running, pod)
+
+		for _, key := range []string{AnnotationDoNotDisrupt, annotationDoNotEvict} {
+			if pod.Annotations[key] != "true" {
+				continue
+			}
+			podKey := fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+			audit.DoNotDisruptPods = append(audit.DoNotDisruptPods, DoNotDisruptFinding{
+				Kind: "Pod", Name: podKey, Node: pod.Spec.NodeName, NodePool: nodePoolOf[pod.Spec.NodeName], Annotation: key,
+			})
+			if b := block(pod.Spec.NodeName, "pod annotated "+key); b != nil && !contains(b.BlockingPods, podKey) {
+				b.BlockingPods = append(b.BlockingPods, podKey)
+			}
+			break
+		}
+	}
+
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		for _, key := range []string{AnnotationDoNotDisrupt, annotationDoNotConsolidate} {
+			if node.Annotations[key] != "true" {
+				continue
+			}
+			audit.DoNotDisruptNodes = append(audit.DoNotDisruptNodes, DoNotDisruptFinding{
+				Kind: "Node", Name: node.Name, Node: node.Name, NodePool: nodePoolOf[node.Name], Annotation: key,
+			})
+			block(node.Name, "node annotated "+key)
+			break
+		}
+	}
+
+	for i := range pdbs {
+		pdb := &pdbs[i]
+		finding, ok := auditPDB(pdb, running)
+		if !ok {
+			continue
+		}
+		audit.PDBs = append(audit.PDBs, finding)
+		if finding.SingleReplica {
+			audit.SingleReplicaWorkloads = append(audit.SingleReplicaWorkloads, finding)
+		}
+		for _, podKey := range finding.BlockingPods {
+			for _, pod := range running {
+				if fmt.Sprintf("%s/%s", pod.Namespace, pod.Name) != podKey {
+					continue
+				}
+				if b := block(pod.Spec.NodeName, "PDB "+finding.PDBName+" allows 0 disruptions"); b != nil {
+					if !contains(b.BlockingPDBs, finding.PDBName) {
+						b.BlockingPDBs = append(b.BlockingPDBs, finding.PDBName)
+					}
+					if !contains(b.BlockingPods, podKey) {
+						b.BlockingPods = append(b.BlockingPods, podKey)
+					}
+				}
+			}
+		}
+	}
+
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		b, ok := blocked[node.Name]
+		if !ok {
+			continue
+		}
+		b.InstanceType = node.Labels["node.kubernetes.io/instance-type"]
+		b.CapacityType = node.Labels["karpenter.sh/capacity-type"]
+		if cpu, ok := node.Status.Allocatable[corev1.ResourceCPU]; ok {
+			b.CPU = float64(cpu.MilliValue()) / 1000.0
+		}
+		if mem, ok := node.Status.Allocatable[corev1.ResourceMemory]; ok {
+			b.MemoryGiB = float64(mem.Value()) / (1024.0 * 1024.0 * 1024.0)
+		}
+		audit.BlockedCPU += b.CPU
+		audit.BlockedMemoryGiB += b.MemoryGiB
+		audit.BlockedNodes = append(audit.BlockedNodes, *b)
+	}
+
+	sort.Slice(audit.PDBs, func(i, j int) bool { return audit.PDBs[i].PDBName < audit.PDBs[j].PDBName })
+	sort.Slice(audit.SingleReplicaWorkloads, func(i, j int) bool {
+		return audit.SingleReplicaWorkloads[i].PDBName < audit.SingleReplicaWorkloads[j].PDBName
+	})
+	sort.Slice(audit.DoNotDisruptPods, func(i, j int) bool { return audit.DoNotDisruptPods[i].Name < audit.DoNotDisruptPods[j].Name })
+	sort.Slice(audit.DoNotDisruptNodes, func(i, j int) bool { return audit.DoNotDisruptNodes[i].Name < audit.DoNotDisruptNodes[j].Name })
+	sort.Slice(audit.BlockedNodes, func(i, j int) bool { return audit.BlockedNodes[i].Name < audit.BlockedNodes[j].Name })
+
+	return audit, nil
+}
+
+// auditPDB reports a PDB that allows no disruptions while every pod it expects is healthy.
+// A PDB at zero because pods are unhealthy is transient and is left to checkBlockingConstraints.
+func auditPDB(pdb *policyv1.PodDisruptionBudget, pods []*corev1.Pod) (PDBAuditFinding, bool) {
+	if pdb.Status.DisruptionsAllowed != 0 || pdb.Status.ExpectedPods == 0 || pdb.Status.CurrentHealthy < pdb.Status.ExpectedPods {
+		return PDBAuditFinding{}, false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil {
+		return PDBAuditFinding{}, false
+	}
+
+	finding := PDBAuditFinding{
+		PDBBlockingInfo: *newPDBBlockingInfo(pdb),
+		ExpectedPods:    pdb.Status.ExpectedPods,
+		Nodes:           []string{},
+	}
+	for _, pod := range pods {
+		if pod.Namespace != pdb.Namespace || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		finding.BlockingPods = append(finding.BlockingPods, fmt.Sprintf("%s/%s", pod.Namespace, pod.Name))
+		if !contains(finding.Nodes, pod.Spec.NodeName) {
+			finding.Nodes = append(finding.Nodes, pod.Spec.NodeName)
+		}
+		if finding.Workload == "" {
+			if name, kind := podWorkload(pod); kind != "" {
+				finding.Workload = pod.Namespace + "/" + name
+				finding.WorkloadType = kind
+			}
+		}
+	}
+
+	fullyGuarded := (pdb.Spec.MinAvailable != nil && (pdb.Spec.MinAvailable.Type == intstr.Int && pdb.Spec.MinAvailable.IntVal >= pdb.Status.ExpectedPods ||
+		pdb.Spec.MinAvailable.Type == intstr.String && pdb.Spec.MinAvailable.StrVal == "100%")) ||
+		(pdb.Spec.MaxUnavailable != nil && (pdb.Spec.MaxUnavailable.Type == intstr.Int && pdb.Spec.MaxUnavailable.IntVal == 0 ||
+			pdb.Spec.MaxUnavailable.Type == intstr.String && strings.TrimSuffix(pdb.Spec.MaxUnavailable.StrVal, "%") == "0"))
+	finding.SingleReplica = pdb.Status.ExpectedPods == 1 && fullyGuarded
+
+	switch {
+	case finding.SingleReplica:
+		finding.Reason = "Single replica with minAvailable 1: the only pod can never be evicted. Scale to 2+ replicas or use maxUnavailable 1."
+	case fullyGuarded:
+		finding.Reason = fmt.Sprintf("Budget requires all %d pods to stay available; use maxUnavailable 1 or a lower minAvailable.", pdb.Status.ExpectedPods)
+	default:
+		finding.Reason = fmt.Sprintf("0 disruptions allowed with all %d pods healthy; the budget leaves no room for a single eviction.", pdb.Status.ExpectedPods)
+	}
+	return finding, true
+}
+
+// podWorkload returns the controlling workload of a pod (ReplicaSets resolve to their Deployment)
+func podWorkload(pod *corev1.Pod) (string, string) {
+	for _, owner := range pod.OwnerReferences {
+		switch owner.Kind {
+		case "ReplicaSet":
+			if idx := strings.LastIndex(owner.Name, "-"); idx > 0 {
+				return owner.Name[:idx], "deployment"
+			}
+			return owner.Name, "replicaset"
+		case "StatefulSet", "DaemonSet", "Job":
+			return owner.Name, strings.ToLower(owner.Kind)
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,100 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAuditDisruptionBlockers(t *testing.T) {
+	node := func(name, nodePool string, annotations map[string]string) *corev1.Node {
+		labels := map[string]string{"node.kubernetes.io/instance-type": "m5.large"}
+		if nodePool != "" {
+			labels["karpenter.sh/nodepool"] = nodePool
+		}
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels, Annotations: annotations},
+			Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("2"),
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+			}},
+		}
+	}
+	pod := func(name, nodeName, app string, annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: "default", Labels: map[string]string{"app": app}, Annotations: annotations,
+				OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: app + "-5d8f7c"}},
+			},
+			Spec:   corev1.PodSpec{NodeName: nodeName},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	pdb := func(name, app string, minAvailable intstr.IntOrString, expected, healthy int32) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable: &minAvailable,
+				Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+			},
+			Status: policyv1.PodDisruptionBudgetStatus{ExpectedPods: expected, CurrentHealthy: healthy, DesiredHealthy: minAvailable.IntVal},
+		}
+	}
+	doNotDisrupt := map[string]string{AnnotationDoNotDisrupt: "true"}
+
+	c := &Client{clientset: fake.NewSimpleClientset(
+		node("node-a", "general", nil),
+		node("node-b", "general", nil),
+		node("node-c", "batch", doNotDisrupt),
+		node("node-d", "general", nil),
+		node("unmanaged", "", nil),
+		pod("db-0", "node-a", "db", nil),
+		pod("api-1", "node-b", "api", nil),
+		pod("api-2", "node-d", "api", nil),
+		pod("train-0", "node-b", "train", doNotDisrupt),
+		pod("cache-0", "node-d", "cache", nil),
+		pod("agent-0", "unmanaged", "agent", doNotDisrupt),
+		pdb("db", "db", intstr.FromInt(1), 1, 1),           // Single replica, minAvailable 1
+		pdb("api", "api", intstr.FromString("100%"), 2, 2), // Every replica must stay up
+		pdb("cache", "cache", intstr.FromInt(1), 1, 0),     // Pod unhealthy: transient, not flagged
+	)}
+
+	audit, err := c.AuditDisruptionBlockers(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, audit.PDBs, 2)
+	assert.Equal(t, "default/api", audit.PDBs[0].PDBName)
+	assert.False(t, audit.PDBs[0].SingleReplica)
+	assert.ElementsMatch(t, []string{"node-b", "node-d"}, audit.PDBs[0].Nodes)
+	require.Len(t, audit.SingleReplicaWorkloads, 1)
+	assert.Equal(t, "default/db", audit.SingleReplicaWorkloads[0].PDBName)
+	assert.Equal(t, "default/db", audit.SingleReplicaWorkloads[0].Workload)
+	assert.Equal(t, "deployment", audit.SingleReplicaWorkloads[0].WorkloadType)
+
+	require.Len(t, audit.DoNotDisruptPods, 2)
+	assert.Equal(t, "default/agent-0", audit.DoNotDisruptPods[0].Name)
+	assert.Equal(t, "default/train-0", audit.DoNotDisruptPods[1].Name)
+	require.Len(t, audit.DoNotDisruptNodes, 1)
+	assert.Equal(t, "node-c", audit.DoNotDisruptNodes[0].Name)
+
+	blocked := make(map[string]BlockedNode)
+	for _, b := range audit.BlockedNodes {
+		blocked[b.Name] = b
+	}
+	assert.Len(t, blocked, 4, "unmanaged nodes are never consolidated by Karpenter")
+	assert.Equal(t, []string{"default/db"}, blocked["node-a"].BlockingPDBs)
+	assert.ElementsMatch(t, []string{"default/api-1", "default/train-0"}, blocked["node-b"].BlockingPods)
+	assert.Len(t, blocked["node-b"].Reasons, 2)
+	assert.Equal(t, "batch", blocked["node-c"].NodePool)
+	assert.Equal(t, []string{"default/api"}, blocked["node-d"].BlockingPDBs)
+	assert.InDelta(t, 8, audit.BlockedCPU, 0.001)
+	assert.InDelta(t, 32, audit.BlockedMemoryGiB, 0.001)
+}
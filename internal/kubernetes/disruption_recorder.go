@@ -0,0 +1,548 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Normalized disruption reasons
+const (
+	DisruptionReasonConsolidation    = "Consolidation"
+	DisruptionReasonDrift            = "Drift"
+	DisruptionReasonExpiration       = "Expiration"
+	DisruptionReasonSpotInterruption = "SpotInterruption"
+	DisruptionReasonOther            = "Other" // Deleted for a reason none of the above matches
+)
+
+// Karpenter taints disruption candidates before draining them (v1: karpenter.sh/disrupted, v1beta1: karpenter.sh/disruption=disrupting)
+var karpenterDisruptionTaintKeys = []string{"karpenter.sh/disrupted", "karpenter.sh/disruption"}
+
+// Event reasons that mean Karpenter started disrupting a node
+var disruptionStartReasons = []string{
+	"DisruptionTerminating", "Disrupting", "Terminating", "SpotInterrupted", "SpotRebalanceRecommendation",
+	"InstanceTerminating", "InstanceStopping", "Expired", "Drifted",
+}
+
+// Event reasons that mean eviction/termination is blocked
+var disruptionBlockedReasons = []string{"FailedDraining", "DisruptionBlocked", "FailedEviction"}
+
+const (
+	// disruptionEventTTL bounds the events the recorder applies and remembers. The API server
+	// keeps events for an hour by default (--event-ttl), so older ones are not redelivered.
+	disruptionEventTTL = 6 * time.Hour
+	// disruptionFlushInterval batches store writes; a burst of events is written once
+	disruptionFlushInterval = 30 * time.Second
+)
+
+// ClassifyDisruptionReason maps a disruption's reason and message (Karpenter annotations,
+// NodeClaim conditions, event reasons and messages, or a recorded reason) to Consolidation,
+// Drift, Expiration or SpotInterruption, or Other
+func ClassifyDisruptionReason(reason, message string) string {
+	lower := strings.ToLower(reason + " " + message)
+	switch {
+	case strings.Contains(lower, "underutilized"), strings.Contains(lower, "empty"), strings.Contains(lower, "consolidat"):
+		return DisruptionReasonConsolidation
+	case strings.Contains(lower, "drift"):
+		return DisruptionReasonDrift
+	case strings.Contains(lower, "expir"):
+		return DisruptionReasonExpiration
+	case strings.Contains(lower, "interrupt"), strings.Contains(lower, "rebalance"),
+		strings.Contains(lower, "instanceterminating"), strings.Contains(lower, "instancestopping"):
+		return DisruptionReasonSpotInterruption
+	}
+	return DisruptionReasonOther
+}
+
+// normalizeDisruptionReason classifies a single text, returning "" rather than Other so
+// callers can keep a reason they already know
+func normalizeDisruptionReason(text string) string {
+	if reason := ClassifyDisruptionReason(text, ""); reason != DisruptionReasonOther {
+		return reason
+	}
+	return ""
+}
+
+// DisruptionRecorder watches Nodes, NodeClaims and Karpenter events and persists every
+// disruption to a DisruptionStore
+type DisruptionRecorder struct {
+	client *Client
+	store  *DisruptionStore
+
+	mu             sync.Mutex
+	nodeClaimNodes map[string]nodeClaimRef // NodeClaim name -> Node
+	seenEvents     map[string]seenEvent    // Event UID -> occurrences already applied
+	nodeLister     func(name string) (*corev1.Node, bool)
+}
+
+// nodeClaimRef is the Node of a NodeClaim, kept after deletion for its late events
+type nodeClaimRef struct {
+	nodeName  string
+	deletedAt time.Time
+}
+
+// seenEvent is how many occurrences of an event were applied, and when it last occurred
+type seenEvent struct {
+	count    int32
+	lastSeen time.Time
+}
+
+// NewDisruptionRecorder creates a recorder and makes the client read history from the store
+func NewDisruptionRecorder(c *Client, store *DisruptionStore) *DisruptionRecorder {
+	c.disruptionStore = store
+	return &DisruptionRecorder{
+		client:         c,
+		store:          store,
+		nodeClaimNodes: make(map[string]nodeClaimRef),
+		seenEvents:     make(map[string]seenEvent),
+		nodeLister:     func(string) (*corev1.Node, bool) { return nil, false },
+	}
+}
+
+// Run watches the cluster until ctx is cancelled
+func (r *DisruptionRecorder) Run(ctx context.Context) error {
+	factory := informers.NewSharedInformerFactory(r.client.clientset, 0)
+
+	nodeInformer := factory.Core().V1().Nodes().Informer()
+	if _, err := nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if node, ok := obj.(*corev1.Node); ok {
+				r.observeNode(ctx, node)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if node, ok := obj.(*corev1.Node); ok {
+				r.observeNode(ctx, node)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if node, ok := obj.(*corev1.Node); ok {
+				r.nodeDeleted(node, time.Now())
+			}
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to watch nodes: %w", err)
+	}
+	nodeStore := nodeInformer.GetStore()
+	r.nodeLister = func(name string) (*corev1.Node, bool) {
+		obj, exists, err := nodeStore.GetByKey(name)
+		if err != nil || !exists {
+			return nil, false
+		}
+		node, ok := obj.(*corev1.Node)
+		return node, ok
+	}
+
+	eventHandler := func(obj interface{}) {
+		if event, ok := obj.(*corev1.Event); ok {
+			r.observeEvent(ctx, event)
+		}
+	}
+	if _, err := factory.Core().V1().Events().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    eventHandler,
+		UpdateFunc: func(_, obj interface{}) { eventHandler(obj) },
+	}); err != nil {
+		return fmt.Errorf("failed to watch events: %w", err)
+	}
+
+	// NodeClaims are optional: older Karpenter versions and restricted RBAC still get Node/event history
+	var dynamicFactory dynamicinformer.DynamicSharedInformerFactory
+	if r.client.dynamicClient != nil {
+		if gvr, err := r.client.discoverKarpenterResource(ctx, "nodeclaims", "nodeclaim"); err == nil {
+			dynamicFactory = dynamicinformer.NewDynamicSharedInformerFactory(r.client.dynamicClient, 0)
+			nodeClaimHandler := func(obj interface{}) {
+				if nodeClaim, ok := obj.(*unstructured.Unstructured); ok {
+					r.observeNodeClaim(ctx, nodeClaim)
+				}
+			}
+			if _, err := dynamicFactory.ForResource(gvr).Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+				AddFunc:    nodeClaimHandler,
+				UpdateFunc: func(_, obj interface{}) { nodeClaimHandler(obj) },
+				DeleteFunc: func(obj interface{}) {
+					if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+						obj = tombstone.Obj
+					}
+					if nodeClaim, ok := obj.(*unstructured.Unstructured); ok {
+						r.nodeClaimDeleted(nodeClaim.GetName(), time.Now())
+					}
+				},
+			}); err != nil {
+				return fmt.Errorf("failed to watch nodeclaims: %w", err)
+			}
+		} else {
+			r.client.debugLog("Debug: NodeClaims not available for disruption recording: %v\n", err)
+		}
+	}
+
+	factory.Start(ctx.Done())
+	if dynamicFactory != nil {
+		dynamicFactory.Start(ctx.Done())
+		dynamicFactory.WaitForCacheSync(ctx.Done())
+	}
+	for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced && ctx.Err() == nil {
+			return fmt.Errorf("failed to sync %v informer", informerType)
+		}
+	}
+
+	r.reconcile(time.Now())
+	r.flush()
+
+	ticker := time.NewTicker(disruptionFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			factory.Shutdown()
+			if dynamicFactory != nil {
+				dynamicFactory.Shutdown()
+			}
+			r.flush()
+			return nil
+		case now := <-ticker.C:
+			r.prune(now)
+			r.flush()
+		}
+	}
+}
+
+// prune forgets events too old to be redelivered and NodeClaims deleted before them
+func (r *DisruptionRecorder) prune(now time.Time) {
+	cutoff := now.Add(-disruptionEventTTL)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for uid, seen := range r.seenEvents {
+		if seen.lastSeen.Before(cutoff) {
+			delete(r.seenEvents, uid)
+		}
+	}
+	for name, ref := range r.nodeClaimNodes {
+		if !ref.deletedAt.IsZero() && ref.deletedAt.Before(cutoff) {
+			delete(r.nodeClaimNodes, name)
+		}
+	}
+}
+
+// flush writes pending store changes, logging rather than failing the watch on I/O errors
+func (r *DisruptionRecorder) flush() {
+	if err := r.store.Flush(); err != nil {
+		fmt.Printf("Warning: Failed to persist disruptions: %v\n", err)
+	}
+}
+
+// reconcile closes records for nodes that were deleted while the recorder was not running
+func (r *DisruptionRecorder) reconcile(now time.Time) {
+	for _, record := range r.store.List(time.Time{}) {
+		if !record.NodeStillExists {
+			continue
+		}
+		if _, exists := r.nodeLister(record.NodeName); exists {
+			continue
+		}
+		// Deletion time is unknown; the last observation is the best lower bound
+		deletedAt := parseTime(record.LastSeen)
+		if deletedAt.IsZero() {
+			deletedAt = now
+		}
+		r.update(record.NodeName, record.CreationTime, func(rec *NodeDisruptionInfo) {
+			completeDisruption(rec, deletedAt)
+		})
+	}
+}
+
+// isDisrupting reports whether Karpenter is disrupting the node
+func isDisrupting(node *corev1.Node) bool {
+	if node.DeletionTimestamp != nil {
+		return true
+	}
+	for _, taint := range node.Spec.Taints {
+		if contains(karpenterDisruptionTaintKeys, taint.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// observeNode starts a record when a Karpenter node is tainted for disruption or marked for deletion
+func (r *DisruptionRecorder) observeNode(ctx context.Context, node *corev1.Node) {
+	if _, ok := node.Labels["karpenter.sh/nodepool"]; !ok || !isDisrupting(node) {
+		return
+	}
+
+	creation := node.CreationTimestamp.Format(time.RFC3339)
+	reason := nodeDisruptionReason(node)
+	if existing, ok := r.store.Get(node.Name); ok && existing.NodeStillExists && existing.CreationTime == creation {
+		// Already recording; only the deletion time or a more specific reason is new
+		if (existing.DeletionTime != "" || node.DeletionTimestamp == nil) && (reason == "" || existing.Reason == reason) {
+			return
+		}
+	}
+
+	// Capture pods before they are evicted
+	var pods []PodInfo
+	if podInfos, err := r.client.getPodsOnNode(ctx, node.Name); err == nil {
+		for _, pod := range podInfos {
+			if pod.NodeName == node.Name {
+				pods = append(pods, pod)
+			}
+		}
+	}
+
+	r.update(node.Name, creation, func(record *NodeDisruptionInfo) {
+		fillNodeDetails(record, node)
+		now := time.Now().Format(time.RFC3339)
+		if record.FirstSeen == "" {
+			record.FirstSeen = now
+			record.EventCount = 0
+		}
+		record.LastSeen = now
+		if reason != "" {
+			record.Reason = reason
+		} else if record.Reason == "" {
+			record.Reason = "Disrupting"
+		}
+		if node.DeletionTimestamp != nil && record.DeletionTime == "" {
+			record.DeletionTime = node.DeletionTimestamp.Format(time.RFC3339)
+		}
+		if len(record.AffectedPods) == 0 {
+			record.AffectedPods = pods
+		}
+	})
+}
+
+// nodeDisruptionReason reads the reason from Karpenter annotations and taint values
+func nodeDisruptionReason(node *corev1.Node) string {
+	for _, key := range []string{"karpenter.sh/disruption", "karpenter.sh/disruption-reason"} {
+		if value, ok := node.Annotations[key]; ok {
+			if reason := normalizeDisruptionReason(value); reason != "" {
+				return reason
+			}
+		}
+	}
+	return ""
+}
+
+// fillNodeDetails copies identifying node fields onto a record
+func fillNodeDetails(record *NodeDisruptionInfo, node *corev1.Node) {
+	record.NodeName = node.Name
+	record.NodePool = node.Labels["karpenter.sh/nodepool"]
+	record.InstanceType = node.Labels["node.kubernetes.io/instance-type"]
+	record.CapacityType = node.Labels["karpenter.sh/capacity-type"]
+	record.CreationTime = node.CreationTimestamp.Format(time.RFC3339)
+	if cpu, ok := node.Status.Capacity[corev1.ResourceCPU]; ok {
+		record.ResourceCapacity.CPU = cpu.String()
+	}
+	if memory, ok := node.Status.Capacity[corev1.ResourceMemory]; ok {
+		record.ResourceCapacity.Memory = memory.String()
+	}
+}
+
+// observeEvent applies Karpenter disruption events for Nodes and NodeClaims
+func (r *DisruptionRecorder) observeEvent(ctx context.Context, event *corev1.Event) {
+	nodeName := ""
+	switch event.InvolvedObject.Kind {
+	case "Node":
+		nodeName = event.InvolvedObject.Name
+	case "NodeClaim":
+		r.mu.Lock()
+		nodeName = r.nodeClaimNodes[event.InvolvedObject.Name].nodeName
+		r.mu.Unlock()
+	}
+	if nodeName == "" {
+		return
+	}
+
+	isStart := contains(disruptionStartReasons, event.Reason)
+	isBlocked := contains(disruptionBlockedReasons, event.Reason)
+	if !isStart && !isBlocked {
+		return
+	}
+
+	seenAt := eventTime(*event)
+	if seenAt.IsZero() {
+		seenAt = time.Now()
+	}
+	if seenAt.Before(time.Now().Add(-disruptionEventTTL)) {
+		// Forgotten by prune, so it could be applied twice
+		return
+	}
+
+	// Informers redeliver events on every count bump; apply each occurrence once
+	count := event.Count
+	if count == 0 {
+		count = 1
+	}
+	r.mu.Lock()
+	applied := r.seenEvents[string(event.UID)].count
+	if count <= applied {
+		r.mu.Unlock()
+		return
+	}
+	r.seenEvents[string(event.UID)] = seenEvent{count: count, lastSeen: seenAt}
+	r.mu.Unlock()
+
+	existing, ok := r.store.Get(nodeName)
+	open := ok && existing.NodeStillExists
+	if !open && !isStart {
+		// Blocked disruption on a node we are not tracking (e.g., DisruptionBlocked by do-not-disrupt): not a disruption
+		return
+	}
+
+	node, nodeExists := r.nodeLister(nodeName)
+	creation := existing.CreationTime
+	if nodeExists {
+		creation = node.CreationTimestamp.Format(time.RFC3339)
+	}
+	if !open && nodeExists {
+		// Capture pods while the node is still there
+		r.observeNode(ctx, node)
+	}
+
+	r.update(nodeName, creation, func(record *NodeDisruptionInfo) {
+		if nodeExists {
+			fillNodeDetails(record, node)
+		}
+		if record.FirstSeen == "" || seenAt.Before(parseTime(record.FirstSeen)) {
+			record.FirstSeen = seenAt.Format(time.RFC3339)
+		}
+		if seenAt.After(parseTime(record.LastSeen)) {
+			record.LastSeen = seenAt.Format(time.RFC3339)
+		}
+		record.EventCount += int(count - applied)
+		record.Message = event.Message
+
+		if reason := normalizeDisruptionReason(event.Reason + " " + event.Message); reason != "" {
+			record.Reason = reason
+		} else if record.Reason == "" {
+			record.Reason = event.Reason
+		}
+
+		if isBlocked {
+			record.IsBlocked = true
+			record.BlockingReason = event.Message
+			if record.BlockedSince == "" {
+				record.BlockedSince = seenAt.Format(time.RFC3339)
+			}
+		}
+		if !nodeExists && record.DeletedTime == "" && isStart {
+			// Disruption seen only through events (node already gone)
+			completeDisruption(record, seenAt)
+		}
+	})
+}
+
+// observeNodeClaim tracks NodeClaim -> Node names and records NodeClaim-driven disruptions
+func (r *DisruptionRecorder) observeNodeClaim(ctx context.Context, nodeClaim *unstructured.Unstructured) {
+	nodeName, _, _ := unstructured.NestedString(nodeClaim.Object, "status", "nodeName")
+	if nodeName == "" {
+		return
+	}
+	r.mu.Lock()
+	r.nodeClaimNodes[nodeClaim.GetName()] = nodeClaimRef{nodeName: nodeName}
+	r.mu.Unlock()
+
+	if nodeClaim.GetDeletionTimestamp() == nil {
+		return
+	}
+
+	// Drifted/Expired/Empty conditions explain why the NodeClaim is being deleted
+	reason := ""
+	conditions, _, _ := unstructured.NestedSlice(nodeClaim.Object, "status", "conditions")
+	for _, raw := range conditions {
+		condition, ok := raw.(map[string]interface{})
+		if !ok || condition["status"] != "True" {
+			continue
+		}
+		conditionType, _ := condition["type"].(string)
+		if r := normalizeDisruptionReason(conditionType); r != "" {
+			reason = r
+			break
+		}
+	}
+
+	node, exists := r.nodeLister(nodeName)
+	if !exists {
+		return
+	}
+	r.observeNode(ctx, node)
+	r.update(nodeName, node.CreationTimestamp.Format(time.RFC3339), func(record *NodeDisruptionInfo) {
+		fillNodeDetails(record, node)
+		record.NodeClaim = nodeClaim.GetName()
+		if record.FirstSeen == "" {
+			record.FirstSeen = time.Now().Format(time.RFC3339)
+			record.LastSeen = record.FirstSeen
+		}
+		if reason != "" && normalizeDisruptionReason(record.Reason) == "" {
+			record.Reason = reason
+		}
+		if record.DeletionTime == "" {
+			record.DeletionTime = nodeClaim.GetDeletionTimestamp().Format(time.RFC3339)
+		}
+	})
+}
+
+// nodeClaimDeleted keeps the NodeClaim's Node until its events expire
+func (r *DisruptionRecorder) nodeClaimDeleted(name string, deletedAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ref, ok := r.nodeClaimNodes[name]; ok {
+		ref.deletedAt = deletedAt
+		r.nodeClaimNodes[name] = ref
+	}
+}
+
+// nodeDeleted completes the record for a removed Karpenter node
+func (r *DisruptionRecorder) nodeDeleted(node *corev1.Node, deletedAt time.Time) {
+	if _, ok := node.Labels["karpenter.sh/nodepool"]; !ok {
+		return
+	}
+	r.update(node.Name, node.CreationTimestamp.Format(time.RFC3339), func(record *NodeDisruptionInfo) {
+		fillNodeDetails(record, node)
+		if record.FirstSeen == "" {
+			// Deleted without being observed as disrupting (e.g., manual delete or very fast consolidation)
+			record.FirstSeen = deletedAt.Format(time.RFC3339)
+		}
+		if record.Reason == "" {
+			if record.Reason = nodeDisruptionReason(node); record.Reason == "" {
+				record.Reason = "Deleted"
+			}
+		}
+		if record.DeletionTime == "" && node.DeletionTimestamp != nil {
+			record.DeletionTime = node.DeletionTimestamp.Format(time.RFC3339)
+		}
+		completeDisruption(record, deletedAt)
+	})
+}
+
+// completeDisruption marks a record as finished at deletedAt
+func completeDisruption(record *NodeDisruptionInfo, deletedAt time.Time) {
+	record.NodeStillExists = false
+	record.DeletedTime = deletedAt.Format(time.RFC3339)
+	if deletedAt.After(parseTime(record.LastSeen)) {
+		record.LastSeen = record.DeletedTime
+	}
+	if created := parseTime(record.CreationTime); !created.IsZero() {
+		record.LifetimeSeconds = deletedAt.Sub(created).Seconds()
+	}
+	if blockedSince := parseTime(record.BlockedSince); !blockedSince.IsZero() {
+		record.BlockedSeconds += deletedAt.Sub(blockedSince).Seconds()
+		record.BlockedSince = ""
+	}
+	record.IsBlocked = false
+}
+
+// update changes the node's record; the store is written by the periodic flush
+func (r *DisruptionRecorder) update(nodeName, creationTime string, fn func(record *NodeDisruptionInfo)) {
+	r.store.Update(nodeName, creationTime, fn)
+}
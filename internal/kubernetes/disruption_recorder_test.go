@@ -0,0 +1,153 @@
+package kubernetes
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDisruptionRecorder(t *testing.T) {
+	created := metav1.NewTime(time.Now().Add(-2 * time.Hour).Truncate(time.Second))
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "ip-10-0-1-10.ec2.internal",
+			CreationTimestamp: created,
+			Labels: map[string]string{
+				"karpenter.sh/nodepool":            "general",
+				"karpenter.sh/capacity-type":       "spot",
+				"node.kubernetes.io/instance-type": "m5.large",
+			},
+		},
+		Status: corev1.NodeStatus{Capacity: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("2"),
+			corev1.ResourceMemory: resource.MustParse("8Gi"),
+		}},
+	}
+	pod := func(name, nodeName string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+		}
+	}
+	clientset := fake.NewSimpleClientset(node, pod("web-1", node.Name), pod("web-2", "other-node"))
+	c := &Client{clientset: clientset}
+	path := filepath.Join(t.TempDir(), "disruptions.json")
+	store, err := NewDisruptionStore(path, 24*time.Hour)
+	require.NoError(t, err)
+	recorder := NewDisruptionRecorder(c, store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- recorder.Run(ctx) }()
+
+	recorded := func() NodeDisruptionInfo {
+		record, _ := store.Get(node.Name)
+		return record
+	}
+
+	// Karpenter taints the node, reports why, then fails to drain it
+	require.Eventually(t, func() bool {
+		tainted := node.DeepCopy()
+		tainted.Spec.Taints = []corev1.Taint{{Key: "karpenter.sh/disrupted", Effect: corev1.TaintEffectNoSchedule}}
+		_, err := clientset.CoreV1().Nodes().Update(ctx, tainted, metav1.UpdateOptions{})
+		return err == nil && recorded().FirstSeen != ""
+	}, 5*time.Second, 50*time.Millisecond)
+
+	blockedAt := time.Now().Add(-10 * time.Minute)
+	for _, event := range []*corev1.Event{
+		{
+			ObjectMeta:     metav1.ObjectMeta{Name: "disrupting", Namespace: "default", UID: "e1"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Node", Name: node.Name},
+			Reason:         "DisruptionTerminating",
+			Message:        "Disrupting Node: Underutilized/Delete",
+			Source:         corev1.EventSource{Component: "karpenter"},
+			LastTimestamp:  metav1.NewTime(blockedAt.Add(-time.Minute)),
+			Count:          1,
+		},
+		{
+			ObjectMeta:     metav1.ObjectMeta{Name: "failed-draining", Namespace: "default", UID: "e2"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Node", Name: node.Name},
+			Reason:         "FailedDraining",
+			Message:        "Failed to drain node, 1 pods are waiting to be evicted",
+			Source:         corev1.EventSource{Component: "karpenter"},
+			LastTimestamp:  metav1.NewTime(blockedAt),
+			Count:          1,
+		},
+	} {
+		_, err := clientset.CoreV1().Events("default").Create(ctx, event, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool { return recorded().IsBlocked }, 5*time.Second, 50*time.Millisecond)
+
+	require.NoError(t, clientset.CoreV1().Nodes().Delete(ctx, node.Name, metav1.DeleteOptions{}))
+	require.Eventually(t, func() bool { return !recorded().NodeStillExists }, 5*time.Second, 50*time.Millisecond)
+
+	record := recorded()
+	assert.Equal(t, "general", record.NodePool)
+	assert.Equal(t, "m5.large", record.InstanceType)
+	assert.Equal(t, "spot", record.CapacityType)
+	assert.Equal(t, DisruptionReasonConsolidation, record.Reason)
+	assert.Equal(t, 2, record.EventCount)
+	assert.False(t, record.IsBlocked)
+	assert.NotEmpty(t, record.DeletedTime)
+	assert.InDelta(t, 2*time.Hour.Seconds(), record.LifetimeSeconds, 60)
+	assert.InDelta(t, 10*time.Minute.Seconds(), record.BlockedSeconds, 60)
+	if assert.Len(t, record.AffectedPods, 1) {
+		assert.Equal(t, "web-1", record.AffectedPods[0].Name)
+	}
+
+	// The endpoints read the deleted node from the store
+	deletions, err := c.GetRecentNodeDeletions(ctx, 24)
+	require.NoError(t, err)
+	if assert.Len(t, deletions, 1) {
+		assert.True(t, deletions[0].Recorded)
+	}
+
+	// Pending changes are written when the recorder stops
+	cancel()
+	assert.NoError(t, <-done)
+	reloaded, err := NewDisruptionStore(path, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, store.List(time.Time{}), reloaded.List(time.Time{}))
+}
+
+func TestDisruptionRecorderPrune(t *testing.T) {
+	store, err := NewDisruptionStore("", 24*time.Hour)
+	require.NoError(t, err)
+	recorder := NewDisruptionRecorder(&Client{clientset: fake.NewSimpleClientset()}, store)
+	now := time.Now()
+
+	recorder.seenEvents["old"] = seenEvent{count: 3, lastSeen: now.Add(-disruptionEventTTL - time.Minute)}
+	recorder.seenEvents["recent"] = seenEvent{count: 1, lastSeen: now.Add(-time.Minute)}
+	recorder.nodeClaimNodes["live"] = nodeClaimRef{nodeName: "node-a"}
+	recorder.nodeClaimNodes["deleted"] = nodeClaimRef{nodeName: "node-b"}
+	recorder.nodeClaimNodes["just-deleted"] = nodeClaimRef{nodeName: "node-c"}
+	recorder.nodeClaimDeleted("deleted", now.Add(-disruptionEventTTL-time.Minute))
+	recorder.nodeClaimDeleted("just-deleted", now)
+
+	recorder.prune(now)
+	assert.Len(t, recorder.seenEvents, 1)
+	assert.Contains(t, recorder.seenEvents, "recent")
+	assert.Len(t, recorder.nodeClaimNodes, 2)
+	assert.NotContains(t, recorder.nodeClaimNodes, "deleted")
+
+	// Events older than the TTL are ignored rather than applied after being forgotten
+	recorder.observeEvent(context.Background(), &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{UID: "stale"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Node", Name: "node-a"},
+		Reason:         "Drifted",
+		LastTimestamp:  metav1.NewTime(now.Add(-disruptionEventTTL - time.Hour)),
+	})
+	_, recorded := store.Get("node-a")
+	assert.False(t, recorded)
+	assert.NotContains(t, recorder.seenEvents, "stale")
+}
@@ -0,0 +1,200 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DisruptionStore persists node disruptions to a local JSON file so history outlives
+// Kubernetes events (about an hour) and deleted Node objects. Updates are kept in memory
+// and written in batches by Flush.
+type DisruptionStore struct {
+	mu        sync.RWMutex
+	saveMu    sync.Mutex // Serializes writes so the newest snapshot always lands last
+	path      string
+	retention time.Duration
+	records   map[string]*NodeDisruptionInfo // disruptionKey -> record
+	dirty     bool                           // Records changed since the last write
+}
+
+// NewDisruptionStore opens (or creates) a store at path. Records older than retention are
+// pruned on write; zero retention keeps everything. An empty path keeps records in memory only.
+func NewDisruptionStore(path string, retention time.Duration) (*DisruptionStore, error) {
+	store := &DisruptionStore{
+		path:      path,
+		retention: retention,
+		records:   make(map[string]*NodeDisruptionInfo),
+	}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read disruption store: %w", err)
+	}
+	var records []NodeDisruptionInfo
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse disruption store: %w", err)
+	}
+	for i := range records {
+		record := records[i]
+		store.records[disruptionKey(&record)] = &record
+	}
+	return store, nil
+}
+
+// disruptionKey identifies one disruption: node names (ip-based) can be reused by later nodes
+func disruptionKey(record *NodeDisruptionInfo) string {
+	return record.NodeName + "/" + record.CreationTime
+}
+
+// Get returns the most recent record for a node, if any
+func (s *DisruptionStore) Get(nodeName string) (NodeDisruptionInfo, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var latest *NodeDisruptionInfo
+	for _, record := range s.records {
+		if record.NodeName != nodeName {
+			continue
+		}
+		if latest == nil || parseTime(record.FirstSeen).After(parseTime(latest.FirstSeen)) {
+			latest = record
+		}
+	}
+	if latest == nil {
+		return NodeDisruptionInfo{}, false
+	}
+	return *latest, true
+}
+
+// Update applies fn to the record for the node (creating it when missing). The change is
+// written to disk by the next Flush.
+func (s *DisruptionStore) Update(nodeName, creationTime string, fn func(record *NodeDisruptionInfo)) {
+	s.mu.Lock()
+	key := nodeName + "/" + creationTime
+	record, ok := s.records[key]
+	if !ok {
+		// Events can arrive before the node's creation time is known: adopt an open record for the same node
+		for k, r := range s.records {
+			if r.NodeName == nodeName && r.NodeStillExists && (creationTime == "" || r.CreationTime == "") {
+				record, key = r, k
+				break
+			}
+		}
+	}
+	if record == nil {
+		record = &NodeDisruptionInfo{NodeName: nodeName, CreationTime: creationTime, NodeStillExists: true, Recorded: true}
+	}
+	fn(record)
+	record.Recorded = true
+	if newKey := disruptionKey(record); newKey != key {
+		delete(s.records, key)
+		key = newKey
+	}
+	s.records[key] = record
+	s.pruneLocked(time.Now())
+	s.dirty = true
+	s.mu.Unlock()
+}
+
+// List returns records last seen at or after since, most recent first
+func (s *DisruptionStore) List(since time.Time) []NodeDisruptionInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]NodeDisruptionInfo, 0, len(s.records))
+	for _, record := range s.records {
+		if !since.IsZero() && parseTime(record.LastSeen).Before(since) {
+			continue
+		}
+		records = append(records, *record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return parseTime(records[i].LastSeen).After(parseTime(records[j].LastSeen))
+	})
+	return records
+}
+
+// pruneLocked drops records older than the retention window
+func (s *DisruptionStore) pruneLocked(now time.Time) {
+	if s.retention <= 0 {
+		return
+	}
+	cutoff := now.Add(-s.retention)
+	for key, record := range s.records {
+		if !record.NodeStillExists && parseTime(record.LastSeen).Before(cutoff) {
+			delete(s.records, key)
+		}
+	}
+}
+
+// Flush writes the store if it changed since the last write. The file is replaced atomically
+// (temp file + rename) so a crash never leaves a truncated file.
+func (s *DisruptionStore) Flush() error {
+	if s.path == "" {
+		return nil
+	}
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	records := make([]NodeDisruptionInfo, 0, len(s.records))
+	for _, record := range s.records {
+		records = append(records, *record)
+	}
+	s.dirty = false
+	s.mu.Unlock()
+	sort.Slice(records, func(i, j int) bool { return records[i].FirstSeen < records[j].FirstSeen })
+
+	if err := s.write(records); err != nil {
+		// Retry with the next flush
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (s *DisruptionStore) write(records []NodeDisruptionInfo) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal disruptions: %w", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write disruptions: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write disruptions: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write disruptions: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write disruptions: %w", err)
+	}
+	return nil
+}
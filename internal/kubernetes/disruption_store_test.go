@@ -0,0 +1,104 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisruptionStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disruptions.json")
+	store, err := NewDisruptionStore(path, 24*time.Hour)
+	require.NoError(t, err)
+
+	now := time.Now()
+	created := now.Add(-3 * time.Hour).Format(time.RFC3339)
+
+	t.Run("events before creation time is known are re-keyed", func(t *testing.T) {
+		store.Update("node-a", "", func(record *NodeDisruptionInfo) {
+			record.Reason = DisruptionReasonDrift
+			record.FirstSeen = now.Add(-time.Hour).Format(time.RFC3339)
+			record.LastSeen = record.FirstSeen
+		})
+		store.Update("node-a", created, func(record *NodeDisruptionInfo) {
+			record.CreationTime = created
+			completeDisruption(record, now)
+		})
+
+		records := store.List(time.Time{})
+		require.Len(t, records, 1)
+		assert.Equal(t, DisruptionReasonDrift, records[0].Reason)
+		assert.Equal(t, created, records[0].CreationTime)
+		assert.False(t, records[0].NodeStillExists)
+		assert.True(t, records[0].Recorded)
+		assert.InDelta(t, 3*time.Hour.Seconds(), records[0].LifetimeSeconds, 1)
+	})
+
+	t.Run("a reused node name starts a new record", func(t *testing.T) {
+		store.Update("node-a", now.Format(time.RFC3339), func(record *NodeDisruptionInfo) {
+			record.FirstSeen = now.Format(time.RFC3339)
+			record.LastSeen = record.FirstSeen
+		})
+		assert.Len(t, store.List(time.Time{}), 2)
+		latest, ok := store.Get("node-a")
+		require.True(t, ok)
+		assert.True(t, latest.NodeStillExists)
+	})
+
+	t.Run("records are reloaded from disk", func(t *testing.T) {
+		_, err := os.Stat(path)
+		assert.True(t, os.IsNotExist(err), "updates are only written by Flush")
+		require.NoError(t, store.Flush())
+
+		reloaded, err := NewDisruptionStore(path, 24*time.Hour)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, store.List(time.Time{}), reloaded.List(time.Time{}))
+		assert.Len(t, reloaded.List(now.Add(-30*time.Minute)), 2)
+		assert.Empty(t, reloaded.List(now.Add(time.Hour)))
+	})
+
+	t.Run("completed records older than retention are pruned", func(t *testing.T) {
+		old := now.Add(-48 * time.Hour)
+		store.Update("node-old", old.Add(-time.Hour).Format(time.RFC3339), func(record *NodeDisruptionInfo) {
+			record.FirstSeen = old.Format(time.RFC3339)
+			completeDisruption(record, old)
+		})
+		_, ok := store.Get("node-old")
+		assert.False(t, ok)
+	})
+}
+
+func TestClassifyDisruptionReason(t *testing.T) {
+	tests := map[string]string{
+		"Consolidation":                          DisruptionReasonConsolidation,
+		"Drift":                                  DisruptionReasonDrift,
+		"Expiration":                             DisruptionReasonExpiration,
+		"SpotInterruption":                       DisruptionReasonSpotInterruption,
+		"InstanceStopping":                       DisruptionReasonSpotInterruption,
+		"SpotRebalanceRecommendation":            DisruptionReasonSpotInterruption,
+		"Disrupting Node: Underutilized/Delete":  DisruptionReasonConsolidation,
+		"Empty":                                  DisruptionReasonConsolidation,
+		"Drifted":                                DisruptionReasonDrift,
+		"Expired":                                DisruptionReasonExpiration,
+		"SpotInterrupted":                        DisruptionReasonSpotInterruption,
+		"InstanceTerminating":                    DisruptionReasonSpotInterruption,
+		"Cannot disrupt Node: pdb prevents pods": DisruptionReasonOther,
+		"Deleted":                                DisruptionReasonOther,
+	}
+	for input, want := range tests {
+		t.Run(input, func(t *testing.T) {
+			assert.Equal(t, want, ClassifyDisruptionReason(input, ""))
+			if want == DisruptionReasonOther {
+				want = ""
+			}
+			assert.Equal(t, want, normalizeDisruptionReason(input))
+		})
+	}
+
+	// The reason and message of a recorded disruption are classified together
+	assert.Equal(t, DisruptionReasonConsolidation, ClassifyDisruptionReason("DisruptionTerminating", "Disrupting Node: Empty/Delete"))
+}
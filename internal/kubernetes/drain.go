@@ -0,0 +1,411 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Drain simulation outcomes for a pod
+const (
+	DrainOutcomeReschedule = "reschedule" // Pod fits on a remaining node in the NodePool
+	DrainOutcomePending    = "pending"    // Pod fits nowhere and would go Pending (Karpenter may provision a node)
+	DrainOutcomeBlocked    = "blocked"    // Eviction is blocked by a PodDisruptionBudget
+	DrainOutcomeSkipped    = "skipped"    // DaemonSet, mirror or completed pods are not evicted
+)
+
+// DrainPodResult describes what would happen to a pod if its node were drained
+type DrainPodResult struct {
+	Name          string       `json:"name"`
+	Namespace     string       `json:"namespace"`
+	WorkloadName  string       `json:"workloadName"`
+	WorkloadType  string       `json:"workloadType"`
+	FromNode      string       `json:"fromNode"`
+	ToNode        string       `json:"toNode,omitempty"` // Target node for rescheduled pods
+	Outcome       string       `json:"outcome"`          // reschedule, pending, blocked, skipped
+	Reason        string       `json:"reason,omitempty"`
+	Requests      ResourceInfo `json:"requests,omitempty"`
+	BlockingPDBs  []string     `json:"blockingPDBs,omitempty"` // PDBs blocking eviction (namespace/name format)
+	DelayedByPDBs []string     `json:"delayedByPDBs,omitempty"`
+}
+
+// DrainNodeCapacity is the free capacity of a remaining node before and after the simulated drain
+type DrainNodeCapacity struct {
+	Name             string  `json:"name"`
+	NodePool         string  `json:"nodePool"`
+	FreeCPUBefore    float64 `json:"freeCPUBefore"`    // Cores
+	FreeMemoryBefore float64 `json:"freeMemoryBefore"` // GiB
+	FreeCPUAfter     float64 `json:"freeCPUAfter"`
+	FreeMemoryAfter  float64 `json:"freeMemoryAfter"`
+	PodsReceived     int     `json:"podsReceived"`
+}
+
+// DrainSimulation is the result of a node drain what-if analysis
+type DrainSimulation struct {
+	Nodes          []string            `json:"nodes"`
+	NodePools      []string            `json:"nodePools"`
+	Safe           bool                `json:"safe"` // True if every evictable pod reschedules and no PDB blocks
+	Rescheduled    []DrainPodResult    `json:"rescheduled"`
+	Pending        []DrainPodResult    `json:"pending"`
+	Blocked        []DrainPodResult    `json:"blocked"`
+	Skipped        []DrainPodResult    `json:"skipped"`
+	BlockingPDBs   []PDBBlockingInfo   `json:"blockingPDBs"`
+	RemainingNodes []DrainNodeCapacity `json:"remainingNodes"`
+	Summary        string              `json:"summary"`
+}
+
+// drainCandidate tracks free capacity of a node that could receive evicted pods
+type drainCandidate struct {
+	node      *corev1.Node
+	nodePool  string
+	freeCPU   float64
+	freeMem   float64
+	freePods  int
+	freeGPU   int
+	capacity  DrainNodeCapacity
+	placement int
+}
+
+// newPDBBlockingInfo builds blocking details for a PDB (pods are added by the caller)
+func newPDBBlockingInfo(pdb *policyv1.PodDisruptionBudget) *PDBBlockingInfo {
+	info := &PDBBlockingInfo{
+		PDBName:            fmt.Sprintf("%s/%s", pdb.Namespace, pdb.Name),
+		Namespace:          pdb.Namespace,
+		Name:               pdb.Name,
+		BlockingPods:       []string{},
+		CurrentHealthy:     pdb.Status.CurrentHealthy,
+		DesiredHealthy:     pdb.Status.DesiredHealthy,
+		DisruptionsAllowed: pdb.Status.DisruptionsAllowed,
+	}
+
+	// Extract minAvailable or maxUnavailable from spec
+	// These can be either int values or percentage strings (e.g., "50%")
+	if pdb.Spec.MinAvailable != nil {
+		switch pdb.Spec.MinAvailable.Type {
+		case intstr.Int:
+			info.MinAvailable = fmt.Sprintf("%d", pdb.Spec.MinAvailable.IntVal)
+		case intstr.String:
+			info.MinAvailable = pdb.Spec.MinAvailable.StrVal
+		}
+	}
+	if pdb.Spec.MaxUnavailable != nil {
+		switch pdb.Spec.MaxUnavailable.Type {
+		case intstr.Int:
+			info.MaxUnavailable = fmt.Sprintf("%d", pdb.Spec.MaxUnavailable.IntVal)
+		case intstr.String:
+			info.MaxUnavailable = pdb.Spec.MaxUnavailable.StrVal
+		}
+	}
+	return info
+}
+
+// pdbBlocksEviction reports whether a PDB currently blocks evictions.
+// A PDB blocks eviction when DisruptionsAllowed is 0 and we're at or below the desired healthy threshold.
+func pdbBlocksEviction(pdb *policyv1.PodDisruptionBudget) bool {
+	return pdb.Status.DisruptionsAllowed == 0 && pdb.Status.CurrentHealthy <= pdb.Status.DesiredHealthy
+}
+
+// SimulateDrain reports what would happen if the given nodes were cordoned and drained:
+// which pods reschedule onto remaining nodes of the same NodePool(s), which go pending,
+// and which evictions PodDisruptionBudgets would block. Nothing in the cluster is modified.
+func (c *Client) SimulateDrain(ctx context.Context, nodeNames []string) (*DrainSimulation, error) {
+	if len(nodeNames) == 0 {
+		return nil, fmt.Errorf("at least one node name is required")
+	}
+
+	draining := make(map[string]bool, len(nodeNames))
+	nodePools := make(map[string]bool)
+	for _, name := range nodeNames {
+		node, err := c.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get node %s: %w", name, err)
+		}
+		draining[name] = true
+		// Nodes Karpenter doesn't manage have no NodePool to reschedule within
+		if nodePool := node.Labels["karpenter.sh/nodepool"]; nodePool != "" {
+			nodePools[nodePool] = true
+		}
+	}
+
+	nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	allPods, err := c.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	var pdbs []policyv1.PodDisruptionBudget
+	if pdbList, err := c.clientset.PolicyV1().PodDisruptionBudgets("").List(ctx, metav1.ListOptions{}); err == nil {
+		pdbs = pdbList.Items
+	} else {
+		// PDB API might not be available, continue without PDB checks
+		c.debugLog("Debug: Failed to list PDBs for drain simulation: %v\n", err)
+	}
+
+	podsByKey := make(map[string]*corev1.Pod, len(allPods.Items))
+	podsByNode := make(map[string][]*corev1.Pod)
+	for i := range allPods.Items {
+		pod := &allPods.Items[i]
+		podsByKey[fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)] = pod
+		if pod.Spec.NodeName != "" && pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+			podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+		}
+	}
+
+	// Remaining nodes of the same NodePool(s) that can still accept pods
+	var candidates []*drainCandidate
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		nodePool := node.Labels["karpenter.sh/nodepool"]
+		if draining[node.Name] || nodePool == "" || !nodePools[nodePool] || node.Spec.Unschedulable || node.DeletionTimestamp != nil {
+			continue
+		}
+		candidates = append(candidates, newDrainCandidate(node, nodePool, podsByNode[node.Name]))
+	}
+
+	sim := &DrainSimulation{
+		Nodes:          nodeNames,
+		NodePools:      []string{},
+		Rescheduled:    []DrainPodResult{},
+		Pending:        []DrainPodResult{},
+		Blocked:        []DrainPodResult{},
+		Skipped:        []DrainPodResult{},
+		BlockingPDBs:   []PDBBlockingInfo{},
+		RemainingNodes: []DrainNodeCapacity{},
+	}
+	for np := range nodePools {
+		sim.NodePools = append(sim.NodePools, np)
+	}
+	sort.Strings(sim.NodePools)
+
+	// Collect evictable pods from the drained nodes
+	type evictable struct {
+		info PodInfo
+		pod  *corev1.Pod
+		cpu  float64
+		mem  float64
+	}
+	var toEvict []evictable
+	for _, name := range nodeNames {
+		podInfos, err := c.getPodsOnNode(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pods on node %s: %w", name, err)
+		}
+		for _, info := range podInfos {
+			if info.NodeName != name {
+				continue
+			}
+			result := drainPodResult(info)
+			pod := podsByKey[fmt.Sprintf("%s/%s", info.Namespace, info.Name)]
+			switch {
+			case pod == nil:
+				continue
+			case info.Phase == string(corev1.PodSucceeded) || info.Phase == string(corev1.PodFailed):
+				result.Outcome, result.Reason = DrainOutcomeSkipped, "pod has completed"
+				sim.Skipped = append(sim.Skipped, result)
+			case isDaemonSetPod(pod):
+				result.Outcome, result.Reason = DrainOutcomeSkipped, "DaemonSet pods are not evicted"
+				sim.Skipped = append(sim.Skipped, result)
+			case pod.Annotations[corev1.MirrorPodAnnotationKey] != "":
+				result.Outcome, result.Reason = DrainOutcomeSkipped, "static (mirror) pods are not evicted"
+				sim.Skipped = append(sim.Skipped, result)
+			default:
+				cpu, mem := podRequests(pod)
+				toEvict = append(toEvict, evictable{info: info, pod: pod, cpu: cpu, mem: mem})
+			}
+		}
+	}
+
+	// Evictions consume PDB budgets; once a budget is spent further evictions wait
+	// for replacements to become healthy, and a PDB already at zero blocks the drain.
+	// Only pods that are evicted and rescheduled consume budget.
+	pdbBudget := make(map[string]int32, len(pdbs))
+	pdbDetails := make(map[string]*PDBBlockingInfo)
+	for i := range pdbs {
+		pdbBudget[fmt.Sprintf("%s/%s", pdbs[i].Namespace, pdbs[i].Name)] = pdbs[i].Status.DisruptionsAllowed
+	}
+
+	// Place the largest pods first (first-fit decreasing), like a scheduler under pressure
+	sort.SliceStable(toEvict, func(i, j int) bool {
+		return toEvict[i].cpu+toEvict[i].mem/4 > toEvict[j].cpu+toEvict[j].mem/4
+	})
+
+	for _, e := range toEvict {
+		result := drainPodResult(e.info)
+		podKey := fmt.Sprintf("%s/%s", e.pod.Namespace, e.pod.Name)
+
+		var budgets []string
+		for i := range pdbs {
+			pdb := &pdbs[i]
+			if pdb.Namespace != e.pod.Namespace {
+				continue
+			}
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			if err != nil || !selector.Matches(labels.Set(e.pod.Labels)) {
+				continue
+			}
+			pdbKey := fmt.Sprintf("%s/%s", pdb.Namespace, pdb.Name)
+			if pdbBlocksEviction(pdb) {
+				if _, exists := pdbDetails[pdbKey]; !exists {
+					pdbDetails[pdbKey] = newPDBBlockingInfo(pdb)
+				}
+				pdbDetails[pdbKey].BlockingPods = append(pdbDetails[pdbKey].BlockingPods, podKey)
+				result.BlockingPDBs = append(result.BlockingPDBs, pdbKey)
+				continue
+			}
+			if pdbBudget[pdbKey] <= 0 {
+				result.DelayedByPDBs = append(result.DelayedByPDBs, pdbKey)
+			}
+			budgets = append(budgets, pdbKey)
+		}
+
+		if len(result.BlockingPDBs) > 0 {
+			result.Outcome = DrainOutcomeBlocked
+			result.Reason = fmt.Sprintf("eviction blocked by PDB %v (0 disruptions allowed)", result.BlockingPDBs)
+			sim.Blocked = append(sim.Blocked, result)
+			continue
+		}
+
+		target, reason := placeDrainedPod(e.pod, e.cpu, e.mem, candidates)
+		if target == nil {
+			result.Outcome = DrainOutcomePending
+			result.Reason = reason
+			sim.Pending = append(sim.Pending, result)
+			continue
+		}
+
+		for _, pdbKey := range budgets {
+			pdbBudget[pdbKey]--
+		}
+		result.Outcome = DrainOutcomeReschedule
+		result.ToNode = target.node.Name
+		if len(result.DelayedByPDBs) > 0 {
+			result.Reason = fmt.Sprintf("eviction waits for replacements to become healthy (PDB %v)", result.DelayedByPDBs)
+		}
+		sim.Rescheduled = append(sim.Rescheduled, result)
+	}
+
+	for _, details := range pdbDetails {
+		sim.BlockingPDBs = append(sim.BlockingPDBs, *details)
+	}
+	sort.Slice(sim.BlockingPDBs, func(i, j int) bool {
+		return sim.BlockingPDBs[i].PDBName < sim.BlockingPDBs[j].PDBName
+	})
+
+	for _, cand := range candidates {
+		cand.capacity.FreeCPUAfter = cand.freeCPU
+		cand.capacity.FreeMemoryAfter = cand.freeMem
+		cand.capacity.PodsReceived = cand.placement
+		sim.RemainingNodes = append(sim.RemainingNodes, cand.capacity)
+	}
+
+	sim.Safe = len(sim.Pending) == 0 && len(sim.Blocked) == 0
+	sim.Summary = fmt.Sprintf("Draining %d node(s): %d pod(s) reschedule on %d remaining node(s), %d would go pending, %d blocked by %d PDB(s), %d skipped.",
+		len(nodeNames), len(sim.Rescheduled), len(candidates), len(sim.Pending), len(sim.Blocked), len(sim.BlockingPDBs), len(sim.Skipped))
+	if len(sim.Pending) > 0 {
+		sim.Summary += " Pending pods will trigger Karpenter provisioning if a NodePool can satisfy them."
+	}
+
+	return sim, nil
+}
+
+// newDrainCandidate computes free capacity on a remaining node from its scheduled pods
+func newDrainCandidate(node *corev1.Node, nodePool string, pods []*corev1.Pod) *drainCandidate {
+	cand := &drainCandidate{node: node, nodePool: nodePool}
+	if cpu, ok := node.Status.Allocatable[corev1.ResourceCPU]; ok {
+		cand.freeCPU = float64(cpu.MilliValue()) / 1000.0
+	}
+	if mem, ok := node.Status.Allocatable[corev1.ResourceMemory]; ok {
+		cand.freeMem = float64(mem.Value()) / (1024.0 * 1024.0 * 1024.0)
+	}
+	if podsQty, ok := node.Status.Allocatable[corev1.ResourcePods]; ok {
+		cand.freePods = int(podsQty.Value())
+	}
+	if gpu := gpuUsageFromNode(node); gpu != nil {
+		cand.freeGPU = gpu.Allocatable
+	}
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		cpu, mem := podRequests(pod)
+		cand.freeCPU -= cpu
+		cand.freeMem -= mem
+		cand.freePods--
+		cand.freeGPU -= podGPUUnits(pod)
+	}
+	cand.capacity = DrainNodeCapacity{
+		Name:             node.Name,
+		NodePool:         nodePool,
+		FreeCPUBefore:    cand.freeCPU,
+		FreeMemoryBefore: cand.freeMem,
+	}
+	return cand
+}
+
+// placeDrainedPod picks the feasible node with the most free CPU (least-allocated scoring)
+// and reserves the pod's requests on it. Returns nil and the most specific reason when nothing fits.
+func placeDrainedPod(pod *corev1.Pod, cpu, mem float64, candidates []*drainCandidate) (*drainCandidate, string) {
+	if len(candidates) == 0 {
+		return nil, "no other schedulable nodes in the NodePool"
+	}
+
+	gpu := podGPUUnits(pod)
+	var best *drainCandidate
+	reason := ""
+	for _, cand := range candidates {
+		if taint := untoleratedTaint(pod.Spec.Tolerations, cand.node.Spec.Taints); taint != nil {
+			if reason == "" {
+				reason = fmt.Sprintf("does not tolerate taint %s=%s:%s", taint.Key, taint.Value, taint.Effect)
+			}
+			continue
+		}
+		if mismatch := podNodeSelectorMismatch(&pod.Spec, cand.node.Labels); mismatch != "" {
+			if reason == "" {
+				reason = mismatch
+			}
+			continue
+		}
+		if cand.freePods < 1 || cand.freeCPU < cpu || cand.freeMem < mem || cand.freeGPU < gpu {
+			// Resource shortfall is the most useful reason, so it overrides placement constraints
+			reason = fmt.Sprintf("insufficient free capacity (needs %.2f CPU, %.2f GiB memory", cpu, mem)
+			if gpu > 0 {
+				reason += fmt.Sprintf(", %d GPU", gpu)
+			}
+			reason += ")"
+			continue
+		}
+		if best == nil || cand.freeCPU > best.freeCPU {
+			best = cand
+		}
+	}
+
+	if best == nil {
+		return nil, reason
+	}
+	best.freeCPU -= cpu
+	best.freeMem -= mem
+	best.freePods--
+	best.freeGPU -= gpu
+	best.placement++
+	return best, ""
+}
+
+// drainPodResult seeds a result from pod info returned by getPodsOnNode
+func drainPodResult(info PodInfo) DrainPodResult {
+	return DrainPodResult{
+		Name:         info.Name,
+		Namespace:    info.Namespace,
+		WorkloadName: info.WorkloadName,
+		WorkloadType: info.WorkloadType,
+		FromNode:     info.NodeName,
+		Requests:     info.Requests,
+	}
+}
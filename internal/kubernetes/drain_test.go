@@ -0,0 +1,162 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testNode(name, nodePool, cpu, memory string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"karpenter.sh/nodepool": nodePool},
+		},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+				corev1.ResourcePods:   resource.MustParse("110"),
+			},
+		},
+	}
+}
+
+func testPod(name, namespace, nodeName, cpu, memory string, podLabels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: podLabels},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse(cpu),
+						corev1.ResourceMemory: resource.MustParse(memory),
+					},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func TestSimulateDrain(t *testing.T) {
+	minAvailable := intstr.FromInt(1)
+	tainted := testNode("node-c", "general", "8", "32Gi")
+	tainted.Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "batch", Effect: corev1.TaintEffectNoSchedule}}
+
+	clientset := fake.NewSimpleClientset(
+		testNode("node-a", "general", "4", "16Gi"),
+		testNode("node-b", "general", "4", "16Gi"),
+		tainted,
+		testNode("node-gpu", "gpu", "8", "32Gi"),
+		testPod("web-1", "default", "node-a", "1", "2Gi", map[string]string{"app": "web"}),
+		testPod("big-1", "default", "node-a", "3", "4Gi", map[string]string{"app": "big"}),
+		testPod("db-0", "data", "node-a", "500m", "1Gi", map[string]string{"app": "db"}),
+		testPod("filler", "default", "node-b", "2", "4Gi", nil),
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-pdb", Namespace: "data"},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable: &minAvailable,
+				Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			},
+			Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 0, CurrentHealthy: 1, DesiredHealthy: 1},
+		},
+	)
+	c := &Client{clientset: clientset}
+
+	sim, err := c.SimulateDrain(context.Background(), []string{"node-a"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"general"}, sim.NodePools)
+	assert.False(t, sim.Safe)
+
+	require.Len(t, sim.Rescheduled, 1)
+	assert.Equal(t, "web-1", sim.Rescheduled[0].Name)
+	assert.Equal(t, "node-b", sim.Rescheduled[0].ToNode, "tainted node-c must not receive untolerating pods")
+
+	require.Len(t, sim.Pending, 1)
+	assert.Equal(t, "big-1", sim.Pending[0].Name)
+	assert.Contains(t, sim.Pending[0].Reason, "insufficient free capacity")
+
+	require.Len(t, sim.Blocked, 1)
+	assert.Equal(t, "db-0", sim.Blocked[0].Name)
+	require.Len(t, sim.BlockingPDBs, 1)
+	assert.Equal(t, "data/db-pdb", sim.BlockingPDBs[0].PDBName)
+	assert.Equal(t, "1", sim.BlockingPDBs[0].MinAvailable)
+
+	// Nodes from other NodePools are never considered
+	for _, n := range sim.RemainingNodes {
+		assert.NotEqual(t, "node-gpu", n.Name)
+	}
+}
+
+func TestSimulateDrainSkipsUnlabeledNodes(t *testing.T) {
+	unmanaged := testNode("unmanaged", "", "8", "32Gi")
+	delete(unmanaged.Labels, "karpenter.sh/nodepool")
+	other := testNode("other-unmanaged", "", "8", "32Gi")
+	delete(other.Labels, "karpenter.sh/nodepool")
+
+	clientset := fake.NewSimpleClientset(
+		unmanaged,
+		other,
+		testNode("node-a", "general", "8", "32Gi"),
+		testPod("web-1", "default", "unmanaged", "1", "2Gi", nil),
+	)
+	c := &Client{clientset: clientset}
+
+	sim, err := c.SimulateDrain(context.Background(), []string{"unmanaged"})
+	require.NoError(t, err)
+	assert.Empty(t, sim.NodePools)
+	assert.Empty(t, sim.RemainingNodes, "nodes without a NodePool are not drain candidates")
+	require.Len(t, sim.Pending, 1)
+	assert.Equal(t, "no other schedulable nodes in the NodePool", sim.Pending[0].Reason)
+}
+
+func TestSimulateDrainPDBBudget(t *testing.T) {
+	maxUnavailable := intstr.FromInt(1)
+	clientset := fake.NewSimpleClientset(
+		testNode("node-a", "general", "8", "32Gi"),
+		testNode("node-b", "general", "2", "8Gi"),
+		testPod("big-1", "default", "node-a", "4", "8Gi", map[string]string{"app": "web"}),
+		testPod("web-1", "default", "node-a", "1", "2Gi", map[string]string{"app": "web"}),
+		testPod("web-2", "default", "node-a", "500m", "1Gi", map[string]string{"app": "web"}),
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-pdb", Namespace: "default"},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MaxUnavailable: &maxUnavailable,
+				Selector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+			Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1, CurrentHealthy: 3, DesiredHealthy: 2},
+		},
+	)
+	c := &Client{clientset: clientset}
+
+	sim, err := c.SimulateDrain(context.Background(), []string{"node-a"})
+	require.NoError(t, err)
+
+	// big-1 goes pending and doesn't spend the budget, so web-1 is evicted without waiting
+	require.Len(t, sim.Pending, 1)
+	assert.Equal(t, "big-1", sim.Pending[0].Name)
+	require.Len(t, sim.Rescheduled, 2)
+	assert.Equal(t, "web-1", sim.Rescheduled[0].Name)
+	assert.Empty(t, sim.Rescheduled[0].DelayedByPDBs)
+	assert.Equal(t, "web-2", sim.Rescheduled[1].Name)
+	assert.Equal(t, []string{"default/web-pdb"}, sim.Rescheduled[1].DelayedByPDBs)
+}
+
+func TestSimulateDrainUnknownNode(t *testing.T) {
+	c := &Client{clientset: fake.NewSimpleClientset()}
+
+	_, err := c.SimulateDrain(context.Background(), []string{"missing"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,222 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Well-known GPU resource names and labels (NVIDIA device plugin, GPU feature discovery and Karpenter)
+const (
+	ResourceNvidiaGPU       corev1.ResourceName = "nvidia.com/gpu"
+	ResourceNvidiaGPUShared corev1.ResourceName = "nvidia.com/gpu.shared" // time-slicing with renameByDefault=true
+	resourceNvidiaMIGPrefix                     = "nvidia.com/mig-"
+
+	LabelGPUProduct         = "nvidia.com/gpu.product"
+	LabelGPUCount           = "nvidia.com/gpu.count"
+	LabelGPUMemory          = "nvidia.com/gpu.memory" // MiB per GPU
+	LabelGPUReplicas        = "nvidia.com/gpu.replicas"
+	LabelGPUSharingStrategy = "nvidia.com/gpu.sharing-strategy"
+	LabelMIGStrategy        = "nvidia.com/mig.strategy"
+	LabelKarpenterGPUName   = "karpenter.k8s.aws/instance-gpu-name"
+	LabelKarpenterGPUMemory = "karpenter.k8s.aws/instance-gpu-memory" // MiB per GPU
+	LabelEKSAccelerator     = "k8s.amazonaws.com/accelerator"
+)
+
+// gpuModelKeys are node selector / affinity keys that pin a workload to a GPU model, in priority order
+var gpuModelKeys = []string{LabelGPUProduct, LabelKarpenterGPUName, LabelEKSAccelerator}
+
+// GPUUsage represents GPU allocation on a node
+type GPUUsage struct {
+	Model           string   `json:"model,omitempty"`           // GPU product (e.g., NVIDIA-A10G, Tesla-T4)
+	MemoryMiB       int64    `json:"memoryMiB,omitempty"`       // Memory per physical GPU in MiB
+	Physical        int      `json:"physical"`                  // Physical GPUs on the node
+	Allocatable     int      `json:"allocatable"`               // Schedulable GPU units (includes time-slicing replicas)
+	Allocated       int      `json:"allocated"`                 // GPU units requested by scheduled pods
+	IdleAllocated   int      `json:"idleAllocated"`             // GPU units held by pods that are not Ready
+	Replicas        int      `json:"replicas,omitempty"`        // Time-slicing replicas per physical GPU
+	SharingStrategy string   `json:"sharingStrategy,omitempty"` // time-slicing, mps, none
+	MIGProfiles     []string `json:"migProfiles,omitempty"`     // MIG profiles advertised by the node
+	IdlePods        []string `json:"idlePods,omitempty"`        // Pods holding GPUs while not Ready (namespace/name)
+}
+
+// extractGPURequirements fills in the GPU model, memory, MIG and time-slicing requirements of a pod template
+func extractGPURequirements(podSpec *corev1.PodSpec, workload *WorkloadInfo) {
+	for _, container := range podSpec.Containers {
+		if shared := gpuQuantity(container.Resources, ResourceNvidiaGPUShared); shared > 0 {
+			workload.SharedGPU += shared
+		}
+		for name := range container.Resources.Limits {
+			profile, ok := migProfileFromResource(name)
+			if !ok {
+				continue
+			}
+			if count := gpuQuantity(container.Resources, name); count > 0 {
+				if workload.MIGProfiles == nil {
+					workload.MIGProfiles = make(map[string]int)
+				}
+				workload.MIGProfiles[profile] += count
+			}
+		}
+	}
+
+	// Node selectors take precedence over affinity
+	for _, key := range gpuModelKeys {
+		if val := podSpec.NodeSelector[key]; val != "" {
+			workload.GPUModel = val
+			break
+		}
+	}
+	if mem := podSpec.NodeSelector[LabelKarpenterGPUMemory]; mem != "" {
+		if mib, err := strconv.ParseInt(mem, 10, 64); err == nil {
+			workload.GPUMemoryMiB = mib
+		}
+	}
+
+	if podSpec.Affinity == nil || podSpec.Affinity.NodeAffinity == nil ||
+		podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return
+	}
+	for _, term := range podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, expr := range term.MatchExpressions {
+			switch {
+			case workload.GPUModel == "" && contains(gpuModelKeys, expr.Key) &&
+				expr.Operator == corev1.NodeSelectorOpIn && len(expr.Values) > 0:
+				workload.GPUModel = expr.Values[0]
+			case workload.GPUMemoryMiB == 0 && expr.Key == LabelKarpenterGPUMemory && len(expr.Values) > 0:
+				mib, err := strconv.ParseInt(expr.Values[0], 10, 64)
+				if err != nil {
+					continue
+				}
+				// Gt is exclusive, so the workload needs at least one MiB more
+				if expr.Operator == corev1.NodeSelectorOpGt {
+					mib++
+				}
+				if expr.Operator == corev1.NodeSelectorOpIn || expr.Operator == corev1.NodeSelectorOpGt {
+					workload.GPUMemoryMiB = mib
+				}
+			}
+		}
+	}
+}
+
+// gpuQuantity returns the requested amount of a GPU resource (requests preferred, limits as fallback)
+func gpuQuantity(resources corev1.ResourceRequirements, name corev1.ResourceName) int {
+	if req, ok := resources.Requests[name]; ok && !req.IsZero() {
+		return int(req.Value())
+	}
+	if limit, ok := resources.Limits[name]; ok && !limit.IsZero() {
+		return int(limit.Value())
+	}
+	return 0
+}
+
+// podGPUUnits returns the GPU units (full, shared and MIG slices) requested by a pod's regular containers
+func podGPUUnits(pod *corev1.Pod) int {
+	units := 0
+	for _, container := range pod.Spec.Containers {
+		units += gpuQuantity(container.Resources, ResourceNvidiaGPU)
+		units += gpuQuantity(container.Resources, ResourceNvidiaGPUShared)
+		for name := range container.Resources.Limits {
+			if _, ok := migProfileFromResource(name); ok {
+				units += gpuQuantity(container.Resources, name)
+			}
+		}
+	}
+	return units
+}
+
+// migProfileFromResource extracts the MIG profile (e.g., "1g.5gb") from a resource name like nvidia.com/mig-1g.5gb
+func migProfileFromResource(name corev1.ResourceName) (string, bool) {
+	s := string(name)
+	if !strings.HasPrefix(s, resourceNvidiaMIGPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(s, resourceNvidiaMIGPrefix), true
+}
+
+// isPodReady reports whether the pod's Ready condition is true
+func isPodReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// gpuUsageFromNode builds the GPU capacity view of a node from its labels and status.
+// Returns nil for nodes without GPUs.
+func gpuUsageFromNode(node *corev1.Node) *GPUUsage {
+	allocatable := 0
+	var migProfiles []string
+	for name, qty := range node.Status.Allocatable {
+		switch {
+		case name == ResourceNvidiaGPU || name == ResourceNvidiaGPUShared:
+			allocatable += int(qty.Value())
+		default:
+			if profile, ok := migProfileFromResource(name); ok && !qty.IsZero() {
+				allocatable += int(qty.Value())
+				migProfiles = append(migProfiles, profile)
+			}
+		}
+	}
+
+	physical := 0
+	if count := node.Labels[LabelGPUCount]; count != "" {
+		physical, _ = strconv.Atoi(count)
+	}
+	if physical == 0 && len(migProfiles) == 0 {
+		if capQty, ok := node.Status.Capacity[ResourceNvidiaGPU]; ok {
+			physical = int(capQty.Value())
+		}
+	}
+	if allocatable == 0 && physical == 0 {
+		return nil
+	}
+
+	usage := &GPUUsage{
+		Model:           node.Labels[LabelGPUProduct],
+		Physical:        physical,
+		Allocatable:     allocatable,
+		SharingStrategy: node.Labels[LabelGPUSharingStrategy],
+		MIGProfiles:     migProfiles,
+	}
+	if usage.Model == "" {
+		usage.Model = node.Labels[LabelKarpenterGPUName]
+	}
+	for _, key := range []string{LabelGPUMemory, LabelKarpenterGPUMemory} {
+		if mem := node.Labels[key]; mem != "" {
+			if mib, err := strconv.ParseInt(mem, 10, 64); err == nil {
+				usage.MemoryMiB = mib
+				break
+			}
+		}
+	}
+	if replicas := node.Labels[LabelGPUReplicas]; replicas != "" {
+		usage.Replicas, _ = strconv.Atoi(replicas)
+	}
+	// Time-slicing advertises replicas*physical GPUs without always labeling the node
+	if usage.Replicas == 0 && physical > 0 && allocatable > physical && len(migProfiles) == 0 {
+		usage.Replicas = allocatable / physical
+	}
+	if usage.Replicas > 1 && usage.SharingStrategy == "" {
+		usage.SharingStrategy = "time-slicing"
+	}
+	return usage
+}
+
+// addPodGPUUsage accounts a scheduled pod's GPU requests against the node's GPU usage
+func (u *GPUUsage) addPodGPUUsage(pod *corev1.Pod) {
+	units := podGPUUnits(pod)
+	if units == 0 {
+		return
+	}
+	u.Allocated += units
+	if pod.Status.Phase != corev1.PodRunning || !isPodReady(pod) {
+		u.IdleAllocated += units
+		u.IdlePods = append(u.IdlePods, fmt.Sprintf("%s/%s", pod.Namespace, pod.Name))
+	}
+}
@@ -0,0 +1,194 @@
+package kubernetes
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Karpenter log follow event types
+const (
+	LogEventLine          = "line"
+	LogEventPodStarted    = "pod-started" // Started following a pod (new replica, restart, or initial)
+	LogEventPodStopped    = "pod-stopped" // Log stream ended (container restart, pod deleted)
+	LogEventLeaderChanged = "leader-changed"
+)
+
+// karpenterLeaseName is the leader election Lease Karpenter holds in its namespace
+const karpenterLeaseName = "karpenter-leader-election"
+
+// KarpenterLogEvent is a log line or a change in the set of followed Karpenter pods
+type KarpenterLogEvent struct {
+	Type      string    `json:"type"`
+	Pod       string    `json:"pod,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	Line      string    `json:"line,omitempty"`
+	Leader    string    `json:"leader,omitempty"` // Pod holding the leader Lease (leader-changed events)
+	Message   string    `json:"message,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// FollowKarpenterLogs follows logs from every running Karpenter pod until ctx is cancelled.
+// Pods are rediscovered every resync so restarted containers and replacement pods are picked
+// up; reconnects resume from when the previous stream ended. All replicas are followed, so a
+// leader change needs no reconnect, but it is reported from the leader election Lease.
+// since > 0 backfills that much history; otherwise only new lines are sent.
+// The returned channel is closed when ctx is done.
+func (c *Client) FollowKarpenterLogs(ctx context.Context, since, resync time.Duration) <-chan KarpenterLogEvent {
+	events := make(chan KarpenterLogEvent, 256)
+	if resync <= 0 {
+		resync = 10 * time.Second
+	}
+
+	go func() {
+		var wg sync.WaitGroup
+		defer func() {
+			wg.Wait()
+			close(events)
+		}()
+
+		var mu sync.Mutex
+		following := make(map[string]bool)       // namespace/name -> stream active
+		resumeFrom := make(map[string]time.Time) // namespace/name -> when the last stream ended
+		leader := ""
+		startedAt := time.Now()
+
+		send := func(event KarpenterLogEvent) bool {
+			if event.Time.IsZero() {
+				event.Time = time.Now()
+			}
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		ticker := time.NewTicker(resync)
+		defer ticker.Stop()
+
+		for {
+			pods, err := c.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+				LabelSelector: "app.kubernetes.io/name=karpenter",
+			})
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				c.debugLog("Debug: Failed to list Karpenter pods for log follow: %v\n", err)
+			} else {
+				namespaces := make(map[string]bool)
+				for i := range pods.Items {
+					pod := &pods.Items[i]
+					namespaces[pod.Namespace] = true
+					if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+						continue
+					}
+					key := pod.Namespace + "/" + pod.Name
+
+					mu.Lock()
+					if following[key] {
+						mu.Unlock()
+						continue
+					}
+					following[key] = true
+					opts := &corev1.PodLogOptions{Follow: true}
+					if resume, ok := resumeFrom[key]; ok {
+						resumeTime := metav1.NewTime(resume)
+						opts.SinceTime = &resumeTime
+					} else if since > 0 {
+						// Backfill only applies to pods present when following started
+						seconds := int64(since.Seconds())
+						if pod.CreationTimestamp.Time.After(startedAt) {
+							seconds = int64(time.Since(pod.CreationTimestamp.Time).Seconds()) + 1
+						}
+						opts.SinceSeconds = &seconds
+					} else {
+						// No backfill: only lines written from now on
+						var tail int64
+						opts.TailLines = &tail
+					}
+					mu.Unlock()
+
+					if !send(KarpenterLogEvent{Type: LogEventPodStarted, Pod: pod.Name, Namespace: pod.Namespace}) {
+						return
+					}
+
+					wg.Add(1)
+					go func(namespace, name string, opts *corev1.PodLogOptions) {
+						defer wg.Done()
+						reason := c.followPodLogs(ctx, namespace, name, opts, send)
+
+						mu.Lock()
+						following[namespace+"/"+name] = false
+						resumeFrom[namespace+"/"+name] = time.Now()
+						mu.Unlock()
+
+						if ctx.Err() == nil {
+							send(KarpenterLogEvent{Type: LogEventPodStopped, Pod: name, Namespace: namespace, Message: reason})
+						}
+					}(pod.Namespace, pod.Name, opts)
+				}
+
+				if holder := c.karpenterLeader(ctx, namespaces); holder != "" && holder != leader {
+					leader = holder
+					if !send(KarpenterLogEvent{Type: LogEventLeaderChanged, Leader: holder}) {
+						return
+					}
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return events
+}
+
+// followPodLogs streams a pod's logs until the stream ends and returns why it ended
+func (c *Client) followPodLogs(ctx context.Context, namespace, name string, opts *corev1.PodLogOptions, send func(KarpenterLogEvent) bool) string {
+	stream, err := c.clientset.CoreV1().Pods(namespace).GetLogs(name, opts).Stream(ctx)
+	if err != nil {
+		return fmt.Sprintf("failed to get log stream: %v", err)
+	}
+	defer func() {
+		_ = stream.Close() // Ignore close errors
+	}()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if !send(KarpenterLogEvent{Type: LogEventLine, Pod: name, Namespace: namespace, Line: scanner.Text()}) {
+			return "stopped"
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Sprintf("log stream error: %v", err)
+	}
+	return "log stream ended"
+}
+
+// karpenterLeader returns the pod holding the Karpenter leader election Lease, or ""
+func (c *Client) karpenterLeader(ctx context.Context, namespaces map[string]bool) string {
+	for namespace := range namespaces {
+		lease, err := c.clientset.CoordinationV1().Leases(namespace).Get(ctx, karpenterLeaseName, metav1.GetOptions{})
+		if err != nil || lease.Spec.HolderIdentity == nil {
+			continue
+		}
+		// Holder identity is "<pod name>_<uuid>"
+		holder, _, _ := strings.Cut(*lease.Spec.HolderIdentity, "_")
+		return holder
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFollowKarpenterLogs(t *testing.T) {
+	karpenterPod := func(name string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "karpenter", Labels: map[string]string{"app.kubernetes.io/name": "karpenter"}},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	holder := "karpenter-a_4f2c9b1e"
+	c := &Client{clientset: fake.NewSimpleClientset(
+		karpenterPod("karpenter-a", corev1.PodRunning),
+		karpenterPod("karpenter-b", corev1.PodPending),
+		&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: karpenterLeaseName, Namespace: "karpenter"},
+			Spec:       coordinationv1.LeaseSpec{HolderIdentity: &holder},
+		},
+	)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	seen := make(map[string][]KarpenterLogEvent)
+	for event := range c.FollowKarpenterLogs(ctx, time.Minute, 50*time.Millisecond) {
+		seen[event.Type] = append(seen[event.Type], event)
+		// The fake log stream ends immediately, so a reconnect proves restart handling
+		if len(seen[LogEventPodStarted]) >= 2 {
+			cancel()
+		}
+	}
+
+	for _, event := range seen[LogEventPodStarted] {
+		assert.Equal(t, "karpenter-a", event.Pod, "pending pods are not followed")
+	}
+	assert.NotEmpty(t, seen[LogEventLine])
+	assert.Equal(t, "karpenter", seen[LogEventLine][0].Namespace)
+	assert.NotEmpty(t, seen[LogEventPodStopped])
+	if assert.Len(t, seen[LogEventLeaderChanged], 1) {
+		assert.Equal(t, "karpenter-a", seen[LogEventLeaderChanged][0].Leader)
+	}
+}
@@ -0,0 +1,119 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// AnnotationExcludeFromAutoApply on a NodePool ("true") stops optimization plans from being applied to it automatically
+const AnnotationExcludeFromAutoApply = "karpenter-optimizer.io/exclude"
+
+// NewClientFromInterfaces creates a client from existing clientsets (e.g. fakes in tests).
+// Without a discovery client, Karpenter resources default to karpenter.sh/v1.
+func NewClientFromInterfaces(clientset kubernetes.Interface, dynamicClient dynamic.Interface) *Client {
+	return &Client{clientset: clientset, dynamicClient: dynamicClient}
+}
+
+// nodePoolGVR returns the NodePool resource, defaulting to karpenter.sh/v1 when discovery fails
+func (c *Client) nodePoolGVR(ctx context.Context) schema.GroupVersionResource {
+	gvr, err := c.discoverNodePoolResource(ctx)
+	if err != nil {
+		return schema.GroupVersionResource{Group: "karpenter.sh", Version: "v1", Resource: "nodepools"}
+	}
+	return gvr
+}
+
+// GetNodePoolSpec returns a copy of a NodePool's raw spec and its annotations
+func (c *Client) GetNodePoolSpec(ctx context.Context, name string) (map[string]interface{}, map[string]string, error) {
+	item, err := c.dynamicClient.Resource(c.nodePoolGVR(ctx)).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get nodepool %s: %w", name, err)
+	}
+	spec, _, err := unstructured.NestedMap(item.Object, "spec")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read nodepool %s spec: %w", name, err)
+	}
+	return spec, item.GetAnnotations(), nil
+}
+
+// PatchNodePoolSpec merge-patches a NodePool's spec. With dryRun the API server
+// validates and admits the change without persisting it.
+func (c *Client) PatchNodePoolSpec(ctx context.Context, name string, specPatch map[string]interface{}, dryRun bool) error {
+	data, err := json.Marshal(map[string]interface{}{"spec": specPatch})
+	if err != nil {
+		return fmt.Errorf("failed to marshal nodepool patch: %w", err)
+	}
+	opts := metav1.PatchOptions{FieldManager: "karpenter-optimizer"}
+	if dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	if _, err := c.dynamicClient.Resource(c.nodePoolGVR(ctx)).Patch(ctx, name, types.MergePatchType, data, opts); err != nil {
+		if dryRun {
+			return fmt.Errorf("dry-run patch of nodepool %s rejected: %w", name, err)
+		}
+		return fmt.Errorf("failed to patch nodepool %s: %w", name, err)
+	}
+	return nil
+}
+
+// ReplaceNodePoolSpec restores a NodePool's whole spec (e.g. to revert a patch), retrying on conflicts
+func (c *Client) ReplaceNodePoolSpec(ctx context.Context, name string, spec map[string]interface{}) error {
+	resource := c.dynamicClient.Resource(c.nodePoolGVR(ctx))
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		item, err := resource.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if err := unstructured.SetNestedMap(item.Object, spec, "spec"); err != nil {
+			return err
+		}
+		_, err = resource.Update(ctx, item, metav1.UpdateOptions{FieldManager: "karpenter-optimizer"})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore nodepool %s spec: %w", name, err)
+	}
+	return nil
+}
+
+// KarpenterWarnings lists Warning events Karpenter reported since the given time for
+// a NodePool or its NodeClaims (named <nodepool>-<suffix>), e.g. launch or disruption failures
+func (c *Client) KarpenterWarnings(ctx context.Context, nodePool string, since time.Time) ([]string, error) {
+	events, err := c.clientset.CoreV1().Events("").List(ctx, metav1.ListOptions{FieldSelector: "type=" + corev1.EventTypeWarning})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+
+	var warnings []string
+	for _, event := range events.Items {
+		if event.Type != corev1.EventTypeWarning || eventTime(event).Before(since) {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(event.Source.Component+event.ReportingController), "karpenter") {
+			continue
+		}
+		obj := event.InvolvedObject
+		switch {
+		case obj.Kind == "NodePool" && obj.Name == nodePool:
+		case obj.Kind == "NodeClaim" && strings.HasPrefix(obj.Name, nodePool+"-"):
+		default:
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("%s %s: %s: %s", obj.Kind, obj.Name, event.Reason, event.Message))
+	}
+	sort.Strings(warnings)
+	return warnings, nil
+}
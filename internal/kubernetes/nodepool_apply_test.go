@@ -0,0 +1,124 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var testNodePoolGVR = schema.GroupVersionResource{Group: "karpenter.sh", Version: "v1", Resource: "nodepools"}
+
+// newTestNodePool returns a karpenter.sh/v1 NodePool restricted to the given instance types
+func newTestNodePool(name string, annotations map[string]string, instanceTypes ...interface{}) *unstructured.Unstructured {
+	np := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "karpenter.sh/v1",
+		"kind":       "NodePool",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"requirements": []interface{}{
+						map[string]interface{}{"key": "kubernetes.io/arch", "operator": "In", "values": []interface{}{"amd64"}},
+						map[string]interface{}{"key": "node.kubernetes.io/instance-type", "operator": "In", "values": instanceTypes},
+					},
+				},
+			},
+		},
+	}}
+	np.SetAnnotations(annotations)
+	return np
+}
+
+// newFakeNodePoolDynamicClient returns a fake dynamic client serving the given NodePools.
+// Like the API server, dry-run patches are admitted without being persisted.
+func newFakeNodePoolDynamicClient(objects ...runtime.Object) (*dynamicfake.FakeDynamicClient, *int) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{testNodePoolGVR: "NodePoolList"}, objects...)
+	dryRuns := 0
+	client.PrependReactor("patch", "nodepools", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if len(action.(k8stesting.PatchActionImpl).GetPatchOptions().DryRun) > 0 {
+			dryRuns++
+			return true, nil, nil
+		}
+		return false, nil, nil
+	})
+	return client, &dryRuns
+}
+
+func TestNodePoolSpecPatchAndRestore(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient, dryRuns := newFakeNodePoolDynamicClient(newTestNodePool("default", map[string]string{AnnotationExcludeFromAutoApply: "true"}, "m5.large"))
+	client := NewClientFromInterfaces(fake.NewSimpleClientset(), dynamicClient)
+
+	spec, annotations, err := client.GetNodePoolSpec(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, "true", annotations[AnnotationExcludeFromAutoApply])
+
+	patch := map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+		"requirements": []interface{}{
+			map[string]interface{}{"key": "node.kubernetes.io/instance-type", "operator": "In", "values": []interface{}{"m6i.large"}},
+		},
+	}}}
+
+	// A dry run does not change the NodePool
+	require.NoError(t, client.PatchNodePoolSpec(ctx, "default", patch, true))
+	assert.Equal(t, 1, *dryRuns)
+	current, _, err := client.GetNodePoolSpec(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, spec, current)
+
+	require.NoError(t, client.PatchNodePoolSpec(ctx, "default", patch, false))
+	current, _, err = client.GetNodePoolSpec(ctx, "default")
+	require.NoError(t, err)
+	requirements, _, _ := unstructured.NestedSlice(current, "template", "spec", "requirements")
+	require.Len(t, requirements, 1)
+	assert.Equal(t, []interface{}{"m6i.large"}, requirements[0].(map[string]interface{})["values"])
+
+	require.NoError(t, client.ReplaceNodePoolSpec(ctx, "default", spec))
+	current, _, err = client.GetNodePoolSpec(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, spec, current)
+
+	_, _, err = client.GetNodePoolSpec(ctx, "missing")
+	assert.Error(t, err)
+}
+
+func TestKarpenterWarnings(t *testing.T) {
+	now := time.Now()
+	event := func(name, kind, object, component string, at time.Time) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: kind, Name: object},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "FailedLaunch",
+			Message:        "insufficient capacity",
+			Source:         corev1.EventSource{Component: component},
+			LastTimestamp:  metav1.NewTime(at),
+		}
+	}
+	client := &Client{clientset: fake.NewSimpleClientset(
+		event("claim", "NodeClaim", "default-abcde", "karpenter", now),
+		event("pool", "NodePool", "default", "karpenter", now),
+		event("old", "NodeClaim", "default-old", "karpenter", now.Add(-time.Hour)),
+		event("other-pool", "NodeClaim", "batch-abcde", "karpenter", now),
+		event("not-karpenter", "NodePool", "default", "kubelet", now),
+	)}
+
+	warnings, err := client.KarpenterWarnings(context.Background(), "default", now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"NodeClaim default-abcde: FailedLaunch: insufficient capacity",
+		"NodePool default: FailedLaunch: insufficient capacity",
+	}, warnings)
+}
@@ -0,0 +1,499 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// NodePoolSchedulingSpec is the scheduling-relevant part of a NodePool: what nodes it can launch and how much is left
+type NodePoolSchedulingSpec struct {
+	Name         string                           `json:"name"`
+	Labels       map[string]string                `json:"labels,omitempty"` // spec.template.metadata.labels
+	Requirements []corev1.NodeSelectorRequirement `json:"requirements,omitempty"`
+	Taints       []corev1.Taint                   `json:"taints,omitempty"`
+	Limits       corev1.ResourceList              `json:"limits,omitempty"`
+	Usage        corev1.ResourceList              `json:"usage,omitempty"` // status.resources
+}
+
+// PodEventInfo is a scheduling-related event recorded for a pod
+type PodEventInfo struct {
+	Type     string `json:"type"`
+	Reason   string `json:"reason"`
+	Message  string `json:"message"`
+	Source   string `json:"source"` // default-scheduler, karpenter
+	Count    int32  `json:"count"`
+	LastSeen string `json:"lastSeen,omitempty"` // RFC3339 timestamp
+}
+
+// NodePoolEvaluation is the result of checking a pending pod against one NodePool
+type NodePoolEvaluation struct {
+	NodePool   string   `json:"nodePool"`
+	Compatible bool     `json:"compatible"`
+	Reasons    []string `json:"reasons,omitempty"` // Why the NodePool cannot launch a node for the pod
+}
+
+// PendingPodDiagnosis explains why a pending pod is not being scheduled or provisioned for
+type PendingPodDiagnosis struct {
+	Name               string               `json:"name"`
+	Namespace          string               `json:"namespace"`
+	WorkloadName       string               `json:"workloadName,omitempty"`
+	PendingSince       string               `json:"pendingSince,omitempty"` // RFC3339 timestamp
+	Requests           ResourceInfo         `json:"requests,omitempty"`
+	GPU                int                  `json:"gpu,omitempty"`
+	SchedulerMessage   string               `json:"schedulerMessage,omitempty"`   // Latest FailedScheduling from kube-scheduler
+	NominatedNodeClaim string               `json:"nominatedNodeClaim,omitempty"` // From Karpenter Nominated events
+	Events             []PodEventInfo       `json:"events,omitempty"`
+	NodePools          []NodePoolEvaluation `json:"nodePools"`
+	Category           string               `json:"category"` // nominated, nodepools-unavailable, no-compatible-nodepool, limits-exhausted, provisioning-failed, awaiting-provisioning
+	Explanation        string               `json:"explanation"`
+}
+
+// wellKnownLabelPrefixes are node labels Karpenter can satisfy without a NodePool defining them
+var wellKnownLabelPrefixes = []string{
+	"kubernetes.io/", "node.kubernetes.io/", "topology.kubernetes.io/", "beta.kubernetes.io/",
+	"failure-domain.beta.kubernetes.io/", "karpenter.sh/", "karpenter.k8s.aws/", "topology.k8s.aws/",
+}
+
+// ListNodePoolSchedulingSpecs returns requirements, taints, limits and usage for every NodePool
+func (c *Client) ListNodePoolSchedulingSpecs(ctx context.Context) ([]NodePoolSchedulingSpec, error) {
+	nodePools, err := c.listNodePoolObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	specs := make([]NodePoolSchedulingSpec, 0, len(nodePools.Items))
+	for i := range nodePools.Items {
+		specs = append(specs, parseNodePoolSchedulingSpec(&nodePools.Items[i]))
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs, nil
+}
+
+// parseNodePoolSchedulingSpec extracts scheduling fields from a v1/v1beta1 NodePool
+func parseNodePoolSchedulingSpec(item *unstructured.Unstructured) NodePoolSchedulingSpec {
+	spec := NodePoolSchedulingSpec{Name: item.GetName()}
+
+	spec.Labels, _, _ = unstructured.NestedStringMap(item.Object, "spec", "template", "metadata", "labels")
+
+	requirements, _, _ := unstructured.NestedSlice(item.Object, "spec", "template", "spec", "requirements")
+	for _, raw := range requirements {
+		reqMap, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		req := corev1.NodeSelectorRequirement{}
+		req.Key, _ = reqMap["key"].(string)
+		operator, _ := reqMap["operator"].(string)
+		req.Operator = corev1.NodeSelectorOperator(operator)
+		values, _ := reqMap["values"].([]interface{})
+		for _, v := range values {
+			if str, ok := v.(string); ok {
+				req.Values = append(req.Values, str)
+			}
+		}
+		if req.Key != "" {
+			spec.Requirements = append(spec.Requirements, req)
+		}
+	}
+
+	// Startup taints are removed once the node initializes, so only regular taints matter
+	taints, _, _ := unstructured.NestedSlice(item.Object, "spec", "template", "spec", "taints")
+	for _, raw := range taints {
+		taintMap, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		taint := corev1.Taint{}
+		taint.Key, _ = taintMap["key"].(string)
+		taint.Value, _ = taintMap["value"].(string)
+		effect, _ := taintMap["effect"].(string)
+		taint.Effect = corev1.TaintEffect(effect)
+		spec.Taints = append(spec.Taints, taint)
+	}
+
+	spec.Limits = resourceListFromMap(item.Object, "spec", "limits")
+	spec.Usage = resourceListFromMap(item.Object, "status", "resources")
+	return spec
+}
+
+// resourceListFromMap parses a map of resource name -> quantity string (or number) at the given path
+func resourceListFromMap(obj map[string]interface{}, fields ...string) corev1.ResourceList {
+	raw, found, _ := unstructured.NestedMap(obj, fields...)
+	if !found {
+		return nil
+	}
+	list := corev1.ResourceList{}
+	for name, value := range raw {
+		var str string
+		switch v := value.(type) {
+		case string:
+			str = v
+		case int64:
+			str = strconv.FormatInt(v, 10)
+		case float64:
+			str = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			continue
+		}
+		if qty, err := resource.ParseQuantity(str); err == nil {
+			list[corev1.ResourceName(name)] = qty
+		}
+	}
+	return list
+}
+
+// DiagnosePendingPods lists unschedulable pods and explains, per pod, why no node is being
+// provisioned by evaluating the pod against every NodePool and reading scheduler/Karpenter events.
+// An empty namespace means all namespaces.
+func (c *Client) DiagnosePendingPods(ctx context.Context, namespace string) ([]PendingPodDiagnosis, error) {
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "status.phase=Pending",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending pods: %w", err)
+	}
+
+	var pending []*corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodPending && pod.Spec.NodeName == "" && pod.DeletionTimestamp == nil {
+			pending = append(pending, pod)
+		}
+	}
+	if len(pending) == 0 {
+		return []PendingPodDiagnosis{}, nil
+	}
+
+	// Still report events if NodePools can't be read; the diagnosis says why they are missing
+	nodePools, nodePoolsErr := c.ListNodePoolSchedulingSpecs(ctx)
+	if nodePoolsErr != nil {
+		fmt.Printf("Warning: Failed to list NodePools for pending pod diagnosis: %v\n", nodePoolsErr)
+	}
+
+	events, err := c.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "involvedObject.kind=Pod",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pod events: %w", err)
+	}
+	eventsByPod := make(map[string][]corev1.Event)
+	for _, event := range events.Items {
+		if event.InvolvedObject.Kind != "Pod" {
+			continue
+		}
+		key := fmt.Sprintf("%s/%s", event.InvolvedObject.Namespace, event.InvolvedObject.Name)
+		eventsByPod[key] = append(eventsByPod[key], event)
+	}
+
+	diagnoses := make([]PendingPodDiagnosis, 0, len(pending))
+	for _, pod := range pending {
+		diagnoses = append(diagnoses, diagnosePendingPod(pod, nodePools, nodePoolsErr, eventsByPod[fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)]))
+	}
+
+	// Longest-pending first
+	sort.SliceStable(diagnoses, func(i, j int) bool {
+		return diagnoses[i].PendingSince < diagnoses[j].PendingSince
+	})
+	return diagnoses, nil
+}
+
+// diagnosePendingPod builds the diagnosis for a single pod. nodePoolsErr is why the NodePools
+// could not be listed, if they could not.
+func diagnosePendingPod(pod *corev1.Pod, nodePools []NodePoolSchedulingSpec, nodePoolsErr error, events []corev1.Event) PendingPodDiagnosis {
+	cpu, mem := podRequests(pod)
+	diag := PendingPodDiagnosis{
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+		GPU:       podGPUUnits(pod),
+		NodePools: []NodePoolEvaluation{},
+	}
+	if cpu > 0 {
+		diag.Requests.CPU = strconv.FormatFloat(cpu, 'f', -1, 64)
+	}
+	if mem > 0 {
+		diag.Requests.Memory = fmt.Sprintf("%.2fGi", mem)
+	}
+	for _, owner := range pod.OwnerReferences {
+		diag.WorkloadName = owner.Name
+		break
+	}
+	if !pod.CreationTimestamp.IsZero() {
+		diag.PendingSince = pod.CreationTimestamp.Format(time.RFC3339)
+	}
+
+	// Events, most recent first
+	sort.Slice(events, func(i, j int) bool {
+		return eventTime(events[i]).After(eventTime(events[j]))
+	})
+	var karpenterFailure string
+	for _, event := range events {
+		source := event.Source.Component
+		if source == "" {
+			source = event.ReportingController
+		}
+		info := PodEventInfo{
+			Type:    event.Type,
+			Reason:  event.Reason,
+			Message: event.Message,
+			Source:  source,
+			Count:   event.Count,
+		}
+		if t := eventTime(event); !t.IsZero() {
+			info.LastSeen = t.Format(time.RFC3339)
+		}
+
+		isKarpenter := strings.Contains(strings.ToLower(source), "karpenter")
+		switch {
+		case event.Reason == "Nominated" && diag.NominatedNodeClaim == "":
+			diag.NominatedNodeClaim = nominatedTarget(event.Message)
+		case event.Reason == "FailedScheduling" && !isKarpenter && diag.SchedulerMessage == "":
+			diag.SchedulerMessage = event.Message
+		case (event.Reason == "FailedProvisioning" || (event.Reason == "FailedScheduling" && isKarpenter)) && karpenterFailure == "":
+			karpenterFailure = event.Message
+		}
+		if event.Reason == "FailedScheduling" || event.Reason == "Nominated" || event.Reason == "FailedProvisioning" || isKarpenter {
+			diag.Events = append(diag.Events, info)
+		}
+	}
+
+	var compatible []string
+	limitsOnly := len(nodePools) > 0
+	for _, np := range nodePools {
+		eval := evaluatePodAgainstNodePool(pod, np, cpu, mem, diag.GPU)
+		diag.NodePools = append(diag.NodePools, eval)
+		if eval.Compatible {
+			compatible = append(compatible, np.Name)
+			limitsOnly = false
+		} else if len(eval.Reasons) == 0 || !strings.Contains(eval.Reasons[0], "limits exhausted") {
+			limitsOnly = false
+		}
+	}
+
+	switch {
+	case diag.NominatedNodeClaim != "":
+		diag.Category = "nominated"
+		diag.Explanation = fmt.Sprintf("Karpenter nominated %s for this pod; waiting for the node to launch and become ready.", diag.NominatedNodeClaim)
+	case nodePoolsErr != nil:
+		diag.Category = "nodepools-unavailable"
+		diag.Explanation = fmt.Sprintf("NodePools could not be read, so they were not checked against this pod: %v", nodePoolsErr)
+	case len(nodePools) == 0:
+		diag.Category = "no-compatible-nodepool"
+		diag.Explanation = "No Karpenter NodePools were found, so nothing will be provisioned for this pod."
+	case len(compatible) == 0 && limitsOnly:
+		diag.Category = "limits-exhausted"
+		diag.Explanation = "Every NodePool that could run this pod has exhausted its limits: " + summarizeEvaluations(diag.NodePools)
+	case len(compatible) == 0:
+		diag.Category = "no-compatible-nodepool"
+		diag.Explanation = "No NodePool can launch a node for this pod: " + summarizeEvaluations(diag.NodePools)
+	case karpenterFailure != "":
+		diag.Category = "provisioning-failed"
+		diag.Explanation = fmt.Sprintf("NodePool(s) %s are compatible but Karpenter reported: %s", strings.Join(compatible, ", "), karpenterFailure)
+	default:
+		diag.Category = "awaiting-provisioning"
+		diag.Explanation = fmt.Sprintf("NodePool(s) %s are compatible; Karpenter should provision a node shortly. Check Karpenter logs if the pod stays pending.", strings.Join(compatible, ", "))
+	}
+	return diag
+}
+
+// evaluatePodAgainstNodePool checks taints, requirements (including arch) and limits
+func evaluatePodAgainstNodePool(pod *corev1.Pod, np NodePoolSchedulingSpec, cpu, mem float64, gpu int) NodePoolEvaluation {
+	eval := NodePoolEvaluation{NodePool: np.Name}
+
+	if taint := untoleratedTaint(pod.Spec.Tolerations, np.Taints); taint != nil {
+		eval.Reasons = append(eval.Reasons, fmt.Sprintf("pod does not tolerate taint %s=%s:%s", taint.Key, taint.Value, taint.Effect))
+	}
+
+	eval.Reasons = append(eval.Reasons, podRequirementConflicts(&pod.Spec, np)...)
+
+	if np.Limits != nil {
+		checks := []struct {
+			name      corev1.ResourceName
+			requested float64
+		}{
+			{corev1.ResourceCPU, cpu},
+			{corev1.ResourceMemory, mem * 1024 * 1024 * 1024},
+			{ResourceNvidiaGPU, float64(gpu)},
+		}
+		for _, check := range checks {
+			limit, ok := np.Limits[check.name]
+			if !ok || check.requested == 0 {
+				continue
+			}
+			used := np.Usage[check.name]
+			if used.AsApproximateFloat64()+check.requested > limit.AsApproximateFloat64() {
+				eval.Reasons = append(eval.Reasons, fmt.Sprintf("NodePool %s limits exhausted (%s %s/%s used)", np.Name, check.name, used.String(), limit.String()))
+			}
+		}
+	}
+
+	eval.Compatible = len(eval.Reasons) == 0
+	return eval
+}
+
+// podRequirementConflicts compares a pod's node selector and required node affinity with
+// what a NodePool can produce. Required affinity terms are ORed, so a conflict is only
+// reported when every term conflicts.
+func podRequirementConflicts(podSpec *corev1.PodSpec, np NodePoolSchedulingSpec) []string {
+	var conflicts []string
+	for key, value := range podSpec.NodeSelector {
+		if reason := requirementConflict(corev1.NodeSelectorRequirement{Key: key, Operator: corev1.NodeSelectorOpIn, Values: []string{value}}, np); reason != "" {
+			conflicts = append(conflicts, reason)
+		}
+	}
+
+	if podSpec.Affinity == nil || podSpec.Affinity.NodeAffinity == nil ||
+		podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return conflicts
+	}
+	var firstTermConflict string
+	for _, term := range podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		termConflict := ""
+		for _, expr := range term.MatchExpressions {
+			if reason := requirementConflict(expr, np); reason != "" {
+				termConflict = reason
+				break
+			}
+		}
+		if termConflict == "" {
+			return conflicts
+		}
+		if firstTermConflict == "" {
+			firstTermConflict = termConflict
+		}
+	}
+	if firstTermConflict != "" {
+		conflicts = append(conflicts, firstTermConflict)
+	}
+	return conflicts
+}
+
+// requirementConflict returns why a pod requirement can't be met by nodes from the NodePool, or ""
+func requirementConflict(podReq corev1.NodeSelectorRequirement, np NodePoolSchedulingSpec) string {
+	// Template labels are fixed values on every node
+	if value, ok := np.Labels[podReq.Key]; ok {
+		if !matchesSelectorRequirement(podReq, map[string]string{podReq.Key: value}) {
+			return fmt.Sprintf("pod requires %s %s %v but NodePool labels nodes %s=%s", podReq.Key, podReq.Operator, podReq.Values, podReq.Key, value)
+		}
+		return ""
+	}
+
+	var npReq *corev1.NodeSelectorRequirement
+	for i := range np.Requirements {
+		if np.Requirements[i].Key == podReq.Key {
+			npReq = &np.Requirements[i]
+			break
+		}
+	}
+
+	if npReq == nil {
+		// Karpenter only sets well-known labels (arch, zone, instance type...) or ones the NodePool defines
+		if isWellKnownLabel(podReq.Key) {
+			return ""
+		}
+		switch podReq.Operator {
+		case corev1.NodeSelectorOpIn, corev1.NodeSelectorOpExists, corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
+			return fmt.Sprintf("pod requires label %s which the NodePool does not define", podReq.Key)
+		}
+		return ""
+	}
+
+	compatible := true
+	switch npReq.Operator {
+	case corev1.NodeSelectorOpIn:
+		// At least one allowed value must satisfy the pod
+		compatible = false
+		for _, v := range npReq.Values {
+			if matchesSelectorRequirement(podReq, map[string]string{podReq.Key: v}) {
+				compatible = true
+				break
+			}
+		}
+	case corev1.NodeSelectorOpNotIn:
+		if podReq.Operator == corev1.NodeSelectorOpIn {
+			compatible = false
+			for _, v := range podReq.Values {
+				if !contains(npReq.Values, v) {
+					compatible = true
+					break
+				}
+			}
+		}
+	case corev1.NodeSelectorOpExists:
+		compatible = podReq.Operator != corev1.NodeSelectorOpDoesNotExist
+	case corev1.NodeSelectorOpDoesNotExist:
+		compatible = podReq.Operator == corev1.NodeSelectorOpDoesNotExist || podReq.Operator == corev1.NodeSelectorOpNotIn
+	case corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
+		if podReq.Operator == corev1.NodeSelectorOpIn {
+			compatible = false
+			for _, v := range podReq.Values {
+				if matchesSelectorRequirement(*npReq, map[string]string{podReq.Key: v}) {
+					compatible = true
+					break
+				}
+			}
+		}
+	}
+	if compatible {
+		return ""
+	}
+
+	if podReq.Key == "kubernetes.io/arch" {
+		return fmt.Sprintf("architecture mismatch: pod requires %v, NodePool allows %s %v", podReq.Values, npReq.Operator, npReq.Values)
+	}
+	return fmt.Sprintf("pod requires %s %s %v but NodePool allows %s %v", podReq.Key, podReq.Operator, podReq.Values, npReq.Operator, npReq.Values)
+}
+
+// isWellKnownLabel reports whether Karpenter can set a label without the NodePool defining it
+func isWellKnownLabel(key string) bool {
+	for _, prefix := range wellKnownLabelPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// summarizeEvaluations renders the first reason of each incompatible NodePool
+func summarizeEvaluations(evals []NodePoolEvaluation) string {
+	var parts []string
+	for _, eval := range evals {
+		if !eval.Compatible && len(eval.Reasons) > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %s", eval.NodePool, eval.Reasons[0]))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// nominatedTarget extracts the node/nodeclaim from a Karpenter Nominated event
+// (e.g., "Pod should schedule on: nodeclaim/default-abcde")
+func nominatedTarget(message string) string {
+	if idx := strings.Index(message, ":"); idx >= 0 {
+		target := strings.TrimSpace(message[idx+1:])
+		if fields := strings.Fields(target); len(fields) > 0 {
+			return strings.TrimSuffix(fields[0], ",")
+		}
+	}
+	return message
+}
+
+// eventTime returns the most precise timestamp available on an event
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time
+	}
+	return event.CreationTimestamp.Time
+}
@@ -0,0 +1,126 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestParseNodePoolSchedulingSpec(t *testing.T) {
+	item := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "gpu"},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{"team": "ml"}},
+				"spec": map[string]interface{}{
+					"requirements": []interface{}{
+						map[string]interface{}{"key": "kubernetes.io/arch", "operator": "In", "values": []interface{}{"amd64"}},
+					},
+					"taints": []interface{}{
+						map[string]interface{}{"key": "nvidia.com/gpu", "effect": "NoSchedule"},
+					},
+				},
+			},
+			"limits": map[string]interface{}{"cpu": "100", "nvidia.com/gpu": int64(8)},
+		},
+		"status": map[string]interface{}{"resources": map[string]interface{}{"cpu": "40", "nvidia.com/gpu": "8"}},
+	}}
+
+	spec := parseNodePoolSchedulingSpec(item)
+	assert.Equal(t, "gpu", spec.Name)
+	assert.Equal(t, "ml", spec.Labels["team"])
+	require.Len(t, spec.Requirements, 1)
+	assert.Equal(t, []string{"amd64"}, spec.Requirements[0].Values)
+	require.Len(t, spec.Taints, 1)
+	assert.Equal(t, corev1.TaintEffectNoSchedule, spec.Taints[0].Effect)
+	gpuLimit := spec.Limits[ResourceNvidiaGPU]
+	assert.Equal(t, int64(8), gpuLimit.Value())
+	cpuUsage := spec.Usage[corev1.ResourceCPU]
+	assert.Equal(t, int64(40), cpuUsage.Value())
+}
+
+func TestDiagnosePendingPod(t *testing.T) {
+	general := NodePoolSchedulingSpec{
+		Name: "general",
+		Requirements: []corev1.NodeSelectorRequirement{
+			{Key: "kubernetes.io/arch", Operator: corev1.NodeSelectorOpIn, Values: []string{"arm64"}},
+		},
+	}
+	gpu := NodePoolSchedulingSpec{
+		Name:   "gpu",
+		Taints: []corev1.Taint{{Key: "nvidia.com/gpu", Effect: corev1.TaintEffectNoSchedule}},
+		Limits: corev1.ResourceList{ResourceNvidiaGPU: resource.MustParse("8")},
+		Usage:  corev1.ResourceList{ResourceNvidiaGPU: resource.MustParse("8")},
+	}
+	gpuToleration := corev1.Toleration{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists}
+
+	amd64Pod := func() *corev1.Pod {
+		pod := testPod("app", "default", "", "1", "1Gi", nil)
+		pod.Status.Phase = corev1.PodPending
+		pod.Spec.NodeSelector = map[string]string{"kubernetes.io/arch": "amd64"}
+		return pod
+	}
+
+	t.Run("no NodePool tolerates or matches", func(t *testing.T) {
+		diag := diagnosePendingPod(amd64Pod(), []NodePoolSchedulingSpec{general, gpu}, nil, nil)
+		assert.Equal(t, "no-compatible-nodepool", diag.Category)
+		require.Len(t, diag.NodePools, 2)
+		assert.Contains(t, diag.NodePools[0].Reasons[0], "architecture mismatch")
+		assert.Contains(t, diag.NodePools[1].Reasons[0], "does not tolerate taint nvidia.com/gpu")
+	})
+
+	t.Run("gpu limits exhausted", func(t *testing.T) {
+		pod := amd64Pod()
+		pod.Spec.Tolerations = []corev1.Toleration{gpuToleration}
+		pod.Spec.Containers[0].Resources.Limits = corev1.ResourceList{ResourceNvidiaGPU: resource.MustParse("1")}
+		diag := diagnosePendingPod(pod, []NodePoolSchedulingSpec{gpu}, nil, nil)
+		assert.Equal(t, "limits-exhausted", diag.Category)
+		assert.Contains(t, diag.Explanation, "NodePool gpu limits exhausted")
+	})
+
+	t.Run("nominated by karpenter", func(t *testing.T) {
+		pod := amd64Pod()
+		pod.Spec.Tolerations = []corev1.Toleration{gpuToleration}
+		events := []corev1.Event{
+			{Reason: "FailedScheduling", Message: "0/3 nodes are available", Source: corev1.EventSource{Component: "default-scheduler"}},
+			{Reason: "Nominated", Message: "Pod should schedule on: nodeclaim/gpu-abcde", Source: corev1.EventSource{Component: "karpenter"}},
+		}
+		diag := diagnosePendingPod(pod, []NodePoolSchedulingSpec{gpu}, nil, events)
+		assert.Equal(t, "nominated", diag.Category)
+		assert.Equal(t, "nodeclaim/gpu-abcde", diag.NominatedNodeClaim)
+		assert.Equal(t, "0/3 nodes are available", diag.SchedulerMessage)
+		assert.Len(t, diag.Events, 2)
+	})
+}
+
+func TestDiagnosePendingPodsNodePoolsUnavailable(t *testing.T) {
+	pod := testPod("app", "default", "", "1", "1Gi", nil)
+	pod.Status.Phase = corev1.PodPending
+	listKinds := map[schema.GroupVersionResource]string{}
+	for _, version := range []string{"v1", "v1beta1", "v1alpha1"} {
+		listKinds[schema.GroupVersionResource{Group: "karpenter.sh", Version: version, Resource: "nodepools"}] = "NodePoolList"
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+	dynamicClient.PrependReactor("list", "nodepools", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("nodepools.karpenter.sh is forbidden")
+	})
+	client := NewClientFromInterfaces(fake.NewSimpleClientset(pod), dynamicClient)
+
+	diagnoses, err := client.DiagnosePendingPods(context.Background(), "")
+	require.NoError(t, err)
+	require.Len(t, diagnoses, 1)
+	assert.Equal(t, "nodepools-unavailable", diagnoses[0].Category)
+	assert.Contains(t, diagnoses[0].Explanation, "nodepools.karpenter.sh is forbidden")
+	assert.NotContains(t, diagnoses[0].Explanation, "No Karpenter NodePools were found")
+}
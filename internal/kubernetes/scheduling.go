@@ -0,0 +1,154 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// podRequests returns the CPU (cores) and memory (GiB) requested by a pod's regular containers.
+// Init containers are excluded to match node usage calculation (eks-node-viewer style).
+func podRequests(pod *corev1.Pod) (float64, float64) {
+	var cpu, mem float64
+	for _, container := range pod.Spec.Containers {
+		if cpuReq := container.Resources.Requests[corev1.ResourceCPU]; !cpuReq.IsZero() {
+			cpu += float64(cpuReq.MilliValue()) / 1000.0
+		}
+		if memReq := container.Resources.Requests[corev1.ResourceMemory]; !memReq.IsZero() {
+			mem += float64(memReq.Value()) / (1024.0 * 1024.0 * 1024.0)
+		}
+	}
+	return cpu, mem
+}
+
+// isDaemonSetPod reports whether a pod is owned by a DaemonSet
+func isDaemonSetPod(pod *corev1.Pod) bool {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
+
+// toleratesTaint reports whether a toleration matches a taint
+func toleratesTaint(toleration corev1.Toleration, taint corev1.Taint) bool {
+	if toleration.Effect != "" && toleration.Effect != taint.Effect {
+		return false
+	}
+	if toleration.Key == "" {
+		// Empty key with Exists tolerates everything
+		return toleration.Operator == corev1.TolerationOpExists
+	}
+	if toleration.Key != taint.Key {
+		return false
+	}
+	switch toleration.Operator {
+	case corev1.TolerationOpExists:
+		return true
+	case corev1.TolerationOpEqual, "":
+		return toleration.Value == taint.Value
+	}
+	return false
+}
+
+// untoleratedTaint returns the first NoSchedule/NoExecute taint not tolerated by the pod, or nil
+func untoleratedTaint(tolerations []corev1.Toleration, taints []corev1.Taint) *corev1.Taint {
+	for i := range taints {
+		taint := taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for _, toleration := range tolerations {
+			if toleratesTaint(toleration, taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return &taint
+		}
+	}
+	return nil
+}
+
+// UnmetRequirement returns the first NodePool requirement the labels of a prospective node
+// violate, or nil. Requirements on keys the labels don't set are skipped, so callers can
+// check just the labels they know (instance type, family, architecture...).
+func (np NodePoolInfo) UnmetRequirement(nodeLabels map[string]string) *corev1.NodeSelectorRequirement {
+	for i, req := range np.NodeRequirements {
+		if _, ok := nodeLabels[req.Key]; !ok {
+			continue
+		}
+		if !matchesSelectorRequirement(req, nodeLabels) {
+			return &np.NodeRequirements[i]
+		}
+	}
+	return nil
+}
+
+// matchesSelectorRequirement evaluates a node selector requirement against a label set
+func matchesSelectorRequirement(req corev1.NodeSelectorRequirement, nodeLabels map[string]string) bool {
+	value, exists := nodeLabels[req.Key]
+	switch req.Operator {
+	case corev1.NodeSelectorOpIn:
+		return exists && contains(req.Values, value)
+	case corev1.NodeSelectorOpNotIn:
+		return !exists || !contains(req.Values, value)
+	case corev1.NodeSelectorOpExists:
+		return exists
+	case corev1.NodeSelectorOpDoesNotExist:
+		return !exists
+	case corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
+		if !exists || len(req.Values) == 0 {
+			return false
+		}
+		actual, err1 := strconv.ParseInt(value, 10, 64)
+		bound, err2 := strconv.ParseInt(req.Values[0], 10, 64)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		if req.Operator == corev1.NodeSelectorOpGt {
+			return actual > bound
+		}
+		return actual < bound
+	}
+	return false
+}
+
+// podNodeSelectorMismatch checks a pod's nodeSelector and required node affinity against node labels.
+// Returns an empty string when the labels satisfy the pod, otherwise a short reason.
+func podNodeSelectorMismatch(podSpec *corev1.PodSpec, nodeLabels map[string]string) string {
+	for key, value := range podSpec.NodeSelector {
+		if nodeLabels[key] != value {
+			return fmt.Sprintf("nodeSelector %s=%s not matched", key, value)
+		}
+	}
+
+	if podSpec.Affinity == nil || podSpec.Affinity.NodeAffinity == nil ||
+		podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return ""
+	}
+
+	// Terms are ORed, expressions within a term are ANDed
+	terms := podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	reason := ""
+	for _, term := range terms {
+		matched := true
+		for _, expr := range term.MatchExpressions {
+			if !matchesSelectorRequirement(expr, nodeLabels) {
+				matched = false
+				if reason == "" {
+					reason = fmt.Sprintf("node affinity %s %s %v not matched", expr.Key, expr.Operator, expr.Values)
+				}
+				break
+			}
+		}
+		if matched {
+			return ""
+		}
+	}
+	return reason
+}
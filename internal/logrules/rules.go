@@ -0,0 +1,270 @@
+// Package logrules categorizes Karpenter error messages using regex rules loaded from YAML.
+// Default rules are embedded; a user rule file can override, disable or extend them.
+package logrules
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed default_rules.yaml
+var defaultRulesYAML []byte
+
+// Severity levels
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+// UnknownCategory is reported when no rule matches
+const UnknownCategory = "Unknown"
+
+// Rule categorizes error messages matching any of its regexes
+type Rule struct {
+	ID          string   `json:"id"`
+	Category    string   `json:"category"`
+	Severity    string   `json:"severity"`
+	Match       []string `json:"match"`             // Any match selects the rule
+	Capture     []string `json:"capture,omitempty"` // Extra regexes only used for named groups
+	Explanation string   `json:"explanation"`       // text/template over named groups
+	Remediation []string `json:"remediation,omitempty"`
+	Disabled    bool     `json:"disabled,omitempty"` // In override files: remove the default rule with this ID
+
+	match       []*regexp.Regexp
+	capture     []*regexp.Regexp
+	explanation *template.Template
+}
+
+// RuleFile is the YAML document holding rules
+type RuleFile struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Result is the categorization of a single error message
+type Result struct {
+	RuleID      string            `json:"ruleId,omitempty"`
+	Category    string            `json:"category"`
+	Severity    string            `json:"severity"`
+	Explanation string            `json:"explanation"`
+	Remediation []string          `json:"remediation,omitempty"`
+	Captures    map[string]string `json:"captures,omitempty"`
+}
+
+// Engine evaluates rules in order; the first matching rule wins
+type Engine struct {
+	rules []*Rule
+}
+
+var (
+	defaultEngine     *Engine
+	defaultEngineOnce sync.Once
+)
+
+// Default returns an engine with only the embedded rules
+func Default() *Engine {
+	defaultEngineOnce.Do(func() {
+		rules, err := Parse(defaultRulesYAML)
+		if err != nil {
+			// Embedded rules are covered by tests; failing here is a build defect
+			panic(fmt.Sprintf("invalid embedded log rules: %v", err))
+		}
+		defaultEngine = &Engine{rules: rules}
+	})
+	return defaultEngine
+}
+
+// Load returns the embedded rules merged with the rules in overridePath (if set)
+func Load(overridePath string) (*Engine, error) {
+	if overridePath == "" {
+		return Default(), nil
+	}
+
+	data, err := os.ReadFile(overridePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log rules file: %w", err)
+	}
+	overrides, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load log rules from %s: %w", overridePath, err)
+	}
+	return Merge(Default().rules, overrides), nil
+}
+
+// Merge applies override rules to base rules: same ID replaces the base rule in place,
+// Disabled removes it, and new IDs are evaluated before all base rules
+func Merge(base, overrides []*Rule) *Engine {
+	byID := make(map[string]*Rule, len(overrides))
+	var added []*Rule
+	baseIDs := make(map[string]bool, len(base))
+	for _, rule := range base {
+		baseIDs[rule.ID] = true
+	}
+	for _, rule := range overrides {
+		if baseIDs[rule.ID] {
+			byID[rule.ID] = rule
+		} else if !rule.Disabled {
+			added = append(added, rule)
+		}
+	}
+
+	rules := append([]*Rule{}, added...)
+	for _, rule := range base {
+		if override, ok := byID[rule.ID]; ok {
+			if override.Disabled {
+				continue
+			}
+			rule = override
+		}
+		rules = append(rules, rule)
+	}
+	return &Engine{rules: rules}
+}
+
+// Parse parses and validates a YAML rule file
+func Parse(data []byte) ([]*Rule, error) {
+	var file RuleFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	rules := make([]*Rule, 0, len(file.Rules))
+	for i := range file.Rules {
+		rule := file.Rules[i]
+		if rule.ID == "" {
+			return nil, fmt.Errorf("rule %d: id is required", i)
+		}
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("rule %s: duplicate id", rule.ID)
+		}
+		seen[rule.ID] = true
+		if rule.Disabled {
+			rules = append(rules, &rule)
+			continue
+		}
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+		}
+		rules = append(rules, &rule)
+	}
+	return rules, nil
+}
+
+// compile validates a rule and prepares its regexes and template
+func (r *Rule) compile() error {
+	if r.Category == "" {
+		return fmt.Errorf("category is required")
+	}
+	switch r.Severity {
+	case SeverityCritical, SeverityWarning, SeverityInfo:
+	default:
+		return fmt.Errorf("severity must be one of critical, warning, info (got %q)", r.Severity)
+	}
+	if len(r.Match) == 0 {
+		return fmt.Errorf("at least one match regex is required")
+	}
+
+	for _, expr := range r.Match {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid match regex %q: %w", expr, err)
+		}
+		r.match = append(r.match, re)
+	}
+	for _, expr := range r.Capture {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid capture regex %q: %w", expr, err)
+		}
+		r.capture = append(r.capture, re)
+	}
+
+	tmpl, err := template.New(r.ID).Option("missingkey=zero").Parse(strings.TrimSpace(r.Explanation))
+	if err != nil {
+		return fmt.Errorf("invalid explanation template: %w", err)
+	}
+	r.explanation = tmpl
+	return nil
+}
+
+// Rules returns the active rules in evaluation order
+func (e *Engine) Rules() []Rule {
+	rules := make([]Rule, 0, len(e.rules))
+	for _, rule := range e.rules {
+		rules = append(rules, *rule)
+	}
+	return rules
+}
+
+// Categorize returns the result of the first rule matching the message
+func (e *Engine) Categorize(message string) Result {
+	for _, rule := range e.rules {
+		captures, ok := rule.evaluate(message)
+		if !ok {
+			continue
+		}
+
+		explanation := rule.Explanation
+		var sb strings.Builder
+		if err := rule.explanation.Execute(&sb, captures); err == nil {
+			explanation = sb.String()
+		}
+		return Result{
+			RuleID:      rule.ID,
+			Category:    rule.Category,
+			Severity:    rule.Severity,
+			Explanation: explanation,
+			Remediation: rule.Remediation,
+			Captures:    captures,
+		}
+	}
+
+	return Result{
+		Category:    UnknownCategory,
+		Severity:    SeverityInfo,
+		Explanation: "Unable to categorize this error. Review the error message for details.",
+	}
+}
+
+// evaluate reports whether the rule matches and collects named groups
+func (r *Rule) evaluate(message string) (map[string]string, bool) {
+	captures := make(map[string]string)
+	matched := false
+	for _, re := range r.match {
+		if collectGroups(re, message, captures) {
+			matched = true
+		}
+	}
+	if !matched {
+		return nil, false
+	}
+	for _, re := range r.capture {
+		collectGroups(re, message, captures)
+	}
+	return captures, true
+}
+
+// collectGroups adds non-empty named groups from the first match; earlier captures win
+func collectGroups(re *regexp.Regexp, message string, captures map[string]string) bool {
+	match := re.FindStringSubmatch(message)
+	if match == nil {
+		return false
+	}
+	for i, name := range re.SubexpNames() {
+		if name == "" || captures[name] != "" {
+			continue
+		}
+		if value := strings.TrimSpace(match[i]); value != "" {
+			captures[name] = value
+		}
+	}
+	return true
+}
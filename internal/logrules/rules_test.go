@@ -0,0 +1,113 @@
+package logrules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+type corpusEntry struct {
+	Rule     string            `json:"rule"`
+	Line     string            `json:"line"`
+	Captures map[string]string `json:"captures"`
+}
+
+func TestDefaultRulesCorpus(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "corpus.yaml"))
+	require.NoError(t, err)
+	var corpus []corpusEntry
+	require.NoError(t, yaml.Unmarshal(data, &corpus))
+	require.NotEmpty(t, corpus)
+
+	engine := Default()
+	covered := make(map[string]bool)
+	for _, entry := range corpus {
+		t.Run(entry.Line, func(t *testing.T) {
+			result := engine.Categorize(entry.Line)
+			assert.Equal(t, entry.Rule, result.RuleID)
+			for name, value := range entry.Captures {
+				assert.Equal(t, value, result.Captures[name], "capture %s", name)
+				assert.Contains(t, result.Explanation, value, "explanation should use capture %s", name)
+			}
+			if entry.Rule == "" {
+				assert.Equal(t, UnknownCategory, result.Category)
+			} else {
+				assert.NotEmpty(t, result.Remediation)
+			}
+			assert.NotContains(t, result.Explanation, "<no value>")
+		})
+		covered[entry.Rule] = true
+	}
+
+	// Every default rule needs at least one sample line
+	for _, rule := range engine.Rules() {
+		assert.True(t, covered[rule.ID], "no corpus line for rule %s", rule.ID)
+	}
+}
+
+func TestParseValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		err  string
+	}{
+		{"missing id", "rules:\n- category: X\n  severity: info\n  match: ['x']", "id is required"},
+		{"bad severity", "rules:\n- id: a\n  category: X\n  severity: high\n  match: ['x']", "severity"},
+		{"no match", "rules:\n- id: a\n  category: X\n  severity: info", "match regex is required"},
+		{"bad regex", "rules:\n- id: a\n  category: X\n  severity: info\n  match: ['(']", "invalid match regex"},
+		{"bad template", "rules:\n- id: a\n  category: X\n  severity: info\n  match: ['x']\n  explanation: '{{.x'", "invalid explanation template"},
+		{"duplicate", "rules:\n- id: a\n  disabled: true\n- id: a\n  disabled: true", "duplicate id"},
+		{"unknown field", "rules:\n- id: a\n  category: X\n  severity: info\n  matches: ['x']", "unknown field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.yaml))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+rules:
+  - id: strimzi
+    category: Strimzi Drain Cleaner
+    severity: info
+    match: ['strimzi-drainer']
+    explanation: Expected during Kafka rolls
+  - id: insufficient-capacity
+    category: ICE
+    severity: critical
+    match: ['InsufficientInstanceCapacity']
+    explanation: 'ICE for {{.type}}'
+    capture: ['sufficient (?P<type>\S+) capacity']
+  - id: resource-constraint
+    disabled: true
+`), 0o600))
+
+	engine, err := Load(path)
+	require.NoError(t, err)
+
+	// New rules take precedence over defaults
+	assert.Equal(t, "strimzi", engine.Categorize(`admission webhook "strimzi-drainer.strimzi.io" denied the request`).RuleID)
+
+	// Overridden rule keeps its position but uses the new definition
+	result := engine.Categorize("InsufficientInstanceCapacity: We currently do not have sufficient p4d.24xlarge capacity")
+	assert.Equal(t, "ICE", result.Category)
+	assert.Equal(t, "ICE for p4d.24xlarge", result.Explanation)
+
+	// Disabled rule no longer matches
+	assert.Equal(t, UnknownCategory, engine.Categorize("not enough memory").Category)
+
+	// Defaults are untouched
+	assert.Equal(t, "Resource Constraint", Default().Categorize("not enough memory").Category)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,163 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+const (
+	anthropicVersion   = "2023-06-01"
+	anthropicMaxTokens = 4096
+)
+
+// anthropicProvider speaks the Anthropic Messages API. It has no JSON mode: ChatJSON
+// already puts the schema in the prompt and validates and repairs the reply.
+type anthropicProvider struct {
+	httpProvider
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	System    string             `json:"system,omitempty"`
+	Messages  []anthropicMessage `json:"messages"`
+	Tools     []anthropicTool    `json:"tools,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicBlock is a text, tool_use or tool_result content block
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`          // tool_use
+	Name      string          `json:"name,omitempty"`        // tool_use
+	Input     json.RawMessage `json:"input,omitempty"`       // tool_use
+	ToolUseID string          `json:"tool_use_id,omitempty"` // tool_result
+	Content   string          `json:"content,omitempty"`     // tool_result
+}
+
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type anthropicResponse struct {
+	ID         string           `json:"id"`
+	Model      string           `json:"model"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// toAnthropicRequest converts a chat request: system messages become the system prompt,
+// tool calls become tool_use blocks and tool messages tool_result blocks of a user turn
+func (p *anthropicProvider) toAnthropicRequest(params ChatParams) (anthropicRequest, error) {
+	req := anthropicRequest{Model: p.model, MaxTokens: anthropicMaxTokens}
+	var system []string
+	for _, m := range params.Messages {
+		var role string
+		var blocks []anthropicBlock
+		switch m.Role {
+		case "system":
+			system = append(system, m.Content)
+			continue
+		case "tool":
+			role = "user"
+			blocks = []anthropicBlock{{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.Content}}
+		case "assistant":
+			role = "assistant"
+			if m.Content != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: m.Content})
+			}
+			for _, call := range m.ToolCalls {
+				input := json.RawMessage(call.Function.Arguments)
+				if len(input) == 0 {
+					input = json.RawMessage("{}")
+				}
+				if !json.Valid(input) {
+					return req, fmt.Errorf("invalid arguments for tool call %s: %s", call.Function.Name, call.Function.Arguments)
+				}
+				blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: input})
+			}
+		default:
+			role = "user"
+			blocks = []anthropicBlock{{Type: "text", Text: m.Content}}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		// Consecutive turns of the same role (e.g. several tool results) are merged
+		if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == role {
+			req.Messages[n-1].Content = append(req.Messages[n-1].Content, blocks...)
+			continue
+		}
+		req.Messages = append(req.Messages, anthropicMessage{Role: role, Content: blocks})
+	}
+	req.System = strings.Join(system, "\n\n")
+
+	for _, tool := range params.Tools {
+		schema := tool.Function.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object"}
+		}
+		req.Tools = append(req.Tools, anthropicTool{Name: tool.Function.Name, Description: tool.Function.Description, InputSchema: schema})
+	}
+	return req, nil
+}
+
+func (p *anthropicProvider) Chat(ctx context.Context, params ChatParams) (*Message, error) {
+	reqBody, err := p.toAnthropicRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{"anthropic-version": anthropicVersion}
+	if p.apiKey != "" {
+		headers["x-api-key"] = p.apiKey
+	}
+
+	baseURL := strings.TrimSuffix(strings.TrimSuffix(p.baseURL, "/v1/messages"), "/")
+	bodyBytes, err := p.post(ctx, fmt.Sprintf("%s/v1/messages", baseURL), headers, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	var resp anthropicResponse
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return nil, p.decodeError(err, bodyBytes)
+	}
+
+	message := Message{Role: "assistant"}
+	var text []string
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text = append(text, block.Text)
+		case "tool_use":
+			args := string(block.Input)
+			if args == "" || args == "null" {
+				args = "{}"
+			}
+			message.ToolCalls = append(message.ToolCalls, ToolCall{
+				ID:       block.ID,
+				Type:     "function",
+				Function: ToolCallFunction{Name: block.Name, Arguments: args},
+			})
+		}
+	}
+	message.Content = strings.Join(text, "")
+	if p.debug {
+		log.Printf("[LLM] Anthropic response received: %d input / %d output tokens, response length: %d characters, %d tool call(s)",
+			resp.Usage.InputTokens, resp.Usage.OutputTokens, len(message.Content), len(message.ToolCalls))
+	}
+	return &message, nil
+}
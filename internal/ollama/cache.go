@@ -0,0 +1,345 @@
+package ollama
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of the response cache bounds
+const (
+	DefaultCacheTTL        = 24 * time.Hour
+	DefaultCacheMaxEntries = 500
+	DefaultCacheMaxBytes   = 8 << 20
+)
+
+// cachePersistDelay is how long changes are batched before the cache file is rewritten
+const cachePersistDelay = 5 * time.Second
+
+type cacheBypassKey struct{}
+
+// WithoutCache returns a context whose LLM requests skip the response cache lookup. The
+// fresh replies are still stored, so a bypassed request also refreshes the cache.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// ResponseCache caches LLM replies keyed by the model and a hash of the request with its
+// whitespace normalized, so repeated prompts are answered without calling the LLM. Entries
+// expire after the TTL, the least recently used are evicted beyond the entry and byte
+// bounds, and the cache is persisted to a JSON file when a path is set. Changes are written
+// cachePersistDelay after the first one, and by Flush.
+type ResponseCache struct {
+	mu         sync.Mutex
+	entries    map[string]*cacheEntry
+	bytes      int64
+	ttl        time.Duration
+	maxEntries int
+	maxBytes   int64
+	path       string
+
+	persistTimer *time.Timer // Pending write, nil when the file is up to date
+	writeMu      sync.Mutex  // Serializes file writes so an older snapshot never replaces a newer one
+
+	hits      int64
+	misses    int64
+	bypassed  int64
+	evictions int64
+	expired   int64
+}
+
+type cacheEntry struct {
+	Key      string    `json:"key"`
+	Model    string    `json:"model"`
+	Reply    Message   `json:"reply"`
+	StoredAt time.Time `json:"storedAt"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+// size approximates the memory an entry holds
+func (e *cacheEntry) size() int64 {
+	n := len(e.Key) + len(e.Model) + len(e.Reply.Content)
+	for _, call := range e.Reply.ToolCalls {
+		n += len(call.ID) + len(call.Function.Name) + len(call.Function.Arguments)
+	}
+	return int64(n)
+}
+
+// CacheStats are the response cache's size and hit/miss counters since startup
+type CacheStats struct {
+	Entries    int     `json:"entries"`
+	Bytes      int64   `json:"bytes"`
+	MaxEntries int     `json:"maxEntries"`
+	MaxBytes   int64   `json:"maxBytes"`
+	TTLSeconds float64 `json:"ttlSeconds"`
+	Hits       int64   `json:"hits"`
+	Misses     int64   `json:"misses"`
+	Bypassed   int64   `json:"bypassed"`  // Lookups skipped with WithoutCache
+	Evictions  int64   `json:"evictions"` // Entries dropped to stay within the bounds
+	Expired    int64   `json:"expired"`   // Entries dropped after the TTL
+	HitRate    float64 `json:"hitRate"`   // hits / (hits + misses)
+	Path       string  `json:"path,omitempty"`
+}
+
+// NewResponseCache creates a response cache. Non-positive bounds use the defaults, and
+// entries persisted at path (if set) are loaded.
+func NewResponseCache(ttl time.Duration, maxEntries int, maxBytes int64, path string) (*ResponseCache, error) {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheMaxEntries
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultCacheMaxBytes
+	}
+	cache := &ResponseCache{
+		entries:    make(map[string]*cacheEntry),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		path:       path,
+	}
+	if path == "" {
+		return cache, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cache, nil
+		}
+		return nil, fmt.Errorf("failed to read LLM cache: %w", err)
+	}
+	var entries []*cacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse LLM cache: %w", err)
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.Key == "" || now.Sub(entry.StoredAt) > ttl {
+			continue
+		}
+		cache.entries[entry.Key] = entry
+		cache.bytes += entry.size()
+	}
+	cache.evict()
+	return cache, nil
+}
+
+// cacheKey fingerprints a request: the model plus a hash of the request JSON with every
+// string's whitespace collapsed, so reformatted prompts share an entry
+func cacheKey(model string, reqBody interface{}) (string, error) {
+	data, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return "", err
+	}
+	// Maps are marshaled with sorted keys, so the normalized JSON is stable
+	normalized, err := json.Marshal(normalizeWhitespace(value))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(normalized)
+	return model + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+func normalizeWhitespace(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return strings.Join(strings.Fields(v), " ")
+	case []interface{}:
+		for i := range v {
+			v[i] = normalizeWhitespace(v[i])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = normalizeWhitespace(v[key])
+		}
+	}
+	return value
+}
+
+// get returns the cached reply for key, counting a hit or a miss
+func (c *ResponseCache) get(key string) (*Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if ok && time.Since(entry.StoredAt) > c.ttl {
+		c.remove(entry)
+		c.expired++
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	entry.LastUsed = time.Now()
+	reply := entry.Reply
+	return &reply, true
+}
+
+// bypass counts a lookup skipped with WithoutCache
+func (c *ResponseCache) bypass() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bypassed++
+}
+
+// put stores a reply and persists the cache
+func (c *ResponseCache) put(key, model string, reply Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.entries[key]; ok {
+		c.remove(old)
+	}
+	now := time.Now()
+	entry := &cacheEntry{Key: key, Model: model, Reply: reply, StoredAt: now, LastUsed: now}
+	c.entries[key] = entry
+	c.bytes += entry.size()
+	c.evict()
+	c.persist()
+}
+
+// forget drops an entry, e.g. a reply that failed validation
+func (c *ResponseCache) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok {
+		c.remove(entry)
+		c.persist()
+	}
+}
+
+// Clear drops every entry; the counters are kept
+func (c *ResponseCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*cacheEntry)
+	c.bytes = 0
+	c.persist()
+}
+
+// Stats returns the cache's size and counters
+func (c *ResponseCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := CacheStats{
+		Entries:    len(c.entries),
+		Bytes:      c.bytes,
+		MaxEntries: c.maxEntries,
+		MaxBytes:   c.maxBytes,
+		TTLSeconds: c.ttl.Seconds(),
+		Hits:       c.hits,
+		Misses:     c.misses,
+		Bypassed:   c.bypassed,
+		Evictions:  c.evictions,
+		Expired:    c.expired,
+		Path:       c.path,
+	}
+	if lookups := c.hits + c.misses; lookups > 0 {
+		stats.HitRate = float64(c.hits) / float64(lookups)
+	}
+	return stats
+}
+
+func (c *ResponseCache) remove(entry *cacheEntry) {
+	delete(c.entries, entry.Key)
+	c.bytes -= entry.size()
+}
+
+// evict drops expired entries, then the least recently used until the cache is within its bounds
+func (c *ResponseCache) evict() {
+	now := time.Now()
+	entries := make([]*cacheEntry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		if now.Sub(entry.StoredAt) > c.ttl {
+			c.remove(entry)
+			c.expired++
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) <= c.maxEntries && c.bytes <= c.maxBytes {
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LastUsed.Before(entries[j].LastUsed) })
+	for _, entry := range entries {
+		if len(c.entries) <= c.maxEntries && c.bytes <= c.maxBytes {
+			break
+		}
+		c.remove(entry)
+		c.evictions++
+	}
+}
+
+// persist schedules a write of the cache file; the caller holds c.mu
+func (c *ResponseCache) persist() {
+	if c.path == "" || c.persistTimer != nil {
+		return
+	}
+	c.persistTimer = time.AfterFunc(cachePersistDelay, c.Flush)
+}
+
+// Flush writes pending changes to the cache file now. Call it before exiting so the last
+// replies are kept across restarts.
+func (c *ResponseCache) Flush() {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.mu.Lock()
+	if c.persistTimer == nil {
+		c.mu.Unlock()
+		return
+	}
+	c.persistTimer.Stop()
+	c.persistTimer = nil
+	// Entries are copied since lookups update LastUsed after the lock is released
+	entries := make([]cacheEntry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		entries = append(entries, *entry)
+	}
+	c.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	c.write(entries)
+}
+
+// write replaces the cache file with entries; failures are logged since the cache is an optimization
+func (c *ResponseCache) write(entries []cacheEntry) {
+	err := func() error {
+		if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		data, err := json.Marshal(entries)
+		if err != nil {
+			return fmt.Errorf("failed to marshal entries: %w", err)
+		}
+		tmp := c.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0644); err != nil {
+			return fmt.Errorf("failed to write entries: %w", err)
+		}
+		return os.Rename(tmp, c.path)
+	}()
+	if err != nil {
+		log.Printf("[LLM] Failed to persist response cache: %v", err)
+	}
+}
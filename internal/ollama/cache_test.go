@@ -0,0 +1,219 @@
+package ollama_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/karpenter-optimizer/internal/ollama"
+	"github.com/karpenter-optimizer/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCachedClient(t *testing.T, url, model string, cache *ollama.ResponseCache) *ollama.Client {
+	t.Helper()
+	client := ollama.NewClient(url, model, "litellm", "", false)
+	client.SetCache(cache)
+	return client
+}
+
+func TestResponseCache(t *testing.T) {
+	t.Run("hit for the same normalized prompt", func(t *testing.T) {
+		server := testutil.NewFakeLLMServer(ollama.Message{Role: "assistant", Content: "first"})
+		defer server.Close()
+		cache, err := ollama.NewResponseCache(time.Hour, 10, 0, "")
+		require.NoError(t, err)
+		client := newCachedClient(t, server.URL, "test-model", cache)
+
+		reply, err := client.Chat(context.Background(), "Summarize  the cluster:\n  3 NodePools")
+		require.NoError(t, err)
+		assert.Equal(t, "first", reply)
+		reply, err = client.Chat(context.Background(), "Summarize the cluster: 3 NodePools\n")
+		require.NoError(t, err)
+		assert.Equal(t, "first", reply)
+
+		assert.Len(t, server.Requests(), 1)
+		stats := cache.Stats()
+		assert.Equal(t, int64(1), stats.Hits)
+		assert.Equal(t, int64(1), stats.Misses)
+		assert.InDelta(t, 0.5, stats.HitRate, 1e-9)
+		assert.Equal(t, 1, stats.Entries)
+	})
+
+	t.Run("miss for another model or prompt", func(t *testing.T) {
+		server := testutil.NewFakeLLMServer(
+			ollama.Message{Role: "assistant", Content: "a"},
+			ollama.Message{Role: "assistant", Content: "b"},
+			ollama.Message{Role: "assistant", Content: "c"},
+		)
+		defer server.Close()
+		cache, err := ollama.NewResponseCache(time.Hour, 10, 0, "")
+		require.NoError(t, err)
+
+		_, err = newCachedClient(t, server.URL, "model-a", cache).Chat(context.Background(), "prompt")
+		require.NoError(t, err)
+		reply, err := newCachedClient(t, server.URL, "model-b", cache).Chat(context.Background(), "prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "b", reply)
+		reply, err = newCachedClient(t, server.URL, "model-a", cache).Chat(context.Background(), "other prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "c", reply)
+		assert.Equal(t, int64(3), cache.Stats().Misses)
+	})
+
+	t.Run("bypass refreshes the entry", func(t *testing.T) {
+		server := testutil.NewFakeLLMServer(
+			ollama.Message{Role: "assistant", Content: "stale"},
+			ollama.Message{Role: "assistant", Content: "fresh"},
+		)
+		defer server.Close()
+		cache, err := ollama.NewResponseCache(time.Hour, 10, 0, "")
+		require.NoError(t, err)
+		client := newCachedClient(t, server.URL, "test-model", cache)
+
+		_, err = client.Chat(context.Background(), "prompt")
+		require.NoError(t, err)
+		reply, err := client.Chat(ollama.WithoutCache(context.Background()), "prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "fresh", reply)
+		reply, err = client.Chat(context.Background(), "prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "fresh", reply)
+
+		stats := cache.Stats()
+		assert.Equal(t, int64(1), stats.Bypassed)
+		assert.Equal(t, int64(1), stats.Hits)
+	})
+
+	t.Run("expired entries are not served", func(t *testing.T) {
+		server := testutil.NewFakeLLMServer(
+			ollama.Message{Role: "assistant", Content: "old"},
+			ollama.Message{Role: "assistant", Content: "new"},
+		)
+		defer server.Close()
+		cache, err := ollama.NewResponseCache(20*time.Millisecond, 10, 0, "")
+		require.NoError(t, err)
+		client := newCachedClient(t, server.URL, "test-model", cache)
+
+		_, err = client.Chat(context.Background(), "prompt")
+		require.NoError(t, err)
+		time.Sleep(40 * time.Millisecond)
+		reply, err := client.Chat(context.Background(), "prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "new", reply)
+		assert.Equal(t, int64(1), cache.Stats().Expired)
+	})
+
+	t.Run("least recently used entries are evicted", func(t *testing.T) {
+		server := testutil.NewFakeLLMServer(
+			ollama.Message{Role: "assistant", Content: "1"},
+			ollama.Message{Role: "assistant", Content: "2"},
+			ollama.Message{Role: "assistant", Content: "3"},
+			ollama.Message{Role: "assistant", Content: "1 again"},
+		)
+		defer server.Close()
+		cache, err := ollama.NewResponseCache(time.Hour, 2, 0, "")
+		require.NoError(t, err)
+		client := newCachedClient(t, server.URL, "test-model", cache)
+
+		for _, prompt := range []string{"one", "two", "two", "three", "one"} {
+			_, err := client.Chat(context.Background(), prompt)
+			require.NoError(t, err)
+			time.Sleep(time.Millisecond)
+		}
+		// "one" was the least recently used when "three" was stored, "two" was hit before
+		stats := cache.Stats()
+		assert.Equal(t, 2, stats.Entries)
+		assert.Equal(t, int64(2), stats.Evictions)
+		assert.Equal(t, int64(1), stats.Hits)
+		assert.Len(t, server.Requests(), 4)
+	})
+
+	t.Run("persisted across restarts", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache", "llm.json")
+		server := testutil.NewFakeLLMServer(ollama.Message{Role: "assistant", Content: "persisted"})
+		defer server.Close()
+		cache, err := ollama.NewResponseCache(time.Hour, 10, 0, path)
+		require.NoError(t, err)
+		_, err = newCachedClient(t, server.URL, "test-model", cache).Chat(context.Background(), "prompt")
+		require.NoError(t, err)
+		_, err = os.Stat(path)
+		assert.True(t, os.IsNotExist(err), "writes are batched instead of made on every reply")
+		cache.Flush()
+
+		reloaded, err := ollama.NewResponseCache(time.Hour, 10, 0, path)
+		require.NoError(t, err)
+		reply, err := newCachedClient(t, server.URL, "test-model", reloaded).Chat(context.Background(), "prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "persisted", reply)
+		assert.Len(t, server.Requests(), 1)
+
+		reloaded.Clear()
+		reloaded.Flush()
+		cleared, err := ollama.NewResponseCache(time.Hour, 10, 0, path)
+		require.NoError(t, err)
+		assert.Zero(t, cleared.Stats().Entries)
+	})
+
+	t.Run("fallback replies are not cached", func(t *testing.T) {
+		primary, calls := flakyServer(t, 500)
+		fallback := testutil.NewFakeLLMServer(ollama.Message{Role: "assistant", Content: "from fallback"})
+		defer fallback.Close()
+		cache, err := ollama.NewResponseCache(time.Hour, 10, 0, "")
+		require.NoError(t, err)
+		client := ollama.NewClient(primary.URL, "primary-model", "ollama", "", false)
+		client.SetCache(cache)
+		client.SetResilience(ollama.ResilienceOptions{FailureThreshold: 1, OpenDuration: 10 * time.Millisecond})
+		provider, err := ollama.NewProvider(ollama.ProviderConfig{Provider: "litellm", URL: fallback.URL, Model: "fallback-model"}, false)
+		require.NoError(t, err)
+		client.AddFallback(provider)
+
+		reply, err := client.Chat(context.Background(), "prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "from fallback", reply)
+		assert.Zero(t, cache.Stats().Entries)
+
+		// Once the primary recovers it answers, and its reply is cached
+		time.Sleep(20 * time.Millisecond)
+		reply, err = client.Chat(context.Background(), "prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "recovered", reply)
+		reply, err = client.Chat(context.Background(), "prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "recovered", reply)
+		assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+		assert.Equal(t, 1, cache.Stats().Entries)
+	})
+
+	t.Run("corrupt file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "llm.json")
+		require.NoError(t, os.WriteFile(path, []byte("{not json"), 0644))
+		_, err := ollama.NewResponseCache(time.Hour, 10, 0, path)
+		assert.ErrorContains(t, err, "failed to parse LLM cache")
+	})
+}
+
+func TestChatJSONDoesNotCacheInvalidReplies(t *testing.T) {
+	server := testutil.NewFakeLLMServer(
+		ollama.Message{Role: "assistant", Content: `{"instanceTypes": []}`},
+		ollama.Message{Role: "assistant", Content: `{"instanceTypes": ["m6i.large"], "nodeCount": 2, "capacityType": "spot", "rationale": "fits"}`},
+	)
+	defer server.Close()
+	cache, err := ollama.NewResponseCache(time.Hour, 10, 0, "")
+	require.NoError(t, err)
+	client := newCachedClient(t, server.URL, "test-model", cache)
+
+	var out advice
+	require.NoError(t, client.ChatJSON(context.Background(), "Size the NodePool", adviceSchema, &out))
+	// Only the repaired reply is kept; the first request is asked again next time
+	assert.Equal(t, 1, cache.Stats().Entries)
+
+	var again advice
+	err = client.ChatJSON(context.Background(), "Size the NodePool", adviceSchema, &again)
+	require.Error(t, err)
+	assert.Len(t, server.Requests(), 3)
+}
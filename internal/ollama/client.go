@@ -1,25 +1,25 @@
 package ollama
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"strings"
-	"time"
+	"sync"
 )
 
+// Client sends chat requests to an LLM provider, falling back to the next provider in
+// order when one fails. Each provider is retried, rate limited and guarded by a circuit
+// breaker per the ResilienceOptions, and replies may be served from a ResponseCache.
 type Client struct {
-	baseURL    string
-	httpClient *http.Client
-	model      string
-	provider   string // "ollama" or "litellm"
-	apiKey     string // Optional API key for LiteLLM
-	debug      bool   // Enable debug logging
+	model    string // Model of the primary provider
+	provider string // Primary provider: ollama, litellm, openai, anthropic or azure
+	debug    bool   // Enable debug logging
+
+	cache      *ResponseCache     // Optional reply cache (nil = every request goes to the LLM)
+	resilience ResilienceOptions  // Applied to every provider
+	providers  []*guardedProvider // Primary first, then the fallbacks in order
+	mu         sync.RWMutex       // Guards providers
 }
 
 type ChatRequest struct {
@@ -29,8 +29,11 @@ type ChatRequest struct {
 }
 
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // Functions the assistant wants called
+	ToolCallID string     `json:"tool_call_id,omitempty"` // Call answered by a "tool" message
+	Name       string     `json:"name,omitempty"`         // Function name of a "tool" message
 }
 
 type ChatResponse struct {
@@ -46,9 +49,10 @@ type ChatResponse struct {
 	EvalDuration       int64   `json:"eval_duration"`
 }
 
-// NewClient creates a new LLM client supporting both Ollama and LiteLLM
-// provider: "ollama" or "litellm" (default: "ollama")
-// apiKey: Optional API key for LiteLLM authentication
+// NewClient creates a new LLM client
+// provider: "ollama", "litellm"/"openai" (OpenAI-compatible), "anthropic" or "azure"
+// (default: detected from the URL)
+// apiKey: Optional API key
 // debug: Enable debug logging
 func NewClient(baseURL, model, provider, apiKey string, debug bool) *Client {
 	if baseURL == "" {
@@ -59,27 +63,16 @@ func NewClient(baseURL, model, provider, apiKey string, debug bool) *Client {
 	}
 	if provider == "" {
 		// Auto-detect provider from URL
-		baseURLLower := strings.ToLower(baseURL)
-		if strings.Contains(baseURLLower, "/v1/chat/completions") ||
-			strings.Contains(baseURLLower, "litellm") ||
-			strings.Contains(baseURLLower, "openai") ||
-			strings.Contains(baseURLLower, "vllm") {
-			provider = "litellm"
-		} else {
-			provider = "ollama"
-		}
+		provider = DetectProvider(baseURL)
 	}
 
-	client := &Client{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 120 * time.Second, // Longer timeout for LLM
-		},
-		model:    model,
-		provider: provider,
-		apiKey:   apiKey,
-		debug:    debug,
+	primary, err := NewProvider(ProviderConfig{Provider: provider, URL: baseURL, Model: model, APIKey: apiKey}, debug)
+	if err != nil {
+		log.Printf("[LLM] %v, using ollama", err)
+		provider = ProviderOllama
+		primary, _ = NewProvider(ProviderConfig{Provider: provider, URL: baseURL, Model: model}, debug)
 	}
+	client := NewClientWithProvider(primary, debug)
 
 	if debug {
 		log.Printf("[LLM] Initialized client: provider=%s, url=%s, model=%s", provider, baseURL, model)
@@ -88,6 +81,63 @@ func NewClient(baseURL, model, provider, apiKey string, debug bool) *Client {
 	return client
 }
 
+// NewClientWithProvider creates a client sending requests to provider
+func NewClientWithProvider(provider LLMProvider, debug bool) *Client {
+	return &Client{
+		model:     provider.Model(),
+		provider:  provider.Name(),
+		debug:     debug,
+		providers: []*guardedProvider{newGuardedProvider(provider, ResilienceOptions{})},
+	}
+}
+
+// AddFallback adds a provider that is tried, after those added before, when the
+// primary provider fails or its circuit is open
+func (c *Client) AddFallback(provider LLMProvider) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.providers = append(c.providers, newGuardedProvider(provider, c.resilience))
+}
+
+// SetResilience sets the retries, concurrency limit and circuit breaker of every
+// provider. Circuit breakers and counters are reset.
+func (c *Client) SetResilience(opts ResilienceOptions) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resilience = opts
+	for i, g := range c.providers {
+		c.providers[i] = newGuardedProvider(g.LLMProvider, opts)
+	}
+}
+
+// Available reports whether a provider accepts requests, i.e. not every circuit
+// breaker is open. AI enhancement is skipped while the LLM is unavailable.
+func (c *Client) Available() bool {
+	for _, g := range c.guardedProviders() {
+		if g.available() {
+			return true
+		}
+	}
+	return false
+}
+
+// ProviderStatuses returns the circuit breaker state and counters of every provider,
+// primary first
+func (c *Client) ProviderStatuses() []ProviderStatus {
+	providers := c.guardedProviders()
+	statuses := make([]ProviderStatus, 0, len(providers))
+	for _, g := range providers {
+		statuses = append(statuses, g.status())
+	}
+	return statuses
+}
+
+func (c *Client) guardedProviders() []*guardedProvider {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]*guardedProvider(nil), c.providers...)
+}
+
 // LiteLLMChatRequest is the OpenAI-compatible request format for LiteLLM
 type LiteLLMChatRequest struct {
 	Model    string    `json:"model"`
@@ -114,162 +164,97 @@ type LiteLLMChatResponse struct {
 }
 
 func (c *Client) Chat(ctx context.Context, prompt string) (string, error) {
-	var url string
-	var reqBody interface{}
-
-	// Determine endpoint and request format based on provider
-	if c.provider == "litellm" {
-		// LiteLLM uses OpenAI-compatible format
-		// Remove /v1/chat/completions from URL if present (we'll add it)
-		baseURL := strings.TrimSuffix(c.baseURL, "/v1/chat/completions")
-		baseURL = strings.TrimSuffix(baseURL, "/")
-		url = fmt.Sprintf("%s/v1/chat/completions", baseURL)
-
-		reqBody = LiteLLMChatRequest{
-			Model: c.model,
-			Messages: []Message{
-				{
-					Role:    "user",
-					Content: prompt,
-				},
-			},
-			Stream: false,
-		}
-
-		if c.debug {
-			log.Printf("[LLM] Sending LiteLLM request to %s with model %s", url, c.model)
-			log.Printf("[LLM] Prompt length: %d characters", len(prompt))
-		}
-	} else {
-		// Ollama format
-		url = fmt.Sprintf("%s/api/chat", c.baseURL)
-		reqBody = ChatRequest{
-			Model: c.model,
-			Messages: []Message{
-				{
-					Role:    "user",
-					Content: prompt,
-				},
-			},
-			Stream: false,
-		}
-
-		if c.debug {
-			log.Printf("[LLM] Sending Ollama request to %s with model %s", url, c.model)
-			log.Printf("[LLM] Prompt length: %d characters", len(prompt))
-		}
+	messages := []Message{
+		{
+			Role:    "user",
+			Content: prompt,
+		},
 	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+	if c.debug {
+		log.Printf("[LLM] Prompt length: %d characters", len(prompt))
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	message, err := c.chat(ctx, ChatParams{Messages: messages})
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", err
 	}
+	return message.Content, nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
-
-	// Add API key for LiteLLM if provided
-	if c.provider == "litellm" && c.apiKey != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
-		if c.debug {
-			log.Printf("[LLM] Added Authorization header with API key")
-		}
-	}
+// SetCache sets the cache replies are served from and stored in (nil disables caching)
+func (c *Client) SetCache(cache *ResponseCache) {
+	c.cache = cache
+}
 
-	startTime := time.Now()
-	resp, err := c.httpClient.Do(req)
-	duration := time.Since(startTime)
+// Cache returns the response cache, or nil when caching is disabled
+func (c *Client) Cache() *ResponseCache {
+	return c.cache
+}
 
-	if err != nil {
-		if c.debug {
-			log.Printf("[LLM] Request failed after %v: %v", duration, err)
-		}
-		return "", fmt.Errorf("failed to execute request: %w", err)
+// chat sends a chat request and returns the reply message. With a response cache, a
+// cached reply to the same model and normalized request is returned instead unless the
+// context bypasses the cache, and new replies of the primary model are stored.
+func (c *Client) chat(ctx context.Context, params ChatParams) (*Message, error) {
+	if c.cache == nil {
+		reply, _, err := c.send(ctx, params)
+		return reply, err
 	}
-	defer func() {
-		_ = resp.Body.Close() // Ignore close errors
-	}()
-
-	if c.debug {
-		log.Printf("[LLM] Response received in %v with status %d", duration, resp.StatusCode)
+	key, err := cacheKey(c.model, params)
+	if err != nil {
+		reply, _, err := c.send(ctx, params)
+		return reply, err
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		errorMsg := fmt.Sprintf("%s request failed with status %d: %s", c.provider, resp.StatusCode, string(body))
+	if cacheBypassed(ctx) {
+		c.cache.bypass()
+	} else if reply, ok := c.cache.get(key); ok {
 		if c.debug {
-			log.Printf("[LLM] %s", errorMsg)
+			log.Printf("[LLM] Response cache hit (%s)", key)
 		}
-		return "", errors.New(errorMsg)
+		return reply, nil
 	}
 
-	// Read the response body first so we can log it if parsing fails
-	bodyBytes, err := io.ReadAll(resp.Body)
+	reply, fromFallback, err := c.send(ctx, params)
 	if err != nil {
-		if c.debug {
-			log.Printf("[LLM] Failed to read response body: %v", err)
-		}
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
+	}
+	// The key is the primary model's: a fallback's reply would be served in its place
+	// long after the primary recovered
+	if !fromFallback {
+		c.cache.put(key, c.model, *reply)
 	}
+	return reply, nil
+}
 
-	// Parse response based on provider
-	if c.provider == "litellm" {
-		var chatResp LiteLLMChatResponse
-		if err := json.Unmarshal(bodyBytes, &chatResp); err != nil {
-			// Log the actual response for debugging
-			bodyStr := string(bodyBytes)
-			if c.debug {
-				log.Printf("[LLM] Failed to decode LiteLLM response: %v", err)
-				log.Printf("[LLM] Response body (first 500 chars): %s", truncateString(bodyStr, 500))
-				log.Printf("[LLM] Response Content-Type: %s", resp.Header.Get("Content-Type"))
-			}
-			// If response looks like HTML/XML, provide a more helpful error
-			if strings.HasPrefix(strings.TrimSpace(bodyStr), "<") {
-				return "", fmt.Errorf("received HTML/XML response instead of JSON (status 200). This may indicate the endpoint path is incorrect or the service returned an error page. Response preview: %s", truncateString(bodyStr, 200))
-			}
-			return "", fmt.Errorf("failed to decode response: %w. Response preview: %s", err, truncateString(bodyStr, 200))
-		}
+// uncache drops the cached reply to a request, e.g. one that failed validation
+func (c *Client) uncache(params ChatParams) {
+	if c.cache == nil {
+		return
+	}
+	if key, err := cacheKey(c.model, params); err == nil {
+		c.cache.forget(key)
+	}
+}
 
-		if len(chatResp.Choices) == 0 {
-			return "", fmt.Errorf("no choices in LiteLLM response")
+// send sends a chat request to the providers in order until one replies, and reports
+// whether a fallback provider answered
+func (c *Client) send(ctx context.Context, params ChatParams) (*Message, bool, error) {
+	providers := c.guardedProviders()
+	var errs []error
+	for i, g := range providers {
+		reply, err := g.chat(ctx, params)
+		if err == nil {
+			return reply, i > 0, nil
 		}
-
-		content := chatResp.Choices[0].Message.Content
-		if c.debug {
-			log.Printf("[LLM] LiteLLM response received: %d tokens used, response length: %d characters",
-				chatResp.Usage.TotalTokens, len(content))
+		if len(providers) == 1 {
+			return nil, false, err
 		}
-
-		return content, nil
-	} else {
-		// Ollama format
-		var chatResp ChatResponse
-		if err := json.Unmarshal(bodyBytes, &chatResp); err != nil {
-			bodyStr := string(bodyBytes)
-			if c.debug {
-				log.Printf("[LLM] Failed to decode Ollama response: %v", err)
-				log.Printf("[LLM] Response body (first 500 chars): %s", truncateString(bodyStr, 500))
-			}
-			return "", fmt.Errorf("failed to decode response: %w. Response preview: %s", err, truncateString(bodyStr, 200))
+		errs = append(errs, fmt.Errorf("%s (%s): %w", g.Name(), g.Model(), err))
+		if ctx.Err() != nil {
+			break
 		}
-
-		content := chatResp.Message.Content
-		if c.debug {
-			log.Printf("[LLM] Ollama response received: response length: %d characters", len(content))
+		if i+1 < len(providers) {
+			log.Printf("[LLM] %s failed, falling back to %s: %v", g.Name(), providers[i+1].Name(), err)
 		}
-
-		return content, nil
-	}
-}
-
-// truncateString truncates a string to a maximum length, adding "..." if truncated
-func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
 	}
-	return s[:maxLen] + "..."
+	return nil, false, fmt.Errorf("all LLM providers failed: %w", errors.Join(errs...))
 }
@@ -0,0 +1,360 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Provider names
+const (
+	ProviderOllama    = "ollama"
+	ProviderLiteLLM   = "litellm" // OpenAI-compatible: LiteLLM, vLLM, OpenAI...
+	ProviderOpenAI    = "openai"  // Alias of litellm
+	ProviderAnthropic = "anthropic"
+	ProviderAzure     = "azure"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI api-version used when none is configured
+const DefaultAzureAPIVersion = "2024-06-01"
+
+// LLMProvider sends chat requests to one LLM backend in its wire format
+type LLMProvider interface {
+	// Name is the provider: ollama, litellm, openai, anthropic or azure
+	Name() string
+	// Model is the model (or Azure OpenAI deployment) requests are sent to
+	Model() string
+	// Chat sends a chat request and returns the reply message
+	Chat(ctx context.Context, req ChatParams) (*Message, error)
+}
+
+// ChatParams is a chat request independent of the provider's wire format
+type ChatParams struct {
+	Messages []Message `json:"messages"`
+	Tools    []Tool    `json:"tools,omitempty"`  // Functions the model may call
+	Schema   *Schema   `json:"schema,omitempty"` // JSON Schema the reply must match (JSON mode)
+}
+
+// usesTools reports whether the request must be sent in the tool calling format
+func (p ChatParams) usesTools() bool {
+	if len(p.Tools) > 0 {
+		return true
+	}
+	for _, m := range p.Messages {
+		if m.Role == "tool" || len(m.ToolCalls) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ProviderConfig configures an LLM provider
+type ProviderConfig struct {
+	Provider   string // ollama, litellm/openai, anthropic or azure ("" = detected from the URL)
+	URL        string
+	Model      string // Model, or deployment for Azure OpenAI
+	APIKey     string
+	APIVersion string // Azure OpenAI api-version (default: DefaultAzureAPIVersion)
+}
+
+// DetectProvider guesses the provider from the LLM URL
+func DetectProvider(url string) string {
+	urlLower := strings.ToLower(url)
+	switch {
+	case strings.Contains(urlLower, "anthropic"):
+		return ProviderAnthropic
+	case strings.Contains(urlLower, ".openai.azure.com") || strings.Contains(urlLower, "/openai/deployments/"):
+		return ProviderAzure
+	case strings.Contains(urlLower, "/v1/chat/completions") ||
+		strings.Contains(urlLower, "litellm") ||
+		strings.Contains(urlLower, "openai") ||
+		strings.Contains(urlLower, "vllm"):
+		return ProviderLiteLLM
+	}
+	return ProviderOllama
+}
+
+// NewProvider creates the provider selected by cfg.Provider
+func NewProvider(cfg ProviderConfig, debug bool) (LLMProvider, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("LLM provider URL is required")
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = DetectProvider(cfg.URL)
+	}
+	httpClient := &http.Client{
+		Timeout: 120 * time.Second, // Longer timeout for LLM
+	}
+	base := httpProvider{name: cfg.Provider, baseURL: cfg.URL, model: cfg.Model, apiKey: cfg.APIKey, httpClient: httpClient, debug: debug}
+
+	switch cfg.Provider {
+	case ProviderOllama:
+		return &ollamaProvider{base}, nil
+	case ProviderLiteLLM, ProviderOpenAI:
+		return &openAIProvider{httpProvider: base}, nil
+	case ProviderAzure:
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("azure provider requires the deployment name as model")
+		}
+		apiVersion := cfg.APIVersion
+		if apiVersion == "" {
+			apiVersion = DefaultAzureAPIVersion
+		}
+		return &openAIProvider{httpProvider: base, azureAPIVersion: apiVersion}, nil
+	case ProviderAnthropic:
+		return &anthropicProvider{base}, nil
+	}
+	return nil, fmt.Errorf("unknown LLM provider %q (expected ollama, litellm, openai, anthropic or azure)", cfg.Provider)
+}
+
+// ParseProviderList parses fallback providers from "provider,url,model[,API key env var]"
+// entries separated by semicolons, e.g.
+// "anthropic,https://api.anthropic.com,claude-sonnet-4-5,ANTHROPIC_API_KEY;ollama,http://ollama:11434,gemma3:1b".
+// API keys are read from the named environment variables so they stay out of the list.
+func ParseProviderList(spec string) ([]ProviderConfig, error) {
+	var configs []ProviderConfig
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		fields := strings.Split(entry, ",")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		if len(fields) < 3 || len(fields) > 4 || fields[1] == "" {
+			return nil, fmt.Errorf("invalid LLM provider %q: expected provider,url,model[,API key env var]", entry)
+		}
+		cfg := ProviderConfig{Provider: fields[0], URL: fields[1], Model: fields[2]}
+		if len(fields) == 4 && fields[3] != "" {
+			cfg.APIKey = os.Getenv(fields[3])
+			if cfg.APIKey == "" {
+				return nil, fmt.Errorf("invalid LLM provider %q: environment variable %s is not set", entry, fields[3])
+			}
+		}
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+// ProviderError is a reply with a non-200 status from an LLM provider
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // From the Retry-After header (0 = not set)
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s request failed with status %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// httpProvider holds what every HTTP provider needs and sends the requests
+type httpProvider struct {
+	name       string
+	baseURL    string
+	model      string
+	apiKey     string
+	httpClient *http.Client
+	debug      bool
+}
+
+func (p *httpProvider) Name() string {
+	return p.name
+}
+
+func (p *httpProvider) Model() string {
+	return p.model
+}
+
+// post sends reqBody as JSON with the headers and returns the body of a 200 reply
+func (p *httpProvider) post(ctx context.Context, url string, headers map[string]string, reqBody interface{}) ([]byte, error) {
+	if p.debug {
+		log.Printf("[LLM] Sending %s request to %s with model %s", p.name, url, p.model)
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	startTime := time.Now()
+	resp, err := p.httpClient.Do(req)
+	duration := time.Since(startTime)
+	if err != nil {
+		if p.debug {
+			log.Printf("[LLM] Request failed after %v: %v", duration, err)
+		}
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close() // Ignore close errors
+	}()
+
+	if p.debug {
+		log.Printf("[LLM] Response received in %v with status %d", duration, resp.StatusCode)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		providerErr := &ProviderError{Provider: p.name, StatusCode: resp.StatusCode, Body: string(body)}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			providerErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		if p.debug {
+			log.Printf("[LLM] %s", providerErr.Error())
+		}
+		return nil, providerErr
+	}
+
+	// Read the response body first so we can log it if parsing fails
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if p.debug {
+			log.Printf("[LLM] Failed to read response body: %v", err)
+		}
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return bodyBytes, nil
+}
+
+// decodeError explains a 200 reply that could not be decoded
+func (p *httpProvider) decodeError(err error, bodyBytes []byte) error {
+	bodyStr := string(bodyBytes)
+	if p.debug {
+		log.Printf("[LLM] Failed to decode %s response: %v", p.name, err)
+		log.Printf("[LLM] Response body (first 500 chars): %s", truncateString(bodyStr, 500))
+	}
+	// If response looks like HTML/XML, provide a more helpful error
+	if strings.HasPrefix(strings.TrimSpace(bodyStr), "<") {
+		return fmt.Errorf("received HTML/XML response instead of JSON (status 200). This may indicate the endpoint path is incorrect or the service returned an error page. Response preview: %s", truncateString(bodyStr, 200))
+	}
+	return fmt.Errorf("failed to decode response: %w. Response preview: %s", err, truncateString(bodyStr, 200))
+}
+
+// ollamaProvider speaks the Ollama /api/chat format
+type ollamaProvider struct {
+	httpProvider
+}
+
+func (p *ollamaProvider) Chat(ctx context.Context, params ChatParams) (*Message, error) {
+	var reqBody interface{}
+	switch {
+	case params.Schema != nil:
+		reqBody = ollamaJSONRequest{Model: p.model, Messages: params.Messages, Format: params.Schema}
+	case params.usesTools():
+		converted, err := toOllamaMessages(params.Messages)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = ollamaToolRequest{Model: p.model, Messages: converted, Tools: params.Tools}
+	default:
+		reqBody = ChatRequest{Model: p.model, Messages: params.Messages, Stream: false}
+	}
+
+	bodyBytes, err := p.post(ctx, fmt.Sprintf("%s/api/chat", p.baseURL), nil, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	var chatResp ChatResponse
+	if err := json.Unmarshal(bodyBytes, &chatResp); err != nil {
+		return nil, p.decodeError(err, bodyBytes)
+	}
+
+	message := chatResp.Message
+	if p.debug {
+		log.Printf("[LLM] Ollama response received: response length: %d characters, %d tool call(s)", len(message.Content), len(message.ToolCalls))
+	}
+	return &message, nil
+}
+
+// openAIProvider speaks the OpenAI chat completions format, used by LiteLLM, vLLM,
+// OpenAI and (with an api-version) Azure OpenAI
+type openAIProvider struct {
+	httpProvider
+	azureAPIVersion string // Set for Azure OpenAI
+}
+
+// chatURL returns the chat completions endpoint
+func (p *openAIProvider) chatURL() string {
+	if p.azureAPIVersion != "" {
+		baseURL := p.baseURL
+		if i := strings.Index(baseURL, "/openai/"); i >= 0 {
+			baseURL = baseURL[:i]
+		}
+		return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s", strings.TrimSuffix(baseURL, "/"), p.model, p.azureAPIVersion)
+	}
+	// Remove /v1/chat/completions from URL if present (we'll add it)
+	baseURL := strings.TrimSuffix(p.baseURL, "/v1/chat/completions")
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	return fmt.Sprintf("%s/v1/chat/completions", baseURL)
+}
+
+func (p *openAIProvider) Chat(ctx context.Context, params ChatParams) (*Message, error) {
+	var reqBody interface{}
+	switch {
+	case params.Schema != nil:
+		reqBody = liteLLMJSONRequest{
+			Model:          p.model,
+			Messages:       params.Messages,
+			ResponseFormat: &responseFormat{Type: "json_schema", JSONSchema: &jsonSchemaFormat{Name: "response", Schema: params.Schema}},
+		}
+	case params.usesTools():
+		reqBody = liteLLMToolRequest{Model: p.model, Messages: params.Messages, Tools: params.Tools}
+	default:
+		reqBody = LiteLLMChatRequest{Model: p.model, Messages: params.Messages, Stream: false}
+	}
+
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		if p.azureAPIVersion != "" {
+			headers["api-key"] = p.apiKey
+		} else {
+			headers["Authorization"] = fmt.Sprintf("Bearer %s", p.apiKey)
+		}
+	}
+
+	bodyBytes, err := p.post(ctx, p.chatURL(), headers, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	var chatResp LiteLLMChatResponse
+	if err := json.Unmarshal(bodyBytes, &chatResp); err != nil {
+		return nil, p.decodeError(err, bodyBytes)
+	}
+	if len(chatResp.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in %s response", p.name)
+	}
+
+	message := chatResp.Choices[0].Message
+	if p.debug {
+		log.Printf("[LLM] %s response received: %d tokens used, response length: %d characters, %d tool call(s)",
+			p.name, chatResp.Usage.TotalTokens, len(message.Content), len(message.ToolCalls))
+	}
+	return &message, nil
+}
+
+// truncateString truncates a string to a maximum length, adding "..." if truncated
+func truncateString(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
+}
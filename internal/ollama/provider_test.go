@@ -0,0 +1,273 @@
+package ollama_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/karpenter-optimizer/internal/ollama"
+	"github.com/karpenter-optimizer/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectProvider(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://localhost:11434", ollama.ProviderOllama},
+		{"https://litellm.example.com", ollama.ProviderLiteLLM},
+		{"http://vllm:8000/v1/chat/completions", ollama.ProviderLiteLLM},
+		{"https://api.openai.com", ollama.ProviderLiteLLM},
+		{"https://my-resource.openai.azure.com", ollama.ProviderAzure},
+		{"https://api.anthropic.com", ollama.ProviderAnthropic},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.Equal(t, tt.want, ollama.DetectProvider(tt.url))
+		})
+	}
+}
+
+func TestParseProviderList(t *testing.T) {
+	t.Setenv("TEST_ANTHROPIC_KEY", "sk-ant")
+
+	configs, err := ollama.ParseProviderList(" anthropic, https://api.anthropic.com, claude-sonnet-4-5, TEST_ANTHROPIC_KEY ; ollama,http://ollama:11434,gemma3:1b;")
+	require.NoError(t, err)
+	assert.Equal(t, []ollama.ProviderConfig{
+		{Provider: "anthropic", URL: "https://api.anthropic.com", Model: "claude-sonnet-4-5", APIKey: "sk-ant"},
+		{Provider: "ollama", URL: "http://ollama:11434", Model: "gemma3:1b"},
+	}, configs)
+
+	_, err = ollama.ParseProviderList("ollama,http://ollama:11434")
+	assert.ErrorContains(t, err, "expected provider,url,model")
+	_, err = ollama.ParseProviderList("openai,https://api.openai.com,gpt-4o,TEST_MISSING_KEY")
+	assert.ErrorContains(t, err, "TEST_MISSING_KEY is not set")
+}
+
+// recordingServer replies with body to every request and records the last one
+type recordingServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	request *http.Request
+	body    map[string]interface{}
+}
+
+func newRecordingServer(t *testing.T, reply string) *recordingServer {
+	t.Helper()
+	s := &recordingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.request = r
+		_ = json.Unmarshal(data, &s.body)
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, reply)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestAnthropicProvider(t *testing.T) {
+	server := newRecordingServer(t, `{"id": "msg_1", "type": "message", "role": "assistant", "content": [
+		{"type": "text", "text": "Checking the NodePool."},
+		{"type": "tool_use", "id": "toolu_2", "name": "get_nodepool", "input": {"name": "default"}}
+	], "stop_reason": "tool_use", "usage": {"input_tokens": 120, "output_tokens": 30}}`)
+
+	provider, err := ollama.NewProvider(ollama.ProviderConfig{Provider: "anthropic", URL: server.URL, Model: "claude-sonnet-4-5", APIKey: "sk-ant"}, false)
+	require.NoError(t, err)
+	client := ollama.NewClientWithProvider(provider, false)
+
+	reply, err := client.ChatWithTools(context.Background(), []ollama.Message{
+		{Role: "system", Content: "You optimize Karpenter NodePools."},
+		{Role: "user", Content: "Right-size the cluster"},
+		testutil.ToolCallReply("toolu_1", "list_nodepools", map[string]interface{}{}),
+		{Role: "tool", ToolCallID: "toolu_1", Name: "list_nodepools", Content: `["default"]`},
+	}, []ollama.Tool{ollama.NewFunctionTool("get_nodepool", "Get a NodePool", map[string]interface{}{"type": "object"})})
+	require.NoError(t, err)
+	assert.Equal(t, "Checking the NodePool.", reply.Content)
+	require.Len(t, reply.ToolCalls, 1)
+	assert.Equal(t, "toolu_2", reply.ToolCalls[0].ID)
+	assert.Equal(t, `{"name": "default"}`, reply.ToolCalls[0].Function.Arguments)
+
+	assert.Equal(t, "/v1/messages", server.request.URL.Path)
+	assert.Equal(t, "sk-ant", server.request.Header.Get("x-api-key"))
+	assert.Equal(t, "2023-06-01", server.request.Header.Get("anthropic-version"))
+	assert.Equal(t, "You optimize Karpenter NodePools.", server.body["system"])
+	assert.Equal(t, "claude-sonnet-4-5", server.body["model"])
+	assert.NotZero(t, server.body["max_tokens"])
+
+	messages := server.body["messages"].([]interface{})
+	require.Len(t, messages, 3)
+	assert.Equal(t, "assistant", messages[1].(map[string]interface{})["role"])
+	toolUse := messages[1].(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "tool_use", toolUse["type"])
+	assert.Equal(t, map[string]interface{}{}, toolUse["input"])
+	toolResult := messages[2].(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "tool_result", toolResult["type"])
+	assert.Equal(t, "toolu_1", toolResult["tool_use_id"])
+
+	tool := server.body["tools"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "get_nodepool", tool["name"])
+	assert.Equal(t, map[string]interface{}{"type": "object"}, tool["input_schema"])
+}
+
+func TestAzureProvider(t *testing.T) {
+	server := newRecordingServer(t, `{"choices": [{"index": 0, "message": {"role": "assistant", "content": "ok"}, "finish_reason": "stop"}]}`)
+
+	provider, err := ollama.NewProvider(ollama.ProviderConfig{Provider: "azure", URL: server.URL + "/", Model: "gpt-4o-prod", APIKey: "azure-key"}, false)
+	require.NoError(t, err)
+	reply, err := ollama.NewClientWithProvider(provider, false).Chat(context.Background(), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", reply)
+
+	assert.Equal(t, "/openai/deployments/gpt-4o-prod/chat/completions", server.request.URL.Path)
+	assert.Equal(t, ollama.DefaultAzureAPIVersion, server.request.URL.Query().Get("api-version"))
+	assert.Equal(t, "azure-key", server.request.Header.Get("api-key"))
+	assert.Empty(t, server.request.Header.Get("Authorization"))
+
+	_, err = ollama.NewProvider(ollama.ProviderConfig{Provider: "azure", URL: server.URL}, false)
+	assert.ErrorContains(t, err, "deployment")
+	_, err = ollama.NewProvider(ollama.ProviderConfig{Provider: "bedrock", URL: server.URL}, false)
+	assert.ErrorContains(t, err, "unknown LLM provider")
+}
+
+// flakyServer answers with the statuses in order, then with a valid Ollama reply
+func flakyServer(t *testing.T, statuses ...int) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		if n <= len(statuses) {
+			http.Error(w, "unavailable", statuses[n-1])
+			return
+		}
+		_, _ = io.WriteString(w, `{"message": {"role": "assistant", "content": "recovered"}, "done": true}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestResilience(t *testing.T) {
+	t.Run("retries transient failures", func(t *testing.T) {
+		server, calls := flakyServer(t, 503, 429)
+		client := ollama.NewClient(server.URL, "test-model", "ollama", "", false)
+		client.SetResilience(ollama.ResilienceOptions{MaxRetries: 2, RetryBaseDelay: time.Millisecond})
+
+		reply, err := client.Chat(context.Background(), "prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "recovered", reply)
+		assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+		status := client.ProviderStatuses()[0]
+		assert.Equal(t, int64(2), status.Retries)
+		assert.Equal(t, ollama.CircuitClosed, status.Circuit)
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		server, calls := flakyServer(t, 400)
+		client := ollama.NewClient(server.URL, "test-model", "ollama", "", false)
+		client.SetResilience(ollama.ResilienceOptions{MaxRetries: 2, RetryBaseDelay: time.Millisecond, FailureThreshold: 1})
+
+		_, err := client.Chat(context.Background(), "prompt")
+		var providerErr *ollama.ProviderError
+		require.True(t, errors.As(err, &providerErr))
+		assert.Equal(t, 400, providerErr.StatusCode)
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+		assert.True(t, client.Available(), "client errors don't open the circuit")
+	})
+
+	t.Run("circuit breaker opens and recovers", func(t *testing.T) {
+		server, calls := flakyServer(t, 500, 500)
+		client := ollama.NewClient(server.URL, "test-model", "ollama", "", false)
+		client.SetResilience(ollama.ResilienceOptions{FailureThreshold: 2, OpenDuration: 50 * time.Millisecond})
+
+		for i := 0; i < 2; i++ {
+			_, err := client.Chat(context.Background(), "prompt")
+			require.Error(t, err)
+		}
+		_, err := client.Chat(context.Background(), "prompt")
+		assert.ErrorIs(t, err, ollama.ErrCircuitOpen)
+		assert.Equal(t, int32(2), atomic.LoadInt32(calls), "an open circuit doesn't call the provider")
+		assert.False(t, client.Available())
+		status := client.ProviderStatuses()[0]
+		assert.Equal(t, ollama.CircuitOpen, status.Circuit)
+		assert.Equal(t, int64(1), status.Rejected)
+		require.NotNil(t, status.OpenUntil)
+
+		time.Sleep(60 * time.Millisecond)
+		assert.True(t, client.Available())
+		reply, err := client.Chat(context.Background(), "prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "recovered", reply)
+		assert.Equal(t, ollama.CircuitClosed, client.ProviderStatuses()[0].Circuit)
+	})
+
+	t.Run("falls back in order", func(t *testing.T) {
+		primary, _ := flakyServer(t, 500, 500)
+		fallback := testutil.NewFakeLLMServer(ollama.Message{Role: "assistant", Content: "from fallback"})
+		defer fallback.Close()
+
+		client := ollama.NewClient(primary.URL, "primary-model", "ollama", "", false)
+		client.SetResilience(ollama.ResilienceOptions{FailureThreshold: 1, OpenDuration: time.Hour})
+		provider, err := ollama.NewProvider(ollama.ProviderConfig{Provider: "litellm", URL: fallback.URL, Model: "fallback-model"}, false)
+		require.NoError(t, err)
+		client.AddFallback(provider)
+
+		reply, err := client.Chat(context.Background(), "prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "from fallback", reply)
+		assert.Equal(t, "fallback-model", fallback.Requests()[0].Model)
+		assert.True(t, client.Available(), "the fallback is still available")
+
+		// Once every provider fails, the errors of all of them are returned
+		_, err = client.Chat(context.Background(), "prompt")
+		assert.ErrorContains(t, err, "all LLM providers failed")
+		assert.ErrorIs(t, err, ollama.ErrCircuitOpen)
+		assert.ErrorContains(t, err, "script exhausted")
+		statuses := client.ProviderStatuses()
+		require.Len(t, statuses, 2)
+		assert.Equal(t, "ollama", statuses[0].Provider)
+		assert.Equal(t, "fallback-model", statuses[1].Model)
+	})
+
+	t.Run("limits concurrent requests", func(t *testing.T) {
+		var inFlight, maxInFlight int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				highest := atomic.LoadInt32(&maxInFlight)
+				if n <= highest || atomic.CompareAndSwapInt32(&maxInFlight, highest, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+			_, _ = io.WriteString(w, `{"message": {"role": "assistant", "content": "ok"}, "done": true}`)
+		}))
+		defer server.Close()
+		client := ollama.NewClient(server.URL, "test-model", "ollama", "", false)
+		client.SetResilience(ollama.ResilienceOptions{MaxConcurrency: 2})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := client.Chat(context.Background(), "prompt")
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		assert.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(2))
+		assert.Equal(t, int64(6), client.ProviderStatuses()[0].Requests)
+	})
+}
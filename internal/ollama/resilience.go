@@ -0,0 +1,240 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling a provider while its circuit breaker is open
+var ErrCircuitOpen = errors.New("LLM provider circuit breaker is open")
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"    // Requests are sent
+	CircuitOpen     = "open"      // Requests are rejected until the open duration has passed
+	CircuitHalfOpen = "half-open" // One trial request decides whether the circuit closes again
+)
+
+// ResilienceOptions configure the retries, concurrency limit and circuit breaker of each
+// provider. The zero value sends every request once, unlimited, and never opens the circuit.
+type ResilienceOptions struct {
+	MaxRetries       int           // Retries of requests failing with a transport error, 408, 429 or 5xx
+	RetryBaseDelay   time.Duration // Backoff before the first retry, doubled per retry and jittered
+	RetryMaxDelay    time.Duration // Upper bound of a backoff, also applied to Retry-After (0 = 30s)
+	MaxConcurrency   int           // Requests in flight per provider (0 = unlimited)
+	FailureThreshold int           // Consecutive failed requests that open the circuit (0 = never)
+	OpenDuration     time.Duration // How long an open circuit rejects requests before a trial request
+}
+
+// ProviderStatus is a provider's circuit breaker state and request counters
+type ProviderStatus struct {
+	Provider            string     `json:"provider"`
+	Model               string     `json:"model"`
+	Circuit             string     `json:"circuit"`
+	OpenUntil           *time.Time `json:"openUntil,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	InFlight            int        `json:"inFlight"`
+	Requests            int64      `json:"requests"`
+	Failures            int64      `json:"failures"`
+	Retries             int64      `json:"retries"`
+	Rejected            int64      `json:"rejected"` // Rejected by the open circuit
+	LastError           string     `json:"lastError,omitempty"`
+}
+
+// guardedProvider sends a provider's requests with retries, a concurrency limit and a
+// circuit breaker
+type guardedProvider struct {
+	LLMProvider
+	opts  ResilienceOptions
+	slots chan struct{} // Concurrency limit (nil = unlimited)
+
+	mu                  sync.Mutex
+	circuit             string
+	openedAt            time.Time
+	trialInFlight       bool
+	consecutiveFailures int
+	inFlight            int
+	requests            int64
+	failures            int64
+	retries             int64
+	rejected            int64
+	lastError           string
+}
+
+func newGuardedProvider(provider LLMProvider, opts ResilienceOptions) *guardedProvider {
+	g := &guardedProvider{LLMProvider: provider, opts: opts, circuit: CircuitClosed}
+	if opts.MaxConcurrency > 0 {
+		g.slots = make(chan struct{}, opts.MaxConcurrency)
+	}
+	return g
+}
+
+// available reports whether the circuit lets a request through
+func (g *guardedProvider) available() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.circuit == CircuitClosed || (time.Since(g.openedAt) >= g.opts.OpenDuration && !g.trialInFlight)
+}
+
+// admit checks the circuit breaker before a request; while half-open only one trial
+// request is let through
+func (g *guardedProvider) admit() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.circuit != CircuitClosed {
+		if g.trialInFlight || time.Since(g.openedAt) < g.opts.OpenDuration {
+			g.rejected++
+			return fmt.Errorf("%s: %w", g.Name(), ErrCircuitOpen)
+		}
+		g.circuit = CircuitHalfOpen
+		g.trialInFlight = true
+	}
+	g.requests++
+	g.inFlight++
+	return nil
+}
+
+// record updates the circuit breaker with the outcome of a request. Only failures of
+// the provider (transport errors, 408, 429, 5xx) count, not rejected requests.
+func (g *guardedProvider) record(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inFlight--
+	wasTrial := g.trialInFlight
+	g.trialInFlight = false
+
+	if err == nil || !retryable(err) {
+		if err != nil {
+			g.failures++
+			g.lastError = err.Error()
+		}
+		g.consecutiveFailures = 0
+		g.circuit = CircuitClosed
+		return
+	}
+	g.failures++
+	g.consecutiveFailures++
+	g.lastError = err.Error()
+	if wasTrial || (g.opts.FailureThreshold > 0 && g.consecutiveFailures >= g.opts.FailureThreshold) {
+		if g.circuit != CircuitOpen {
+			log.Printf("[LLM] Circuit breaker opened for %s after %d consecutive failure(s): %v", g.Name(), g.consecutiveFailures, err)
+		}
+		g.circuit = CircuitOpen
+		g.openedAt = time.Now()
+	}
+}
+
+// chat sends a request through the circuit breaker and concurrency limit, retrying
+// transient failures with jittered exponential backoff
+func (g *guardedProvider) chat(ctx context.Context, params ChatParams) (*Message, error) {
+	if err := g.admit(); err != nil {
+		return nil, err
+	}
+
+	reply, err := g.send(ctx, params)
+	if ctx.Err() != nil && err != nil {
+		// The caller gave up; that says nothing about the provider
+		g.mu.Lock()
+		g.inFlight--
+		g.trialInFlight = false
+		g.mu.Unlock()
+		return nil, err
+	}
+	g.record(err)
+	return reply, err
+}
+
+func (g *guardedProvider) send(ctx context.Context, params ChatParams) (*Message, error) {
+	if g.slots != nil {
+		select {
+		case g.slots <- struct{}{}:
+			defer func() { <-g.slots }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		reply, err := g.LLMProvider.Chat(ctx, params)
+		if err == nil || attempt >= g.opts.MaxRetries || !retryable(err) || ctx.Err() != nil {
+			return reply, err
+		}
+		delay := g.backoff(attempt, err)
+		log.Printf("[LLM] %s request failed (attempt %d/%d), retrying in %v: %v", g.Name(), attempt+1, g.opts.MaxRetries+1, delay.Round(time.Millisecond), err)
+		g.mu.Lock()
+		g.retries++
+		g.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// backoff returns the delay before retry attempt+1: the base delay doubled per attempt
+// (or the provider's Retry-After), capped, with its upper half jittered
+func (g *guardedProvider) backoff(attempt int, err error) time.Duration {
+	maxDelay := g.opts.RetryMaxDelay
+	if maxDelay <= 0 {
+		maxDelay = 30 * time.Second
+	}
+	delay := g.opts.RetryBaseDelay << attempt
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) && providerErr.RetryAfter > 0 {
+		delay = providerErr.RetryAfter
+	}
+	if delay > maxDelay || (delay <= 0 && g.opts.RetryBaseDelay > 0) {
+		delay = maxDelay
+	}
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half + rand.Int63n(half+1))
+	}
+	return delay
+}
+
+// status returns the circuit state and counters
+func (g *guardedProvider) status() ProviderStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	status := ProviderStatus{
+		Provider:            g.Name(),
+		Model:               g.Model(),
+		Circuit:             g.circuit,
+		ConsecutiveFailures: g.consecutiveFailures,
+		InFlight:            g.inFlight,
+		Requests:            g.requests,
+		Failures:            g.failures,
+		Retries:             g.retries,
+		Rejected:            g.rejected,
+		LastError:           g.lastError,
+	}
+	if g.circuit == CircuitOpen {
+		openUntil := g.openedAt.Add(g.opts.OpenDuration)
+		status.OpenUntil = &openUntil
+	}
+	return status
+}
+
+// retryable reports whether a failed request may succeed when sent again: transport
+// errors, timeouts, rate limiting and server errors
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return providerErr.StatusCode == 408 || providerErr.StatusCode == 429 || providerErr.StatusCode >= 500
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) || errors.Is(err, context.DeadlineExceeded)
+}
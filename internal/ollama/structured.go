@@ -0,0 +1,238 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+)
+
+// maxJSONRepairs is how many times an invalid structured reply is sent back for repair
+const maxJSONRepairs = 1
+
+// Schema is the subset of JSON Schema used for structured replies. It is sent to the
+// provider to constrain generation and used to validate the reply.
+type Schema struct {
+	Type        string             `json:"type"` // object, array, string, integer, number or boolean
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	MinLength   int                `json:"minLength,omitempty"`
+	MaxLength   int                `json:"maxLength,omitempty"`
+	MinItems    int                `json:"minItems,omitempty"`
+	MaxItems    int                `json:"maxItems,omitempty"`
+}
+
+// Bound returns a pointer to v for Schema.Minimum and Schema.Maximum
+func Bound(v float64) *float64 {
+	return &v
+}
+
+// Validate checks a decoded JSON value (from json.Unmarshal into interface{}) and
+// returns one message per violation, prefixed with its path
+func (s *Schema) Validate(value interface{}) []string {
+	var errs []string
+	s.validate("$", value, &errs)
+	return errs
+}
+
+func (s *Schema) validate(path string, value interface{}, errs *[]string) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, path+": "+fmt.Sprintf(format, args...))
+	}
+	if value == nil {
+		fail("must not be null")
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			fail("expected an object")
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				fail("missing required field %q", name)
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if v, ok := obj[name]; ok && (v != nil || contains(s.Required, name)) {
+				s.Properties[name].validate(path+"."+name, v, errs)
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			fail("expected an array")
+			return
+		}
+		if len(items) < s.MinItems {
+			fail("must have at least %d item(s)", s.MinItems)
+		}
+		if s.MaxItems > 0 && len(items) > s.MaxItems {
+			fail("must have at most %d item(s)", s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			fail("expected a string")
+			return
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			fail("must be one of %s", strings.Join(s.Enum, ", "))
+		}
+		if len(strings.TrimSpace(str)) < s.MinLength {
+			fail("must have at least %d character(s)", s.MinLength)
+		}
+		if s.MaxLength > 0 && len(str) > s.MaxLength {
+			fail("must have at most %d characters", s.MaxLength)
+		}
+	case "integer", "number":
+		num, ok := value.(float64)
+		if !ok {
+			fail("expected a number")
+			return
+		}
+		if s.Type == "integer" && num != math.Trunc(num) {
+			fail("expected an integer")
+		}
+		if s.Minimum != nil && num < *s.Minimum {
+			fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && num > *s.Maximum {
+			fail("must be at most %v", *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("expected a boolean")
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaError is returned when a structured reply still does not match its schema
+// after the repair attempts
+type SchemaError struct {
+	Errors []string
+	Reply  string // Last reply received
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("LLM reply does not match the schema: %s", strings.Join(e.Errors, "; "))
+}
+
+// responseFormat is the OpenAI-compatible structured output setting
+type responseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *jsonSchemaFormat `json:"json_schema,omitempty"`
+}
+
+type jsonSchemaFormat struct {
+	Name   string  `json:"name"`
+	Schema *Schema `json:"schema"`
+}
+
+// liteLLMJSONRequest is the OpenAI-compatible chat request with a response format
+type liteLLMJSONRequest struct {
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	ResponseFormat *responseFormat `json:"response_format"`
+}
+
+// ollamaJSONRequest is the Ollama chat request constrained to a JSON Schema
+type ollamaJSONRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Format   *Schema   `json:"format"`
+	Stream   bool      `json:"stream"`
+}
+
+// ChatJSON sends prompt in JSON mode constrained to schema (Ollama "format",
+// OpenAI-compatible "response_format"; Anthropic only gets the schema in the prompt)
+// and decodes the reply into out. A reply that
+// is not JSON or does not match the schema is sent back once with the validation
+// errors for repair; if that fails too a *SchemaError is returned.
+func (c *Client) ChatJSON(ctx context.Context, prompt string, schema *Schema, out interface{}) error {
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema: %w", err)
+	}
+	messages := []Message{{
+		Role:    "user",
+		Content: fmt.Sprintf("%s\n\nRespond with only a JSON object matching this JSON Schema:\n%s", prompt, schemaJSON),
+	}}
+
+	var schemaErr *SchemaError
+	for attempt := 0; attempt <= maxJSONRepairs; attempt++ {
+		params := ChatParams{Messages: messages, Schema: schema}
+		reply, err := c.chat(ctx, params)
+		if err != nil {
+			return err
+		}
+		errs := decodeStructured(reply.Content, schema, out)
+		if len(errs) == 0 {
+			return nil
+		}
+		// A cached invalid reply would be repaired again on every call
+		c.uncache(params)
+		schemaErr = &SchemaError{Errors: errs, Reply: reply.Content}
+		if c.debug {
+			log.Printf("[LLM] Structured reply failed validation (attempt %d): %s", attempt+1, strings.Join(errs, "; "))
+		}
+		messages = append(messages,
+			Message{Role: "assistant", Content: reply.Content},
+			Message{Role: "user", Content: fmt.Sprintf("Your reply is invalid:\n- %s\nReply with only the corrected JSON object matching the schema.", strings.Join(errs, "\n- "))},
+		)
+	}
+	return schemaErr
+}
+
+// decodeStructured validates content against schema and decodes it into out. The
+// JSON object may be wrapped in a code fence or text.
+func decodeStructured(content string, schema *Schema, out interface{}) []string {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return []string{"$: no JSON object found"}
+	}
+	data := []byte(content[start : end+1])
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return []string{fmt.Sprintf("$: invalid JSON: %v", err)}
+	}
+	if errs := schema.Validate(value); len(errs) > 0 {
+		return errs
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return []string{fmt.Sprintf("$: %v", err)}
+	}
+	return nil
+}
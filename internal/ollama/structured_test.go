@@ -0,0 +1,129 @@
+package ollama_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/karpenter-optimizer/internal/ollama"
+	"github.com/karpenter-optimizer/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var adviceSchema = &ollama.Schema{
+	Type: "object",
+	Properties: map[string]*ollama.Schema{
+		"instanceTypes": {Type: "array", Items: &ollama.Schema{Type: "string"}, MinItems: 1},
+		"nodeCount":     {Type: "integer", Minimum: ollama.Bound(0)},
+		"maxSize":       {Type: "integer", Minimum: ollama.Bound(0)},
+		"capacityType":  {Type: "string", Enum: []string{"spot", "on-demand"}},
+		"rationale":     {Type: "string", MinLength: 1, MaxLength: 40},
+	},
+	Required: []string{"instanceTypes", "nodeCount", "capacityType", "rationale"},
+}
+
+type advice struct {
+	InstanceTypes []string `json:"instanceTypes"`
+	NodeCount     int      `json:"nodeCount"`
+	MaxSize       *int     `json:"maxSize"`
+	CapacityType  string   `json:"capacityType"`
+	Rationale     string   `json:"rationale"`
+}
+
+func TestSchemaValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  []string
+	}{
+		{"valid", `{"instanceTypes": ["m6i.large"], "nodeCount": 3, "maxSize": null, "capacityType": "spot", "rationale": "fits", "extra": 1}`, nil},
+		{"missing fields", `{"instanceTypes": ["m6i.large"]}`, []string{`$: missing required field "nodeCount"`, `$: missing required field "capacityType"`, `$: missing required field "rationale"`}},
+		{"wrong types", `{"instanceTypes": "m6i.large", "nodeCount": 2.5, "capacityType": "reserved", "rationale": " "}`, []string{"$.capacityType: must be one of spot, on-demand", "$.instanceTypes: expected an array", "$.nodeCount: expected an integer", "$.rationale: must have at least 1 character(s)"}},
+		{"bounds", `{"instanceTypes": [], "nodeCount": -1, "capacityType": "spot", "rationale": "a rationale that is far longer than forty characters"}`, []string{"$.instanceTypes: must have at least 1 item(s)", "$.nodeCount: must be at least 0", "$.rationale: must have at most 40 characters"}},
+		{"items and null", `{"instanceTypes": ["m6i.large", 4], "nodeCount": null, "capacityType": "spot", "rationale": "fits"}`, []string{"$.instanceTypes[1]: expected a string", "$.nodeCount: must not be null"}},
+		{"not an object", `[]`, []string{"$: expected an object"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value interface{}
+			require.NoError(t, json.Unmarshal([]byte(tt.reply), &value))
+			assert.Equal(t, tt.want, adviceSchema.Validate(value))
+		})
+	}
+}
+
+func TestChatJSON(t *testing.T) {
+	valid := `{"instanceTypes": ["m6i.large", "m7i.large"], "nodeCount": 3, "capacityType": "spot", "rationale": "Usage fits 3 nodes"}`
+
+	for _, provider := range []string{"litellm", "ollama"} {
+		t.Run(provider+" sends the schema", func(t *testing.T) {
+			server := testutil.NewFakeLLMServer(ollama.Message{Role: "assistant", Content: "```json\n" + valid + "\n```"})
+			defer server.Close()
+			client := ollama.NewClient(server.URL, "test-model", provider, "", false)
+
+			var out advice
+			require.NoError(t, client.ChatJSON(context.Background(), "Size the NodePool", adviceSchema, &out))
+			assert.Equal(t, advice{InstanceTypes: []string{"m6i.large", "m7i.large"}, NodeCount: 3, CapacityType: "spot", Rationale: "Usage fits 3 nodes"}, out)
+
+			requests := server.Requests()
+			require.Len(t, requests, 1)
+			assert.Contains(t, requests[0].Messages[0].Content, "Size the NodePool")
+			assert.Contains(t, requests[0].Messages[0].Content, `"capacityType":{"type":"string","enum":["spot","on-demand"]}`)
+			var sent *ollama.Schema
+			if provider == "litellm" {
+				var format struct {
+					Type       string `json:"type"`
+					JSONSchema struct {
+						Name   string         `json:"name"`
+						Schema *ollama.Schema `json:"schema"`
+					} `json:"json_schema"`
+				}
+				require.NoError(t, json.Unmarshal(requests[0].ResponseFormat, &format))
+				assert.Equal(t, "json_schema", format.Type)
+				sent = format.JSONSchema.Schema
+			} else {
+				require.NoError(t, json.Unmarshal(requests[0].Format, &sent))
+			}
+			assert.Equal(t, adviceSchema, sent)
+		})
+	}
+
+	t.Run("repairs an invalid reply", func(t *testing.T) {
+		server := testutil.NewFakeLLMServer(
+			ollama.Message{Role: "assistant", Content: `{"instanceTypes": ["m6i.large"], "nodeCount": "3", "capacityType": "spot"}`},
+			ollama.Message{Role: "assistant", Content: valid},
+		)
+		defer server.Close()
+		client := ollama.NewClient(server.URL, "test-model", "litellm", "", false)
+
+		var out advice
+		require.NoError(t, client.ChatJSON(context.Background(), "Size the NodePool", adviceSchema, &out))
+		assert.Equal(t, 3, out.NodeCount)
+
+		repair := server.Requests()[1].Messages
+		require.Len(t, repair, 3)
+		assert.Equal(t, "assistant", repair[1].Role)
+		assert.Contains(t, repair[2].Content, `$: missing required field "rationale"`)
+		assert.Contains(t, repair[2].Content, "$.nodeCount: expected a number")
+	})
+
+	t.Run("returns a schema error after the repair", func(t *testing.T) {
+		server := testutil.NewFakeLLMServer(
+			ollama.Message{Role: "assistant", Content: "I recommend m6i.large."},
+			ollama.Message{Role: "assistant", Content: `{"instanceTypes": ["m6i.large"], "nodeCount": 3, "capacityType": "reserved", "rationale": "fits"`},
+		)
+		defer server.Close()
+		client := ollama.NewClient(server.URL, "test-model", "ollama", "", false)
+
+		var out advice
+		err := client.ChatJSON(context.Background(), "Size the NodePool", adviceSchema, &out)
+		var schemaErr *ollama.SchemaError
+		require.True(t, errors.As(err, &schemaErr))
+		assert.Len(t, schemaErr.Errors, 1)
+		assert.Contains(t, schemaErr.Errors[0], "no JSON object found")
+		assert.Contains(t, server.Requests()[1].Messages[2].Content, "no JSON object found")
+		assert.Len(t, server.Requests(), 2)
+	})
+}
@@ -0,0 +1,139 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Tool is a function the model may call (OpenAI function calling format, also
+// accepted by Ollama)
+type Tool struct {
+	Type     string       `json:"type"` // Always "function"
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a callable function; Parameters is a JSON Schema object
+type ToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// ToolCall is a function call requested by the model
+type ToolCall struct {
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction names the function to call and its JSON-encoded arguments
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// UnmarshalJSON accepts arguments as a JSON-encoded string (OpenAI) or as an
+// object (Ollama)
+func (f *ToolCallFunction) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	f.Name = raw.Name
+	f.Arguments = "{}"
+	args := bytes.TrimSpace(raw.Arguments)
+	switch {
+	case len(args) == 0 || bytes.Equal(args, []byte("null")):
+	case args[0] == '"':
+		if err := json.Unmarshal(args, &f.Arguments); err != nil {
+			return err
+		}
+		if f.Arguments == "" {
+			f.Arguments = "{}"
+		}
+	default:
+		f.Arguments = string(args)
+	}
+	return nil
+}
+
+// NewFunctionTool describes a function tool
+func NewFunctionTool(name, description string, parameters map[string]interface{}) Tool {
+	return Tool{Type: "function", Function: ToolFunction{Name: name, Description: description, Parameters: parameters}}
+}
+
+// liteLLMToolRequest is the OpenAI-compatible chat request with tools
+type liteLLMToolRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Tools    []Tool    `json:"tools,omitempty"`
+	Stream   bool      `json:"stream,omitempty"`
+}
+
+// ollamaToolRequest is the Ollama chat request with tools. Ollama expects tool
+// call arguments as objects rather than strings.
+type ollamaToolRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []Tool          `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// ChatWithTools sends a conversation with the tools the model may call and returns
+// the model's reply. A reply with ToolCalls asks for those functions to be run and
+// their results sent back as "tool" messages; otherwise Content is the answer.
+func (c *Client) ChatWithTools(ctx context.Context, messages []Message, tools []Tool) (*Message, error) {
+	return c.chat(ctx, ChatParams{Messages: messages, Tools: tools})
+}
+
+// toOllamaMessages converts messages to the Ollama format, which expects tool call
+// arguments as objects rather than strings
+func toOllamaMessages(messages []Message) ([]ollamaMessage, error) {
+	converted := make([]ollamaMessage, 0, len(messages))
+	for _, m := range messages {
+		om := ollamaMessage{Role: m.Role, Content: m.Content}
+		if m.Role == "tool" {
+			om.ToolName = m.Name
+		}
+		for _, call := range m.ToolCalls {
+			var oc ollamaToolCall
+			oc.Function.Name = call.Function.Name
+			oc.Function.Arguments = json.RawMessage(call.Function.Arguments)
+			if !json.Valid(oc.Function.Arguments) {
+				return nil, fmt.Errorf("invalid arguments for tool call %s: %s", call.Function.Name, call.Function.Arguments)
+			}
+			om.ToolCalls = append(om.ToolCalls, oc)
+		}
+		converted = append(converted, om)
+	}
+	return converted, nil
+}
+
+// Model returns the configured model name
+func (c *Client) Model() string {
+	return c.model
+}
+
+// Provider returns the primary provider (ollama, litellm, openai, anthropic or azure)
+func (c *Client) Provider() string {
+	return c.provider
+}
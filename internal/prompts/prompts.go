@@ -0,0 +1,202 @@
+// Package prompts renders the LLM prompts from versioned text/template files.
+// The templates are embedded in the binary; a directory holding files of the same name
+// (e.g. a mounted ConfigMap) overrides them without a rebuild.
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var embeddedTemplates embed.FS
+
+// Template names
+const (
+	NodePoolAdvice            = "nodepool-advice"            // NodePool recommendation from workloads
+	ClusterSummaryAdvice      = "cluster-summary-advice"     // NodePool recommendation from the cluster summary
+	ReasoningEnhancement      = "reasoning-enhancement"      // Rewrites a capacity recommendation's reasoning
+	RecommendationExplanation = "recommendation-explanation" // Benefits and risks of a capacity recommendation
+	LogErrorExplanation       = "log-error-explanation"      // Explains a Karpenter log error
+)
+
+// Template sources
+const (
+	SourceEmbedded = "embedded"
+	SourceOverride = "override"
+)
+
+// fileExt is the extension of template files, which are named after the template
+const fileExt = ".tmpl"
+
+// versionPattern matches the version comment every template starts with, e.g. {{- /* version: 3 */ -}}
+var versionPattern = regexp.MustCompile(`^\{\{-?\s*/\*\s*version:\s*([A-Za-z0-9._-]+)\s*\*/\s*-?\}\}`)
+
+var funcs = template.FuncMap{
+	"join": strings.Join,
+	"add":  func(a, b int) int { return a + b },
+	// percent returns part as a percentage of whole, 0 when whole is 0
+	"percent": func(part, whole float64) float64 {
+		if whole == 0 {
+			return 0
+		}
+		return part / whole * 100
+	},
+}
+
+// Template is a parsed prompt template
+type Template struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Source  string `json:"source"`
+	Path    string `json:"path,omitempty"` // Override file
+
+	tmpl *template.Template
+}
+
+// ID identifies the template version in AI outputs, e.g. "nodepool-advice@3"
+func (t *Template) ID() string {
+	return t.Name + "@" + t.Version
+}
+
+// Prompt is a rendered prompt and the version of the template it came from
+type Prompt struct {
+	Text    string
+	Version string // Template ID, e.g. "nodepool-advice@3"
+}
+
+// Set holds one template per name
+type Set struct {
+	templates map[string]*Template
+	embedded  map[string]*Template // Used when an override fails to render
+}
+
+var (
+	defaultSet     *Set
+	defaultSetOnce sync.Once
+)
+
+// Default returns the embedded templates
+func Default() *Set {
+	defaultSetOnce.Do(func() {
+		templates := make(map[string]*Template)
+		for _, name := range Names() {
+			data, err := embeddedTemplates.ReadFile("templates/" + name + fileExt)
+			if err != nil {
+				panic(fmt.Sprintf("missing embedded prompt template %s: %v", name, err))
+			}
+			tmpl, err := Parse(name, data)
+			if err != nil {
+				// Embedded templates are covered by tests; failing here is a build defect
+				panic(fmt.Sprintf("invalid embedded prompt template: %v", err))
+			}
+			tmpl.Source = SourceEmbedded
+			templates[name] = tmpl
+		}
+		defaultSet = &Set{templates: templates, embedded: templates}
+	})
+	return defaultSet
+}
+
+// Names returns the template names
+func Names() []string {
+	return []string{NodePoolAdvice, ClusterSummaryAdvice, ReasoningEnhancement, RecommendationExplanation, LogErrorExplanation}
+}
+
+// Load returns the embedded templates with the *.tmpl files in dir (if set) overriding
+// the templates of the same name. Other files, such as a ConfigMap's ..data links, are ignored.
+func Load(dir string) (*Set, error) {
+	if dir == "" {
+		return Default(), nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt templates directory: %w", err)
+	}
+	base := Default()
+	set := &Set{templates: make(map[string]*Template, len(base.templates)), embedded: base.embedded}
+	for name, tmpl := range base.templates {
+		set.templates[name] = tmpl
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), fileExt) {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), fileExt)
+		if _, ok := base.templates[name]; !ok {
+			return nil, fmt.Errorf("unknown prompt template %s (expected one of %s)", entry.Name(), strings.Join(Names(), ", "))
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt template: %w", err)
+		}
+		tmpl, err := Parse(name, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load prompt templates from %s: %w", dir, err)
+		}
+		tmpl.Source = SourceOverride
+		tmpl.Path = path
+		set.templates[name] = tmpl
+	}
+	return set, nil
+}
+
+// Parse parses a template, which must start with a version comment
+func Parse(name string, data []byte) (*Template, error) {
+	match := versionPattern.FindSubmatch(bytes.TrimLeft(data, " \t\r\n"))
+	if match == nil {
+		return nil, fmt.Errorf("template %s: must start with a version comment, e.g. {{- /* version: 1 */ -}}", name)
+	}
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=zero").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	return &Template{Name: name, Version: string(match[1]), tmpl: tmpl}, nil
+}
+
+// Render executes the named template. When an override fails to render (e.g. it uses a
+// field that does not exist), the embedded template is used instead.
+func (s *Set) Render(name string, data interface{}) (Prompt, error) {
+	tmpl, ok := s.templates[name]
+	if !ok {
+		return Prompt{}, fmt.Errorf("unknown prompt template %s", name)
+	}
+	text, err := tmpl.execute(data)
+	if err != nil && tmpl.Source == SourceOverride {
+		fmt.Printf("Warning: prompt template %s from %s failed, using embedded template: %v\n", tmpl.ID(), tmpl.Path, err)
+		tmpl = s.embedded[name]
+		text, err = tmpl.execute(data)
+	}
+	if err != nil {
+		return Prompt{}, fmt.Errorf("failed to render prompt template %s: %w", tmpl.ID(), err)
+	}
+	return Prompt{Text: text, Version: tmpl.ID()}, nil
+}
+
+func (t *Template) execute(data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// List returns the templates in use, sorted by name
+func (s *Set) List() []Template {
+	list := make([]Template, 0, len(s.templates))
+	for _, tmpl := range s.templates {
+		list = append(list, *tmpl)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
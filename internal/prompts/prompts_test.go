@@ -0,0 +1,102 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTemplate(t *testing.T, dir, file, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0644))
+}
+
+func TestDefault(t *testing.T) {
+	set := Default()
+	list := set.List()
+	require.Len(t, list, len(Names()))
+	for _, tmpl := range list {
+		assert.Equal(t, "1", tmpl.Version, tmpl.Name)
+		assert.Equal(t, SourceEmbedded, tmpl.Source, tmpl.Name)
+		assert.Empty(t, tmpl.Path, tmpl.Name)
+	}
+
+	loaded, err := Load("")
+	require.NoError(t, err)
+	assert.Same(t, set, loaded)
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("override replaces the embedded template", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTemplate(t, dir, "log-error-explanation.tmpl", "{{- /* version: 2 */ -}}\nExplain this {{.ErrorType}} error.\n")
+		writeTemplate(t, dir, "README.md", "ignored")
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "..data"), 0755))
+
+		set, err := Load(dir)
+		require.NoError(t, err)
+		prompt, err := set.Render(LogErrorExplanation, map[string]string{"ErrorType": "eviction"})
+		require.NoError(t, err)
+		assert.Equal(t, Prompt{Text: "Explain this eviction error.", Version: "log-error-explanation@2"}, prompt)
+
+		for _, tmpl := range set.List() {
+			if tmpl.Name == LogErrorExplanation {
+				assert.Equal(t, SourceOverride, tmpl.Source)
+				assert.Equal(t, filepath.Join(dir, "log-error-explanation.tmpl"), tmpl.Path)
+			} else {
+				assert.Equal(t, SourceEmbedded, tmpl.Source)
+			}
+		}
+		// The embedded set is unchanged
+		assert.Equal(t, SourceEmbedded, Default().templates[LogErrorExplanation].Source)
+	})
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{"missing version", "nodepool-advice.tmpl", "Size {{.NodePool.Name}}", "must start with a version comment"},
+		{"version not first", "nodepool-advice.tmpl", "Size it\n{{/* version: 2 */}}", "must start with a version comment"},
+		{"syntax error", "nodepool-advice.tmpl", "{{/* version: 2 */}}\n{{if .MaxGPU}}GPU", "template nodepool-advice"},
+		{"unknown template", "nodepool-sizing.tmpl", "{{/* version: 1 */}}", "unknown prompt template nodepool-sizing.tmpl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTemplate(t, dir, tt.file, tt.content)
+			_, err := Load(dir)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := Load(filepath.Join(t.TempDir(), "missing"))
+		assert.ErrorContains(t, err, "failed to read prompt templates directory")
+	})
+}
+
+func TestRenderFallsBackToEmbedded(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "log-error-explanation.tmpl", "{{- /* version: 2 */ -}}\nExplain {{.Severity}}")
+	set, err := Load(dir)
+	require.NoError(t, err)
+
+	data := struct {
+		ErrorType string
+		Context   []string
+		Causes    []struct{ Severity, Error, Explanation string }
+	}{ErrorType: "scheduling", Context: []string{"- NodePool: default"}}
+	prompt, err := set.Render(LogErrorExplanation, data)
+	require.NoError(t, err)
+	assert.Equal(t, "log-error-explanation@1", prompt.Version)
+	assert.Contains(t, prompt.Text, "Error Type: scheduling")
+	assert.Contains(t, prompt.Text, "Available Context:\n- NodePool: default\n")
+
+	_, err = set.Render("unknown", data)
+	assert.ErrorContains(t, err, "unknown prompt template unknown")
+}
@@ -0,0 +1,377 @@
+package recommender
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+)
+
+// Churn analysis defaults
+const (
+	defaultShortLivedThreshold = time.Hour
+	defaultNodeBootTime        = 3 * time.Minute // Launch to Ready: billed while no pods can run
+	ec2BilledMinimum           = time.Minute     // EC2 per-second billing has a 60 second minimum
+	karpenterDefaultExpiry     = 720 * time.Hour // Karpenter's default expireAfter
+)
+
+// ChurnOptions tunes the churn cost estimate
+type ChurnOptions struct {
+	ShortLivedThreshold time.Duration // Nodes removed before this age count as short-lived
+	BootTime            time.Duration // Time a new node is billed before it can run pods
+}
+
+// LifetimeBucket counts disrupted nodes by age at removal
+type LifetimeBucket struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// LifetimeDistribution summarizes how long disrupted nodes lived
+type LifetimeDistribution struct {
+	Count     int              `json:"count"`
+	MinHours  float64          `json:"minHours"`
+	P50Hours  float64          `json:"p50Hours"`
+	P90Hours  float64          `json:"p90Hours"`
+	MaxHours  float64          `json:"maxHours"`
+	MeanHours float64          `json:"meanHours"`
+	Buckets   []LifetimeBucket `json:"buckets"`
+}
+
+// ChurnDay is one day of disruption activity
+type ChurnDay struct {
+	Date             string `json:"date"` // YYYY-MM-DD (UTC)
+	Consolidation    int    `json:"consolidation"`
+	Drift            int    `json:"drift"`
+	Expiration       int    `json:"expiration"`
+	SpotInterruption int    `json:"spotInterruption"`
+	Other            int    `json:"other"`
+	PodsEvicted      int    `json:"podsEvicted"`
+}
+
+// ChurnRecommendation suggests NodePool disruption settings
+type ChurnRecommendation struct {
+	ConsolidateAfter string   `json:"consolidateAfter,omitempty"` // Empty when the current value is fine
+	ExpireAfter      string   `json:"expireAfter,omitempty"`      // Empty when the current value is fine
+	Reasons          []string `json:"reasons"`
+}
+
+// NodePoolChurnReport is the disruption churn of one NodePool
+type NodePoolChurnReport struct {
+	NodePool            string               `json:"nodePool"`
+	ConsolidationPolicy string               `json:"consolidationPolicy,omitempty"`
+	ConsolidateAfter    string               `json:"consolidateAfter,omitempty"` // Current setting
+	ExpireAfter         string               `json:"expireAfter,omitempty"`      // Current setting
+	CurrentNodes        int                  `json:"currentNodes"`
+	Disruptions         int                  `json:"disruptions"`
+	ByReason            map[string]int       `json:"byReason"`
+	DisruptionsPerDay   float64              `json:"disruptionsPerDay"`
+	PodsEvicted         int                  `json:"podsEvicted"`
+	PodsEvictedPerDay   float64              `json:"podsEvictedPerDay"`
+	Lifetime            LifetimeDistribution `json:"lifetime"`
+	Daily               []ChurnDay           `json:"daily"`
+	ShortLivedNodes     int                  `json:"shortLivedNodes"`
+	WastedCost          float64              `json:"wastedCost"` // USD over the window
+	WastedCostPerMonth  float64              `json:"wastedCostPerMonth"`
+	WastedCostByReason  map[string]float64   `json:"wastedCostByReason"`
+	Recommendation      ChurnRecommendation  `json:"recommendation"`
+}
+
+// churnRecord is a disruption with its lifetime resolved
+type churnRecord struct {
+	info     kubernetes.NodeDisruptionInfo
+	reason   string
+	removed  time.Time
+	lifetime time.Duration // 0 when unknown
+}
+
+// AnalyzeDisruptionChurn builds a churn report per NodePool from disruption records within [since, now].
+// Wasted cost is what short-lived nodes paid for boot time and the EC2 billed minimum: capacity that
+// was never usable by pods and would not have been paid had the node not been churned.
+func (r *Recommender) AnalyzeDisruptionChurn(ctx context.Context, disruptions []kubernetes.NodeDisruptionInfo, nodePools []kubernetes.NodePoolInfo, since, now time.Time, opts ChurnOptions) []NodePoolChurnReport {
+	if opts.ShortLivedThreshold <= 0 {
+		opts.ShortLivedThreshold = defaultShortLivedThreshold
+	}
+	if opts.BootTime <= 0 {
+		opts.BootTime = defaultNodeBootTime
+	}
+	days := now.Sub(since).Hours() / 24
+	if days <= 0 {
+		days = 1
+	}
+
+	byPool := make(map[string][]churnRecord)
+	for _, d := range disruptions {
+		if d.NodePool == "" {
+			continue
+		}
+		removed := parseChurnTime(d.DeletedTime)
+		if removed.IsZero() {
+			removed = parseChurnTime(d.LastSeen)
+		}
+		if removed.IsZero() || removed.Before(since) || removed.After(now) {
+			continue
+		}
+		record := churnRecord{info: d, reason: kubernetes.ClassifyDisruptionReason(d.Reason, d.Message), removed: removed}
+		if d.LifetimeSeconds > 0 {
+			record.lifetime = time.Duration(d.LifetimeSeconds * float64(time.Second))
+		} else if created := parseChurnTime(d.CreationTime); !created.IsZero() && removed.After(created) {
+			record.lifetime = removed.Sub(created)
+		}
+		byPool[d.NodePool] = append(byPool[d.NodePool], record)
+	}
+
+	poolInfo := make(map[string]kubernetes.NodePoolInfo, len(nodePools))
+	for _, np := range nodePools {
+		poolInfo[np.Name] = np
+	}
+
+	prices := make(map[string]float64) // instanceType/capacityType -> hourly cost
+	reports := make([]NodePoolChurnReport, 0, len(byPool))
+	for name, records := range byPool {
+		np := poolInfo[name]
+		report := NodePoolChurnReport{
+			NodePool:            name,
+			ConsolidationPolicy: np.ConsolidationPolicy,
+			ConsolidateAfter:    np.ConsolidateAfter,
+			ExpireAfter:         np.ExpireAfter,
+			CurrentNodes:        np.CurrentNodes,
+			Disruptions:         len(records),
+			ByReason:            make(map[string]int),
+			WastedCostByReason:  make(map[string]float64),
+		}
+
+		dailyByDate := make(map[string]*ChurnDay)
+		var lifetimes []time.Duration
+		for _, record := range records {
+			report.ByReason[record.reason]++
+			report.PodsEvicted += len(record.info.AffectedPods)
+
+			date := record.removed.UTC().Format("2006-01-02")
+			day, ok := dailyByDate[date]
+			if !ok {
+				day = &ChurnDay{Date: date}
+				dailyByDate[date] = day
+			}
+			day.PodsEvicted += len(record.info.AffectedPods)
+			switch record.reason {
+			case kubernetes.DisruptionReasonConsolidation:
+				day.Consolidation++
+			case kubernetes.DisruptionReasonDrift:
+				day.Drift++
+			case kubernetes.DisruptionReasonExpiration:
+				day.Expiration++
+			case kubernetes.DisruptionReasonSpotInterruption:
+				day.SpotInterruption++
+			default:
+				day.Other++
+			}
+
+			if record.lifetime <= 0 {
+				continue
+			}
+			lifetimes = append(lifetimes, record.lifetime)
+			if record.lifetime >= opts.ShortLivedThreshold {
+				continue
+			}
+			report.ShortLivedNodes++
+			if record.info.InstanceType == "" {
+				continue
+			}
+			capacityType := record.info.CapacityType
+			if capacityType == "" {
+				capacityType = "on-demand"
+			}
+			priceKey := record.info.InstanceType + "/" + capacityType
+			hourlyCost, ok := prices[priceKey]
+			if !ok {
+				hourlyCost = r.estimateCost(ctx, []string{record.info.InstanceType}, capacityType, 1)
+				prices[priceKey] = hourlyCost
+			}
+			wasted := hourlyCost * wastedChurnTime(record.lifetime, opts.BootTime).Hours()
+			report.WastedCost += wasted
+			report.WastedCostByReason[record.reason] += wasted
+		}
+
+		for _, day := range dailyByDate {
+			report.Daily = append(report.Daily, *day)
+		}
+		sort.Slice(report.Daily, func(i, j int) bool { return report.Daily[i].Date < report.Daily[j].Date })
+
+		report.DisruptionsPerDay = float64(report.Disruptions) / days
+		report.PodsEvictedPerDay = float64(report.PodsEvicted) / days
+		report.WastedCostPerMonth = report.WastedCost / days * 30
+		report.Lifetime = lifetimeDistribution(lifetimes)
+		report.Recommendation = recommendChurnSettings(report, records, opts.ShortLivedThreshold, days)
+		reports = append(reports, report)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].WastedCost != reports[j].WastedCost {
+			return reports[i].WastedCost > reports[j].WastedCost
+		}
+		return reports[i].Disruptions > reports[j].Disruptions
+	})
+	return reports
+}
+
+// wastedChurnTime is the billed time of a short-lived node that did no work: its boot time,
+// plus whatever the billing minimum charged beyond the node's actual life
+func wastedChurnTime(lifetime, bootTime time.Duration) time.Duration {
+	wasted := bootTime
+	if lifetime < bootTime {
+		wasted = lifetime
+	}
+	if lifetime < ec2BilledMinimum {
+		wasted += ec2BilledMinimum - lifetime
+	}
+	return wasted
+}
+
+// lifetimeDistribution computes percentiles and fixed buckets of node lifetimes
+func lifetimeDistribution(lifetimes []time.Duration) LifetimeDistribution {
+	buckets := []struct {
+		label string
+		max   time.Duration
+	}{
+		{"<15m", 15 * time.Minute},
+		{"15m-1h", time.Hour},
+		{"1h-6h", 6 * time.Hour},
+		{"6h-24h", 24 * time.Hour},
+		{"1d-7d", 7 * 24 * time.Hour},
+		{">7d", time.Duration(math.MaxInt64)},
+	}
+	dist := LifetimeDistribution{Count: len(lifetimes), Buckets: make([]LifetimeBucket, len(buckets))}
+	for i, b := range buckets {
+		dist.Buckets[i].Label = b.label
+	}
+	if len(lifetimes) == 0 {
+		return dist
+	}
+
+	sorted := append([]time.Duration{}, lifetimes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	total := 0.0
+	for _, lifetime := range sorted {
+		total += lifetime.Hours()
+		for i, b := range buckets {
+			if lifetime < b.max {
+				dist.Buckets[i].Count++
+				break
+			}
+		}
+	}
+	dist.MinHours = roundHours(sorted[0])
+	dist.MaxHours = roundHours(sorted[len(sorted)-1])
+	dist.P50Hours = roundHours(durationPercentile(sorted, 0.5))
+	dist.P90Hours = roundHours(durationPercentile(sorted, 0.9))
+	dist.MeanHours = math.Round(total/float64(len(sorted))*100) / 100
+	return dist
+}
+
+// durationPercentile returns the nearest-rank percentile of sorted durations
+func durationPercentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
+
+func roundHours(d time.Duration) float64 {
+	return math.Round(d.Hours()*100) / 100
+}
+
+// recommendChurnSettings suggests consolidateAfter and expireAfter from the observed churn
+func recommendChurnSettings(report NodePoolChurnReport, records []churnRecord, shortLived time.Duration, days float64) ChurnRecommendation {
+	rec := ChurnRecommendation{Reasons: []string{}}
+
+	// consolidateAfter: nodes consolidated soon after launch mean capacity is being removed and re-added
+	// as load fluctuates. Waiting about as long as those nodes lived keeps them through the dip.
+	var consolidated, shortConsolidated []time.Duration
+	for _, record := range records {
+		if record.reason != kubernetes.DisruptionReasonConsolidation || record.lifetime <= 0 {
+			continue
+		}
+		consolidated = append(consolidated, record.lifetime)
+		if record.lifetime < shortLived {
+			shortConsolidated = append(shortConsolidated, record.lifetime)
+		}
+	}
+	current, currentSet := parseKarpenterDuration(report.ConsolidateAfter)
+	if len(consolidated) > 0 && len(shortConsolidated)*100/len(consolidated) >= 25 && float64(len(shortConsolidated))/days >= 1 {
+		sort.Slice(shortConsolidated, func(i, j int) bool { return shortConsolidated[i] < shortConsolidated[j] })
+		suggested := durationPercentile(shortConsolidated, 0.75).Round(5 * time.Minute)
+		if suggested < 5*time.Minute {
+			suggested = 5 * time.Minute
+		}
+		if !currentSet || current < suggested {
+			rec.ConsolidateAfter = formatKarpenterDuration(suggested)
+			rec.Reasons = append(rec.Reasons, fmt.Sprintf(
+				"%d of %d consolidated nodes lived less than %s (%.1f per day); consolidateAfter=%s keeps capacity through short load dips instead of relaunching it",
+				len(shortConsolidated), len(consolidated), formatKarpenterDuration(shortLived), float64(len(shortConsolidated))/days, rec.ConsolidateAfter))
+		}
+	}
+
+	// expireAfter: expirations are planned churn. Many per day with a short expiry means nodes are
+	// recycled more often than patching requires; no expiry at all means nodes never pick up new AMIs.
+	expiry, expirySet := parseKarpenterDuration(report.ExpireAfter)
+	expirations := report.ByReason[kubernetes.DisruptionReasonExpiration]
+	switch {
+	case expirySet && expiry < 7*24*time.Hour && expirations > 0 && report.Disruptions > 0 && expirations*100/report.Disruptions >= 30:
+		suggested := expiry * 2
+		if suggested < 7*24*time.Hour {
+			suggested = 7 * 24 * time.Hour
+		}
+		rec.ExpireAfter = formatKarpenterDuration(suggested)
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf(
+			"Expiration causes %d of %d disruptions (%.1f per day) with expireAfter=%s; expireAfter=%s halves planned churn while still rotating nodes weekly or better",
+			expirations, report.Disruptions, float64(expirations)/days, report.ExpireAfter, rec.ExpireAfter))
+	case strings.EqualFold(report.ExpireAfter, "Never") && report.Lifetime.MaxHours > karpenterDefaultExpiry.Hours():
+		rec.ExpireAfter = formatKarpenterDuration(karpenterDefaultExpiry)
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf(
+			"Nodes live up to %.0f days with expireAfter=Never; expireAfter=%s rotates them onto patched AMIs",
+			report.Lifetime.MaxHours/24, rec.ExpireAfter))
+	}
+
+	if len(rec.Reasons) == 0 {
+		rec.Reasons = append(rec.Reasons, "Disruption churn is within normal bounds; keep current settings")
+	}
+	return rec
+}
+
+// parseKarpenterDuration parses a Karpenter duration ("30s", "720h"); "Never" and empty are not set
+func parseKarpenterDuration(value string) (time.Duration, bool) {
+	if value == "" || strings.EqualFold(value, "Never") {
+		return 0, false
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
+
+// formatKarpenterDuration renders a duration the way NodePool specs usually write it ("30m", "168h")
+func formatKarpenterDuration(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	if d >= time.Minute && d%time.Minute == 0 {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%ds", int(d.Seconds()))
+}
+
+// parseChurnTime parses an RFC3339 timestamp, returning zero time on failure
+func parseChurnTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
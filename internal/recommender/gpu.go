@@ -0,0 +1,420 @@
+package recommender
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+)
+
+// GPUInstanceSpec describes an AWS GPU instance type
+type GPUInstanceSpec struct {
+	InstanceType string  `json:"instanceType"`
+	GPUModel     string  `json:"gpuModel"`     // Normalized GPU model (t4, a10g, l4, l40s, v100, a100, h100)
+	GPUCount     int     `json:"gpuCount"`     // Physical GPUs per instance
+	GPUMemoryGiB float64 `json:"gpuMemoryGiB"` // Memory per GPU
+	VCPU         float64 `json:"vcpu"`
+	MemoryGiB    float64 `json:"memoryGiB"`
+	MIGCapable   bool    `json:"migCapable"`
+}
+
+// gpuInstanceCatalog lists supported GPU instance types, cheapest family first.
+// Pricing comes from estimateCost so the AWS Pricing API is used when available.
+var gpuInstanceCatalog = []GPUInstanceSpec{
+	{InstanceType: "g4dn.xlarge", GPUModel: "t4", GPUCount: 1, GPUMemoryGiB: 16, VCPU: 4, MemoryGiB: 16},
+	{InstanceType: "g4dn.2xlarge", GPUModel: "t4", GPUCount: 1, GPUMemoryGiB: 16, VCPU: 8, MemoryGiB: 32},
+	{InstanceType: "g4dn.4xlarge", GPUModel: "t4", GPUCount: 1, GPUMemoryGiB: 16, VCPU: 16, MemoryGiB: 64},
+	{InstanceType: "g4dn.12xlarge", GPUModel: "t4", GPUCount: 4, GPUMemoryGiB: 16, VCPU: 48, MemoryGiB: 192},
+	{InstanceType: "g6.xlarge", GPUModel: "l4", GPUCount: 1, GPUMemoryGiB: 24, VCPU: 4, MemoryGiB: 16},
+	{InstanceType: "g6.2xlarge", GPUModel: "l4", GPUCount: 1, GPUMemoryGiB: 24, VCPU: 8, MemoryGiB: 32},
+	{InstanceType: "g6.4xlarge", GPUModel: "l4", GPUCount: 1, GPUMemoryGiB: 24, VCPU: 16, MemoryGiB: 64},
+	{InstanceType: "g6.12xlarge", GPUModel: "l4", GPUCount: 4, GPUMemoryGiB: 24, VCPU: 48, MemoryGiB: 192},
+	{InstanceType: "g5.xlarge", GPUModel: "a10g", GPUCount: 1, GPUMemoryGiB: 24, VCPU: 4, MemoryGiB: 16},
+	{InstanceType: "g5.2xlarge", GPUModel: "a10g", GPUCount: 1, GPUMemoryGiB: 24, VCPU: 8, MemoryGiB: 32},
+	{InstanceType: "g5.4xlarge", GPUModel: "a10g", GPUCount: 1, GPUMemoryGiB: 24, VCPU: 16, MemoryGiB: 64},
+	{InstanceType: "g5.12xlarge", GPUModel: "a10g", GPUCount: 4, GPUMemoryGiB: 24, VCPU: 48, MemoryGiB: 192},
+	{InstanceType: "g5.48xlarge", GPUModel: "a10g", GPUCount: 8, GPUMemoryGiB: 24, VCPU: 192, MemoryGiB: 768},
+	{InstanceType: "g6e.xlarge", GPUModel: "l40s", GPUCount: 1, GPUMemoryGiB: 48, VCPU: 4, MemoryGiB: 32},
+	{InstanceType: "g6e.12xlarge", GPUModel: "l40s", GPUCount: 4, GPUMemoryGiB: 48, VCPU: 48, MemoryGiB: 384},
+	{InstanceType: "p3.2xlarge", GPUModel: "v100", GPUCount: 1, GPUMemoryGiB: 16, VCPU: 8, MemoryGiB: 61},
+	{InstanceType: "p3.8xlarge", GPUModel: "v100", GPUCount: 4, GPUMemoryGiB: 16, VCPU: 32, MemoryGiB: 244},
+	{InstanceType: "p4d.24xlarge", GPUModel: "a100", GPUCount: 8, GPUMemoryGiB: 40, VCPU: 96, MemoryGiB: 1152, MIGCapable: true},
+	{InstanceType: "p5.48xlarge", GPUModel: "h100", GPUCount: 8, GPUMemoryGiB: 80, VCPU: 192, MemoryGiB: 2048, MIGCapable: true},
+}
+
+// migSliceMemoryGiB is the memory of the smallest MIG slice per GPU model
+var migSliceMemoryGiB = map[string]float64{
+	"a100": 5,
+	"h100": 10,
+}
+
+// migSlicesPerGPU is the number of compute slices a MIG-capable GPU is partitioned into
+const migSlicesPerGPU = 7
+
+// lookupGPUInstance returns the catalog entry for an instance type
+func lookupGPUInstance(instanceType string) (GPUInstanceSpec, bool) {
+	it := strings.ToLower(instanceType)
+	for _, spec := range gpuInstanceCatalog {
+		if spec.InstanceType == it {
+			return spec, true
+		}
+	}
+	return GPUInstanceSpec{}, false
+}
+
+// isGPUInstanceType reports whether an instance type belongs to a GPU/accelerator family
+func isGPUInstanceType(instanceType string) bool {
+	familyName, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(instanceType)), ".")
+	family, ok := parseInstanceFamily(familyName)
+	return ok && acceleratorCategories[family.category]
+}
+
+// normalizeGPUModel maps GPU product names from GPU feature discovery (Tesla-T4, NVIDIA-A10G),
+// Karpenter labels (a10g) and EKS accelerator labels (nvidia-tesla-t4) to a canonical model name
+func normalizeGPUModel(model string) string {
+	m := strings.ToLower(strings.TrimSpace(model))
+	if m == "" {
+		return ""
+	}
+	m = strings.TrimPrefix(m, "nvidia-")
+	m = strings.TrimPrefix(m, "tesla-")
+	// Order matters: l40s before l4, a100 before a10g
+	for _, known := range []string{"l40s", "l4", "a100", "a10g", "h100", "v100", "t4"} {
+		if strings.HasPrefix(m, known) {
+			return known
+		}
+	}
+	return m
+}
+
+// parseMIGProfile parses a MIG profile like "1g.5gb" into compute slices and memory
+func parseMIGProfile(profile string) (int, float64, bool) {
+	parts := strings.SplitN(strings.ToLower(profile), ".", 2)
+	if len(parts) != 2 || !strings.HasSuffix(parts[0], "g") || !strings.HasSuffix(parts[1], "gb") {
+		return 0, 0, false
+	}
+	slices, err := strconv.Atoi(strings.TrimSuffix(parts[0], "g"))
+	if err != nil || slices <= 0 {
+		return 0, 0, false
+	}
+	mem, err := strconv.ParseFloat(strings.TrimSuffix(parts[1], "gb"), 64)
+	if err != nil || mem <= 0 {
+		return 0, 0, false
+	}
+	return slices, mem, true
+}
+
+// GPURequirement summarizes what a set of GPU workloads needs from a NodePool
+type GPURequirement struct {
+	PerPodGPUs   int            `json:"perPodGPUs"`             // Largest full-GPU request of a single pod (must fit on one node)
+	TotalGPUs    float64        `json:"totalGPUs"`              // Physical GPU equivalents across workloads
+	Model        string         `json:"model,omitempty"`        // Normalized GPU model, empty if any model works
+	MinMemoryGiB float64        `json:"minMemoryGiB,omitempty"` // Minimum memory per GPU
+	MIGProfiles  map[string]int `json:"migProfiles,omitempty"`  // MIG profile -> slices requested
+	SharedGPUs   int            `json:"sharedGPUs,omitempty"`   // Time-sliced GPU replicas requested
+	Replicas     int            `json:"replicas,omitempty"`     // Time-slicing replicas per physical GPU
+}
+
+// needsGPU reports whether a workload requests any GPU resource (full, MIG slice or time-sliced)
+func (w Workload) needsGPU() bool {
+	return w.GPU > 0 || w.SharedGPU > 0 || len(w.MIGProfiles) > 0
+}
+
+// gpuRequirementFromWorkloads aggregates GPU requirements across workloads.
+// replicas is the time-slicing replica count per physical GPU (values < 1 mean no sharing).
+func gpuRequirementFromWorkloads(workloads []Workload, replicas int) GPURequirement {
+	if replicas < 1 {
+		replicas = 1
+	}
+	req := GPURequirement{Replicas: replicas}
+	migSlices := 0
+	for _, w := range workloads {
+		if !w.needsGPU() {
+			continue
+		}
+		if w.GPU > req.PerPodGPUs {
+			req.PerPodGPUs = w.GPU
+		}
+		req.TotalGPUs += float64(w.GPU)
+		req.SharedGPUs += w.SharedGPU
+
+		if model := normalizeGPUModel(w.GPUModel); model != "" && req.Model == "" {
+			req.Model = model
+		}
+		if mem := float64(w.GPUMemoryMiB) / 1024.0; mem > req.MinMemoryGiB {
+			req.MinMemoryGiB = mem
+		}
+		for profile, count := range w.MIGProfiles {
+			if req.MIGProfiles == nil {
+				req.MIGProfiles = make(map[string]int)
+			}
+			req.MIGProfiles[profile] += count
+			if slices, mem, ok := parseMIGProfile(profile); ok {
+				migSlices += slices * count
+				if mem > req.MinMemoryGiB {
+					req.MinMemoryGiB = mem
+				}
+			}
+		}
+	}
+	req.TotalGPUs += float64(migSlices) / migSlicesPerGPU
+	req.TotalGPUs += float64(req.SharedGPUs) / float64(replicas)
+	return req
+}
+
+// supportsMIGProfiles reports whether every requested MIG profile can be carved out of the instance's GPUs
+func (spec GPUInstanceSpec) supportsMIGProfiles(profiles map[string]int) bool {
+	if len(profiles) == 0 {
+		return true
+	}
+	if !spec.MIGCapable {
+		return false
+	}
+	sliceMem := migSliceMemoryGiB[spec.GPUModel]
+	for profile := range profiles {
+		slices, mem, ok := parseMIGProfile(profile)
+		if !ok || mem < float64(slices)*sliceMem || mem > spec.GPUMemoryGiB {
+			return false
+		}
+	}
+	return true
+}
+
+// GPUInstanceChoice is a candidate GPU instance type sized for a GPURequirement
+type GPUInstanceChoice struct {
+	GPUInstanceSpec
+	Nodes int     `json:"nodes"`
+	Cost  float64 `json:"cost"` // Hourly cost for all nodes
+}
+
+// selectGPUInstances returns GPU instance types that satisfy the requirement, cheapest first
+func (r *Recommender) selectGPUInstances(ctx context.Context, req GPURequirement, capacityType string) []GPUInstanceChoice {
+	physicalGPUs := math.Max(req.TotalGPUs, float64(req.PerPodGPUs))
+	if physicalGPUs <= 0 {
+		physicalGPUs = 1
+	}
+
+	var choices []GPUInstanceChoice
+	for _, spec := range gpuInstanceCatalog {
+		if req.Model != "" && spec.GPUModel != req.Model {
+			continue
+		}
+		if spec.GPUMemoryGiB < req.MinMemoryGiB {
+			continue
+		}
+		if spec.GPUCount < req.PerPodGPUs {
+			continue
+		}
+		if !spec.supportsMIGProfiles(req.MIGProfiles) {
+			continue
+		}
+		nodes := int(math.Ceil(physicalGPUs / float64(spec.GPUCount)))
+		if nodes < 1 {
+			nodes = 1
+		}
+		cost := r.estimateCost(ctx, []string{spec.InstanceType}, capacityType, nodes)
+		if cost <= 0 {
+			continue
+		}
+		choices = append(choices, GPUInstanceChoice{GPUInstanceSpec: spec, Nodes: nodes, Cost: cost})
+	}
+
+	sort.SliceStable(choices, func(i, j int) bool {
+		if choices[i].Cost != choices[j].Cost {
+			return choices[i].Cost < choices[j].Cost
+		}
+		return choices[i].GPUMemoryGiB > choices[j].GPUMemoryGiB
+	})
+	return choices
+}
+
+// gpuInstanceTypeNames returns up to limit instance type names from the choices
+func gpuInstanceTypeNames(choices []GPUInstanceChoice, limit int) []string {
+	names := make([]string, 0, limit)
+	for _, choice := range choices {
+		if len(names) >= limit {
+			break
+		}
+		names = append(names, choice.InstanceType)
+	}
+	return names
+}
+
+// describeGPURequirement renders a short human-readable summary of a GPU requirement
+func describeGPURequirement(req GPURequirement) string {
+	desc := fmt.Sprintf("%.1f GPU(s)", req.TotalGPUs)
+	if req.Model != "" {
+		desc += fmt.Sprintf(" of model %s", req.Model)
+	}
+	if req.MinMemoryGiB > 0 {
+		desc += fmt.Sprintf(" with >=%.0f GiB GPU memory", req.MinMemoryGiB)
+	}
+	if len(req.MIGProfiles) > 0 {
+		profiles := make([]string, 0, len(req.MIGProfiles))
+		for profile, count := range req.MIGProfiles {
+			if count > 0 {
+				profile = fmt.Sprintf("%dx%s", count, profile)
+			}
+			profiles = append(profiles, profile)
+		}
+		sort.Strings(profiles)
+		desc += fmt.Sprintf(" (MIG %s)", strings.Join(profiles, ", "))
+	}
+	if req.SharedGPUs > 0 {
+		desc += fmt.Sprintf(" (%d time-sliced replicas, %d per GPU)", req.SharedGPUs, req.Replicas)
+	}
+	return desc
+}
+
+// IdleGPUFinding reports GPUs on a node that are paid for but not doing work
+type IdleGPUFinding struct {
+	NodeName      string   `json:"nodeName"`
+	NodePool      string   `json:"nodePool"`
+	InstanceType  string   `json:"instanceType"`
+	GPUModel      string   `json:"gpuModel,omitempty"`
+	Allocatable   int      `json:"allocatable"`
+	Allocated     int      `json:"allocated"`
+	IdleAllocated int      `json:"idleAllocated"` // Held by pods that are not Ready
+	Unallocated   int      `json:"unallocated"`   // Not requested by any pod
+	IdlePods      []string `json:"idlePods,omitempty"`
+	HourlyCost    float64  `json:"hourlyCost"`
+	WastedCost    float64  `json:"wastedCost"` // Hourly cost attributable to idle GPU units
+}
+
+// DetectIdleGPUs finds nodes whose GPUs are unallocated or allocated to pods that are not Ready
+func (r *Recommender) DetectIdleGPUs(ctx context.Context, nodes []kubernetes.NodeInfo) []IdleGPUFinding {
+	var findings []IdleGPUFinding
+	for _, node := range nodes {
+		if node.GPU == nil || node.GPU.Allocatable == 0 {
+			continue
+		}
+		unallocated := node.GPU.Allocatable - node.GPU.Allocated
+		if unallocated < 0 {
+			unallocated = 0
+		}
+		idle := unallocated + node.GPU.IdleAllocated
+		if idle == 0 {
+			continue
+		}
+
+		capacityType := node.CapacityType
+		if capacityType == "" {
+			capacityType = "on-demand"
+		}
+		hourlyCost := 0.0
+		if node.InstanceType != "" {
+			hourlyCost = r.estimateCost(ctx, []string{node.InstanceType}, capacityType, 1)
+		}
+		findings = append(findings, IdleGPUFinding{
+			NodeName:      node.Name,
+			NodePool:      node.NodePool,
+			InstanceType:  node.InstanceType,
+			GPUModel:      normalizeGPUModel(node.GPU.Model),
+			Allocatable:   node.GPU.Allocatable,
+			Allocated:     node.GPU.Allocated,
+			IdleAllocated: node.GPU.IdleAllocated,
+			Unallocated:   unallocated,
+			IdlePods:      node.GPU.IdlePods,
+			HourlyCost:    hourlyCost,
+			WastedCost:    hourlyCost * float64(idle) / float64(node.GPU.Allocatable),
+		})
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].WastedCost > findings[j].WastedCost
+	})
+	return findings
+}
+
+// GPURecommendation is the GPU-specific part of a NodePool recommendation
+type GPURecommendation struct {
+	Requirement              GPURequirement      `json:"requirement"`
+	CurrentModel             string              `json:"currentModel,omitempty"`
+	CurrentGPUs              int                 `json:"currentGPUs"`   // Allocatable GPU units across nodes
+	AllocatedGPUs            int                 `json:"allocatedGPUs"` // GPU units requested by scheduled pods
+	IdleAllocatedGPUs        int                 `json:"idleAllocatedGPUs"`
+	UnallocatedGPUs          int                 `json:"unallocatedGPUs"`
+	IdleNodes                []IdleGPUFinding    `json:"idleNodes,omitempty"`
+	RecommendedInstanceTypes []string            `json:"recommendedInstanceTypes"`
+	Alternatives             []GPUInstanceChoice `json:"alternatives,omitempty"`
+}
+
+// gpuRequirementFromNodes derives the GPU requirement of a NodePool from what its Ready pods actually hold.
+// GPUs held by pods that are not Ready are excluded so they do not inflate the recommendation.
+func gpuRequirementFromNodes(nodes []kubernetes.NodeInfo) (GPURequirement, *GPURecommendation) {
+	rec := &GPURecommendation{}
+	req := GPURequirement{Replicas: 1}
+	for _, node := range nodes {
+		if node.GPU == nil {
+			continue
+		}
+		rec.CurrentGPUs += node.GPU.Allocatable
+		rec.AllocatedGPUs += node.GPU.Allocated
+		rec.IdleAllocatedGPUs += node.GPU.IdleAllocated
+		if unallocated := node.GPU.Allocatable - node.GPU.Allocated; unallocated > 0 {
+			rec.UnallocatedGPUs += unallocated
+		}
+
+		// Time-slicing replicas and MIG slices both advertise more units than physical GPUs
+		unitsPerGPU := 1.0
+		if node.GPU.Physical > 0 && node.GPU.Allocatable > node.GPU.Physical {
+			unitsPerGPU = float64(node.GPU.Allocatable) / float64(node.GPU.Physical)
+		}
+		req.TotalGPUs += float64(node.GPU.Allocated-node.GPU.IdleAllocated) / unitsPerGPU
+
+		model := normalizeGPUModel(node.GPU.Model)
+		if rec.CurrentModel == "" {
+			rec.CurrentModel = model
+		}
+		if node.GPU.Replicas > req.Replicas {
+			req.Replicas = node.GPU.Replicas
+		}
+		if len(node.GPU.MIGProfiles) > 0 {
+			// MIG partitions are laid out for a specific GPU, so stay on the same model
+			req.Model = model
+			if req.MIGProfiles == nil {
+				req.MIGProfiles = make(map[string]int)
+			}
+			// Slice counts per profile are not tracked per node; record the profile layout only
+			for _, profile := range node.GPU.MIGProfiles {
+				req.MIGProfiles[profile] = 0
+			}
+		}
+	}
+	if rec.CurrentGPUs == 0 {
+		return req, nil
+	}
+	req.PerPodGPUs = 1
+	rec.Requirement = req
+	return req, rec
+}
+
+// gpuReplicasFromNodes returns the largest time-slicing replica count advertised by the nodes
+func gpuReplicasFromNodes(nodes []kubernetes.NodeInfo) int {
+	replicas := 1
+	for _, node := range nodes {
+		if node.GPU != nil && node.GPU.Replicas > replicas {
+			replicas = node.GPU.Replicas
+		}
+	}
+	return replicas
+}
+
+// findOptimalGPUInstanceTypes picks the cheapest GPU instance type for a NodePool's GPU requirement.
+// GPU capacity is kept on its current capacity type since spot interruptions are costly for GPU jobs.
+func (r *Recommender) findOptimalGPUInstanceTypes(ctx context.Context, req GPURequirement, gpuRec *GPURecommendation, capacityType string) ([]string, int, float64, string) {
+	choices := r.selectGPUInstances(ctx, req, capacityType)
+	if len(choices) == 0 {
+		return []string{}, 0, 0.0, capacityType
+	}
+	if len(choices) > 3 {
+		gpuRec.Alternatives = choices[1:4]
+	} else {
+		gpuRec.Alternatives = choices[1:]
+	}
+	gpuRec.RecommendedInstanceTypes = []string{choices[0].InstanceType}
+	return []string{choices[0].InstanceType}, choices[0].Nodes, choices[0].Cost, capacityType
+}
@@ -0,0 +1,139 @@
+package recommender
+
+import "strings"
+
+// Size ladders shared by several families
+const (
+	sizesBurstable = "nano micro small medium large xlarge 2xlarge"
+	sizesGen4      = "large xlarge 2xlarge 4xlarge 8xlarge 16xlarge"
+	sizesGen5      = "large xlarge 2xlarge 4xlarge 8xlarge 12xlarge 16xlarge 24xlarge metal"
+	sizesGen5AMD   = "large xlarge 2xlarge 4xlarge 8xlarge 12xlarge 16xlarge 24xlarge"
+	sizesC5        = "large xlarge 2xlarge 4xlarge 9xlarge 12xlarge 18xlarge 24xlarge metal"
+	sizesGen6Intel = "large xlarge 2xlarge 4xlarge 8xlarge 12xlarge 16xlarge 24xlarge 32xlarge metal"
+	sizesGen6AMD   = "large xlarge 2xlarge 4xlarge 8xlarge 12xlarge 16xlarge 24xlarge 32xlarge 48xlarge metal"
+	sizesGraviton  = "medium large xlarge 2xlarge 4xlarge 8xlarge 12xlarge 16xlarge metal"
+	sizesGraviton4 = "medium large xlarge 2xlarge 4xlarge 8xlarge 12xlarge 16xlarge 24xlarge 48xlarge metal-24xl metal-48xl"
+	sizesGen7Intel = "large xlarge 2xlarge 4xlarge 8xlarge 12xlarge 16xlarge 24xlarge 48xlarge metal-24xl metal-48xl"
+	sizesGen7AMD   = "medium large xlarge 2xlarge 4xlarge 8xlarge 12xlarge 16xlarge 24xlarge 32xlarge 48xlarge metal-48xl"
+	sizesGen8Intel = "large xlarge 2xlarge 4xlarge 8xlarge 12xlarge 16xlarge 24xlarge 32xlarge 48xlarge 96xlarge metal-48xl metal-96xl"
+	sizesFlex      = "large xlarge 2xlarge 4xlarge 8xlarge 12xlarge 16xlarge"
+	sizesGPU       = "xlarge 2xlarge 4xlarge 8xlarge 12xlarge 16xlarge 24xlarge 48xlarge"
+)
+
+// instanceFamilySizes lists the EC2 instance families Karpenter can launch and their sizes.
+// Instance types outside it were made up or mistyped; add families here as AWS releases them.
+var instanceFamilySizes = map[string]string{
+	// Burstable
+	"t2": sizesBurstable, "t3": sizesBurstable, "t3a": sizesBurstable, "t4g": sizesBurstable,
+
+	// General purpose
+	"a1": "medium large xlarge 2xlarge 4xlarge metal",
+	"m4": "large xlarge 2xlarge 4xlarge 10xlarge 16xlarge",
+	"m5": sizesGen5, "m5d": sizesGen5, "m5n": sizesGen5, "m5dn": sizesGen5,
+	"m5a": sizesGen5AMD, "m5ad": sizesGen5AMD,
+	"m5zn": "large xlarge 2xlarge 3xlarge 6xlarge 12xlarge metal",
+	"m6i":  sizesGen6Intel, "m6id": sizesGen6Intel, "m6in": sizesGen6Intel, "m6idn": sizesGen6Intel,
+	"m6a": sizesGen6AMD,
+	"m6g": sizesGraviton, "m6gd": sizesGraviton,
+	"m7i": sizesGen7Intel, "m7i-flex": sizesFlex, "m7a": sizesGen7AMD,
+	"m7g": sizesGraviton, "m7gd": sizesGraviton,
+	"m8i": sizesGen8Intel, "m8i-flex": sizesFlex, "m8a": sizesGraviton4,
+	"m8g": sizesGraviton4, "m8gd": sizesGraviton4,
+
+	// Compute optimized
+	"c4": "large xlarge 2xlarge 4xlarge 8xlarge",
+	"c5": sizesC5, "c5d": sizesC5,
+	"c5a": sizesGen5AMD, "c5ad": sizesGen5AMD,
+	"c5n": "large xlarge 2xlarge 4xlarge 9xlarge 18xlarge metal",
+	"c6i": sizesGen6Intel, "c6id": sizesGen6Intel, "c6in": sizesGen6Intel,
+	"c6a": sizesGen6AMD,
+	"c6g": sizesGraviton, "c6gd": sizesGraviton,
+	"c6gn": "medium large xlarge 2xlarge 4xlarge 8xlarge 12xlarge 16xlarge",
+	"c7i":  sizesGen7Intel, "c7i-flex": sizesFlex, "c7a": sizesGen7AMD,
+	"c7g": sizesGraviton, "c7gd": sizesGraviton, "c7gn": sizesGraviton,
+	"c8i": sizesGen8Intel, "c8i-flex": sizesFlex,
+	"c8g": sizesGraviton4, "c8gd": sizesGraviton4,
+
+	// Memory optimized
+	"r4": sizesGen4,
+	"r5": sizesGen5, "r5d": sizesGen5, "r5b": sizesGen5, "r5n": sizesGen5, "r5dn": sizesGen5,
+	"r5a": sizesGen5AMD, "r5ad": sizesGen5AMD,
+	"r6i": sizesGen6Intel, "r6id": sizesGen6Intel, "r6in": sizesGen6Intel, "r6idn": sizesGen6Intel,
+	"r6a": sizesGen6AMD,
+	"r6g": sizesGraviton, "r6gd": sizesGraviton,
+	"r7i": sizesGen7Intel, "r7a": sizesGen7AMD,
+	"r7iz": "large xlarge 2xlarge 4xlarge 8xlarge 12xlarge 16xlarge 32xlarge metal-16xl metal-32xl",
+	"r7g":  sizesGraviton, "r7gd": sizesGraviton,
+	"r8i": sizesGen8Intel, "r8i-flex": sizesFlex,
+	"r8g": sizesGraviton4, "r8gd": sizesGraviton4,
+	"x1":     "16xlarge 32xlarge",
+	"x1e":    "xlarge 2xlarge 4xlarge 8xlarge 16xlarge 32xlarge",
+	"x2gd":   sizesGraviton,
+	"x2idn":  "16xlarge 24xlarge 32xlarge metal",
+	"x2iedn": "xlarge 2xlarge 4xlarge 8xlarge 16xlarge 24xlarge 32xlarge metal",
+	"x2iezn": "2xlarge 4xlarge 6xlarge 8xlarge 12xlarge metal",
+	"x8g":    sizesGraviton4,
+	"z1d":    "large xlarge 2xlarge 3xlarge 6xlarge 12xlarge metal",
+
+	// Storage optimized
+	"d2":     "xlarge 2xlarge 4xlarge 8xlarge",
+	"d3":     "xlarge 2xlarge 4xlarge 8xlarge",
+	"d3en":   "xlarge 2xlarge 4xlarge 6xlarge 8xlarge 12xlarge",
+	"h1":     "2xlarge 4xlarge 8xlarge 16xlarge",
+	"i3":     "large xlarge 2xlarge 4xlarge 8xlarge 16xlarge metal",
+	"i3en":   "large xlarge 2xlarge 3xlarge 6xlarge 12xlarge 24xlarge metal",
+	"i4i":    "large xlarge 2xlarge 4xlarge 8xlarge 12xlarge 16xlarge 24xlarge 32xlarge metal",
+	"i4g":    "large xlarge 2xlarge 4xlarge 8xlarge 16xlarge",
+	"im4gn":  "large xlarge 2xlarge 4xlarge 8xlarge 16xlarge",
+	"is4gen": "medium large xlarge 2xlarge 4xlarge 8xlarge",
+	"i7ie":   "large xlarge 2xlarge 3xlarge 6xlarge 12xlarge 18xlarge 24xlarge 48xlarge metal-24xl metal-48xl",
+	"i8g":    "large xlarge 2xlarge 4xlarge 8xlarge 12xlarge 16xlarge 24xlarge 48xlarge metal-24xl",
+
+	// HPC, media and FPGA
+	"hpc6a":  "48xlarge",
+	"hpc6id": "32xlarge",
+	"hpc7a":  "12xlarge 24xlarge 48xlarge 96xlarge",
+	"hpc7g":  "4xlarge 8xlarge 16xlarge",
+	"vt1":    "3xlarge 6xlarge 24xlarge",
+	"f1":     "2xlarge 4xlarge 16xlarge",
+	"f2":     "6xlarge 12xlarge 48xlarge",
+
+	// GPUs and ML accelerators
+	"g3":    "4xlarge 8xlarge 16xlarge",
+	"g3s":   "xlarge",
+	"g4dn":  "xlarge 2xlarge 4xlarge 8xlarge 12xlarge 16xlarge metal",
+	"g4ad":  "xlarge 2xlarge 4xlarge 8xlarge 16xlarge",
+	"g5":    sizesGPU,
+	"g5g":   "xlarge 2xlarge 4xlarge 8xlarge 16xlarge metal",
+	"g6":    sizesGPU,
+	"g6e":   sizesGPU,
+	"g6f":   "large xlarge 2xlarge 4xlarge",
+	"gr6":   "4xlarge 8xlarge",
+	"gr6f":  "4xlarge",
+	"p3":    "2xlarge 8xlarge 16xlarge",
+	"p3dn":  "24xlarge",
+	"p4d":   "24xlarge",
+	"p4de":  "24xlarge",
+	"p5":    "4xlarge 48xlarge",
+	"p5e":   "48xlarge",
+	"p5en":  "48xlarge",
+	"inf1":  "xlarge 2xlarge 6xlarge 24xlarge",
+	"inf2":  "xlarge 8xlarge 24xlarge 48xlarge",
+	"trn1":  "2xlarge 32xlarge",
+	"trn1n": "32xlarge",
+	"trn2":  "48xlarge",
+	"dl1":   "24xlarge",
+	"dl2q":  "24xlarge",
+}
+
+// instanceCatalog indexes instanceFamilySizes: family -> size -> true
+var instanceCatalog = func() map[string]map[string]bool {
+	catalog := make(map[string]map[string]bool, len(instanceFamilySizes))
+	for family, sizes := range instanceFamilySizes {
+		catalog[family] = make(map[string]bool)
+		for _, size := range strings.Fields(sizes) {
+			catalog[family][size] = true
+		}
+	}
+	return catalog
+}()
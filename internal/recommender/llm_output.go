@@ -0,0 +1,116 @@
+package recommender
+
+import (
+	"context"
+	"strings"
+
+	"github.com/karpenter-optimizer/internal/ollama"
+)
+
+// NodePoolAdvice is the LLM's structured recommendation for a NodePool
+type NodePoolAdvice struct {
+	InstanceTypes []string `json:"instanceTypes"`
+	NodeCount     int      `json:"nodeCount"`
+	MinSize       *int     `json:"minSize,omitempty"`
+	MaxSize       *int     `json:"maxSize,omitempty"`
+	CapacityType  string   `json:"capacityType"`
+	Summary       string   `json:"summary"` // One sentence with the key insight
+	Rationale     string   `json:"rationale"`
+	Risks         []string `json:"risks"`
+
+	Rejections    []LLMRejection `json:"-"` // Suggestions that failed validation and were dropped
+	PromptVersion string         `json:"-"` // Prompt template the advice was asked with
+}
+
+var nodePoolAdviceSchema = &ollama.Schema{
+	Type: "object",
+	Properties: map[string]*ollama.Schema{
+		"instanceTypes": {Type: "array", Items: &ollama.Schema{Type: "string", MinLength: 1}, MinItems: 1, MaxItems: 10, Description: "Recommended EC2 instance types"},
+		"nodeCount":     {Type: "integer", Minimum: ollama.Bound(0), Description: "Nodes needed for the workloads"},
+		"minSize":       {Type: "integer", Minimum: ollama.Bound(0)},
+		"maxSize":       {Type: "integer", Minimum: ollama.Bound(0)},
+		"capacityType":  {Type: "string", Enum: []string{"spot", "on-demand"}},
+		"summary":       {Type: "string", MinLength: 1, MaxLength: 200, Description: "One sentence with the key insight"},
+		"rationale":     {Type: "string", MinLength: 1, Description: "Why this configuration fits the workloads, cost and disruption patterns"},
+		"risks":         {Type: "array", Items: &ollama.Schema{Type: "string"}, Description: "Risks or considerations of the change"},
+	},
+	Required: []string{"instanceTypes", "nodeCount", "capacityType", "summary", "rationale", "risks"},
+}
+
+// Reasoning is the advice's rationale followed by its risks
+func (a *NodePoolAdvice) Reasoning() string {
+	return withRisks(a.Rationale, a.Risks)
+}
+
+// boundedNodeCount returns the advised node count within the recommended NodePool size
+// (maxSize 0 = no upper bound), or fallback when the LLM advised no nodes
+func (a *NodePoolAdvice) boundedNodeCount(fallback, minSize, maxSize int) int {
+	if a.NodeCount <= 0 {
+		return fallback
+	}
+	count := a.NodeCount
+	if count < minSize {
+		count = minSize
+	}
+	if maxSize > 0 && count > maxSize {
+		count = maxSize
+	}
+	return count
+}
+
+// RecommendationExplanation is the LLM's structured explanation of a recommendation
+type RecommendationExplanation struct {
+	Rationale string   `json:"rationale"`
+	Benefits  []string `json:"benefits"`
+	Risks     []string `json:"risks"`
+}
+
+var recommendationExplanationSchema = &ollama.Schema{
+	Type: "object",
+	Properties: map[string]*ollama.Schema{
+		"rationale": {Type: "string", MinLength: 1, Description: "2-3 sentences on why the change is beneficial"},
+		"benefits":  {Type: "array", Items: &ollama.Schema{Type: "string"}, Description: "Improvements in cost, efficiency or performance"},
+		"risks":     {Type: "array", Items: &ollama.Schema{Type: "string"}, Description: "Considerations or risks of the change"},
+	},
+	Required: []string{"rationale", "benefits", "risks"},
+}
+
+// Text is the explanation's rationale followed by its risks
+func (e *RecommendationExplanation) Text() string {
+	return withRisks(e.Rationale, e.Risks)
+}
+
+func withRisks(text string, risks []string) string {
+	text = strings.TrimSpace(text)
+	var kept []string
+	for _, risk := range risks {
+		if risk = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(risk), ".")); risk != "" {
+			kept = append(kept, risk)
+		}
+	}
+	if len(kept) == 0 {
+		return text
+	}
+	if text != "" && !strings.HasSuffix(text, ".") {
+		text += "."
+	}
+	return strings.TrimSpace(text + " Risks: " + strings.Join(kept, "; ") + ".")
+}
+
+// requestNodePoolAdvice asks the LLM for a schema-validated NodePool recommendation
+func (r *Recommender) requestNodePoolAdvice(ctx context.Context, prompt string) (*NodePoolAdvice, error) {
+	var advice NodePoolAdvice
+	if err := r.ollamaClient.ChatJSON(ctx, prompt, nodePoolAdviceSchema, &advice); err != nil {
+		return nil, err
+	}
+	return &advice, nil
+}
+
+// requestRecommendationExplanation asks the LLM for a schema-validated explanation
+func (r *Recommender) requestRecommendationExplanation(ctx context.Context, prompt string) (*RecommendationExplanation, error) {
+	var explanation RecommendationExplanation
+	if err := r.ollamaClient.ChatJSON(ctx, prompt, recommendationExplanationSchema, &explanation); err != nil {
+		return nil, err
+	}
+	return &explanation, nil
+}
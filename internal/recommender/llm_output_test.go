@@ -0,0 +1,90 @@
+package recommender
+
+import (
+	"context"
+	"testing"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/ollama"
+	"github.com/karpenter-optimizer/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnhanceRecommendationsWithOllama(t *testing.T) {
+	server := testutil.NewFakeLLMServer(
+		ollama.Message{Role: "assistant", Content: `{"explanation": "Fewer, larger nodes"}`},
+		ollama.Message{Role: "assistant", Content: `{"rationale": "Fewer, larger nodes cut idle capacity", "benefits": ["Lower cost"], "risks": ["Spot interruptions.", " "]}`},
+		ollama.Message{Role: "assistant", Content: "not JSON"},
+		ollama.Message{Role: "assistant", Content: "still not JSON"},
+	)
+	defer server.Close()
+	r := &Recommender{ollamaClient: ollama.NewClient(server.URL, "test-model", "litellm", "", false)}
+
+	recommendations := []NodePoolCapacityRecommendation{
+		{NodePoolName: "default", Reasoning: "Over-provisioned.", CurrentCPUCapacity: 8, CurrentMemoryCapacity: 32},
+		{NodePoolName: "batch", Reasoning: "Right-sized.", CurrentCPUCapacity: 8, CurrentMemoryCapacity: 32},
+	}
+	enhanced, err := r.EnhanceRecommendationsWithOllama(context.Background(), recommendations)
+	require.NoError(t, err)
+
+	// The first reply is repaired; the second NodePool keeps its reasoning when repair fails
+	assert.Equal(t, "Fewer, larger nodes cut idle capacity. Risks: Spot interruptions.", enhanced[0].AIReasoning)
+	assert.Equal(t, "Over-provisioned. Fewer, larger nodes cut idle capacity. Risks: Spot interruptions.", enhanced[0].Reasoning)
+	assert.Equal(t, "recommendation-explanation@1", enhanced[0].AIPromptVersion)
+	assert.Empty(t, enhanced[1].AIReasoning)
+	assert.Empty(t, enhanced[1].AIPromptVersion)
+	assert.Equal(t, "Right-sized.", enhanced[1].Reasoning)
+	assert.Len(t, server.Requests(), 4)
+}
+
+func TestEnhanceWithOllama(t *testing.T) {
+	server := testutil.NewFakeLLMServer(
+		ollama.Message{Role: "assistant", Content: `{"instanceTypes": ["g5.xlarge", "m6i.xlarge"], "nodeCount": 2, "minSize": 1, "capacityType": "spot", "summary": "Spot m6i fits the workloads", "rationale": "Usage fits two nodes", "risks": []}`},
+		ollama.Message{Role: "assistant", Content: `{"instanceTypes": ["g5.xlarge"], "nodeCount": 2, "capacityType": "on-demand", "summary": "GPU nodes", "rationale": "GPU nodes", "risks": []}`},
+	)
+	defer server.Close()
+	r := &Recommender{ollamaClient: ollama.NewClient(server.URL, "test-model", "ollama", "", false)}
+	np := kubernetes.NodePoolInfo{Name: "default"}
+
+	advice := r.enhanceWithOllama(context.Background(), np, nil, 4, 16, 0, []string{"m5.large"}, false, DisruptionInsights{})
+	require.NotNil(t, advice)
+	assert.Equal(t, []string{"m6i.xlarge"}, advice.InstanceTypes)
+	assert.Equal(t, 2, advice.NodeCount)
+	assert.Equal(t, 1, *advice.MinSize)
+	assert.Nil(t, advice.MaxSize)
+	assert.Equal(t, "Usage fits two nodes", advice.Reasoning())
+	assert.Equal(t, "nodepool-advice@1", advice.PromptVersion)
+	require.Len(t, advice.Rejections, 1)
+	assert.Equal(t, "g5.xlarge", advice.Rejections[0].Value)
+	assert.Equal(t, "GPU instance type but no workload requests a GPU", advice.Rejections[0].Reason)
+
+	// Only GPU types for non-GPU workloads keeps the current types
+	advice = r.enhanceWithOllama(context.Background(), np, nil, 4, 16, 0, []string{"m5.large"}, false, DisruptionInsights{})
+	require.NotNil(t, advice)
+	assert.Equal(t, []string{"m5.large"}, advice.InstanceTypes)
+
+	// LLM failures return no advice
+	assert.Nil(t, r.enhanceWithOllama(context.Background(), np, nil, 4, 16, 0, []string{"m5.large"}, false, DisruptionInsights{}))
+}
+
+func TestNodePoolAdviceBoundedNodeCount(t *testing.T) {
+	tests := []struct {
+		name      string
+		nodeCount int
+		min, max  int
+		want      int
+	}{
+		{name: "no count advised", nodeCount: 0, min: 0, max: 6, want: 4},
+		{name: "within bounds", nodeCount: 3, min: 1, max: 6, want: 3},
+		{name: "below minimum", nodeCount: 1, min: 2, max: 6, want: 2},
+		{name: "above maximum", nodeCount: 10, min: 0, max: 6, want: 6},
+		{name: "no maximum", nodeCount: 10, min: 0, max: 0, want: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			advice := &NodePoolAdvice{NodeCount: tt.nodeCount}
+			assert.Equal(t, tt.want, advice.boundedNodeCount(4, tt.min, tt.max))
+		})
+	}
+}
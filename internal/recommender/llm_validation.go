@@ -0,0 +1,290 @@
+package recommender
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/ollama"
+)
+
+// LLMRejection is an LLM-suggested value that failed validation and was not used
+type LLMRejection struct {
+	NodePool string    `json:"nodePool,omitempty"`
+	Field    string    `json:"field"` // Field of the LLM output, e.g. instanceTypes or pricePerHour
+	Value    string    `json:"value"`
+	Reason   string    `json:"reason"`
+	At       time.Time `json:"at"`
+}
+
+// maxLLMRejections bounds the rejections kept in memory for the API
+const maxLLMRejections = 200
+
+// llmPriceBandFactor is how far an LLM price may be from the family estimate (in either direction)
+const llmPriceBandFactor = 3.0
+
+// acceleratorCategories are the categories with GPUs or ML accelerators
+var acceleratorCategories = map[string]bool{"dl": true, "g": true, "gr": true, "inf": true, "p": true, "trn": true}
+
+// instanceFamily is an EC2 instance family split by the naming scheme (m7gd -> m, gd)
+type instanceFamily struct {
+	category   string
+	attributes string
+}
+
+// parseInstanceFamily splits an EC2 instance family name. It returns false when the family
+// is not in instanceCatalog.
+func parseInstanceFamily(family string) (instanceFamily, bool) {
+	parts := instanceFamilyPattern.FindStringSubmatch(family)
+	if parts == nil || instanceCatalog[family] == nil {
+		return instanceFamily{}, false
+	}
+	return instanceFamily{category: parts[1], attributes: parts[3]}, true
+}
+
+// architecture is arm64 for Graviton families (g attribute, and the first generation a1)
+func (f instanceFamily) architecture() string {
+	if f.category == "a" || strings.Contains(strings.TrimSuffix(f.attributes, "-flex"), "g") {
+		return "arm64"
+	}
+	return "amd64"
+}
+
+// categoryBaseCost is the on-demand xlarge price used as the reference for catalog
+// families without their own estimate in familyBaseCost
+var categoryBaseCost = map[string]float64{
+	"t": 0.1664, // t3.xlarge
+	"m": 0.192,  // m6i.xlarge
+	"c": 0.17,   // c6i.xlarge
+	"r": 0.252,  // r6i.xlarge
+}
+
+var instanceSizePattern = regexp.MustCompile(`^(nano|micro|small|medium|large|metal|metal-[0-9]+xl|[0-9]*xlarge)$`)
+
+// instanceFamilyPattern splits a family into category, generation and attributes (m7gd -> m, 7, gd)
+var instanceFamilyPattern = regexp.MustCompile(`^([a-z]+)([0-9]+)([a-z-]*)$`)
+
+// llmPrice is the LLM's reply to a pricing question
+type llmPrice struct {
+	InstanceType string  `json:"instanceType"`
+	PricePerHour float64 `json:"pricePerHour"`
+	Region       string  `json:"region"`
+}
+
+var llmPriceSchema = &ollama.Schema{
+	Type: "object",
+	Properties: map[string]*ollama.Schema{
+		"instanceType": {Type: "string", MinLength: 1},
+		"pricePerHour": {Type: "number", Minimum: ollama.Bound(0), Description: "On-demand USD per hour"},
+		"region":       {Type: "string"},
+	},
+	Required: []string{"instanceType", "pricePerHour"},
+}
+
+// instanceTypeLabels returns the well-known Karpenter labels of a catalog instance type. When
+// the name is not a valid instance type of a catalog family it returns nil and the reason.
+func instanceTypeLabels(instanceType string) (map[string]string, string) {
+	it := strings.ToLower(strings.TrimSpace(instanceType))
+	family, size, ok := strings.Cut(it, ".")
+	parts := instanceFamilyPattern.FindStringSubmatch(family)
+	if !ok || parts == nil || !instanceSizePattern.MatchString(size) {
+		return nil, "not a valid EC2 instance type name"
+	}
+	parsed, known := parseInstanceFamily(family)
+	if !known {
+		return nil, fmt.Sprintf("instance family %s is not in the catalog", family)
+	}
+	if !instanceCatalog[family][size] {
+		return nil, fmt.Sprintf("instance family %s has no %s size", family, size)
+	}
+	return map[string]string{
+		"node.kubernetes.io/instance-type":      it,
+		"karpenter.k8s.aws/instance-family":     family,
+		"karpenter.k8s.aws/instance-category":   parts[1],
+		"karpenter.k8s.aws/instance-generation": parts[2],
+		"karpenter.k8s.aws/instance-size":       size,
+		"kubernetes.io/arch":                    parsed.architecture(),
+	}, ""
+}
+
+// InstanceTypeInCatalog reports whether an instance type is a size of a family in the
+// embedded EC2 catalog; other names were made up or mistyped
+func InstanceTypeInCatalog(instanceType string) bool {
+	labels, _ := instanceTypeLabels(instanceType)
+	return labels != nil
+}
+
+// validateInstanceType checks an LLM-suggested instance type against the catalog and the
+// NodePool's requirements. It returns why the type is rejected, or "".
+func validateInstanceType(np kubernetes.NodePoolInfo, instanceType string, gpu bool) string {
+	labels, reason := instanceTypeLabels(instanceType)
+	if labels == nil {
+		return reason
+	}
+	arch := labels["kubernetes.io/arch"]
+	if np.Architecture != "" && np.Architecture != arch {
+		return fmt.Sprintf("%s instance type but the NodePool runs %s", arch, np.Architecture)
+	}
+	if isGPUInstanceType(labels["node.kubernetes.io/instance-type"]) != gpu {
+		if gpu {
+			return "no GPU but the NodePool runs GPU workloads"
+		}
+		return "GPU instance type but no workload requests a GPU"
+	}
+	if req := np.UnmetRequirement(labels); req != nil {
+		return fmt.Sprintf("NodePool requires %s %s %s", req.Key, req.Operator, strings.Join(req.Values, ","))
+	}
+	return ""
+}
+
+// ValidateInstanceTypes splits LLM-suggested instance types into those in the catalog that
+// satisfy the NodePool's requirements and rejections for the rest. gpu is whether the
+// NodePool runs GPU workloads.
+func ValidateInstanceTypes(np kubernetes.NodePoolInfo, instanceTypes []string, gpu bool) ([]string, []LLMRejection) {
+	var valid []string
+	var rejected []LLMRejection
+	for _, it := range instanceTypes {
+		if reason := validateInstanceType(np, it, gpu); reason != "" {
+			rejected = append(rejected, LLMRejection{NodePool: np.Name, Field: "instanceTypes", Value: it, Reason: reason, At: time.Now()})
+			continue
+		}
+		valid = append(valid, strings.ToLower(strings.TrimSpace(it)))
+	}
+	return valid, rejected
+}
+
+// NodePoolUsesGPU reports whether a NodePool launches or reserves GPU nodes
+func NodePoolUsesGPU(np kubernetes.NodePoolInfo) bool {
+	for _, it := range np.InstanceTypes {
+		if isGPUInstanceType(it) {
+			return true
+		}
+	}
+	for _, node := range np.ActualNodes {
+		if isGPUInstanceType(node.InstanceType) {
+			return true
+		}
+	}
+	for _, taint := range np.Taints {
+		if taint.Key == "nvidia.com/gpu" {
+			return true
+		}
+	}
+	return false
+}
+
+// validateNodePoolAdvice drops the LLM's suggestions that fail validation from advice and
+// records them in advice.Rejections. When no instance type is left the current types are kept.
+func (r *Recommender) validateNodePoolAdvice(np kubernetes.NodePoolInfo, advice *NodePoolAdvice, currentTypes []string, gpu bool) {
+	valid, rejected := ValidateInstanceTypes(np, advice.InstanceTypes, gpu)
+	if len(valid) == 0 {
+		valid = currentTypes
+	}
+	advice.InstanceTypes = valid
+
+	if advice.MinSize != nil && advice.MaxSize != nil && *advice.MinSize > *advice.MaxSize {
+		rejected = append(rejected,
+			LLMRejection{NodePool: np.Name, Field: "minSize", Value: strconv.Itoa(*advice.MinSize), Reason: fmt.Sprintf("greater than maxSize %d", *advice.MaxSize), At: time.Now()},
+			LLMRejection{NodePool: np.Name, Field: "maxSize", Value: strconv.Itoa(*advice.MaxSize), Reason: fmt.Sprintf("less than minSize %d", *advice.MinSize), At: time.Now()},
+		)
+		advice.MinSize, advice.MaxSize = nil, nil
+	}
+
+	advice.Rejections = rejected
+	r.RecordLLMRejections(rejected...)
+}
+
+// sizeMultiplier returns the size of an instance relative to xlarge
+func sizeMultiplier(size string) (float64, bool) {
+	switch size {
+	case "nano":
+		return 0.03125, true
+	case "micro":
+		return 0.0625, true
+	case "small":
+		return 0.125, true
+	case "medium":
+		return 0.25, true
+	case "large":
+		return 0.5, true
+	case "xlarge":
+		return 1, true
+	}
+	if n, err := strconv.Atoi(strings.TrimSuffix(size, "xlarge")); err == nil && strings.HasSuffix(size, "xlarge") && n > 0 {
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// referencePrice is the family-based on-demand estimate an LLM price is checked against
+func referencePrice(instanceType string) (float64, bool) {
+	labels, _ := instanceTypeLabels(instanceType)
+	if labels == nil {
+		return 0, false
+	}
+	multiplier, ok := sizeMultiplier(labels["karpenter.k8s.aws/instance-size"])
+	if !ok {
+		return 0, false
+	}
+	if base, ok := familyBaseCost(labels["karpenter.k8s.aws/instance-family"]); ok {
+		return base * multiplier, true
+	}
+	if base, ok := categoryBaseCost[labels["karpenter.k8s.aws/instance-category"]]; ok {
+		return base * multiplier, true
+	}
+	return 0, false
+}
+
+// validateLLMPrice checks an LLM price against the plausible band around the family estimate.
+// It returns the rejection, or nil when the price can be used.
+func validateLLMPrice(instanceType string, price llmPrice) *LLMRejection {
+	rejection := func(reason string) *LLMRejection {
+		return &LLMRejection{Field: "pricePerHour", Value: fmt.Sprintf("$%.4f/hr for %s", price.PricePerHour, instanceType), Reason: reason, At: time.Now()}
+	}
+	if price.InstanceType != "" && !strings.EqualFold(strings.TrimSpace(price.InstanceType), instanceType) {
+		return rejection(fmt.Sprintf("price is for %s", price.InstanceType))
+	}
+	ref, ok := referencePrice(instanceType)
+	if !ok {
+		return rejection("no family estimate to check the price against")
+	}
+	low, high := ref/llmPriceBandFactor, ref*llmPriceBandFactor
+	if price.PricePerHour < low || price.PricePerHour > high {
+		return rejection(fmt.Sprintf("outside the plausible range $%.4f-$%.4f/hr around the family estimate $%.4f/hr", low, high, ref))
+	}
+	return nil
+}
+
+// RecordLLMRejections logs rejected LLM outputs and keeps the most recent for the API
+func (r *Recommender) RecordLLMRejections(rejections ...LLMRejection) {
+	if len(rejections) == 0 {
+		return
+	}
+	r.llmRejectionsMu.Lock()
+	defer r.llmRejectionsMu.Unlock()
+	for _, rejection := range rejections {
+		if rejection.NodePool != "" {
+			fmt.Printf("Warning: Rejected LLM %s %q for NodePool %s: %s\n", rejection.Field, rejection.Value, rejection.NodePool, rejection.Reason)
+		} else {
+			fmt.Printf("Warning: Rejected LLM %s %q: %s\n", rejection.Field, rejection.Value, rejection.Reason)
+		}
+	}
+	r.llmRejections = append(r.llmRejections, rejections...)
+	if len(r.llmRejections) > maxLLMRejections {
+		r.llmRejections = append([]LLMRejection(nil), r.llmRejections[len(r.llmRejections)-maxLLMRejections:]...)
+	}
+}
+
+// RecentLLMRejections returns the most recent rejected LLM outputs, newest first
+func (r *Recommender) RecentLLMRejections() []LLMRejection {
+	r.llmRejectionsMu.Lock()
+	defer r.llmRejectionsMu.Unlock()
+	recent := make([]LLMRejection, len(r.llmRejections))
+	for i, rejection := range r.llmRejections {
+		recent[len(recent)-1-i] = rejection
+	}
+	return recent
+}
@@ -0,0 +1,196 @@
+package recommender
+
+import (
+	"context"
+	"testing"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/ollama"
+	"github.com/karpenter-optimizer/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidateInstanceType(t *testing.T) {
+	general := kubernetes.NodePoolInfo{
+		Name:         "general",
+		Architecture: "amd64",
+		NodeRequirements: []corev1.NodeSelectorRequirement{
+			{Key: "kubernetes.io/arch", Operator: corev1.NodeSelectorOpIn, Values: []string{"amd64"}},
+			{Key: "karpenter.k8s.aws/instance-category", Operator: corev1.NodeSelectorOpIn, Values: []string{"c", "m", "r"}},
+			{Key: "karpenter.k8s.aws/instance-generation", Operator: corev1.NodeSelectorOpGt, Values: []string{"5"}},
+			{Key: "karpenter.k8s.aws/instance-size", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"metal"}},
+			{Key: "karpenter.sh/capacity-type", Operator: corev1.NodeSelectorOpIn, Values: []string{"spot"}},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		np           kubernetes.NodePoolInfo
+		instanceType string
+		gpu          bool
+		want         string
+	}{
+		{"valid", general, "m7i.2xlarge", false, ""},
+		{"case and spaces", general, " C6A.Large ", false, ""},
+		{"invented family", general, "m9z.large", false, "instance family m9z is not in the catalog"},
+		{"invented size", general, "m6i.huge", false, "not a valid EC2 instance type name"},
+		{"size the family lacks", general, "c7gn.nano", false, "instance family c7gn has no nano size"},
+		{"not an instance type", general, "general purpose", false, "not a valid EC2 instance type name"},
+		{"architecture", general, "m7g.xlarge", false, "arm64 instance type but the NodePool runs amd64"},
+		{"category requirement", general, "t3.large", false, "NodePool requires karpenter.k8s.aws/instance-category In c,m,r"},
+		{"generation requirement", general, "m5.xlarge", false, "NodePool requires karpenter.k8s.aws/instance-generation Gt 5"},
+		{"size requirement", general, "m6i.metal", false, "NodePool requires karpenter.k8s.aws/instance-size NotIn metal"},
+		{"GPU without GPU workloads", kubernetes.NodePoolInfo{Name: "default"}, "g5.xlarge", false, "GPU instance type but no workload requests a GPU"},
+		{"GPU NodePool", kubernetes.NodePoolInfo{Name: "gpu"}, "g6.2xlarge", true, ""},
+		{"CPU type for GPU workloads", kubernetes.NodePoolInfo{Name: "gpu"}, "m6i.xlarge", true, "no GPU but the NodePool runs GPU workloads"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, validateInstanceType(tt.np, tt.instanceType, tt.gpu))
+		})
+	}
+}
+
+func TestInstanceTypeInCatalog(t *testing.T) {
+	tests := []struct {
+		instanceType string
+		arch         string
+		gpu          bool
+	}{
+		{"t2.micro", "amd64", false},
+		{"t3a.medium", "amd64", false},
+		{"t4g.small", "arm64", false},
+		{"a1.large", "arm64", false},
+		{"m5d.xlarge", "amd64", false},
+		{"m5zn.large", "amd64", false},
+		{"m6id.2xlarge", "amd64", false},
+		{"m6in.4xlarge", "amd64", false},
+		{"m6idn.8xlarge", "amd64", false},
+		{"m7i-flex.large", "amd64", false},
+		{"m8gd.metal-24xl", "arm64", false},
+		{"c5d.large", "amd64", false},
+		{"c6id.xlarge", "amd64", false},
+		{"c7i-flex.2xlarge", "amd64", false},
+		{"c7gn.16xlarge", "arm64", false},
+		{"r5d.2xlarge", "amd64", false},
+		{"r5b.large", "amd64", false},
+		{"r6id.large", "amd64", false},
+		{"r7iz.xlarge", "amd64", false},
+		{"x2iedn.xlarge", "amd64", false},
+		{"x2gd.medium", "arm64", false},
+		{"z1d.large", "amd64", false},
+		{"i4i.large", "amd64", false},
+		{"im4gn.large", "arm64", false},
+		{"is4gen.medium", "arm64", false},
+		{"d3en.xlarge", "amd64", false},
+		{"hpc7g.4xlarge", "arm64", false},
+		{"g4ad.xlarge", "amd64", true},
+		{"g4dn.xlarge", "amd64", true},
+		{"g5g.xlarge", "arm64", true},
+		{"g6e.12xlarge", "amd64", true},
+		{"p4de.24xlarge", "amd64", true},
+		{"p5en.48xlarge", "amd64", true},
+		{"inf2.xlarge", "amd64", true},
+		{"trn1.2xlarge", "amd64", true},
+		{"trn1n.32xlarge", "amd64", true},
+		{"dl1.24xlarge", "amd64", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.instanceType, func(t *testing.T) {
+			require.True(t, InstanceTypeInCatalog(tt.instanceType))
+			labels, _ := instanceTypeLabels(tt.instanceType)
+			assert.Equal(t, tt.arch, labels["kubernetes.io/arch"])
+			assert.Equal(t, tt.gpu, isGPUInstanceType(tt.instanceType))
+		})
+	}
+
+	for _, invented := range []string{"m9z.large", "c9q.large", "q5.large", "m6x.large", "m0.large", "m6i.huge", "m6i", "gpu-node", "t4.xlarge", "c2.large", "m5g.large"} {
+		assert.False(t, InstanceTypeInCatalog(invented), invented)
+		assert.False(t, isGPUInstanceType(invented), invented)
+	}
+	// Sizes the family does not offer
+	for _, invented := range []string{"p5.medium", "g5.medium", "c7gn.nano", "m8gd.metal", "m6a.nano"} {
+		assert.False(t, InstanceTypeInCatalog(invented), invented)
+	}
+
+	for _, spec := range gpuInstanceCatalog {
+		assert.True(t, InstanceTypeInCatalog(spec.InstanceType), spec.InstanceType)
+	}
+}
+
+func TestValidateLLMPrice(t *testing.T) {
+	tests := []struct {
+		name         string
+		instanceType string
+		price        llmPrice
+		wantReason   string
+	}{
+		{"known family", "m6i.12xlarge", llmPrice{InstanceType: "m6i.12xlarge", PricePerHour: 2.304}, ""},
+		{"category estimate", "m7i.large", llmPrice{InstanceType: "m7i.large", PricePerHour: 0.1008}, ""},
+		{"too expensive", "c7g.xlarge", llmPrice{InstanceType: "c7g.xlarge", PricePerHour: 1.45}, "outside the plausible range $0.0567-$0.5100/hr around the family estimate $0.1700/hr"},
+		{"too cheap", "r6i.2xlarge", llmPrice{PricePerHour: 0.001}, "outside the plausible range $0.1680-$1.5120/hr around the family estimate $0.5040/hr"},
+		{"other instance type", "m6i.large", llmPrice{InstanceType: "m6i.xlarge", PricePerHour: 0.192}, "price is for m6i.xlarge"},
+		{"no estimate", "x2gd.metal", llmPrice{PricePerHour: 5.3}, "no family estimate to check the price against"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rejection := validateLLMPrice(tt.instanceType, tt.price)
+			if tt.wantReason == "" {
+				assert.Nil(t, rejection)
+				return
+			}
+			require.NotNil(t, rejection)
+			assert.Equal(t, "pricePerHour", rejection.Field)
+			assert.Equal(t, tt.wantReason, rejection.Reason)
+		})
+	}
+}
+
+func TestGetPricingFromOllama(t *testing.T) {
+	server := testutil.NewFakeLLMServer(
+		ollama.Message{Role: "assistant", Content: `{"instanceType": "m7i.large", "pricePerHour": 0.1008, "region": "us-east-1"}`},
+		ollama.Message{Role: "assistant", Content: `{"instanceType": "c7i.large", "pricePerHour": 12.5, "region": "us-east-1"}`},
+	)
+	defer server.Close()
+	r := &Recommender{
+		ollamaClient: ollama.NewClient(server.URL, "test-model", "litellm", "", false),
+		priceCache:   make(map[string]float64),
+	}
+
+	// A price within the band is used and cached
+	result, sources := r.estimateCostWithSource(context.Background(), []string{"m7i.large"}, "on-demand", 2)
+	assert.InDelta(t, 0.2016, result.Cost, 1e-9)
+	assert.Equal(t, PricingSourceOllama, sources["m7i.large"])
+	result, sources = r.estimateCostWithSource(context.Background(), []string{"m7i.large"}, "on-demand", 1)
+	assert.InDelta(t, 0.1008, result.Cost, 1e-9)
+	assert.Equal(t, PricingSourceOllamaCache, sources["m7i.large"])
+
+	// An implausible price is rejected, reported and not asked for again
+	result, sources = r.estimateCostWithSource(context.Background(), []string{"c7i.large"}, "on-demand", 1)
+	assert.InDelta(t, 0.2, result.Cost, 1e-9)
+	assert.Equal(t, PricingSourceFamilyEstimate, sources["c7i.large"])
+	assert.Zero(t, r.getPricingFromOllama(context.Background(), "c7i.large"))
+	assert.NotContains(t, r.priceCache, "c7i.large")
+
+	rejections := r.RecentLLMRejections()
+	require.Len(t, rejections, 1)
+	assert.Equal(t, "$12.5000/hr for c7i.large", rejections[0].Value)
+
+	// Known families use the family estimate, and types outside the catalog are never asked about
+	_, sources = r.estimateCostWithSource(context.Background(), []string{"m6i.12xlarge", "z9q.large"}, "on-demand", 2)
+	assert.Equal(t, PricingSourceFamilyEstimate, sources["m6i.12xlarge"])
+	assert.Equal(t, PricingSourceFamilyEstimate, sources["z9q.large"])
+	assert.Len(t, server.Requests(), 2)
+}
+
+func TestRecentLLMRejections(t *testing.T) {
+	r := &Recommender{}
+	for i := 0; i < maxLLMRejections+5; i++ {
+		r.RecordLLMRejections(LLMRejection{Field: "instanceTypes", Value: string(rune('a' + i%26))})
+	}
+	rejections := r.RecentLLMRejections()
+	require.Len(t, rejections, maxLLMRejections)
+	assert.Equal(t, string(rune('a'+(maxLLMRejections+4)%26)), rejections[0].Value)
+}
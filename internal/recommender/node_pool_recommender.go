@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/prompts"
 )
 
 // NodePoolCapacityRecommendation represents a recommendation based on actual node capacity
@@ -31,14 +32,26 @@ type NodePoolCapacityRecommendation struct {
 	CostSavingsPercent       float64  `json:"costSavingsPercent"` // Percentage savings
 	Reasoning                string   `json:"reasoning"`
 	AIReasoning              string   `json:"aiReasoning,omitempty"` // AI-enhanced explanation (if Ollama is available)
+	AIPromptVersion          string   `json:"aiPromptVersion,omitempty"` // Prompt template of the AI explanation, e.g. "reasoning-enhancement@1"
 	Architecture             string   `json:"architecture"`
 	CapacityType             string   `json:"capacityType"`
 	Taints                   []kubernetes.Taint `json:"taints,omitempty"` // Node taints
 	HasRecommendation        bool     `json:"hasRecommendation"` // true if a cost-saving recommendation exists
+	GPU                      *GPURecommendation `json:"gpu,omitempty"` // GPU sizing and idle GPU findings (GPU NodePools only)
 }
 
 // GenerateRecommendationsFromNodePools generates recommendations based on actual node capacity data
 func (r *Recommender) GenerateRecommendationsFromNodePools(ctx context.Context, nodePools []kubernetes.NodePoolInfo, progressCallback func(string, float64)) ([]NodePoolCapacityRecommendation, error) {
+	return r.generateRecommendationsFromNodePools(ctx, nodePools, 0, progressCallback)
+}
+
+// GenerateRecommendationsWithHeadroom generates recommendations like GenerateRecommendationsFromNodePools,
+// sizing each NodePool for at least its current usage plus headroomPercent
+func (r *Recommender) GenerateRecommendationsWithHeadroom(ctx context.Context, nodePools []kubernetes.NodePoolInfo, headroomPercent float64) ([]NodePoolCapacityRecommendation, error) {
+	return r.generateRecommendationsFromNodePools(ctx, nodePools, headroomPercent, nil)
+}
+
+func (r *Recommender) generateRecommendationsFromNodePools(ctx context.Context, nodePools []kubernetes.NodePoolInfo, headroomPercent float64, progressCallback func(string, float64)) ([]NodePoolCapacityRecommendation, error) {
 	var recommendations []NodePoolCapacityRecommendation
 	totalNodePools := len(nodePools)
 
@@ -150,30 +163,43 @@ func (r *Recommender) GenerateRecommendationsFromNodePools(ctx context.Context,
 			}
 		}
 
-		// Try both spot and on-demand to find the best cost option
-		// If all nodes are already spot, prefer spot. If there are on-demand nodes, try converting to spot for savings.
-		bestTypes, bestNodes, bestCost, bestCapacityType := r.findOptimalInstanceTypesWithCapacityType(ctx,
-			currentCPUCapacity,
-			currentMemoryCapacity,
-			architecture,
-			spotNodes > 0,     // Prefer spot if already using spot
-			onDemandNodes > 0, // Consider converting on-demand to spot
-		)
+		var bestTypes []string
+		var bestNodes int
+		var bestCost float64
+		var bestCapacityType string
+
+		// GPU NodePools are sized by the GPUs Ready pods hold, not by CPU/memory
+		gpuReq, gpuRec := gpuRequirementFromNodes(np.ActualNodes)
+		if gpuRec != nil {
+			gpuCapacityType := "on-demand"
+			if (onDemandNodes == 0 && allowsCapacityType(np, "spot")) || !allowsCapacityType(np, "on-demand") {
+				gpuCapacityType = "spot"
+			}
+			gpuRec.IdleNodes = r.DetectIdleGPUs(ctx, np.ActualNodes)
+			bestTypes, bestNodes, bestCost, bestCapacityType = r.findOptimalGPUInstanceTypes(ctx, gpuReq, gpuRec, gpuCapacityType)
+		} else {
+			// Size for the current capacity, or for usage plus the requested headroom if that is more
+			requiredCPU, requiredMemory := currentCPUCapacity, currentMemoryCapacity
+			if headroomPercent > 0 {
+				requiredCPU = math.Max(requiredCPU, currentCPUUsed*(1+headroomPercent/100))
+				requiredMemory = math.Max(requiredMemory, currentMemoryUsed*(1+headroomPercent/100))
+			}
+			// Try both spot and on-demand to find the best cost option
+			// If all nodes are already spot, prefer spot. If there are on-demand nodes, try converting to spot for savings.
+			bestTypes, bestNodes, bestCost, bestCapacityType = r.findOptimalInstanceTypesWithCapacityType(ctx,
+				np,
+				requiredCPU,
+				requiredMemory,
+				architecture,
+				spotNodes > 0,     // Prefer spot if already using spot
+				onDemandNodes > 0, // Consider converting on-demand to spot
+			)
+		}
 
 		// Calculate recommended capacity (distribute nodes across instance types)
 		var recommendedTotalCPU, recommendedTotalMemory float64
 		if len(bestTypes) > 0 && bestNodes > 0 {
-			nodesPerType := bestNodes / len(bestTypes)
-			remainder := bestNodes % len(bestTypes)
-			for i, it := range bestTypes {
-				cpu, mem := r.estimateInstanceCapacity(it)
-				nodesForThisType := nodesPerType
-				if i < remainder {
-					nodesForThisType++
-				}
-				recommendedTotalCPU += cpu * float64(nodesForThisType)
-				recommendedTotalMemory += mem * float64(nodesForThisType)
-			}
+			recommendedTotalCPU, recommendedTotalMemory = r.distributedCapacity(bestTypes, bestNodes)
 		} else {
 			// If no recommendation, use current capacity
 			recommendedTotalCPU = currentCPUCapacity
@@ -191,7 +217,7 @@ func (r *Recommender) GenerateRecommendationsFromNodePools(ctx context.Context,
 		}
 
 		// Check if there's cost savings
-		hasRecommendation := bestCost < currentCost
+		hasRecommendation := bestNodes > 0 && bestCost < currentCost
 		var costSavings, costSavingsPercent float64
 		var reasoning string
 
@@ -241,6 +267,11 @@ func (r *Recommender) GenerateRecommendationsFromNodePools(ctx context.Context,
 			}
 		}
 
+		if gpuRec != nil {
+			reasoning += fmt.Sprintf(" GPU: %d allocatable unit(s), %d allocated (%d held by pods that are not Ready), %d unallocated; Ready pods need %s.",
+				gpuRec.CurrentGPUs, gpuRec.AllocatedGPUs, gpuRec.IdleAllocatedGPUs, gpuRec.UnallocatedGPUs, describeGPURequirement(gpuReq))
+		}
+
 		rec := NodePoolCapacityRecommendation{
 			NodePoolName:             np.Name,
 			CurrentNodes:             np.CurrentNodes,
@@ -262,12 +293,16 @@ func (r *Recommender) GenerateRecommendationsFromNodePools(ctx context.Context,
 			CapacityType:             bestCapacityType,
 			Taints:                   np.Taints,
 			HasRecommendation:        hasRecommendation,
+			GPU:                      gpuRec,
 		}
 
 		// Generate AI-enhanced reasoning if Ollama is available
-		if r.ollamaClient != nil {
-			aiReasoning := r.generateAIReasoning(ctx, reasoning, rec)
+		if r.HasLLM() {
+			aiReasoning, promptVersion := r.generateAIReasoning(ctx, reasoning, rec)
 			rec.AIReasoning = aiReasoning
+			if aiReasoning != "" {
+				rec.AIPromptVersion = promptVersion
+			}
 		}
 
 		recommendations = append(recommendations, rec)
@@ -276,44 +311,19 @@ func (r *Recommender) GenerateRecommendationsFromNodePools(ctx context.Context,
 	return recommendations, nil
 }
 
-// generateAIReasoning generates an AI-enhanced explanation from the base reasoning
-func (r *Recommender) generateAIReasoning(ctx context.Context, baseReasoning string, rec NodePoolCapacityRecommendation) string {
-	if r.ollamaClient == nil {
-		return ""
+// generateAIReasoning generates an AI-enhanced explanation from the base reasoning and
+// returns it with the version of the prompt template
+func (r *Recommender) generateAIReasoning(ctx context.Context, baseReasoning string, rec NodePoolCapacityRecommendation) (string, string) {
+	if !r.HasLLM() {
+		return "", ""
 	}
 
 	// Build prompt to enhance the reasoning
-	prompt := fmt.Sprintf(`You are a Kubernetes infrastructure expert. Enhance and improve this NodePool optimization explanation to make it more clear, professional, and actionable.
-
-Original Explanation:
-%s
-
-NodePool Details:
-- Name: %s
-- Current: %d nodes, $%.2f/hr
-- Recommended: %d nodes, $%.2f/hr
-- Savings: $%.2f/hr (%.1f%%)
-- Architecture: %s
-- Capacity Type: %s
-
-Provide an enhanced explanation (2-4 sentences) that:
-1. Makes the explanation more clear and professional
-2. Highlights the key benefits and impact
-3. Provides actionable insights
-4. Maintains accuracy of the technical details
-
-Return only the enhanced explanation text, no additional formatting.`,
-		baseReasoning,
-		rec.NodePoolName,
-		rec.CurrentNodes,
-		rec.CurrentCost,
-		rec.RecommendedNodes,
-		rec.RecommendedCost,
-		rec.CostSavings,
-		rec.CostSavingsPercent,
-		rec.Architecture,
-		rec.CapacityType,
-	)
+	prompt, err := r.renderPrompt(prompts.ReasoningEnhancement, reasoningPromptData{BaseReasoning: baseReasoning, Recommendation: rec})
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return "", ""
+	}
 
 	// Use appropriate timeout for AI reasoning based on context
 	// Check parent context deadline to avoid exceeding it
@@ -333,10 +343,10 @@ Return only the enhanced explanation text, no additional formatting.`,
 	aiCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	response, err := r.ollamaClient.Chat(aiCtx, prompt)
+	response, err := r.ollamaClient.Chat(aiCtx, prompt.Text)
 	if err != nil {
 		// If Ollama fails, return empty string (field will be omitted from JSON)
-		return ""
+		return "", ""
 	}
 
 	// Clean up the response - remove any JSON formatting if present
@@ -360,12 +370,12 @@ Return only the enhanced explanation text, no additional formatting.`,
 		}
 	}
 
-	return strings.TrimSpace(aiReasoning)
+	return strings.TrimSpace(aiReasoning), prompt.Version
 }
 
 // EnhanceRecommendationsWithOllama enhances recommendations with AI-generated explanations
 func (r *Recommender) EnhanceRecommendationsWithOllama(ctx context.Context, recommendations []NodePoolCapacityRecommendation) ([]NodePoolCapacityRecommendation, error) {
-	if r.ollamaClient == nil {
+	if !r.HasLLM() {
 		return recommendations, nil // Return original if Ollama not available
 	}
 
@@ -374,42 +384,11 @@ func (r *Recommender) EnhanceRecommendationsWithOllama(ctx context.Context, reco
 		enhanced[i] = rec
 
 		// Build prompt for Ollama to explain the recommendation
-		prompt := fmt.Sprintf(`You are a Kubernetes infrastructure expert. Explain the benefits and impact of this NodePool optimization recommendation.
-
-Current State:
-- NodePool: %s
-- Nodes: %d
-- Instance Types: %v
-- CPU Capacity: %.1f cores (%.1f%% used)
-- Memory Capacity: %.1f GiB (%.1f%% used)
-- Current Cost: $%.2f/hr
-- Capacity Type: %s
-
-Recommended State:
-- Nodes: %d
-- Instance Types: %v
-- CPU Capacity: %.1f cores
-- Memory Capacity: %.1f GiB
-- Recommended Cost: $%.2f/hr
-- Capacity Type: %s
-- Cost Savings: $%.2f/hr (%.1f%%)
-
-Provide a concise explanation (2-3 sentences) focusing on:
-1. Why this change is beneficial
-2. What improvements it brings (cost, efficiency, performance)
-3. Any considerations or risks
-
-Respond with JSON:
-{
-  "explanation": "Your explanation here"
-}`, rec.NodePoolName, rec.CurrentNodes, rec.CurrentInstanceTypes,
-			rec.CurrentCPUCapacity, (rec.CurrentCPUUsed/rec.CurrentCPUCapacity)*100,
-			rec.CurrentMemoryCapacity, (rec.CurrentMemoryUsed/rec.CurrentMemoryCapacity)*100,
-			rec.CurrentCost, rec.CapacityType,
-			rec.RecommendedNodes, rec.RecommendedInstanceTypes,
-			rec.RecommendedTotalCPU, rec.RecommendedTotalMemory,
-			rec.RecommendedCost, rec.CapacityType,
-			rec.CostSavings, rec.CostSavingsPercent)
+		prompt, err := r.renderPrompt(prompts.RecommendationExplanation, explanationPromptData{Recommendation: rec})
+		if err != nil {
+			fmt.Printf("Warning: LLM enhancement failed for recommendation %d: %v\n", i, err)
+			continue
+		}
 
 		// Use adaptive timeout based on parent context
 		// Remote LLM services (vLLM, LiteLLM) typically need more time than local Ollama
@@ -431,32 +410,19 @@ Respond with JSON:
 		}
 		
 		ollamaCtx, cancel := context.WithTimeout(ctx, timeout)
-		response, err := r.ollamaClient.Chat(ollamaCtx, prompt)
+		explanation, err := r.requestRecommendationExplanation(ollamaCtx, prompt.Text)
 		cancel()
 
 		if err == nil {
-			// Parse Ollama response
-			var ollamaResp struct {
-				Explanation string `json:"explanation"`
-			}
-			if err := json.Unmarshal([]byte(response), &ollamaResp); err != nil {
-				// Try to extract JSON from markdown
-				jsonStart := strings.Index(response, "{")
-				jsonEnd := strings.LastIndex(response, "}")
-				if jsonStart >= 0 && jsonEnd > jsonStart {
-					jsonStr := response[jsonStart : jsonEnd+1]
-					_ = json.Unmarshal([]byte(jsonStr), &ollamaResp) // Ignore unmarshal error, use original response
-				}
-			}
-			if ollamaResp.Explanation != "" {
-				// Set both Reasoning and AIReasoning when LLM enhancement is applied
-				enhanced[i].AIReasoning = ollamaResp.Explanation
-				// Keep original reasoning, but add AI explanation as enhancement
-				if enhanced[i].Reasoning != "" {
-					enhanced[i].Reasoning = enhanced[i].Reasoning + " " + ollamaResp.Explanation
-				} else {
-					enhanced[i].Reasoning = ollamaResp.Explanation
-				}
+			text := explanation.Text()
+			// Set both Reasoning and AIReasoning when LLM enhancement is applied
+			enhanced[i].AIReasoning = text
+			enhanced[i].AIPromptVersion = prompt.Version
+			// Keep original reasoning, but add AI explanation as enhancement
+			if enhanced[i].Reasoning != "" {
+				enhanced[i].Reasoning = enhanced[i].Reasoning + " " + text
+			} else {
+				enhanced[i].Reasoning = text
 			}
 		} else {
 			// Log LLM enhancement failure but don't fail the whole operation
@@ -468,14 +434,14 @@ Respond with JSON:
 }
 
 // findOptimalInstanceTypesWithCapacityType finds the best instance type combination and capacity type
-// It tries both spot and on-demand to find the optimal cost
-func (r *Recommender) findOptimalInstanceTypesWithCapacityType(ctx context.Context, requiredCPU, requiredMemory float64, architecture string, preferSpot, hasOnDemand bool) ([]string, int, float64, string) {
+// It tries both spot and on-demand to find the optimal cost, using only instance types the NodePool's requirements allow
+func (r *Recommender) findOptimalInstanceTypesWithCapacityType(ctx context.Context, np kubernetes.NodePoolInfo, requiredCPU, requiredMemory float64, architecture string, preferSpot, hasOnDemand bool) ([]string, int, float64, string) {
 	// Add 10% headroom for bin-packing efficiency
 	targetCPU := requiredCPU * 1.1
 	targetMemory := requiredMemory * 1.1
 
 	// Get candidate instance types based on architecture
-	candidates := r.getCandidateInstanceTypes(architecture, requiredCPU, requiredMemory)
+	candidates := r.getCandidateInstanceTypes(np, architecture, requiredCPU, requiredMemory)
 
 	if len(candidates) == 0 {
 		return []string{}, 0, 0.0, "on-demand"
@@ -494,6 +460,9 @@ func (r *Recommender) findOptimalInstanceTypesWithCapacityType(ctx context.Conte
 	}
 
 	for _, capType := range capacityTypesToTry {
+		if !allowsCapacityType(np, capType) {
+			continue
+		}
 		// Try different combinations of instance types (1-3 types)
 		// Try single instance type
 		for _, it := range candidates {
@@ -517,19 +486,35 @@ func (r *Recommender) findOptimalInstanceTypesWithCapacityType(ctx context.Conte
 			for _, combo := range combinations {
 				// Calculate average capacity per instance type
 				avgCPU, avgMemory := 0.0, 0.0
+				sized := true
 				for _, it := range combo {
 					cpu, mem := r.estimateInstanceCapacity(it)
+					if cpu == 0 || mem == 0 {
+						sized = false
+						break
+					}
 					avgCPU += cpu
 					avgMemory += mem
 				}
-				avgCPU /= float64(len(combo))
-				avgMemory /= float64(len(combo))
-
-				if avgCPU == 0 || avgMemory == 0 {
+				if !sized {
 					continue
 				}
+				avgCPU /= float64(len(combo))
+				avgMemory /= float64(len(combo))
 
+				// Every type in the combination gets at least one node, and the nodes are spread
+				// over the types the way they are priced, so the average can fall short of the target
 				nodesNeeded := int(math.Ceil(math.Max(targetCPU/avgCPU, targetMemory/avgMemory)))
+				if nodesNeeded < len(combo) {
+					nodesNeeded = len(combo)
+				}
+				for {
+					cpu, mem := r.distributedCapacity(combo, nodesNeeded)
+					if cpu >= targetCPU && mem >= targetMemory {
+						break
+					}
+					nodesNeeded++
+				}
 				cost := r.estimateCost(ctx, combo, capType, nodesNeeded)
 				if cost < bestCost {
 					bestCost = cost
@@ -548,13 +533,31 @@ func (r *Recommender) findOptimalInstanceTypesWithCapacityType(ctx context.Conte
 // Deprecated: Use findOptimalInstanceTypesWithCapacityType instead
 //nolint:unused // Kept for backward compatibility
 func (r *Recommender) findOptimalInstanceTypes(requiredCPU, requiredMemory float64, architecture, capacityType string) ([]string, int, float64) {
-	types, nodes, cost, _ := r.findOptimalInstanceTypesWithCapacityType(context.Background(), requiredCPU, requiredMemory, architecture, capacityType == "spot", capacityType != "spot")
+	types, nodes, cost, _ := r.findOptimalInstanceTypesWithCapacityType(context.Background(), kubernetes.NodePoolInfo{}, requiredCPU, requiredMemory, architecture, capacityType == "spot", capacityType != "spot")
 	return types, nodes, cost
 }
 
+// distributedCapacity returns the total CPU and memory of nodes spread over instance types the way
+// estimateCost prices them: evenly, with the remainder on the first types
+func (r *Recommender) distributedCapacity(instanceTypes []string, nodes int) (float64, float64) {
+	var totalCPU, totalMemory float64
+	nodesPerType := nodes / len(instanceTypes)
+	remainder := nodes % len(instanceTypes)
+	for i, it := range instanceTypes {
+		cpu, mem := r.estimateInstanceCapacity(it)
+		nodesForThisType := nodesPerType
+		if i < remainder {
+			nodesForThisType++
+		}
+		totalCPU += cpu * float64(nodesForThisType)
+		totalMemory += mem * float64(nodesForThisType)
+	}
+	return totalCPU, totalMemory
+}
+
 // getCandidateInstanceTypes returns candidate instance types based on architecture and requirements
 // Queries AWS Pricing API for available instance types instead of hardcoding
-func (r *Recommender) getCandidateInstanceTypes(architecture string, cpu, memory float64) []string {
+func (r *Recommender) getCandidateInstanceTypes(np kubernetes.NodePoolInfo, architecture string, cpu, memory float64) []string {
 	// Try to get instance types from AWS Pricing API
 	if r.awsPricing != nil {
 		// Increase timeout to 60 seconds - the pricing index file can be very large (several MB)
@@ -565,7 +568,7 @@ func (r *Recommender) getCandidateInstanceTypes(architecture string, cpu, memory
 		availableTypes, err := r.awsPricing.GetAvailableEC2InstanceTypes(ctx, architecture)
 		if err == nil && len(availableTypes) > 0 {
 			// Filter by CPU/Memory ratio and requirements
-			return r.filterInstanceTypesByRequirements(availableTypes, architecture, cpu, memory)
+			return r.filterInstanceTypesByRequirements(np, availableTypes, architecture, cpu, memory)
 		}
 		// If AWS API fails, fall back to hardcoded list
 		if err != nil {
@@ -581,11 +584,12 @@ func (r *Recommender) getCandidateInstanceTypes(architecture string, cpu, memory
 	}
 
 	// Fallback to hardcoded list if AWS API is unavailable
-	return r.getHardcodedCandidateInstanceTypes(architecture, cpu, memory)
+	return r.getHardcodedCandidateInstanceTypes(np, architecture, cpu, memory)
 }
 
 // filterInstanceTypesByRequirements filters AWS instance types based on CPU/Memory requirements
-func (r *Recommender) filterInstanceTypesByRequirements(availableTypes []string, architecture string, cpu, memory float64) []string {
+// and the NodePool's node requirements (instance family, category...)
+func (r *Recommender) filterInstanceTypesByRequirements(np kubernetes.NodePoolInfo, availableTypes []string, architecture string, cpu, memory float64) []string {
 	memoryToCPURatio := memory / cpu
 	var candidates []string
 
@@ -597,6 +601,11 @@ func (r *Recommender) filterInstanceTypesByRequirements(availableTypes []string,
 			continue
 		}
 
+		// Skip instance types the NodePool doesn't allow
+		if !allowedByNodePool(np, it) {
+			continue
+		}
+
 		// Get instance capacity
 		instanceCPU, instanceMemory := r.estimateInstanceCapacity(it)
 		if instanceCPU == 0 || instanceMemory == 0 {
@@ -656,7 +665,7 @@ func (r *Recommender) filterInstanceTypesByRequirements(availableTypes []string,
 }
 
 // getHardcodedCandidateInstanceTypes returns hardcoded instance types as fallback
-func (r *Recommender) getHardcodedCandidateInstanceTypes(architecture string, cpu, memory float64) []string {
+func (r *Recommender) getHardcodedCandidateInstanceTypes(np kubernetes.NodePoolInfo, architecture string, cpu, memory float64) []string {
 	var candidates []string
 	memoryToCPURatio := memory / cpu
 
@@ -686,11 +695,11 @@ func (r *Recommender) getHardcodedCandidateInstanceTypes(architecture string, cp
 		}
 	}
 
-	// Filter out invalid instance types and sort by cost efficiency
+	// Filter out invalid and disallowed instance types and sort by cost efficiency
 	validCandidates := []string{}
 	for _, it := range candidates {
 		instCPU, instMem := r.estimateInstanceCapacity(it)
-		if instCPU > 0 && instMem > 0 {
+		if instCPU > 0 && instMem > 0 && allowedByNodePool(np, it) {
 			validCandidates = append(validCandidates, it)
 		}
 	}
@@ -715,6 +724,21 @@ func (r *Recommender) getHardcodedCandidateInstanceTypes(architecture string, cp
 	return validCandidates
 }
 
+// allowedByNodePool reports whether the NodePool's requirements allow an instance type. Types
+// outside the catalog can't be checked and are only allowed when the NodePool has no requirements.
+func allowedByNodePool(np kubernetes.NodePoolInfo, instanceType string) bool {
+	labels, _ := instanceTypeLabels(instanceType)
+	if labels == nil {
+		return len(np.NodeRequirements) == 0
+	}
+	return np.UnmetRequirement(labels) == nil
+}
+
+// allowsCapacityType reports whether the NodePool's karpenter.sh/capacity-type requirement allows a capacity type
+func allowsCapacityType(np kubernetes.NodePoolInfo, capacityType string) bool {
+	return np.UnmetRequirement(map[string]string{"karpenter.sh/capacity-type": capacityType}) == nil
+}
+
 // generateCombinations generates all combinations of n items from the candidates list
 func (r *Recommender) generateCombinations(candidates []string, n int) [][]string {
 	if n > len(candidates) {
@@ -0,0 +1,151 @@
+package recommender
+
+import (
+	"context"
+	"testing"
+
+	"github.com/karpenter-optimizer/internal/config"
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func gravitonNodePool() kubernetes.NodePoolInfo {
+	node := func(name string, cpu, memory float64) kubernetes.NodeInfo {
+		return kubernetes.NodeInfo{
+			Name:         name,
+			InstanceType: "m6g.2xlarge",
+			CapacityType: "on-demand",
+			Architecture: "arm64",
+			CPUUsage:     &kubernetes.NodeUsage{Used: cpu, Allocatable: 7.9},
+			MemoryUsage:  &kubernetes.NodeUsage{Used: memory, Allocatable: 30.5},
+		}
+	}
+	return kubernetes.NodePoolInfo{
+		Name:         "arm",
+		Architecture: "arm64",
+		CapacityType: "on-demand",
+		CurrentNodes: 3,
+		NodeRequirements: []corev1.NodeSelectorRequirement{
+			{Key: "kubernetes.io/arch", Operator: corev1.NodeSelectorOpIn, Values: []string{"arm64"}},
+			{Key: "karpenter.k8s.aws/instance-family", Operator: corev1.NodeSelectorOpIn, Values: []string{"m6g", "c6g"}},
+		},
+		ActualNodes: []kubernetes.NodeInfo{node("a", 1.5, 6), node("b", 1, 5), node("c", 0.8, 4.5)},
+	}
+}
+
+func TestAllowedByNodePool(t *testing.T) {
+	graviton := gravitonNodePool()
+	tests := []struct {
+		name         string
+		np           kubernetes.NodePoolInfo
+		instanceType string
+		want         bool
+	}{
+		{"no requirements", kubernetes.NodePoolInfo{}, "c7g.4xlarge", true},
+		{"allowed family", graviton, "c6g.4xlarge", true},
+		{"other family", graviton, "c7g.4xlarge", false},
+		{"other architecture", graviton, "m6i.xlarge", false},
+		{"unknown type without requirements", kubernetes.NodePoolInfo{}, "m9z.large", true},
+		{"unknown type with requirements", graviton, "m9z.large", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, allowedByNodePool(tt.np, tt.instanceType))
+		})
+	}
+}
+
+func TestDistributedCapacity(t *testing.T) {
+	rec := NewOfflineRecommender(&config.Config{})
+	types := []string{"m6i.xlarge", "m6i.4xlarge"}
+
+	cpu, memory := rec.distributedCapacity(types, 1)
+	assert.Equal(t, 4.0, cpu, "a single node goes to the first type")
+	assert.Equal(t, 16.0, memory)
+
+	cpu, memory = rec.distributedCapacity(types, 3)
+	assert.Equal(t, 24.0, cpu, "the remainder goes to the first types")
+	assert.Equal(t, 96.0, memory)
+}
+
+func TestFindOptimalInstanceTypesWithCapacityType(t *testing.T) {
+	rec := NewOfflineRecommender(&config.Config{})
+	ctx := context.Background()
+
+	t.Run("only instance types the NodePool allows", func(t *testing.T) {
+		np := gravitonNodePool()
+		types, nodes, _, _ := rec.findOptimalInstanceTypesWithCapacityType(ctx, np, 23.7, 91.5, "arm64", false, true)
+		require.NotEmpty(t, types)
+		assert.Positive(t, nodes)
+		for _, it := range types {
+			assert.True(t, allowedByNodePool(np, it), it)
+		}
+	})
+
+	t.Run("no allowed candidate", func(t *testing.T) {
+		np := kubernetes.NodePoolInfo{NodeRequirements: []corev1.NodeSelectorRequirement{
+			{Key: "karpenter.k8s.aws/instance-family", Operator: corev1.NodeSelectorOpIn, Values: []string{"r7g"}},
+		}}
+		types, nodes, _, _ := rec.findOptimalInstanceTypesWithCapacityType(ctx, np, 4, 8, "amd64", false, true)
+		assert.Empty(t, types)
+		assert.Zero(t, nodes)
+	})
+
+	t.Run("only capacity types the NodePool allows", func(t *testing.T) {
+		np := kubernetes.NodePoolInfo{NodeRequirements: []corev1.NodeSelectorRequirement{
+			{Key: "karpenter.sh/capacity-type", Operator: corev1.NodeSelectorOpIn, Values: []string{"on-demand"}},
+		}}
+		types, _, _, capacityType := rec.findOptimalInstanceTypesWithCapacityType(ctx, np, 7.8, 13.8, "amd64", true, true)
+		require.NotEmpty(t, types)
+		assert.Equal(t, "on-demand", capacityType)
+	})
+
+	t.Run("capacity covers the target however nodes are spread", func(t *testing.T) {
+		types, nodes, cost, _ := rec.findOptimalInstanceTypesWithCapacityType(ctx, kubernetes.NodePoolInfo{}, 7.8, 13.8, "amd64", true, false)
+		require.NotEmpty(t, types)
+		assert.GreaterOrEqual(t, nodes, len(types), "every instance type gets a node")
+		cpu, memory := rec.distributedCapacity(types, nodes)
+		assert.GreaterOrEqual(t, cpu, 7.8*1.1)
+		assert.GreaterOrEqual(t, memory, 13.8*1.1)
+		assert.InDelta(t, rec.estimateCost(ctx, types, "spot", nodes), cost, 0.0001)
+	})
+}
+
+func TestGenerateRecommendationsFromNodePools(t *testing.T) {
+	rec := NewOfflineRecommender(&config.Config{})
+	busy := func(name string, cpu, memory float64) kubernetes.NodeInfo {
+		return kubernetes.NodeInfo{
+			Name:         name,
+			InstanceType: "c6i.xlarge",
+			CapacityType: "spot",
+			Architecture: "amd64",
+			CPUUsage:     &kubernetes.NodeUsage{Used: cpu, Allocatable: 3.9},
+			MemoryUsage:  &kubernetes.NodeUsage{Used: memory, Allocatable: 6.9},
+		}
+	}
+	rightSized := kubernetes.NodePoolInfo{
+		Name:         "batch",
+		Architecture: "amd64",
+		CapacityType: "spot",
+		CurrentNodes: 2,
+		ActualNodes:  []kubernetes.NodeInfo{busy("a", 3.3, 5.2), busy("b", 3.1, 4.8)},
+	}
+	graviton := gravitonNodePool()
+
+	recommendations, err := rec.GenerateRecommendationsFromNodePools(context.Background(), []kubernetes.NodePoolInfo{graviton, rightSized}, nil)
+	require.NoError(t, err)
+	require.Len(t, recommendations, 2)
+
+	arm := recommendations[0]
+	require.True(t, arm.HasRecommendation)
+	for _, it := range arm.RecommendedInstanceTypes {
+		assert.True(t, allowedByNodePool(graviton, it), it)
+	}
+	assert.GreaterOrEqual(t, arm.RecommendedTotalCPU, arm.CurrentCPUCapacity)
+
+	batch := recommendations[1]
+	assert.False(t, batch.HasRecommendation, "no cheaper set of nodes covers the capacity: %s", batch.Reasoning)
+	assert.GreaterOrEqual(t, batch.RecommendedTotalCPU, batch.CurrentCPUUsed)
+}
@@ -0,0 +1,75 @@
+package recommender
+
+import (
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/prompts"
+)
+
+// nodePoolAdvicePromptData is the data of the nodepool-advice template
+type nodePoolAdvicePromptData struct {
+	NodePool        kubernetes.NodePoolInfo
+	CurrentTypes    []string
+	TotalCPU        float64 // Cores requested by the workloads
+	TotalMemory     float64 // GiB requested by the workloads
+	MaxGPU          int
+	Overprovisioned bool
+	Workloads       []promptWorkload
+	Disruptions     DisruptionInsights
+}
+
+// promptWorkload is a workload's resource requests as shown to the LLM
+type promptWorkload struct {
+	Namespace string
+	Name      string
+	Type      string
+	CPU       string
+	Memory    string
+}
+
+// clusterSummaryAdvicePromptData is the data of the cluster-summary-advice template
+type clusterSummaryAdvicePromptData struct {
+	NodePool          kubernetes.NodePoolInfo
+	CurrentTypes      []string
+	CurrentNodes      int
+	TotalNodes        int // Cluster-wide
+	SpotNodes         int // Cluster-wide
+	OnDemandNodes     int // Cluster-wide
+	TotalPods         int // Cluster-wide
+	CPUUsed           float64
+	CPUAllocatable    float64
+	CPUUtilization    float64
+	MemoryUsed        float64
+	MemoryAllocatable float64
+	MemoryUtilization float64
+	Overprovisioned   bool
+	Disruptions       DisruptionInsights
+
+	// The NodePool's nodes by capacity type (nodes without one use the NodePool's)
+	ActualNodes         int
+	ActualSpotNodes     int
+	ActualOnDemandNodes int
+}
+
+// reasoningPromptData is the data of the reasoning-enhancement template
+type reasoningPromptData struct {
+	BaseReasoning  string
+	Recommendation NodePoolCapacityRecommendation
+}
+
+// explanationPromptData is the data of the recommendation-explanation template
+type explanationPromptData struct {
+	Recommendation NodePoolCapacityRecommendation
+}
+
+// Prompts returns the prompt templates in use
+func (r *Recommender) Prompts() *prompts.Set {
+	if r.prompts == nil {
+		return prompts.Default()
+	}
+	return r.prompts
+}
+
+// renderPrompt renders a prompt template; the returned version is recorded with the AI output
+func (r *Recommender) renderPrompt(name string, data interface{}) (prompts.Prompt, error) {
+	return r.Prompts().Render(name, data)
+}
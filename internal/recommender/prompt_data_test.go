@@ -0,0 +1,90 @@
+package recommender
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/karpenter-optimizer/internal/kubernetes"
+	"github.com/karpenter-optimizer/internal/ollama"
+	"github.com/karpenter-optimizer/internal/prompts"
+	"github.com/karpenter-optimizer/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildOllamaPrompt(t *testing.T) {
+	r := &Recommender{}
+	np := kubernetes.NodePoolInfo{Name: "default", CapacityType: "on-demand", Architecture: "amd64", CurrentNodes: 3, MaxSize: 10}
+	workloads := []Workload{
+		{Namespace: "shop", Name: "api", WorkloadType: "deployment", CPU: "2", CPURequest: "500m", Memory: "1Gi"},
+		{Namespace: "shop", Name: "worker", WorkloadType: "deployment", CPU: "1", Memory: "2Gi"},
+	}
+
+	prompt, err := r.buildOllamaPrompt(np, workloads, 1.5, 3, 0, []string{"m5.large", "m5.xlarge"}, false, DisruptionInsights{})
+	require.NoError(t, err)
+	assert.Equal(t, "nodepool-advice@1", prompt.Version)
+	assert.Contains(t, prompt.Text, "- Current Instance Types: [m5.large m5.xlarge]")
+	assert.Contains(t, prompt.Text, "- Total CPU: 1.50 cores")
+	assert.Contains(t, prompt.Text, "- Number of workloads: 2")
+	assert.Contains(t, prompt.Text, "- shop/api (deployment): CPU=500m, Memory=1Gi\n- shop/worker (deployment): CPU=1, Memory=2Gi\n\nPlease provide")
+	assert.Contains(t, prompt.Text, "recommend 20-30% node reduction")
+	assert.NotContains(t, prompt.Text, "Disruption Analysis")
+
+	prompt, err = r.buildOllamaPrompt(np, workloads, 1.5, 3, 0, nil, true, DisruptionInsights{TotalDisruptions: 9, ConsolidationCount: 7, ConsolidationRate: 2.5, HasHighConsolidation: true})
+	require.NoError(t, err)
+	assert.Contains(t, prompt.Text, "Memory=2Gi\nDisruption Analysis (last 7 days):\n- Total Disruptions: 9\n- Consolidations: 7 (2.5 per day)")
+	assert.Contains(t, prompt.Text, "- High Consolidation Rate: true")
+}
+
+func TestBuildOllamaPromptFromClusterSummary(t *testing.T) {
+	r := &Recommender{}
+	np := kubernetes.NodePoolInfo{Name: "default", CapacityType: "spot", Architecture: "arm64", MinSize: 1, MaxSize: 10}
+	build := func(nodes []kubernetes.NodeInfo, insights DisruptionInsights) prompts.Prompt {
+		t.Helper()
+		prompt, err := r.buildOllamaPromptFromClusterSummary(np, []string{"m6g.large"}, 3, 12, 8, 32,
+			len(nodes), 10, 6, 4, 40, 37.5, 37.5, true, insights, nodes)
+		require.NoError(t, err)
+		assert.Equal(t, "cluster-summary-advice@1", prompt.Version)
+		return prompt
+	}
+
+	t.Run("all spot", func(t *testing.T) {
+		prompt := build([]kubernetes.NodeInfo{{CapacityType: "spot"}, {}}, DisruptionInsights{})
+		assert.Contains(t, prompt.Text, "Current: 2 nodes, types: [m6g.large], capacity: spot, arch: arm64, size: 1-10")
+		assert.Contains(t, prompt.Text, "Usage: CPU 37.5% (3.00/8.00 cores), Memory 37.5% (12.00/32.00 GiB)\n\nIMPORTANT: All 2 nodes")
+		assert.Contains(t, prompt.Text, "- Capacity: Keep spot (all nodes already spot)")
+	})
+
+	t.Run("on-demand nodes", func(t *testing.T) {
+		prompt := build([]kubernetes.NodeInfo{{CapacityType: "spot"}, {CapacityType: "on-demand"}}, DisruptionInsights{TotalDisruptions: 4, ConsolidationCount: 3})
+		assert.Contains(t, prompt.Text, "Disruptions: 4 disruptions (consolidations: 3) \nIMPORTANT: This NodePool has 1 on-demand node(s) and 1 spot node(s)")
+		assert.Contains(t, prompt.Text, "- Capacity: Convert 1 on-demand to spot")
+	})
+
+	t.Run("no nodes", func(t *testing.T) {
+		prompt := build(nil, DisruptionInsights{HasHighConsolidation: true, ConsolidationRate: 4})
+		assert.Contains(t, prompt.Text, "Disruptions: High consolidation (4/day) - reduce nodes. \n\nRules:")
+		assert.NotContains(t, prompt.Text, "IMPORTANT")
+		assert.Contains(t, prompt.Text, "- Capacity: Spot preferred for cost")
+	})
+}
+
+func TestPromptTemplateOverride(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "reasoning-enhancement.tmpl"),
+		[]byte("{{- /* version: 2-short */ -}}\nRewrite for {{.Recommendation.NodePoolName}}: {{.BaseReasoning}}"), 0644))
+	set, err := prompts.Load(dir)
+	require.NoError(t, err)
+
+	server := testutil.NewFakeLLMServer(ollama.Message{Role: "assistant", Content: "Two nodes are enough."})
+	defer server.Close()
+	r := &Recommender{ollamaClient: ollama.NewClient(server.URL, "test-model", "litellm", "", false), prompts: set}
+
+	reasoning, version := r.generateAIReasoning(context.Background(), "Over-provisioned.", NodePoolCapacityRecommendation{NodePoolName: "default"})
+	assert.Equal(t, "Two nodes are enough.", reasoning)
+	assert.Equal(t, "reasoning-enhancement@2-short", version)
+	require.Len(t, server.Requests(), 1)
+	assert.Equal(t, "Rewrite for default: Over-provisioned.", server.Requests()[0].Messages[0].Content)
+}
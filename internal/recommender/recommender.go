@@ -2,7 +2,6 @@ package recommender
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"math"
 	"strconv"
@@ -14,6 +13,7 @@ import (
 	"github.com/karpenter-optimizer/internal/config"
 	"github.com/karpenter-optimizer/internal/kubernetes"
 	"github.com/karpenter-optimizer/internal/ollama"
+	"github.com/karpenter-optimizer/internal/prompts"
 )
 
 // ProgressCallback is a function type for reporting progress during recommendation generation
@@ -41,32 +41,67 @@ type Recommender struct {
 	config       *config.Config
 	k8sClient    *kubernetes.Client
 	ollamaClient *ollama.Client
+	prompts      *prompts.Set       // LLM prompt templates (nil = embedded templates)
 	awsPricing   *awspricing.Client // AWS Pricing API client
 	priceCache   map[string]float64 // Cache for Ollama-fetched pricing
 	priceCacheMu sync.RWMutex       // Mutex for thread-safe cache access
+
+	rejectedPrices  map[string]bool // Instance types whose LLM price failed validation (guarded by priceCacheMu)
+	llmRejections   []LLMRejection  // Recent LLM outputs that failed validation
+	llmRejectionsMu sync.Mutex
 }
 
-// HasLLM returns true if LLM client is configured and available
+// HasLLM returns true if LLM client is configured and available (not every provider's
+// circuit breaker is open)
 func (r *Recommender) HasLLM() bool {
-	return r.ollamaClient != nil
+	return r.ollamaClient != nil && r.ollamaClient.Available()
 }
 
-func NewRecommender(cfg *config.Config) *Recommender {
-	var ollamaClient *ollama.Client
-	// Use new LLM config if available, otherwise fall back to legacy Ollama config
-	llmURL := cfg.LLMURL
-	llmModel := cfg.LLMModel
-	if llmURL == "" {
-		llmURL = cfg.OllamaURL
-		llmModel = cfg.OllamaModel
+// newLLMClient creates the LLM client for the primary provider with the fallback
+// providers and the retry, concurrency and circuit breaker settings. It returns nil
+// when the primary provider is misconfigured.
+func newLLMClient(cfg *config.Config, llmURL, llmModel string) *ollama.Client {
+	primary, err := ollama.NewProvider(ollama.ProviderConfig{
+		Provider:   cfg.LLMProvider,
+		URL:        llmURL,
+		Model:      llmModel,
+		APIKey:     cfg.LLMAPIKey,
+		APIVersion: cfg.LLMAzureAPIVersion,
+	}, cfg.Debug)
+	if err != nil {
+		fmt.Printf("Warning: LLM disabled: %v\n", err)
+		return nil
 	}
-	
-	if llmURL != "" {
-		ollamaClient = ollama.NewClient(llmURL, llmModel, cfg.LLMProvider, cfg.LLMAPIKey, cfg.Debug)
-		if cfg.Debug {
-			fmt.Printf("LLM client initialized: provider=%s, url=%s, model=%s\n", cfg.LLMProvider, llmURL, llmModel)
+	client := ollama.NewClientWithProvider(primary, cfg.Debug)
+	client.SetResilience(ollama.ResilienceOptions{
+		MaxRetries:       cfg.LLMMaxRetries,
+		RetryBaseDelay:   cfg.LLMRetryBaseDelay,
+		MaxConcurrency:   cfg.LLMMaxConcurrency,
+		FailureThreshold: cfg.LLMCircuitFailureThreshold,
+		OpenDuration:     cfg.LLMCircuitOpenDuration,
+	})
+
+	fallbacks, err := ollama.ParseProviderList(cfg.LLMFallbacks)
+	if err != nil {
+		fmt.Printf("Warning: Ignoring LLM fallbacks: %v\n", err)
+	}
+	for _, fallbackCfg := range fallbacks {
+		if fallbackCfg.Provider == ollama.ProviderAzure {
+			fallbackCfg.APIVersion = cfg.LLMAzureAPIVersion
 		}
+		fallback, err := ollama.NewProvider(fallbackCfg, cfg.Debug)
+		if err != nil {
+			fmt.Printf("Warning: Ignoring LLM fallback %s: %v\n", fallbackCfg.URL, err)
+			continue
+		}
+		client.AddFallback(fallback)
+		fmt.Printf("LLM fallback configured: provider=%s, model=%s\n", fallback.Name(), fallback.Model())
 	}
+	return client
+}
+
+func NewRecommender(cfg *config.Config) *Recommender {
+	r := NewOfflineRecommender(cfg)
 
 	// Initialize AWS Pricing client
 	// REQUIRES AWS credentials - uses GetProducts API (queries specific instance types, no 400MB download)
@@ -93,11 +128,50 @@ func NewRecommender(cfg *config.Config) *Recommender {
 			fmt.Printf("Debug: AWS Region=%s, Pricing API endpoint=us-east-1\n", cfg.AWSRegion)
 		}
 	}
+	r.awsPricing = awsPricingClient
+	return r
+}
+
+// NewOfflineRecommender creates a recommender that never calls the AWS Pricing API: instance
+// types are priced from the built-in price table and family estimates (or the LLM, if
+// configured). It is used to evaluate recorded cluster snapshots.
+func NewOfflineRecommender(cfg *config.Config) *Recommender {
+	var ollamaClient *ollama.Client
+	// Use new LLM config if available, otherwise fall back to legacy Ollama config
+	llmURL := cfg.LLMURL
+	llmModel := cfg.LLMModel
+	if llmURL == "" {
+		llmURL = cfg.OllamaURL
+		llmModel = cfg.OllamaModel
+	}
+	
+	if llmURL != "" {
+		ollamaClient = newLLMClient(cfg, llmURL, llmModel)
+	}
+	if ollamaClient != nil {
+		if cfg.Debug {
+			fmt.Printf("LLM client initialized: provider=%s, url=%s, model=%s\n", cfg.LLMProvider, llmURL, llmModel)
+		}
+		if cfg.LLMCacheEnabled {
+			cache, err := ollama.NewResponseCache(cfg.LLMCacheTTL, cfg.LLMCacheMaxEntries, int64(cfg.LLMCacheMaxBytes), cfg.LLMCachePath)
+			if err != nil {
+				fmt.Printf("Warning: Failed to load LLM response cache, caching in memory only: %v\n", err)
+				cache, _ = ollama.NewResponseCache(cfg.LLMCacheTTL, cfg.LLMCacheMaxEntries, int64(cfg.LLMCacheMaxBytes), "")
+			}
+			ollamaClient.SetCache(cache)
+		}
+	}
+
+	promptSet, err := prompts.Load(cfg.PromptTemplatesDir)
+	if err != nil {
+		fmt.Printf("Warning: %v; using embedded prompt templates\n", err)
+		promptSet = prompts.Default()
+	}
 
 	return &Recommender{
 		config:       cfg,
 		ollamaClient: ollamaClient,
-		awsPricing:   awsPricingClient,
+		prompts:      promptSet,
 		priceCache:   make(map[string]float64),
 	}
 }
@@ -122,6 +196,10 @@ type Workload struct {
 	CPU           string // e.g., "100m", "2", "1.5" - can be request or limit
 	Memory        string // e.g., "128Mi", "2Gi", "4G" - can be request or limit
 	GPU           int
+	GPUModel      string         // GPU model pinned via node selector/affinity (e.g., NVIDIA-A10G)
+	GPUMemoryMiB  int64          // Minimum memory per GPU in MiB
+	MIGProfiles   map[string]int // MIG profile -> slices requested (e.g., "1g.5gb": 2)
+	SharedGPU     int            // Time-sliced GPU replicas requested
 	Labels        map[string]string
 	CPUUsage      *float64 // Reserved for future use (not currently used)
 	MemoryUsage   *float64 // Reserved for future use (not currently used)
@@ -143,6 +221,8 @@ type NodePoolRecommendation struct {
 	Reasoning        string            `json:"reasoning"`
 	WorkloadsMatched []string          `json:"workloadsMatched"`
 	CurrentState     *CurrentState     `json:"currentState,omitempty"` // Before state
+	LLMRejections    []LLMRejection    `json:"llmRejections,omitempty"` // LLM suggestions that failed validation
+	PromptVersion    string            `json:"promptVersion,omitempty"` // Prompt template of the applied LLM advice, e.g. "nodepool-advice@1"
 }
 
 type CurrentState struct {
@@ -159,9 +239,10 @@ type CurrentState struct {
 }
 
 type Requirements struct {
-	CPU    ResourceRange `json:"cpu"`
-	Memory ResourceRange `json:"memory"`
-	GPU    int           `json:"gpu"`
+	CPU      ResourceRange `json:"cpu"`
+	Memory   ResourceRange `json:"memory"`
+	GPU      int           `json:"gpu"`
+	GPUModel string        `json:"gpuModel,omitempty"` // Normalized GPU model (t4, a10g, l4, ...) when workloads pin one
 }
 
 type ResourceRange struct {
@@ -519,31 +600,35 @@ func (r *Recommender) GenerateRecommendationsFromClusterSummary(clusterCPUUsed,
 			reasoning += "All nodes are already spot instances - maintaining spot configuration. "
 		}
 
-		if r.ollamaClient != nil {
+		var llmRejections []LLMRejection
+		var promptVersion string
+		if r.HasLLM() {
 			if progressCallback != nil {
 				progressCallback(fmt.Sprintf("Generating AI recommendations for '%s'...", np.Name), 20.0+(float64(i)/float64(totalNodePools))*60.0)
 			}
 			ollamaCtx, ollamaCancel := context.WithTimeout(context.Background(), 90*time.Second)
-			enhancedReasoning, enhancedTypes, minSize, maxSize, ollamaCapacityType := r.enhanceWithOllamaFromClusterSummary(
+			advice := r.enhanceWithOllamaFromClusterSummary(
 				ollamaCtx, np, currentTypes, npCPUUsed, npMemoryUsed, npCPUAllocatable, npMemoryAllocatable,
 				actualNodeCount, totalNodes, spotNodes, onDemandNodes, totalPods,
 				npCPUUtilization, npMemoryUtilization, isNPOverprovisioned, npDisruptionInsights, actualNodes)
 			ollamaCancel()
 
-			if enhancedReasoning != "" {
-				reasoning = enhancedReasoning
-			}
-			if len(enhancedTypes) > 0 {
-				recommendedInstanceTypes = enhancedTypes
-			}
-			if minSize != nil {
-				recommendedMinSize = *minSize
-			}
-			if maxSize != nil {
-				recommendedMaxSize = *maxSize
-			}
-			if ollamaCapacityType != "" {
-				recommendedCapacityType = ollamaCapacityType
+			if advice != nil {
+				reasoning = advice.Reasoning()
+				recommendedInstanceTypes = advice.InstanceTypes
+				llmRejections = advice.Rejections
+				promptVersion = advice.PromptVersion
+				if advice.MinSize != nil {
+					recommendedMinSize = *advice.MinSize
+				}
+				if advice.MaxSize != nil {
+					recommendedMaxSize = *advice.MaxSize
+				}
+				recommendedCapacityType = advice.CapacityType
+				// Size the advised instance types with the advised node count
+				if nodesNeeded > 0 {
+					nodesNeeded = advice.boundedNodeCount(nodesNeeded, recommendedMinSize, recommendedMaxSize)
+				}
 			}
 		}
 
@@ -713,6 +798,8 @@ func (r *Recommender) GenerateRecommendationsFromClusterSummary(clusterCPUUsed,
 			Reasoning:        reasoning + fmt.Sprintf(" Recommended configuration would provision %.1f CPU cores and %.1f GiB memory across %d node(s).", totalProvisionedCPU, totalProvisionedMemory, nodesNeeded),
 			WorkloadsMatched: []string{fmt.Sprintf("NodePool '%s': %d nodes, %.1f%% CPU, %.1f%% Memory", np.Name, actualNodeCount, npCPUUtilization, npMemoryUtilization)},
 			CurrentState:     currentState,
+			LLMRejections:    llmRejections,
+			PromptVersion:    promptVersion,
 		})
 	}
 
@@ -724,69 +811,38 @@ func (r *Recommender) GenerateRecommendationsFromClusterSummary(clusterCPUUsed,
 	return recommendations, nil
 }
 
-// enhanceWithOllamaFromClusterSummary uses Ollama to generate recommendations based on cluster summary
+// enhanceWithOllamaFromClusterSummary asks the LLM for a structured recommendation based on
+// cluster summary data. Suggestions that fail validation are dropped and reported in
+// advice.Rejections. It returns nil when the LLM fails or its reply does not validate.
 func (r *Recommender) enhanceWithOllamaFromClusterSummary(ctx context.Context, np kubernetes.NodePoolInfo, currentTypes []string,
 	npCPUUsed, npMemoryUsed, npCPUAllocatable, npMemoryAllocatable float64,
 	currentNodes, totalNodes, spotNodes, onDemandNodes, totalPods int,
 	cpuUtilization, memoryUtilization float64, isOverprovisioned bool, disruptionInsights DisruptionInsights,
-	actualNodes []kubernetes.NodeInfo) (string, []string, *int, *int, string) {
+	actualNodes []kubernetes.NodeInfo) *NodePoolAdvice {
 
-	prompt := r.buildOllamaPromptFromClusterSummary(np, currentTypes, npCPUUsed, npMemoryUsed, npCPUAllocatable, npMemoryAllocatable,
+	prompt, err := r.buildOllamaPromptFromClusterSummary(np, currentTypes, npCPUUsed, npMemoryUsed, npCPUAllocatable, npMemoryAllocatable,
 		currentNodes, totalNodes, spotNodes, onDemandNodes, totalPods, cpuUtilization, memoryUtilization, isOverprovisioned, disruptionInsights, actualNodes)
-
-	response, err := r.ollamaClient.Chat(ctx, prompt)
 	if err != nil {
-		fmt.Printf("Ollama request failed: %v\n", err)
-		return "", currentTypes, nil, nil, ""
-	}
-
-	// Parse Ollama response
-	var ollamaRec struct {
-		Reasoning     string   `json:"reasoning"`
-		InstanceTypes []string `json:"instanceTypes"`
-		Explanation   string   `json:"explanation"`
-		MinSize       *int     `json:"minSize,omitempty"`
-		MaxSize       *int     `json:"maxSize,omitempty"`
-		CapacityType  string   `json:"capacityType,omitempty"`
-	}
-
-	if err := json.Unmarshal([]byte(response), &ollamaRec); err != nil {
-		// Try to extract JSON from markdown code blocks
-		jsonStart := strings.Index(response, "{")
-		jsonEnd := strings.LastIndex(response, "}")
-		if jsonStart >= 0 && jsonEnd > jsonStart {
-			jsonStr := response[jsonStart : jsonEnd+1]
-			if err := json.Unmarshal([]byte(jsonStr), &ollamaRec); err != nil {
-				return response, currentTypes, nil, nil, ""
-			}
-		} else {
-			return response, currentTypes, nil, nil, ""
-		}
-	}
-
-	instanceTypes := currentTypes
-	if len(ollamaRec.InstanceTypes) > 0 {
-		instanceTypes = ollamaRec.InstanceTypes
+		fmt.Printf("Warning: %v\n", err)
+		return nil
 	}
 
-	reasoning := ollamaRec.Explanation
-	if ollamaRec.Reasoning != "" {
-		if reasoning != "" {
-			reasoning += ". " + ollamaRec.Reasoning
-		} else {
-			reasoning = ollamaRec.Reasoning
-		}
+	advice, err := r.requestNodePoolAdvice(ctx, prompt.Text)
+	if err != nil {
+		fmt.Printf("Ollama request failed: %v\n", err)
+		return nil
 	}
-
-	return reasoning, instanceTypes, ollamaRec.MinSize, ollamaRec.MaxSize, ollamaRec.CapacityType
+	advice.PromptVersion = prompt.Version
+	r.validateNodePoolAdvice(np, advice, currentTypes, NodePoolUsesGPU(np))
+	return advice
 }
 
-// buildOllamaPromptFromClusterSummary creates a prompt for Ollama based on cluster summary data
+// buildOllamaPromptFromClusterSummary renders the cluster-summary-advice prompt template
 func (r *Recommender) buildOllamaPromptFromClusterSummary(np kubernetes.NodePoolInfo, currentTypes []string,
 	npCPUUsed, npMemoryUsed, npCPUAllocatable, npMemoryAllocatable float64,
 	currentNodes, totalNodes, spotNodes, onDemandNodes, totalPods int,
 	cpuUtilization, memoryUtilization float64, isOverprovisioned bool, disruptionInsights DisruptionInsights,
-	actualNodes []kubernetes.NodeInfo) string {
+	actualNodes []kubernetes.NodeInfo) (prompts.Prompt, error) {
 
 	// Determine actual capacity type distribution
 	actualSpotCount := 0
@@ -803,69 +859,26 @@ func (r *Recommender) buildOllamaPromptFromClusterSummary(np kubernetes.NodePool
 		}
 	}
 
-	capacityTypeContext := ""
-	if len(actualNodes) > 0 {
-		if actualSpotCount == len(actualNodes) {
-			capacityTypeContext = fmt.Sprintf("\nIMPORTANT: All %d nodes in this NodePool are already using SPOT instances. DO NOT recommend changing capacity type - keep it as 'spot'.", len(actualNodes))
-		} else if actualOnDemandCount > 0 {
-			capacityTypeContext = fmt.Sprintf("\nIMPORTANT: This NodePool has %d on-demand node(s) and %d spot node(s). Recommend converting on-demand nodes to SPOT instances for cost savings (75%% discount).", actualOnDemandCount, actualSpotCount)
-		}
-	}
-
-	// Build concise disruption summary
-	disruptionSummary := ""
-	if disruptionInsights.HasHighConsolidation {
-		disruptionSummary = fmt.Sprintf("High consolidation (%d/day) - reduce nodes.", int(disruptionInsights.ConsolidationRate))
-	} else if disruptionInsights.TotalDisruptions > 0 {
-		disruptionSummary = fmt.Sprintf("%d disruptions (consolidations: %d)", disruptionInsights.TotalDisruptions, disruptionInsights.ConsolidationCount)
-	}
-
-	prompt := fmt.Sprintf(`Karpenter NodePool optimization for: %s
-
-Current: %d nodes, types: %v, capacity: %s, arch: %s, size: %d-%d
-Usage: CPU %.1f%% (%.2f/%.2f cores), Memory %.1f%% (%.2f/%.2f GiB)
-%s%s
-
-Rules:
-- No GPU unless required
-- Low util (<50%%) or high consolidation → reduce nodes, smaller types
-- Capacity: %s
-- Diversity: 3-5 types (c/m/r/t families)
-- Headroom: 20-30%% normal, 10-15%% if consolidating
-
-Return JSON only:
-{"reasoning":"brief why","instanceTypes":["m6i.2xlarge"],"minSize":0,"maxSize":10,"capacityType":"spot","explanation":"concise explanation"}`,
-		np.Name,
-		currentNodes,
-		currentTypes,
-		np.CapacityType,
-		np.Architecture,
-		np.MinSize,
-		np.MaxSize,
-		cpuUtilization,
-		npCPUUsed,
-		npCPUAllocatable,
-		memoryUtilization,
-		npMemoryUsed,
-		npMemoryAllocatable,
-		func() string {
-			if disruptionSummary != "" {
-				return "Disruptions: " + disruptionSummary + " "
-			}
-			return ""
-		}(),
-		capacityTypeContext,
-		func() string {
-			if actualSpotCount == len(actualNodes) && len(actualNodes) > 0 {
-				return "Keep spot (all nodes already spot)"
-			} else if actualOnDemandCount > 0 {
-				return fmt.Sprintf("Convert %d on-demand to spot", actualOnDemandCount)
-			}
-			return "Spot preferred for cost"
-		}(),
-	)
-
-	return prompt
+	return r.renderPrompt(prompts.ClusterSummaryAdvice, clusterSummaryAdvicePromptData{
+		NodePool:            np,
+		CurrentTypes:        currentTypes,
+		CurrentNodes:        currentNodes,
+		TotalNodes:          totalNodes,
+		SpotNodes:           spotNodes,
+		OnDemandNodes:       onDemandNodes,
+		TotalPods:           totalPods,
+		CPUUsed:             npCPUUsed,
+		CPUAllocatable:      npCPUAllocatable,
+		CPUUtilization:      cpuUtilization,
+		MemoryUsed:          npMemoryUsed,
+		MemoryAllocatable:   npMemoryAllocatable,
+		MemoryUtilization:   memoryUtilization,
+		Overprovisioned:     isOverprovisioned,
+		Disruptions:         disruptionInsights,
+		ActualNodes:         len(actualNodes),
+		ActualSpotNodes:     actualSpotCount,
+		ActualOnDemandNodes: actualOnDemandCount,
+	})
 }
 
 // GenerateClusterRecommendations analyzes all workloads in the cluster and recommends
@@ -919,6 +932,10 @@ func (r *Recommender) GenerateClusterRecommendations() ([]NodePoolRecommendation
 			CPURequest:    w.CPURequest,
 			MemoryRequest: w.MemoryRequest,
 			GPU:           w.GPU,
+			GPUModel:      w.GPUModel,
+			GPUMemoryMiB:  w.GPUMemoryMiB,
+			MIGProfiles:   w.MIGProfiles,
+			SharedGPU:     w.SharedGPU,
 			Labels:        w.Labels,
 			WorkloadType:  w.Type,
 		}
@@ -1244,12 +1261,21 @@ func (r *Recommender) optimizeNodePool(np kubernetes.NodePoolInfo, workloads []W
 
 		// Only count GPU if workload explicitly requests GPU resources (nvidia.com/gpu)
 		// GPU must be > 0 to be considered
-		if w.GPU > 0 {
+		if w.GPU > maxGPU {
 			maxGPU = w.GPU
 		}
 		workloadNames = append(workloadNames, fmt.Sprintf("%s/%s", w.Namespace, w.Name))
 	}
 
+	// MIG slices and time-sliced replicas also need GPU nodes
+	gpuReq := gpuRequirementFromWorkloads(workloads, gpuReplicasFromNodes(np.ActualNodes))
+	if gpuReq.Model == "" {
+		gpuReq.Model = normalizeGPUModel(np.Requirements[kubernetes.LabelKarpenterGPUName])
+	}
+	if maxGPU == 0 && gpuReq.TotalGPUs > 0 {
+		maxGPU = int(math.Ceil(gpuReq.TotalGPUs))
+	}
+
 	// Debug: log GPU detection
 	if maxGPU > 0 {
 		fmt.Printf("DEBUG: Detected GPU requirement: %d GPUs across workloads\n", maxGPU)
@@ -1347,30 +1373,28 @@ func (r *Recommender) optimizeNodePool(np kubernetes.NodePoolInfo, workloads []W
 			recommendedMaxSize = int(math.Ceil(float64(nodesNeeded) * 1.5)) // More conservative max
 		}
 
-		if r.ollamaClient != nil && len(workloads) > 0 {
+		var llmRejections []LLMRejection
+		var promptVersion string
+		if r.HasLLM() && len(workloads) > 0 {
 			ollamaCtx, ollamaCancel := context.WithTimeout(context.Background(), 90*time.Second) // Longer timeout for gemma3:1b
 			defer ollamaCancel()
-			enhancedReasoning, enhancedTypes, minSize, maxSize, ollamaCapacityType := r.enhanceWithOllama(ollamaCtx, np, workloads, totalCPU, totalMemory, 0, recommendedInstanceTypes, isOverprovisioned, disruptionInsights)
-			if enhancedReasoning != "" {
-				// Use concise version - extract key points only
-				reasoning = r.extractConciseReasoning(enhancedReasoning, reasoning)
-			}
-			if len(enhancedTypes) > 0 {
-				recommendedInstanceTypes = enhancedTypes
-				// Recalculate cost with new instance types
-				recommendedCost = r.estimateCost(context.Background(), recommendedInstanceTypes, recommendedCapacityType, nodesNeeded)
-			}
-			if ollamaCapacityType != "" {
-				recommendedCapacityType = ollamaCapacityType
-				// Recalculate cost with new capacity type
+			if advice := r.enhanceWithOllama(ollamaCtx, np, workloads, totalCPU, totalMemory, 0, recommendedInstanceTypes, isOverprovisioned, disruptionInsights); advice != nil {
+				// Use the concise summary - the full rationale is too long for this view
+				reasoning = advice.Summary
+				recommendedInstanceTypes = advice.InstanceTypes
+				llmRejections = advice.Rejections
+				promptVersion = advice.PromptVersion
+				recommendedCapacityType = advice.CapacityType
+				if advice.MinSize != nil {
+					recommendedMinSize = *advice.MinSize
+				}
+				if advice.MaxSize != nil {
+					recommendedMaxSize = *advice.MaxSize
+				}
+				// Recalculate cost with new instance and capacity types and the advised node count
+				nodesNeeded = advice.boundedNodeCount(nodesNeeded, recommendedMinSize, recommendedMaxSize)
 				recommendedCost = r.estimateCost(context.Background(), recommendedInstanceTypes, recommendedCapacityType, nodesNeeded)
 			}
-			if minSize != nil {
-				recommendedMinSize = *minSize
-			}
-			if maxSize != nil {
-				recommendedMaxSize = *maxSize
-			}
 		}
 
 		return NodePoolRecommendation{
@@ -1396,6 +1420,8 @@ func (r *Recommender) optimizeNodePool(np kubernetes.NodePoolInfo, workloads []W
 			Reasoning:        reasoning,
 			WorkloadsMatched: workloadNames,
 			CurrentState:     currentState,
+			LLMRejections:    llmRejections,
+			PromptVersion:    promptVersion,
 		}
 	} else {
 		// GPU workloads - handle separately
@@ -1403,16 +1429,30 @@ func (r *Recommender) optimizeNodePool(np kubernetes.NodePoolInfo, workloads []W
 		cpuPerNode := math.Max(totalCPU/4.0, 1.0) // Minimum 1
 		memoryPerNode := math.Max(totalMemory/8.0, 1.0) // Minimum 1
 
-		recommendedInstanceTypes := r.selectInstanceTypes(cpuPerNode, memoryPerNode, maxGPU)
 		recommendedCapacityType := r.selectCapacityType(workloads, false)
 		recommendedArchitecture := r.selectArchitecture(workloads)
-		nodesNeeded := int(math.Ceil(float64(maxGPU) / 4.0)) // Rough estimate: 4 GPUs per node
-		if nodesNeeded < 1 {
-			nodesNeeded = 1
+
+		// Pick GPU instances by model, GPU memory and price
+		var recommendedInstanceTypes []string
+		var nodesNeeded int
+		var recommendedCost float64
+		choices := r.selectGPUInstances(context.Background(), gpuReq, recommendedCapacityType)
+		if len(choices) > 0 {
+			recommendedInstanceTypes = []string{choices[0].InstanceType}
+			nodesNeeded = choices[0].Nodes
+			recommendedCost = choices[0].Cost
+		} else {
+			// No catalog match (unknown model) - fall back to generic GPU selection
+			recommendedInstanceTypes = r.selectInstanceTypes(cpuPerNode, memoryPerNode, maxGPU)
+			nodesNeeded = int(math.Ceil(float64(maxGPU) / 4.0)) // Rough estimate: 4 GPUs per node
+			if nodesNeeded < 1 {
+				nodesNeeded = 1
+			}
+			recommendedCost = r.estimateCost(context.Background(), recommendedInstanceTypes, recommendedCapacityType, nodesNeeded)
 		}
-		recommendedCost := r.estimateCost(context.Background(), recommendedInstanceTypes, recommendedCapacityType, nodesNeeded)
 
 		reasoning := r.generateReasoning(workloads, totalCPU, totalMemory, maxGPU, false, currentNodeCount, nodesNeeded)
+		reasoning = fmt.Sprintf("Needs %s. ", describeGPURequirement(gpuReq)) + reasoning
 
 		// Add disruption insights with educational context
 		if disruptionInsights.HasHighConsolidation {
@@ -1447,7 +1487,8 @@ func (r *Recommender) optimizeNodePool(np kubernetes.NodePoolInfo, workloads []W
 					Min: fmt.Sprintf("%.1fGi", totalMemory*0.8),
 					Max: fmt.Sprintf("%.1fGi", totalMemory*1.2),
 				},
-				GPU: maxGPU,
+				GPU:      maxGPU,
+				GPUModel: gpuReq.Model,
 			},
 			EstimatedCost:    recommendedCost,
 			Reasoning:        reasoning,
@@ -1457,89 +1498,34 @@ func (r *Recommender) optimizeNodePool(np kubernetes.NodePoolInfo, workloads []W
 	}
 }
 
-// enhanceWithOllama uses Ollama LLM to generate intelligent recommendations
-func (r *Recommender) enhanceWithOllama(ctx context.Context, np kubernetes.NodePoolInfo, workloads []Workload, totalCPU, totalMemory float64, maxGPU int, currentTypes []string, isOverprovisioned bool, disruptionInsights DisruptionInsights) (string, []string, *int, *int, string) {
-	// Build prompt for Ollama
-	prompt := r.buildOllamaPrompt(np, workloads, totalCPU, totalMemory, maxGPU, currentTypes, isOverprovisioned, disruptionInsights)
-
-	response, err := r.ollamaClient.Chat(ctx, prompt)
+// enhanceWithOllama asks the LLM for a structured NodePool recommendation. Instance types
+// outside the catalog or the NodePool's requirements, and GPU types unless workloads need
+// GPUs, are dropped and reported in advice.Rejections. It returns nil when the LLM fails or its
+// reply does not validate.
+func (r *Recommender) enhanceWithOllama(ctx context.Context, np kubernetes.NodePoolInfo, workloads []Workload, totalCPU, totalMemory float64, maxGPU int, currentTypes []string, isOverprovisioned bool, disruptionInsights DisruptionInsights) *NodePoolAdvice {
+	prompt, err := r.buildOllamaPrompt(np, workloads, totalCPU, totalMemory, maxGPU, currentTypes, isOverprovisioned, disruptionInsights)
 	if err != nil {
-		// If Ollama fails, return original values
-		fmt.Printf("Ollama request failed: %v\n", err)
-		return "", currentTypes, nil, nil, ""
-	}
-
-	// Parse Ollama response (expecting JSON)
-	var ollamaRec struct {
-		Reasoning     string   `json:"reasoning"`
-		InstanceTypes []string `json:"instanceTypes"`
-		Explanation   string   `json:"explanation"`
-		MinSize       *int     `json:"minSize,omitempty"`
-		MaxSize       *int     `json:"maxSize,omitempty"`
-		CapacityType  string   `json:"capacityType,omitempty"`
-	}
-
-	if err := json.Unmarshal([]byte(response), &ollamaRec); err != nil {
-		// If JSON parsing fails, try to extract JSON from markdown code blocks
-		jsonStart := strings.Index(response, "{")
-		jsonEnd := strings.LastIndex(response, "}")
-		if jsonStart >= 0 && jsonEnd > jsonStart {
-			jsonStr := response[jsonStart : jsonEnd+1]
-			if err := json.Unmarshal([]byte(jsonStr), &ollamaRec); err != nil {
-				// If still fails, use response as reasoning
-				return response, currentTypes, nil, nil, ""
-			}
-		} else {
-			// If no JSON found, use response as reasoning
-			return response, currentTypes, nil, nil, ""
-		}
-	}
-
-	// Filter out GPU instances if not needed - CRITICAL: Never recommend GPU for non-GPU workloads
-	instanceTypes := currentTypes
-	if len(ollamaRec.InstanceTypes) > 0 {
-		// ALWAYS remove GPU instances if maxGPU is 0 (no GPU workloads)
-		filteredTypes := []string{}
-		for _, it := range ollamaRec.InstanceTypes {
-			itLower := strings.ToLower(it)
-			// Only include GPU instances if workloads actually need GPU
-			if strings.HasPrefix(itLower, "g4") || strings.HasPrefix(itLower, "g5") {
-				if maxGPU > 0 {
-					filteredTypes = append(filteredTypes, it)
-				} else {
-					fmt.Printf("DEBUG: Filtering out GPU instance %s - no GPU workloads detected\n", it)
-				}
-			} else {
-				// Regular instances - always include
-				filteredTypes = append(filteredTypes, it)
-			}
-		}
-		if len(filteredTypes) > 0 {
-			instanceTypes = filteredTypes
-		} else if maxGPU == 0 {
-			// If all instances were GPU and we filtered them out, use original types
-			fmt.Printf("DEBUG: Ollama recommended only GPU instances but no GPU workloads - keeping original types\n")
-			instanceTypes = currentTypes
-		}
+		fmt.Printf("Warning: %v\n", err)
+		return nil
 	}
 
-	reasoning := ollamaRec.Explanation
-	if ollamaRec.Reasoning != "" {
-		if reasoning != "" {
-			reasoning += ". " + ollamaRec.Reasoning
-		} else {
-			reasoning = ollamaRec.Reasoning
-		}
+	advice, err := r.requestNodePoolAdvice(ctx, prompt.Text)
+	if err != nil {
+		// If Ollama fails, the caller keeps its own recommendation
+		fmt.Printf("Ollama request failed: %v\n", err)
+		return nil
 	}
+	advice.PromptVersion = prompt.Version
 
-	// Return full Ollama reasoning - don't truncate AI-generated content
+	// Drop instance types outside the catalog or the NodePool's requirements - CRITICAL: Never recommend GPU for non-GPU workloads
+	r.validateNodePoolAdvice(np, advice, currentTypes, maxGPU > 0)
 
-	return reasoning, instanceTypes, ollamaRec.MinSize, ollamaRec.MaxSize, ollamaRec.CapacityType
+	return advice
 }
 
-// buildOllamaPrompt creates a prompt for Ollama to generate recommendations
-func (r *Recommender) buildOllamaPrompt(np kubernetes.NodePoolInfo, workloads []Workload, totalCPU, totalMemory float64, maxGPU int, currentTypes []string, isOverprovisioned bool, disruptionInsights DisruptionInsights) string {
-	workloadSummary := make([]string, 0, len(workloads))
+// buildOllamaPrompt renders the nodepool-advice prompt template
+func (r *Recommender) buildOllamaPrompt(np kubernetes.NodePoolInfo, workloads []Workload, totalCPU, totalMemory float64, maxGPU int, currentTypes []string, isOverprovisioned bool, disruptionInsights DisruptionInsights) (prompts.Prompt, error) {
+	promptWorkloads := make([]promptWorkload, 0, len(workloads))
 	for _, w := range workloads {
 		// Use resource requests
 		cpuStr := w.CPU
@@ -1551,93 +1537,25 @@ func (r *Recommender) buildOllamaPrompt(np kubernetes.NodePoolInfo, workloads []
 			memStr = w.MemoryRequest
 		}
 
-		workloadSummary = append(workloadSummary, fmt.Sprintf("- %s/%s (%s): CPU=%s, Memory=%s",
-			w.Namespace, w.Name, w.WorkloadType, cpuStr, memStr))
-	}
-
-	// Build disruption context for prompt
-	disruptionContext := ""
-	if disruptionInsights.TotalDisruptions > 0 {
-		disruptionContext = fmt.Sprintf(`
-Disruption Analysis (last 7 days):
-- Total Disruptions: %d
-- Consolidations: %d (%.1f per day)
-- Expirations/Drift: %d
-- Terminations: %d
-- High Consolidation Rate: %v (indicates over-provisioning)
-- Expiration Issues: %v (indicates configuration problems)
-
-IMPORTANT: High consolidation rate (>2/day) strongly indicates over-provisioning. Recommend reducing node count and using smaller instance types.`,
-			disruptionInsights.TotalDisruptions,
-			disruptionInsights.ConsolidationCount,
-			disruptionInsights.ConsolidationRate,
-			disruptionInsights.ExpirationCount,
-			disruptionInsights.TerminationCount,
-			disruptionInsights.HasHighConsolidation,
-			disruptionInsights.HasExpirationIssues)
-	}
-
-	prompt := fmt.Sprintf(`You are a Kubernetes infrastructure expert specializing in Karpenter NodePool optimization.
-
-Current NodePool Configuration:
-- Name: %s
-- Current Instance Types: %v
-- Capacity Type: %s
-- Architecture: %s
-- Current Nodes: %d
-- Min Size: %d
-- Max Size: %d
-
-Workload Requirements (based on resource requests):
-- Total CPU: %.2f cores
-- Total Memory: %.2f GiB
-- Max GPU: %d
-- Number of workloads: %d
-
-Workload Details:
-%s%s
-
-Please provide recommendations in JSON format:
-{
-  "reasoning": "Brief explanation of why these instance types and configuration are optimal",
-	"instanceTypes": ["m6i.2xlarge", "m6i.4xlarge", "m6i.8xlarge"],
-  "minSize": 0,
-  "maxSize": 10,
-  "capacityType": "spot" or "on-demand",
-  "explanation": "Detailed explanation considering workload patterns, cost optimization, flexibility needs, and recommended NodePool configuration"
-}
-
-CRITICAL RULES:
-1. DO NOT recommend GPU instances (g4dn, g5) unless workloads explicitly require GPU resources
-2. If cluster is overprovisioned OR has high consolidation rate, recommend REDUCING node count and using smaller/cheaper instance types
-3. High consolidation rate (>2/day) = STRONG indicator of over-provisioning - recommend 20-30%% node reduction
-4. Frequent expirations indicate configuration issues - review instance types and capacity settings
-5. Prefer spot instances for cost savings unless workloads require on-demand (check workload labels)
-6. Instance type diversity for better bin-packing and availability (3-5 types recommended)
-8. AWS instance families: c (compute), m (balanced), r (memory), t (burst) - NO GPU unless needed
-9. Node capacity vs workload requirements with appropriate headroom:
-   - Normal: 20-30%% overhead
-   - High consolidation: 10-15%% overhead (reduce waste)
-10. Recommended minSize and maxSize based on workload patterns, desired availability, AND disruption patterns
-11. ALWAYS include "capacityType" field: "spot" for cost optimization or "on-demand" if workloads require it
-
-Return only valid JSON, no markdown formatting.`,
-		np.Name,
-		currentTypes,
-		np.CapacityType,
-		np.Architecture,
-		np.CurrentNodes,
-		np.MinSize,
-		np.MaxSize,
-		totalCPU,
-		totalMemory,
-		maxGPU,
-		len(workloads),
-		strings.Join(workloadSummary, "\n"),
-		disruptionContext,
-	)
+		promptWorkloads = append(promptWorkloads, promptWorkload{
+			Namespace: w.Namespace,
+			Name:      w.Name,
+			Type:      w.WorkloadType,
+			CPU:       cpuStr,
+			Memory:    memStr,
+		})
+	}
 
-	return prompt
+	return r.renderPrompt(prompts.NodePoolAdvice, nodePoolAdvicePromptData{
+		NodePool:        np,
+		CurrentTypes:    currentTypes,
+		TotalCPU:        totalCPU,
+		TotalMemory:     totalMemory,
+		MaxGPU:          maxGPU,
+		Overprovisioned: isOverprovisioned,
+		Workloads:       promptWorkloads,
+		Disruptions:     disruptionInsights,
+	})
 }
 
 // Legacy methods for backward compatibility
@@ -1659,11 +1577,12 @@ func (r *Recommender) groupWorkloads(workloads []Workload) [][]Workload {
 	var nonGpuGroups []Workload
 
 	for _, w := range workloads {
-		if w.GPU > 0 {
-			// Find matching GPU group or create new one
+		if w.needsGPU() {
+			// Find matching GPU group (same GPU count and model) or create new one
 			found := false
 			for i := range gpuGroups {
-				if len(gpuGroups[i]) > 0 && gpuGroups[i][0].GPU == w.GPU {
+				if len(gpuGroups[i]) > 0 && gpuGroups[i][0].GPU == w.GPU &&
+					normalizeGPUModel(gpuGroups[i][0].GPUModel) == normalizeGPUModel(w.GPUModel) {
 					gpuGroups[i] = append(gpuGroups[i], w)
 					found = true
 					break
@@ -1764,6 +1683,18 @@ func (r *Recommender) generateRecommendationForGroup(group []Workload, index int
 	capacityType := r.selectCapacityType(group, false) // Not checking overprovisioning in this path
 	architecture := r.selectArchitecture(group)
 
+	// GPU groups are sized by GPUs, not CPU/memory
+	gpuReq := gpuRequirementFromWorkloads(group, 1)
+	if gpuReq.TotalGPUs > 0 {
+		if choices := r.selectGPUInstances(context.Background(), gpuReq, capacityType); len(choices) > 0 {
+			instanceTypes = gpuInstanceTypeNames(choices, 3)
+			nodesNeeded = choices[0].Nodes
+		}
+		if maxGPU == 0 {
+			maxGPU = int(math.Ceil(gpuReq.TotalGPUs))
+		}
+	}
+
 		recommendedCost := r.estimateCost(context.Background(), instanceTypes, capacityType, nodesNeeded)
 
 	// Try to get actual current state from existing NodePools
@@ -1809,7 +1740,8 @@ func (r *Recommender) generateRecommendationForGroup(group []Workload, index int
 				Min: r.formatMemory(memoryPerNode * 0.8),
 				Max: r.formatMemory(memoryPerNode * 1.2),
 			},
-			GPU: maxGPU,
+			GPU:      maxGPU,
+			GPUModel: gpuReq.Model,
 		},
 		EstimatedCost:    recommendedCost,
 		Reasoning:        r.generateReasoning(group, cpuPerNode, memoryPerNode, maxGPU, false, 0, nodesNeeded),
@@ -1825,7 +1757,11 @@ func (r *Recommender) selectInstanceTypes(cpu, memory float64, gpu int) []string
 	// This prevents recommending GPU instances for non-GPU workloads
 	if gpu > 0 {
 		fmt.Printf("DEBUG: Selecting GPU instances for %d GPU requirement\n", gpu)
-		// GPU instances
+		// GPU instances - cheapest catalog entries that fit the GPU count on one node
+		choices := r.selectGPUInstances(context.Background(), GPURequirement{PerPodGPUs: gpu, TotalGPUs: float64(gpu)}, "on-demand")
+		if len(choices) > 0 {
+			return gpuInstanceTypeNames(choices, 4)
+		}
 		return []string{"g4dn.xlarge", "g4dn.2xlarge", "g5.xlarge", "g5.2xlarge"}
 	}
 
@@ -1896,10 +1832,20 @@ func (r *Recommender) calculateCurrentCapacity(np kubernetes.NodePoolInfo, nodeC
 	return avgCPU * float64(nodeCount), avgMemory * float64(nodeCount)
 }
 
+// EstimateInstanceCapacity returns the estimated vCPUs and memory (GiB) of an instance type
+func (r *Recommender) EstimateInstanceCapacity(instanceType string) (float64, float64) {
+	return r.estimateInstanceCapacity(instanceType)
+}
+
 // estimateInstanceCapacity estimates CPU and memory for an instance type
 func (r *Recommender) estimateInstanceCapacity(instanceType string) (float64, float64) {
 	it := strings.ToLower(instanceType)
 
+	// GPU instances don't follow the xlarge scaling rule (e.g., p4d.24xlarge)
+	if spec, ok := lookupGPUInstance(it); ok {
+		return spec.VCPU, spec.MemoryGiB
+	}
+
 	// Extract size multiplier
 	multiplier := 1.0
 	if strings.Contains(it, ".2xlarge") {
@@ -1940,7 +1886,9 @@ func (r *Recommender) estimateInstanceCapacity(instanceType string) (float64, fl
 		baseCPU, baseMemory = 4, 8 // c6g/c7g/c6gn.xlarge: 4 vCPU, 8 GiB (Graviton)
 	} else if strings.HasPrefix(it, "r6g") || strings.HasPrefix(it, "r7g") {
 		baseCPU, baseMemory = 4, 32 // r6g/r7g.xlarge: 4 vCPU, 32 GiB (Graviton)
-	} else if strings.HasPrefix(it, "g4") || strings.HasPrefix(it, "g5") {
+	} else if strings.HasPrefix(it, "g6e") {
+		baseCPU, baseMemory = 4, 32 // g6e.xlarge: 4 vCPU, 32 GiB (L40S)
+	} else if strings.HasPrefix(it, "g4") || strings.HasPrefix(it, "g5") || strings.HasPrefix(it, "g6") {
 		baseCPU, baseMemory = 4, 16 // GPU instances
 	} else {
 		baseCPU, baseMemory = 4, 8 // Default
@@ -2098,10 +2046,25 @@ func (r *Recommender) estimateCostWithSource(ctx context.Context, instanceTypes
 		"c6g.4xlarge": 0.544, // 16 vCPU, 32 GiB
 		"c6g.8xlarge": 1.088, // 32 vCPU, 64 GiB
 		// GPU instances
-		"g4dn.xlarge":  0.526,
-		"g4dn.2xlarge": 0.752,
-		"g5.xlarge":    1.006,
-		"g5.2xlarge":   1.212,
+		"g4dn.xlarge":   0.526,
+		"g4dn.2xlarge":  0.752,
+		"g4dn.4xlarge":  1.204,
+		"g4dn.12xlarge": 3.912,
+		"g5.xlarge":     1.006,
+		"g5.2xlarge":    1.212,
+		"g5.4xlarge":    1.624,
+		"g5.12xlarge":   5.672,
+		"g5.48xlarge":   16.288,
+		"g6.xlarge":     0.8048,
+		"g6.2xlarge":    0.9776,
+		"g6.4xlarge":    1.3232,
+		"g6.12xlarge":   4.6016,
+		"g6e.xlarge":    1.861,
+		"g6e.12xlarge":  10.493,
+		"p3.2xlarge":    3.06,
+		"p3.8xlarge":    12.24,
+		"p4d.24xlarge":  32.7726,
+		"p5.48xlarge":   98.32,
 	}
 
 	// Calculate cost like eks-node-viewer: distribute nodes across instance types and sum costs
@@ -2177,17 +2140,15 @@ func (r *Recommender) estimateCostWithSource(ctx context.Context, instanceTypes
 			}
 		}
 
-		// If still not found, try family estimation
-		if !priceFound {
+		// If still not found, try family estimation for known families
+		if _, known := familyBaseCost(itLower); !priceFound && known {
 			instanceCost = r.estimateCostFromFamily(it)
-			if instanceCost > 0 {
-				priceFound = true
-				source = PricingSourceFamilyEstimate
-			}
+			priceFound = true
+			source = PricingSourceFamilyEstimate
 		}
 
-		// Last resort: try Ollama if available
-		if !priceFound && r.ollamaClient != nil {
+		// Then try Ollama if available - only prices within the plausible band are used
+		if !priceFound && r.HasLLM() {
 			// Use background context for pricing queries (they're quick and cached)
 			ollamaPrice := r.getPricingFromOllama(context.Background(), it)
 			if ollamaPrice > 0 {
@@ -2201,6 +2162,13 @@ func (r *Recommender) estimateCostWithSource(ctx context.Context, instanceTypes
 			}
 		}
 
+		// Last resort: default family estimate
+		if !priceFound {
+			instanceCost = r.estimateCostFromFamily(it)
+			priceFound = true
+			source = PricingSourceFamilyEstimate
+		}
+
 		// Skip this instance type if we couldn't find a price
 		if !priceFound {
 			continue
@@ -2266,102 +2234,106 @@ func (r *Recommender) estimateCostFromFamily(instanceType string) float64 {
 		multiplier = 0.125
 	}
 
+	baseCost, ok := familyBaseCost(it)
+	if !ok {
+		return 0.2 // Default fallback
+	}
+
+	return baseCost * multiplier
+}
+
+// familyBaseCost returns the on-demand xlarge price of a known instance family
+func familyBaseCost(instanceType string) (float64, bool) {
+	it := strings.ToLower(instanceType)
+
 	// Base on-demand costs per family (per xlarge equivalent) - US East (N. Virginia)
 	// These are the xlarge prices, which will be multiplied by the size multiplier
-	var baseCost float64
 	if strings.HasPrefix(it, "t3") || strings.HasPrefix(it, "t4g") {
-		baseCost = 0.1664 // t3.xlarge on-demand
+		return 0.1664, true // t3.xlarge on-demand
 	} else if strings.HasPrefix(it, "m6i") || strings.HasPrefix(it, "m5") {
-		baseCost = 0.192 // m6i.xlarge on-demand (note: m6i.xlarge exists, but m6i.large/medium don't)
+		return 0.192, true // m6i.xlarge on-demand (note: m6i.xlarge exists, but m6i.large/medium don't)
 	} else if strings.HasPrefix(it, "m6a") {
-		baseCost = 0.1728 // m6a.xlarge on-demand
+		return 0.1728, true // m6a.xlarge on-demand
 	} else if strings.HasPrefix(it, "c6i") || strings.HasPrefix(it, "c5") {
-		baseCost = 0.17 // c6i.xlarge on-demand
+		return 0.17, true // c6i.xlarge on-demand
 	} else if strings.HasPrefix(it, "c6a") {
-		baseCost = 0.153 // c6a.xlarge on-demand
+		return 0.153, true // c6a.xlarge on-demand
 	} else if strings.HasPrefix(it, "r6i") || strings.HasPrefix(it, "r5") {
-		baseCost = 0.252 // r6i.xlarge on-demand
+		return 0.252, true // r6i.xlarge on-demand
 	} else if strings.HasPrefix(it, "r6a") {
-		baseCost = 0.2268 // r6a.xlarge on-demand
+		return 0.2268, true // r6a.xlarge on-demand
 	} else if strings.HasPrefix(it, "r8i") {
-		baseCost = 0.252 // r8i.xlarge on-demand
+		return 0.252, true // r8i.xlarge on-demand
 	} else if strings.HasPrefix(it, "x2gd") {
-		baseCost = 0.0669 // x2gd.xlarge on-demand (Graviton2)
+		return 0.0669, true // x2gd.xlarge on-demand (Graviton2)
 	} else if strings.HasPrefix(it, "x8g") {
-		baseCost = 0.0672 // x8g.xlarge on-demand (Graviton3)
+		return 0.0672, true // x8g.xlarge on-demand (Graviton3)
 	} else if strings.HasPrefix(it, "m6g") {
-		baseCost = 0.1536 // m6g.xlarge on-demand (Graviton2)
+		return 0.1536, true // m6g.xlarge on-demand (Graviton2)
 	} else if strings.HasPrefix(it, "c6g") {
-		baseCost = 0.136 // c6g.xlarge on-demand (Graviton2)
+		return 0.136, true // c6g.xlarge on-demand (Graviton2)
 	} else if strings.HasPrefix(it, "g4") {
-		baseCost = 0.526 // g4dn.xlarge on-demand
+		return 0.526, true // g4dn.xlarge on-demand
 	} else if strings.HasPrefix(it, "g5") {
-		baseCost = 1.006 // g5.xlarge on-demand
-	} else {
-		return 0.2 // Default fallback
-	}
-
-	return baseCost * multiplier
+		return 1.006, true // g5.xlarge on-demand
+	} else if strings.HasPrefix(it, "g6e") {
+		return 1.861, true // g6e.xlarge on-demand
+	} else if strings.HasPrefix(it, "g6") {
+		return 0.8048, true // g6.xlarge on-demand
+	} else if strings.HasPrefix(it, "p3") {
+		return 1.53, true // p3.2xlarge on-demand / 2
+	}
+	return 0, false
 }
 
-// getPricingFromOllama queries Ollama for AWS EC2 instance pricing
+// getPricingFromOllama queries Ollama for AWS EC2 instance pricing. The price is only
+// returned when it is within the plausible band around the family estimate; rejected
+// prices are recorded and the instance type is not asked about again.
 func (r *Recommender) getPricingFromOllama(ctx context.Context, instanceType string) float64 {
-	if r.ollamaClient == nil {
+	if !r.HasLLM() {
+		return 0.0
+	}
+
+	itLower := strings.ToLower(instanceType)
+	r.priceCacheMu.RLock()
+	rejected := r.rejectedPrices[itLower]
+	r.priceCacheMu.RUnlock()
+	if rejected {
+		return 0.0
+	}
+	// Without a family estimate the reply could not be checked
+	if _, ok := referencePrice(itLower); !ok {
 		return 0.0
 	}
 
 	// Create a prompt for Ollama to get AWS EC2 pricing
 	prompt := fmt.Sprintf(`You are an AWS pricing expert. Provide the on-demand hourly cost in USD for AWS EC2 instance type "%s" in the us-east-1 (N. Virginia) region.
 
-Respond ONLY with a JSON object in this exact format:
-{
-  "instanceType": "%s",
-  "pricePerHour": 0.123,
-  "region": "us-east-1"
-}
-
-If you don't know the exact price, estimate it based on similar instance types in the same family. The price should be a positive number representing USD per hour.`, instanceType, instanceType)
+If you don't know the exact price, estimate it based on similar instance types in the same family. The price should be a positive number representing USD per hour.`, instanceType)
 
 	// Use a shorter timeout for pricing queries (10 seconds)
 	pricingCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	response, err := r.ollamaClient.Chat(pricingCtx, prompt)
-	if err != nil {
+	var pricingResp llmPrice
+	if err := r.ollamaClient.ChatJSON(pricingCtx, prompt, llmPriceSchema, &pricingResp); err != nil {
 		fmt.Printf("Warning: Failed to get pricing from Ollama for %s: %v\n", instanceType, err)
 		return 0.0
 	}
 
-	// Parse the response
-	var pricingResp struct {
-		InstanceType string  `json:"instanceType"`
-		PricePerHour float64 `json:"pricePerHour"`
-		Region       string  `json:"region"`
-	}
-
-	// Try to extract JSON from response
-	jsonStart := strings.Index(response, "{")
-	jsonEnd := strings.LastIndex(response, "}")
-	if jsonStart >= 0 && jsonEnd > jsonStart {
-		jsonStr := response[jsonStart : jsonEnd+1]
-		if err := json.Unmarshal([]byte(jsonStr), &pricingResp); err != nil {
-			fmt.Printf("Warning: Failed to parse Ollama pricing response for %s: %v\n", instanceType, err)
-			return 0.0
+	if rejection := validateLLMPrice(itLower, pricingResp); rejection != nil {
+		r.priceCacheMu.Lock()
+		if r.rejectedPrices == nil {
+			r.rejectedPrices = make(map[string]bool)
 		}
-	} else {
-		// Try parsing the whole response
-		if err := json.Unmarshal([]byte(response), &pricingResp); err != nil {
-			fmt.Printf("Warning: Failed to parse Ollama pricing response for %s: %v\n", instanceType, err)
-			return 0.0
-		}
-	}
-
-	if pricingResp.PricePerHour > 0 {
-		fmt.Printf("Info: Got pricing from Ollama for %s: $%.4f/hr\n", instanceType, pricingResp.PricePerHour)
-		return pricingResp.PricePerHour
+		r.rejectedPrices[itLower] = true
+		r.priceCacheMu.Unlock()
+		r.RecordLLMRejections(*rejection)
+		return 0.0
 	}
 
-	return 0.0
+	fmt.Printf("Info: Got pricing from Ollama for %s: $%.4f/hr\n", instanceType, pricingResp.PricePerHour)
+	return pricingResp.PricePerHour
 }
 
 // limitToWords limits a string to approximately n words
@@ -2393,44 +2365,6 @@ func (r *Recommender) generateReasoning(workloads []Workload, cpu, memory float6
 	return fmt.Sprintf("Recommended %d nodes based on workload analysis and resource utilization patterns.", recommendedNodes)
 }
 
-// extractConciseReasoning extracts key educational points from longer reasoning text
-func (r *Recommender) extractConciseReasoning(longReasoning, fallback string) string {
-	// If reasoning is already reasonable length, use it
-	if len(longReasoning) <= 150 {
-		return longReasoning
-	}
-
-	// Try to extract first sentence which usually contains the main insight
-	sentences := strings.Split(longReasoning, ".")
-	if len(sentences) > 0 {
-		firstSentence := strings.TrimSpace(sentences[0])
-		if len(firstSentence) <= 150 {
-			return firstSentence
-		}
-		// If first sentence is too long, try first two sentences
-		if len(sentences) > 1 {
-			twoSentences := strings.TrimSpace(sentences[0] + ". " + sentences[1])
-			if len(twoSentences) <= 200 {
-				return twoSentences
-			}
-		}
-	}
-
-	// Look for key educational patterns (explanations, insights)
-	if strings.Contains(longReasoning, "indicates") || strings.Contains(longReasoning, "suggests") {
-		// Extract the insight part
-		parts := strings.Split(longReasoning, ".")
-		for _, part := range parts {
-			if (strings.Contains(part, "indicates") || strings.Contains(part, "suggests")) && len(part) <= 150 {
-				return strings.TrimSpace(part) + "."
-			}
-		}
-	}
-
-	// Fallback to original educational reasoning
-	return fallback
-}
-
 func (r *Recommender) parseCPU(cpuStr string) float64 {
 	cpuStr = strings.TrimSpace(cpuStr)
 	cpuStr = strings.ToLower(cpuStr)
@@ -3,9 +3,12 @@ package recommender
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/karpenter-optimizer/internal/config"
+	"github.com/karpenter-optimizer/internal/kubernetes"
 )
 
 func TestNewRecommender(t *testing.T) {
@@ -108,3 +111,197 @@ func TestEstimateInstanceCapacity(t *testing.T) {
 	}
 }
 
+
+func TestNormalizeGPUModel(t *testing.T) {
+	tests := map[string]string{
+		"Tesla-T4":               "t4",
+		"NVIDIA-A10G":            "a10g",
+		"nvidia-l4":              "l4",
+		"NVIDIA-L40S":            "l40s",
+		"NVIDIA-A100-SXM4-40GB":  "a100",
+		"NVIDIA-H100-80GB-HBM3":  "h100",
+		"nvidia-tesla-v100":      "v100",
+		"":                       "",
+	}
+
+	for input, expected := range tests {
+		t.Run(input, func(t *testing.T) {
+			assert.Equal(t, expected, normalizeGPUModel(input))
+		})
+	}
+}
+
+func TestSelectGPUInstances(t *testing.T) {
+	cfg := &config.Config{}
+	rec := NewRecommender(cfg)
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		workloads    []Workload
+		replicas     int
+		expectFirst  string
+		expectNodes  int
+		expectFamily string
+	}{
+		{
+			name:        "single GPU any model picks cheapest",
+			workloads:   []Workload{{Name: "infer", GPU: 1}},
+			replicas:    1,
+			expectFirst: "g4dn.xlarge",
+			expectNodes: 1,
+		},
+		{
+			name:         "pinned model",
+			workloads:    []Workload{{Name: "infer", GPU: 1, GPUModel: "NVIDIA-A10G"}},
+			replicas:     1,
+			expectFamily: "g5.",
+			expectNodes:  1,
+		},
+		{
+			name:         "GPU memory requirement excludes T4",
+			workloads:    []Workload{{Name: "llm", GPU: 1, GPUMemoryMiB: 20480}},
+			replicas:     1,
+			expectFamily: "g6.",
+			expectNodes:  1,
+		},
+		{
+			name:         "MIG profiles need A100",
+			workloads:    []Workload{{Name: "notebook", MIGProfiles: map[string]int{"1g.5gb": 14}}},
+			replicas:     1,
+			expectFamily: "p4d.",
+			expectNodes:  1,
+		},
+		{
+			name:        "time-sliced replicas share GPUs",
+			workloads:   []Workload{{Name: "dev", SharedGPU: 8}},
+			replicas:    4,
+			expectFirst: "g4dn.xlarge",
+			expectNodes: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := gpuRequirementFromWorkloads(tt.workloads, tt.replicas)
+			choices := rec.selectGPUInstances(ctx, req, "on-demand")
+			require.NotEmpty(t, choices)
+
+			if tt.expectFirst != "" {
+				assert.Equal(t, tt.expectFirst, choices[0].InstanceType)
+			}
+			if tt.expectFamily != "" {
+				assert.Contains(t, choices[0].InstanceType, tt.expectFamily)
+			}
+			assert.Equal(t, tt.expectNodes, choices[0].Nodes)
+			for i := 1; i < len(choices); i++ {
+				assert.LessOrEqual(t, choices[i-1].Cost, choices[i].Cost, "choices should be sorted by cost")
+			}
+		})
+	}
+}
+
+func TestDetectIdleGPUs(t *testing.T) {
+	cfg := &config.Config{}
+	rec := NewRecommender(cfg)
+
+	nodes := []kubernetes.NodeInfo{
+		{
+			Name:         "gpu-busy",
+			InstanceType: "g5.xlarge",
+			GPU:          &kubernetes.GPUUsage{Physical: 1, Allocatable: 1, Allocated: 1},
+		},
+		{
+			Name:         "gpu-crashloop",
+			InstanceType: "g5.12xlarge",
+			GPU:          &kubernetes.GPUUsage{Physical: 4, Allocatable: 4, Allocated: 2, IdleAllocated: 2, IdlePods: []string{"ml/train-0", "ml/train-1"}},
+		},
+		{
+			Name:         "cpu-only",
+			InstanceType: "m6i.xlarge",
+		},
+	}
+
+	findings := rec.DetectIdleGPUs(context.Background(), nodes)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "gpu-crashloop", findings[0].NodeName)
+	assert.Equal(t, 2, findings[0].IdleAllocated)
+	assert.Equal(t, 2, findings[0].Unallocated)
+	assert.InDelta(t, findings[0].HourlyCost, findings[0].WastedCost, 0.0001, "all four GPUs are idle")
+}
+
+func TestAnalyzeDisruptionChurn(t *testing.T) {
+	rec := NewRecommender(&config.Config{})
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	disruption := func(nodePool, reason string, removedAgo, lifetime time.Duration, pods int) kubernetes.NodeDisruptionInfo {
+		removed := now.Add(-removedAgo)
+		return kubernetes.NodeDisruptionInfo{
+			NodeName:     "node",
+			NodePool:     nodePool,
+			InstanceType: "m5.large",
+			CapacityType: "on-demand",
+			Reason:       reason,
+			CreationTime: removed.Add(-lifetime).Format(time.RFC3339),
+			LastSeen:     removed.Format(time.RFC3339),
+			DeletedTime:  removed.Format(time.RFC3339),
+			AffectedPods: make([]kubernetes.PodInfo, pods),
+		}
+	}
+
+	var disruptions []kubernetes.NodeDisruptionInfo
+	for i := 0; i < 8; i++ {
+		// Two days of nodes consolidated 20-40 minutes after launch
+		disruptions = append(disruptions, disruption("general", "Consolidation", time.Duration(i)*6*time.Hour, time.Duration(20+i*2)*time.Minute, 3))
+	}
+	disruptions = append(disruptions,
+		disruption("general", "Drift", 30*time.Hour, 5*24*time.Hour, 10),
+		disruption("batch", "Expiration", 2*time.Hour, 24*time.Hour, 2),
+		disruption("batch", "Expiration", 20*time.Hour, 24*time.Hour, 2),
+		disruption("batch", "SpotInterruption", 10*time.Hour, 3*time.Hour, 1),
+		disruption("batch", "Expiration", 10*24*time.Hour, 24*time.Hour, 2), // Outside the window
+		disruption("", "Consolidation", time.Hour, time.Hour, 1),            // Not a Karpenter node
+	)
+	nodePools := []kubernetes.NodePoolInfo{
+		{Name: "general", ConsolidateAfter: "0s", ExpireAfter: "720h", CurrentNodes: 5},
+		{Name: "batch", ConsolidateAfter: "1m", ExpireAfter: "24h", CurrentNodes: 2},
+	}
+
+	reports := rec.AnalyzeDisruptionChurn(context.Background(), disruptions, nodePools, now.AddDate(0, 0, -2), now, ChurnOptions{})
+	require.Len(t, reports, 2)
+
+	general := reports[0]
+	assert.Equal(t, "general", general.NodePool, "the pool wasting the most is reported first")
+	assert.Equal(t, 9, general.Disruptions)
+	assert.Equal(t, 8, general.ByReason["Consolidation"])
+	assert.Equal(t, 8, general.ShortLivedNodes)
+	assert.Equal(t, 34, general.PodsEvicted)
+	assert.InDelta(t, 4.5, general.DisruptionsPerDay, 0.01)
+	assert.Equal(t, 8, general.Lifetime.Buckets[1].Count, "15m-1h")
+	assert.Equal(t, 1, general.Lifetime.Buckets[4].Count, "1d-7d")
+	hourly := rec.EstimateCost(context.Background(), []string{"m5.large"}, "on-demand", 1)
+	assert.InDelta(t, 8*hourly*defaultNodeBootTime.Hours(), general.WastedCost, 0.0001)
+	assert.InDelta(t, general.WastedCost/2*30, general.WastedCostPerMonth, 0.0001)
+	assert.Equal(t, "30m", general.Recommendation.ConsolidateAfter)
+	assert.Empty(t, general.Recommendation.ExpireAfter)
+
+	days := 0
+	for _, day := range general.Daily {
+		days++
+		assert.Equal(t, day.Consolidation*3+day.Drift*10, day.PodsEvicted)
+	}
+	assert.Equal(t, 3, days)
+
+	batch := reports[1]
+	assert.Equal(t, 3, batch.Disruptions)
+	assert.Equal(t, 2, batch.ByReason["Expiration"])
+	assert.Equal(t, 1, batch.ByReason["SpotInterruption"])
+	assert.Zero(t, batch.WastedCost)
+	assert.Empty(t, batch.Recommendation.ConsolidateAfter)
+	assert.Equal(t, "168h", batch.Recommendation.ExpireAfter)
+}
+
+func TestWastedChurnTime(t *testing.T) {
+	assert.Equal(t, 3*time.Minute, wastedChurnTime(20*time.Minute, 3*time.Minute))
+	assert.Equal(t, 2*time.Minute, wastedChurnTime(2*time.Minute, 3*time.Minute), "only the node's life is billed past the minimum")
+	assert.Equal(t, time.Minute, wastedChurnTime(20*time.Second, 3*time.Minute), "the billed minimum applies to very short lives")
+}
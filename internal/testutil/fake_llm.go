@@ -0,0 +1,128 @@
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/karpenter-optimizer/internal/ollama"
+)
+
+// FakeLLMServer is a scripted chat completion server for deterministic LLM tests.
+// It answers OpenAI-compatible (/v1/chat/completions) and Ollama (/api/chat)
+// requests with the scripted replies in order and records every request.
+type FakeLLMServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	script   []ollama.Message
+	requests []FakeLLMRequest
+}
+
+// FakeLLMRequest is a chat request received by a FakeLLMServer
+type FakeLLMRequest struct {
+	Path     string           `json:"-"`
+	Model    string           `json:"model"`
+	Messages []ollama.Message `json:"messages"`
+	Tools    []ollama.Tool    `json:"tools"`
+
+	ResponseFormat json.RawMessage `json:"response_format"` // OpenAI-compatible structured output
+	Format         json.RawMessage `json:"format"`          // Ollama structured output
+}
+
+// NewFakeLLMServer starts a server replying with script, one message per request.
+// Requests after the script is used up fail with status 500.
+func NewFakeLLMServer(script ...ollama.Message) *FakeLLMServer {
+	f := &FakeLLMServer{script: script}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+// ToolCallReply is an assistant message calling one function with args
+func ToolCallReply(id, name string, args interface{}) ollama.Message {
+	data, err := json.Marshal(args)
+	if err != nil {
+		panic(err)
+	}
+	return ollama.Message{Role: "assistant", ToolCalls: []ollama.ToolCall{{
+		ID:       id,
+		Type:     "function",
+		Function: ollama.ToolCallFunction{Name: name, Arguments: string(data)},
+	}}}
+}
+
+// Requests returns the requests received so far
+func (f *FakeLLMServer) Requests() []FakeLLMRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeLLMRequest(nil), f.requests...)
+}
+
+func (f *FakeLLMServer) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := FakeLLMRequest{Path: r.URL.Path}
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	if len(f.script) == 0 {
+		f.mu.Unlock()
+		http.Error(w, "fake LLM script exhausted", http.StatusInternalServerError)
+		return
+	}
+	reply := f.script[0]
+	f.script = f.script[1:]
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/v1/chat/completions":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      fmt.Sprintf("chatcmpl-%d", len(f.Requests())),
+			"object":  "chat.completion",
+			"model":   req.Model,
+			"choices": []map[string]interface{}{{"index": 0, "message": reply, "finish_reason": finishReason(reply)}},
+		})
+	case "/api/chat":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"model":   req.Model,
+			"message": ollamaReply(reply),
+			"done":    true,
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func finishReason(reply ollama.Message) string {
+	if len(reply.ToolCalls) > 0 {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+// ollamaReply encodes tool call arguments as objects, like Ollama
+func ollamaReply(reply ollama.Message) map[string]interface{} {
+	message := map[string]interface{}{"role": reply.Role, "content": reply.Content}
+	var calls []map[string]interface{}
+	for _, call := range reply.ToolCalls {
+		calls = append(calls, map[string]interface{}{"function": map[string]interface{}{
+			"name":      call.Function.Name,
+			"arguments": json.RawMessage(call.Function.Arguments),
+		}})
+	}
+	if len(calls) > 0 {
+		message["tool_calls"] = calls
+	}
+	return message
+}